@@ -5,8 +5,8 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"html/template"
 	"io"
 	"io/ioutil"
 	"mime"
@@ -18,10 +18,15 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"cloud.google.com/go/storage"
+	alitea "github.com/alibabacloud-go/tea/tea"
 	"github.com/aliyun/alibabacloud-oss-go-sdk-v2/oss"
 	osscreds "github.com/aliyun/alibabacloud-oss-go-sdk-v2/oss/credentials"
+	alicreds "github.com/aliyun/credentials-go/credentials"
 	"github.com/allegro/bigcache/v3"
 	"github.com/dgraph-io/badger/v4"
 	"github.com/go-playground/validator/v10"
@@ -32,6 +37,7 @@ import (
 	"github.com/minio/minio-go/v7/pkg/credentials"
 	"github.com/redis/go-redis/v9"
 	"github.com/sirupsen/logrus"
+	"google.golang.org/api/option"
 	"gopkg.in/natefinch/lumberjack.v2"
 	"gopkg.in/yaml.v3"
 )
@@ -52,12 +58,19 @@ type ModConfig struct {
 		Version     string   `yaml:"version"`
 		ServiceBase string   `yaml:"service_base"`
 		TokenKeys   []string `yaml:"token_keys"`
+		Env         string   `yaml:"env"` // 运行环境，如 development、staging、production，MOD_ENV 环境变量优先
 	} `yaml:"app"`
 
+	// ID生成策略，用于请求ID、文件ID和自动生成的文件名
+	IDGenerator struct {
+		Type string `yaml:"type"` // snowflake（默认）、ulid、uuidv7
+	} `yaml:"id_generator"`
+
 	// 服务器配置 - 从app中拆分出来的独立配置
 	Server struct {
 		Host                      string   `yaml:"host"`
 		Port                      int      `yaml:"port"`
+		PortAuto                  bool     `yaml:"port_auto"` // 端口被占用时自动改用其他端口，仅用于开发环境
 		ReadTimeout               string   `yaml:"read_timeout"`
 		WriteTimeout              string   `yaml:"write_timeout"`
 		IdleTimeout               string   `yaml:"idle_timeout"`
@@ -81,6 +94,7 @@ type ModConfig struct {
 		Concurrency               int      `yaml:"concurrency"`
 		Views                     string   `yaml:"views"`
 		TrustedProxies            []string `yaml:"trusted_proxies"`
+		ShutdownTimeout           string   `yaml:"shutdown_timeout"` // 关闭服务时等待后台任务（ctx.Go）结束的最长时间，默认30s
 
 		// CORS跨域配置
 		CORS struct {
@@ -150,6 +164,13 @@ type ModConfig struct {
 			KeepOriginalName bool     `yaml:"keep_original_name"` // 是否保持原始文件名
 			AutoCreateDir    bool     `yaml:"auto_create_dir"`    // 自动创建上传目录
 			DateSubDir       bool     `yaml:"date_sub_dir"`       // 按日期创建子目录
+
+			// 静态加密：使用 AES256-GCM 加密写入磁盘，下载路由透明解密，适用于无法启用磁盘加密但需要在本地保存敏感文件的部署
+			Encryption struct {
+				Enabled bool   `yaml:"enabled"`  // 是否加密写入的文件，启用前上传的文件仍按明文读取
+				Key     string `yaml:"key"`      // base64编码的32字节密钥，也可通过 keys.entries 的 file_upload.local 配置
+				KeyFile string `yaml:"key_file"` // 密钥文件路径（原始字节）
+			} `yaml:"encryption"`
 		} `yaml:"local"`
 
 		S3 struct {
@@ -164,12 +185,150 @@ type ModConfig struct {
 		OSS struct {
 			Enabled         bool   `yaml:"enabled"`
 			Bucket          string `yaml:"bucket"`
-			Endpoint        string `yaml:"endpoint"`
+			Region          string `yaml:"region"`       // 地域，如 cn-hangzhou（为空时从endpoint推断）
+			Endpoint        string `yaml:"endpoint"`     // 外网访问端点，用于生成访问URL
+			UseInternal     bool   `yaml:"use_internal"` // 是否通过内网端点上传（同地域ECS推荐）
 			AccessKeyID     string `yaml:"access_key_id"`
 			AccessKeySecret string `yaml:"access_key_secret"`
+			SecurityToken   string `yaml:"security_token"` // STS临时凭证的SecurityToken（可选）
+
+			// STS AssumeRole配置，启用后使用上面的AK/SK扮演角色获取临时凭证并自动刷新
+			STS struct {
+				Enabled         bool   `yaml:"enabled"`           // 是否启用STS AssumeRole
+				RoleArn         string `yaml:"role_arn"`          // 角色ARN
+				RoleSessionName string `yaml:"role_session_name"` // 会话名称
+				Duration        string `yaml:"duration"`          // 临时凭证有效期，默认1h
+				Policy          string `yaml:"policy"`            // 可选的权限策略（JSON）
+				Endpoint        string `yaml:"endpoint"`          // STS服务端点，默认sts.aliyuncs.com
+			} `yaml:"sts"`
 		} `yaml:"oss"`
+
+		GCS struct {
+			Enabled         bool   `yaml:"enabled"`
+			Bucket          string `yaml:"bucket"`
+			CredentialsFile string `yaml:"credentials_file"` // 服务账号凭证JSON文件路径
+			CredentialsJSON string `yaml:"credentials_json"` // 服务账号凭证JSON内容（与文件二选一）
+			CDNDomain       string `yaml:"cdn_domain"`       // 可选的CDN域名，用于生成访问URL
+		} `yaml:"gcs"`
+
+		// 腾讯云COS配置（通过S3兼容接口访问）
+		COS struct {
+			Enabled   bool   `yaml:"enabled"`
+			Bucket    string `yaml:"bucket"`     // 存储桶名称，格式为 BucketName-APPID
+			Region    string `yaml:"region"`     // 地域，如 ap-guangzhou
+			SecretID  string `yaml:"secret_id"`  // 访问密钥ID
+			SecretKey string `yaml:"secret_key"` // 访问密钥
+			Endpoint  string `yaml:"endpoint"`   // 自定义端点，默认 cos.<region>.myqcloud.com
+			Domain    string `yaml:"domain"`     // 可选的自定义/CDN域名，用于生成访问URL
+		} `yaml:"cos"`
+
+		// 七牛云Kodo配置（通过S3兼容接口访问）
+		Qiniu struct {
+			Enabled   bool   `yaml:"enabled"`
+			Bucket    string `yaml:"bucket"`
+			Region    string `yaml:"region"`     // 区域ID，如 cn-east-1
+			AccessKey string `yaml:"access_key"` // AccessKey
+			SecretKey string `yaml:"secret_key"` // SecretKey
+			Endpoint  string `yaml:"endpoint"`   // 自定义端点，默认 s3.<region>.qiniucs.com
+			Domain    string `yaml:"domain"`     // 空间绑定的访问域名（七牛必须通过绑定域名访问）
+		} `yaml:"qiniu"`
+
+		// 文件元数据存储配置
+		Metadata struct {
+			Enabled   bool   `yaml:"enabled"`    // 是否记录上传文件元数据并注册文件管理服务
+			Path      string `yaml:"path"`       // BadgerDB存储路径，默认 ./data/files
+			InMemory  bool   `yaml:"in_memory"`  // 是否纯内存模式（重启后丢失）
+			KeyPrefix string `yaml:"key_prefix"` // 存储键前缀，默认 file:
+
+			// 生命周期规则，定期清理过期文件（同时删除元数据和存储对象）
+			Lifecycle struct {
+				Enabled  bool   `yaml:"enabled"`  // 是否启用生命周期清理
+				Interval string `yaml:"interval"` // 检查间隔，默认1h
+				Rules    []struct {
+					Name     string `yaml:"name"`     // 规则名称（用于审计日志）
+					Category string `yaml:"category"` // 匹配上传时指定的分类，如 temp
+					MIME     string `yaml:"mime"`     // 匹配MIME类型前缀
+					Backend  string `yaml:"backend"`  // 匹配存储后端
+					MaxAge   string `yaml:"max_age"`  // 最长保留时间，支持 7d、72h 等格式
+				} `yaml:"rules"`
+			} `yaml:"lifecycle"`
+		} `yaml:"metadata"`
+
+		// 文件下载配置（依赖文件元数据存储），通过带签名的限时链接分享私有文件
+		Download struct {
+			Enabled bool   `yaml:"enabled"` // 是否启用 /download/{fileID} 下载路由
+			Path    string `yaml:"path"`    // 下载路由前缀，默认 /download
+			Secret  string `yaml:"secret"`  // 链接签名密钥，为空时由JWT密钥派生
+			Expire  string `yaml:"expire"`  // 链接默认有效期，默认1h
+			Mode    string `yaml:"mode"`    // 下载方式：redirect（重定向到预签名地址，默认）、proxy（服务端代理）
+		} `yaml:"download"`
+
+		// 访问URL改写规则，按顺序匹配第一条，将存储桶地址映射到 CDN 或自定义域名
+		URLRewrite []FileURLRule `yaml:"url_rewrite"`
+
+		// 上传配额配置（依赖文件元数据存储），按用户和租户统计已上传文件的总大小
+		Quota struct {
+			Enabled     bool              `yaml:"enabled"`      // 是否启用上传配额
+			UserLimit   string            `yaml:"user_limit"`   // 每个用户的默认配额，如 1GB，为空表示不限制
+			TenantLimit string            `yaml:"tenant_limit"` // 每个租户的默认配额，为空表示不限制
+			TenantKey   string            `yaml:"tenant_key"`   // 从JWT扩展字段或Token数据中读取租户ID的键，默认 tenant_id
+			Users       map[string]string `yaml:"users"`        // 指定用户的配额，覆盖默认值
+			Tenants     map[string]string `yaml:"tenants"`      // 指定租户的配额，覆盖默认值
+		} `yaml:"quota"`
+
+		// 文件内容深度校验配置，对所有存储后端生效
+		Validation struct {
+			Enabled        bool     `yaml:"enabled"`          // 是否启用内容深度校验
+			StrictMIME     bool     `yaml:"strict_mime"`      // 扩展名与文件头签名（magic bytes）必须一致
+			BlockedExts    []string `yaml:"blocked_exts"`     // 禁止上传的扩展名，如 .exe、.php
+			BlockDoubleExt bool     `yaml:"block_double_ext"` // 禁止包含危险扩展名的多重扩展名，如 a.php.jpg
+			BlockSVGScript bool     `yaml:"block_svg_script"` // 禁止包含脚本、事件处理器或外部链接的SVG（按XML解析检查）
+
+			// 压缩包检查，防止压缩炸弹和包含危险文件
+			Archive struct {
+				Enabled         bool    `yaml:"enabled"`          // 是否检查zip/gzip压缩包
+				MaxEntries      int     `yaml:"max_entries"`      // 最大文件数，默认1000
+				MaxUncompressed string  `yaml:"max_uncompressed"` // 解压后最大总大小（按实际解压的字节数计算），默认100MB
+				MaxRatio        float64 `yaml:"max_ratio"`        // 最大压缩比，默认100
+				BlockNested     bool    `yaml:"block_nested"`     // 是否禁止嵌套压缩包
+				BlockDangerous  bool    `yaml:"block_dangerous"`  // 是否禁止包含危险扩展名的条目
+			} `yaml:"archive"`
+		} `yaml:"validation"`
+
+		// 图片处理：上传的 JPEG、PNG、GIF 图片去除EXIF，并生成缩略图、尺寸预设和格式转换版本，与原图保存在同一后端
+		Image struct {
+			Enabled   bool          `yaml:"enabled"`    // 是否处理上传的图片，无法识别为图片的文件不受影响
+			StripEXIF bool          `yaml:"strip_exif"` // 去除原图的 EXIF、XMP 等元数据，带方向信息的 JPEG 按方向旋转后重新编码
+			Format    string        `yaml:"format"`     // 转换格式：jpeg、png、gif 或通过 RegisterImageEncoder 注册的格式（如 webp），设置后额外保存原尺寸的转换版本，缩略图和预设也使用该格式
+			Quality   int           `yaml:"quality"`    // 有损格式的质量（1-100），默认85
+			MaxPixels int           `yaml:"max_pixels"` // 允许处理的最大像素数，默认50000000，超过时拒绝上传
+			Thumbnail ImagePreset   `yaml:"thumbnail"`  // 缩略图，设置 width 或 height 时生成，名称默认为 thumbnail
+			Presets   []ImagePreset `yaml:"presets"`    // 尺寸预设
+		} `yaml:"image"`
+
+		// 批量上传配置
+		Batch struct {
+			Concurrency  int    `yaml:"concurrency"`    // 并发处理的文件数，默认1（顺序处理）
+			MaxFiles     int    `yaml:"max_files"`      // 单次批量上传的最大文件数，0表示不限制
+			MaxTotalSize string `yaml:"max_total_size"` // 单次批量上传的总大小限制，如 100MB，为空表示不限制
+			AllOrNothing bool   `yaml:"all_or_nothing"` // 任一文件失败时整批失败，并清理已保存的文件
+		} `yaml:"batch"`
 	} `yaml:"file_upload"`
 
+	// HTML错误页：浏览器访问文档、静态挂载等页面出错时渲染带品牌信息的错误页，代替JSON响应
+	ErrorPages struct {
+		Enabled     bool             `yaml:"enabled"`      // 是否启用HTML错误页（仅在使用框架错误处理器时生效）
+		TemplateDir string           `yaml:"template_dir"` // 模板目录，按 404.html、4xx.html、error.html 的顺序查找，缺失时使用内置页面
+		Brand       ErrorPageBrand   `yaml:"brand"`        // 品牌信息
+		Groups      []ErrorPageGroup `yaml:"groups"`       // 按URL前缀覆盖模板目录和品牌信息
+	} `yaml:"error_pages"`
+
+	// 模板：文档页面、错误页和应用自己的HTML模板（如邮件）
+	Templates struct {
+		Dir       string `yaml:"dir"`        // 模板目录，其中的 *.html、*.tmpl 按相对路径注册，与内置模板同名时覆盖（docs.html、error.html）
+		HotReload bool   `yaml:"hot_reload"` // 开发模式：渲染前检查模板文件的修改时间并重新加载
+	} `yaml:"templates"`
+
 	StaticMounts []struct {
 		URLPrefix  string `yaml:"url_prefix"`
 		LocalPath  string `yaml:"local_path"`
@@ -225,9 +384,33 @@ type ModConfig struct {
 			SkipExpiredCheck bool   `yaml:"skip_expired_check"`
 			CacheStrategy    string `yaml:"cache_strategy"` // "bigcache", "badger", "redis"
 			CacheKeyPrefix   string `yaml:"cache_key_prefix"`
+
+			// 缓存故障（Redis、BadgerDB 查询出错）时的处理策略
+			Degradation struct {
+				Policy        string            `yaml:"policy"`         // fail_open（默认，允许通过）、fail_closed（拒绝，返回503）、local_fallback（使用本地最近验证成功的Token，未命中时拒绝）
+				Environments  map[string]string `yaml:"environments"`   // 按运行环境（app.env）覆盖 policy，如 production: fail_closed
+				FallbackSize  int               `yaml:"fallback_size"`  // local_fallback 本地缓存的最大Token数量，默认10000
+				FallbackTTL   string            `yaml:"fallback_ttl"`   // local_fallback 本地缓存的有效期，默认5m
+				AlertInterval string            `yaml:"alert_interval"` // 故障告警（错误日志和 OnTokenCacheDegraded 回调）的最小间隔，默认1m
+			} `yaml:"degradation"`
+
+			// Token管理服务（token_list、token_touch、token_remove）：查询、续期和删除Token缓存中的 token
+			Admin struct {
+				Enabled  bool `yaml:"enabled"`   // 是否注册Token管理服务
+				SkipAuth bool `yaml:"skip_auth"` // 管理服务是否跳过认证（仅建议在开发环境开启）
+			} `yaml:"admin"`
 		} `yaml:"validation"`
+
+		ScopeClaim string `yaml:"scope_claim"` // JWT声明（extra）或Token缓存数据中的权限范围字段，默认 scope
 	} `yaml:"token"`
 
+	// 密钥管理：JWT签名、服务加解密等功能的密钥来源
+	Keys struct {
+		RefreshInterval string               `yaml:"refresh_interval"` // 刷新间隔，刷新时发现密钥变化即视为轮换，默认 5m
+		KeepVersions    int                  `yaml:"keep_versions"`    // 轮换后保留的旧版本数量，用于解密和验证轮换前的数据，默认 2
+		Entries         map[string]KeyConfig `yaml:"entries"`          // 密钥名称 -> 提供者配置，如 jwt、encryption.symmetric
+	} `yaml:"keys"`
+
 	// 服务加解密配置 - 支持三个级别的加解密设置
 	Encryption struct {
 		// 全局加解密设置
@@ -283,8 +466,48 @@ type ModConfig struct {
 		} `yaml:"whitelist"`
 	} `yaml:"encryption"`
 
+	// 外部请求配置 - ctx.HTTP() 返回的客户端
+	HTTPClient struct {
+		Timeout               string   `yaml:"timeout"`                 // 单次调用总超时（含重试），默认30s
+		DialTimeout           string   `yaml:"dial_timeout"`            // 建立连接超时，默认5s
+		TLSHandshakeTimeout   string   `yaml:"tls_handshake_timeout"`   // TLS握手超时，默认5s
+		ResponseHeaderTimeout string   `yaml:"response_header_timeout"` // 等待响应头超时，默认10s
+		IdleConnTimeout       string   `yaml:"idle_conn_timeout"`       // 空闲连接保留时间，默认90s
+		MaxIdleConnsPerHost   int      `yaml:"max_idle_conns_per_host"` // 每个主机的最大空闲连接数，默认16
+		PropagateHeaders      []string `yaml:"propagate_headers"`       // 从入站请求透传的请求头，默认 traceparent、tracestate
+
+		// 失败重试：仅对幂等请求（GET、HEAD、OPTIONS、PUT、DELETE 或携带 Idempotency-Key）生效
+		Retry struct {
+			MaxRetries int    `yaml:"max_retries"` // 最大重试次数，默认2，小于0表示不重试
+			Backoff    string `yaml:"backoff"`     // 首次重试等待时间，按指数增长并加入随机抖动，默认100ms
+			MaxBackoff string `yaml:"max_backoff"` // 最大等待时间，默认2s
+			Statuses   []int  `yaml:"statuses"`    // 触发重试的响应状态码，默认 502、503、504
+		} `yaml:"retry"`
+
+		// 按主机熔断：连续失败达到阈值后在 open_duration 内直接返回 mod.ErrCircuitOpen
+		CircuitBreaker struct {
+			FailureThreshold int    `yaml:"failure_threshold"` // 连续失败次数阈值，默认5，小于0表示不熔断
+			OpenDuration     string `yaml:"open_duration"`     // 熔断持续时间，之后放行一个探测请求，默认30s
+		} `yaml:"circuit_breaker"`
+	} `yaml:"http_client"`
+
 	// Mock配置 - 支持三个级别的Mock设置
 	Mock struct {
+		// 随机种子，非0时生成可复现的Mock数据；单个请求可通过 X-Mock-Seed 请求头指定
+		Seed int64 `yaml:"seed"`
+
+		// 禁止Mock的运行环境（app.env）：存在启用Mock的服务时拒绝启动，运行时开关不能开启Mock，请求时执行真实的处理函数
+		ForbidIn []string `yaml:"forbid_in"`
+		// 需要告警的运行环境：启动时或运行时开启Mock后记录错误日志并调用 app.OnMockAlert 回调，每次Mock响应记录审计日志
+		WarnIn []string `yaml:"warn_in"`
+
+		// 运行时Mock管理（mock_status、mock_toggle、mock_reset 服务及文档页面开关）
+		Admin struct {
+			Enabled  bool   `yaml:"enabled"`   // 是否启用运行时Mock管理
+			SkipAuth bool   `yaml:"skip_auth"` // 管理服务是否跳过认证（仅建议在开发环境开启）
+			CacheKey string `yaml:"cache_key"` // 开关持久化到缓存（Redis/BadgerDB）时使用的键，默认 mod:mock:overrides
+		} `yaml:"admin"`
+
 		// 全局Mock设置
 		Global struct {
 			Enabled bool `yaml:"enabled"` // 是否启用全局Mock
@@ -297,9 +520,316 @@ type ModConfig struct {
 
 		// 服务级别Mock设置
 		Services map[string]struct {
-			Enabled bool `yaml:"enabled"` // 是否启用该服务的Mock
+			Enabled  bool   `yaml:"enabled"`  // 是否启用该服务的Mock
+			Fixture  string `yaml:"fixture"`  // Mock数据文件（JSON），或包含多个场景文件的目录
+			Scenario string `yaml:"scenario"` // 默认场景名（fixture为目录时使用），可通过 X-Mock-Scenario 请求头覆盖
+			Upstream bool   `yaml:"upstream"` // 是否仅模拟该服务通过 ctx.HTTP() 发出的外部请求（处理函数正常执行）
 		} `yaml:"services"`
+
+		// 外部依赖模拟：启用后 ctx.HTTP() 发出的请求按规则返回预设响应
+		Upstream struct {
+			Enabled     bool `yaml:"enabled"`     // 是否对所有服务启用外部请求模拟
+			Passthrough bool `yaml:"passthrough"` // 未匹配规则的请求是否放行到真实网络，默认返回错误
+			Rules       []struct {
+				Service  string            `yaml:"service"`   // 仅对指定服务生效，为空表示全部服务
+				Method   string            `yaml:"method"`    // 请求方法，为空表示任意方法
+				URL      string            `yaml:"url"`       // 请求地址，支持 * 通配符，如 https://api.example.com/orders/*
+				Status   int               `yaml:"status"`    // 响应状态码，默认200
+				Headers  map[string]string `yaml:"headers"`   // 响应头
+				Body     string            `yaml:"body"`      // 响应内容
+				BodyFile string            `yaml:"body_file"` // 从文件读取响应内容（优先于body）
+				Delay    string            `yaml:"delay"`     // 模拟延迟，如 200ms
+			} `yaml:"rules"`
+		} `yaml:"upstream"`
 	} `yaml:"mock"`
+
+	// 文档可见性：隐藏的服务仍可正常调用，但不出现在 /services/docs、OpenAPI 和 TypeScript SDK 中
+	Docs struct {
+		// 隐藏规则，匹配当前运行环境（app.env）的规则生效
+		Hide []struct {
+			Env      []string `yaml:"env"`      // 生效的运行环境，为空表示所有环境
+			Groups   []string `yaml:"groups"`   // 隐藏的分组
+			Services []string `yaml:"services"` // 隐藏的服务，支持 * 通配符，如 debug_*
+		} `yaml:"hide"`
+		// 仍然展示隐藏服务（标记为内部）的运行环境，如 development
+		ShowHiddenIn []string `yaml:"show_hidden_in"`
+	} `yaml:"docs"`
+
+	// 接口兼容性检查：启动时将当前生成的 OpenAPI 文档与上一版本的基线比较，记录删除字段、修改类型等不兼容变更
+	SchemaCheck struct {
+		Baseline string   `yaml:"baseline"` // 基线文档路径（app.WriteOpenAPI 导出的 openapi.json），为空时不检查
+		FailIn   []string `yaml:"fail_in"`  // 存在不兼容变更时拒绝启动的运行环境，其他环境只记录警告日志
+	} `yaml:"schema_check"`
+
+	// 国际化：按语言加载消息目录，Reply 的消息为目录中的键时按请求语言返回
+	I18n struct {
+		Dir           string `yaml:"dir"`            // 消息目录文件所在目录，文件名为语言，如 zh-CN.yml、en.yml
+		DefaultLocale string `yaml:"default_locale"` // 默认语言，为空时使用最先加载的语言
+		QueryKey      string `yaml:"query_key"`      // 指定语言的查询参数，默认 lang
+		ClaimKey      string `yaml:"claim_key"`      // 用户声明或Token数据中的语言字段，默认 locale
+	} `yaml:"i18n"`
+
+	// 时区：ctx.Location() 按请求头、查询参数或用户声明确定调用方时区
+	Timezone struct {
+		Default         string `yaml:"default"`          // 默认时区，如 Asia/Shanghai，默认 UTC
+		Header          string `yaml:"header"`           // 指定时区的请求头，默认 X-Timezone
+		QueryKey        string `yaml:"query_key"`        // 指定时区的查询参数，默认 tz
+		ClaimKey        string `yaml:"claim_key"`        // 用户声明或Token数据中的时区字段，默认 timezone
+		ConvertResponse bool   `yaml:"convert_response"` // 将响应数据中的 time.Time 转换为调用方时区
+	} `yaml:"timezone"`
+
+	// 流量镜像：按比例将服务的线上请求复制给影子实现（app.Shadow 注册的处理函数或上游地址），影子响应不返回给客户端
+	Shadow struct {
+		Timeout       string `yaml:"timeout"`        // 单次镜像请求超时，默认5s
+		MaxConcurrent int    `yaml:"max_concurrent"` // 同时执行的镜像请求上限，超出时丢弃，默认100
+		Services      map[string]struct {
+			Percent float64 `yaml:"percent"` // 镜像比例，0-100
+			URL     string  `yaml:"url"`     // 影子实现地址，转发原始请求体和请求头；为空时使用 app.Shadow 注册的处理函数
+			Compare bool    `yaml:"compare"` // 比较影子实现与主实现的响应，不一致时记录警告日志
+		} `yaml:"services"`
+	} `yaml:"shadow"`
+
+	// 灰度发布：同名服务注册多个版本（Service.Version）时按权重或请求头/Cookie分发
+	Canary struct {
+		Header   string                    `yaml:"header"`   // 指定版本的请求头，默认 X-Canary
+		Cookie   string                    `yaml:"cookie"`   // 指定版本的Cookie，默认 canary
+		Sticky   bool                      `yaml:"sticky"`   // 按权重选中版本后写入Cookie，后续请求固定到该版本
+		Services map[string]map[string]int `yaml:"services"` // 服务名 -> 版本 -> 权重，未配置时全部流量进入最先注册的版本
+	} `yaml:"canary"`
+
+	// 请求捕获：将失败请求的完整快照（请求头、请求体、用户、rid）保存到BadgerDB，通过 capture_replay 服务重放
+	Capture struct {
+		Enabled       bool     `yaml:"enabled"`        // 是否启用请求捕获并注册捕获管理服务
+		Path          string   `yaml:"path"`           // BadgerDB存储路径，默认 ./data/captures
+		InMemory      bool     `yaml:"in_memory"`      // 是否纯内存模式（重启后丢失）
+		MinStatus     int      `yaml:"min_status"`     // 捕获响应状态码不低于该值的请求，默认500
+		Services      []string `yaml:"services"`       // 仅捕获指定的服务，为空表示全部服务
+		MaxBodySize   string   `yaml:"max_body_size"`  // 请求体和响应体保存的最大长度，超出部分截断，默认1MB
+		TTL           string   `yaml:"ttl"`            // 快照保存时间，默认72h
+		RedactHeaders []string `yaml:"redact_headers"` // 不保存的请求头，默认为 token_keys、Cookie、API Key 和签名请求头
+		SkipAuth      bool     `yaml:"skip_auth"`      // 管理服务是否跳过认证（仅建议在开发环境开启）
+	} `yaml:"capture"`
+
+	// 认证方式：按服务或分组选择 none、token_cache、jwt、api_key、signature
+	Auth struct {
+		Default      string            `yaml:"default"`        // 默认认证方式，默认 token_cache
+		Groups       map[string]string `yaml:"groups"`         // 分组名 -> 认证方式
+		Services     map[string]string `yaml:"services"`       // 服务名 -> 认证方式（服务未在代码中指定 Auth/SkipAuth 时生效）
+		APIKeyHeader string            `yaml:"api_key_header"` // api_key 方式读取密钥的请求头，默认 X-API-Key
+		Signature    struct {
+			Secret          string `yaml:"secret"`           // HMAC-SHA256 签名密钥
+			Header          string `yaml:"header"`           // 签名请求头，默认 X-Signature
+			TimestampHeader string `yaml:"timestamp_header"` // 时间戳请求头（秒级Unix时间戳），默认 X-Timestamp
+			MaxSkew         string `yaml:"max_skew"`         // 允许的时间偏差，默认5m
+		} `yaml:"signature"`
+		// 管理接口（/admin/slo、/admin/metrics 等）和框架注册的管理服务（token_list 等）的认证，配置了 skip_auth 时不校验
+		Admin struct {
+			Strategy string   `yaml:"strategy"` // 认证方式，默认使用 auth.default，未配置或为 none 时为 token_cache
+			Scopes   []string `yaml:"scopes"`   // Token须被授予的权限范围，默认 admin；signature 方式不检查
+		} `yaml:"admin"`
+	} `yaml:"auth"`
+
+	// 运行时业务设置：app.Settings() 读写的键值对持久化到 Redis 或 BadgerDB
+	Settings struct {
+		CacheKey string `yaml:"cache_key"` // Redis 哈希的键（BadgerDB 中为键前缀），默认 mod:settings
+		Admin    struct {
+			Enabled  bool `yaml:"enabled"`   // 是否注册设置管理服务（settings_list、settings_set、settings_delete）
+			SkipAuth bool `yaml:"skip_auth"` // 管理服务是否跳过认证（仅建议在开发环境开启）
+		} `yaml:"admin"`
+	} `yaml:"settings"`
+
+	// 运行时重载：通过管理服务增删静态文件挂载、重新加载或切换上传存储后端，无需重启实例
+	Reload struct {
+		Admin struct {
+			Enabled  bool `yaml:"enabled"`   // 是否注册重载管理服务（reload_status、static_mount_add 等）
+			SkipAuth bool `yaml:"skip_auth"` // 管理服务是否跳过认证（仅建议在开发环境开启）
+		} `yaml:"admin"`
+	} `yaml:"reload"`
+
+	// 负载卸载：实例过载（goroutine、内存、调度延迟超过上限）时按优先级拒绝请求，保护关键服务的延迟
+	LoadShedding struct {
+		Enabled       bool               `yaml:"enabled"`        // 是否启用负载卸载
+		Interval      string             `yaml:"interval"`       // 采样间隔，默认1s
+		MaxGoroutines int                `yaml:"max_goroutines"` // goroutine 数量上限，0表示不检查
+		MaxMemory     string             `yaml:"max_memory"`     // 堆内存上限，如 2GB，为空时不检查
+		MaxLatency    string             `yaml:"max_latency"`    // 调度延迟上限，如 50ms，为空时不检查
+		RetryAfter    string             `yaml:"retry_after"`    // 拒绝时的 Retry-After，默认5s
+		Classes       map[string]float64 `yaml:"classes"`        // 优先级 -> 开始拒绝的负载系数，默认 low: 0.8、normal: 1；critical 始终不拒绝
+		Services      map[string]string  `yaml:"services"`       // 服务名 -> 优先级，优先于 Service.Priority
+	} `yaml:"load_shedding"`
+
+	// 并发限制：同时执行的服务处理函数超过上限时按优先级排队，高优先级的服务先获得执行槽位
+	ConcurrencyLimit struct {
+		Enabled      bool           `yaml:"enabled"`       // 是否启用并发限制
+		MaxInFlight  int            `yaml:"max_in_flight"` // 同时执行的服务处理函数上限
+		QueueSize    int            `yaml:"queue_size"`    // 排队上限，默认1000，已满时挤出更低优先级的请求或拒绝
+		QueueTimeout string         `yaml:"queue_timeout"` // 排队超时，默认3s
+		Classes      map[string]int `yaml:"classes"`       // 优先级 -> 排序值（越大越先执行），内置 critical 300、high 200、normal 100、low 0
+	} `yaml:"concurrency_limit"`
+
+	// 请求体大小上限和接受的格式：未配置时使用 server.body_limit，接受全部格式
+	RequestBody struct {
+		Default  RequestBody            `yaml:"default"`  // 所有服务的配置
+		Services map[string]RequestBody `yaml:"services"` // 服务名 -> 配置，优先于 Service.RequestBody
+	} `yaml:"request_body"`
+
+	// 响应大小上限：防止无分页的列表服务返回过大的响应拖垮实例，超过上限时记录警告日志并截断列表或返回错误
+	ResponseLimit struct {
+		Default  ResponseLimit            `yaml:"default"`  // 所有服务的上限，为空时不限制
+		Services map[string]ResponseLimit `yaml:"services"` // 服务名 -> 上限，优先于 Service.ResponseLimit
+	} `yaml:"response_limit"`
+
+	// 响应格式协商：请求的 Accept 为 application/xml 或 application/msgpack 时服务的JSON响应按对应格式返回
+	ResponseFormat struct {
+		Disabled bool     `yaml:"disabled"`  // 关闭协商，所有服务始终返回JSON
+		JSONOnly []string `yaml:"json_only"` // 始终返回JSON的服务名称，等同于 Service.JSONOnly
+	} `yaml:"response_format"`
+
+	// 响应缓存：幂等查询服务的响应数据按请求参数缓存，命中时不调用处理函数
+	ResponseCache struct {
+		MaxSize  string                   `yaml:"max_size"` // bigcache 后端的内存上限，默认64MB
+		Services map[string]ResponseCache `yaml:"services"` // 服务名 -> 缓存配置，优先于 Service.Cache
+	} `yaml:"response_cache"`
+
+	// 异步服务：Service.Async 的请求进入任务队列，由后台工作协程执行
+	Jobs struct {
+		Backend   string `yaml:"backend"`    // memory（默认）、redis（使用 cache.redis，多实例共享队列）
+		Workers   int    `yaml:"workers"`    // 每个实例的工作协程数，默认4
+		QueueSize int    `yaml:"queue_size"` // memory 队列容量，默认1000，队列已满时响应503
+		ResultTTL string `yaml:"result_ttl"` // 任务完成后状态和结果的保留时间，默认24h
+	} `yaml:"jobs"`
+
+	// 定时任务：app.Schedule 注册的任务按 cron 表达式执行，运行记录通过 GET /admin/schedules 查看
+	Schedule struct {
+		Path     string                  `yaml:"path"`      // 运行记录路由（GET），默认 /admin/schedules
+		SkipAuth bool                    `yaml:"skip_auth"` // 运行记录是否跳过认证
+		Timezone string                  `yaml:"timezone"`  // cron 表达式的时区，如 Asia/Shanghai，默认为本地时区
+		History  int                     `yaml:"history"`   // 每个任务保留的运行记录数，默认20
+		Tasks    map[string]ScheduleTask `yaml:"tasks"`     // 任务名 -> 表达式、停用和超时配置，优先于 app.Schedule 的参数
+	} `yaml:"schedule"`
+
+	// 链路追踪：使用 OpenTelemetry 全局 TracerProvider，由应用通过 otel.SetTracerProvider 配置导出器
+	Tracing struct {
+		Enabled  bool                    `yaml:"enabled"`  // 为每次服务调用创建 span，父 span 取自请求头中的 traceparent
+		Services map[string]ServiceTrace `yaml:"services"` // 服务名 -> span 属性和业务字段，优先于 Service.Trace
+	} `yaml:"tracing"`
+
+	// 批量导入：MakeImportHandler 创建的服务逐行读取 NDJSON 请求体，解析校验后通过通道传给处理函数
+	Import struct {
+		Stream      bool   `yaml:"stream"`        // 流式读取请求体（Fiber StreamRequestBody），导入服务不受 server.body_limit 限制且不将请求体读入内存
+		MaxLineSize string `yaml:"max_line_size"` // 单行最大长度，默认1MB，超过的行记为失败
+		MaxErrors   int    `yaml:"max_errors"`    // 结果中最多记录的失败行数，默认1000，-1 表示不限制；失败行数始终完整统计
+		Buffer      int    `yaml:"buffer"`        // 通道缓冲的行数，默认100
+	} `yaml:"import"`
+
+	// 重试：ctx.Retry 的命名策略和重试预算，预算按策略名称分别计算，防止下游故障时重试放大流量
+	Retry struct {
+		Budget struct {
+			Ratio float64 `yaml:"ratio"` // 每次调用为预算增加的重试次数，默认0.2，即重试次数不超过调用次数的20%；小于0表示不限制
+			Burst int     `yaml:"burst"` // 预算上限，也是初始值，默认10
+		} `yaml:"budget"`
+		Policies map[string]RetryPolicy `yaml:"policies"` // 策略名称 -> 重试策略，填充 ctx.Retry 传入的同名策略中未设置的字段
+	} `yaml:"retry"`
+
+	// 防刷限流：登录、短信验证码等敏感服务按 IP、账号、设备多个维度各自限流，规则优先于 Service.Throttle
+	Throttle struct {
+		Services map[string]ThrottleRule `yaml:"services"` // 服务名 -> 防刷规则
+	} `yaml:"throttle"`
+
+	// 请求限流：全局、分组和服务三层规则同时生效，任一层超限时响应429并设置 Retry-After
+	RateLimit struct {
+		Backend        string               `yaml:"backend"`          // 计数后端：memory、redis，为空时配置了 Redis 使用 redis，否则使用 memory
+		CacheKeyPrefix string               `yaml:"cache_key_prefix"` // Redis 中的键前缀，默认 mod:ratelimit:
+		Global         RateLimit            `yaml:"global"`           // 所有服务共用的规则
+		Groups         map[string]RateLimit `yaml:"groups"`           // 分组名 -> 分组内服务共用的规则
+		Services       map[string]RateLimit `yaml:"services"`         // 服务名 -> 规则，优先于 Service.RateLimit
+	} `yaml:"rate_limit"`
+
+	// 请求头和响应头策略：必需的请求头和固定的响应头，按 default、groups、Service.Headers、services 的顺序合并
+	Headers struct {
+		Security   bool                    `yaml:"security"`     // 为所有响应添加安全基线响应头（HSTS、X-Content-Type-Options、X-Frame-Options、Referrer-Policy）
+		HSTSMaxAge string                  `yaml:"hsts_max_age"` // HSTS 有效期，默认8760h（一年）
+		Default    HeaderPolicy            `yaml:"default"`      // 所有服务的策略
+		Groups     map[string]HeaderPolicy `yaml:"groups"`       // 分组名 -> 策略
+		Services   map[string]HeaderPolicy `yaml:"services"`     // 服务名 -> 策略，优先于 Service.Headers
+	} `yaml:"headers"`
+
+	// 风控：签发Token和服务认证通过后调用 OnRiskCheck 注册的回调，可信设备保存在 Redis、BadgerDB 或进程内存中
+	Risk struct {
+		DeviceHeader   string `yaml:"device_header"`    // 设备标识请求头，默认 X-Device-ID
+		CountryHeader  string `yaml:"country_header"`   // 国家/地区请求头，如CDN添加的 CF-IPCountry
+		RegionHeader   string `yaml:"region_header"`    // 省份请求头
+		CityHeader     string `yaml:"city_header"`      // 城市请求头
+		TrustedTTL     string `yaml:"trusted_ttl"`      // 可信设备有效期，默认720h
+		CacheKeyPrefix string `yaml:"cache_key_prefix"` // 可信设备的缓存键前缀，默认 mod:devices:
+		ChallengeCode  int    `yaml:"challenge_code"`   // 需要额外验证时的状态码，默认428
+		DenyCode       int    `yaml:"deny_code"`        // 拒绝时的状态码，默认403
+	} `yaml:"risk"`
+
+	// 长轮询：ctx.LongPoll 等待 app.Publish 发布的变更，配置 Redis 时通过发布订阅通知所有实例
+	LongPoll struct {
+		CacheKeyPrefix string `yaml:"cache_key_prefix"` // Redis 键和频道的前缀，默认 mod:longpoll:
+		TTL            string `yaml:"ttl"`              // Redis 中变更数据的保留时间，默认24h
+		MaxTimeout     string `yaml:"max_timeout"`      // 单次长轮询的最长等待时间，默认60s
+	} `yaml:"long_poll"`
+
+	// WebSocket：app.RegisterWS 注册的服务，配置 Redis 时 app.Broadcast 通过发布订阅发送到所有实例
+	WebSocket struct {
+		AllowedOrigins []string `yaml:"allowed_origins"`  // 允许的 Origin，为空时只允许同源，"*" 允许所有来源
+		MaxMessageSize string   `yaml:"max_message_size"` // 客户端消息的最大大小，默认1MB
+		PingInterval   string   `yaml:"ping_interval"`    // 心跳间隔，默认30s；超过两个间隔未收到客户端数据时断开
+		WriteTimeout   string   `yaml:"write_timeout"`    // 发送超时，默认10s
+		SendQueue      int      `yaml:"send_queue"`       // 每个连接的发送队列长度，默认64；广播时队列已满的连接被断开
+		CacheKeyPrefix string   `yaml:"cache_key_prefix"` // Redis 频道前缀，默认 mod:ws:
+	} `yaml:"websocket"`
+
+	// Webhook 回调的签名校验和防重放，配置优先于 Service.Webhook
+	Webhook struct {
+		Services map[string]WebhookConfig `yaml:"services"` // 服务名 -> Webhook 配置
+	} `yaml:"webhook"`
+
+	// Prometheus 指标：服务请求数、耗时、错误、请求/响应大小，Token缓存命中率和文件上传吞吐量
+	Metrics struct {
+		Enabled        bool      `yaml:"enabled"`         // 是否启用指标路由
+		Path           string    `yaml:"path"`            // 指标路由（GET），默认 /metrics
+		SkipAuth       bool      `yaml:"skip_auth"`       // 指标路由是否跳过认证，仅在内网可访问时开启
+		LatencyBuckets []float64 `yaml:"latency_buckets"` // 耗时直方图分桶（秒），默认 0.005 到 10
+		SizeBuckets    []float64 `yaml:"size_buckets"`    // 大小直方图分桶（字节），默认 100 到 100MB
+	} `yaml:"metrics"`
+
+	// 服务SLO：按目标延迟和达标率统计滚动窗口内的错误预算，消耗过快时告警
+	SLO struct {
+		Enabled       bool                 `yaml:"enabled"`        // 是否启用SLO统计和报告路由
+		Path          string               `yaml:"path"`           // 报告路由（GET），默认 /admin/slo
+		SkipAuth      bool                 `yaml:"skip_auth"`      // 报告是否跳过认证
+		Window        string               `yaml:"window"`         // 滚动窗口，默认1h
+		BurnRate      float64              `yaml:"burn_rate"`      // 错误预算消耗速度达到该值时告警，默认2
+		MinRequests   int                  `yaml:"min_requests"`   // 窗口内请求数不少于该值时才告警，默认20
+		AlertInterval string               `yaml:"alert_interval"` // 同一服务的告警间隔，默认10m
+		Services      map[string]SLOTarget `yaml:"services"`       // 服务名 -> SLO目标，优先于 Service.SLO
+	} `yaml:"slo"`
+
+	// 配置解析说明：GET /admin/resolve?service=get_order 返回服务生效的 Mock、加解密、认证、限流和权限配置及来源
+	Resolve struct {
+		Enabled  bool   `yaml:"enabled"`   // 是否注册配置解析说明路由
+		Path     string `yaml:"path"`      // 路由（GET），默认 /admin/resolve
+		SkipAuth bool   `yaml:"skip_auth"` // 是否跳过认证
+	} `yaml:"resolve"`
+
+	// 连接池统计：GET /admin/pools 返回 Redis 连接池、BadgerDB、BigCache、文件上传后端和 Fiber 并发的使用情况
+	Pools struct {
+		Enabled  bool   `yaml:"enabled"`   // 是否注册连接池统计路由
+		Path     string `yaml:"path"`      // 路由（GET），默认 /admin/pools
+		SkipAuth bool   `yaml:"skip_auth"` // 是否跳过认证
+	} `yaml:"pools"`
+
+	// 启动校验：New() 记录配置、缓存、文件上传等子系统的初始化结果并输出启动报告
+	Startup struct {
+		FailFast bool `yaml:"fail_fast"` // 存在初始化失败的子系统时终止进程，避免带着不完整的配置对外服务
+	} `yaml:"startup"`
+
+	// 子应用配置：app.SubApp(name) 创建的子应用在父应用配置的基础上叠加 apps.<name> 中的配置
+	Apps map[string]yaml.Node `yaml:"apps"`
 }
 
 // loadModConfig attempts to load configuration from mod.yml file
@@ -325,8 +855,17 @@ func loadModConfig() (*ModConfig, error) {
 		return nil, fmt.Errorf("failed to read config file %s: %w", configPath, err)
 	}
 
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", configPath, err)
+	}
+	// 解密 !enc 标签的加密值
+	if err := decryptConfigNode(&root); err != nil {
+		return nil, fmt.Errorf("failed to decrypt config file %s: %w", configPath, err)
+	}
+
 	var config ModConfig
-	if err := yaml.Unmarshal(data, &config); err != nil {
+	if err := root.Decode(&config); err != nil {
 		return nil, fmt.Errorf("failed to parse config file %s: %w", configPath, err)
 	}
 
@@ -592,13 +1131,15 @@ func New(config ...Config) *App {
 	var cfg Config
 	var fileConfig *ModConfig
 	var err error
+	configStart := time.Now()
 
 	if len(config) > 0 {
 		cfg = config[0]
 	}
 
 	// Try to load configuration from mod.yml file
-	if fileConfig, err = loadModConfig(); err != nil {
+	fileConfig, err = loadModConfig()
+	if err != nil {
 		// Log warning but continue with manual config
 		logrus.Warnf("Failed to load mod.yml config: %v", err)
 	} else if fileConfig != nil {
@@ -650,6 +1191,11 @@ func New(config ...Config) *App {
 		cfg.Config.WriteBufferSize = 8192 // 8KB 写入缓冲区
 	}
 
+	// 批量导入服务流式读取请求体，其他服务在处理时按 BodyLimit 检查
+	if cfg.ModConfig.Import.Stream {
+		cfg.Config.StreamRequestBody = true
+	}
+
 	// 设置合理的默认布尔值
 	cfg.Config.DisableStartupMessage = false // 显示启动消息
 	cfg.Config.StrictRouting = false         // 不启用严格路由
@@ -689,34 +1235,81 @@ func New(config ...Config) *App {
 		applyLoggingConfig(cfg.Logger, fileConfig)
 	}
 
-	app := &App{
-		App:       fiber.New(cfg.Config),
-		cfg:       cfg,
-		logger:    cfg.Logger,
-		tokenKeys: cfg.ModConfig.App.TokenKeys,
+	// 未指定 ErrorHandler 时使用框架的错误处理器，错误统一为标准响应格式
+	var app *App
+	frameworkErrors := cfg.Config.ErrorHandler == nil
+	if frameworkErrors {
+		cfg.Config.ErrorHandler = func(c *fiber.Ctx, err error) error {
+			return app.handleError(c, err)
+		}
+	}
+
+	app = &App{
+		App:             fiber.New(cfg.Config),
+		cfg:             cfg,
+		logger:          cfg.Logger,
+		tokenKeys:       cfg.ModConfig.App.TokenKeys,
+		frameworkErrors: frameworkErrors,
+		clock:           &clockRef{},
+	}
+	// 将 panic 转换为错误交由错误处理器处理，须在其他中间件之前注册
+	if frameworkErrors {
+		app.Use(recoverMiddleware)
+	}
+	if err != nil || fileConfig != nil {
+		configPath := os.Getenv("MOD_PATH")
+		if configPath == "" {
+			configPath = "mod.yml"
+		}
+		app.recordStartup("config", configPath, configStart, err)
 	}
 
+	// 初始化ID生成策略
+	app.configureIDGenerator()
+
+	// 加载国际化消息目录
+	app.configureI18n()
+
+	// 配置密钥提供者
+	app.configureKeys()
+
+	// 校验认证方式配置
+	app.checkAuthConfig()
+
 	// 初始化 Token 缓存
 	if fileConfig != nil && fileConfig.Token.Validation.Enabled {
-		switch fileConfig.Token.Validation.CacheStrategy {
+		start := time.Now()
+		strategy := fileConfig.Token.Validation.CacheStrategy
+		switch strategy {
 		case "bigcache":
 			if fileConfig.Cache.BigCache.Enabled {
-				app.initTokenCache(fileConfig)
+				app.recordStartup("cache.bigcache", "", start, app.initTokenCache(fileConfig))
+			} else {
+				app.recordStartup("cache.bigcache", "", start, fmt.Errorf("token validation uses bigcache but cache.bigcache is not enabled"))
 			}
 		case "badger":
 			if fileConfig.Cache.Badger.Enabled {
-				app.initBadgerDB(fileConfig)
+				app.recordStartup("cache.badger", fileConfig.Cache.Badger.Path, start, app.initBadgerDB(fileConfig))
+			} else {
+				app.recordStartup("cache.badger", "", start, fmt.Errorf("token validation uses badger but cache.badger is not enabled"))
 			}
 		case "redis":
 			if fileConfig.Cache.Redis.Enabled {
-				app.initRedisClient(fileConfig)
+				app.recordStartup("cache.redis", fileConfig.Cache.Redis.Address, start, app.initRedisClient(fileConfig))
+			} else {
+				app.recordStartup("cache.redis", "", start, fmt.Errorf("token validation uses redis but cache.redis is not enabled"))
 			}
+		default:
+			app.recordStartup("cache", "", start, fmt.Errorf("unknown token validation cache_strategy %q", strategy))
 		}
 	}
 
 	// 配置CORS中间件（在路由注册之前）
 	app.configureCORS()
 
+	// 配置安全基线响应头（在路由注册之前）
+	app.configureSecurityHeaders()
+
 	// 配置ETag中间件（启用ETag优化性能）
 	app.configureETag()
 
@@ -726,8 +1319,77 @@ func New(config ...Config) *App {
 	// 配置文件上传功能
 	app.configureFileUpload()
 
-	// 注册文档路由
+	// 注册运行时重载管理服务
+	app.configureReloadAdmin()
+
+	// 配置运行时Mock管理
+	app.configureMockAdmin()
+
+	// 配置运行时业务设置
+	app.configureSettings()
+
+	// 注册Token管理服务
+	app.configureTokenAdmin()
+
+	// 初始化风控回调和可信设备存储
+	app.configureRisk()
+
+	// 初始化长轮询状态
+	app.configureLongPoll()
+
+	// 初始化 WebSocket 连接和房间
+	app.configureWebSocket()
+
+	// 初始化后台任务跟踪
+	app.configureGoroutines()
+
+	// 预编译内置模板和模板目录
+	app.configureTemplates()
+
+	// 配置请求捕获与重放
+	app.configureCapture()
+
+	// 配置服务SLO统计
+	app.configureSLO()
+
+	// 配置解析说明路由
+	app.configureResolve()
+
+	// 连接池统计路由
+	app.configurePools()
+
+	// 配置负载卸载和并发限制
+	app.configureLoadShedding()
+	app.configureConcurrencyLimit()
+
+	// 配置请求限流的计数后端
+	app.configureRateLimit()
+
+	// 初始化内置指标并注册指标路由
+	app.configureMetrics()
+
+	// 解析Token缓存故障处理策略
+	app.configureTokenDegradation()
+
+	// 初始化 ctx.Retry 的重试预算
+	app.configureRetry()
+
+	// 初始化服务响应缓存
+	app.configureResponseCache()
+
+	// 初始化异步服务的任务队列
+	app.configureJobs()
+
+	// 初始化定时任务
+	app.configureSchedule()
+
+	// 注册文档路由（包含挂载的子应用中的服务）
 	app.Get("/services/docs", app.handleDocs)
+	app.Get("/services/sdk/typescript", app.handleTypeScriptSDK)
+	app.Get("/services/sdk/go", app.handleGoClient)
+
+	// 输出启动报告，fail_fast 模式下存在失败的子系统时终止进程
+	app.verifyStartup()
 
 	return app
 }
@@ -783,6 +1445,9 @@ func (app *App) configureETag() {
 
 // configureStaticMounts 配置静态文件挂载
 func (app *App) configureStaticMounts() {
+	// 静态文件由挂载表分发，运行时可通过 AddStaticMount、RemoveStaticMount 增删挂载
+	app.Use(app.serveStaticMounts)
+
 	// 检查是否有配置静态文件挂载
 	if app.cfg.ModConfig == nil || len(app.cfg.ModConfig.StaticMounts) == 0 {
 		app.logger.Debug("No static mounts configured")
@@ -790,52 +1455,28 @@ func (app *App) configureStaticMounts() {
 	}
 
 	for _, mount := range app.cfg.ModConfig.StaticMounts {
-		// 参数校验
-		if mount.URLPrefix == "" || mount.LocalPath == "" {
-			app.logger.WithFields(logrus.Fields{
-				"url_prefix": mount.URLPrefix,
-				"local_path": mount.LocalPath,
-			}).Error("Invalid static mount configuration: url_prefix and local_path are required")
-			continue
-		}
+		start := time.Now()
+		target := mount.URLPrefix + " -> " + mount.LocalPath
 
-		// 路径安全检查
-		if !app.isValidStaticPath(mount.LocalPath) {
-			app.logger.WithField("local_path", mount.LocalPath).Error("Invalid local path for static mount")
-			continue
-		}
-
-		// 检查本地路径是否存在
-		if _, err := os.Stat(mount.LocalPath); os.IsNotExist(err) {
-			app.logger.WithFields(logrus.Fields{
+		entry, err := app.newStaticMountEntry(StaticMount(mount))
+		if err != nil {
+			app.logger.WithError(err).WithFields(logrus.Fields{
 				"url_prefix": mount.URLPrefix,
 				"local_path": mount.LocalPath,
-			}).Warn("Static mount local path does not exist, skipping")
+			}).Error("Invalid static mount configuration, skipping")
+			app.recordStartup("static", target, start, err)
 			continue
 		}
-
-		// 构造静态文件配置
-		staticConfig := fiber.Static{
-			Compress:  true,             // 启用压缩
-			ByteRange: true,             // 支持范围请求
-			Browse:    mount.Browseable, // 目录浏览
-		}
-
-		// 设置默认索引文件
-		if mount.IndexFile != "" {
-			staticConfig.Index = mount.IndexFile
-		} else {
-			staticConfig.Index = "index.html" // 默认索引文件
-		}
+		app.recordStartup("static", target, start, nil)
 
 		// 挂载静态文件服务
-		app.Static(mount.URLPrefix, mount.LocalPath, staticConfig)
+		app.reload.putStaticMount(entry)
 
 		app.logger.WithFields(logrus.Fields{
-			"url_prefix": mount.URLPrefix,
-			"local_path": mount.LocalPath,
-			"browseable": mount.Browseable,
-			"index_file": staticConfig.Index,
+			"url_prefix": entry.URLPrefix,
+			"local_path": entry.LocalPath,
+			"browseable": entry.Browseable,
+			"index_file": entry.IndexFile,
 		}).Info("Static mount configured successfully")
 	}
 }
@@ -891,63 +1532,113 @@ func (app *App) configureFileUpload() {
 	hasLocal := config.Local.Enabled
 	hasS3 := config.S3.Enabled
 	hasOSS := config.OSS.Enabled
+	hasGCS := config.GCS.Enabled
+	hasCOS := config.COS.Enabled
+	hasQiniu := config.Qiniu.Enabled
 
-	if !hasLocal && !hasS3 && !hasOSS {
+	if !hasLocal && !hasS3 && !hasOSS && !hasGCS && !hasCOS && !hasQiniu {
 		app.logger.Debug("File upload is disabled")
+		// 启用重载管理时仍注册上传路由，以便运行时启用上传后端
+		if app.cfg.ModConfig.Reload.Admin.Enabled {
+			app.registerUploadRoutes()
+		}
 		return
 	}
 
 	// 本地上传配置
 	if hasLocal {
-		if err := app.configureLocalUpload(); err != nil {
+		start := time.Now()
+		err := app.configureLocalUpload()
+		if err != nil {
 			app.logger.WithError(err).Error("Failed to configure local file upload")
 			hasLocal = false
 		}
+		app.recordStartup("upload.local", "", start, err)
 	}
 
 	// S3上传配置
 	if hasS3 {
-		if err := app.configureS3Upload(); err != nil {
+		start := time.Now()
+		err := app.configureS3Upload()
+		if err != nil {
 			app.logger.WithError(err).Error("Failed to configure S3 file upload")
 			hasS3 = false
 		}
+		app.recordStartup("upload.s3", "", start, err)
 	}
 
 	// OSS上传配置
 	if hasOSS {
-		if err := app.configureOSSUpload(); err != nil {
+		start := time.Now()
+		err := app.configureOSSUpload()
+		if err != nil {
 			app.logger.WithError(err).Error("Failed to configure OSS file upload")
 			hasOSS = false
 		}
+		app.recordStartup("upload.oss", "", start, err)
+	}
+
+	// GCS上传配置
+	if hasGCS {
+		start := time.Now()
+		err := app.configureGCSUpload()
+		if err != nil {
+			app.logger.WithError(err).Error("Failed to configure GCS file upload")
+			hasGCS = false
+		}
+		app.recordStartup("upload.gcs", "", start, err)
+	}
+
+	// COS上传配置
+	if hasCOS {
+		start := time.Now()
+		err := app.configureCOSUpload()
+		if err != nil {
+			app.logger.WithError(err).Error("Failed to configure COS file upload")
+			hasCOS = false
+		}
+		app.recordStartup("upload.cos", "", start, err)
+	}
+
+	// 七牛上传配置
+	if hasQiniu {
+		start := time.Now()
+		err := app.configureQiniuUpload()
+		if err != nil {
+			app.logger.WithError(err).Error("Failed to configure Qiniu file upload")
+			hasQiniu = false
+		}
+		app.recordStartup("upload.qiniu", "", start, err)
 	}
 
-	if !hasLocal && !hasS3 && !hasOSS {
+	if !hasLocal && !hasS3 && !hasOSS && !hasGCS && !hasCOS && !hasQiniu {
 		app.logger.Error("All file upload backends failed to configure")
+		if app.cfg.ModConfig.Reload.Admin.Enabled {
+			app.registerUploadRoutes()
+		}
 		return
 	}
 
 	// 解析最大文件大小
-	var maxSizeBytes int64 = 10 * 1024 * 1024 // 默认10MB
-	if hasLocal && config.Local.MaxSize != "" {
-		if size, err := parseSize(config.Local.MaxSize); err == nil {
-			maxSizeBytes = size
-		}
-	}
+	maxSizeBytes := uploadMaxSize(hasLocal, config.Local.MaxSize)
+	app.reload.uploadMaxSize = maxSizeBytes
 
 	// 注册文件上传路由
-	app.Post("/upload", func(c *fiber.Ctx) error {
-		return app.handleFileUpload(c, maxSizeBytes)
-	})
+	app.registerUploadRoutes()
 
-	// 注册批量文件上传路由
-	app.Post("/upload/batch", func(c *fiber.Ctx) error {
-		return app.handleBatchFileUpload(c, maxSizeBytes)
-	})
+	// 配置文件元数据存储
+	app.configureFileMetadata()
+
+	// 检查图片处理配置
+	app.checkImageConfig()
 
 	app.logger.WithFields(logrus.Fields{
 		"local_enabled": hasLocal,
 		"s3_enabled":    hasS3,
 		"oss_enabled":   hasOSS,
+		"gcs_enabled":   hasGCS,
+		"cos_enabled":   hasCOS,
+		"qiniu_enabled": hasQiniu,
 		"max_size":      maxSizeBytes,
 	}).Info("File upload configured successfully")
 }
@@ -978,6 +1669,12 @@ func (app *App) configureLocalUpload() error {
 		return fmt.Errorf("upload directory does not exist: %s", config.UploadDir)
 	}
 
+	if config.Encryption.Enabled {
+		if err := app.checkLocalEncryption(); err != nil {
+			return fmt.Errorf("local file encryption: %w", err)
+		}
+	}
+
 	app.logger.WithField("upload_dir", config.UploadDir).Info("Local file upload configured")
 	return nil
 }
@@ -990,8 +1687,8 @@ func (app *App) configureOSSUpload() error {
 	if config.Bucket == "" {
 		return fmt.Errorf("bucket is required for OSS file upload")
 	}
-	if config.Endpoint == "" {
-		return fmt.Errorf("endpoint is required for OSS file upload")
+	if config.Endpoint == "" && config.Region == "" {
+		return fmt.Errorf("endpoint or region is required for OSS file upload")
 	}
 	if config.AccessKeyID == "" {
 		return fmt.Errorf("access_key_id is required for OSS file upload")
@@ -999,30 +1696,148 @@ func (app *App) configureOSSUpload() error {
 	if config.AccessKeySecret == "" {
 		return fmt.Errorf("access_key_secret is required for OSS file upload")
 	}
+	if config.STS.Enabled && config.STS.RoleArn == "" {
+		return fmt.Errorf("sts.role_arn is required when OSS STS is enabled")
+	}
 
-	// 创建OSS客户端进行连接测试
-	cfg := oss.LoadDefaultConfig().
-		WithCredentialsProvider(osscreds.NewStaticCredentialsProvider(config.AccessKeyID, config.AccessKeySecret)).
-		WithRegion("cn-shenzhen")
-
-	client := oss.NewClient(cfg)
+	// 创建OSS客户端
+	client, err := app.newOSSClient()
+	if err != nil {
+		return err
+	}
 
 	// 测试连接（获取bucket信息）
 	ctx := context.Background()
-	_, err := client.GetBucketInfo(ctx, &oss.GetBucketInfoRequest{
+	_, err = client.GetBucketInfo(ctx, &oss.GetBucketInfoRequest{
 		Bucket: oss.Ptr(config.Bucket),
 	})
 	if err != nil {
 		return fmt.Errorf("failed to connect to OSS bucket %s: %v", config.Bucket, err)
 	}
 
+	app.ossClient = client
+
 	app.logger.WithFields(logrus.Fields{
-		"bucket":   config.Bucket,
-		"endpoint": config.Endpoint,
+		"bucket":       config.Bucket,
+		"region":       app.ossRegion(),
+		"endpoint":     config.Endpoint,
+		"use_internal": config.UseInternal,
+		"sts":          config.STS.Enabled,
 	}).Info("OSS file upload configured")
 	return nil
 }
 
+// newOSSClient 根据配置创建OSS客户端
+func (app *App) newOSSClient() (*oss.Client, error) {
+	config := app.cfg.ModConfig.FileUpload.OSS
+
+	provider, err := app.newOSSCredentialsProvider()
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := oss.LoadDefaultConfig().
+		WithCredentialsProvider(provider).
+		WithRegion(app.ossRegion())
+
+	if config.UseInternal {
+		// 内网端点由SDK根据region自动生成
+		cfg = cfg.WithUseInternalEndpoint(true)
+	} else if config.Endpoint != "" {
+		cfg = cfg.WithEndpoint(config.Endpoint)
+	}
+
+	return oss.NewClient(cfg), nil
+}
+
+// newOSSCredentialsProvider 创建OSS凭证提供者，支持静态AK/SK、STS Token以及AssumeRole自动刷新
+func (app *App) newOSSCredentialsProvider() (osscreds.CredentialsProvider, error) {
+	config := app.cfg.ModConfig.FileUpload.OSS
+
+	if !config.STS.Enabled {
+		if config.SecurityToken != "" {
+			return osscreds.NewStaticCredentialsProvider(config.AccessKeyID, config.AccessKeySecret, config.SecurityToken), nil
+		}
+		return osscreds.NewStaticCredentialsProvider(config.AccessKeyID, config.AccessKeySecret), nil
+	}
+
+	sessionName := config.STS.RoleSessionName
+	if sessionName == "" {
+		sessionName = "mod-oss-upload"
+	}
+
+	duration := time.Hour
+	if config.STS.Duration != "" {
+		if d, err := time.ParseDuration(config.STS.Duration); err == nil {
+			duration = d
+		} else {
+			app.logger.WithError(err).Warn("Invalid OSS STS duration, using default 1h")
+		}
+	}
+
+	credConfig := new(alicreds.Config).
+		SetType("ram_role_arn").
+		SetAccessKeyId(config.AccessKeyID).
+		SetAccessKeySecret(config.AccessKeySecret).
+		SetRoleArn(config.STS.RoleArn).
+		SetRoleSessionName(sessionName).
+		SetRoleSessionExpiration(int(duration.Seconds()))
+	if config.SecurityToken != "" {
+		credConfig.SetSecurityToken(config.SecurityToken)
+	}
+	if config.STS.Policy != "" {
+		credConfig.SetPolicy(config.STS.Policy)
+	}
+	if config.STS.Endpoint != "" {
+		credConfig.SetSTSEndpoint(config.STS.Endpoint)
+	}
+
+	// credentials-go 内部会在临时凭证即将过期时自动刷新
+	roleCredential, err := alicreds.NewCredential(credConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OSS STS credential: %v", err)
+	}
+
+	return osscreds.CredentialsProviderFunc(func(ctx context.Context) (osscreds.Credentials, error) {
+		cred, err := roleCredential.GetCredential()
+		if err != nil {
+			return osscreds.Credentials{}, fmt.Errorf("failed to assume OSS STS role: %v", err)
+		}
+		return osscreds.Credentials{
+			AccessKeyID:     alitea.StringValue(cred.AccessKeyId),
+			AccessKeySecret: alitea.StringValue(cred.AccessKeySecret),
+			SecurityToken:   alitea.StringValue(cred.SecurityToken),
+		}, nil
+	}), nil
+}
+
+// ossRegion 获取OSS地域，未配置时尝试从endpoint推断（如 oss-cn-hangzhou.aliyuncs.com）
+func (app *App) ossRegion() string {
+	config := app.cfg.ModConfig.FileUpload.OSS
+	if config.Region != "" {
+		return config.Region
+	}
+
+	host := strings.TrimPrefix(strings.TrimPrefix(config.Endpoint, "https://"), "http://")
+	if strings.HasPrefix(host, "oss-") {
+		region := strings.SplitN(strings.TrimPrefix(host, "oss-"), ".", 2)[0]
+		return strings.TrimSuffix(region, "-internal")
+	}
+
+	return "cn-shenzhen"
+}
+
+// ossPublicEndpoint 获取用于生成访问URL的外网端点
+func (app *App) ossPublicEndpoint() string {
+	config := app.cfg.ModConfig.FileUpload.OSS
+	endpoint := strings.TrimPrefix(strings.TrimPrefix(config.Endpoint, "https://"), "http://")
+	if endpoint == "" {
+		return fmt.Sprintf("oss-%s.aliyuncs.com", app.ossRegion())
+	}
+	// 访问URL始终使用外网端点
+	return strings.Replace(endpoint, "-internal.", ".", 1)
+}
+
 // configureS3Upload 配置S3文件上传
 func (app *App) configureS3Upload() error {
 	config := app.cfg.ModConfig.FileUpload.S3
@@ -1087,85 +1902,301 @@ func (app *App) configureS3Upload() error {
 	return nil
 }
 
-// isValidUploadPath 验证上传路径的安全性
-func (app *App) isValidUploadPath(path string) bool {
-	// 基本路径验证
-	if path == "" {
-		return false
-	}
+// newS3Client 根据配置创建S3客户端
+func (app *App) newS3Client() (*minio.Client, error) {
+	config := app.cfg.ModConfig.FileUpload.S3
 
-	// 防止路径遍历攻击
-	if strings.Contains(path, "..") {
-		app.logger.WithField("path", path).Warn("Upload path traversal attempt detected")
-		return false
+	endpoint := "s3.amazonaws.com"
+	useSSL := true
+	if config.Endpoint != "" {
+		endpoint = config.Endpoint
+		useSSL = strings.HasPrefix(endpoint, "https://")
+		endpoint = strings.TrimPrefix(endpoint, "http://")
+		endpoint = strings.TrimPrefix(endpoint, "https://")
 	}
 
-	// 转换为绝对路径进行进一步检查
-	absPath, err := filepath.Abs(path)
+	client, err := minio.New(endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(config.AccessKey, config.SecretKey, ""),
+		Secure: useSSL,
+		Region: config.Region,
+	})
 	if err != nil {
-		app.logger.WithError(err).WithField("path", path).Error("Failed to resolve absolute upload path")
-		return false
+		return nil, fmt.Errorf("failed to create S3 client: %v", err)
 	}
+	return client, nil
+}
 
-	// 获取工作目录
-	wd, err := os.Getwd()
+// configureGCSUpload 配置Google Cloud Storage文件上传
+func (app *App) configureGCSUpload() error {
+	config := app.cfg.ModConfig.FileUpload.GCS
+
+	// 参数校验
+	if config.Bucket == "" {
+		return fmt.Errorf("bucket is required for GCS file upload")
+	}
+
+	// 创建GCS客户端，未配置凭证时使用应用默认凭证（ADC）
+	var opts []option.ClientOption
+	if config.CredentialsFile != "" {
+		opts = append(opts, option.WithCredentialsFile(config.CredentialsFile))
+	} else if config.CredentialsJSON != "" {
+		opts = append(opts, option.WithCredentialsJSON([]byte(config.CredentialsJSON)))
+	}
+
+	ctx := context.Background()
+	client, err := storage.NewClient(ctx, opts...)
 	if err != nil {
-		app.logger.WithError(err).Error("Failed to get working directory for upload validation")
-		return true // 如果无法获取工作目录，允许通过（降级处理）
+		return fmt.Errorf("failed to create GCS client: %v", err)
 	}
 
-	// 检查路径是否在工作目录下或其子目录中
-	workdirAbs, _ := filepath.Abs(wd)
-	if !strings.HasPrefix(absPath, workdirAbs) {
-		app.logger.WithFields(logrus.Fields{
-			"abs_path": absPath,
-			"workdir":  workdirAbs,
-		}).Warn("Upload path is outside of working directory")
-		// 这里不严格禁止，只是警告，因为可能有合法的用例
+	// 测试连接（获取bucket信息）
+	if _, err := client.Bucket(config.Bucket).Attrs(ctx); err != nil {
+		client.Close()
+		return fmt.Errorf("failed to connect to GCS bucket %s: %v", config.Bucket, err)
 	}
 
-	return true
+	app.gcsClient = client
+
+	app.logger.WithFields(logrus.Fields{
+		"bucket":     config.Bucket,
+		"cdn_domain": config.CDNDomain,
+	}).Info("GCS file upload configured")
+	return nil
 }
 
-// handleFileUpload 处理单文件上传
-func (app *App) handleFileUpload(c *fiber.Ctx, maxSizeBytes int64) error {
-	// 获取上传的文件
-	file, err := c.FormFile("file")
-	if err != nil {
-		return c.Status(400).JSON(fiber.Map{
-			"error":   "No file provided",
-			"message": "请选择要上传的文件",
-		})
+// configureCOSUpload 配置腾讯云COS文件上传
+func (app *App) configureCOSUpload() error {
+	config := app.cfg.ModConfig.FileUpload.COS
+
+	// 参数校验
+	if config.Bucket == "" {
+		return fmt.Errorf("bucket is required for COS file upload")
+	}
+	if config.Region == "" {
+		return fmt.Errorf("region is required for COS file upload")
+	}
+	if config.SecretID == "" {
+		return fmt.Errorf("secret_id is required for COS file upload")
+	}
+	if config.SecretKey == "" {
+		return fmt.Errorf("secret_key is required for COS file upload")
 	}
 
-	// 验证文件
-	if err := app.validateUploadFile(file, maxSizeBytes); err != nil {
-		return c.Status(400).JSON(fiber.Map{
-			"error":   "File validation failed",
-			"message": err.Error(),
-		})
+	endpoint := config.Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("cos.%s.myqcloud.com", config.Region)
 	}
 
-	// 确定上传后端
-	backend := app.determineUploadBackend()
-	if backend == "" {
-		return c.Status(500).JSON(fiber.Map{
-			"error":   "No upload backend available",
-			"message": "文件上传服务不可用",
-		})
+	client, err := app.newS3CompatibleClient(endpoint, config.SecretID, config.SecretKey, config.Region)
+	if err != nil {
+		return fmt.Errorf("failed to create COS client: %v", err)
 	}
 
-	// 保存文件
-	result, err := app.saveUploadFile(file, backend)
+	// 测试连接（检查bucket是否存在）
+	exists, err := client.BucketExists(context.Background(), config.Bucket)
 	if err != nil {
-		app.logger.WithError(err).Error("Failed to save uploaded file")
-		return c.Status(500).JSON(fiber.Map{
-			"error":   "Failed to save file",
-			"message": "文件保存失败",
-		})
+		return fmt.Errorf("failed to check COS bucket %s: %v", config.Bucket, err)
+	}
+	if !exists {
+		return fmt.Errorf("COS bucket %s does not exist", config.Bucket)
 	}
 
-	// 返回成功响应
+	app.cosClient = client
+
+	app.logger.WithFields(logrus.Fields{
+		"bucket":   config.Bucket,
+		"region":   config.Region,
+		"endpoint": endpoint,
+	}).Info("COS file upload configured")
+	return nil
+}
+
+// configureQiniuUpload 配置七牛云Kodo文件上传
+func (app *App) configureQiniuUpload() error {
+	config := app.cfg.ModConfig.FileUpload.Qiniu
+
+	// 参数校验
+	if config.Bucket == "" {
+		return fmt.Errorf("bucket is required for Qiniu file upload")
+	}
+	if config.Region == "" && config.Endpoint == "" {
+		return fmt.Errorf("region or endpoint is required for Qiniu file upload")
+	}
+	if config.AccessKey == "" {
+		return fmt.Errorf("access_key is required for Qiniu file upload")
+	}
+	if config.SecretKey == "" {
+		return fmt.Errorf("secret_key is required for Qiniu file upload")
+	}
+	if config.Domain == "" {
+		return fmt.Errorf("domain is required for Qiniu file upload")
+	}
+
+	endpoint := config.Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("s3.%s.qiniucs.com", config.Region)
+	}
+
+	client, err := app.newS3CompatibleClient(endpoint, config.AccessKey, config.SecretKey, config.Region)
+	if err != nil {
+		return fmt.Errorf("failed to create Qiniu client: %v", err)
+	}
+
+	// 测试连接（检查bucket是否存在）
+	exists, err := client.BucketExists(context.Background(), config.Bucket)
+	if err != nil {
+		return fmt.Errorf("failed to check Qiniu bucket %s: %v", config.Bucket, err)
+	}
+	if !exists {
+		return fmt.Errorf("Qiniu bucket %s does not exist", config.Bucket)
+	}
+
+	app.qiniuClient = client
+
+	app.logger.WithFields(logrus.Fields{
+		"bucket":   config.Bucket,
+		"region":   config.Region,
+		"endpoint": endpoint,
+		"domain":   config.Domain,
+	}).Info("Qiniu file upload configured")
+	return nil
+}
+
+// newS3CompatibleClient 创建S3兼容存储客户端（COS、七牛等）
+func (app *App) newS3CompatibleClient(endpoint, accessKey, secretKey, region string) (*minio.Client, error) {
+	useSSL := !strings.HasPrefix(endpoint, "http://")
+	endpoint = strings.TrimPrefix(endpoint, "http://")
+	endpoint = strings.TrimPrefix(endpoint, "https://")
+
+	return minio.New(endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(accessKey, secretKey, ""),
+		Secure: useSSL,
+		Region: region,
+	})
+}
+
+// isValidUploadPath 验证上传路径的安全性
+func (app *App) isValidUploadPath(path string) bool {
+	// 基本路径验证
+	if path == "" {
+		return false
+	}
+
+	// 防止路径遍历攻击
+	if strings.Contains(path, "..") {
+		app.logger.WithField("path", path).Warn("Upload path traversal attempt detected")
+		return false
+	}
+
+	// 转换为绝对路径进行进一步检查
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		app.logger.WithError(err).WithField("path", path).Error("Failed to resolve absolute upload path")
+		return false
+	}
+
+	// 获取工作目录
+	wd, err := os.Getwd()
+	if err != nil {
+		app.logger.WithError(err).Error("Failed to get working directory for upload validation")
+		return true // 如果无法获取工作目录，允许通过（降级处理）
+	}
+
+	// 检查路径是否在工作目录下或其子目录中
+	workdirAbs, _ := filepath.Abs(wd)
+	if !strings.HasPrefix(absPath, workdirAbs) {
+		app.logger.WithFields(logrus.Fields{
+			"abs_path": absPath,
+			"workdir":  workdirAbs,
+		}).Warn("Upload path is outside of working directory")
+		// 这里不严格禁止，只是警告，因为可能有合法的用例
+	}
+
+	return true
+}
+
+// handleFileUpload 处理单文件上传
+func (app *App) handleFileUpload(c *fiber.Ctx, maxSizeBytes int64) error {
+	// 获取上传的文件
+	file, err := c.FormFile("file")
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error":   "No file provided",
+			"message": "请选择要上传的文件",
+		})
+	}
+
+	// 验证文件
+	if err := app.validateUploadFile(file, maxSizeBytes); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error":   "File validation failed",
+			"message": err.Error(),
+		})
+	}
+
+	// 检查上传配额
+	if err := app.checkUploadQuota(c, file.Size); err != nil {
+		if quotaErr, ok := err.(*QuotaExceededError); ok {
+			return c.Status(413).JSON(fiber.Map{
+				"error":   "Upload quota exceeded",
+				"message": quotaErr.Error(),
+				"quota":   quotaErr.Quota,
+			})
+		}
+		app.logger.WithError(err).Error("Failed to check upload quota")
+		return c.Status(500).JSON(fiber.Map{
+			"error":   "Failed to check upload quota",
+			"message": "上传配额检查失败",
+		})
+	}
+
+	// 确定上传后端
+	backend := app.determineUploadBackend()
+	if backend == "" {
+		return c.Status(500).JSON(fiber.Map{
+			"error":   "No upload backend available",
+			"message": "文件上传服务不可用",
+		})
+	}
+
+	// 处理图片
+	file, img, err := app.prepareUploadImage(file)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error":   "Image processing failed",
+			"message": err.Error(),
+		})
+	}
+
+	// 保存文件
+	result, err := app.saveUploadFile(file, backend)
+	if err != nil {
+		app.logger.WithError(err).Error("Failed to save uploaded file")
+		return c.Status(500).JSON(fiber.Map{
+			"error":   "Failed to save file",
+			"message": "文件保存失败",
+		})
+	}
+
+	// 保存图片的派生版本，失败时删除原图
+	if err := app.saveImageVariants(img, backend, result); err != nil {
+		app.logger.WithError(err).Error("Failed to save image variants")
+		meta := &FileMetadata{Backend: backend}
+		meta.applyUploadResult(result)
+		if err := app.deleteStoredObject(meta); err != nil {
+			app.logger.WithError(err).Error("Failed to clean up uploaded file")
+		}
+		return c.Status(500).JSON(fiber.Map{
+			"error":   "Failed to save file",
+			"message": "文件保存失败",
+		})
+	}
+
+	// 记录文件元数据
+	app.recordUploadFile(c, file, backend, result)
+	app.rewriteUploadResult(backend, result)
+
+	// 返回成功响应
 	return c.JSON(fiber.Map{
 		"success": true,
 		"message": "文件上传成功",
@@ -1174,8 +2205,20 @@ func (app *App) handleFileUpload(c *fiber.Ctx, maxSizeBytes int64) error {
 	})
 }
 
+// 批量上传中单个文件的错误码
+const (
+	UploadErrValidation = "VALIDATION_FAILED" // 文件校验失败
+	UploadErrQuota      = "QUOTA_EXCEEDED"    // 超出上传配额
+	UploadErrImage      = "IMAGE_FAILED"      // 图片处理失败
+	UploadErrSave       = "SAVE_FAILED"       // 保存到存储后端失败
+	UploadErrAborted    = "ABORTED"           // 整批模式下因其他文件失败而未处理
+	UploadErrRolledBack = "ROLLED_BACK"       // 整批模式下已保存但因其他文件失败而被清理
+)
+
 // handleBatchFileUpload 处理批量文件上传
 func (app *App) handleBatchFileUpload(c *fiber.Ctx, maxSizeBytes int64) error {
+	config := app.cfg.ModConfig.FileUpload.Batch
+
 	// 获取所有上传的文件
 	form, err := c.MultipartForm()
 	if err != nil {
@@ -1193,6 +2236,28 @@ func (app *App) handleBatchFileUpload(c *fiber.Ctx, maxSizeBytes int64) error {
 		})
 	}
 
+	// 检查批次文件数和总大小
+	if config.MaxFiles > 0 && len(files) > config.MaxFiles {
+		return c.Status(400).JSON(fiber.Map{
+			"error":   "Too many files",
+			"message": fmt.Sprintf("单次最多上传 %d 个文件", config.MaxFiles),
+		})
+	}
+	if config.MaxTotalSize != "" {
+		if maxTotal, err := parseSize(config.MaxTotalSize); err == nil {
+			var total int64
+			for _, file := range files {
+				total += file.Size
+			}
+			if total > maxTotal {
+				return c.Status(413).JSON(fiber.Map{
+					"error":   "Batch too large",
+					"message": fmt.Sprintf("批量上传总大小 %d 超过限制 %d", total, maxTotal),
+				})
+			}
+		}
+	}
+
 	// 确定上传后端
 	backend := app.determineUploadBackend()
 	if backend == "" {
@@ -1202,38 +2267,145 @@ func (app *App) handleBatchFileUpload(c *fiber.Ctx, maxSizeBytes int64) error {
 		})
 	}
 
-	var results []fiber.Map
-	var successCount int
+	concurrency := config.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	results := make([]fiber.Map, len(files))
+	saved := make([]fiber.Map, len(files))
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		failed   bool
+		reserved int64 // 本批次已通过配额检查但尚未记录元数据的字节数
+	)
+	sem := make(chan struct{}, concurrency)
 
 	// 处理每个文件
-	for _, file := range files {
-		result := fiber.Map{
+	for i, file := range files {
+		results[i] = fiber.Map{
 			"filename": file.Filename,
 			"size":     file.Size,
 		}
 
-		// 验证文件
-		if err := app.validateUploadFile(file, maxSizeBytes); err != nil {
-			result["success"] = false
-			result["error"] = err.Error()
-			results = append(results, result)
-			continue
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, file *multipart.FileHeader) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			fail := func(code, message string) {
+				mu.Lock()
+				defer mu.Unlock()
+				failed = true
+				results[i]["success"] = false
+				results[i]["error_code"] = code
+				results[i]["error"] = message
+			}
+
+			// 整批模式下已有文件失败时不再处理
+			mu.Lock()
+			aborted := config.AllOrNothing && failed
+			mu.Unlock()
+			if aborted {
+				fail(UploadErrAborted, "其他文件上传失败，已取消")
+				return
+			}
+
+			// 验证文件
+			if err := app.validateUploadFile(file, maxSizeBytes); err != nil {
+				fail(UploadErrValidation, err.Error())
+				return
+			}
+
+			// 检查上传配额（计入本批次已接受的文件）
+			mu.Lock()
+			err := app.checkUploadQuota(c, reserved+file.Size)
+			if err == nil {
+				reserved += file.Size
+			}
+			mu.Unlock()
+			if err != nil {
+				if _, ok := err.(*QuotaExceededError); ok {
+					fail(UploadErrQuota, err.Error())
+				} else {
+					app.logger.WithError(err).WithField("filename", file.Filename).Error("Failed to check upload quota in batch")
+					fail(UploadErrQuota, "上传配额检查失败")
+				}
+				return
+			}
+
+			// 处理图片
+			processed, img, err := app.prepareUploadImage(file)
+			if err != nil {
+				fail(UploadErrImage, err.Error())
+				return
+			}
+
+			// 保存文件
+			savedResult, err := app.saveUploadFile(processed, backend)
+			if err != nil {
+				app.logger.WithError(err).WithField("filename", file.Filename).Error("Failed to save uploaded file in batch")
+				fail(UploadErrSave, "文件保存失败")
+				return
+			}
+			if err := app.saveImageVariants(img, backend, savedResult); err != nil {
+				app.logger.WithError(err).WithField("filename", file.Filename).Error("Failed to save image variants in batch")
+				meta := &FileMetadata{Backend: backend}
+				meta.applyUploadResult(savedResult)
+				if err := app.deleteStoredObject(meta); err != nil {
+					app.logger.WithError(err).WithField("filename", file.Filename).Error("Failed to clean up uploaded file in batch")
+				}
+				fail(UploadErrSave, "文件保存失败")
+				return
+			}
+			files[i] = processed
+			saved[i] = savedResult
+		}(i, file)
+	}
+	wg.Wait()
+
+	// 整批模式下有文件失败时清理已保存的文件
+	if config.AllOrNothing && failed {
+		for i, savedResult := range saved {
+			if savedResult == nil {
+				continue
+			}
+			meta := &FileMetadata{Backend: backend}
+			meta.applyUploadResult(savedResult)
+			if err := app.deleteStoredObject(meta); err != nil {
+				app.logger.WithError(err).WithField("filename", files[i].Filename).Error("Failed to clean up uploaded file in batch")
+			}
+			results[i]["success"] = false
+			results[i]["error_code"] = UploadErrRolledBack
+			results[i]["error"] = "其他文件上传失败，已清理"
 		}
 
-		// 保存文件
-		savedResult, err := app.saveUploadFile(file, backend)
-		if err != nil {
-			app.logger.WithError(err).WithField("filename", file.Filename).Error("Failed to save uploaded file in batch")
-			result["success"] = false
-			result["error"] = "文件保存失败"
-			results = append(results, result)
+		return c.Status(400).JSON(fiber.Map{
+			"success":       false,
+			"message":       "批量上传失败，已清理全部已保存的文件",
+			"backend":       backend,
+			"total":         len(files),
+			"success_count": 0,
+			"failed_count":  len(files),
+			"results":       results,
+		})
+	}
+
+	// 按顺序记录文件元数据
+	var successCount int
+	for i, savedResult := range saved {
+		if savedResult == nil {
 			continue
 		}
+		app.recordUploadFile(c, files[i], backend, savedResult)
+		app.rewriteUploadResult(backend, savedResult)
 
-		result["success"] = true
-		result["data"] = savedResult
+		results[i]["success"] = true
+		results[i]["data"] = savedResult
 		successCount++
-		results = append(results, result)
 	}
 
 	// 返回批量上传结果
@@ -1266,6 +2438,21 @@ func (app *App) determineUploadBackend() string {
 		return "oss"
 	}
 
+	// 然后使用GCS（如果启用且初始化成功）
+	if config.GCS.Enabled && app.gcsClient != nil {
+		return "gcs"
+	}
+
+	// 然后使用COS（如果启用且初始化成功）
+	if config.COS.Enabled && app.cosClient != nil {
+		return "cos"
+	}
+
+	// 然后使用七牛（如果启用且初始化成功）
+	if config.Qiniu.Enabled && app.qiniuClient != nil {
+		return "qiniu"
+	}
+
 	// 最后使用本地存储
 	if config.Local.Enabled {
 		return "local"
@@ -1274,13 +2461,29 @@ func (app *App) determineUploadBackend() string {
 	return ""
 }
 
-// saveUploadFile 根据后端类型保存文件
+// saveUploadFile 根据后端类型保存文件，并记录上传指标
 func (app *App) saveUploadFile(file *multipart.FileHeader, backend string) (fiber.Map, error) {
+	start := time.Now()
+	done := app.trackStorage(backend, true, file.Size)
+	result, err := app.storeUploadFile(file, backend)
+	done(err)
+	app.recordUpload(backend, file.Size, start, err)
+	return result, err
+}
+
+// storeUploadFile 根据后端类型保存文件
+func (app *App) storeUploadFile(file *multipart.FileHeader, backend string) (fiber.Map, error) {
 	switch backend {
 	case "s3":
 		return app.saveFileToS3(file)
 	case "oss":
 		return app.saveFileToOSS(file)
+	case "gcs":
+		return app.saveFileToGCS(file)
+	case "cos":
+		return app.saveFileToCOS(file)
+	case "qiniu":
+		return app.saveFileToQiniu(file)
 	case "local":
 		return app.saveFileToLocal(file)
 	default:
@@ -1288,6 +2491,80 @@ func (app *App) saveUploadFile(file *multipart.FileHeader, backend string) (fibe
 	}
 }
 
+// deleteStoredObject 删除已存储的文件对象及图片的派生版本，并记录操作统计
+func (app *App) deleteStoredObject(meta *FileMetadata) error {
+	done := app.trackStorage(meta.Backend, false, 0)
+	err := app.removeStoredObject(meta)
+	done(err)
+	for _, variant := range meta.Variants {
+		variantMeta := &FileMetadata{Backend: meta.Backend, Bucket: variant.Bucket, ObjectKey: variant.ObjectKey}
+		err = errors.Join(err, app.deleteStoredObject(variantMeta))
+	}
+	return err
+}
+
+// removeStoredObject 根据后端类型删除已存储的文件对象
+func (app *App) removeStoredObject(meta *FileMetadata) error {
+	ctx := context.Background()
+	config := app.cfg.ModConfig.FileUpload
+
+	switch meta.Backend {
+	case "s3":
+		client, err := app.newS3Client()
+		if err != nil {
+			return err
+		}
+		return client.RemoveObject(ctx, app.fileBucket(meta, config.S3.Bucket), meta.ObjectKey, minio.RemoveObjectOptions{})
+	case "oss":
+		client := app.ossClient
+		if client == nil {
+			var err error
+			if client, err = app.newOSSClient(); err != nil {
+				return err
+			}
+		}
+		_, err := client.DeleteObject(ctx, &oss.DeleteObjectRequest{
+			Bucket: oss.Ptr(app.fileBucket(meta, config.OSS.Bucket)),
+			Key:    oss.Ptr(meta.ObjectKey),
+		})
+		return err
+	case "gcs":
+		if app.gcsClient == nil {
+			return fmt.Errorf("GCS client is not initialized")
+		}
+		err := app.gcsClient.Bucket(app.fileBucket(meta, config.GCS.Bucket)).Object(meta.ObjectKey).Delete(ctx)
+		if err == storage.ErrObjectNotExist {
+			return nil
+		}
+		return err
+	case "cos":
+		if app.cosClient == nil {
+			return fmt.Errorf("COS client is not initialized")
+		}
+		return app.cosClient.RemoveObject(ctx, app.fileBucket(meta, config.COS.Bucket), meta.ObjectKey, minio.RemoveObjectOptions{})
+	case "qiniu":
+		if app.qiniuClient == nil {
+			return fmt.Errorf("Qiniu client is not initialized")
+		}
+		return app.qiniuClient.RemoveObject(ctx, app.fileBucket(meta, config.Qiniu.Bucket), meta.ObjectKey, minio.RemoveObjectOptions{})
+	case "local":
+		if err := os.Remove(meta.ObjectKey); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported upload backend: %s", meta.Backend)
+	}
+}
+
+// fileBucket 返回文件所在的存储桶，元数据未记录时使用当前配置
+func (app *App) fileBucket(meta *FileMetadata, fallback string) string {
+	if meta.Bucket != "" {
+		return meta.Bucket
+	}
+	return fallback
+}
+
 // saveFileToOSS 保存文件到阿里云OSS
 func (app *App) saveFileToOSS(file *multipart.FileHeader) (fiber.Map, error) {
 	config := app.cfg.ModConfig.FileUpload.OSS
@@ -1295,12 +2572,14 @@ func (app *App) saveFileToOSS(file *multipart.FileHeader) (fiber.Map, error) {
 	// 生成对象键
 	objectKey := app.generateOSSObjectKey(file.Filename)
 
-	// 创建OSS客户端
-	cfg := oss.LoadDefaultConfig().
-		WithCredentialsProvider(osscreds.NewStaticCredentialsProvider(config.AccessKeyID, config.AccessKeySecret)).
-		WithRegion("cn-shenzhen")
-
-	client := oss.NewClient(cfg)
+	// 获取OSS客户端
+	client := app.ossClient
+	if client == nil {
+		var err error
+		if client, err = app.newOSSClient(); err != nil {
+			return nil, err
+		}
+	}
 
 	// 打开上传文件
 	src, err := file.Open()
@@ -1321,7 +2600,7 @@ func (app *App) saveFileToOSS(file *multipart.FileHeader) (fiber.Map, error) {
 	}
 
 	// 生成访问URL
-	accessURL := fmt.Sprintf("https://%s.%s/%s", config.Bucket, config.Endpoint, objectKey)
+	accessURL := fmt.Sprintf("https://%s.%s/%s", config.Bucket, app.ossPublicEndpoint(), objectKey)
 
 	return fiber.Map{
 		"filename":   filepath.Base(objectKey),
@@ -1413,6 +2692,148 @@ func (app *App) saveFileToS3(file *multipart.FileHeader) (fiber.Map, error) {
 	}, nil
 }
 
+// saveFileToGCS 保存文件到Google Cloud Storage
+func (app *App) saveFileToGCS(file *multipart.FileHeader) (fiber.Map, error) {
+	config := app.cfg.ModConfig.FileUpload.GCS
+
+	if app.gcsClient == nil {
+		return nil, fmt.Errorf("GCS client is not initialized")
+	}
+
+	// 生成对象键（与S3/OSS相同的格式）
+	objectKey := app.generateS3ObjectKey(file.Filename)
+
+	// 打开上传文件
+	src, err := file.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open uploaded file: %v", err)
+	}
+	defer src.Close()
+
+	// 检测文件MIME类型
+	contentType := mime.TypeByExtension(filepath.Ext(file.Filename))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	// 上传文件到GCS
+	ctx := context.Background()
+	writer := app.gcsClient.Bucket(config.Bucket).Object(objectKey).NewWriter(ctx)
+	writer.ContentType = contentType
+	if _, err := io.Copy(writer, src); err != nil {
+		writer.Close()
+		return nil, fmt.Errorf("failed to upload file to GCS: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("failed to upload file to GCS: %v", err)
+	}
+
+	// 生成访问URL
+	var accessURL string
+	if config.CDNDomain != "" {
+		accessURL = fmt.Sprintf("%s/%s", strings.TrimSuffix(app.normalizeDomainURL(config.CDNDomain), "/"), objectKey)
+	} else {
+		accessURL = fmt.Sprintf("https://storage.googleapis.com/%s/%s", config.Bucket, objectKey)
+	}
+
+	return fiber.Map{
+		"filename":   filepath.Base(objectKey),
+		"object_key": objectKey,
+		"url":        accessURL,
+		"size":       file.Size,
+		"bucket":     config.Bucket,
+	}, nil
+}
+
+// saveFileToCOS 保存文件到腾讯云COS
+func (app *App) saveFileToCOS(file *multipart.FileHeader) (fiber.Map, error) {
+	config := app.cfg.ModConfig.FileUpload.COS
+
+	objectKey, err := app.putS3CompatibleObject(app.cosClient, config.Bucket, file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to upload file to COS: %v", err)
+	}
+
+	// 生成访问URL
+	var accessURL string
+	if config.Domain != "" {
+		accessURL = fmt.Sprintf("%s/%s", strings.TrimSuffix(app.normalizeDomainURL(config.Domain), "/"), objectKey)
+	} else {
+		accessURL = fmt.Sprintf("https://%s.cos.%s.myqcloud.com/%s", config.Bucket, config.Region, objectKey)
+	}
+
+	return fiber.Map{
+		"filename":   filepath.Base(objectKey),
+		"object_key": objectKey,
+		"url":        accessURL,
+		"size":       file.Size,
+		"bucket":     config.Bucket,
+		"region":     config.Region,
+	}, nil
+}
+
+// saveFileToQiniu 保存文件到七牛云Kodo
+func (app *App) saveFileToQiniu(file *multipart.FileHeader) (fiber.Map, error) {
+	config := app.cfg.ModConfig.FileUpload.Qiniu
+
+	objectKey, err := app.putS3CompatibleObject(app.qiniuClient, config.Bucket, file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to upload file to Qiniu: %v", err)
+	}
+
+	// 七牛通过空间绑定的域名访问
+	accessURL := fmt.Sprintf("%s/%s", strings.TrimSuffix(app.normalizeDomainURL(config.Domain), "/"), objectKey)
+
+	return fiber.Map{
+		"filename":   filepath.Base(objectKey),
+		"object_key": objectKey,
+		"url":        accessURL,
+		"size":       file.Size,
+		"bucket":     config.Bucket,
+		"region":     config.Region,
+	}, nil
+}
+
+// putS3CompatibleObject 通过S3兼容接口上传文件，返回对象键
+func (app *App) putS3CompatibleObject(client *minio.Client, bucket string, file *multipart.FileHeader) (string, error) {
+	if client == nil {
+		return "", fmt.Errorf("storage client is not initialized")
+	}
+
+	// 生成对象键（与S3/OSS相同的格式）
+	objectKey := app.generateS3ObjectKey(file.Filename)
+
+	// 打开上传文件
+	src, err := file.Open()
+	if err != nil {
+		return "", fmt.Errorf("failed to open uploaded file: %v", err)
+	}
+	defer src.Close()
+
+	// 检测文件MIME类型
+	contentType := mime.TypeByExtension(filepath.Ext(file.Filename))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	_, err = client.PutObject(context.Background(), bucket, objectKey, src, file.Size, minio.PutObjectOptions{
+		ContentType: contentType,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return objectKey, nil
+}
+
+// normalizeDomainURL 为未指定协议的域名补全https前缀
+func (app *App) normalizeDomainURL(domain string) string {
+	if strings.HasPrefix(domain, "http://") || strings.HasPrefix(domain, "https://") {
+		return domain
+	}
+	return "https://" + domain
+}
+
 // saveFileToLocal 保存文件到本地（重构现有方法）
 func (app *App) saveFileToLocal(file *multipart.FileHeader) (fiber.Map, error) {
 	config := app.cfg.ModConfig.FileUpload.Local
@@ -1467,7 +2888,14 @@ func (app *App) saveFileToLocal(file *multipart.FileHeader) (fiber.Map, error) {
 	}
 	defer dst.Close()
 
-	if _, err := io.Copy(dst, src); err != nil {
+	if config.Encryption.Enabled {
+		// 加密失败时删除已写入的部分内容
+		if err := app.saveEncryptedFile(dst, src); err != nil {
+			dst.Close()
+			os.Remove(savePath)
+			return nil, fmt.Errorf("failed to save encrypted file: %v", err)
+		}
+	} else if _, err := io.Copy(dst, src); err != nil {
 		return nil, fmt.Errorf("failed to save file: %v", err)
 	}
 
@@ -1528,6 +2956,14 @@ func (app *App) validateUploadFile(file *multipart.FileHeader, maxSizeBytes int6
 		// OSS使用本地配置的验证规则
 		allowedTypes = app.cfg.ModConfig.FileUpload.Local.AllowedTypes
 		allowedExts = app.cfg.ModConfig.FileUpload.Local.AllowedExts
+	} else if app.cfg.ModConfig.FileUpload.GCS.Enabled {
+		// GCS使用本地配置的验证规则
+		allowedTypes = app.cfg.ModConfig.FileUpload.Local.AllowedTypes
+		allowedExts = app.cfg.ModConfig.FileUpload.Local.AllowedExts
+	} else if app.cfg.ModConfig.FileUpload.COS.Enabled || app.cfg.ModConfig.FileUpload.Qiniu.Enabled {
+		// COS/七牛使用本地配置的验证规则
+		allowedTypes = app.cfg.ModConfig.FileUpload.Local.AllowedTypes
+		allowedExts = app.cfg.ModConfig.FileUpload.Local.AllowedExts
 	} else if app.cfg.ModConfig.FileUpload.Local.Enabled {
 		allowedTypes = app.cfg.ModConfig.FileUpload.Local.AllowedTypes
 		allowedExts = app.cfg.ModConfig.FileUpload.Local.AllowedExts
@@ -1581,11 +3017,15 @@ func (app *App) validateUploadFile(file *multipart.FileHeader, maxSizeBytes int6
 		}
 	}
 
-	return nil
+	// 内容深度校验
+	return app.inspectUploadContent(file)
 }
 
-// generateRandomFilename 生成随机文件名
+// generateRandomFilename 生成随机文件名，配置了ID生成策略时使用对应的ID
 func (app *App) generateRandomFilename() (string, error) {
+	if app.idGenerator != nil {
+		return app.idGenerator(), nil
+	}
 	bytes := make([]byte, 16)
 	if _, err := rand.Read(bytes); err != nil {
 		return "", err
@@ -1594,9 +3034,9 @@ func (app *App) generateRandomFilename() (string, error) {
 }
 
 // initTokenCache 初始化 Token 缓存
-func (app *App) initTokenCache(config *ModConfig) {
+func (app *App) initTokenCache(config *ModConfig) error {
 	if !config.Cache.BigCache.Enabled {
-		return
+		return nil
 	}
 
 	// 解析配置参数
@@ -1634,17 +3074,18 @@ func (app *App) initTokenCache(config *ModConfig) {
 	cache, err := bigcache.New(context.Background(), bigCacheConfig)
 	if err != nil {
 		app.logger.WithError(err).Error("Failed to initialize BigCache for token validation")
-		return
+		return err
 	}
 
 	app.tokenCache = cache
 	app.logger.Info("BigCache for token validation initialized successfully")
+	return nil
 }
 
 // initBadgerDB 初始化 BadgerDB
-func (app *App) initBadgerDB(config *ModConfig) {
+func (app *App) initBadgerDB(config *ModConfig) error {
 	if !config.Cache.Badger.Enabled {
-		return
+		return nil
 	}
 
 	dbPath := config.Cache.Badger.Path
@@ -1675,11 +3116,12 @@ func (app *App) initBadgerDB(config *ModConfig) {
 	db, err := badger.Open(opts)
 	if err != nil {
 		app.logger.WithError(err).WithField("path", dbPath).Error("Failed to initialize BadgerDB for token validation")
-		return
+		return err
 	}
 
 	app.badgerDB = db
 	app.logger.WithField("path", dbPath).Info("BadgerDB for token validation initialized successfully")
+	return nil
 }
 
 // badgerLogger 实现 BadgerDB 的 Logger 接口
@@ -1704,16 +3146,16 @@ func (bl *badgerLogger) Debugf(f string, v ...any) {
 }
 
 // initRedisClient 初始化 Redis 客户端
-func (app *App) initRedisClient(config *ModConfig) {
+func (app *App) initRedisClient(config *ModConfig) error {
 	if !config.Cache.Redis.Enabled {
-		return
+		return nil
 	}
 
 	// 从主 Redis 配置获取连接信息
 	redisConfig := config.Cache.Redis
 	if redisConfig.Address == "" {
 		app.logger.Error("Redis address not configured for token validation")
-		return
+		return fmt.Errorf("redis address not configured")
 	}
 
 	// 创建 Redis 客户端选项
@@ -1762,11 +3204,13 @@ func (app *App) initRedisClient(config *ModConfig) {
 	_, err := rdb.Ping(ctx).Result()
 	if err != nil {
 		app.logger.WithError(err).WithField("address", redisConfig.Address).Error("Failed to connect to Redis for token validation")
-		return
+		rdb.Close()
+		return err
 	}
 
 	app.redisClient = rdb
 	app.logger.WithField("address", redisConfig.Address).Info("Redis client for token validation initialized successfully")
+	return nil
 }
 
 type App struct {
@@ -1778,49 +3222,91 @@ type App struct {
 	tokenCache  *bigcache.BigCache // Token验证缓存
 	badgerDB    *badger.DB         // BadgerDB 实例
 	redisClient *redis.Client      // Redis 客户端
-}
+	ossClient   *oss.Client        // OSS 客户端（复用凭证提供者以支持STS自动刷新）
+	gcsClient   *storage.Client    // GCS 客户端
+	cosClient   *minio.Client      // 腾讯云COS 客户端（S3兼容）
+	qiniuClient *minio.Client      // 七牛云Kodo 客户端（S3兼容）
+	fileStore   FileMetadataStore  // 文件元数据存储
 
-func (app *App) Run(addr ...string) {
-	var a string
-	if len(addr) > 0 {
-		a = addr[0]
-	} else {
-		// 优先使用配置文件中的端口和主机
-		host := ""
-		port := 8080 // 默认端口
+	storageStats storageStatsState // 文件上传后端的操作计数
 
-		if app.cfg.ModConfig != nil {
-			if app.cfg.ModConfig.Server.Host != "" {
-				host = app.cfg.ModConfig.Server.Host
-			}
-			if app.cfg.ModConfig.Server.Port > 0 {
-				port = app.cfg.ModConfig.Server.Port
-			}
-		}
+	fileLifecycleStop chan struct{} // 停止文件生命周期清理任务
+	downloadSecret    []byte        // 下载链接签名密钥
 
-		if host == "" || host == "localhost" || host == "127.0.0.1" {
-			a = fmt.Sprintf(":%d", port)
-		} else {
-			a = fmt.Sprintf("%s:%d", host, port)
-		}
-	}
-	app.logger.Info("Starting server on " + a)
-	host := "127.0.0.1"
-	if strings.HasPrefix(a, "0.0.0.0") || strings.HasPrefix(a, "[::]") {
-		host = "127.0.0.1"
-	} else if strings.Contains(a, ":") {
-		host = strings.Split(a, ":")[0]
-		if host == "" {
-			host = "127.0.0.1"
-		}
-	}
-	port := strings.TrimPrefix(a, ":")
-	if port == "" {
-		port = "8080"
-	}
-	docsURL := fmt.Sprintf("http://%s:%s/services/docs", host, port)
-	app.logger.Info("API文档: " + docsURL)
-	if err := app.Listen(a); err != nil {
+	keys *keyring // 密钥提供者、缓存和轮换回调
+
+	tokenDegradation *tokenDegradationState // Token缓存故障处理策略、本地回退缓存和告警回调
+
+	retry *retryState // ctx.Retry 各策略的重试预算和统计
+
+	responseCache *responseCacheState // 服务响应缓存的进程内存储
+
+	jobs *jobState // 异步服务的任务队列和工作协程
+
+	schedule *scheduleState // app.Schedule 注册的定时任务
+
+	mockOverrides *mockOverrideStore // 运行时Mock开关
+	settings      *Settings          // 运行时业务设置
+
+	idGenerator IDGenerator // 请求ID、文件ID和文件名的生成策略
+	clock       *clockRef   // 应用时钟，测试中可冻结
+
+	httpClientOnce sync.Once
+	httpClient     *outboundClient // ctx.HTTP() 共享的连接池、熔断器和统计
+
+	shadowOnce sync.Once
+	shadow     *shadowState // 流量镜像的影子处理函数、并发控制和统计
+
+	canaryMu     sync.RWMutex
+	canaryRoutes map[string]*canaryRoute // 多版本服务的灰度路由
+
+	captureDB *badger.DB // 请求捕获快照存储
+
+	i18n *i18nCatalog // 国际化消息目录
+
+	docsGeneration atomic.Uint64 // 服务注册或挂载时递增，使文档缓存失效
+	docsMu         sync.Mutex
+	docsCache      *docsSnapshot // 渲染后的文档页面
+
+	startupChecks []StartupCheck // New() 时各子系统的初始化结果
+
+	listenMu sync.Mutex
+	listen   listenState // 实际监听的地址和启动回调
+
+	authMu    sync.RWMutex
+	groupAuth map[string]AuthStrategy // SetGroupAuth 设置的分组认证方式
+
+	throttle  throttleState     // 敏感服务的防刷令牌桶
+	rateLimit *rateLimitState   // 请求限流的计数后端和规则
+	metrics   *metricsState     // Prometheus 指标和自定义采集器
+	shedder   *loadShedder      // 负载采样和卸载状态，未启用时为 nil
+	limiter   *executionLimiter // 服务执行槽位，未启用并发限制时为 nil
+	webhooks  webhookState      // Webhook 校验配置和投递记录
+	risk      *riskState        // 风控回调和内存中的可信设备
+	longPoll  *longPollState    // 长轮询各键的最新版本和等待者
+	websocket *wsHub            // WebSocket 连接和房间
+
+	goroutines *goroutineState   // 通过 Go 启动的后台任务
+	templates  *TemplateRegistry // 文档页面、错误页等HTML模板
+
+	slo *sloState // 服务SLO的滚动窗口计数和告警回调
+
+	frameworkErrors bool                                    // 是否使用框架的错误处理器
+	errorPages      errorPages                              // HTML错误页模板，首次渲染时加载
+	errorHooks      []func(ctx *Context, event *ErrorEvent) // OnError 注册的错误处理钩子
+
+	serviceMiddlewares serviceMiddlewareState // UseServiceMiddleware、UseGroupMiddleware 注册的服务中间件
+	reload             reloadState            // 运行时可替换的静态文件挂载和上传存储后端
+
+	subName string       // 子应用名称，仅由 SubApp 创建的应用设置
+	parent  *App         // 挂载到的父应用
+	mounts  []mountedApp // 已挂载的子应用
+}
+
+// Run 启动服务，监听地址无效或端口无法绑定时记录错误并 panic；需要处理错误时使用 Start
+func (app *App) Run(addr ...string) {
+	if err := app.Start(addr...); err != nil {
+		app.logger.Error(err.Error())
 		panic(err)
 	}
 }
@@ -1911,36 +3397,109 @@ func (app *App) GetModConfig() *ModConfig {
 	return app.cfg.ModConfig
 }
 
+// Register 注册服务，服务定义不完整、处理函数类型不正确或名称/路径重复时返回错误
 func (app *App) Register(svc Service) error {
-	if err := validate.Struct(&svc); err != nil {
+	if err := app.checkService(&svc); err != nil {
 		return err
 	}
+	if !svc.RawBody && !svc.Handler.ndjson {
+		for _, field := range sourceTypeWarnings(svc.Handler.InputType) {
+			app.logger.WithFields(logrus.Fields{"service": svc.Name, "field": field}).
+				Warn("Only string, number and bool fields can be read from query, header, form or path parameters, the field is bound from the request body only")
+		}
+	}
 
 	// 构建服务路径
-	servicePath := fmt.Sprintf("%s/%s", app.cfg.ModConfig.App.ServiceBase, svc.Name)
+	servicePath := app.serviceRoute(&svc)
+	svc.path = servicePath
+	svc.owner = app
+	svc.headerPolicy = app.headerPolicy(&svc)
+	svc.responseLimit = app.responseLimitPolicy(&svc)
+	svc.requestBody = app.requestBodyPolicy(&svc)
+	svc.responseCache = app.responseCachePolicy(&svc)
+	svc.tracePolicy = app.tracePolicy(&svc)
+	svc.negotiate = app.negotiatesResponse(&svc)
+	if svc.Async {
+		app.jobs.start()
+	}
+
+	handler := func(fc *fiber.Ctx) error {
+		ctx := &Context{Ctx: fc, logger: app.logger, app: app, service: &svc}
+		// 工作协程重放的异步任务请求，提交时已通过负载卸载、限流和风控检查
+		replay := ctx.JobID() != ""
+
+		// 客户端断开连接或超时后取消 ctx.UserContext()
+		release := app.bindRequestContext(fc, &svc)
+		defer release()
+
+		// 链路追踪的服务 span，响应完成后结束
+		defer app.startServiceSpan(ctx, &svc)()
+
+		// 请求失败时保存快照，用于重放排查
+		defer app.captureRequest(ctx, time.Now())
+
+		// 统计服务的SLO达成情况
+		defer app.recordSLO(ctx, time.Now())
+
+		// 记录请求数、耗时和请求/响应大小指标
+		defer app.recordMetrics(ctx, time.Now())
+
+		// 按 Accept 将JSON响应转换为 XML 或 MessagePack，在统计响应大小之前执行
+		defer app.encodeNegotiatedResponse(ctx, &svc)
+
+		// 固定响应头（错误响应同样生效）和必需请求头
+		if err := applyHeaderPolicy(fc, svc.headerPolicy); err != nil {
+			return replyError(ctx, err)
+		}
 
-	app.Add(fiber.MethodPost, servicePath, func(fc *fiber.Ctx) error {
-		ctx := &Context{Ctx: fc, logger: app.logger, app: app}
+		// 实例过载时拒绝低优先级的服务
+		if !replay {
+			if err := app.checkLoadShedding(ctx, &svc); err != nil {
+				return replyError(ctx, err)
+			}
+		}
 
-		var token string
+		// 批量导入以外的服务检查请求体大小（流式读取时按上限读取请求体）和格式
+		if !svc.Handler.ndjson {
+			if err := app.checkRequestBody(fc, &svc); err != nil {
+				return replyError(ctx, err)
+			}
+		}
 
-		// 身份验证检查
-		if !svc.SkipAuth {
-			token = parseToken(fc, app.tokenKeys)
-			if token == "" {
-				return fc.Status(401).JSON(NewErrorResponse(ctx, 401, "Unauthorized"))
+		// 敏感服务的防刷限流，在认证前执行以覆盖登录等无需认证的服务
+		if !replay {
+			if err := app.checkThrottle(ctx, &svc); err != nil {
+				return replyError(ctx, err)
 			}
+		}
 
-			// 验证 token 的有效性
-			if !app.validateToken(token) {
-				app.logger.WithFields(logrus.Fields{
-					"service": svc.Name,
-					"token":   token,
-					"rid":     ctx.GetRequestID(),
-				}).Warn("Token validation failed")
-				return fc.Status(401).JSON(NewErrorResponse(ctx, 401, "Invalid token"))
+		// Webhook 回调的签名校验和防重放
+		releaseWebhook, err := app.verifyWebhook(ctx, &svc)
+		if err != nil {
+			return replyError(ctx, err)
+		}
+		defer releaseWebhook()
+
+		// 身份验证检查
+		strategy := app.authStrategy(&svc)
+		token, err := app.authenticate(ctx, &svc, strategy)
+		if err != nil {
+			return replyError(ctx, err)
+		}
+		// 请求限流，在认证后执行以便按用户计数
+		if !replay {
+			if err := app.checkRateLimit(ctx, &svc); err != nil {
+				return replyError(ctx, err)
 			}
 		}
+		// 风控回调可对已认证的请求要求额外验证或拒绝
+		if token != "" && !replay {
+			if err := app.checkTokenRisk(ctx, token); err != nil {
+				return replyError(ctx, err)
+			}
+		}
+		// 未通过Token认证时，权限检查需要单独校验Token
+		tokenVerified := token != ""
 
 		// 权限检查
 		if svc.Permission != nil {
@@ -1953,36 +3512,67 @@ func (app *App) Register(svc Service) error {
 			}
 
 			// 验证token有效性（如果之前没有验证过）
-			if svc.SkipAuth && !app.validateToken(token) {
+			if !tokenVerified && !app.validateToken(fc, token) {
+				app.logger.WithFields(logrus.Fields{
+					"service": svc.Name,
+					"token":   token,
+					"rid":     ctx.GetRequestID(),
+				}).Warn("Token validation failed during permission check")
+				return replyError(ctx, app.invalidTokenReply(fc, token))
+			}
+
+			// 检查权限，规则中的 PathParam 引用替换为请求路径中的参数值
+			if !ctx.checkPermission(token, resolvePermission(fc, svc.Permission)) {
+				app.logger.WithFields(logrus.Fields{
+					"service":    svc.Name,
+					"permission": svc.Permission,
+					"rid":        ctx.GetRequestID(),
+				}).Warn("Permission check failed")
+				return fc.Status(403).JSON(NewErrorResponse(ctx, 403, "Insufficient permissions"))
+			}
+		}
+
+		// 权限范围检查
+		if len(svc.RequiredScopes) > 0 {
+			if token == "" {
+				token = parseToken(fc, app.tokenKeys)
+			}
+			if token == "" {
+				return fc.Status(401).JSON(NewErrorResponse(ctx, 401, "Authentication required for scope check"))
+			}
+			if !tokenVerified && svc.Permission == nil && !app.validateToken(fc, token) {
+				return replyError(ctx, app.invalidTokenReply(fc, token))
+			}
+			if missing := missingScopes(ctx.Scopes(), svc.RequiredScopes); len(missing) > 0 {
 				app.logger.WithFields(logrus.Fields{
 					"service": svc.Name,
-					"token":   token,
+					"missing": missing,
 					"rid":     ctx.GetRequestID(),
-				}).Warn("Token validation failed during permission check")
-				return fc.Status(401).JSON(NewErrorResponse(ctx, 401, "Invalid token"))
-			}
-
-			// 检查权限
-			if !app.CheckServicePermission(token, svc.Permission) {
-				app.logger.WithFields(logrus.Fields{
-					"service":    svc.Name,
-					"permission": svc.Permission,
-					"rid":        ctx.GetRequestID(),
-				}).Warn("Permission check failed")
-				return fc.Status(403).JSON(NewErrorResponse(ctx, 403, "Insufficient permissions"))
+				}).Warn("Scope check failed")
+				return fc.Status(403).JSON(NewErrorResponse(ctx, 403, "Insufficient scope", "missing scopes: "+strings.Join(missing, " ")))
 			}
 		}
 
 		// 创建输入参数实例
 		var in, out any
-		if svc.Handler.InputType != nil {
-			in = reflect.New(svc.Handler.InputType).Interface()
-			// 解析请求参数到结构体
-			if err := app.parseRequestParamsToStruct(fc, in); err != nil {
+		if svc.RawBody {
+			in = bindRawRequest(fc)
+		} else if svc.Handler.ndjson {
+			in = app.bindImportRequest(fc)
+		} else if svc.Handler.InputType != nil {
+			in = svc.Handler.input()
+			// 解析请求参数到结构体，请求类型有生成的绑定代码时跳过反射解析
+			var err error
+			if binder, ok := in.(ParamBinder); ok {
+				err = binder.BindParams(fc)
+			} else {
+				err = app.parseRequestParamsToStruct(fc, in)
+			}
+			if err != nil {
 				app.logger.WithFields(logrus.Fields{
 					"service": svc.Name,
 					"error":   err.Error(),
-					"body":    string(fc.Body()),
+					"body":    requestBodyForLog(fc),
 					"query":   fc.Context().QueryArgs().String(),
 					"rid":     ctx.GetRequestID(),
 				}).Error("Parameter parsing failed")
@@ -1990,7 +3580,12 @@ func (app *App) Register(svc Service) error {
 			}
 
 			// 参数验证
-			if err := validate.Struct(in); err != nil {
+			if rv, ok := in.(RequestValidator); ok {
+				err = rv.ValidateRequest()
+			} else {
+				err = validate.Struct(in)
+			}
+			if err != nil {
 				app.logger.WithFields(logrus.Fields{
 					"service": svc.Name,
 					"error":   err.Error(),
@@ -2001,13 +3596,37 @@ func (app *App) Register(svc Service) error {
 			}
 		}
 
+		// 请求中的业务字段写入 span 属性和 baggage
+		app.annotateSpan(ctx, &svc, in)
+
+		// 异步服务：通过校验的请求进入任务队列，由工作协程重放执行
+		if svc.Async && !replay {
+			accepted, err := app.enqueueJob(ctx, &svc)
+			if err != nil {
+				return replyError(ctx, err)
+			}
+			fc.Set(fiber.HeaderLocation, accepted.StatusURL)
+			return fc.Status(fiber.StatusAccepted).JSON(NewSuccessResponse(ctx, accepted))
+		}
+
 		// 创建输出参数实例
 		if svc.Handler.OutputType != nil {
-			out = reflect.New(svc.Handler.OutputType).Interface()
+			out = svc.Handler.output()
+		}
+
+		// 响应缓存命中时直接返回缓存的响应数据，不调用处理函数；Mock 模式下不读写缓存
+		mock := app.useMock(ctx, &svc)
+		var cacheKey string
+		var cached bool
+		if svc.responseCache != nil && !mock {
+			var data []byte
+			if cacheKey, data, cached = app.lookupResponseCache(ctx, &svc, in); cached {
+				out = json.RawMessage(data)
+			}
 		}
 
-		// 检查是否启用Mock模式
-		if app.isMockEnabled(&svc) {
+		// 检查是否启用Mock模式，受保护环境中记录审计日志
+		if mock {
 			app.logger.WithFields(logrus.Fields{
 				"service": svc.Name,
 				"group":   svc.Group,
@@ -2016,7 +3635,7 @@ func (app *App) Register(svc Service) error {
 
 			// 生成Mock数据
 			if svc.Handler.OutputType != nil {
-				mockData := app.generateMockResponse(&svc)
+				mockData := app.generateMockResponse(ctx, &svc)
 				if mockData != nil {
 					// 将Mock数据复制到输出参数
 					if reflect.TypeOf(mockData) == svc.Handler.OutputType {
@@ -2031,9 +3650,34 @@ func (app *App) Register(svc Service) error {
 					}
 				}
 			}
-		} else {
-			// 调用实际的服务处理函数
-			if err := svc.Handler.Func(ctx, in, out); err != nil {
+		} else if !cached {
+			// 执行槽位已满时按优先级排队
+			release, err := app.acquireExecution(ctx, &svc)
+			if err != nil {
+				if status, msg, ok := cancelStatus(fc.UserContext()); ok {
+					return fc.Status(status).JSON(NewErrorResponse(ctx, status, msg, err.Error()))
+				}
+				return replyError(ctx, err)
+			}
+
+			// 按配置将部分请求镜像给影子实现
+			mirror := app.startShadow(ctx, &svc)
+
+			// 依次执行服务中间件，再调用实际的服务处理函数，MakeHandler2 创建的处理函数自行返回响应
+			ctx.input, ctx.output = in, out
+			err = app.runServiceMiddlewares(ctx, &svc, func() error {
+				var err error
+				if svc.Handler.call != nil {
+					out, err = svc.Handler.call(ctx, in)
+				} else {
+					err = svc.Handler.Func(ctx, in, out)
+				}
+				ctx.output = out
+				return err
+			})
+			release()
+			mirror.finish(out, err)
+			if err != nil {
 				app.logger.WithFields(logrus.Fields{
 					"service": svc.Name,
 					"error":   err.Error(),
@@ -2041,37 +3685,126 @@ func (app *App) Register(svc Service) error {
 					"rid":     ctx.GetRequestID(),
 				}).Error("Service handler failed")
 
+				if status, msg, ok := cancelStatus(fc.UserContext()); ok {
+					return fc.Status(status).JSON(NewErrorResponse(ctx, status, msg, err.Error()))
+				}
 				if intlErr, ok := err.(*StdReply); ok {
-					resp := NewErrorResponse(ctx, intlErr.Code(), intlErr.Msg(), intlErr.Detail())
-					return fc.Status(intlErr.Code()).JSON(resp)
+					resp := NewErrorResponse(ctx, intlErr.Code(), ctx.replyMessage(intlErr), intlErr.Detail())
+					return fc.Status(intlErr.httpStatus()).JSON(resp)
 				}
 				return fc.Status(500).JSON(NewErrorResponse(ctx, 500, err.Error()))
 			}
 		}
 
-		// 返回结果
-		if svc.ReturnRaw {
-			return fc.JSON(out)
+		// 处理函数已通过 ctx.SendCSV 等方法发送文件
+		if responseSent(fc) {
+			return nil
 		}
-		return fc.JSON(NewSuccessResponse(ctx, out))
-	})
+
+		// 将响应中的时间转换为调用方时区，缓存的响应已按时区分别缓存
+		if app.cfg.ModConfig.Timezone.ConvertResponse && !cached {
+			out = ToLocation(out, ctx.Location())
+		}
+		if cacheKey != "" && !cached {
+			app.storeResponseCache(ctx, &svc, cacheKey, out)
+		}
+
+		// 条件请求：GET、HEAD 请求中处理函数设置的或自动计算的 ETag/Last-Modified 与客户端缓存一致时响应304；
+		// 其他方法不响应304，If-None-Match 与当前 ETag 一致时前置条件不成立，响应412
+		if svc.ETag {
+			if fc.GetRespHeader(fiber.HeaderETag) == "" {
+				if etag, err := responseETag(out); err == nil {
+					fc.Set(fiber.HeaderETag, etag)
+				}
+			}
+			if safeMethod(fc) {
+				if requestFresh(fc) {
+					fc.Status(fiber.StatusNotModified)
+					return nil
+				}
+			} else if etagMatches(fc) {
+				return fc.Status(fiber.StatusPreconditionFailed).JSON(NewErrorResponse(ctx, fiber.StatusPreconditionFailed, "Precondition Failed"))
+			}
+		}
+
+		// 返回结果，超过响应大小上限时截断或返回错误
+		return app.sendServiceResponse(ctx, &svc, out)
+	}
+
+	// 同名服务的多个版本共用一个路由，按灰度规则分发
+	if svc.Version != "" {
+		if route := app.canaryRoute(svc.Name); route != nil {
+			route.add(&svc, handler)
+			handler = nil
+		} else {
+			handler = app.newCanaryRoute(&svc, handler).serve
+		}
+	}
+	if handler != nil {
+		if len(svc.methods) == 0 {
+			app.Add(fiber.MethodPost, servicePath, handler)
+			// 启用 ETag 的服务同时接受 GET、HEAD 请求（参数取自查询字符串），以便客户端发起条件请求
+			if svc.ETag {
+				app.Add(fiber.MethodGet, servicePath, handler)
+				app.Add(fiber.MethodHead, servicePath, handler)
+			}
+		}
+		for _, method := range svc.methods {
+			app.Add(method, servicePath, handler)
+		}
+	}
 
 	// 打印服务注册日志
 	app.logger.WithFields(logrus.Fields{
 		"service":     svc.Name,
+		"version":     svc.Version,
 		"displayName": svc.DisplayName,
-		"method":      "POST",
+		"method":      svc.httpMethod(),
 		"path":        servicePath,
 		"skipAuth":    svc.SkipAuth,
+		"auth":        app.authStrategy(&svc),
 		"returnRaw":   svc.ReturnRaw,
 	}).Info("Service registered")
 
 	// 保存服务信息用于生成文档
 	app.services = append(app.services, svc)
+	app.invalidateDocs()
 
 	return nil
 }
 
+// replyStatus 返回业务错误码对应的HTTP状态码，超出HTTP状态码范围的业务码统一使用400
+func replyStatus(code int) int {
+	if code < 100 || code > 599 {
+		return fiber.StatusBadRequest
+	}
+	return code
+}
+
+// replyError 输出错误响应：StdReply 使用其错误码、消息和HTTP状态码，其他错误响应500
+func replyError(ctx *Context, err error) error {
+	var reply *StdReply
+	if !errors.As(err, &reply) {
+		return ctx.Ctx.Status(fiber.StatusInternalServerError).JSON(NewErrorResponse(ctx, 500, err.Error()))
+	}
+	return ctx.Ctx.Status(reply.httpStatus()).JSON(NewErrorResponse(ctx, reply.code, ctx.replyMessage(reply), reply.detail))
+}
+
+// GetService 按名称查找已注册的服务，包括挂载的子应用中的服务
+func (app *App) GetService(name string) (Service, bool) {
+	for _, svc := range app.allServices() {
+		if svc.Name == name {
+			return svc, true
+		}
+	}
+	return Service{}, false
+}
+
+// ServicePath 返回服务的访问路径
+func (app *App) ServicePath(name string) string {
+	return fmt.Sprintf("%s/%s", app.cfg.ModConfig.App.ServiceBase, name)
+}
+
 func parseToken(kc *fiber.Ctx, keys []string) string {
 	cacheKey := "MOD_TOKEN"
 	if v := kc.Context().UserValue(cacheKey); v != nil {
@@ -2119,127 +3852,6 @@ func parseToken(kc *fiber.Ctx, keys []string) string {
 	return value
 }
 
-// validateToken 验证 token 的有效性
-// 当 SkipAuth 为 false 时，需要验证 token 是否在缓存中存在
-func (app *App) validateToken(token string) bool {
-	// 如果没有配置 token 验证，或者验证被禁用，则跳过验证
-	if app.cfg.ModConfig == nil || !app.cfg.ModConfig.Token.Validation.Enabled {
-		return true
-	}
-
-	if token == "" {
-		return false
-	}
-
-	config := app.cfg.ModConfig.Token.Validation
-	cacheKey := config.CacheKeyPrefix + token
-
-	// 根据配置的缓存策略进行验证
-	switch config.CacheStrategy {
-	case "bigcache":
-		if app.tokenCache != nil {
-			// 查询 BigCache 中是否存在该 token
-			_, err := app.tokenCache.Get(cacheKey)
-			if err != nil {
-				// 如果是 bigcache.ErrEntryNotFound，说明 token 不存在或已过期
-				if err == bigcache.ErrEntryNotFound {
-					app.logger.WithFields(logrus.Fields{
-						"token":     token,
-						"cache_key": cacheKey,
-					}).Debug("Token not found in BigCache")
-					return false
-				}
-				// 其他错误，记录日志但允许通过（避免缓存问题影响正常业务）
-				app.logger.WithFields(logrus.Fields{
-					"token":     token,
-					"cache_key": cacheKey,
-					"error":     err.Error(),
-				}).Warn("BigCache query error, allowing token validation to pass")
-				return true
-			}
-			// Token 存在，验证通过
-			app.logger.WithFields(logrus.Fields{
-				"token":     token,
-				"cache_key": cacheKey,
-			}).Debug("Token validated successfully in BigCache")
-			return true
-		}
-	case "badger":
-		if app.badgerDB != nil {
-			// 查询 BadgerDB 中是否存在该 token
-			err := app.badgerDB.View(func(txn *badger.Txn) error {
-				_, err := txn.Get([]byte(cacheKey))
-				return err
-			})
-
-			if err != nil {
-				if err == badger.ErrKeyNotFound {
-					app.logger.WithFields(logrus.Fields{
-						"token":     token,
-						"cache_key": cacheKey,
-					}).Debug("Token not found in BadgerDB")
-					return false
-				}
-				// 其他错误，记录日志但允许通过
-				app.logger.WithFields(logrus.Fields{
-					"token":     token,
-					"cache_key": cacheKey,
-					"error":     err.Error(),
-				}).Warn("BadgerDB query error, allowing token validation to pass")
-				return true
-			}
-
-			// Token 存在，验证通过
-			app.logger.WithFields(logrus.Fields{
-				"token":     token,
-				"cache_key": cacheKey,
-			}).Debug("Token validated successfully in BadgerDB")
-			return true
-		}
-	case "redis":
-		if app.redisClient != nil {
-			// 查询 Redis 中是否存在该 token
-			ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-			defer cancel()
-
-			exists, err := app.redisClient.Exists(ctx, cacheKey).Result()
-			if err != nil {
-				// Redis 查询错误，记录日志但允许通过
-				app.logger.WithFields(logrus.Fields{
-					"token":     token,
-					"cache_key": cacheKey,
-					"error":     err.Error(),
-				}).Warn("Redis query error, allowing token validation to pass")
-				return true
-			}
-
-			if exists == 0 {
-				app.logger.WithFields(logrus.Fields{
-					"token":     token,
-					"cache_key": cacheKey,
-				}).Debug("Token not found in Redis")
-				return false
-			}
-
-			// Token 存在，验证通过
-			app.logger.WithFields(logrus.Fields{
-				"token":     token,
-				"cache_key": cacheKey,
-			}).Debug("Token validated successfully in Redis")
-			return true
-		}
-	}
-
-	// 如果没有匹配的缓存策略，默认返回 false
-	app.logger.WithFields(logrus.Fields{
-		"token":          token,
-		"cache_strategy": config.CacheStrategy,
-		"cache_key":      cacheKey,
-	}).Warn("Token validation failed: no valid cache strategy configured")
-
-	return false
-}
-
 // JWT Token管理方法
 
 // GenerateJWT generates JWT tokens for a user
@@ -2285,20 +3897,15 @@ func (app *App) UseEncryption() {
 
 // EncryptData encrypts data using the configured symmetric or asymmetric algorithm
 func (app *App) EncryptData(data []byte, mode string) ([]byte, error) {
-	config := app.GetModConfig()
-	if config == nil {
-		return nil, fmt.Errorf("no configuration available")
-	}
-
 	switch mode {
 	case "symmetric":
-		symEncryption, err := NewSymmetricEncryption(config)
+		symEncryption, err := app.symmetricEncryption()
 		if err != nil {
 			return nil, err
 		}
 		return symEncryption.Encrypt(data)
 	case "asymmetric":
-		asymEncryption, err := NewAsymmetricEncryption(config)
+		asymEncryption, err := app.asymmetricEncryption()
 		if err != nil {
 			return nil, err
 		}
@@ -2308,22 +3915,18 @@ func (app *App) EncryptData(data []byte, mode string) ([]byte, error) {
 	}
 }
 
-// DecryptData decrypts data using the configured symmetric or asymmetric algorithm
+// DecryptData decrypts data using the configured symmetric or asymmetric algorithm,
+// falling back to key versions retained after rotation
 func (app *App) DecryptData(data []byte, mode string) ([]byte, error) {
-	config := app.GetModConfig()
-	if config == nil {
-		return nil, fmt.Errorf("no configuration available")
-	}
-
 	switch mode {
 	case "symmetric":
-		symEncryption, err := NewSymmetricEncryption(config)
+		symEncryption, err := app.symmetricEncryption()
 		if err != nil {
 			return nil, err
 		}
 		return symEncryption.Decrypt(data)
 	case "asymmetric":
-		asymEncryption, err := NewAsymmetricEncryption(config)
+		asymEncryption, err := app.asymmetricEncryption()
 		if err != nil {
 			return nil, err
 		}
@@ -2335,23 +3938,19 @@ func (app *App) DecryptData(data []byte, mode string) ([]byte, error) {
 
 // SignData creates a digital signature for the given data
 func (app *App) SignData(data []byte) ([]byte, error) {
-	config := app.GetModConfig()
-	if config == nil {
-		return nil, fmt.Errorf("no configuration available")
+	sigVerification, err := app.signatureVerification()
+	if err != nil {
+		return nil, err
 	}
-
-	sigVerification := NewSignatureVerification(config)
 	return sigVerification.Sign(data)
 }
 
 // VerifySignature verifies a digital signature for the given data
 func (app *App) VerifySignature(data, signature []byte) error {
-	config := app.GetModConfig()
-	if config == nil {
-		return fmt.Errorf("no configuration available")
+	sigVerification, err := app.signatureVerification()
+	if err != nil {
+		return err
 	}
-
-	sigVerification := NewSignatureVerification(config)
 	return sigVerification.Verify(data, signature)
 }
 
@@ -2361,6 +3960,7 @@ func (app *App) SetToken(token string, data any) error {
 	if app.cfg.ModConfig == nil || !app.cfg.ModConfig.Token.Validation.Enabled {
 		return nil
 	}
+	app.forgetTokens(token)
 
 	config := app.cfg.ModConfig.Token.Validation
 	cacheKey := config.CacheKeyPrefix + token
@@ -2503,6 +4103,7 @@ func (app *App) RemoveToken(token string) error {
 	if app.cfg.ModConfig == nil || !app.cfg.ModConfig.Token.Validation.Enabled {
 		return nil
 	}
+	app.forgetTokens(token)
 
 	config := app.cfg.ModConfig.Token.Validation
 	cacheKey := config.CacheKeyPrefix + token
@@ -2575,7 +4176,8 @@ func (app *App) RemoveToken(token string) error {
 }
 
 // GetTokenData 从缓存中获取 token 相关的数据
-// 这个方法可以用来获取存储在 token 中的用户信息等数据
+// 这个方法可以用来获取存储在 token 中的用户信息等数据，token 不存在或已过期时返回 ErrTokenNotFound；
+// 处理请求时使用 ctx.TokenData()，与认证、权限检查共用本次请求的查询结果
 func (app *App) GetTokenData(token string) ([]byte, error) {
 	if app.cfg.ModConfig == nil || !app.cfg.ModConfig.Token.Validation.Enabled {
 		return nil, fmt.Errorf("token validation not enabled")
@@ -2590,7 +4192,7 @@ func (app *App) GetTokenData(token string) ([]byte, error) {
 			data, err := app.tokenCache.Get(cacheKey)
 			if err != nil {
 				if err == bigcache.ErrEntryNotFound {
-					return nil, fmt.Errorf("token not found")
+					return nil, ErrTokenNotFound
 				}
 				return nil, fmt.Errorf("failed to get token data from BigCache: %w", err)
 			}
@@ -2612,7 +4214,7 @@ func (app *App) GetTokenData(token string) ([]byte, error) {
 
 			if err != nil {
 				if err == badger.ErrKeyNotFound {
-					return nil, fmt.Errorf("token not found")
+					return nil, ErrTokenNotFound
 				}
 				return nil, fmt.Errorf("failed to get token data from BadgerDB: %w", err)
 			}
@@ -2626,7 +4228,7 @@ func (app *App) GetTokenData(token string) ([]byte, error) {
 			val, err := app.redisClient.Get(ctx, cacheKey).Result()
 			if err != nil {
 				if err == redis.Nil {
-					return nil, fmt.Errorf("token not found")
+					return nil, ErrTokenNotFound
 				}
 				return nil, fmt.Errorf("failed to get token data from Redis: %w", err)
 			}
@@ -2634,11 +4236,16 @@ func (app *App) GetTokenData(token string) ([]byte, error) {
 		}
 	}
 
-	return nil, fmt.Errorf("no valid cache strategy configured for token data retrieval")
+	return nil, errNoTokenStore
 }
 
 // Close 关闭应用时释放资源
 func (app *App) Close() error {
+	// 子应用与父应用共享缓存、存储等资源，由父应用负责关闭
+	if app.subName != "" {
+		return nil
+	}
+
 	var errors []error
 
 	// 关闭 BadgerDB
@@ -2661,6 +4268,52 @@ func (app *App) Close() error {
 		}
 	}
 
+	// 停止密钥刷新
+	if app.keys != nil && app.keys.stop != nil {
+		close(app.keys.stop)
+		app.keys.stop = nil
+	}
+
+	// 停止负载采样
+	if app.shedder != nil {
+		close(app.shedder.stop)
+		app.shedder = nil
+	}
+
+	// 停止文件生命周期清理任务
+	if app.fileLifecycleStop != nil {
+		close(app.fileLifecycleStop)
+		app.fileLifecycleStop = nil
+	}
+
+	// 关闭请求捕获存储
+	if app.captureDB != nil {
+		if err := app.captureDB.Close(); err != nil {
+			app.logger.WithError(err).Error("Failed to close capture store")
+			errors = append(errors, fmt.Errorf("failed to close capture store: %w", err))
+		}
+	}
+
+	// 关闭文件元数据存储
+	if app.fileStore != nil {
+		if err := app.fileStore.Close(); err != nil {
+			app.logger.WithError(err).Error("Failed to close file metadata store")
+			errors = append(errors, fmt.Errorf("failed to close file metadata store: %w", err))
+		}
+	}
+
+	// 关闭 GCS 客户端
+	if app.gcsClient != nil {
+		if err := app.gcsClient.Close(); err != nil {
+			app.logger.WithError(err).Error("Failed to close GCS client")
+			errors = append(errors, fmt.Errorf("failed to close GCS client: %w", err))
+		}
+	}
+
+	for _, client := range app.reload.retiredGCS {
+		client.Close()
+	}
+
 	// 关闭 BigCache（BigCache v3 会自动清理，无需手动关闭）
 
 	if len(errors) > 0 {
@@ -2687,12 +4340,9 @@ func (app *App) parseRequestParamsToStruct(fc *fiber.Ctx, in any) error {
 
 	rt := rv.Type()
 
-	// 首先解析 JSON body（如果存在）
-	body := fc.Body()
-	if len(body) > 0 {
-		if err := json.Unmarshal(body, in); err != nil {
-			return fmt.Errorf("failed to parse JSON body: %w", err)
-		}
+	// 首先按 Content-Type 解析请求体（JSON、XML、MessagePack、表单），multipart/form-data 请求读取上传的文件和表单值
+	if err := BindBody(fc, in); err != nil {
+		return err
 	}
 
 	// 然后根据 mod 标签或默认规则解析其他来源的参数
@@ -2709,6 +4359,10 @@ func (app *App) parseRequestParamsToStruct(fc *fiber.Ctx, in any) error {
 
 		// 检查 mod 标签
 		modTag := fieldType.Tag.Get("mod")
+		if from := modTagValue(modTag, "from", ""); from == "file" || from == "body" {
+			// 文件字段已由 BindMultipart 绑定，请求体字段已由 BindBody 绑定
+			continue
+		}
 		if modTag != "" {
 			value = app.parseFieldValue(fc, modTag, fieldName)
 		} else {
@@ -2770,7 +4424,7 @@ func (app *App) parseFieldValue(fc *fiber.Ctx, modTag, fieldName string) string
 	case "form":
 		return fc.FormValue(name)
 	case "param":
-		return fc.Params(name)
+		return pathParamValue(fc, name)
 	default:
 		// 默认尝试从 query 获取
 		return fc.Query(name)
@@ -2806,7 +4460,8 @@ type DocField struct {
 	Type          string
 	Description   string
 	Required      bool
-	From          string // query, header, form, param
+	From          string // body, query, header, form, param, file
+	FromDesc      string // 参数来源的说明
 	Tag           string
 	Level         int        // 嵌套层级，0为顶层
 	Parent        string     // 父字段名
@@ -2819,8 +4474,21 @@ type DocField struct {
 type DocService struct {
 	Service
 	ServicePath  string
+	Method       string // 请求方法，RegisterDownload 注册的下载服务为 GET，RegisterWS 注册的服务为 WS
 	InputFields  []DocField
 	OutputFields []DocField
+	WebSocket    bool         // 是否为 WebSocket 服务
+	ClientFields []DocField   // WebSocket 客户端发送的消息字段
+	ServerFields []DocField   // WebSocket 服务端推送的消息字段
+	MockEnabled  bool // 当前是否启用Mock
+	MockToggle   bool // 是否可在文档页面切换Mock
+	AuthStrategy AuthStrategy // 生效的认证方式
+	AuthLabel    string       // 认证方式名称
+	ErrorCodes   []ErrorCode  // 服务声明的错误码
+
+	ExampleRequest  string // 请求体示例（JSON）
+	ExampleResponse string // 响应示例（JSON）
+	SearchText      string // 文档页面搜索使用的文本（名称、路径、描述及字段名，小写）
 }
 
 type DocGroup struct {
@@ -2836,29 +4504,20 @@ type DocData struct {
 		Description string
 		Version     string
 	}
-	Groups []DocGroup
+	Groups         []DocGroup
+	MockTogglePath string // mock_toggle 服务地址，为空时不显示切换按钮
 }
 
 // 处理文档请求
 func (app *App) handleDocs(c *fiber.Ctx) error {
-	// 按组分类并排序服务
-	groups := app.groupAndSortServices()
-
-	// 准备文档数据
-	docData := DocData{
-		Groups: groups,
+	// 检查是否请求 OpenAPI 文档
+	if c.Query("o") == "openapi" {
+		return c.JSON(app.OpenAPI())
 	}
 
-	// 设置应用信息
-	docData.AppInfo.Name = app.cfg.ModConfig.App.Name
-	docData.AppInfo.DisplayName = app.cfg.ModConfig.App.DisplayName
-	docData.AppInfo.Description = app.cfg.ModConfig.App.Description
-	docData.AppInfo.Version = app.cfg.ModConfig.App.Version
-
-	// 设置默认值
-	if docData.AppInfo.DisplayName == "" {
-		docData.AppInfo.DisplayName = "API 文档"
-	}
+	// 文档数据和HTML在服务注册或Mock开关变化后重新生成
+	docs := app.renderedDocs()
+	docData := docs.data
 
 	// 检查是否请求 Markdown 格式
 	if c.Query("o") == "md" {
@@ -2873,26 +4532,69 @@ func (app *App) handleDocs(c *fiber.Ctx) error {
 		return c.SendString(md)
 	}
 
-	// 生成HTML
-	html := app.generateDocsHTML(docData)
+	// 内容未变化时返回304
+	c.Set(fiber.HeaderETag, docs.etag)
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	if c.Fresh() {
+		return c.SendStatus(fiber.StatusNotModified)
+	}
 
 	c.Set("Content-Type", "text/html; charset=utf-8")
-	return c.SendString(html)
+	return c.SendString(docs.html)
+}
+
+// docData 返回文档数据：应用信息及按组排序的服务
+func (app *App) docData() DocData {
+	// 按组分类并排序服务
+	docData := DocData{
+		Groups: app.groupAndSortServices(),
+	}
+
+	// 设置应用信息
+	docData.AppInfo.Name = app.cfg.ModConfig.App.Name
+	docData.AppInfo.DisplayName = app.cfg.ModConfig.App.DisplayName
+	docData.AppInfo.Description = app.cfg.ModConfig.App.Description
+	docData.AppInfo.Version = app.cfg.ModConfig.App.Version
+
+	// 设置默认值
+	if docData.AppInfo.DisplayName == "" {
+		docData.AppInfo.DisplayName = "API 文档"
+	}
+	return docData
 }
 
 // 按组分类并排序服务
 func (app *App) groupAndSortServices() []DocGroup {
 	groupMap := make(map[string][]DocService)
 
-	// 处理每个服务
-	for _, svc := range app.services {
+	// 处理每个服务（包括挂载的子应用中的服务）
+	for _, svc := range app.allServices() {
+		// 隐藏的服务仅在 docs.show_hidden_in 的环境中展示
+		if !svc.owner.docVisible(&svc) {
+			continue
+		}
+		svc.Hidden = svc.owner.serviceHidden(&svc)
 		docSvc := DocService{
 			Service:     svc,
-			ServicePath: fmt.Sprintf("%s/%s", app.cfg.ModConfig.App.ServiceBase, svc.Name),
+			ServicePath: svc.path,
+			Method:      svc.httpMethod(),
+			MockEnabled: svc.owner.isMockEnabled(&svc),
+			MockToggle:  app.mockOverrides != nil && !svc.builtin,
+			ErrorCodes:  serviceErrorCodes(&svc),
+		}
+		docSvc.AuthStrategy = svc.owner.authStrategy(&svc)
+		docSvc.AuthLabel = authStrategyLabels[docSvc.AuthStrategy]
+		if docSvc.AuthLabel == "" {
+			docSvc.AuthLabel = string(docSvc.AuthStrategy)
+		}
+
+		// 生成请求及响应示例，下载服务的响应为文件内容
+		if len(svc.methods) == 0 {
+			docSvc.ExampleRequest, docSvc.ExampleResponse = app.generateDocExamples(&svc)
 		}
 
-		// 解析输入参数
-		if svc.Handler.InputType != nil {
+		// 解析输入参数，原始请求体服务没有参数
+		if svc.Handler.InputType != nil && !svc.RawBody {
 			docSvc.InputFields = app.parseStructFields(svc.Handler.InputType)
 		}
 
@@ -2901,6 +4603,20 @@ func (app *App) groupAndSortServices() []DocGroup {
 			docSvc.OutputFields = app.parseStructFields(svc.Handler.OutputType)
 		}
 
+		// WebSocket 服务展示双向的消息结构
+		if svc.ws != nil {
+			docSvc.WebSocket = true
+			docSvc.Method = "WS"
+			docSvc.MockToggle = false
+			if svc.ws.clientType != nil {
+				docSvc.ClientFields = app.parseStructFields(svc.ws.clientType)
+			}
+			if svc.ws.serverType != nil {
+				docSvc.ServerFields = app.parseStructFields(svc.ws.serverType)
+			}
+		}
+		docSvc.SearchText = docSearchText(&docSvc)
+
 		// 按组分类
 		groupName := svc.Group
 		if groupName == "" {
@@ -2936,6 +4652,24 @@ func (app *App) groupAndSortServices() []DocGroup {
 	return groups
 }
 
+// docSearchText 汇总服务名称、路径、描述及全部字段名，供文档页面搜索
+func docSearchText(svc *DocService) string {
+	parts := []string{svc.Name, svc.DisplayName, svc.ServicePath, svc.Description, svc.Group}
+	parts = append(parts, svc.RequiredScopes...)
+	var collect func(fields []DocField)
+	collect = func(fields []DocField) {
+		for _, field := range fields {
+			parts = append(parts, field.Name, field.Description)
+			collect(field.Children)
+		}
+	}
+	collect(svc.InputFields)
+	collect(svc.OutputFields)
+	collect(svc.ClientFields)
+	collect(svc.ServerFields)
+	return strings.ToLower(strings.Join(parts, " "))
+}
+
 // 解析结构体字段
 func (app *App) parseStructFields(t reflect.Type) []DocField {
 	return app.parseStructFieldsRecursive(t, 0, "")
@@ -2978,8 +4712,8 @@ func (app *App) parseStructFieldsRecursive(t reflect.Type, level int, parentPath
 		// 解析JSON标签，跳过忽略的字段
 		if jsonTag := field.Tag.Get("json"); jsonTag != "" {
 			parts := strings.Split(jsonTag, ",")
-			if parts[0] == "-" {
-				// 字段被JSON tag标记为忽略，跳过
+			if parts[0] == "-" && modTagValue(field.Tag.Get("mod"), "from", "") != "file" {
+				// 字段被JSON tag标记为忽略，跳过（上传的文件字段除外）
 				continue
 			}
 			if parts[0] != "" {
@@ -2993,11 +4727,25 @@ func (app *App) parseStructFieldsRecursive(t reflect.Type, level int, parentPath
 		} else {
 			docField.From = "body"
 		}
+		docField.FromDesc = paramSourceDescription(docField.From)
 
 		if descTag := field.Tag.Get("desc"); descTag != "" {
 			docField.Description = descTag
 		}
 
+		// 上传的文件字段使用表单中的名称，不展开 multipart.FileHeader 的字段
+		if docField.From == "file" {
+			docField.Name = modTagValue(docField.Tag, "name", strings.ToLower(field.Name))
+			docField.Type = "file"
+			if field.Type == fileHeadersType {
+				docField.IsArray = true
+				docField.Type = "array<file>"
+				docField.ArrayItemType = "file"
+			}
+			fields = append(fields, docField)
+			continue
+		}
+
 		// 分析字段类型，处理嵌套结构
 		fieldType := field.Type
 		if fieldType.Kind() == reflect.Ptr {
@@ -3095,14 +4843,7 @@ func (app *App) getFieldTypeString(t reflect.Type) string {
 
 // 解析mod标签的from参数
 func (app *App) parseModTagFrom(modTag string) string {
-	parts := strings.Split(modTag, ",")
-	for _, part := range parts {
-		kv := strings.SplitN(strings.TrimSpace(part), "=", 2)
-		if len(kv) == 2 && strings.TrimSpace(kv[0]) == "from" {
-			return strings.TrimSpace(kv[1])
-		}
-	}
-	return "query"
+	return modTagValue(modTag, "from", "query")
 }
 
 // 生成Markdown文档
@@ -3130,13 +4871,44 @@ func (app *App) generateDocsMarkdown(docData DocData) string {
 		for _, svc := range group.Services {
 			sb.WriteString("#### " + svc.DisplayName + "\n\n")
 			sb.WriteString("- **接口名称**: `" + svc.Name + "`\n")
-			sb.WriteString("- **请求方式**: POST\n")
+			if svc.Version != "" {
+				sb.WriteString("- **版本**: `" + svc.Version + "`\n")
+			}
+			sb.WriteString("- **请求方式**: " + svc.Method + "\n")
 			sb.WriteString("- **路径**: `" + svc.ServicePath + "`\n")
+			sb.WriteString("- **认证**: " + svc.AuthLabel + "\n")
+			if svc.Hidden {
+				sb.WriteString("- **可见性**: 内部（不对外公开）\n")
+			}
 			if svc.Description != "" {
 				sb.WriteString("- **描述**: " + svc.Description + "\n")
 			}
+			if len(svc.RequiredScopes) > 0 {
+				sb.WriteString("- **权限范围**: `" + strings.Join(svc.RequiredScopes, "`, `") + "`\n")
+			}
 			sb.WriteString("\n")
 
+			// WebSocket 服务展示双向的消息结构
+			if svc.WebSocket {
+				for _, messages := range []struct {
+					title  string
+					fields []DocField
+				}{{"客户端消息", svc.ClientFields}, {"服务端消息", svc.ServerFields}} {
+					if len(messages.fields) == 0 {
+						continue
+					}
+					sb.WriteString("**" + messages.title + "**（JSON文本消息）\n\n")
+					sb.WriteString("| 参数名 | 类型 | 是否必须 | 描述 |\n")
+					sb.WriteString("|--------|------|----------|------|\n")
+					for _, field := range messages.fields {
+						sb.WriteString(app.formatMarkdownField(field, 0))
+					}
+					sb.WriteString("\n")
+				}
+				sb.WriteString("---\n\n")
+				continue
+			}
+
 			// 请求参数
 			if len(svc.InputFields) > 0 {
 				sb.WriteString("**请求参数**\n\n")
@@ -3177,10 +4949,39 @@ func (app *App) generateDocsMarkdown(docData DocData) string {
 				}
 			}
 
+			// 错误码
+			if len(svc.ErrorCodes) > 0 {
+				sb.WriteString("**错误码**\n\n")
+				sb.WriteString("| 错误码 | 名称 | HTTP状态码 | 消息 | 说明 |\n")
+				sb.WriteString("|--------|------|------------|------|------|\n")
+				for _, code := range svc.ErrorCodes {
+					sb.WriteString(fmt.Sprintf("| %d | `%s` | %d | %s | %s |\n", code.Code, code.Name, code.HTTPStatus(), code.Message, code.Description))
+				}
+				sb.WriteString("\n")
+			}
+
+			// 示例
+			if svc.ExampleRequest != "" {
+				sb.WriteString("**请求示例**\n\n```json\n" + svc.ExampleRequest + "\n```\n\n")
+			}
+			if svc.ExampleResponse != "" {
+				sb.WriteString("**响应示例**\n\n```json\n" + svc.ExampleResponse + "\n```\n\n")
+			}
+
 			sb.WriteString("---\n\n")
 		}
 	}
 
+	// 请求参数的来源由字段的 mod 标签指定
+	sb.WriteString("## 参数来源\n\n")
+	sb.WriteString("请求参数表格中的来源由字段的 `" + modTagGrammar + "` 标签指定，name 默认为小写字段名：\n\n")
+	sb.WriteString("| 来源 | 说明 |\n")
+	sb.WriteString("|------|------|\n")
+	for _, source := range paramSources {
+		sb.WriteString("| " + source.From + " | " + source.Description + " |\n")
+	}
+	sb.WriteString("\n")
+
 	return sb.String()
 }
 
@@ -3234,1341 +5035,11 @@ func (app *App) formatMarkdownField(field DocField, level int) string {
 	return sb.String()
 }
 
-// 生成HTML文档
+// 生成HTML文档，使用模板注册表中的 docs.html
 func (app *App) generateDocsHTML(docData DocData) string {
-	tmpl := `<!DOCTYPE html>
-<html lang="zh-CN">
-<head>
-    <meta charset="UTF-8">
-    <meta name="viewport" content="width=device-width, initial-scale=1.0">
-    <title>{{.AppInfo.DisplayName}}{{if .AppInfo.Version}} v{{.AppInfo.Version}}{{end}}</title>
-    <style>
-        * {
-            margin: 0;
-            padding: 0;
-            box-sizing: border-box;
-        }
-
-        body {
-            font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, 'Helvetica Neue', Arial, 'Noto Sans', sans-serif, 'Apple Color Emoji', 'Segoe UI Emoji', 'Segoe UI Symbol', 'Noto Color Emoji';
-            line-height: 1.5715;
-            color: rgba(0, 0, 0, 0.85);
-            background-color: #f0f2f5;
-        }
-
-        .container {
-            display: flex;
-            height: 100vh;
-            flex-direction: column;
-        }
-
-        .top-header {
-            position: fixed;
-            top: 0;
-            left: 299px;
-            right: 0;
-            height: 75px;
-            background: #001529;
-            border-bottom: none;
-            z-index: 1001;
-            display: flex;
-            align-items: center;
-            padding: 0 24px;
-            transition: left 0.3s ease;
-			height: 66px;
-    		box-sizing: border-box;
-        }
-
-        .top-header.sidebar-collapsed {
-            left: 0;
-        }
-
-        .menu-toggle {
-            background: #001529;
-            border: none;
-            border-radius: 4px;
-            padding: 8px;
-            cursor: pointer;
-            transition: all 0.3s;
-            color: #fff;
-            display: flex;
-            align-items: center;
-            justify-content: center;
-            width: 40px;
-            height: 40px;
-        }
-
-        .menu-toggle:hover {
-            background: #1890ff;
-        }
-
-        .menu-toggle-icon {
-            width: 20px;
-            height: 14px;
-            position: relative;
-            transform: rotate(0deg);
-            transition: .3s ease-in-out;
-        }
-
-        .menu-toggle-icon span {
-            display: block;
-            position: absolute;
-            height: 2px;
-            width: 100%;
-            background: #fff;
-            border-radius: 1px;
-            opacity: 1;
-            left: 0;
-            transform: rotate(0deg);
-            transition: .25s ease-in-out;
-        }
-
-        .menu-toggle-icon span:nth-child(1) {
-            top: 0px;
-        }
-
-        .menu-toggle-icon span:nth-child(2) {
-            top: 6px;
-        }
-
-        .menu-toggle-icon span:nth-child(3) {
-            top: 12px;
-        }
-
-        .menu-toggle.open .menu-toggle-icon span:nth-child(1) {
-            top: 6px;
-            transform: rotate(135deg);
-        }
-
-        .menu-toggle.open .menu-toggle-icon span:nth-child(2) {
-            opacity: 0;
-            left: -20px;
-        }
-
-        .menu-toggle.open .menu-toggle-icon span:nth-child(3) {
-            top: 6px;
-            transform: rotate(-135deg);
-        }
-
-        .sidebar {
-            width: 300px;
-            background: #fff;
-            border-right: 1px solid #f0f0f0;
-            position: fixed;
-            height: 100vh;
-            box-shadow: 0 2px 8px rgba(0, 0, 0, 0.06);
-            display: flex;
-            flex-direction: column;
-            overflow: hidden;
-            z-index: 1000;
-            transition: transform 0.3s ease;
-        }
-
-        .sidebar.collapsed {
-            transform: translateX(-100%);
-        }
-
-        .sidebar-overlay {
-            position: fixed;
-            top: 0;
-            left: 0;
-            width: 100%;
-            height: 100%;
-            background: rgba(0, 0, 0, 0.5);
-            z-index: 999;
-            opacity: 0;
-            visibility: hidden;
-            transition: all 0.3s ease;
-        }
-
-        .sidebar-overlay.show {
-            opacity: 1;
-            visibility: visible;
-        }
-
-        .sidebar-header {
-            padding: 16px 24px;
-            background: #001529;
-            color: #fff;
-            flex-shrink: 0;
-    		height: 66px;
-    		box-sizing: border-box;
-    		display: flex;
-    		align-items: center;
-        }
-
-        .sidebar-content {
-            flex: 1;
-            overflow-y: auto;
-            background: white;
-        }
-
-        .sidebar-header h1 {
-            font-size: 16px;
-            font-weight: 600;
-            margin: 0;
-        }
-
-        .version {
-            font-size: 12px;
-            font-weight: 400;
-            color: rgba(255, 255, 255, 0.8);
-            margin: 0;
-        }
-
-        .group {
-            margin: 0;
-        }
-
-        .group-title {
-            padding: 8px 24px;
-            background: #fafafa;
-            font-weight: 500;
-            font-size: 12px;
-            color: rgba(0, 0, 0, 0.45);
-            border-bottom: 1px solid #f0f0f0;
-            cursor: pointer;
-            transition: background-color 0.3s;
-            text-transform: uppercase;
-            letter-spacing: 0.5px;
-        }
-
-        .group-title:hover {
-            background: #f5f5f5;
-        }
-
-        .service-list {
-            background: white;
-        }
-
-        .service-item {
-            padding: 12px 24px 12px 48px;
-            cursor: pointer;
-            border-bottom: 1px solid #f0f0f0;
-            transition: all 0.3s;
-            font-size: 14px;
-            color: rgba(0, 0, 0, 0.85);
-        }
-
-        .service-item:hover {
-            background: #f5f5f5;
-            color: #1890ff;
-        }
-
-        .service-item.active {
-            background: #e6f7ff;
-            border-right: 2px solid #1890ff;
-            color: #1890ff;
-            font-weight: 500;
-        }
-
-        .main-content {
-            flex: 1;
-            margin-left: 300px;
-            margin-top: 75px;
-            padding: 24px;
-            overflow-y: auto;
-            transition: margin-left 0.3s ease;
-        }
-
-        .main-content.sidebar-collapsed {
-            margin-left: 0;
-        }
-
-        .api-section {
-            background: white;
-            border-radius: 6px;
-            margin-bottom: 16px;
-            box-shadow: 0 2px 8px rgba(0, 0, 0, 0.06);
-            border: 1px solid #f0f0f0;
-            overflow: hidden;
-        }
-
-        .api-header {
-            padding: 16px 24px;
-            background: #1890ff;
-            color: white;
-            border-bottom: 1px solid #40a9ff;
-        }
-
-        .api-title {
-            font-size: 18px;
-            font-weight: 600;
-            margin-bottom: 8px;
-        }
-
-        .api-path {
-            font-family: 'SFMono-Regular', Consolas, 'Liberation Mono', Menlo, Courier, monospace;
-            font-size: 12px;
-            background: rgba(255, 255, 255, 0.2);
-            border-radius: 4px;
-            display: flex;
-            align-items: center;
-            margin-bottom: 12px;
-            border: 1px solid rgba(255, 255, 255, 0.3);
-            max-width: fit-content;
-            overflow: hidden;
-        }
-
-        .path-text {
-            padding: 4px 8px;
-            flex: 1;
-        }
-
-        .copy-btn-path {
-            padding: 4px 8px;
-            margin: 0;
-            border: none;
-            border-left: 1px solid rgba(255, 255, 255, 0.3);
-            border-radius: 0;
-            background: rgba(255, 255, 255, 0.1);
-        }
-
-        .copy-btn-path:hover {
-            background: rgba(255, 255, 255, 0.2);
-        }
-
-        .copy-btn {
-            background: rgba(255, 255, 255, 0.2);
-            border: 1px solid rgba(255, 255, 255, 0.3);
-            border-radius: 4px;
-            padding: 4px;
-            color: rgba(255, 255, 255, 0.8);
-            cursor: pointer;
-            transition: all 0.2s;
-            display: flex;
-            align-items: center;
-            justify-content: center;
-        }
-
-        .copy-btn:hover {
-            background: rgba(255, 255, 255, 0.3);
-            color: #fff;
-        }
-
-        .copy-btn.copied {
-            background: #52c41a;
-            color: #fff;
-        }
-
-        .copy-btn-small {
-            padding: 2px;
-            margin-left: 6px;
-        }
-
-        .meta-item {
-            display: flex;
-            align-items: center;
-            gap: 6px;
-        }
-
-        .api-meta {
-            display: flex;
-            gap: 24px;
-            flex-wrap: wrap;
-            font-size: 12px;
-        }
-
-        .meta-label {
-            color: rgba(255, 255, 255, 0.85);
-            font-weight: 400;
-        }
-
-        .meta-value {
-            font-weight: 500;
-            padding: 2px 6px;
-            background: rgba(255, 255, 255, 0.15);
-            border-radius: 4px;
-            border: 1px solid rgba(255, 255, 255, 0.2);
-        }
-
-        .auth-status-badge {
-            font-weight: 500;
-            padding: 2px 8px;
-            border-radius: 4px;
-            font-size: 11px;
-            border: 1px solid;
-        }
-
-        .auth-required {
-            background: #fff2f0;
-            color: #ff4d4f;
-            border-color: #ffccc7;
-        }
-
-        .auth-not-required {
-            background: #f6ffed;
-            color: #52c41a;
-            border-color: #b7eb8f;
-        }
-
-        .meta-value-box {
-            display: flex;
-            align-items: center;
-            background: rgba(255, 255, 255, 0.15);
-            border-radius: 4px;
-            border: 1px solid rgba(255, 255, 255, 0.2);
-            overflow: hidden;
-        }
-
-        .meta-value-text {
-            font-weight: 500;
-            padding: 2px 6px;
-            flex: 1;
-        }
-
-        .copy-btn-inline {
-            padding: 2px 6px;
-            margin: 0;
-            border: none;
-            border-left: 1px solid rgba(255, 255, 255, 0.2);
-            border-radius: 0;
-            background: rgba(255, 255, 255, 0.1);
-        }
-
-        .copy-btn-inline:hover {
-            background: rgba(255, 255, 255, 0.2);
-        }
-
-        .api-description {
-            margin-top: 12px;
-            font-size: 13px;
-            color: rgba(255, 255, 255, 0.85);
-            line-height: 1.5;
-            font-style: italic;
-        }
-
-        .api-body {
-            padding: 24px;
-        }
-
-        .params-section {
-            margin-bottom: 32px;
-        }
-
-        .section-title {
-            font-size: 16px;
-            font-weight: 600;
-            margin-bottom: 16px;
-            color: rgba(0, 0, 0, 0.85);
-            border-bottom: none;
-            padding-bottom: 0;
-        }
-
-        .params-table {
-            width: 100%;
-            border-collapse: collapse;
-            background: white;
-            border-radius: 6px;
-            overflow: hidden;
-            border: 1px solid #f0f0f0;
-        }
-
-        .params-table th,
-        .params-table td {
-            padding: 8px 12px;
-            text-align: left;
-            border-bottom: 1px solid #f0f0f0;
-        }
-
-        .params-table th {
-            background: #fafafa;
-            font-weight: 500;
-            color: rgba(0, 0, 0, 0.85);
-            font-size: 13px;
-        }
-
-        .params-table td {
-            font-size: 13px;
-            color: rgba(0, 0, 0, 0.85);
-        }
-
-        .field-name-box {
-            display: flex;
-            align-items: center;
-            gap: 4px;
-        }
-
-        .field-name {
-            font-family: 'SFMono-Regular', Consolas, 'Liberation Mono', Menlo, Courier, monospace;
-            font-weight: 600;
-            color: #1890ff;
-        }
-
-        .copy-btn-field {
-            padding: 2px;
-            margin: 0;
-            border: 1px solid #d9d9d9;
-            border-radius: 2px;
-            background: #fafafa;
-            color: rgba(0, 0, 0, 0.45);
-            flex-shrink: 0;
-        }
-
-        .copy-btn-field:hover {
-            background: #f0f0f0;
-            color: #1890ff;
-            border-color: #40a9ff;
-        }
-
-        .copy-btn-field.copied {
-            background: #52c41a;
-            color: #fff;
-            border-color: #52c41a;
-        }
-
-        .params-table tr:last-child td {
-            border-bottom: none;
-        }
-
-        .params-table tr:hover {
-            background: #fafafa;
-        }
-
-        .field-type {
-            font-family: 'SFMono-Regular', Consolas, 'Liberation Mono', Menlo, Courier, monospace;
-            color: #722ed1;
-            background: #f9f0ff;
-            padding: 2px 6px;
-            border-radius: 4px;
-            border: 1px solid #d3adf7;
-        }
-
-        .field-required {
-            font-size: 12px;
-            color: rgba(0, 0, 0, 0.45);
-        }
-
-        .field-required.required {
-            color: #ff4d4f;
-            font-weight: 500;
-        }
-
-        .required {
-            color: #ff4d4f;
-            font-weight: 500;
-        }
-
-        .not-required {
-            color: rgba(0, 0, 0, 0.45);
-        }
-
-        .from-tag {
-            font-size: 12px;
-            background: #1890ff;
-            color: white;
-            padding: 2px 6px;
-            border-radius: 4px;
-            font-weight: 400;
-            display: inline-block;
-        }
-
-        .empty-state {
-            text-align: center;
-            color: rgba(0, 0, 0, 0.45);
-            font-style: italic;
-            padding: 48px 24px;
-            background: #fafafa;
-            border-radius: 6px;
-            border: 1px dashed #d9d9d9;
-        }
-
-        .nested-field {
-            border-left: 2px solid #e8f4ff;
-            margin-left: 10px;
-            padding-left: 10px;
-        }
-
-        .nested-field.level-1 {
-            border-left-color: #bae7ff;
-        }
-
-        .nested-field.level-2 {
-            border-left-color: #91d5ff;
-        }
-
-        .nested-field.level-3 {
-            border-left-color: #69c0ff;
-        }
-
-        .field-path {
-            color: rgba(0, 0, 0, 0.45);
-            font-size: 11px;
-            margin-left: 8px;
-            font-style: italic;
-        }
-
-        .expand-btn {
-            border: none;
-            background: none;
-            color: #1890ff;
-            cursor: pointer;
-            padding: 0 4px;
-            font-size: 12px;
-            margin-right: 4px;
-            width: 16px;
-            text-align: center;
-        }
-
-        .expand-btn:hover {
-            background: #f0f8ff;
-        }
-
-        .expand-btn-placeholder {
-            width: 16px;
-            margin-right: 4px;
-            display: inline-block;
-        }
-
-        .nested-table {
-            margin-top: 8px;
-            border: 1px solid #f0f0f0;
-            border-radius: 4px;
-        }
-
-        .nested-table .params-table {
-            margin: 0;
-            border: none;
-        }
-
-        .nested-table .params-table th {
-            background: #f8f9fa;
-            font-size: 12px;
-            padding: 6px 8px;
-        }
-
-        .nested-table .params-table td {
-            font-size: 12px;
-            padding: 6px 8px;
-        }
-
-        @media (max-width: 768px) {
-            .top-header {
-                left: 0;
-                padding: 0 16px;
-            }
-
-            .menu-toggle {
-                width: 36px;
-                height: 36px;
-            }
-
-            .sidebar-overlay.show {
-                display: block;
-            }
-
-            .main-content {
-                margin-left: 0;
-                padding: 16px;
-            }
-
-            .main-content.sidebar-collapsed {
-                margin-left: 0;
-            }
-
-            .api-section {
-                margin-bottom: 24px;
-            }
-
-            .api-header {
-                padding: 12px 16px;
-            }
-
-            .api-title {
-                font-size: 16px;
-                margin-bottom: 6px;
-            }
-
-            .api-meta {
-                flex-direction: column;
-                gap: 8px;
-                font-size: 11px;
-            }
-
-            .api-body {
-                padding: 16px;
-            }
-
-            .params-table {
-                font-size: 12px;
-            }
-
-            .params-table th,
-            .params-table td {
-                padding: 6px 8px;
-            }
-
-            .field-name-box {
-                flex-direction: column;
-                align-items: flex-start !important;
-                gap: 4px;
-            }
-
-            .field-name {
-                font-size: 13px;
-                cursor: pointer;
-                padding: 4px 8px;
-                border-radius: 4px;
-                transition: background-color 0.2s;
-                display: inline-block;
-            }
-
-            .field-name:hover {
-                background-color: rgba(24, 144, 255, 0.1);
-                color: #1890ff;
-            }
-
-            .field-name:active {
-                background-color: rgba(24, 144, 255, 0.2);
-            }
-
-            .field-type {
-                font-size: 11px;
-                padding: 1px 4px;
-            }
-
-            .copy-btn-field {
-                display: none !important;
-            }
-
-            .field-path {
-                font-size: 10px;
-                margin-left: 0;
-            }
-        }
-
-        @media (max-width: 480px) {
-            .main-content {
-                padding: 12px;
-            }
-
-            .api-header {
-                padding: 10px 12px;
-            }
-
-            .api-body {
-                padding: 12px;
-            }
-
-            .api-title {
-                font-size: 14px;
-            }
-
-            .params-table th,
-            .params-table td {
-                padding: 4px 6px;
-                font-size: 11px;
-            }
-
-            .field-name {
-                font-size: 12px;
-            }
-
-            .api-path {
-                font-size: 11px;
-            }
-
-            .meta-value,
-            .meta-value-text {
-                font-size: 10px;
-                padding: 1px 4px;
-            }
-        }
-    </style>
-</head>
-<body>
-    <div class="container">
-        <!-- 顶部固定区域 -->
-        <div class="top-header">
-            <!-- 汉堡包菜单按钮 -->
-            <button class="menu-toggle" id="menuToggle" onclick="toggleSidebar()">
-                <div class="menu-toggle-icon">
-                    <span></span>
-                    <span></span>
-                    <span></span>
-                </div>
-            </button>
-        </div>
-
-        <!-- 侧边栏遮罩层 -->
-        <div class="sidebar-overlay" id="sidebarOverlay" onclick="closeSidebar()"></div>
-
-        <div class="sidebar" id="sidebar">
-            <div class="sidebar-header">
-                <h1>{{.AppInfo.DisplayName}}</h1>
-                {{if .AppInfo.Version}}<div class="version">v{{.AppInfo.Version}}</div>{{end}}
-            </div>
-            <div class="sidebar-content">
-                {{range .Groups}}
-                <div class="group">
-                    <div class="group-title">{{.Name}}</div>
-                    <div class="service-list">
-                        {{range .Services}}
-                        <div class="service-item" onclick="scrollToService('service-{{.Name}}')">
-                            {{.DisplayName}}
-                        </div>
-                        {{end}}
-                    </div>
-                </div>
-                {{end}}
-            </div>
-        </div>
-
-        <div class="main-content" id="mainContent">
-            {{range .Groups}}
-            {{range .Services}}
-            <div class="api-section" id="service-{{.Name}}">
-                <div class="api-header">
-                    <div class="api-title">{{.DisplayName}}</div>
-                    <div class="api-path">
-                        <span class="path-text">POST {{.ServicePath}}</span>
-                        <button class="copy-btn copy-btn-path" onclick="copyToClipboard('{{.ServicePath}}', this)" title="复制接口地址">
-                            <svg width="14" height="14" viewBox="0 0 24 24" fill="currentColor">
-                                <path d="M16 1H4c-1.1 0-2 .9-2 2v14h2V3h12V1zm3 4H8c-1.1 0-2 .9-2 2v14c0 1.1.9 2 2 2h11c1.1 0 2-.9 2-2V7c0-1.1-.9-2-2-2zm0 16H8V7h11v14z"/>
-                            </svg>
-                        </button>
-                    </div>
-                    <div class="api-meta">
-                        <div class="meta-item">
-                            <span class="meta-label">服务名称:</span>
-                            <div class="meta-value-box">
-                                <span class="meta-value-text">{{.Name}}</span>
-                                <button class="copy-btn copy-btn-inline" onclick="copyToClipboard('{{.Name}}', this)" title="复制服务名称">
-                                    <svg width="12" height="12" viewBox="0 0 24 24" fill="currentColor">
-                                        <path d="M16 1H4c-1.1 0-2 .9-2 2v14h2V3h12V1zm3 4H8c-1.1 0-2 .9-2 2v14c0 1.1.9 2 2 2h11c1.1 0 2-.9 2-2V7c0-1.1-.9-2-2-2zm0 16H8V7h11v14z"/>
-                                    </svg>
-                                </button>
-                            </div>
-                        </div>
-                        <div class="meta-item">
-                            <span class="meta-label">认证:</span>
-                            <span class="meta-value auth-status-badge {{if .SkipAuth}}auth-not-required{{else}}auth-required{{end}}">{{if .SkipAuth}}不需要{{else}}需要{{end}}</span>
-                        </div>
-                        <div class="meta-item">
-                            <span class="meta-label">返回格式:</span>
-                            <span class="meta-value auth-status-badge {{if .ReturnRaw}}auth-not-required{{else}}auth-required{{end}}">{{if .ReturnRaw}}原始格式{{else}}标准格式{{end}}</span>
-                        </div>
-                    </div>
-                    {{if .Description}}
-                    <div class="api-description">{{.Description}}</div>
-                    {{end}}
-                </div>
-                <div class="api-body">
-
-                    {{if .InputFields}}
-                    <div class="params-section">
-                        <div class="section-title">请求参数</div>
-                        <table class="params-table">
-                            <thead>
-                                <tr>
-                                    <th>参数名</th>
-                                    <th>类型</th>
-                                    <th>来源</th>
-                                    <th>必填</th>
-                                    <th>描述</th>
-                                </tr>
-                            </thead>
-                            <tbody>
-                                {{range .InputFields}}
-                                {{template "renderField" .}}
-                                {{end}}
-                            </tbody>
-                        </table>
-                    </div>
-                    {{else}}
-                    <div class="params-section">
-                        <div class="section-title">请求参数</div>
-                        <div class="empty-state">无参数</div>
-                    </div>
-                    {{end}}
-
-                    {{if .OutputFields}}
-                    <div class="params-section">
-                        <div class="section-title">返回参数{{if not .ReturnRaw}} (标准格式){{else}} (原始格式){{end}}</div>
-                        {{if not .ReturnRaw}}
-                        <div class="return-format-note">
-                            <div style="margin-bottom: 12px; padding: 8px; background: #f6ffed; border: 1px solid #b7eb8f; border-radius: 4px; font-size: 12px; color: #52c41a;">
-                                <strong>标准返回格式：</strong>返回数据被包装在统一的响应结构中
-                            </div>
-                        </div>
-                        <table class="params-table">
-                            <thead>
-                                <tr>
-                                    <th>参数名</th>
-                                    <th>类型</th>
-                                    <th>是否必须</th>
-                                    <th>描述</th>
-                                </tr>
-                            </thead>
-                            <tbody>
-                                <tr>
-                                    <td>
-                                        <div class="field-name-box">
-                                            <span class="expand-btn-placeholder"></span>
-                                            <span class="field-name">code</span>
-                                        </div>
-                                    </td>
-                                    <td><span class="field-type">int</span></td>
-                                    <td><span class="field-required required">是</span></td>
-                                    <td>响应状态码，0表示成功</td>
-                                </tr>
-                                <tr>
-                                    <td>
-                                        <div class="field-name-box">
-                                            <span class="expand-btn-placeholder"></span>
-                                            <span class="field-name">msg</span>
-                                        </div>
-                                    </td>
-                                    <td><span class="field-type">string</span></td>
-                                    <td><span class="field-required">否</span></td>
-                                    <td>响应消息</td>
-                                </tr>
-                                <tr>
-                                    <td>
-                                        <div class="field-name-box">
-                                            {{if .OutputFields}}
-                                            <button class="expand-btn" onclick="toggleNested(this)">+</button>
-                                            {{else}}
-                                            <span class="expand-btn-placeholder"></span>
-                                            {{end}}
-                                            <span class="field-name">data</span>
-                                        </div>
-                                    </td>
-                                    <td><span class="field-type">object</span></td>
-                                    <td><span class="field-required required">是</span></td>
-                                    <td>实际业务数据</td>
-                                </tr>
-                                {{range .OutputFields}}
-                                {{template "renderOutputFieldNested" .}}
-                                {{end}}
-                                <tr>
-                                    <td>
-                                        <div class="field-name-box">
-                                            <span class="expand-btn-placeholder"></span>
-                                            <span class="field-name">rid</span>
-                                        </div>
-                                    </td>
-                                    <td><span class="field-type">string</span></td>
-                                    <td><span class="field-required required">是</span></td>
-                                    <td>请求ID</td>
-                                </tr>
-                                <tr style="display: none;">
-                                    <td>
-                                        <div class="field-name-box">
-                                            <span class="expand-btn-placeholder"></span>
-                                            <span class="field-name">detail</span>
-                                        </div>
-                                    </td>
-                                    <td><span class="field-type">string</span></td>
-                                    <td><span class="field-required">否</span></td>
-                                    <td>错误详情（仅错误时存在）</td>
-                                </tr>
-                            </tbody>
-                        </table>
-                        {{else}}
-                        <div class="return-format-note">
-                            <div style="margin-bottom: 12px; padding: 8px; background: #fff7e6; border: 1px solid #ffd591; border-radius: 4px; font-size: 12px; color: #fa8c16;">
-                                <strong>原始返回格式：</strong>直接返回业务数据，不包装在标准响应结构中
-                            </div>
-                        </div>
-                        <table class="params-table">
-                            <thead>
-                                <tr>
-                                    <th>参数名</th>
-                                    <th>类型</th>
-                                    <th>是否必须</th>
-                                    <th>描述</th>
-                                </tr>
-                            </thead>
-                            <tbody>
-                                {{range .OutputFields}}
-                                {{template "renderOutputField" .}}
-                                {{end}}
-                            </tbody>
-                        </table>
-                        {{end}}
-                    </div>
-                    {{else}}
-                    <div class="params-section">
-                        <div class="section-title">返回参数</div>
-                        <div class="empty-state">无返回参数</div>
-                    </div>
-                    {{end}}
-                </div>
-            </div>
-            {{end}}
-            {{end}}
-        </div>
-    </div>
-
-    <script>
-        function copyToClipboard(text, button) {
-            navigator.clipboard.writeText(text).then(function() {
-                // 复制成功的视觉反馈
-                const originalClass = button.className;
-                button.classList.add('copied');
-
-                // 临时显示复制成功状态
-                setTimeout(function() {
-                    button.className = originalClass;
-                }, 1500);
-            }).catch(function(err) {
-                // 降级处理：使用传统方法
-                const textArea = document.createElement('textarea');
-                textArea.value = text;
-                document.body.appendChild(textArea);
-                textArea.focus();
-                textArea.select();
-                try {
-                    document.execCommand('copy');
-                    const originalClass = button.className;
-                    button.classList.add('copied');
-                    setTimeout(function() {
-                        button.className = originalClass;
-                    }, 1500);
-                } catch (err) {
-                    console.error('复制失败:', err);
-                }
-                document.body.removeChild(textArea);
-            });
-        }
-
-        // 移动端参数名点击复制功能
-        function copyFieldName(text, element) {
-            // 检查是否为移动端
-            if (window.innerWidth <= 768) {
-                // 创建临时的视觉反馈
-                const originalBg = element.style.backgroundColor;
-                const originalColor = element.style.color;
-
-                // 设置复制成功的视觉效果
-                element.style.backgroundColor = '#52c41a';
-                element.style.color = '#fff';
-
-                // 执行复制
-                navigator.clipboard.writeText(text).then(function() {
-                    // 1.5秒后恢复原样
-                    setTimeout(function() {
-                        element.style.backgroundColor = originalBg;
-                        element.style.color = originalColor;
-                    }, 1500);
-                }).catch(function(err) {
-                    // 降级处理
-                    const textArea = document.createElement('textarea');
-                    textArea.value = text;
-                    document.body.appendChild(textArea);
-                    textArea.focus();
-                    textArea.select();
-                    try {
-                        document.execCommand('copy');
-                        setTimeout(function() {
-                            element.style.backgroundColor = originalBg;
-                            element.style.color = originalColor;
-                        }, 1500);
-                    } catch (err) {
-                        console.error('复制失败:', err);
-                        element.style.backgroundColor = originalBg;
-                        element.style.color = originalColor;
-                    }
-                    document.body.removeChild(textArea);
-                });
-            }
-        }
-
-        function scrollToService(serviceId) {
-            const element = document.getElementById(serviceId);
-            if (element) {
-                element.scrollIntoView({ behavior: 'smooth', block: 'start' });
-
-                // 更新激活状态
-                document.querySelectorAll('.service-item').forEach(item => {
-                    item.classList.remove('active');
-                });
-                event.target.classList.add('active');
-
-                // 移动端自动关闭侧边栏
-                if (window.innerWidth <= 768) {
-                    closeSidebar();
-                }
-            }
-        }
-
-        // 滚动监听，自动更新侧边栏激活状态
-        function updateActiveService() {
-            const sections = document.querySelectorAll('.api-section');
-            const serviceItems = document.querySelectorAll('.service-item');
-
-            let current = '';
-            sections.forEach(section => {
-                const rect = section.getBoundingClientRect();
-                if (rect.top <= 100) {
-                    current = section.id;
-                }
-            });
-
-            serviceItems.forEach(item => {
-                item.classList.remove('active');
-                // 只有当current不为空且匹配时才添加active类
-                if (current && item.getAttribute('onclick').includes(current)) {
-                    item.classList.add('active');
-                }
-            });
-        }
-
-        window.addEventListener('scroll', updateActiveService);
-        document.addEventListener('DOMContentLoaded', updateActiveService);
-
-        // 切换侧边栏显示/隐藏
-        function toggleSidebar() {
-            const sidebar = document.getElementById('sidebar');
-            const menuToggle = document.getElementById('menuToggle');
-            const overlay = document.getElementById('sidebarOverlay');
-            const mainContent = document.getElementById('mainContent');
-            const topHeader = document.querySelector('.top-header');
-
-            const isCollapsed = sidebar.classList.contains('collapsed');
-
-            if (isCollapsed) {
-                // 显示侧边栏
-                sidebar.classList.remove('collapsed');
-                menuToggle.classList.add('open');
-                mainContent.classList.remove('sidebar-collapsed');
-                topHeader.classList.remove('sidebar-collapsed');
-
-                // 移动端显示遮罩层
-                if (window.innerWidth <= 768) {
-                    overlay.classList.add('show');
-                }
-            } else {
-                // 隐藏侧边栏
-                closeSidebar();
-            }
-        }
-
-        // 关闭侧边栏
-        function closeSidebar() {
-            const sidebar = document.getElementById('sidebar');
-            const menuToggle = document.getElementById('menuToggle');
-            const overlay = document.getElementById('sidebarOverlay');
-            const mainContent = document.getElementById('mainContent');
-            const topHeader = document.querySelector('.top-header');
-
-            sidebar.classList.add('collapsed');
-            menuToggle.classList.remove('open');
-            mainContent.classList.add('sidebar-collapsed');
-            topHeader.classList.add('sidebar-collapsed');
-            overlay.classList.remove('show');
-        }
-
-        // 窗口大小变化时的处理
-        window.addEventListener('resize', function() {
-            const sidebar = document.getElementById('sidebar');
-            const overlay = document.getElementById('sidebarOverlay');
-
-            if (window.innerWidth > 768) {
-                // 桌面端隐藏遮罩层
-                overlay.classList.remove('show');
-            } else {
-                // 移动端如果侧边栏显示，则显示遮罩层
-                if (!sidebar.classList.contains('collapsed')) {
-                    overlay.classList.add('show');
-                }
-            }
-        });
-
-        // 初始化状态 - 默认展开侧边栏
-        document.addEventListener('DOMContentLoaded', function() {
-            const sidebar = document.getElementById('sidebar');
-            const mainContent = document.getElementById('mainContent');
-            const topHeader = document.querySelector('.top-header');
-            const menuToggle = document.getElementById('menuToggle');
-
-            // 默认状态是展开的
-            sidebar.classList.remove('collapsed');
-            mainContent.classList.remove('sidebar-collapsed');
-            topHeader.classList.remove('sidebar-collapsed');
-            menuToggle.classList.add('open'); // 设置菜单按钮为打开状态
-        });
-
-        // 展开/折叠嵌套字段
-        function toggleNested(button) {
-            const row = button.closest('tr');
-            const currentLevel = parseInt(row.className.match(/level-(\d+)/)?.[1] || '0');
-            const nextRows = [];
-            let currentRow = row.nextElementSibling;
-
-            // 只收集直接子级行（下一级别）
-            while (currentRow && currentRow.classList.contains('nested-row')) {
-                const rowLevel = parseInt(currentRow.className.match(/level-(\d+)/)?.[1] || '0');
-                if (rowLevel === currentLevel + 1) {
-                    nextRows.push(currentRow);
-                } else if (rowLevel <= currentLevel) {
-                    break;
-                }
-                currentRow = currentRow.nextElementSibling;
-            }
-
-            const isExpanded = button.textContent === '−';
-            button.textContent = isExpanded ? '+' : '−';
-
-            nextRows.forEach(r => {
-                if (isExpanded) {
-                    // 折叠时，隐藏直接子级并递归折叠其所有子级
-                    r.style.display = 'none';
-                    collapseAllChildren(r);
-                } else {
-                    // 展开时，只显示直接子级
-                    r.style.display = '';
-                }
-            });
-        }
-
-        // 递归折叠所有子级
-        function collapseAllChildren(parentRow) {
-            const parentLevel = parseInt(parentRow.className.match(/level-(\d+)/)?.[1] || '0');
-            let currentRow = parentRow.nextElementSibling;
-
-            while (currentRow && currentRow.classList.contains('nested-row')) {
-                const rowLevel = parseInt(currentRow.className.match(/level-(\d+)/)?.[1] || '0');
-                if (rowLevel <= parentLevel) {
-                    break;
-                }
-
-                // 隐藏所有更深层级的行
-                currentRow.style.display = 'none';
-
-                // 将展开按钮重置为+状态
-                const expandBtn = currentRow.querySelector('.expand-btn');
-                if (expandBtn) {
-                    expandBtn.textContent = '+';
-                }
-
-                currentRow = currentRow.nextElementSibling;
-            }
-        }
-    </script>
-
-    <!-- 模板定义 -->
-    {{define "renderField"}}
-    <tr {{if gt .Level 0}}class="nested-row nested-field level-{{.Level}}" style="display: none;"{{end}}>
-        <td>
-            <div class="field-name-box" style="margin-left: {{mul .Level 20}}px;">
-                {{if .Children}}
-                <button class="expand-btn" onclick="toggleNested(this)">+</button>
-                {{else}}
-                <span class="expand-btn-placeholder"></span>
-                {{end}}
-                <span class="field-name" onclick="copyFieldName('{{.Name}}', this)" title="点击复制参数名">{{.Name}}</span>
-                {{if .Parent}}<span class="field-path">({{.Parent}})</span>{{end}}
-                <button class="copy-btn copy-btn-field" onclick="copyToClipboard('{{.Name}}', this)" title="复制参数名">
-                    <svg width="10" height="10" viewBox="0 0 24 24" fill="currentColor">
-                        <path d="M16 1H4c-1.1 0-2 .9-2 2v14h2V3h12V1zm3 4H8c-1.1 0-2 .9-2 2v14c0 1.1.9 2 2 2h11c1.1 0 2-.9 2-2V7c0-1.1-.9-2-2-2zm0 16H8V7h11v14z"/>
-                    </svg>
-                </button>
-            </div>
-        </td>
-        <td><span class="field-type">{{.Type}}</span></td>
-        <td><span class="from-tag">{{.From}}</span></td>
-        <td><span class="{{if .Required}}required{{else}}not-required{{end}}">{{if .Required}}是{{else}}否{{end}}</span></td>
-        <td>{{if .Description}}{{.Description}}{{else}}-{{end}}</td>
-    </tr>
-    {{range .Children}}
-    {{template "renderField" .}}
-    {{end}}
-    {{end}}
-
-    {{define "renderOutputField"}}
-    <tr {{if gt .Level 0}}class="nested-row nested-field level-{{.Level}}" style="display: none;"{{end}}>
-        <td>
-            <div class="field-name-box" style="margin-left: {{mul .Level 20}}px;">
-                {{if .Children}}
-                <button class="expand-btn" onclick="toggleNested(this)">+</button>
-                {{else}}
-                <span class="expand-btn-placeholder"></span>
-                {{end}}
-                <span class="field-name" onclick="copyFieldName('{{.Name}}', this)" title="点击复制参数名">{{.Name}}</span>
-                {{if .Parent}}<span class="field-path">({{.Parent}})</span>{{end}}
-                <button class="copy-btn copy-btn-field" onclick="copyToClipboard('{{.Name}}', this)" title="复制参数名">
-                    <svg width="10" height="10" viewBox="0 0 24 24" fill="currentColor">
-                        <path d="M16 1H4c-1.1 0-2 .9-2 2v14h2V3h12V1zm3 4H8c-1.1 0-2 .9-2 2v14c0 1.1.9 2 2 2h11c1.1 0 2-.9 2-2V7c0-1.1-.9-2-2-2zm0 16H8V7h11v14z"/>
-                    </svg>
-                </button>
-            </div>
-        </td>
-        <td><span class="field-type">{{.Type}}</span></td>
-        <td>{{if .Required}}<span class="field-required required">是</span>{{else}}<span class="field-required">否</span>{{end}}</td>
-        <td>{{if .Description}}{{.Description}}{{else}}-{{end}}</td>
-    </tr>
-    {{range .Children}}
-    {{template "renderOutputField" .}}
-    {{end}}
-    {{end}}
-
-    {{define "renderOutputFieldNested"}}
-    <tr class="nested-row nested-field level-1" style="display: none;">
-        <td>
-            <div class="field-name-box" style="margin-left: 20px;">
-                {{if .Children}}
-                <button class="expand-btn" onclick="toggleNested(this)">+</button>
-                {{else}}
-                <span class="expand-btn-placeholder"></span>
-                {{end}}
-                <span class="field-name" onclick="copyFieldName('{{.Name}}', this)" title="点击复制参数名">{{.Name}}</span>
-                <button class="copy-btn copy-btn-field" onclick="copyToClipboard('{{.Name}}', this)" title="复制参数名">
-                    <svg width="10" height="10" viewBox="0 0 24 24" fill="currentColor">
-                        <path d="M16 1H4c-1.1 0-2 .9-2 2v14h2V3h12V1zm3 4H8c-1.1 0-2 .9-2 2v14c0 1.1.9 2 2 2h11c1.1 0 2-.9 2-2V7c0-1.1-.9-2-2-2zm0 16H8V7h11v14z"/>
-                    </svg>
-                </button>
-            </div>
-        </td>
-        <td><span class="field-type">{{.Type}}</span></td>
-        <td>{{if .Required}}<span class="field-required required">是</span>{{else}}<span class="field-required">否</span>{{end}}</td>
-        <td>{{if .Description}}{{.Description}}{{else}}-{{end}}</td>
-    </tr>
-    {{range .Children}}
-    {{template "renderOutputFieldNestedChild" .}}
-    {{end}}
-    {{end}}
-
-    {{define "renderOutputFieldNestedChild"}}
-    <tr class="nested-row nested-field level-{{add .Level 1}}" style="display: none;">
-        <td>
-            <div class="field-name-box" style="margin-left: {{mul (add .Level 1) 20}}px;">
-                {{if .Children}}
-                <button class="expand-btn" onclick="toggleNested(this)">+</button>
-                {{else}}
-                <span class="expand-btn-placeholder"></span>
-                {{end}}
-                <span class="field-name" onclick="copyFieldName('{{.Name}}', this)" title="点击复制参数名">{{.Name}}</span>
-                {{if .Parent}}<span class="field-path">({{.Parent}})</span>{{end}}
-                <button class="copy-btn copy-btn-field" onclick="copyToClipboard('{{.Name}}', this)" title="复制参数名">
-                    <svg width="10" height="10" viewBox="0 0 24 24" fill="currentColor">
-                        <path d="M16 1H4c-1.1 0-2 .9-2 2v14h2V3h12V1zm3 4H8c-1.1 0-2 .9-2 2v14c0 1.1.9 2 2 2h11c1.1 0 2-.9 2-2V7c0-1.1-.9-2-2-2zm0 16H8V7h11v14z"/>
-                    </svg>
-                </button>
-            </div>
-        </td>
-        <td><span class="field-type">{{.Type}}</span></td>
-        <td>{{if .Required}}<span class="field-required required">是</span>{{else}}<span class="field-required">否</span>{{end}}</td>
-        <td>{{if .Description}}{{.Description}}{{else}}-{{end}}</td>
-    </tr>
-    {{range .Children}}
-    {{template "renderOutputFieldNestedChild" .}}
-    {{end}}
-    {{end}}
-
-</body>
-</html>`
-
-	// 创建模板函数映射
-	funcMap := template.FuncMap{
-		"mul": func(a, b int) int { return a * b },
-		"gt":  func(a, b int) bool { return a > b },
-		"add": func(a, b int) int { return a + b },
-	}
-
-	t := template.Must(template.New("docs").Funcs(funcMap).Parse(tmpl))
-	var buf strings.Builder
-	t.Execute(&buf, docData)
-	return buf.String()
+	html, err := app.templates.RenderString("docs.html", docData)
+	if err != nil {
+		app.logger.WithError(err).Error("Failed to render docs page")
+	}
+	return html
 }
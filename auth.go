@@ -0,0 +1,316 @@
+package mod
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// AuthStrategy 服务的认证方式
+type AuthStrategy string
+
+const (
+	AuthNone       AuthStrategy = "none"        // 不需要认证
+	AuthTokenCache AuthStrategy = "token_cache" // Token须存在于Token缓存中（默认）
+	AuthJWT        AuthStrategy = "jwt"         // 校验JWT签名、过期时间和黑名单
+	AuthAPIKey     AuthStrategy = "api_key"     // 从API Key请求头读取密钥，密钥须存在于Token缓存中
+	AuthSignature  AuthStrategy = "signature"   // 校验请求的HMAC-SHA256签名，用于服务间调用和Webhook
+)
+
+// authStrategyLabels 文档中展示的认证方式名称
+var authStrategyLabels = map[AuthStrategy]string{
+	AuthNone:       "不需要",
+	AuthTokenCache: "Token",
+	AuthJWT:        "JWT",
+	AuthAPIKey:     "API Key",
+	AuthSignature:  "请求签名",
+}
+
+// validAuthStrategy 是否为支持的认证方式
+func validAuthStrategy(strategy AuthStrategy) bool {
+	_, ok := authStrategyLabels[strategy]
+	return ok
+}
+
+// checkAuthConfig 校验 auth 配置中的认证方式
+func (app *App) checkAuthConfig() {
+	config := app.cfg.ModConfig.Auth
+	if config.Default == "" && config.Admin.Strategy == "" && len(config.Groups) == 0 && len(config.Services) == 0 {
+		return
+	}
+	start := time.Now()
+	var err error
+	check := func(scope, value string) {
+		if err == nil && value != "" && !validAuthStrategy(AuthStrategy(value)) {
+			err = fmt.Errorf("%s: unknown auth strategy %q", scope, value)
+		}
+	}
+	check("auth.default", config.Default)
+	check("auth.admin.strategy", config.Admin.Strategy)
+	for group, value := range config.Groups {
+		check("auth.groups."+group, value)
+	}
+	for name, value := range config.Services {
+		check("auth.services."+name, value)
+	}
+	app.recordStartup("auth", "", start, err)
+}
+
+// SetGroupAuth 设置分组的认证方式，分组内未指定 Auth 且未设置 SkipAuth 的服务使用该认证方式，优先级高于 auth.groups 配置
+func (app *App) SetGroupAuth(group string, strategy AuthStrategy) error {
+	if !validAuthStrategy(strategy) {
+		return fmt.Errorf("group %q: unknown auth strategy %q", group, strategy)
+	}
+	app.authMu.Lock()
+	defer app.authMu.Unlock()
+	if app.groupAuth == nil {
+		app.groupAuth = map[string]AuthStrategy{}
+	}
+	app.groupAuth[group] = strategy
+	app.invalidateDocs()
+	return nil
+}
+
+// authStrategy 返回服务生效的认证方式，依次使用 Service.Auth、SkipAuth、auth.services 配置、
+// 分组认证方式（SetGroupAuth、auth.groups 配置）、auth.default 配置，默认为 token_cache
+func (app *App) authStrategy(svc *Service) AuthStrategy {
+	if svc.Auth != "" {
+		return svc.Auth
+	}
+	if svc.SkipAuth {
+		return AuthNone
+	}
+	config := app.cfg.ModConfig.Auth
+	if strategy := AuthStrategy(config.Services[svc.Name]); strategy != "" {
+		return strategy
+	}
+	app.authMu.RLock()
+	strategy, ok := app.groupAuth[svc.Group]
+	app.authMu.RUnlock()
+	if ok {
+		return strategy
+	}
+	if strategy := AuthStrategy(config.Groups[svc.Group]); strategy != "" {
+		return strategy
+	}
+	if config.Default != "" {
+		return AuthStrategy(config.Default)
+	}
+	return AuthTokenCache
+}
+
+// authenticate 按服务的认证方式校验请求，返回请求携带的Token（API Key），失败时返回 StdReply 错误
+func (app *App) authenticate(ctx *Context, svc *Service, strategy AuthStrategy) (string, error) {
+	switch strategy {
+	case AuthNone:
+		return "", nil
+
+	case AuthSignature:
+		if err := app.verifyRequestSignature(ctx); err != nil {
+			app.logger.WithFields(logrus.Fields{
+				"service": svc.Name,
+				"error":   err.Error(),
+				"rid":     ctx.GetRequestID(),
+			}).Warn("Request signature verification failed")
+			return "", Reply(401, "Invalid signature")
+		}
+		return "", nil
+
+	case AuthTokenCache:
+		token := parseToken(ctx.Ctx, app.tokenKeys)
+		if token == "" {
+			return "", Reply(401, "Unauthorized")
+		}
+		// 验证 token 的有效性
+		if !app.validateToken(ctx.Ctx, token) {
+			app.logger.WithFields(logrus.Fields{
+				"service": svc.Name,
+				"token":   token,
+				"rid":     ctx.GetRequestID(),
+			}).Warn("Token validation failed")
+			return "", app.invalidTokenReply(ctx.Ctx, token)
+		}
+		return token, nil
+
+	case AuthJWT:
+		jwtManager := app.GetJWTManager()
+		if !jwtManager.IsEnabled() {
+			app.logger.WithField("service", svc.Name).Error("Service requires JWT authentication but token.jwt is not enabled")
+			return "", Reply(500, "JWT authentication is not configured")
+		}
+		// 已由 JWT 中间件校验时直接使用
+		if claims := ctx.GetJWTClaims(); claims != nil {
+			return ctx.GetJWTToken(), nil
+		}
+		token := jwtManager.ExtractTokenFromRequest(ctx)
+		if token == "" {
+			return "", Reply(401, "Missing authentication token")
+		}
+		if jwtManager.IsTokenBlacklisted(token) {
+			return "", Reply(401, "Token has been revoked")
+		}
+		claims, err := jwtManager.ValidateToken(token)
+		if err != nil {
+			app.logger.WithError(err).WithField("service", svc.Name).Debug("JWT token validation failed")
+			return "", Reply(401, "Invalid authentication token")
+		}
+		setJWTLocals(ctx.Ctx, claims, token)
+		return token, nil
+
+	case AuthAPIKey:
+		key := ctx.Get(app.apiKeyHeader())
+		if key == "" {
+			return "", Reply(401, "Missing API key")
+		}
+		if _, err := app.tokenData(ctx.Ctx, key); err != nil {
+			app.logger.WithFields(logrus.Fields{
+				"service": svc.Name,
+				"error":   err.Error(),
+				"rid":     ctx.GetRequestID(),
+			}).Warn("API key validation failed")
+			return "", Reply(401, "Invalid API key")
+		}
+		return key, nil
+	}
+
+	app.logger.WithFields(logrus.Fields{
+		"service": svc.Name,
+		"auth":    strategy,
+	}).Error("Unknown auth strategy")
+	return "", Reply(500, "Unknown auth strategy")
+}
+
+// defaultAdminScopes 未配置 auth.admin.scopes 时管理接口要求的权限范围
+var defaultAdminScopes = []string{"admin"}
+
+// adminStrategy 返回管理接口的认证方式：auth.admin.strategy，其次 auth.default，未配置或为 none 时为 token_cache
+func (app *App) adminStrategy() AuthStrategy {
+	config := app.cfg.ModConfig.Auth
+	if config.Admin.Strategy != "" {
+		return AuthStrategy(config.Admin.Strategy)
+	}
+	if config.Default != "" && AuthStrategy(config.Default) != AuthNone {
+		return AuthStrategy(config.Default)
+	}
+	return AuthTokenCache
+}
+
+// authorizeAdmin 校验管理接口的请求：按 adminStrategy 认证，并要求Token被授予 auth.admin.scopes 中的权限范围，
+// 失败时返回 StdReply 错误；endpoint 为接口名称，用于日志
+func (app *App) authorizeAdmin(ctx *Context, endpoint string) error {
+	strategy := app.adminStrategy()
+	if strategy == AuthNone {
+		return nil
+	}
+	// 未启用Token校验时任意Token都能通过 token_cache 认证，拒绝访问而不是放行
+	if strategy == AuthTokenCache && !app.cfg.ModConfig.Token.Validation.Enabled {
+		app.logger.WithField("endpoint", endpoint).Error("Admin endpoint requires token_cache authentication but token.validation is not enabled")
+		return Reply(500, "Admin authentication is not configured")
+	}
+	if _, err := app.authenticate(ctx, &Service{Name: endpoint}, strategy); err != nil {
+		return err
+	}
+	if strategy == AuthSignature {
+		return nil
+	}
+
+	scopes := app.cfg.ModConfig.Auth.Admin.Scopes
+	if scopes == nil {
+		scopes = defaultAdminScopes
+	}
+	if missing := missingScopes(ctx.Scopes(), scopes); len(missing) > 0 {
+		app.logger.WithFields(logrus.Fields{
+			"endpoint": endpoint,
+			"missing":  missing,
+			"rid":      ctx.GetRequestID(),
+		}).Warn("Admin scope check failed")
+		return ReplyWithDetail(403, "Insufficient scope", "missing scopes: "+strings.Join(missing, " "))
+	}
+	return nil
+}
+
+// adminService 框架注册的管理服务（Token、设置、重载、Mock、请求捕获）在处理函数之前按 auth.admin 校验管理员身份，
+// 服务配置了 SkipAuth 时不校验
+func (app *App) adminService(svc Service) Service {
+	svc.builtin = true
+	if svc.SkipAuth {
+		return svc
+	}
+	name, handler := svc.Name, svc.Handler.Func
+	svc.Handler.Func = func(ctx *Context, args, reply any) error {
+		if err := app.authorizeAdmin(ctx, name); err != nil {
+			return err
+		}
+		return handler(ctx, args, reply)
+	}
+	return svc
+}
+
+// apiKeyHeader 返回 api_key 方式读取密钥的请求头，默认 X-API-Key
+func (app *App) apiKeyHeader() string {
+	if header := app.cfg.ModConfig.Auth.APIKeyHeader; header != "" {
+		return header
+	}
+	return "X-API-Key"
+}
+
+// signatureHeaders 返回签名和时间戳请求头，默认 X-Signature、X-Timestamp
+func (app *App) signatureHeaders() (header, timestampHeader string) {
+	config := app.cfg.ModConfig.Auth.Signature
+	header, timestampHeader = config.Header, config.TimestampHeader
+	if header == "" {
+		header = "X-Signature"
+	}
+	if timestampHeader == "" {
+		timestampHeader = "X-Timestamp"
+	}
+	return header, timestampHeader
+}
+
+// SignRequest 计算请求签名：HMAC-SHA256(secret, timestamp + "\n" + path + "\n" + body) 的十六进制编码，
+// 调用方将签名和秒级Unix时间戳分别放入 X-Signature 和 X-Timestamp 请求头（可通过 auth.signature 修改）
+func SignRequest(secret, timestamp, path string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "\n" + path + "\n"))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// verifyRequestSignature 校验请求签名和时间戳
+func (app *App) verifyRequestSignature(ctx *Context) error {
+	config := app.cfg.ModConfig.Auth.Signature
+	if config.Secret == "" {
+		return fmt.Errorf("auth.signature.secret is not configured")
+	}
+	header, timestampHeader := app.signatureHeaders()
+	maxSkew := 5 * time.Minute
+	if config.MaxSkew != "" {
+		if d, err := time.ParseDuration(config.MaxSkew); err == nil {
+			maxSkew = d
+		}
+	}
+
+	signature, timestamp := ctx.Get(header), ctx.Get(timestampHeader)
+	if signature == "" || timestamp == "" {
+		return fmt.Errorf("missing %s or %s header", header, timestampHeader)
+	}
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid timestamp %q", timestamp)
+	}
+	if skew := app.now().Sub(time.Unix(seconds, 0)); skew > maxSkew || skew < -maxSkew {
+		return fmt.Errorf("timestamp is outside the allowed window of %s", maxSkew)
+	}
+
+	expected := SignRequest(config.Secret, timestamp, ctx.Path(), ctx.Body())
+	if !hmac.Equal([]byte(signature), []byte(expected)) {
+		return fmt.Errorf("signature mismatch")
+	}
+	return nil
+}
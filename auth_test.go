@@ -0,0 +1,282 @@
+package mod
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+// newTestApp 使用给定的 mod.yml 内容创建应用
+func newTestApp(t *testing.T, config string) *App {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "mod.yml")
+	if err := os.WriteFile(path, []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("MOD_PATH", path)
+	return New()
+}
+
+// tokenCacheConfig 启用 bigcache Token校验的配置
+const tokenCacheConfig = `
+cache:
+  bigcache:
+    enabled: true
+    shards: 16
+    life_window: "1h"
+    clean_window: "1h"
+token:
+  validation:
+    enabled: true
+    cache_strategy: "bigcache"
+`
+
+const adminEndpointsConfig = `
+slo:
+  enabled: true
+metrics:
+  enabled: true
+resolve:
+  enabled: true
+pools:
+  enabled: true
+`
+
+var adminPaths = []string{"/admin/slo", "/metrics", "/admin/schedules", "/admin/resolve", "/admin/pools"}
+
+// newAdminTestApp 创建启用全部管理接口的应用，注册定时任务以启用 /admin/schedules
+func newAdminTestApp(t *testing.T, config string) *App {
+	t.Helper()
+	app := newTestApp(t, config+adminEndpointsConfig)
+	if err := app.Schedule("0 0 1 1 *", "yearly", func(ctx *Context) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	return app
+}
+
+func TestAdminEndpointsRequireAdminToken(t *testing.T) {
+	app := newAdminTestApp(t, tokenCacheConfig)
+	if err := app.SetToken("user-token", map[string]any{"scope": "orders:read"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := app.SetToken("admin-token", map[string]any{"scope": "admin"}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range adminPaths {
+		resp, err := app.TestClient().Get(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.AssertStatus(t, 401)
+
+		resp, err = app.TestClient().WithToken("bogus").Get(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.AssertStatus(t, 401)
+
+		resp, err = app.TestClient().WithToken("user-token").Get(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.AssertStatus(t, 403).AssertMsg(t, "Insufficient scope")
+
+		resp, err = app.TestClient().WithToken("admin-token").Get(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.AssertStatus(t, 200)
+	}
+}
+
+func TestAdminEndpointsRejectWithoutTokenValidation(t *testing.T) {
+	app := newAdminTestApp(t, "")
+	for _, path := range adminPaths {
+		resp, err := app.TestClient().WithToken("bogus").Get(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.AssertStatus(t, 500)
+	}
+}
+
+func TestAdminEndpointsUseAdminStrategy(t *testing.T) {
+	app := newAdminTestApp(t, `
+token:
+  jwt:
+    enabled: true
+    secret_key: "test-secret-key-for-admin-endpoints"
+auth:
+  admin:
+    strategy: jwt
+`)
+	resp, err := app.TestClient().WithToken("bogus").Get("/admin/slo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.AssertStatus(t, 401)
+
+	tokens, err := app.GenerateJWT("u1", "alice", "", "admin", map[string]any{"scope": "admin"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err = app.TestClient().WithToken(tokens.AccessToken).Get("/admin/slo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.AssertStatus(t, 200)
+}
+
+func TestAdminEndpointsSkipAuth(t *testing.T) {
+	app := newTestApp(t, `
+slo:
+  enabled: true
+  skip_auth: true
+`)
+	resp, err := app.TestClient().Get("/admin/slo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.AssertStatus(t, 200)
+}
+
+// assertAdminService 普通用户的 Token 调用框架管理服务时响应403，管理员 Token 通过认证
+func assertAdminService(t *testing.T, app *App, service string, body any) {
+	t.Helper()
+	if err := app.SetToken("user-token", map[string]any{"scope": "orders:read"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := app.SetToken("admin-token", map[string]any{"scope": "admin"}); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := app.TestClient().WithToken("user-token").Call(service, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.AssertStatus(t, 403).AssertMsg(t, "Insufficient scope")
+
+	resp, err = app.TestClient().WithToken("admin-token").Call(service, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode == 401 || resp.StatusCode == 403 {
+		t.Fatalf("%s: admin token rejected with %d: %s", service, resp.StatusCode, resp.Body)
+	}
+}
+
+type pingRequest struct {
+	Value string `json:"value"`
+}
+
+type pingResponse struct {
+	Value string `json:"value"`
+}
+
+// registerPing 注册使用指定认证方式的服务，原样返回请求中的 value
+func registerPing(t *testing.T, app *App, name string, strategy AuthStrategy) {
+	t.Helper()
+	err := app.Register(Service{
+		Name:        name,
+		DisplayName: name,
+		Auth:        strategy,
+		Handler: MakeHandler(func(ctx *Context, req *pingRequest, resp *pingResponse) error {
+			resp.Value = req.Value
+			return nil
+		}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestServiceAuthStrategies(t *testing.T) {
+	app := newTestApp(t, `
+cache:
+  bigcache:
+    enabled: true
+    shards: 16
+    life_window: "1h"
+    clean_window: "1h"
+token:
+  validation:
+    enabled: true
+    cache_strategy: "bigcache"
+  jwt:
+    enabled: true
+    secret_key: "test-secret-key-for-auth-strategies"
+auth:
+  signature:
+    secret: "test-signature-secret"
+`)
+	registerPing(t, app, "ping_token", AuthTokenCache)
+	registerPing(t, app, "ping_jwt", AuthJWT)
+	registerPing(t, app, "ping_api_key", AuthAPIKey)
+	registerPing(t, app, "ping_signature", AuthSignature)
+	registerPing(t, app, "ping_none", AuthNone)
+	if err := app.SetToken("valid-token", map[string]any{}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"ping_token", "ping_jwt"} {
+		resp, err := app.TestClient().WithToken("bogus").Call(name, pingRequest{Value: "x"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.AssertStatus(t, 401).AssertCode(t, 401)
+	}
+
+	resp, err := app.TestClient().WithToken("valid-token").Call("ping_token", pingRequest{Value: "x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.AssertStatus(t, 200).AssertSuccess(t)
+
+	tokens, err := app.GenerateJWT("u1", "alice", "", "user", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err = app.TestClient().WithToken(tokens.AccessToken).Call("ping_jwt", pingRequest{Value: "x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.AssertStatus(t, 200).AssertSuccess(t)
+
+	resp, err = app.TestClient().WithHeader("X-API-Key", "bogus").Call("ping_api_key", pingRequest{Value: "x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.AssertStatus(t, 401).AssertMsg(t, "Invalid API key")
+	resp, err = app.TestClient().WithHeader("X-API-Key", "valid-token").Call("ping_api_key", pingRequest{Value: "x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.AssertStatus(t, 200).AssertSuccess(t)
+
+	body := []byte(`{"value":"x"}`)
+	timestamp := strconv.FormatInt(app.now().Unix(), 10)
+	path := app.ServicePath("ping_signature")
+	resp, err = app.TestClient().
+		WithHeader("X-Timestamp", timestamp).
+		WithHeader("X-Signature", SignRequest("wrong-secret", timestamp, path, body)).
+		Post(path, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.AssertStatus(t, 401).AssertMsg(t, "Invalid signature")
+	resp, err = app.TestClient().
+		WithHeader("X-Timestamp", timestamp).
+		WithHeader("X-Signature", SignRequest("test-signature-secret", timestamp, path, body)).
+		Post(path, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.AssertStatus(t, 200).AssertSuccess(t)
+
+	resp, err = app.TestClient().Call("ping_none", pingRequest{Value: "x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.AssertStatus(t, 200).AssertSuccess(t)
+}
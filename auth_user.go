@@ -0,0 +1,118 @@
+package mod
+
+import "fmt"
+
+// AuthUser 当前请求的用户信息，来自JWT声明或Token缓存数据
+type AuthUser struct {
+	ID       string         `json:"id"`
+	Username string         `json:"username"`
+	Email    string         `json:"email"`
+	Role     string         `json:"role"`
+	Extra    map[string]any `json:"extra,omitempty"` // 其他声明或Token数据字段
+	Source   string         `json:"source"`          // 来源：jwt 或 token
+}
+
+// authUserKey 当前用户在 Fiber locals 中的缓存键
+type authUserKey struct{}
+
+// tokenUserFields Token缓存数据中各用户字段的候选键，按顺序取第一个非空值
+var tokenUserFields = map[string][]string{
+	"id":       {"id", "user_id", "userId", "uid"},
+	"username": {"username", "user_name", "name"},
+	"email":    {"email", "user_email"},
+	"role":     {"role", "user_role"},
+}
+
+// User 返回当前用户，优先使用JWT声明，其次使用Token缓存数据（顶层字段或 user 对象）。
+// 未认证或数据中既没有用户ID也没有用户名时返回 nil 和 false，结果在本次请求内缓存
+func (c *Context) User() (*AuthUser, bool) {
+	if cached, ok := c.Locals(authUserKey{}).(*AuthUser); ok {
+		return cached, cached != nil
+	}
+	user := c.loadUser()
+	c.Locals(authUserKey{}, user)
+	return user, user != nil
+}
+
+// RequireUser 返回当前用户，未认证时返回401错误，可直接作为处理函数的返回值
+func (c *Context) RequireUser() (*AuthUser, error) {
+	if user, ok := c.User(); ok {
+		return user, nil
+	}
+	return nil, Reply(401, "Unauthorized")
+}
+
+func (c *Context) loadUser() *AuthUser {
+	if claims := c.GetJWTClaims(); claims != nil {
+		return &AuthUser{
+			ID:       claims.UserID,
+			Username: claims.Username,
+			Email:    claims.Email,
+			Role:     claims.Role,
+			Extra:    claims.Extra,
+			Source:   "jwt",
+		}
+	}
+
+	if c.app == nil {
+		return nil
+	}
+	token := parseToken(c.Ctx, c.app.tokenKeys)
+	if token == "" {
+		return nil
+	}
+	if _, err := c.app.tokenData(c.Ctx, token); err != nil {
+		return nil
+	}
+	// 数字保留为 json.Number，保持数字ID的原始格式
+	data, err := c.app.tokenDataMap(c.Ctx, token)
+	if err != nil {
+		c.Debugf("Token data is not a JSON object, user unavailable: %v", err)
+		return nil
+	}
+	return userFromTokenData(data)
+}
+
+// userFromTokenData 从Token缓存数据中提取用户信息，顶层缺失的字段从 user 对象中查找
+func userFromTokenData(data map[string]any) *AuthUser {
+	nested, _ := data["user"].(map[string]any)
+	used := map[string]bool{}
+	lookup := func(field string) string {
+		for i, source := range []map[string]any{data, nested} {
+			for _, key := range tokenUserFields[field] {
+				value, ok := source[key]
+				if !ok || value == nil {
+					continue
+				}
+				if s := fmt.Sprint(value); s != "" {
+					if i == 0 {
+						used[key] = true
+					}
+					return s
+				}
+			}
+		}
+		return ""
+	}
+
+	user := &AuthUser{
+		ID:       lookup("id"),
+		Username: lookup("username"),
+		Email:    lookup("email"),
+		Role:     lookup("role"),
+		Source:   "token",
+	}
+	if user.ID == "" && user.Username == "" {
+		return nil
+	}
+	for key, value := range data {
+		if used[key] || key == "user" {
+			continue
+		}
+		if user.Extra == nil {
+			user.Extra = map[string]any{}
+		}
+		user.Extra[key] = value
+	}
+	return user
+}
@@ -0,0 +1,272 @@
+package mod
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp"
+)
+
+// BenchOptions 压测选项
+type BenchOptions struct {
+	Service     string            // 服务名称
+	Concurrency int               // 并发数，默认为 GOMAXPROCS
+	Duration    time.Duration     // 压测时长，默认10秒
+	Requests    int               // 最大请求数，大于0时达到该数量即停止
+	Warmup      int               // 预热请求数，不计入结果
+	Body        any               // 请求体，[]byte、string 原样发送，其他类型序列化为JSON；为空时使用文档中的请求示例
+	Token       string            // 认证token，服务需要认证且未指定时自动签发临时token
+	Headers     map[string]string // 额外请求头
+}
+
+// BenchResult 压测结果
+type BenchResult struct {
+	Service     string        `json:"service"`
+	Concurrency int           `json:"concurrency"`
+	Requests    int           `json:"requests"`
+	Errors      int           `json:"errors"` // HTTP状态码不小于400的请求数
+	Duration    time.Duration `json:"duration"`
+	QPS         float64       `json:"qps"`
+	Min         time.Duration `json:"min"`
+	Mean        time.Duration `json:"mean"`
+	P50         time.Duration `json:"p50"`
+	P90         time.Duration `json:"p90"`
+	P99         time.Duration `json:"p99"`
+	Max         time.Duration `json:"max"`
+	AllocsPerOp uint64        `json:"allocs_per_op"` // 每个请求的平均内存分配次数（含压测自身开销）
+	BytesPerOp  uint64        `json:"bytes_per_op"`  // 每个请求的平均分配字节数
+	StatusCodes map[int]int   `json:"status_codes"`
+}
+
+// String 返回便于阅读的压测报告
+func (r *BenchResult) String() string {
+	codes := make([]int, 0, len(r.StatusCodes))
+	for code := range r.StatusCodes {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	statuses := make([]string, 0, len(codes))
+	for _, code := range codes {
+		statuses = append(statuses, fmt.Sprintf("%d×%d", code, r.StatusCodes[code]))
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "service:     %s (concurrency %d)\n", r.Service, r.Concurrency)
+	fmt.Fprintf(&b, "requests:    %d in %s, %.1f req/s, %d errors\n", r.Requests, r.Duration.Round(time.Millisecond), r.QPS, r.Errors)
+	fmt.Fprintf(&b, "latency:     min %s  mean %s  p50 %s  p90 %s  p99 %s  max %s\n", r.Min, r.Mean, r.P50, r.P90, r.P99, r.Max)
+	fmt.Fprintf(&b, "allocations: %d allocs/op, %d B/op\n", r.AllocsPerOp, r.BytesPerOp)
+	fmt.Fprintf(&b, "status:      %s", strings.Join(statuses, "  "))
+	return b.String()
+}
+
+// Bench 在进程内以指定并发数和时长压测服务，请求直接交给 fiber 处理，不经过网络
+func (app *App) Bench(service string, concurrency int, duration time.Duration) (*BenchResult, error) {
+	return app.BenchWithOptions(BenchOptions{
+		Service:     service,
+		Concurrency: concurrency,
+		Duration:    duration,
+	})
+}
+
+// BenchWithOptions 按选项压测服务
+func (app *App) BenchWithOptions(opts BenchOptions) (*BenchResult, error) {
+	svc, ok := app.GetService(opts.Service)
+	if !ok {
+		return nil, fmt.Errorf("service %s not registered", opts.Service)
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = runtime.GOMAXPROCS(0)
+	}
+	if opts.Duration <= 0 && opts.Requests <= 0 {
+		opts.Duration = 10 * time.Second
+	}
+
+	body, err := app.benchBody(&svc, opts.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	token := opts.Token
+	if token == "" && (svc.owner.authStrategy(&svc) != AuthNone || svc.Permission != nil) {
+		client := app.TestClient().WithUserFactory(func(*App) (*TestUser, error) {
+			return &TestUser{ID: "bench", Username: "bench"}, nil
+		})
+		if token, err = client.Login(); err != nil {
+			return nil, err
+		}
+		defer client.Logout()
+	}
+
+	var req fasthttp.Request
+	req.Header.SetMethod(fiber.MethodPost)
+	req.SetRequestURI(svc.requestPath(body))
+	req.Header.SetContentType(fiber.MIMEApplicationJSON)
+	if token != "" {
+		req.Header.Set(fiber.HeaderAuthorization, "Bearer "+token)
+	}
+	for k, v := range opts.Headers {
+		req.Header.Set(k, v)
+	}
+	req.SetBody(body)
+
+	handler := app.Handler()
+	remoteAddr := &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)}
+
+	// 预热，避免首次请求的初始化开销影响结果
+	if opts.Warmup > 0 {
+		var fctx fasthttp.RequestCtx
+		fctx.Init(&req, remoteAddr, nil)
+		for i := 0; i < opts.Warmup; i++ {
+			fctx.Response.Reset()
+			fctx.ResetUserValues()
+			handler(&fctx)
+		}
+	}
+
+	var (
+		issued   int64
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		all      []time.Duration
+		statuses = map[int]int{}
+	)
+
+	// 每个并发使用独立的请求上下文，在启动前完成复制，避免并发读取共享请求
+	contexts := make([]*fasthttp.RequestCtx, opts.Concurrency)
+	for w := range contexts {
+		contexts[w] = &fasthttp.RequestCtx{}
+		contexts[w].Init(&req, remoteAddr, nil)
+	}
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	start := time.Now()
+
+	deadline := time.Time{}
+	if opts.Duration > 0 {
+		deadline = start.Add(opts.Duration)
+	}
+
+	for _, fctx := range contexts {
+		wg.Add(1)
+		go func(fctx *fasthttp.RequestCtx) {
+			defer wg.Done()
+
+			latencies := make([]time.Duration, 0, 1024)
+			codes := map[int]int{}
+
+			for {
+				if opts.Requests > 0 && atomic.AddInt64(&issued, 1) > int64(opts.Requests) {
+					break
+				}
+				if !deadline.IsZero() && time.Now().After(deadline) {
+					break
+				}
+
+				fctx.Response.Reset()
+				fctx.ResetUserValues()
+
+				begin := time.Now()
+				handler(fctx)
+				latencies = append(latencies, time.Since(begin))
+				codes[fctx.Response.StatusCode()]++
+			}
+
+			mu.Lock()
+			all = append(all, latencies...)
+			for code, n := range codes {
+				statuses[code] += n
+			}
+			mu.Unlock()
+		}(fctx)
+	}
+	wg.Wait()
+
+	elapsed := time.Since(start)
+	runtime.ReadMemStats(&after)
+
+	result := &BenchResult{
+		Service:     svc.Name,
+		Concurrency: opts.Concurrency,
+		Requests:    len(all),
+		Duration:    elapsed,
+		StatusCodes: statuses,
+	}
+	for code, n := range statuses {
+		if code >= 400 {
+			result.Errors += n
+		}
+	}
+	if len(all) == 0 {
+		return result, nil
+	}
+
+	sort.Slice(all, func(i, j int) bool { return all[i] < all[j] })
+	var total time.Duration
+	for _, d := range all {
+		total += d
+	}
+	n := len(all)
+	result.QPS = float64(n) / elapsed.Seconds()
+	result.Min = all[0]
+	result.Max = all[n-1]
+	result.Mean = total / time.Duration(n)
+	result.P50 = benchPercentile(all, 0.50)
+	result.P90 = benchPercentile(all, 0.90)
+	result.P99 = benchPercentile(all, 0.99)
+	result.AllocsPerOp = (after.Mallocs - before.Mallocs) / uint64(n)
+	result.BytesPerOp = (after.TotalAlloc - before.TotalAlloc) / uint64(n)
+
+	app.logger.WithFields(map[string]any{
+		"service":  svc.Name,
+		"requests": n,
+		"qps":      fmt.Sprintf("%.1f", result.QPS),
+		"p99":      result.P99.String(),
+		"errors":   result.Errors,
+	}).Info("Benchmark finished")
+
+	return result, nil
+}
+
+// benchBody 生成压测请求体
+func (app *App) benchBody(svc *Service, body any) ([]byte, error) {
+	switch v := body.(type) {
+	case nil:
+		request, _ := app.generateDocExamples(svc)
+		if request == "" {
+			return []byte("{}"), nil
+		}
+		return []byte(request), nil
+	case []byte:
+		return v, nil
+	case string:
+		return []byte(v), nil
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal bench body: %w", err)
+		}
+		return data, nil
+	}
+}
+
+// benchPercentile 返回已排序耗时的百分位数
+func benchPercentile(sorted []time.Duration, p float64) time.Duration {
+	idx := int(float64(len(sorted))*p+0.5) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	if idx >= len(sorted) {
+		idx = len(sorted) - 1
+	}
+	return sorted[idx]
+}
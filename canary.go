@@ -0,0 +1,225 @@
+package mod
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// HeaderCanaryVersion 响应头，标明处理请求的服务版本
+const HeaderCanaryVersion = "X-Canary-Version"
+
+// CanaryVersionStats 多版本服务中单个版本的流量统计
+type CanaryVersionStats struct {
+	Version    string        `json:"version"`
+	Weight     int           `json:"weight"`      // 当前权重
+	Requests   int64         `json:"requests"`    // 请求次数
+	Pinned     int64         `json:"pinned"`      // 通过请求头或Cookie指定版本的请求次数
+	Errors     int64         `json:"errors"`      // 处理失败或响应5xx的次数
+	AvgLatency time.Duration `json:"avg_latency"` // 平均耗时
+}
+
+// canaryRoute 同名服务多个版本共用的路由
+type canaryRoute struct {
+	app      *App
+	name     string
+	mu       sync.RWMutex
+	versions []*canaryVersion
+	weights  map[string]int // 运行时设置的权重，为 nil 时使用配置
+}
+
+// canaryVersion 服务的一个版本
+type canaryVersion struct {
+	version  string
+	handler  fiber.Handler
+	requests atomic.Int64
+	pinned   atomic.Int64
+	errors   atomic.Int64
+	latency  atomic.Int64
+}
+
+// canaryRoute 返回服务的灰度路由，服务未注册多个版本时返回 nil
+func (app *App) canaryRoute(name string) *canaryRoute {
+	app.canaryMu.RLock()
+	defer app.canaryMu.RUnlock()
+	return app.canaryRoutes[name]
+}
+
+// newCanaryRoute 为首个带版本的服务创建灰度路由
+func (app *App) newCanaryRoute(svc *Service, handler fiber.Handler) *canaryRoute {
+	route := &canaryRoute{app: app, name: svc.Name}
+	route.add(svc, handler)
+
+	app.canaryMu.Lock()
+	if app.canaryRoutes == nil {
+		app.canaryRoutes = map[string]*canaryRoute{}
+	}
+	app.canaryRoutes[svc.Name] = route
+	app.canaryMu.Unlock()
+	return route
+}
+
+// add 添加服务版本
+func (r *canaryRoute) add(svc *Service, handler fiber.Handler) {
+	r.mu.Lock()
+	r.versions = append(r.versions, &canaryVersion{version: svc.Version, handler: handler})
+	r.mu.Unlock()
+}
+
+// version 按版本号查找
+func (r *canaryRoute) version(version string) *canaryVersion {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, v := range r.versions {
+		if v.version == version {
+			return v
+		}
+	}
+	return nil
+}
+
+// weightOf 返回版本的当前权重：运行时设置优先于配置，均未设置时全部流量进入最先注册的版本
+func (r *canaryRoute) weightOf(i int) int {
+	weights := r.weights
+	if weights == nil {
+		weights = r.app.cfg.ModConfig.Canary.Services[r.name]
+	}
+	if weights == nil {
+		if i == 0 {
+			return 100
+		}
+		return 0
+	}
+	return max(weights[r.versions[i].version], 0)
+}
+
+// pick 选择处理请求的版本，请求头或Cookie指定了已注册的版本时 pinned 为 true
+func (r *canaryRoute) pick(fc *fiber.Ctx) (v *canaryVersion, pinned bool) {
+	config := r.app.cfg.ModConfig.Canary
+	header, cookie := config.Header, config.Cookie
+	if header == "" {
+		header = "X-Canary"
+	}
+	if cookie == "" {
+		cookie = "canary"
+	}
+	for _, requested := range []string{fc.Get(header), fc.Cookies(cookie)} {
+		if requested == "" {
+			continue
+		}
+		if v := r.version(requested); v != nil {
+			return v, true
+		}
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	total := 0
+	for i := range r.versions {
+		total += r.weightOf(i)
+	}
+	if total <= 0 {
+		return r.versions[0], false
+	}
+	n := rand.Intn(total)
+	for i, v := range r.versions {
+		if n -= r.weightOf(i); n < 0 {
+			return v, false
+		}
+	}
+	return r.versions[len(r.versions)-1], false
+}
+
+// serve 按灰度规则分发请求并记录各版本的统计
+func (r *canaryRoute) serve(fc *fiber.Ctx) error {
+	v, pinned := r.pick(fc)
+	if pinned {
+		v.pinned.Add(1)
+	} else if config := r.app.cfg.ModConfig.Canary; config.Sticky {
+		cookie := config.Cookie
+		if cookie == "" {
+			cookie = "canary"
+		}
+		fc.Cookie(&fiber.Cookie{Name: cookie, Value: v.version, Path: "/", HTTPOnly: true})
+	}
+	fc.Set(HeaderCanaryVersion, v.version)
+
+	start := time.Now()
+	err := v.handler(fc)
+	v.requests.Add(1)
+	v.latency.Add(int64(time.Since(start)))
+	if err != nil || fc.Response().StatusCode() >= fiber.StatusInternalServerError {
+		v.errors.Add(1)
+	}
+	return err
+}
+
+// SetCanaryWeights 运行时调整多版本服务的流量权重（版本 -> 权重），未列出的版本不再接收按权重分配的流量，
+// weights 为 nil 时恢复 mod.yml 中的配置
+func (app *App) SetCanaryWeights(name string, weights map[string]int) error {
+	route := app.canaryRoute(name)
+	if route == nil {
+		return fmt.Errorf("service %q: no versions registered", name)
+	}
+	for version, weight := range weights {
+		if route.version(version) == nil {
+			return fmt.Errorf("service %q: version %q is not registered", name, version)
+		}
+		if weight < 0 {
+			return fmt.Errorf("service %q: weight of version %q must not be negative", name, version)
+		}
+	}
+
+	var copied map[string]int
+	if weights != nil {
+		copied = make(map[string]int, len(weights))
+		for version, weight := range weights {
+			copied[version] = weight
+		}
+	}
+	route.mu.Lock()
+	route.weights = copied
+	route.mu.Unlock()
+
+	app.logger.WithFields(map[string]any{
+		"service": name,
+		"weights": copied,
+	}).Info("Canary weights updated")
+	return nil
+}
+
+// CanaryStats 返回多版本服务各版本的权重和流量统计，版本按注册顺序排列
+func (app *App) CanaryStats() map[string][]CanaryVersionStats {
+	app.canaryMu.RLock()
+	routes := make([]*canaryRoute, 0, len(app.canaryRoutes))
+	for _, route := range app.canaryRoutes {
+		routes = append(routes, route)
+	}
+	app.canaryMu.RUnlock()
+
+	result := make(map[string][]CanaryVersionStats, len(routes))
+	for _, route := range routes {
+		route.mu.RLock()
+		stats := make([]CanaryVersionStats, 0, len(route.versions))
+		for i, v := range route.versions {
+			s := CanaryVersionStats{
+				Version:  v.version,
+				Weight:   route.weightOf(i),
+				Requests: v.requests.Load(),
+				Pinned:   v.pinned.Load(),
+				Errors:   v.errors.Load(),
+			}
+			if s.Requests > 0 {
+				s.AvgLatency = time.Duration(v.latency.Load() / s.Requests)
+			}
+			stats = append(stats, s)
+		}
+		route.mu.RUnlock()
+		result[route.name] = stats
+	}
+	return result
+}
@@ -0,0 +1,100 @@
+package mod
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+var (
+	// ErrClientDisconnected 客户端在服务处理完成前断开连接，可通过 context.Cause(ctx) 判断
+	ErrClientDisconnected = errors.New("client disconnected")
+	// ErrServiceTimeout 服务处理超过 Service.Timeout，可通过 context.Cause(ctx) 判断
+	ErrServiceTimeout = errors.New("service timeout")
+)
+
+// disconnectPollInterval 检测客户端断开连接的间隔
+const disconnectPollInterval = 100 * time.Millisecond
+
+// MakeHandlerCtx 创建接收 context.Context 的 Handler，客户端断开连接或超过 Service.Timeout 时 ctx 被取消，
+// 可直接传递给数据库查询、外部请求等支持取消的调用
+func MakeHandlerCtx[I any, O any](handler func(ctx context.Context, mctx *Context, args *I, reply *O) error) Handler {
+	return Handler{
+		Func: func(mctx *Context, args any, reply any) error {
+			a, ok := args.(*I)
+			if !ok {
+				return fmt.Errorf("invalid args type")
+			}
+			r, ok := reply.(*O)
+			if !ok {
+				return fmt.Errorf("invalid reply type")
+			}
+			return handler(mctx.UserContext(), mctx, a, r)
+		},
+		InputType:  reflect.TypeOf((*I)(nil)).Elem(),
+		OutputType: reflect.TypeOf((*O)(nil)).Elem(),
+	}
+}
+
+// bindRequestContext 为本次服务调用创建可取消的 context.Context 并设置为 Fiber 的 UserContext，
+// 处理函数通过 ctx.UserContext() 获取；返回的函数用于在处理结束后释放资源
+func (app *App) bindRequestContext(fc *fiber.Ctx, svc *Service) func() {
+	original := fc.UserContext()
+	reqCtx, cancel := context.WithCancelCause(original)
+	cancelTimeout := context.CancelFunc(func() {})
+	if svc.Timeout > 0 {
+		reqCtx, cancelTimeout = context.WithTimeoutCause(reqCtx, svc.Timeout, ErrServiceTimeout)
+	}
+	stopWatch := watchDisconnect(fc.Context().Conn(), func() { cancel(ErrClientDisconnected) })
+
+	fc.SetUserContext(reqCtx)
+	return func() {
+		stopWatch()
+		cancelTimeout()
+		cancel(nil)
+		fc.SetUserContext(original)
+	}
+}
+
+// watchDisconnect 在处理期间定期检测客户端连接是否已关闭，关闭时调用 onClose；
+// 连接不支持检测时（如测试使用的内存连接）不启动检测
+func watchDisconnect(conn net.Conn, onClose func()) func() {
+	if conn == nil || !canDetectClose(conn) {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(disconnectPollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if connClosed(conn) {
+					onClose()
+					return
+				}
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// cancelStatus 处理函数因取消返回错误时的响应状态码和消息
+func cancelStatus(ctx context.Context) (int, string, bool) {
+	switch context.Cause(ctx) {
+	case ErrServiceTimeout:
+		return fiber.StatusGatewayTimeout, "Service timeout", true
+	case ErrClientDisconnected:
+		// 客户端已断开，响应不会被接收，仅用于日志记录
+		return 499, "Client disconnected", true
+	}
+	return 0, "", false
+}
@@ -0,0 +1,422 @@
+package mod
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+)
+
+// captureGroup 请求捕获管理服务所在的分组
+const captureGroup = "请求捕获"
+
+// captureKeyPrefix 快照在BadgerDB中的键前缀
+const captureKeyPrefix = "capture:"
+
+// HeaderReplay 重放请求携带的请求头（值为快照ID），重放请求不会被再次捕获
+const HeaderReplay = "X-Mod-Replay"
+
+// CapturedRequest 失败请求的完整快照
+type CapturedRequest struct {
+	ID                string              `json:"id" desc:"快照ID，与请求的rid相同"`
+	Service           string              `json:"service" desc:"服务名称"`
+	Version           string              `json:"version,omitempty" desc:"服务版本"`
+	Method            string              `json:"method" desc:"请求方法"`
+	URL               string              `json:"url" desc:"请求路径及查询参数"`
+	Headers           map[string][]string `json:"headers" desc:"请求头（已去除 redact_headers）"`
+	Body              string              `json:"body" desc:"请求体"`
+	BodyTruncated     bool                `json:"body_truncated,omitempty" desc:"请求体是否被截断"`
+	IP                string              `json:"ip" desc:"客户端IP"`
+	User              *AuthUser           `json:"user,omitempty" desc:"当前用户"`
+	Status            int                 `json:"status" desc:"响应状态码"`
+	Response          string              `json:"response" desc:"响应体"`
+	ResponseTruncated bool                `json:"response_truncated,omitempty" desc:"响应体是否被截断"`
+	Duration          time.Duration       `json:"duration" desc:"处理耗时"`
+	CapturedAt        time.Time           `json:"captured_at" desc:"捕获时间"`
+}
+
+// CaptureSummary 快照列表项
+type CaptureSummary struct {
+	ID         string    `json:"id" desc:"快照ID"`
+	Service    string    `json:"service" desc:"服务名称"`
+	URL        string    `json:"url" desc:"请求路径"`
+	Status     int       `json:"status" desc:"响应状态码"`
+	UserID     string    `json:"user_id,omitempty" desc:"用户ID"`
+	CapturedAt time.Time `json:"captured_at" desc:"捕获时间"`
+}
+
+// CaptureListRequest 查询快照列表请求
+type CaptureListRequest struct {
+	Service string `json:"service" desc:"按服务名称过滤"`
+	Limit   int    `json:"limit" desc:"返回数量，默认20"`
+	Offset  int    `json:"offset" desc:"偏移量"`
+}
+
+// CaptureListResponse 快照列表响应，按捕获时间倒序
+type CaptureListResponse struct {
+	Items []CaptureSummary `json:"items" desc:"快照列表"`
+	Total int              `json:"total" desc:"符合条件的快照总数"`
+}
+
+// CaptureGetRequest 按ID查询或删除快照请求
+type CaptureGetRequest struct {
+	ID string `json:"id" validate:"required" desc:"快照ID（请求的rid）"`
+}
+
+// CaptureReplayRequest 重放快照请求
+type CaptureReplayRequest struct {
+	ID      string            `json:"id" validate:"required" desc:"快照ID（请求的rid）"`
+	Token   string            `json:"token" desc:"重放使用的认证token，快照不保存原请求的token"`
+	Headers map[string]string `json:"headers" desc:"覆盖或追加的请求头"`
+}
+
+// CaptureReplayResponse 重放结果
+type CaptureReplayResponse struct {
+	OriginalStatus int                 `json:"original_status" desc:"原请求的响应状态码"`
+	Status         int                 `json:"status" desc:"重放的响应状态码"`
+	Headers        map[string][]string `json:"headers" desc:"重放的响应头"`
+	Body           string              `json:"body" desc:"重放的响应体"`
+	Duration       time.Duration       `json:"duration" desc:"重放耗时"`
+}
+
+// CaptureDeleteResponse 删除快照响应
+type CaptureDeleteResponse struct {
+	Deleted bool `json:"deleted" desc:"是否已删除"`
+}
+
+// configureCapture 初始化请求捕获存储并注册捕获管理服务
+func (app *App) configureCapture() {
+	config := app.cfg.ModConfig.Capture
+	if !config.Enabled {
+		return
+	}
+
+	path := config.Path
+	if path == "" {
+		path = "./data/captures" // 默认路径
+	}
+	opts := badger.DefaultOptions(path)
+	opts.Logger = &badgerLogger{logger: app.logger}
+	opts.InMemory = config.InMemory
+	if config.InMemory {
+		opts.Dir = ""
+		opts.ValueDir = ""
+	}
+	start := time.Now()
+	db, err := badger.Open(opts)
+	app.recordStartup("capture", path, start, err)
+	if err != nil {
+		app.logger.WithError(err).WithField("path", path).Error("Failed to initialize capture store")
+		return
+	}
+	app.captureDB = db
+	app.logger.WithField("path", path).Info("Request capture store initialized successfully")
+
+	services := []Service{
+		{
+			Name:        "capture_list",
+			DisplayName: "捕获的请求",
+			Description: "按捕获时间倒序列出失败请求的快照",
+			Group:       captureGroup,
+			Sort:        1,
+			SkipAuth:    config.SkipAuth,
+			Handler: MakeHandler(func(ctx *Context, req *CaptureListRequest, resp *CaptureListResponse) error {
+				items, total, err := app.ListCaptures(req.Service, req.Limit, req.Offset)
+				if err != nil {
+					return err
+				}
+				resp.Items, resp.Total = items, total
+				return nil
+			}),
+		},
+		{
+			Name:        "capture_get",
+			DisplayName: "请求快照详情",
+			Description: "按rid查询失败请求的完整快照",
+			Group:       captureGroup,
+			Sort:        2,
+			SkipAuth:    config.SkipAuth,
+			Handler: MakeHandler(func(ctx *Context, req *CaptureGetRequest, resp *CapturedRequest) error {
+				captured, err := app.GetCapture(req.ID)
+				if err != nil {
+					return err
+				}
+				*resp = *captured
+				return nil
+			}),
+		},
+		{
+			Name:        "capture_replay",
+			DisplayName: "重放请求",
+			Description: "使用快照中的请求头和请求体向当前版本重新发起请求，用于复现线上问题",
+			Group:       captureGroup,
+			Sort:        3,
+			SkipAuth:    config.SkipAuth,
+			Handler: MakeHandler(func(ctx *Context, req *CaptureReplayRequest, resp *CaptureReplayResponse) error {
+				result, err := app.ReplayCapture(req.ID, req.Token, req.Headers)
+				if err != nil {
+					return err
+				}
+				*resp = *result
+				return nil
+			}),
+		},
+		{
+			Name:        "capture_delete",
+			DisplayName: "删除请求快照",
+			Description: "删除指定的请求快照",
+			Group:       captureGroup,
+			Sort:        4,
+			SkipAuth:    config.SkipAuth,
+			Handler: MakeHandler(func(ctx *Context, req *CaptureGetRequest, resp *CaptureDeleteResponse) error {
+				if err := app.DeleteCapture(req.ID); err != nil {
+					return err
+				}
+				resp.Deleted = true
+				return nil
+			}),
+		},
+	}
+
+	for _, svc := range services {
+		if err := app.Register(app.adminService(svc)); err != nil {
+			app.logger.WithError(err).WithField("service", svc.Name).Error("Failed to register capture service")
+		}
+	}
+}
+
+// captureRequest 响应状态码达到 min_status 时保存请求快照，在服务处理函数返回后调用
+func (app *App) captureRequest(ctx *Context, start time.Time) {
+	if app.captureDB == nil || ctx.service == nil || ctx.service.Group == captureGroup {
+		return
+	}
+	config := app.cfg.ModConfig.Capture
+	minStatus := config.MinStatus
+	if minStatus <= 0 {
+		minStatus = 500
+	}
+	status := ctx.Response().StatusCode()
+	if status < minStatus || ctx.Get(HeaderReplay) != "" {
+		return
+	}
+	if len(config.Services) > 0 && !slices.Contains(config.Services, ctx.service.Name) {
+		return
+	}
+
+	maxBody := int64(1024 * 1024)
+	if config.MaxBodySize != "" {
+		if size, err := parseSize(config.MaxBodySize); err == nil && size > 0 {
+			maxBody = size
+		}
+	}
+	ttl := 72 * time.Hour
+	if config.TTL != "" {
+		if d, err := time.ParseDuration(config.TTL); err == nil && d > 0 {
+			ttl = d
+		}
+	}
+	redact := config.RedactHeaders
+	if len(redact) == 0 {
+		signature, timestamp := app.signatureHeaders()
+		redact = append(append([]string{}, app.tokenKeys...), "Cookie", app.apiKeyHeader(), signature, timestamp)
+	}
+
+	captured := &CapturedRequest{
+		ID:         ctx.GetRequestID(),
+		Service:    ctx.service.Name,
+		Version:    ctx.service.Version,
+		Method:     ctx.Method(),
+		URL:        ctx.OriginalURL(),
+		Headers:    map[string][]string{},
+		IP:         ctx.IP(),
+		Status:     status,
+		Duration:   time.Since(start),
+		CapturedAt: time.Now(),
+	}
+	ctx.Request().Header.VisitAll(func(key, value []byte) {
+		name := string(key)
+		for _, r := range redact {
+			if strings.EqualFold(r, name) {
+				return
+			}
+		}
+		captured.Headers[name] = append(captured.Headers[name], string(value))
+	})
+	captured.Body, captured.BodyTruncated = truncateCapture(ctx.Body(), maxBody)
+	captured.Response, captured.ResponseTruncated = truncateCapture(ctx.Response().Body(), maxBody)
+	if user, ok := ctx.User(); ok {
+		captured.User = user
+	}
+
+	data, err := json.Marshal(captured)
+	if err == nil {
+		err = app.captureDB.Update(func(txn *badger.Txn) error {
+			return txn.SetEntry(badger.NewEntry([]byte(captureKeyPrefix+captured.ID), data).WithTTL(ttl))
+		})
+	}
+	if err != nil {
+		app.logger.WithError(err).WithField("rid", captured.ID).Warn("Failed to capture request")
+		return
+	}
+	app.logger.WithFields(map[string]any{
+		"service": captured.Service,
+		"rid":     captured.ID,
+		"status":  status,
+	}).Info("Request captured")
+}
+
+// truncateCapture 截断超过 limit 的内容
+func truncateCapture(data []byte, limit int64) (string, bool) {
+	if int64(len(data)) > limit {
+		return string(data[:limit]), true
+	}
+	return string(data), false
+}
+
+// GetCapture 按ID（请求的rid）查询请求快照
+func (app *App) GetCapture(id string) (*CapturedRequest, error) {
+	if app.captureDB == nil {
+		return nil, Reply(400, "请求捕获未启用")
+	}
+	var captured CapturedRequest
+	err := app.captureDB.View(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(captureKeyPrefix + id))
+		if err != nil {
+			return err
+		}
+		return item.Value(func(val []byte) error {
+			return json.Unmarshal(val, &captured)
+		})
+	})
+	if err == badger.ErrKeyNotFound {
+		return nil, Reply(404, "请求快照不存在")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &captured, nil
+}
+
+// ListCaptures 按捕获时间倒序列出请求快照，service 为空时返回全部服务的快照
+func (app *App) ListCaptures(service string, limit, offset int) ([]CaptureSummary, int, error) {
+	if app.captureDB == nil {
+		return nil, 0, Reply(400, "请求捕获未启用")
+	}
+	if limit <= 0 {
+		limit = 20
+	}
+
+	var items []CaptureSummary
+	err := app.captureDB.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+		prefix := []byte(captureKeyPrefix)
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			var captured CapturedRequest
+			if err := it.Item().Value(func(val []byte) error {
+				return json.Unmarshal(val, &captured)
+			}); err != nil {
+				continue
+			}
+			if service != "" && captured.Service != service {
+				continue
+			}
+			summary := CaptureSummary{
+				ID:         captured.ID,
+				Service:    captured.Service,
+				URL:        captured.URL,
+				Status:     captured.Status,
+				CapturedAt: captured.CapturedAt,
+			}
+			if captured.User != nil {
+				summary.UserID = captured.User.ID
+			}
+			items = append(items, summary)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	slices.SortFunc(items, func(a, b CaptureSummary) int {
+		return b.CapturedAt.Compare(a.CapturedAt)
+	})
+	total := len(items)
+	if offset >= total {
+		return []CaptureSummary{}, total, nil
+	}
+	items = items[offset:]
+	if limit < len(items) {
+		items = items[:limit]
+	}
+	return items, total, nil
+}
+
+// DeleteCapture 删除请求快照
+func (app *App) DeleteCapture(id string) error {
+	if app.captureDB == nil {
+		return Reply(400, "请求捕获未启用")
+	}
+	return app.captureDB.Update(func(txn *badger.Txn) error {
+		return txn.Delete([]byte(captureKeyPrefix + id))
+	})
+}
+
+// ReplayCapture 使用快照中的请求头和请求体向当前版本重新发起请求。快照不保存认证信息，
+// 需要认证的服务通过 token 指定重放使用的token，headers 覆盖或追加请求头
+func (app *App) ReplayCapture(id, token string, headers map[string]string) (*CaptureReplayResponse, error) {
+	captured, err := app.GetCapture(id)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(captured.Method, captured.URL, strings.NewReader(captured.Body))
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range captured.Headers {
+		for _, value := range values {
+			req.Header.Add(name, value)
+		}
+	}
+	req.Header.Del("Content-Length")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	req.Header.Set(HeaderReplay, id)
+
+	start := time.Now()
+	resp, err := app.root().Test(req, -1)
+	if err != nil {
+		return nil, fmt.Errorf("replay %s: %w", id, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	app.logger.WithFields(map[string]any{
+		"rid":             id,
+		"service":         captured.Service,
+		"original_status": captured.Status,
+		"status":          resp.StatusCode,
+	}).Info("Captured request replayed")
+	return &CaptureReplayResponse{
+		OriginalStatus: captured.Status,
+		Status:         resp.StatusCode,
+		Headers:        resp.Header,
+		Body:           string(bytes.TrimSpace(body)),
+		Duration:       time.Since(start),
+	}, nil
+}
@@ -0,0 +1,66 @@
+package mod
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+const captureConfig = `
+capture:
+  enabled: true
+  in_memory: true
+`
+
+func TestCaptureRequiresAdmin(t *testing.T) {
+	app := newTestApp(t, tokenCacheConfig+captureConfig)
+	assertAdminService(t, app, "capture_list", CaptureListRequest{})
+	assertAdminService(t, app, "capture_get", CaptureGetRequest{ID: "missing"})
+	assertAdminService(t, app, "capture_replay", CaptureReplayRequest{ID: "missing"})
+	assertAdminService(t, app, "capture_delete", CaptureGetRequest{ID: "missing"})
+}
+
+func TestCaptureRedactsCredentials(t *testing.T) {
+	app := newTestApp(t, captureConfig+`
+auth:
+  api_key_header: "X-Partner-Key"
+`)
+	err := app.Register(Service{
+		Name:        "failing",
+		DisplayName: "failing",
+		SkipAuth:    true,
+		Handler: MakeHandler(func(ctx *Context, req *pingRequest, resp *pingResponse) error {
+			return Reply(500, "boom")
+		}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := app.TestClient().
+		WithHeader("X-Partner-Key", "secret-key").
+		WithHeader("X-Signature", "secret-signature").
+		WithHeader("X-Timestamp", "1700000000").
+		WithHeader("X-Trace", "kept").
+		Call("failing", pingRequest{Value: "x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.AssertStatus(t, 500)
+	var envelope ApiResponse
+	if err := json.Unmarshal(resp.Body, &envelope); err != nil {
+		t.Fatal(err)
+	}
+
+	captured, err := app.GetCapture(envelope.Rid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"X-Partner-Key", "X-Signature", "X-Timestamp"} {
+		if _, ok := captured.Headers[name]; ok {
+			t.Fatalf("header %s must be redacted: %v", name, captured.Headers)
+		}
+	}
+	if captured.Headers["X-Trace"] == nil {
+		t.Fatalf("expected other headers to be kept: %v", captured.Headers)
+	}
+}
@@ -0,0 +1,286 @@
+package mod
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// HeaderTenantID 服务间调用透传租户ID的请求头
+const HeaderTenantID = "X-Tenant-ID"
+
+// defaultServiceClient 未关联应用且未指定 HTTP 客户端时使用
+var defaultServiceClient = &http.Client{Timeout: 30 * time.Second}
+
+// Client 调用其他 mod 服务的客户端，使用 ctx.Outgoing() 作为上下文时自动透传请求ID、链路追踪头、租户和认证token。
+// 可以在多个 goroutine 中共用，With 系列方法应在创建后立即调用
+type Client struct {
+	baseURL    string
+	token      string
+	headers    map[string]string
+	httpClient *http.Client
+}
+
+// ClientCall 服务的调用元数据，由生成的客户端代码提供
+type ClientCall struct {
+	Service string      // 服务名称
+	Raw     bool        // 服务直接返回数据，不使用标准响应格式
+	Params  []CallParam // 通过查询参数、请求头或路径参数发送的字段
+	Path    string      // 挂载的子应用服务或路径模板服务的完整路径，为空时使用 baseURL/服务名称
+	Base    string      // 目标应用的服务前缀（app.service_base），Path 相对于 baseURL 去掉该前缀后的根地址
+}
+
+// CallParam 不在JSON请求体中发送的字段
+type CallParam struct {
+	Key  string // 请求结构体中的JSON字段名
+	In   string // query、header 或 param
+	Name string // 参数名
+}
+
+// propagationKey 透传信息在 context.Context 中的键
+type propagationKey struct{}
+
+// propagation ctx.Outgoing() 复制的透传信息，请求结束后仍可使用
+type propagation struct {
+	app     *App
+	header  http.Header
+	service string
+	rid     string
+}
+
+// NewClient 创建调用其他 mod 服务的客户端，baseURL 为目标服务前缀的完整地址，如 http://user-service:8080/services
+func NewClient(baseURL string) *Client {
+	return &Client{baseURL: strings.TrimRight(baseURL, "/"), headers: map[string]string{}}
+}
+
+// WithToken 使用固定的token（如服务账号）调用，替代透传的调用方token
+func (cl *Client) WithToken(token string) *Client {
+	cl.token = token
+	return cl
+}
+
+// WithHeader 每个请求附加的请求头
+func (cl *Client) WithHeader(key, value string) *Client {
+	cl.headers[key] = value
+	return cl
+}
+
+// WithHTTPClient 使用指定的 HTTP 客户端，默认使用 ctx.HTTP() 的连接池、重试和熔断
+func (cl *Client) WithHTTPClient(client *http.Client) *Client {
+	cl.httpClient = client
+	return cl
+}
+
+// Outgoing 返回调用其他 mod 服务时使用的上下文：随本次服务调用取消，并携带请求ID、链路追踪头（http_client.propagate_headers）、
+// 租户ID和调用方token，由 mod.Client 自动透传。透传信息在调用时复制，在 ctx.Go 中使用时配合 context.WithoutCancel
+func (c *Context) Outgoing() context.Context {
+	p := &propagation{header: make(http.Header), rid: c.GetRequestID()}
+	if c.service != nil {
+		p.service = c.service.Name
+	}
+	if p.rid != "" {
+		p.header.Set("X-Request-ID", p.rid)
+	}
+	if c.app != nil {
+		p.app = c.app
+		for _, key := range c.app.outboundClient().propagate {
+			if value := c.Get(key); value != "" {
+				p.header.Set(key, value)
+			}
+		}
+		injectTraceHeaders(c.UserContext(), p.header)
+		tenant := c.Get(HeaderTenantID)
+		if tenant == "" && c.app.cfg.ModConfig != nil {
+			tenant = c.app.resolveFileTenant(c.Ctx)
+		}
+		if tenant != "" {
+			p.header.Set(HeaderTenantID, tenant)
+		}
+		if token := parseToken(c.Ctx, c.app.tokenKeys); token != "" {
+			p.header.Set(fiber.HeaderAuthorization, "Bearer "+token)
+		}
+	}
+	return context.WithValue(c.UserContext(), propagationKey{}, p)
+}
+
+// Call 调用服务，req 作为JSON请求体发送，成功时将响应数据解析到 reply；
+// 服务返回非0状态码时返回相同状态码和消息的 StdReply 错误，处理函数可以直接返回
+func (cl *Client) Call(ctx context.Context, service string, req, reply any) error {
+	return cl.Invoke(ctx, ClientCall{Service: service}, req, reply)
+}
+
+// Invoke 按调用元数据调用服务，生成的客户端代码使用
+func (cl *Client) Invoke(ctx context.Context, call ClientCall, req, reply any) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	p, _ := ctx.Value(propagationKey{}).(*propagation)
+
+	body, query, params, headers, err := splitCallParams(req, call.Params)
+	if err != nil {
+		return fmt.Errorf("call %s: %w", call.Service, err)
+	}
+
+	endpoint := cl.baseURL + "/" + call.Service
+	if call.Path != "" {
+		segments := strings.Split(call.Path, "/")
+		for i, segment := range segments {
+			if name, ok := strings.CutPrefix(segment, ":"); ok {
+				if value, ok := params[name]; ok {
+					segments[i] = url.PathEscape(value)
+				}
+			}
+		}
+		endpoint = strings.TrimSuffix(cl.baseURL, call.Base) + strings.Join(segments, "/")
+	}
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("call %s: %w", call.Service, err)
+	}
+	httpReq.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	if p != nil {
+		for key, values := range p.header {
+			httpReq.Header[key] = values
+		}
+	}
+	for key, value := range cl.headers {
+		httpReq.Header.Set(key, value)
+	}
+	if cl.token != "" {
+		httpReq.Header.Set(fiber.HeaderAuthorization, "Bearer "+cl.token)
+	}
+	for key, values := range headers {
+		httpReq.Header[key] = values
+	}
+
+	resp, err := cl.client(ctx, p).Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("call %s: %w", call.Service, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("call %s: %w", call.Service, err)
+	}
+	return decodeCallResponse(call, resp.StatusCode, data, reply)
+}
+
+// client 返回发起请求的 HTTP 客户端，未指定时使用应用共享的连接池、重试和熔断
+func (cl *Client) client(ctx context.Context, p *propagation) *http.Client {
+	if cl.httpClient != nil {
+		return cl.httpClient
+	}
+	if p == nil || p.app == nil {
+		return defaultServiceClient
+	}
+	outbound := p.app.outboundClient()
+	return &http.Client{
+		Transport: &managedTransport{
+			client:  outbound,
+			logger:  p.app.logger,
+			ctx:     ctx,
+			service: p.service,
+			rid:     p.rid,
+			next:    outbound.transport,
+		},
+		Timeout: outbound.timeout,
+	}
+}
+
+// splitCallParams 将请求序列化为JSON，并按调用元数据取出需要通过查询参数、路径参数或请求头发送的字段
+func splitCallParams(req any, params []CallParam) ([]byte, url.Values, map[string]string, http.Header, error) {
+	if req == nil {
+		req = struct{}{}
+	}
+	body, err := json.Marshal(req)
+	if err != nil || len(params) == 0 {
+		return body, nil, nil, nil, err
+	}
+
+	var fields map[string]any
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&fields); err != nil {
+		// 请求不是JSON对象，没有可以拆分的字段
+		return body, nil, nil, nil, nil
+	}
+	query := url.Values{}
+	pathParams := map[string]string{}
+	headers := http.Header{}
+	for _, param := range params {
+		value, ok := fields[param.Key]
+		if !ok || value == nil || value == "" {
+			continue
+		}
+		text := ""
+		switch v := value.(type) {
+		case string:
+			text = v
+		case json.Number:
+			text = v.String()
+		default:
+			raw, _ := json.Marshal(v)
+			text = string(raw)
+		}
+		switch param.In {
+		case "header":
+			headers.Set(param.Name, text)
+		case "param":
+			pathParams[param.Name] = text
+		default:
+			query.Set(param.Name, text)
+		}
+	}
+	return body, query, pathParams, headers, nil
+}
+
+// decodeCallResponse 解析服务响应，非0状态码或HTTP错误时返回 StdReply 错误
+func decodeCallResponse(call ClientCall, status int, data []byte, reply any) error {
+	if call.Raw && status < http.StatusBadRequest {
+		if reply == nil || len(data) == 0 {
+			return nil
+		}
+		if err := json.Unmarshal(data, reply); err != nil {
+			return fmt.Errorf("call %s: failed to decode response: %w", call.Service, err)
+		}
+		return nil
+	}
+
+	var envelope struct {
+		Code   *int            `json:"code"`
+		Data   json.RawMessage `json:"data"`
+		Msg    string          `json:"msg"`
+		Detail string          `json:"detail"`
+	}
+	if err := json.Unmarshal(data, &envelope); err != nil || envelope.Code == nil {
+		if status >= http.StatusBadRequest {
+			return ReplyWithDetail(status, http.StatusText(status), fmt.Sprintf("call %s: unexpected response", call.Service))
+		}
+		return fmt.Errorf("call %s: unexpected response (HTTP %d)", call.Service, status)
+	}
+	if *envelope.Code != 0 {
+		return ReplyWithDetail(*envelope.Code, envelope.Msg, envelope.Detail)
+	}
+	if status >= http.StatusBadRequest {
+		return ReplyWithDetail(status, envelope.Msg, envelope.Detail)
+	}
+	if reply == nil || len(envelope.Data) == 0 || string(envelope.Data) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(envelope.Data, reply); err != nil {
+		return fmt.Errorf("call %s: failed to decode response: %w", call.Service, err)
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package mod
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bwmarrin/snowflake"
+	"github.com/google/uuid"
+)
+
+// Clock 应用时钟，JWT 签发和过期校验、Token 本地回退缓存的过期时间、请求签名和 Webhook 的时间窗口、Mock 数据的日期
+// 以及请求ID等基于时间的ID均通过 app.Clock() 读取当前时间。测试中使用 FrozenClock 可以冻结或拨动时间，无需真实等待；
+// 只影响读取的当前时间，不影响定时器、超时和 BigCache、Redis 等缓存后端自身的过期
+type Clock interface {
+	Now() time.Time
+}
+
+// systemClock 系统时钟（默认）
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+// FrozenClock 冻结的时钟，Now 始终返回设置的时间，通过 Set 和 Advance 拨动，可在多个 goroutine 中使用
+//
+//	clock := app.FreezeTime(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+//	tokens, _ := app.GetJWTManager().GenerateTokens("u1", "alice", "", "user", nil)
+//	clock.Advance(25 * time.Hour) // access token 已过期
+type FrozenClock struct {
+	mu  sync.RWMutex
+	now time.Time
+}
+
+// NewFrozenClock 创建冻结在 t 的时钟
+func NewFrozenClock(t time.Time) *FrozenClock {
+	return &FrozenClock{now: t}
+}
+
+// Now 返回冻结的时间
+func (c *FrozenClock) Now() time.Time {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.now
+}
+
+// Set 将时间设置为 t
+func (c *FrozenClock) Set(t time.Time) {
+	c.mu.Lock()
+	c.now = t
+	c.mu.Unlock()
+}
+
+// Advance 将时间向后拨动 d，d 为负数时向前拨动
+func (c *FrozenClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	c.mu.Unlock()
+}
+
+// clockRef 应用使用的时钟，挂载的子应用与父应用共用
+type clockRef struct {
+	clock atomic.Pointer[Clock]
+}
+
+// Clock 返回应用时钟，未设置时为系统时钟
+func (app *App) Clock() Clock {
+	if app.clock != nil {
+		if c := app.clock.clock.Load(); c != nil {
+			return *c
+		}
+	}
+	return systemClock{}
+}
+
+// SetClock 设置应用时钟，同时作用于挂载的子应用；clock 为 nil 时恢复系统时钟
+func (app *App) SetClock(clock Clock) {
+	if app.clock == nil {
+		app.clock = &clockRef{}
+	}
+	if clock == nil {
+		app.clock.clock.Store(nil)
+		return
+	}
+	app.clock.clock.Store(&clock)
+}
+
+// FreezeTime 将应用时钟冻结在 t，返回的 FrozenClock 用于拨动时间，测试结束后调用 app.SetClock(nil) 恢复
+func (app *App) FreezeTime(t time.Time) *FrozenClock {
+	clock := NewFrozenClock(t)
+	app.SetClock(clock)
+	return clock
+}
+
+// now 返回应用时钟的当前时间
+func (app *App) now() time.Time {
+	return app.Clock().Now()
+}
+
+// customClock 是否设置了非系统时钟，未设置时ID生成沿用原有实现
+func (app *App) customClock() bool {
+	return app.clock != nil && app.clock.clock.Load() != nil
+}
+
+// clockSnowflake 按应用时钟生成雪花ID，节点号与 NextSnowflakeID 的节点不同，避免冻结时间与真实时间重叠时产生重复ID；
+// 同一毫秒内序号用尽时借用下一毫秒，时钟回拨时沿用上一个时间戳，保证进程内单调递增
+var clockSnowflake struct {
+	sync.Mutex
+	ready  bool
+	node   int64
+	lastMs int64
+	step   int64
+}
+
+// nextSnowflakeAt 生成时间戳为 t 的雪花ID
+func nextSnowflakeAt(t time.Time) snowflake.ID {
+	g := &clockSnowflake
+	g.Lock()
+	defer g.Unlock()
+	if !g.ready {
+		g.ready, g.node = true, (snowflakeNode.Generate().Node()+1)&(1<<snowflake.NodeBits-1)
+	}
+	ms := t.UnixMilli() - snowflake.Epoch
+	if ms <= g.lastMs {
+		ms = g.lastMs
+		g.step = (g.step + 1) & (1<<snowflake.StepBits - 1)
+		if g.step == 0 {
+			ms++
+		}
+	} else {
+		g.step = 0
+	}
+	g.lastMs = ms
+	return snowflake.ID(ms<<(snowflake.NodeBits+snowflake.StepBits) | g.node<<snowflake.StepBits | g.step)
+}
+
+// nextUUIDv7At 生成时间戳为 t 的UUIDv7
+func nextUUIDv7At(t time.Time) string {
+	id, err := uuid.NewV7()
+	if err != nil {
+		id = uuid.New()
+		id[6] = id[6]&0x0f | 0x70
+	}
+	ms := uint64(t.UnixMilli())
+	for i := 0; i < 6; i++ {
+		id[i] = byte(ms >> (40 - 8*i))
+	}
+	return id.String()
+}
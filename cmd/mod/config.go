@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/iamdanielyin/mod"
+)
+
+// runConfig 处理 mod config 子命令
+func runConfig(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("用法: mod config <encrypt|decrypt> [value]")
+	}
+	switch args[0] {
+	case "encrypt":
+		return runConfigCrypt(args[1:], true)
+	case "decrypt":
+		return runConfigCrypt(args[1:], false)
+	default:
+		return fmt.Errorf("未知的 config 子命令 %s，可选 encrypt、decrypt", args[0])
+	}
+}
+
+// runConfigCrypt 加密或解密配置值，未传入值时从标准输入读取一行（避免明文进入 shell 历史）
+func runConfigCrypt(args []string, encrypt bool) error {
+	name := "decrypt"
+	if encrypt {
+		name = "encrypt"
+	}
+	fs := flag.NewFlagSet("mod config "+name, flag.ContinueOnError)
+	keyEnv := fs.String("key-env", "MOD_MASTER_KEY", "读取主密钥的环境变量")
+	keyFile := fs.String("key-file", os.Getenv("MOD_MASTER_KEY_FILE"), "主密钥文件，环境变量未设置时使用")
+
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) > 1 {
+		return fmt.Errorf("用法: mod config %s [value] [--key-env 环境变量] [--key-file 文件]", name)
+	}
+
+	key := os.Getenv(*keyEnv)
+	if key == "" && *keyFile != "" {
+		data, err := os.ReadFile(*keyFile)
+		if err != nil {
+			return err
+		}
+		key = strings.TrimSpace(string(data))
+	}
+	if key == "" {
+		return fmt.Errorf("未设置主密钥，请设置 %s 环境变量或使用 --key-file", *keyEnv)
+	}
+
+	var value string
+	if len(positional) == 1 {
+		value = positional[0]
+	} else {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return fmt.Errorf("读取标准输入失败: %w", err)
+		}
+		value = strings.TrimRight(line, "\r\n")
+	}
+
+	if encrypt {
+		encrypted, err := mod.EncryptConfigValue(key, value)
+		if err != nil {
+			return err
+		}
+		fmt.Println("!enc " + encrypted)
+		return nil
+	}
+	plaintext, err := mod.DecryptConfigValue(key, strings.TrimPrefix(strings.TrimSpace(value), "!enc "))
+	if err != nil {
+		return err
+	}
+	fmt.Println(plaintext)
+	return nil
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/iamdanielyin/mod"
+)
+
+// runDiff 比较两个版本的 OpenAPI 文档，存在不兼容变更时返回错误（退出码1），用于在 CI 中检查接口兼容性
+func runDiff(args []string) error {
+	fs := flag.NewFlagSet("mod diff", flag.ContinueOnError)
+	oldPath := fs.String("old", "", "基线文档，如上一版本导出的 openapi.json，也可以是 http(s) 地址")
+	newPath := fs.String("new", "", "当前文档，如 app.WriteOpenAPI 导出的文件或 http://host/services/docs?o=openapi")
+	all := fs.Bool("all", false, "同时输出兼容的变更（新增服务、可选字段等）")
+	asJSON := fs.Bool("json", false, "以 JSON 输出变更列表")
+
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	// 也支持 mod diff <old> <new>
+	if len(positional) == 2 && *oldPath == "" && *newPath == "" {
+		*oldPath, *newPath = positional[0], positional[1]
+	} else if len(positional) > 0 || *oldPath == "" || *newPath == "" {
+		return fmt.Errorf("用法: mod diff --old <spec.json> --new <spec.json> [--all] [--json]")
+	}
+
+	oldSpec, err := loadSpec(*oldPath)
+	if err != nil {
+		return err
+	}
+	newSpec, err := loadSpec(*newPath)
+	if err != nil {
+		return err
+	}
+
+	changes := mod.DiffOpenAPI(oldSpec, newSpec)
+	breaking := changes.Breaking()
+	if !*all {
+		changes = breaking
+	}
+
+	if *asJSON {
+		if changes == nil {
+			changes = mod.SchemaDiff{}
+		}
+		data, err := json.MarshalIndent(changes, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	} else if len(changes) > 0 {
+		fmt.Println(changes)
+	}
+
+	if len(breaking) > 0 {
+		return fmt.Errorf("发现 %d 个不兼容变更", len(breaking))
+	}
+	if !*asJSON {
+		fmt.Println("没有不兼容变更")
+	}
+	return nil
+}
+
+// loadSpec 从文件或 http(s) 地址加载 OpenAPI 文档
+func loadSpec(path string) (*mod.OpenAPISpec, error) {
+	if !strings.HasPrefix(path, "http://") && !strings.HasPrefix(path, "https://") {
+		return mod.LoadOpenAPISpec(path)
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Get(path)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("获取 %s 失败: %s", path, resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	var spec mod.OpenAPISpec
+	if err := json.Unmarshal(data, &spec); err != nil {
+		return nil, fmt.Errorf("解析 %s 失败: %w", path, err)
+	}
+	return &spec, nil
+}
@@ -0,0 +1,1101 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// genKind 字段类型分类
+type genKind int
+
+const (
+	kindFallback genKind = iota // 不支持的类型，回退到 encoding/json 和 validator
+	kindString
+	kindBool
+	kindInt
+	kindUint
+	kindFloat
+	kindBytes
+	kindSlice
+	kindPointer
+	kindStruct
+)
+
+// genType 字段类型信息
+type genType struct {
+	kind  genKind
+	expr  string // Go类型表达式
+	bits  int    // 数值类型位数
+	elem  *genType
+	local bool     // 包内声明的结构体（未生成代码）
+	ast   ast.Expr // 原始类型表达式
+}
+
+// genField 序列化字段（已展开嵌入的结构体）
+type genField struct {
+	name      string // Go字段名
+	path      string // 访问路径，如 Base.ID
+	jsonName  string
+	omitEmpty bool
+	tagged    bool // json标签指定了名称
+	depth     int
+	typ       *genType
+}
+
+// generator 代码生成器
+type generator struct {
+	pkg      string
+	types    map[string]*ast.TypeSpec
+	methods  map[string]map[string]bool
+	selected map[string]bool
+	banned   map[string]string
+	imports  map[string]bool
+	buf      bytes.Buffer
+	tmp      int
+}
+
+// oneofParamPattern 与 validator 解析 oneof 参数的规则一致
+var oneofParamPattern = regexp.MustCompile(`'[^']*'|\S+`)
+
+func runGen(args []string) error {
+	fs := flag.NewFlagSet("mod gen", flag.ContinueOnError)
+	dir := fs.String("dir", ".", "包目录")
+	typeList := fs.String("type", "", "生成代码的类型，多个以逗号分隔，默认为所有以 Request、Response 结尾的结构体")
+	output := fs.String("output", "mod_gen.go", "输出文件名")
+	tags := fs.String("tags", "modgen", "生成文件的构建标签，为空时不添加")
+
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) > 0 {
+		return fmt.Errorf("用法: mod gen [--type 类型列表] [--output 文件名] [--tags 构建标签]")
+	}
+
+	g, err := loadPackage(*dir, *output)
+	if err != nil {
+		return err
+	}
+
+	var roots []string
+	if *typeList != "" {
+		for _, name := range strings.Split(*typeList, ",") {
+			name = strings.TrimSpace(name)
+			spec, ok := g.types[name]
+			if !ok {
+				return fmt.Errorf("类型 %s 不存在", name)
+			}
+			if _, ok := spec.Type.(*ast.StructType); !ok {
+				return fmt.Errorf("类型 %s 不是结构体", name)
+			}
+			roots = append(roots, name)
+		}
+	} else {
+		for name, spec := range g.types {
+			if _, ok := spec.Type.(*ast.StructType); ok && (strings.HasSuffix(name, "Request") || strings.HasSuffix(name, "Response")) {
+				roots = append(roots, name)
+			}
+		}
+	}
+	sort.Strings(roots)
+
+	g.selectTypes(roots)
+	for _, name := range sortedKeys(g.banned) {
+		fmt.Fprintf(os.Stderr, "  跳过 %s: %s\n", name, g.banned[name])
+	}
+	if len(g.selected) == 0 {
+		return fmt.Errorf("没有可生成代码的类型")
+	}
+
+	content, err := g.generate(roots, *tags)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(*dir, *output)
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		return err
+	}
+	fmt.Printf("  生成 %s（%d 个类型）\n", path, len(g.selected))
+	return nil
+}
+
+// loadPackage 解析目录中的Go文件（不含测试文件和输出文件）
+func loadPackage(dir, output string) (*generator, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+
+	g := &generator{
+		types:    map[string]*ast.TypeSpec{},
+		methods:  map[string]map[string]bool{},
+		selected: map[string]bool{},
+		banned:   map[string]string{},
+		imports:  map[string]bool{},
+	}
+	fset := token.NewFileSet()
+	for _, path := range matches {
+		name := filepath.Base(path)
+		if strings.HasSuffix(name, "_test.go") || name == output {
+			continue
+		}
+		file, err := parser.ParseFile(fset, path, nil, parser.SkipObjectResolution)
+		if err != nil {
+			return nil, err
+		}
+		if g.pkg == "" {
+			g.pkg = file.Name.Name
+		} else if g.pkg != file.Name.Name {
+			return nil, fmt.Errorf("目录 %s 中存在多个包", dir)
+		}
+
+		for _, decl := range file.Decls {
+			switch d := decl.(type) {
+			case *ast.GenDecl:
+				if d.Tok != token.TYPE {
+					continue
+				}
+				for _, spec := range d.Specs {
+					ts := spec.(*ast.TypeSpec)
+					g.types[ts.Name.Name] = ts
+				}
+			case *ast.FuncDecl:
+				if d.Recv == nil || len(d.Recv.List) == 0 {
+					continue
+				}
+				recv := receiverName(d.Recv.List[0].Type)
+				if g.methods[recv] == nil {
+					g.methods[recv] = map[string]bool{}
+				}
+				g.methods[recv][d.Name.Name] = true
+			}
+		}
+	}
+	if g.pkg == "" {
+		return nil, fmt.Errorf("目录 %s 中没有Go文件", dir)
+	}
+	return g, nil
+}
+
+// receiverName 返回方法接收者的类型名称
+func receiverName(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.StarExpr:
+		return receiverName(e.X)
+	case *ast.IndexExpr:
+		return receiverName(e.X)
+	case *ast.IndexListExpr:
+		return receiverName(e.X)
+	case *ast.Ident:
+		return e.Name
+	}
+	return ""
+}
+
+// hasJSONMethods 类型是否自定义了JSON或文本序列化方法
+func (g *generator) hasJSONMethods(name string) bool {
+	m := g.methods[name]
+	return m["MarshalJSON"] || m["UnmarshalJSON"] || m["MarshalText"] || m["UnmarshalText"]
+}
+
+// structOf 返回包内结构体声明
+func (g *generator) structOf(name string) *ast.StructType {
+	spec, ok := g.types[name]
+	if !ok || spec.TypeParams != nil {
+		return nil
+	}
+	st, _ := spec.Type.(*ast.StructType)
+	return st
+}
+
+// selectTypes 从根类型出发选出需要生成代码的结构体：
+// 包含字段引用的包内结构体，以及嵌入了已选类型的结构体（否则它们会继承生成的 MarshalJSON）
+func (g *generator) selectTypes(roots []string) {
+	for _, name := range roots {
+		g.selected[name] = true
+	}
+	for {
+		changed := false
+
+		// 字段引用的结构体和嵌入了已选类型的结构体
+		for name := range g.types {
+			st := g.structOf(name)
+			if st == nil {
+				continue
+			}
+			if g.selected[name] {
+				for _, field := range st.Fields.List {
+					ast.Inspect(field.Type, func(n ast.Node) bool {
+						if id, ok := n.(*ast.Ident); ok && g.structOf(id.Name) != nil && !g.selected[id.Name] && g.banned[id.Name] == "" {
+							g.selected[id.Name] = true
+							changed = true
+						}
+						_, isSelector := n.(*ast.SelectorExpr)
+						return !isSelector
+					})
+				}
+				continue
+			}
+			if g.banned[name] != "" {
+				continue
+			}
+			for _, field := range st.Fields.List {
+				if len(field.Names) == 0 && g.selected[receiverName(field.Type)] {
+					g.selected[name] = true
+					changed = true
+					break
+				}
+			}
+		}
+
+		// 排除不支持的类型，被排除的类型嵌入的已选类型同样需要排除
+		for name := range g.selected {
+			if reason := g.unsupported(name); reason != "" {
+				g.ban(name, reason)
+				changed = true
+			}
+		}
+		for name := range g.banned {
+			st := g.structOf(name)
+			if st == nil {
+				continue
+			}
+			for _, field := range st.Fields.List {
+				if embedded := receiverName(field.Type); len(field.Names) == 0 && g.selected[embedded] {
+					g.ban(embedded, "被未生成代码的类型 "+name+" 嵌入")
+					changed = true
+				}
+			}
+		}
+
+		if !changed {
+			return
+		}
+	}
+}
+
+func (g *generator) ban(name, reason string) {
+	delete(g.selected, name)
+	if g.banned[name] == "" {
+		g.banned[name] = reason
+	}
+}
+
+// unsupported 返回类型不支持生成代码的原因
+func (g *generator) unsupported(name string) string {
+	spec := g.types[name]
+	if spec.TypeParams != nil {
+		return "泛型类型"
+	}
+	if g.hasJSONMethods(name) {
+		return "已自定义JSON序列化方法"
+	}
+	st := g.structOf(name)
+	if st == nil {
+		return "不是结构体"
+	}
+	for _, field := range st.Fields.List {
+		tag := fieldTag(field)
+		jsonName, opts := parseJSONTag(tag.Get("json"))
+		if jsonName == "-" && opts == "" {
+			continue
+		}
+		for _, opt := range strings.Split(opts, ",") {
+			if opt == "string" || opt == "omitzero" {
+				return fmt.Sprintf("字段使用了 ,%s 选项", opt)
+			}
+		}
+		if len(field.Names) > 0 {
+			continue
+		}
+
+		// 嵌入字段
+		id, ok := field.Type.(*ast.Ident)
+		if !ok {
+			return "嵌入了 " + types.ExprString(field.Type)
+		}
+		if g.hasJSONMethods(id.Name) && !g.selected[id.Name] {
+			return "嵌入了自定义JSON序列化方法的类型 " + id.Name
+		}
+		if g.structOf(id.Name) != nil && !g.selected[id.Name] {
+			return "嵌入了未生成代码的类型 " + id.Name
+		}
+		if _, ok := g.types[id.Name]; !ok {
+			return "嵌入了 " + id.Name
+		}
+	}
+	return ""
+}
+
+// resolve 解析字段类型
+func (g *generator) resolve(expr ast.Expr) *genType {
+	t := &genType{kind: kindFallback, expr: types.ExprString(expr), ast: expr}
+	switch e := expr.(type) {
+	case *ast.Ident:
+		if basic := basicType(e.Name); basic != nil {
+			basic.expr, basic.ast = e.Name, expr
+			return basic
+		}
+		if g.selected[e.Name] {
+			t.kind = kindStruct
+			return t
+		}
+		spec, ok := g.types[e.Name]
+		if !ok || spec.TypeParams != nil {
+			return t
+		}
+		if _, ok := spec.Type.(*ast.StructType); ok {
+			t.local = true
+			return t
+		}
+		if id, ok := spec.Type.(*ast.Ident); ok && !g.hasJSONMethods(e.Name) {
+			if basic := basicType(id.Name); basic != nil {
+				basic.expr, basic.ast = e.Name, expr
+				return basic
+			}
+		}
+	case *ast.StarExpr:
+		// 元素类型不支持时整体回退，避免生成代码引用其他包的类型
+		if elem := g.resolve(e.X); elem.kind != kindFallback {
+			t.kind, t.elem = kindPointer, elem
+		}
+	case *ast.ArrayType:
+		if e.Len != nil {
+			return t
+		}
+		if id, ok := e.Elt.(*ast.Ident); ok && (id.Name == "byte" || id.Name == "uint8") {
+			t.kind = kindBytes
+			return t
+		}
+		if elem := g.resolve(e.Elt); elem.kind != kindFallback {
+			t.kind, t.elem = kindSlice, elem
+		}
+	}
+	return t
+}
+
+// basicType 返回预声明基础类型信息
+func basicType(name string) *genType {
+	switch name {
+	case "string":
+		return &genType{kind: kindString}
+	case "bool":
+		return &genType{kind: kindBool}
+	case "int", "int64":
+		return &genType{kind: kindInt, bits: 64}
+	case "int8":
+		return &genType{kind: kindInt, bits: 8}
+	case "int16":
+		return &genType{kind: kindInt, bits: 16}
+	case "int32", "rune":
+		return &genType{kind: kindInt, bits: 32}
+	case "uint", "uint64":
+		return &genType{kind: kindUint, bits: 64}
+	case "uint8", "byte":
+		return &genType{kind: kindUint, bits: 8}
+	case "uint16":
+		return &genType{kind: kindUint, bits: 16}
+	case "uint32":
+		return &genType{kind: kindUint, bits: 32}
+	case "float32":
+		return &genType{kind: kindFloat, bits: 32}
+	case "float64":
+		return &genType{kind: kindFloat, bits: 64}
+	}
+	return nil
+}
+
+func fieldTag(field *ast.Field) reflect.StructTag {
+	if field.Tag == nil {
+		return ""
+	}
+	tag, _ := strconv.Unquote(field.Tag.Value)
+	return reflect.StructTag(tag)
+}
+
+func parseJSONTag(tag string) (string, string) {
+	name, opts, _ := strings.Cut(tag, ",")
+	return name, opts
+}
+
+func hasOption(opts, option string) bool {
+	for _, opt := range strings.Split(opts, ",") {
+		if opt == option {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonFields 返回结构体的序列化字段，嵌入结构体的字段按 encoding/json 的规则展开
+func (g *generator) jsonFields(name string) []*genField {
+	var all []*genField
+	g.collectFields(g.structOf(name), "", 0, &all)
+
+	// 同名字段取层级最浅的；同一层级存在多个时，仅保留唯一指定了json名称的字段
+	byName := map[string][]*genField{}
+	for _, f := range all {
+		byName[f.jsonName] = append(byName[f.jsonName], f)
+	}
+	var fields []*genField
+	for _, f := range all {
+		candidates := byName[f.jsonName]
+		if dominant(candidates) == f {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+func dominant(fields []*genField) *genField {
+	depth := fields[0].depth
+	for _, f := range fields {
+		depth = min(depth, f.depth)
+	}
+	var shallow, tagged []*genField
+	for _, f := range fields {
+		if f.depth == depth {
+			shallow = append(shallow, f)
+			if f.tagged {
+				tagged = append(tagged, f)
+			}
+		}
+	}
+	if len(shallow) == 1 {
+		return shallow[0]
+	}
+	if len(tagged) == 1 {
+		return tagged[0]
+	}
+	return nil
+}
+
+func (g *generator) collectFields(st *ast.StructType, path string, depth int, out *[]*genField) {
+	for _, field := range st.Fields.List {
+		jsonName, opts := parseJSONTag(fieldTag(field).Get("json"))
+		if jsonName == "-" && opts == "" {
+			continue
+		}
+
+		names := make([]string, 0, len(field.Names))
+		for _, n := range field.Names {
+			names = append(names, n.Name)
+		}
+		if len(names) == 0 {
+			embedded := receiverName(field.Type)
+			if jsonName == "" && g.selected[embedded] {
+				g.collectFields(g.structOf(embedded), path+embedded+".", depth+1, out)
+				continue
+			}
+			names = append(names, embedded)
+		}
+
+		for _, name := range names {
+			if !ast.IsExported(name) {
+				continue
+			}
+			f := &genField{
+				name:      name,
+				path:      path + name,
+				jsonName:  jsonName,
+				omitEmpty: hasOption(opts, "omitempty"),
+				tagged:    jsonName != "",
+				depth:     depth,
+				typ:       g.resolve(field.Type),
+			}
+			if f.jsonName == "" {
+				f.jsonName = name
+			}
+			*out = append(*out, f)
+		}
+	}
+}
+
+func (g *generator) printf(format string, args ...any) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+func (g *generator) tempVar(prefix string) string {
+	g.tmp++
+	return fmt.Sprintf("%s%d", prefix, g.tmp)
+}
+
+// generate 生成代码并格式化
+func (g *generator) generate(roots []string, tags string) ([]byte, error) {
+	names := sortedKeys(g.selected)
+	g.imports["github.com/iamdanielyin/mod"] = true
+	for _, name := range names {
+		g.genDecode(name)
+		g.genEncode(name)
+		g.genValidate(name)
+	}
+	for _, name := range roots {
+		if g.selected[name] && !strings.HasSuffix(name, "Response") {
+			g.genRequest(name)
+		}
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintln(&out, "// Code generated by mod gen. DO NOT EDIT.")
+	fmt.Fprintln(&out)
+	if tags != "" {
+		fmt.Fprintf(&out, "//go:build %s\n\n", tags)
+	}
+	fmt.Fprintf(&out, "package %s\n\nimport (\n", g.pkg)
+	var std, external []string
+	for _, path := range sortedKeys(g.imports) {
+		if strings.Contains(path, ".") {
+			external = append(external, path)
+		} else {
+			std = append(std, path)
+		}
+	}
+	for _, path := range std {
+		fmt.Fprintf(&out, "\t%q\n", path)
+	}
+	if len(std) > 0 {
+		fmt.Fprintln(&out)
+	}
+	for _, path := range external {
+		fmt.Fprintf(&out, "\t%q\n", path)
+	}
+	fmt.Fprintln(&out, ")")
+	out.Write(g.buf.Bytes())
+
+	content, err := format.Source(out.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("格式化生成代码失败: %w", err)
+	}
+	return content, nil
+}
+
+// genDecode 生成JSON解析方法
+func (g *generator) genDecode(name string) {
+	fields := g.jsonFields(name)
+	quoted := make([]string, 0, len(fields))
+	for _, f := range fields {
+		quoted = append(quoted, strconv.Quote(f.jsonName))
+	}
+
+	g.printf("\nvar modJSONFields%s = []string{%s}\n", name, strings.Join(quoted, ", "))
+	g.printf("\n// ModDecodeJSON 从JSON读取器解析 %s\n", name)
+	g.printf("func (x *%s) ModDecodeJSON(l *mod.JSONLexer) {\n", name)
+	g.printf("if l.IsNull() {\nreturn\n}\n")
+	g.printf("l.Object(func(key []byte) {\nswitch mod.JSONFieldIndex(key, modJSONFields%s) {\n", name)
+	for i, f := range fields {
+		g.printf("case %d:\n", i)
+		g.decodeValue(f.typ, "x."+f.path)
+	}
+	g.printf("default:\nl.Skip()\n}\n})\n}\n")
+
+	g.printf("\n// UnmarshalJSON 实现 json.Unmarshaler\n")
+	g.printf("func (x *%s) UnmarshalJSON(data []byte) error {\n", name)
+	g.printf("l := mod.NewJSONLexer(data)\nx.ModDecodeJSON(l)\nl.End()\nreturn l.Err()\n}\n")
+}
+
+// decodeValue 生成读取当前JSON值到 target 的代码，target 必须可寻址
+func (g *generator) decodeValue(t *genType, target string) {
+	switch t.kind {
+	case kindString:
+		g.printf("if !l.IsNull() {\n%s = %s\n}\n", target, convert(t.expr, "string", "l.String()"))
+	case kindBool:
+		g.printf("if !l.IsNull() {\n%s = %s\n}\n", target, convert(t.expr, "bool", "l.Bool()"))
+	case kindInt:
+		g.printf("if !l.IsNull() {\n%s = %s\n}\n", target, convert(t.expr, "int64", fmt.Sprintf("l.Int(%d)", t.bits)))
+	case kindUint:
+		g.printf("if !l.IsNull() {\n%s = %s\n}\n", target, convert(t.expr, "uint64", fmt.Sprintf("l.Uint(%d)", t.bits)))
+	case kindFloat:
+		g.printf("if !l.IsNull() {\n%s = %s\n}\n", target, convert(t.expr, "float64", fmt.Sprintf("l.Float(%d)", t.bits)))
+	case kindBytes:
+		g.printf("if l.IsNull() {\n%s = nil\n} else {\n%s = l.Bytes()\n}\n", target, target)
+	case kindStruct:
+		g.printf("%s.ModDecodeJSON(l)\n", target)
+	case kindPointer:
+		g.printf("if l.IsNull() {\n%s = nil\n} else {\n", target)
+		g.printf("if %s == nil {\n%s = new(%s)\n}\n", target, target, t.elem.expr)
+		if t.elem.kind == kindStruct {
+			g.printf("%s.ModDecodeJSON(l)\n", target)
+		} else {
+			g.decodeValue(t.elem, "*"+target)
+		}
+		g.printf("}\n")
+	case kindSlice:
+		elem := g.tempVar("v")
+		g.printf("if l.IsNull() {\n%s = nil\n} else {\n", target)
+		g.printf("if %s == nil {\n%s = %s{}\n} else {\n%s = %s[:0]\n}\n", target, target, t.expr, target, paren(target))
+		g.printf("l.Array(func() {\nvar %s %s\n", elem, t.elem.expr)
+		g.decodeValue(t.elem, elem)
+		g.printf("%s = append(%s, %s)\n})\n}\n", target, target, elem)
+	default:
+		g.printf("l.Unmarshal(&%s)\n", target)
+	}
+}
+
+// genEncode 生成JSON序列化方法
+func (g *generator) genEncode(name string) {
+	g.printf("\n// ModEncodeJSON 将 %s 写入JSON写入器\n", name)
+	g.printf("func (x *%s) ModEncodeJSON(w *mod.JSONWriter) {\nw.RawByte('{')\n", name)
+	for _, f := range g.jsonFields(name) {
+		value := "x." + f.path
+		present := nonEmptyCheck(f.typ, value)
+		if f.omitEmpty && present != "" {
+			g.printf("if %s {\n", present)
+		}
+		g.printf("w.Key(%s)\n", strconv.Quote(f.jsonName))
+		g.encodeValue(f.typ, value)
+		if f.omitEmpty && present != "" {
+			g.printf("}\n")
+		}
+	}
+	g.printf("w.RawByte('}')\n}\n")
+
+	g.printf("\n// MarshalJSON 实现 json.Marshaler\n")
+	g.printf("func (x %s) MarshalJSON() ([]byte, error) {\n", name)
+	g.printf("var w mod.JSONWriter\nx.ModEncodeJSON(&w)\nreturn w.Bytes()\n}\n")
+}
+
+// nonEmptyCheck 返回 omitempty 的非空判断表达式，结构体永远不为空时返回空字符串
+func nonEmptyCheck(t *genType, value string) string {
+	switch t.kind {
+	case kindString:
+		return value + ` != ""`
+	case kindBool:
+		return value
+	case kindInt, kindUint, kindFloat:
+		return value + " != 0"
+	case kindBytes, kindSlice:
+		return "len(" + value + ") > 0"
+	case kindPointer:
+		return value + " != nil"
+	case kindStruct:
+		return ""
+	}
+	switch e := t.ast.(type) {
+	case *ast.MapType:
+		return "len(" + value + ") > 0"
+	case *ast.InterfaceType:
+		return value + " != nil"
+	case *ast.Ident:
+		if e.Name == "any" || e.Name == "error" {
+			return value + " != nil"
+		}
+		if t.local {
+			return ""
+		}
+	}
+	return "!mod.IsEmptyValue(" + value + ")"
+}
+
+// encodeValue 生成写入 value 的代码，value 必须可寻址
+func (g *generator) encodeValue(t *genType, value string) {
+	switch t.kind {
+	case kindString:
+		g.printf("w.String(%s)\n", convert("string", t.expr, value))
+	case kindBool:
+		g.printf("w.Bool(%s)\n", convert("bool", t.expr, value))
+	case kindInt:
+		g.printf("w.Int(%s)\n", convert("int64", t.expr, value))
+	case kindUint:
+		g.printf("w.Uint(%s)\n", convert("uint64", t.expr, value))
+	case kindFloat:
+		g.printf("w.Float(%s, %d)\n", convert("float64", t.expr, value), t.bits)
+	case kindBytes:
+		g.printf("w.Base64(%s)\n", value)
+	case kindStruct:
+		g.printf("%s.ModEncodeJSON(w)\n", value)
+	case kindPointer:
+		g.printf("if %s == nil {\nw.Null()\n} else {\n", value)
+		if t.elem.kind == kindStruct {
+			g.printf("%s.ModEncodeJSON(w)\n", value)
+		} else {
+			g.encodeValue(t.elem, "*"+value)
+		}
+		g.printf("}\n")
+	case kindSlice:
+		i := g.tempVar("i")
+		g.printf("if %s == nil {\nw.Null()\n} else {\nw.RawByte('[')\n", value)
+		g.printf("for %s := range %s {\nif %s > 0 {\nw.RawByte(',')\n}\n", i, value, i)
+		g.encodeValue(t.elem, paren(value)+"["+i+"]")
+		g.printf("}\nw.RawByte(']')\n}\n")
+	default:
+		g.printf("w.Marshal(&%s)\n", value)
+	}
+}
+
+// convert 生成 from 类型的表达式到 to 类型的转换，类型相同时不转换
+func convert(to, from, expr string) string {
+	if to == from {
+		return expr
+	}
+	return to + "(" + expr + ")"
+}
+
+// paren 为解引用表达式加上括号，便于索引和切片
+func paren(expr string) string {
+	if strings.HasPrefix(expr, "*") {
+		return "(" + expr + ")"
+	}
+	return expr
+}
+
+// genValidate 生成参数校验方法，规则与 validator 一致，不支持的规则回退到 validator
+func (g *generator) genValidate(name string) {
+	g.printf("\n// modValidate 按 validate 标签校验 %s，ns 为字段命名空间\n", name)
+	g.printf("func (x *%s) modValidate(ns string, errs *mod.ValidationErrors) {\n", name)
+	for _, field := range g.structOf(name).Fields.List {
+		tag := fieldTag(field).Get("validate")
+		if tag == "-" {
+			continue
+		}
+		typ := g.resolve(field.Type)
+		if len(field.Names) == 0 {
+			g.validateField(receiverName(field.Type), typ, tag)
+			continue
+		}
+		for _, n := range field.Names {
+			if ast.IsExported(n.Name) {
+				g.validateField(n.Name, typ, tag)
+			}
+		}
+	}
+	g.printf("}\n")
+}
+
+func (g *generator) validateField(field string, t *genType, tag string) {
+	value := "x." + field
+	ns := fmt.Sprintf("ns+%q", "."+field)
+	fallback := func() {
+		g.printf("*errs = append(*errs, mod.ValidateVar(%s, %q, %s, %q)...)\n", ns, field, value, tag)
+	}
+
+	switch t.kind {
+	case kindStruct:
+		if tag != "" && tag != "omitempty" {
+			fallback()
+		}
+		g.printf("%s.modValidate(%s, errs)\n", value, ns)
+		return
+	case kindPointer:
+		if t.elem.kind != kindStruct {
+			break
+		}
+		switch tag {
+		case "", "omitempty":
+		case "required":
+			g.printf("if %s == nil {\n*errs = append(*errs, mod.ValidationFailure(%s, %q, \"required\"))\n}\n", value, ns, field)
+		default:
+			fallback()
+		}
+		g.printf("if %s != nil {\n%s.modValidate(%s, errs)\n}\n", value, value, ns)
+		return
+	}
+
+	if t.kind == kindSlice && g.validateElements(field, t, tag) {
+		return
+	}
+
+	if tag == "" {
+		if t.local {
+			g.printf("*errs = append(*errs, mod.ValidateStruct(&%s)...)\n", value)
+		}
+		return
+	}
+
+	omit, checks, ok := g.compileRules(t, value, tag)
+	if !ok {
+		fallback()
+		return
+	}
+	if len(checks) == 0 {
+		return
+	}
+	if omit {
+		g.printf("if %s {\n", nonZeroCheck(t, value))
+	}
+	g.printf("switch {\n")
+	for _, c := range checks {
+		g.printf("case %s:\n*errs = append(*errs, mod.ValidationFailure(%s, %q, %q))\n", c[1], ns, field, c[0])
+	}
+	g.printf("}\n")
+	if omit {
+		g.printf("}\n")
+	}
+}
+
+// validateElements 处理以 dive 结尾、元素为生成了代码的结构体（或其指针）的切片规则：dive 之前的规则校验切片本身，
+// 再对每个元素调用 modValidate，命名空间与 validator 一致（如 Items[0].Name）；不符合时返回 false
+func (g *generator) validateElements(field string, t *genType, tag string) bool {
+	rules := strings.Split(tag, ",")
+	if rules[len(rules)-1] != "dive" {
+		return false
+	}
+	elem := t.elem
+	pointer := elem.kind == kindPointer
+	if pointer {
+		elem = elem.elem
+	}
+	if elem.kind != kindStruct {
+		return false
+	}
+	if pre := strings.Join(rules[:len(rules)-1], ","); pre != "" {
+		g.validateField(field, t, pre)
+	}
+
+	value := "x." + field
+	i := g.tempVar("i")
+	item := fmt.Sprintf("%s[%s]", value, i)
+	ns := fmt.Sprintf("ns+%q+strconv.Itoa(%s)+\"]\"", "."+field+"[", i)
+	g.printf("for %s := range %s {\n", i, value)
+	if pointer {
+		// validator 跳过为 nil 的元素
+		g.printf("if %s != nil {\n%s.modValidate(%s, errs)\n}\n", item, item, ns)
+	} else {
+		g.printf("%s.modValidate(%s, errs)\n", item, ns)
+	}
+	g.printf("}\n")
+	g.imports["strconv"] = true
+	return true
+}
+
+// zeroCheck 返回 required 规则的零值判断表达式（切片与 validator 一致，仅 nil 视为零值）
+func zeroCheck(t *genType, value string) string {
+	switch t.kind {
+	case kindString:
+		return value + ` == ""`
+	case kindBool:
+		return "!" + value
+	case kindInt, kindUint, kindFloat:
+		return value + " == 0"
+	}
+	return value + " == nil"
+}
+
+// nonZeroCheck 返回 omitempty 规则的非零值判断表达式
+func nonZeroCheck(t *genType, value string) string {
+	switch t.kind {
+	case kindString:
+		return value + ` != ""`
+	case kindBool:
+		return value
+	case kindInt, kindUint, kindFloat:
+		return value + " != 0"
+	}
+	return value + " != nil"
+}
+
+// compileRules 将 validate 标签转换为 [规则, 失败条件] 列表，包含不支持的规则时 ok 为 false
+func (g *generator) compileRules(t *genType, value, tag string) (omit bool, checks [][2]string, ok bool) {
+	switch t.kind {
+	case kindString, kindBool, kindInt, kindUint, kindFloat, kindBytes, kindSlice:
+	default:
+		return false, nil, false
+	}
+	if strings.ContainsAny(tag, "|\\") {
+		return false, nil, false
+	}
+
+	useUTF8 := false
+	for i, rule := range strings.Split(tag, ",") {
+		name, param, _ := strings.Cut(rule, "=")
+		switch name {
+		case "omitempty":
+			if i != 0 {
+				return false, nil, false
+			}
+			omit = true
+		case "required":
+			checks = append(checks, [2]string{name, zeroCheck(t, value)})
+		case "min", "max", "len", "gt", "gte", "lt", "lte":
+			if t.kind == kindBool {
+				return false, nil, false
+			}
+			operand, literal, valid := compareOperand(t, value, param)
+			if !valid {
+				return false, nil, false
+			}
+			if t.kind == kindString {
+				useUTF8 = true
+			}
+			op := map[string]string{"min": "<", "max": ">", "len": "!=", "gt": "<=", "gte": "<", "lt": ">=", "lte": ">"}[name]
+			checks = append(checks, [2]string{name, operand + " " + op + " " + literal})
+		case "oneof":
+			cond, valid := oneofCheck(t, value, param)
+			if !valid {
+				return false, nil, false
+			}
+			checks = append(checks, [2]string{name, cond})
+		default:
+			return false, nil, false
+		}
+	}
+	if useUTF8 {
+		g.imports["unicode/utf8"] = true
+	}
+	return omit, checks, true
+}
+
+// compareOperand 返回比较规则的操作数和参数字面量，参数解析方式与 validator 一致
+func compareOperand(t *genType, value, param string) (string, string, bool) {
+	switch t.kind {
+	case kindString, kindBytes, kindSlice, kindInt:
+		n, err := strconv.ParseInt(param, 0, 64)
+		if err != nil {
+			return "", "", false
+		}
+		literal := strconv.FormatInt(n, 10)
+		switch t.kind {
+		case kindString:
+			return "utf8.RuneCountInString(" + convert("string", t.expr, value) + ")", literal, true
+		case kindInt:
+			return convert("int64", t.expr, value), literal, true
+		default:
+			return "len(" + value + ")", literal, true
+		}
+	case kindUint:
+		n, err := strconv.ParseUint(param, 0, 64)
+		if err != nil {
+			return "", "", false
+		}
+		return convert("uint64", t.expr, value), strconv.FormatUint(n, 10), true
+	case kindFloat:
+		f, err := strconv.ParseFloat(param, 64)
+		if err != nil || math.IsInf(f, 0) || math.IsNaN(f) {
+			return "", "", false
+		}
+		return convert("float64", t.expr, value), strconv.FormatFloat(f, 'g', -1, 64), true
+	}
+	return "", "", false
+}
+
+// oneofCheck 返回 oneof 规则的失败条件，整数按十进制字符串比较（与 validator 一致）
+func oneofCheck(t *genType, value, param string) (string, bool) {
+	values := oneofParamPattern.FindAllString(param, -1)
+	if len(values) == 0 {
+		return "", false
+	}
+	conds := make([]string, 0, len(values))
+	for _, v := range values {
+		v = strings.ReplaceAll(v, "'", "")
+		switch t.kind {
+		case kindString:
+			conds = append(conds, value+" != "+strconv.Quote(v))
+		case kindInt:
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil || strconv.FormatInt(n, 10) != v {
+				return "", false
+			}
+			conds = append(conds, convert("int64", t.expr, value)+" != "+v)
+		case kindUint:
+			n, err := strconv.ParseUint(v, 10, 64)
+			if err != nil || strconv.FormatUint(n, 10) != v {
+				return "", false
+			}
+			conds = append(conds, convert("uint64", t.expr, value)+" != "+v)
+		default:
+			return "", false
+		}
+	}
+	return strings.Join(conds, " && "), true
+}
+
+// genRequest 生成请求类型的参数绑定和校验方法，绑定规则与基于反射的 parseRequestParamsToStruct 一致
+func (g *generator) genRequest(name string) {
+	g.imports["github.com/gofiber/fiber/v2"] = true
+
+	g.printf("\n// BindParams 实现 mod.ParamBinder\n")
+	g.printf("func (x *%s) BindParams(c *fiber.Ctx) error {\n", name)
+	// XML、MessagePack、表单和 multipart 请求体由 mod.BindBody 解析
+	g.printf("if !mod.IsJSONBody(c) {\nif err := mod.BindBody(c, x); err != nil {\nreturn err\n}\n")
+	// 解析失败时由 mod.JSONBodyError 返回与 BindBody 一致的错误信息
+	g.printf("} else if body := c.Body(); len(body) > 0 {\nif err := x.UnmarshalJSON(body); err != nil {\n")
+	g.printf("return mod.JSONBodyError(body, x, err)\n}\n}\n")
+
+	for _, field := range g.structOf(name).Fields.List {
+		t := g.resolve(field.Type)
+		switch t.kind {
+		case kindString, kindBool, kindInt, kindUint, kindFloat:
+		default:
+			continue
+		}
+		modTag := fieldTag(field).Get("mod")
+		for _, n := range field.Names {
+			if !ast.IsExported(n.Name) {
+				continue
+			}
+			from, paramName := "", strings.ToLower(n.Name)
+			if modTag != "" {
+				from = "query"
+				for _, part := range strings.Split(modTag, ";") {
+					key, value, found := strings.Cut(strings.TrimSpace(part), "=")
+					if !found {
+						continue
+					}
+					switch strings.TrimSpace(key) {
+					case "from":
+						from = strings.TrimSpace(value)
+					case "name":
+						paramName = strings.TrimSpace(value)
+					}
+				}
+				if from == "" {
+					from = "query"
+				}
+			}
+			if from == "body" {
+				// 请求体字段已由 UnmarshalJSON 或 mod.BindBody 赋值
+				continue
+			}
+
+			g.printf("if v := mod.ParamValue(c, %q, %q, %q); v != \"\" {\n", from, paramName, n.Name)
+			g.bindValue(t, "x."+n.Name)
+			g.printf("}\n")
+		}
+	}
+	g.printf("return nil\n}\n")
+
+	g.printf("\n// ValidateRequest 实现 mod.RequestValidator\n")
+	g.printf("func (x *%s) ValidateRequest() error {\n", name)
+	g.printf("var errs mod.ValidationErrors\nx.modValidate(%q, &errs)\n", name)
+	g.printf("if len(errs) > 0 {\nreturn errs\n}\nreturn nil\n}\n")
+}
+
+// bindValue 生成字符串参数转换代码，转换失败时忽略（与 setFieldValue 一致）
+func (g *generator) bindValue(t *genType, target string) {
+	switch t.kind {
+	case kindString:
+		g.printf("%s = %s\n", target, convert(t.expr, "string", "v"))
+		return
+	case kindBool:
+		g.printf("if b, err := strconv.ParseBool(v); err == nil {\n%s = %s\n}\n", target, convert(t.expr, "bool", "b"))
+	case kindInt:
+		g.printf("if n, err := strconv.ParseInt(v, 10, 64); err == nil {\n%s = %s\n}\n", target, convert(t.expr, "int64", "n"))
+	case kindUint:
+		g.printf("if n, err := strconv.ParseUint(v, 10, 64); err == nil {\n%s = %s\n}\n", target, convert(t.expr, "uint64", "n"))
+	case kindFloat:
+		g.printf("if f, err := strconv.ParseFloat(v, 64); err == nil {\n%s = %s\n}\n", target, convert(t.expr, "float64", "f"))
+	}
+	g.imports["strconv"] = true
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
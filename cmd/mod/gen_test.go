@@ -0,0 +1,131 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// genParitySource 生成代码与反射绑定对比使用的服务，输出各请求的错误码、消息和详情
+const genParitySource = `package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/iamdanielyin/mod"
+)
+
+type Item struct {
+	Name string ` + "`json:\"name\" validate:\"required\"`" + `
+	Qty  int    ` + "`json:\"qty\" validate:\"min=1\"`" + `
+}
+
+type CreateOrderRequest struct {
+	Title string   ` + "`json:\"title\" validate:\"required\"`" + `
+	Age   int      ` + "`json:\"age\"`" + `
+	Items []Item   ` + "`json:\"items\" validate:\"required,dive\"`" + `
+	Refs  []*Item  ` + "`json:\"refs\" validate:\"dive\"`" + `
+	Tags  []string ` + "`json:\"tags\" validate:\"dive,min=2\"`" + `
+}
+
+type CreateOrderResponse struct {
+	OK bool ` + "`json:\"ok\"`" + `
+}
+
+func main() {
+	app := mod.New()
+	app.Register(mod.Service{
+		Name:        "create_order",
+		DisplayName: "create_order",
+		SkipAuth:    true,
+		Handler: mod.MakeHandler(func(ctx *mod.Context, req *CreateOrderRequest, resp *CreateOrderResponse) error {
+			resp.OK = true
+			return nil
+		}),
+	})
+	_, generated := any(&CreateOrderRequest{}).(mod.ParamBinder)
+	fmt.Println("RESULT generated", generated)
+	for _, body := range []string{
+		` + "`{\"title\":\"t\",\"items\":[{\"name\":\"a\",\"qty\":1},{\"qty\":0}],\"refs\":[null,{\"name\":\"b\"}],\"tags\":[\"a\",\"bb\"]}`" + `,
+		` + "`{\"title\":\"t\"}`" + `,
+		` + "`{\"title\":\"t\",\"age\":\"x\"}`" + `,
+		` + "`{\"title\":\"t\",\"items\":[{\"name\":\"a\",\"qty\":\"y\"}]}`" + `,
+		` + "`{\"title\":\"t\",\"items\":\"z\"}`" + `,
+		` + "`{\"title\":`" + `,
+		` + "`{\"title\" 1}`" + `,
+	} {
+		resp, err := app.TestClient().Post(app.ServicePath("create_order"), body)
+		if err != nil {
+			panic(err)
+		}
+		var envelope mod.ApiResponse
+		json.Unmarshal(resp.Body, &envelope)
+		fmt.Printf("RESULT %d %d %s %q\n", resp.StatusCode, envelope.Code, envelope.Msg, envelope.Detail)
+	}
+}
+`
+
+// TestGenMatchesReflectiveBinding 生成绑定和校验代码后，分别以 modgen 和默认构建运行同一组请求，错误详情应完全一致
+func TestGenMatchesReflectiveBinding(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds a temporary module")
+	}
+	goBin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go command not available")
+	}
+	root, err := filepath.Abs("../..")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum, err := os.ReadFile(filepath.Join(root, "go.sum"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	goMod := "module gentest\n\ngo 1.24.2\n\nrequire github.com/iamdanielyin/mod v0.0.0\n\nreplace github.com/iamdanielyin/mod => " + root + "\n"
+	files := map[string][]byte{"go.mod": []byte(goMod), "go.sum": sum, "main.go": []byte(genParitySource)}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := runGen([]string{"--dir", dir}); err != nil {
+		t.Fatal(err)
+	}
+
+	run := func(tags string) string {
+		cmd := exec.Command(goBin, "run", "-mod=mod", "-tags="+tags, ".")
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "GOFLAGS=", "GOWORK=off", "GOPROXY=off")
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("go run -tags=%s: %v\n%s", tags, err, out)
+		}
+		var results []string
+		for _, line := range strings.Split(string(out), "\n") {
+			if strings.HasPrefix(line, "RESULT ") {
+				results = append(results, line)
+			}
+		}
+		return strings.Join(results, "\n")
+	}
+
+	generated := run("modgen")
+	reflective := run("")
+	if !strings.HasPrefix(generated, "RESULT generated true") || !strings.HasPrefix(reflective, "RESULT generated false") {
+		t.Fatalf("unexpected builds:\n%s\n---\n%s", generated, reflective)
+	}
+	generated = strings.TrimPrefix(generated, "RESULT generated true")
+	reflective = strings.TrimPrefix(reflective, "RESULT generated false")
+	if generated != reflective {
+		t.Fatalf("generated binding differs from reflective binding:\n%s\n---\n%s", generated, reflective)
+	}
+	if !strings.Contains(reflective, "CreateOrderRequest.Items[1].Qty") {
+		t.Fatalf("expected element namespace in validation errors:\n%s", reflective)
+	}
+}
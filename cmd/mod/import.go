@@ -0,0 +1,1026 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// 以下为导入所需的 OpenAPI 3.x / Swagger 2.0 文档子集
+
+type oaDoc struct {
+	Swagger     string                `yaml:"swagger"`
+	OpenAPI     string                `yaml:"openapi"`
+	Paths       oaPaths               `yaml:"paths"`
+	Components  oaComponents          `yaml:"components"`
+	Definitions map[string]*oaSchema  `yaml:"definitions"`
+	Parameters  map[string]*oaParam   `yaml:"parameters"`
+	Responses   map[string]*oaResp    `yaml:"responses"`
+	Security    []map[string][]string `yaml:"security"`
+}
+
+type oaComponents struct {
+	Schemas       map[string]*oaSchema      `yaml:"schemas"`
+	Parameters    map[string]*oaParam       `yaml:"parameters"`
+	RequestBodies map[string]*oaRequestBody `yaml:"requestBodies"`
+	Responses     map[string]*oaResp        `yaml:"responses"`
+}
+
+// oaPaths 保持文档中的路径顺序
+type oaPaths []oaPathEntry
+
+type oaPathEntry struct {
+	Path string
+	Item *oaPathItem
+}
+
+func (p *oaPaths) UnmarshalYAML(node *yaml.Node) error {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		var item oaPathItem
+		if err := node.Content[i+1].Decode(&item); err != nil {
+			return err
+		}
+		*p = append(*p, oaPathEntry{Path: node.Content[i].Value, Item: &item})
+	}
+	return nil
+}
+
+type oaPathItem struct {
+	Parameters []*oaParam   `yaml:"parameters"`
+	Get        *oaOperation `yaml:"get"`
+	Put        *oaOperation `yaml:"put"`
+	Post       *oaOperation `yaml:"post"`
+	Delete     *oaOperation `yaml:"delete"`
+	Options    *oaOperation `yaml:"options"`
+	Head       *oaOperation `yaml:"head"`
+	Patch      *oaOperation `yaml:"patch"`
+}
+
+type oaOperation struct {
+	OperationID string                 `yaml:"operationId"`
+	Summary     string                 `yaml:"summary"`
+	Description string                 `yaml:"description"`
+	Tags        []string               `yaml:"tags"`
+	Parameters  []*oaParam             `yaml:"parameters"`
+	RequestBody *oaRequestBody         `yaml:"requestBody"`
+	Responses   map[string]*oaResp     `yaml:"responses"`
+	Security    *[]map[string][]string `yaml:"security"`
+}
+
+type oaParam struct {
+	Ref         string    `yaml:"$ref"`
+	Name        string    `yaml:"name"`
+	In          string    `yaml:"in"`
+	Description string    `yaml:"description"`
+	Required    bool      `yaml:"required"`
+	Schema      *oaSchema `yaml:"schema"`
+
+	// Swagger 2.0 非 body 参数直接声明类型
+	Type   oaType    `yaml:"type"`
+	Format string    `yaml:"format"`
+	Items  *oaSchema `yaml:"items"`
+	Enum   []any     `yaml:"enum"`
+}
+
+type oaRequestBody struct {
+	Ref      string              `yaml:"$ref"`
+	Required bool                `yaml:"required"`
+	Content  map[string]*oaMedia `yaml:"content"`
+}
+
+type oaResp struct {
+	Ref         string              `yaml:"$ref"`
+	Description string              `yaml:"description"`
+	Content     map[string]*oaMedia `yaml:"content"`
+	Schema      *oaSchema           `yaml:"schema"` // Swagger 2.0
+}
+
+type oaMedia struct {
+	Schema *oaSchema `yaml:"schema"`
+}
+
+type oaSchema struct {
+	Ref                  string       `yaml:"$ref"`
+	Type                 oaType       `yaml:"type"`
+	Format               string       `yaml:"format"`
+	Title                string       `yaml:"title"`
+	Description          string       `yaml:"description"`
+	Nullable             bool         `yaml:"nullable"`
+	Enum                 []any        `yaml:"enum"`
+	Properties           oaProperties `yaml:"properties"`
+	Required             []string     `yaml:"required"`
+	Items                *oaSchema    `yaml:"items"`
+	AdditionalProperties yaml.Node    `yaml:"additionalProperties"`
+	AllOf                []*oaSchema  `yaml:"allOf"`
+	OneOf                []*oaSchema  `yaml:"oneOf"`
+	AnyOf                []*oaSchema  `yaml:"anyOf"`
+	MinLength            *int         `yaml:"minLength"`
+	MaxLength            *int         `yaml:"maxLength"`
+	MinItems             *int         `yaml:"minItems"`
+	MaxItems             *int         `yaml:"maxItems"`
+	Minimum              *float64     `yaml:"minimum"`
+	Maximum              *float64     `yaml:"maximum"`
+	ExclusiveMinimum     any          `yaml:"exclusiveMinimum"` // 3.0 为布尔值，3.1 为数值
+	ExclusiveMaximum     any          `yaml:"exclusiveMaximum"`
+}
+
+// oaType 兼容 3.1 中 type: [string, "null"] 的写法
+type oaType struct {
+	Name     string
+	Nullable bool
+}
+
+func (t *oaType) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		t.Name = node.Value
+		return nil
+	}
+	var names []string
+	if err := node.Decode(&names); err != nil {
+		return err
+	}
+	for _, name := range names {
+		if name == "null" {
+			t.Nullable = true
+		} else if t.Name == "" {
+			t.Name = name
+		}
+	}
+	return nil
+}
+
+// oaProperties 保持文档中的属性顺序
+type oaProperties []oaProperty
+
+type oaProperty struct {
+	Name   string
+	Schema *oaSchema
+}
+
+func (p *oaProperties) UnmarshalYAML(node *yaml.Node) error {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		var schema oaSchema
+		if err := node.Content[i+1].Decode(&schema); err != nil {
+			return err
+		}
+		*p = append(*p, oaProperty{Name: node.Content[i].Value, Schema: &schema})
+	}
+	return nil
+}
+
+// goStruct 生成的结构体
+type goStruct struct {
+	Name    string
+	Comment string
+	Fields  []goField
+}
+
+type goField struct {
+	Name    string
+	Type    string
+	Tag     string
+	Comment string
+}
+
+// importedService 由一个接口生成的服务
+type importedService struct {
+	serviceData
+	Method   string
+	Path     string
+	Request  *goStruct
+	Response *goStruct
+	Structs  []*goStruct // 内联对象生成的类型
+}
+
+// importer OpenAPI 导入器
+type importer struct {
+	doc      *oaDoc
+	names    map[string]bool      // 已使用的Go标识符
+	schemas  map[*oaSchema]string // 组件Schema对应的类型名称
+	models   []*goStruct          // 组件生成的公共类型
+	current  *[]*goStruct         // 当前服务内联生成的类型
+	services []*importedService
+	warnings []string
+}
+
+var (
+	// goInitialisms 生成字段名时保持全大写的缩写
+	goInitialisms = map[string]bool{
+		"ID": true, "URL": true, "URI": true, "API": true, "HTTP": true, "HTTPS": true, "JSON": true, "XML": true,
+		"IP": true, "UUID": true, "SQL": true, "HTML": true, "CPU": true, "UID": true, "UI": true, "SSH": true,
+	}
+	identSplitPattern = regexp.MustCompile(`[^A-Za-z0-9]+`)
+)
+
+// httpMethods 按固定顺序遍历路径下的接口
+var httpMethods = []string{"get", "put", "post", "delete", "options", "head", "patch"}
+
+func runImport(args []string) error {
+	fs := flag.NewFlagSet("mod import", flag.ContinueOnError)
+	dir := fs.String("dir", ".", "生成目录")
+	pkg := fs.String("package", "", "包名，默认沿用目录中已有文件的包名，没有则为 main")
+	force := fs.Bool("force", false, "覆盖已存在的文件")
+
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return fmt.Errorf("用法: mod import <openapi.yaml|openapi.json> [--dir 目录] [--package 包名]")
+	}
+
+	data, err := os.ReadFile(positional[0])
+	if err != nil {
+		return err
+	}
+	var doc oaDoc
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("解析 %s 失败: %w", positional[0], err)
+	}
+	if doc.OpenAPI == "" && doc.Swagger == "" {
+		return fmt.Errorf("%s 不是 OpenAPI/Swagger 文档", positional[0])
+	}
+
+	packageName := *pkg
+	if packageName == "" {
+		packageName = detectPackage(*dir)
+	}
+
+	im := &importer{
+		doc:     &doc,
+		names:   map[string]bool{},
+		schemas: map[*oaSchema]string{},
+	}
+	if err := im.run(packageName); err != nil {
+		return err
+	}
+	if len(im.services) == 0 {
+		return fmt.Errorf("文档中没有接口定义")
+	}
+
+	files := map[string][]byte{}
+	for _, svc := range im.services {
+		files[svc.Name+".go"] = im.renderService(svc)
+	}
+	if len(im.models) > 0 {
+		files["models.go"] = im.renderModels(packageName)
+	}
+	files["services.go"] = im.renderRegistry(packageName)
+
+	if err := os.MkdirAll(*dir, 0755); err != nil {
+		return err
+	}
+	if err := writeFiles(*dir, files, *force); err != nil {
+		return err
+	}
+	for _, w := range im.warnings {
+		fmt.Fprintln(os.Stderr, "  注意:", w)
+	}
+	fmt.Printf("已导入 %d 个服务，请在 main.go 中调用 RegisterServices(app) 完成注册\n", len(im.services))
+	return nil
+}
+
+// run 遍历文档中的接口生成服务
+func (im *importer) run(pkg string) error {
+	used := map[string]string{}
+	for _, entry := range im.doc.Paths {
+		for _, method := range httpMethods {
+			op := entry.Item.operation(method)
+			if op == nil {
+				continue
+			}
+
+			name := serviceNameOf(op.OperationID, method, entry.Path)
+			if prev, ok := used[name]; ok {
+				return fmt.Errorf("接口 %s %s 与 %s 生成的服务名称 %s 重复，请设置不同的 operationId", strings.ToUpper(method), entry.Path, prev, name)
+			}
+			used[name] = strings.ToUpper(method) + " " + entry.Path
+
+			svc := &importedService{
+				serviceData: serviceData{
+					Package:     pkg,
+					Name:        name,
+					Type:        im.uniqueName(toCamel(name)),
+					DisplayName: singleLine(op.Summary),
+					Description: singleLine(op.Description),
+					Sort:        len(im.services) + 1,
+					SkipAuth:    im.skipAuth(op),
+				},
+				Method: strings.ToUpper(method),
+				Path:   entry.Path,
+			}
+			if svc.DisplayName == "" {
+				svc.DisplayName = name
+			}
+			if len(op.Tags) > 0 {
+				svc.Group = op.Tags[0]
+			}
+			im.names["Register"+svc.Type] = true
+
+			im.current = &svc.Structs
+			svc.Request = im.requestStruct(svc, append(entry.Item.Parameters, op.Parameters...), op.RequestBody)
+			svc.Response = im.responseStruct(svc, op.Responses)
+			im.services = append(im.services, svc)
+		}
+	}
+	return nil
+}
+
+func (item *oaPathItem) operation(method string) *oaOperation {
+	switch method {
+	case "get":
+		return item.Get
+	case "put":
+		return item.Put
+	case "post":
+		return item.Post
+	case "delete":
+		return item.Delete
+	case "options":
+		return item.Options
+	case "head":
+		return item.Head
+	case "patch":
+		return item.Patch
+	}
+	return nil
+}
+
+// skipAuth 接口声明了空的 security，或文档没有全局认证要求且接口未声明时跳过认证
+func (im *importer) skipAuth(op *oaOperation) bool {
+	if op.Security != nil {
+		return len(*op.Security) == 0
+	}
+	return len(im.doc.Security) == 0
+}
+
+// requestStruct 生成请求结构体：查询参数和请求头通过 mod 标签读取，路径参数与请求体字段合并为JSON字段
+func (im *importer) requestStruct(svc *importedService, params []*oaParam, body *oaRequestBody) *goStruct {
+	s := &goStruct{Name: im.uniqueName(svc.Type + "Request"), Comment: svc.DisplayName + "请求"}
+	fieldNames := map[string]bool{}
+
+	// 路径级参数可被接口级同名参数覆盖
+	resolved := map[string]*oaParam{}
+	var order []string
+	for _, p := range params {
+		p = im.resolveParam(p)
+		if p == nil {
+			continue
+		}
+		key := p.In + ":" + p.Name
+		if _, ok := resolved[key]; !ok {
+			order = append(order, key)
+		}
+		resolved[key] = p
+	}
+
+	for _, key := range order {
+		p := resolved[key]
+		schema := p.Schema
+		if schema == nil {
+			schema = &oaSchema{Type: p.Type, Format: p.Format, Items: p.Items, Enum: p.Enum}
+		}
+
+		switch p.In {
+		case "body":
+			// Swagger 2.0 请求体参数
+			im.mergeBody(s, fieldNames, svc.Type+"Body", schema)
+			continue
+		case "formData":
+			im.warnings = append(im.warnings, fmt.Sprintf("%s: 表单参数 %s 已转换为JSON字段", svc.Name, p.Name))
+		case "cookie":
+			im.warnings = append(im.warnings, fmt.Sprintf("%s: 忽略Cookie参数 %s", svc.Name, p.Name))
+			continue
+		}
+
+		field := im.field(p.Name, schema, p.Required, svc.Type+goName(p.Name))
+		field.Name = uniqueField(fieldNames, field.Name)
+		switch p.In {
+		case "query":
+			field.Tag += fmt.Sprintf(` mod:"from=query;name=%s"`, p.Name)
+		case "header":
+			field.Tag = strings.Replace(field.Tag, `json:"`+jsonTagOf(p.Name, !p.Required)+`"`, `json:"-"`, 1)
+			field.Tag += fmt.Sprintf(` mod:"from=header;name=%s"`, p.Name)
+		case "path":
+			field.Comment = strings.TrimSpace(field.Comment + " (原路径参数)")
+		}
+		s.Fields = append(s.Fields, field)
+	}
+
+	if body != nil {
+		body = im.resolveRequestBody(body)
+	}
+	if body != nil {
+		if media := jsonMedia(body.Content); media != nil && media.Schema != nil {
+			im.mergeBody(s, fieldNames, svc.Type+"Body", media.Schema)
+		} else if len(body.Content) > 0 {
+			im.warnings = append(im.warnings, fmt.Sprintf("%s: 仅支持JSON请求体，请手动补充请求参数", svc.Name))
+		}
+	}
+	return s
+}
+
+// mergeBody 将请求体合并到请求结构体：对象的字段直接展开，组件类型嵌入，其他类型作为 Body 字段
+func (im *importer) mergeBody(s *goStruct, fieldNames map[string]bool, hint string, schema *oaSchema) {
+	target := im.resolveSchema(schema)
+	if isObject(target) && hasFields(target) && schema.Ref != "" {
+		name := im.goType(schema, hint)
+		if !fieldNames[name] {
+			fieldNames[name] = true
+			s.Fields = append(s.Fields, goField{Name: name})
+			return
+		}
+	}
+	if isObject(target) && hasFields(target) {
+		for _, prop := range im.properties(target) {
+			field := im.field(prop.Name, prop.Schema, contains(prop.required, prop.Name), hint+goName(prop.Name))
+			field.Name = uniqueField(fieldNames, field.Name)
+			s.Fields = append(s.Fields, field)
+		}
+		return
+	}
+	field := im.field("body", schema, true, hint)
+	field.Name = uniqueField(fieldNames, field.Name)
+	s.Fields = append(s.Fields, field)
+}
+
+// responseStruct 生成响应结构体，取第一个2xx响应的JSON内容
+func (im *importer) responseStruct(svc *importedService, responses map[string]*oaResp) *goStruct {
+	s := &goStruct{Name: im.uniqueName(svc.Type + "Response"), Comment: svc.DisplayName + "响应"}
+
+	codes := make([]string, 0, len(responses))
+	for code := range responses {
+		if strings.HasPrefix(code, "2") {
+			codes = append(codes, code)
+		}
+	}
+	sort.Strings(codes)
+	if len(codes) == 0 {
+		if _, ok := responses["default"]; ok {
+			codes = append(codes, "default")
+		}
+	}
+	if len(codes) == 0 {
+		return s
+	}
+
+	resp := im.resolveResponse(responses[codes[0]])
+	if resp == nil {
+		return s
+	}
+	schema := resp.Schema
+	if media := jsonMedia(resp.Content); media != nil {
+		schema = media.Schema
+	}
+	if schema == nil {
+		return s
+	}
+
+	fieldNames := map[string]bool{}
+	target := im.resolveSchema(schema)
+	switch {
+	case isObject(target) && hasFields(target) && schema.Ref != "":
+		s.Fields = append(s.Fields, goField{Name: im.goType(schema, svc.Type+"Data")})
+	case isObject(target) && hasFields(target):
+		for _, prop := range im.properties(target) {
+			field := im.field(prop.Name, prop.Schema, contains(prop.required, prop.Name), svc.Type+goName(prop.Name))
+			field.Name = uniqueField(fieldNames, field.Name)
+			s.Fields = append(s.Fields, field)
+		}
+	case target.Type.Name == "array":
+		field := im.field("items", schema, true, svc.Type+"Item")
+		field.Comment = strings.TrimSpace(field.Comment + " (原响应为数组)")
+		s.Fields = append(s.Fields, field)
+	default:
+		field := im.field("value", schema, true, svc.Type+"Value")
+		field.Comment = strings.TrimSpace(field.Comment + " (原响应为" + target.Type.Name + ")")
+		s.Fields = append(s.Fields, field)
+	}
+	return s
+}
+
+// namedProperty 展开后的属性，required 为所属对象的必填列表
+type namedProperty struct {
+	oaProperty
+	required []string
+}
+
+// properties 返回对象的属性，allOf 的各部分依次合并
+func (im *importer) properties(schema *oaSchema) []namedProperty {
+	var props []namedProperty
+	for _, part := range schema.AllOf {
+		props = append(props, im.properties(im.resolveSchema(part))...)
+	}
+	for _, prop := range schema.Properties {
+		props = append(props, namedProperty{oaProperty: prop, required: schema.Required})
+	}
+	return props
+}
+
+// field 生成结构体字段，包含 json、validate、desc 标签
+func (im *importer) field(name string, schema *oaSchema, required bool, hint string) goField {
+	target := im.resolveSchema(schema)
+	goType := im.goType(schema, hint)
+	if (target.Nullable || target.Type.Nullable) && !required && isScalar(goType) {
+		goType = "*" + goType
+	}
+
+	tags := []string{fmt.Sprintf(`json:"%s"`, jsonTagOf(name, !required))}
+	if rules := validateRules(target, required); rules != "" {
+		tags = append(tags, fmt.Sprintf(`validate:"%s"`, rules))
+	}
+	desc := singleLine(schema.Description)
+	if desc == "" {
+		desc = singleLine(target.Description)
+	}
+	if desc == "" {
+		desc = singleLine(target.Title)
+	}
+	if desc != "" {
+		tags = append(tags, fmt.Sprintf(`desc:"%s"`, tagEscape(desc)))
+	}
+	return goField{Name: goName(name), Type: goType, Tag: strings.Join(tags, " ")}
+}
+
+// validateRules 将Schema约束转换为 validate 规则
+func validateRules(schema *oaSchema, required bool) string {
+	var rules []string
+	typ := schema.Type.Name
+	switch typ {
+	case "string":
+		if schema.MinLength != nil {
+			rules = append(rules, "min="+strconv.Itoa(*schema.MinLength))
+		}
+		if schema.MaxLength != nil {
+			rules = append(rules, "max="+strconv.Itoa(*schema.MaxLength))
+		}
+		switch schema.Format {
+		case "email":
+			rules = append(rules, "email")
+		case "uuid":
+			rules = append(rules, "uuid")
+		case "uri", "url":
+			rules = append(rules, "url")
+		case "ipv4":
+			rules = append(rules, "ipv4")
+		case "ipv6":
+			rules = append(rules, "ipv6")
+		}
+	case "integer", "number":
+		rules = append(rules, boundRule(schema.Minimum, schema.ExclusiveMinimum, "gte", "gt")...)
+		rules = append(rules, boundRule(schema.Maximum, schema.ExclusiveMaximum, "lte", "lt")...)
+	case "array":
+		if schema.MinItems != nil {
+			rules = append(rules, "min="+strconv.Itoa(*schema.MinItems))
+		}
+		if schema.MaxItems != nil {
+			rules = append(rules, "max="+strconv.Itoa(*schema.MaxItems))
+		}
+	}
+	if oneof := enumRule(schema.Enum); oneof != "" && (typ == "string" || typ == "integer" || typ == "number") {
+		rules = append(rules, oneof)
+	}
+
+	if required {
+		return strings.Join(append([]string{"required"}, rules...), ",")
+	}
+	if len(rules) > 0 {
+		return strings.Join(append([]string{"omitempty"}, rules...), ",")
+	}
+	return ""
+}
+
+// boundRule 转换数值范围，exclusive 在 3.0 中为布尔值，在 3.1 中为数值
+func boundRule(bound *float64, exclusive any, inclusiveRule, exclusiveRule string) []string {
+	switch v := exclusive.(type) {
+	case bool:
+		if bound != nil && v {
+			return []string{exclusiveRule + "=" + formatNumber(*bound)}
+		}
+	case int:
+		return []string{exclusiveRule + "=" + strconv.Itoa(v)}
+	case float64:
+		return []string{exclusiveRule + "=" + formatNumber(v)}
+	}
+	if bound != nil {
+		return []string{inclusiveRule + "=" + formatNumber(*bound)}
+	}
+	return nil
+}
+
+// enumRule 转换枚举为 oneof 规则，取值包含 validator 无法表示的字符时忽略
+func enumRule(values []any) string {
+	if len(values) == 0 {
+		return ""
+	}
+	parts := make([]string, 0, len(values))
+	for _, v := range values {
+		if v == nil {
+			continue
+		}
+		s := fmt.Sprint(v)
+		if s == "" || strings.ContainsAny(s, `,|'"`+"`") {
+			return ""
+		}
+		if strings.ContainsAny(s, " \t") {
+			s = "'" + s + "'"
+		}
+		parts = append(parts, s)
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return "oneof=" + strings.Join(parts, " ")
+}
+
+func formatNumber(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// goType 返回Schema对应的Go类型，对象类型生成结构体，hint 为内联对象的类型名称
+func (im *importer) goType(schema *oaSchema, hint string) string {
+	if schema == nil {
+		return "any"
+	}
+	if schema.Ref != "" {
+		target := im.resolveSchema(schema)
+		if name, ok := im.schemas[target]; ok {
+			return name
+		}
+		if isObject(target) && hasFields(target) {
+			name := im.uniqueName(goName(refName(schema.Ref)))
+			im.schemas[target] = name
+			saved := im.current
+			im.current = &im.models
+			im.buildStruct(name, target)
+			im.current = saved
+			return name
+		}
+		return im.goType(target, goName(refName(schema.Ref)))
+	}
+
+	switch {
+	case len(schema.OneOf) > 0 || len(schema.AnyOf) > 0:
+		return "any"
+	case len(schema.AllOf) == 1 && len(schema.Properties) == 0:
+		return im.goType(schema.AllOf[0], hint)
+	case isObject(schema) && hasFields(schema):
+		name := im.uniqueName(hint)
+		im.buildStruct(name, schema)
+		return name
+	}
+
+	switch schema.Type.Name {
+	case "string":
+		switch schema.Format {
+		case "date-time":
+			return "time.Time"
+		case "byte":
+			return "[]byte"
+		}
+		return "string"
+	case "integer":
+		switch schema.Format {
+		case "int32":
+			return "int32"
+		case "int64":
+			return "int64"
+		}
+		return "int"
+	case "number":
+		if schema.Format == "float" {
+			return "float32"
+		}
+		return "float64"
+	case "boolean":
+		return "bool"
+	case "array":
+		return "[]" + im.goType(schema.Items, strings.TrimSuffix(hint, "s")+"Item")
+	case "object":
+		if schema.AdditionalProperties.Kind == yaml.MappingNode {
+			var value oaSchema
+			if err := schema.AdditionalProperties.Decode(&value); err == nil {
+				return "map[string]" + im.goType(&value, hint+"Value")
+			}
+		}
+		return "map[string]any"
+	}
+	return "any"
+}
+
+// buildStruct 生成结构体，先登记名称以支持递归引用
+func (im *importer) buildStruct(name string, schema *oaSchema) {
+	s := &goStruct{Name: name, Comment: singleLine(schema.Description)}
+	if s.Comment == "" {
+		s.Comment = singleLine(schema.Title)
+	}
+	*im.current = append(*im.current, s)
+
+	fieldNames := map[string]bool{}
+	for _, prop := range im.properties(schema) {
+		field := im.field(prop.Name, prop.Schema, contains(prop.required, prop.Name), name+goName(prop.Name))
+		if field.Type == name {
+			field.Type = "*" + name
+		}
+		field.Name = uniqueField(fieldNames, field.Name)
+		s.Fields = append(s.Fields, field)
+	}
+}
+
+// resolveSchema 解析 $ref 引用的组件Schema
+func (im *importer) resolveSchema(schema *oaSchema) *oaSchema {
+	for depth := 0; schema != nil && schema.Ref != "" && depth < 16; depth++ {
+		name := refName(schema.Ref)
+		next := im.doc.Components.Schemas[name]
+		if next == nil {
+			next = im.doc.Definitions[name]
+		}
+		if next == nil {
+			im.warnings = append(im.warnings, "无法解析引用 "+schema.Ref)
+			return &oaSchema{}
+		}
+		schema = next
+	}
+	if schema == nil {
+		return &oaSchema{}
+	}
+	return schema
+}
+
+func (im *importer) resolveParam(p *oaParam) *oaParam {
+	if p == nil || p.Ref == "" {
+		return p
+	}
+	name := refName(p.Ref)
+	if resolved := im.doc.Components.Parameters[name]; resolved != nil {
+		return resolved
+	}
+	if resolved := im.doc.Parameters[name]; resolved != nil {
+		return resolved
+	}
+	im.warnings = append(im.warnings, "无法解析引用 "+p.Ref)
+	return nil
+}
+
+func (im *importer) resolveRequestBody(body *oaRequestBody) *oaRequestBody {
+	if body.Ref == "" {
+		return body
+	}
+	if resolved := im.doc.Components.RequestBodies[refName(body.Ref)]; resolved != nil {
+		return resolved
+	}
+	im.warnings = append(im.warnings, "无法解析引用 "+body.Ref)
+	return nil
+}
+
+func (im *importer) resolveResponse(resp *oaResp) *oaResp {
+	if resp == nil || resp.Ref == "" {
+		return resp
+	}
+	name := refName(resp.Ref)
+	if resolved := im.doc.Components.Responses[name]; resolved != nil {
+		return resolved
+	}
+	if resolved := im.doc.Responses[name]; resolved != nil {
+		return resolved
+	}
+	im.warnings = append(im.warnings, "无法解析引用 "+resp.Ref)
+	return nil
+}
+
+// uniqueName 返回包内唯一的类型名称
+func (im *importer) uniqueName(name string) string {
+	candidate := name
+	for i := 2; im.names[candidate]; i++ {
+		candidate = fmt.Sprintf("%s%d", name, i)
+	}
+	im.names[candidate] = true
+	return candidate
+}
+
+// renderService 生成单个服务文件
+func (im *importer) renderService(svc *importedService) []byte {
+	var body bytes.Buffer
+	writeStruct(&body, svc.Request)
+	writeStruct(&body, svc.Response)
+	for _, s := range svc.Structs {
+		writeStruct(&body, s)
+	}
+
+	fmt.Fprintf(&body, "\n// %s %s\n// 原接口: %s %s\n", svc.Type, svc.DisplayName, svc.Method, svc.Path)
+	fmt.Fprintf(&body, "func %s(ctx *mod.Context, req *%s, resp *%s) error {\n", svc.Type, svc.Request.Name, svc.Response.Name)
+	body.WriteString("\t// TODO: 实现业务逻辑，失败时返回 mod.Reply(code, msg)\n\treturn nil\n}\n")
+
+	fmt.Fprintf(&body, "\n// Register%s 注册%s服务\nfunc Register%s(app *mod.App) error {\n", svc.Type, svc.DisplayName, svc.Type)
+	fmt.Fprintf(&body, "\treturn app.Register(mod.Service{\n")
+	fmt.Fprintf(&body, "\t\tName: %q,\n\t\tDisplayName: %q,\n\t\tDescription: %q,\n\t\tGroup: %q,\n\t\tSort: %d,\n\t\tSkipAuth: %t,\n",
+		svc.Name, svc.DisplayName, svc.Description, svc.Group, svc.Sort, svc.SkipAuth)
+	fmt.Fprintf(&body, "\t\tHandler: mod.MakeHandler(%s),\n\t})\n}\n", svc.Type)
+
+	return withImports(svc.Package, body.Bytes(), "github.com/iamdanielyin/mod")
+}
+
+// renderModels 生成组件公共类型文件
+func (im *importer) renderModels(pkg string) []byte {
+	var body bytes.Buffer
+	for _, s := range im.models {
+		writeStruct(&body, s)
+	}
+	return withImports(pkg, body.Bytes())
+}
+
+// renderRegistry 生成统一注册函数
+func (im *importer) renderRegistry(pkg string) []byte {
+	var body bytes.Buffer
+	body.WriteString("\n// RegisterServices 注册由 OpenAPI 文档导入的全部服务\n")
+	body.WriteString("func RegisterServices(app *mod.App) error {\n\tfor _, register := range []func(*mod.App) error{\n")
+	for _, svc := range im.services {
+		fmt.Fprintf(&body, "\t\tRegister%s,\n", svc.Type)
+	}
+	body.WriteString("\t} {\n\t\tif err := register(app); err != nil {\n\t\t\treturn err\n\t\t}\n\t}\n\treturn nil\n}\n")
+	return withImports(pkg, body.Bytes(), "github.com/iamdanielyin/mod")
+}
+
+// withImports 拼接包声明和导入，time 包按需导入
+func withImports(pkg string, body []byte, imports ...string) []byte {
+	if bytes.Contains(body, []byte("time.Time")) {
+		imports = append([]string{"time"}, imports...)
+	}
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "package %s\n", pkg)
+	if len(imports) > 0 {
+		out.WriteString("\nimport (\n")
+		for i, path := range imports {
+			if i > 0 && path != "time" && imports[i-1] == "time" {
+				out.WriteString("\n")
+			}
+			fmt.Fprintf(&out, "\t%q\n", path)
+		}
+		out.WriteString(")\n")
+	}
+	out.Write(body)
+	return out.Bytes()
+}
+
+func writeStruct(b *bytes.Buffer, s *goStruct) {
+	comment := s.Comment
+	if comment == "" {
+		comment = "由 OpenAPI 文档导入"
+	}
+	fmt.Fprintf(b, "\n// %s %s\ntype %s struct {\n", s.Name, comment, s.Name)
+	for _, f := range s.Fields {
+		if f.Type == "" {
+			// 嵌入组件类型
+			fmt.Fprintf(b, "\t%s\n", f.Name)
+			continue
+		}
+		fmt.Fprintf(b, "\t%s %s `%s`", f.Name, f.Type, f.Tag)
+		if f.Comment != "" {
+			fmt.Fprintf(b, " // %s", f.Comment)
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("}\n")
+}
+
+// serviceNameOf 由 operationId 或请求方法和路径生成服务名称，如 getUserById -> get_user_by_id
+func serviceNameOf(operationID, method, path string) string {
+	source := operationID
+	if source == "" {
+		source = method + "_" + strings.NewReplacer("{", "by_", "}", "").Replace(path)
+	}
+
+	var b strings.Builder
+	runes := []rune(source)
+	for i, r := range runes {
+		switch {
+		case unicode.IsUpper(r):
+			if i > 0 && (unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1]) ||
+				(i+1 < len(runes) && unicode.IsLower(runes[i+1]) && unicode.IsUpper(runes[i-1]))) {
+				b.WriteByte('_')
+			}
+			b.WriteRune(unicode.ToLower(r))
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			b.WriteRune(r)
+		default:
+			b.WriteByte('_')
+		}
+	}
+
+	name := strings.Trim(regexp.MustCompile(`_+`).ReplaceAllString(b.String(), "_"), "_")
+	if name == "" || !serviceNamePattern.MatchString(name) {
+		name = "op_" + name
+	}
+	return name
+}
+
+// goName 将属性名转换为导出的Go标识符，常见缩写保持全大写，如 user_id -> UserID
+func goName(name string) string {
+	var b strings.Builder
+	for _, part := range identSplitPattern.Split(name, -1) {
+		for _, word := range splitCamel(part) {
+			if goInitialisms[strings.ToUpper(word)] {
+				b.WriteString(strings.ToUpper(word))
+			} else {
+				b.WriteString(strings.ToUpper(word[:1]) + word[1:])
+			}
+		}
+	}
+	result := b.String()
+	if result == "" || unicode.IsDigit(rune(result[0])) {
+		result = "X" + result
+	}
+	return result
+}
+
+// splitCamel 按大小写边界拆分单词，如 userId -> [user Id]
+func splitCamel(s string) []string {
+	var words []string
+	start := 0
+	for i := 1; i < len(s); i++ {
+		if unicode.IsUpper(rune(s[i])) && !unicode.IsUpper(rune(s[i-1])) {
+			words = append(words, s[start:i])
+			start = i
+		}
+	}
+	if start < len(s) {
+		words = append(words, s[start:])
+	}
+	return words
+}
+
+// uniqueField 返回结构体内唯一的字段名
+func uniqueField(used map[string]bool, name string) string {
+	candidate := name
+	for i := 2; used[candidate]; i++ {
+		candidate = fmt.Sprintf("%s%d", name, i)
+	}
+	used[candidate] = true
+	return candidate
+}
+
+func jsonTagOf(name string, omitempty bool) string {
+	if omitempty {
+		return name + ",omitempty"
+	}
+	return name
+}
+
+func jsonMedia(content map[string]*oaMedia) *oaMedia {
+	if media, ok := content["application/json"]; ok {
+		return media
+	}
+	for mediaType, media := range content {
+		if strings.HasSuffix(mediaType, "+json") || strings.HasPrefix(mediaType, "*/*") {
+			return media
+		}
+	}
+	return nil
+}
+
+func isObject(schema *oaSchema) bool {
+	return schema.Type.Name == "object" || (schema.Type.Name == "" && hasFields(schema))
+}
+
+// hasFields 对象声明了属性或由 allOf 组合而成时生成结构体
+func hasFields(schema *oaSchema) bool {
+	return len(schema.Properties) > 0 || len(schema.AllOf) > 0
+}
+
+func isScalar(goType string) bool {
+	switch goType {
+	case "string", "int", "int32", "int64", "float32", "float64", "bool", "time.Time":
+		return true
+	}
+	return false
+}
+
+func refName(ref string) string {
+	return ref[strings.LastIndex(ref, "/")+1:]
+}
+
+func contains(items []string, target string) bool {
+	for _, item := range items {
+		if item == target {
+			return true
+		}
+	}
+	return false
+}
+
+func singleLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// tagEscape 去除结构体标签中无法表示的字符
+func tagEscape(s string) string {
+	return strings.NewReplacer(`"`, "'", "`", "'", `\`, "/").Replace(s)
+}
@@ -0,0 +1,86 @@
+// mod 命令行工具
+//
+//	mod new app <name> [--module path] [--display 显示名称]
+//	mod new service <name> [--group 分组] [--display 显示名称] [--dir 目录]
+//	mod gen [--type 类型列表] [--output 文件名] [--tags 构建标签]
+//	mod import <openapi.yaml> [--dir 目录] [--package 包名]
+//	mod config encrypt|decrypt [value] [--key-env 环境变量] [--key-file 文件]
+//	mod diff --old <spec.json> --new <spec.json> [--all] [--json]
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const usage = `mod - MOD框架命令行工具
+
+用法:
+  mod new app <name>          创建新项目（main.go、mod.yml、go.mod 及示例服务）
+  mod new service <name>      创建服务（请求/响应结构体、处理函数、注册函数及测试）
+  mod gen                     为请求/响应类型生成参数绑定、校验和JSON序列化代码（配合 go:generate 使用）
+  mod import <file>           从 OpenAPI/Swagger 文档生成服务定义、请求/响应结构体及空处理函数
+  mod config encrypt [value]  使用主密钥加密配置值，输出可写入 mod.yml 的 !enc AES:... 形式
+  mod config decrypt [value]  解密配置值，用于核对
+  mod diff --old a --new b    比较两个版本的 OpenAPI 文档，存在不兼容变更（删除字段、修改类型、新增必填字段等）时退出码为1
+
+执行 mod <command> -h 查看命令参数
+`
+
+func main() {
+	if err := run(os.Args[1:]); err != nil && !errors.Is(err, flag.ErrHelp) {
+		fmt.Fprintln(os.Stderr, "错误:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "--help" || args[0] == "help" {
+		fmt.Print(usage)
+		return nil
+	}
+
+	switch args[0] {
+	case "new":
+		return runNew(args[1:])
+	case "gen":
+		return runGen(args[1:])
+	case "import":
+		return runImport(args[1:])
+	case "config":
+		return runConfig(args[1:])
+	case "diff":
+		return runDiff(args[1:])
+	default:
+		fmt.Print(usage)
+		return fmt.Errorf("未知命令 %s", args[0])
+	}
+}
+
+// parseFlags 解析参数，允许标志出现在位置参数之后（如 mod new service get_user --group 用户管理）
+func parseFlags(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// toCamel 将下划线或中划线分隔的名称转换为驼峰形式，如 get_user -> GetUser
+func toCamel(name string) string {
+	var b strings.Builder
+	for _, part := range strings.FieldsFunc(name, func(r rune) bool { return r == '_' || r == '-' || r == '.' }) {
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return b.String()
+}
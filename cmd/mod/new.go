@@ -0,0 +1,348 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// serviceNamePattern 服务名称只允许小写字母、数字和下划线，与 /services/<name> 路径保持一致
+var serviceNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// serviceData 服务模板数据
+type serviceData struct {
+	Package     string
+	Name        string
+	Type        string
+	DisplayName string
+	Description string
+	Group       string
+	Sort        int
+	SkipAuth    bool
+}
+
+var serviceTemplate = template.Must(template.New("service").Parse(`package {{.Package}}
+
+import (
+	"github.com/iamdanielyin/mod"
+)
+
+// {{.Type}}Request {{.DisplayName}}请求
+type {{.Type}}Request struct {
+	// TODO: 定义请求参数，如 ID string ` + "`" + `json:"id" validate:"required" desc:"ID"` + "`" + `
+}
+
+// {{.Type}}Response {{.DisplayName}}响应
+type {{.Type}}Response struct {
+	// TODO: 定义响应数据
+}
+
+// {{.Type}} {{.DisplayName}}
+func {{.Type}}(ctx *mod.Context, req *{{.Type}}Request, resp *{{.Type}}Response) error {
+	// TODO: 实现业务逻辑，失败时返回 mod.Reply(code, msg)
+	return nil
+}
+
+// Register{{.Type}} 注册{{.DisplayName}}
+func Register{{.Type}}(app *mod.App) error {
+	return app.Register(mod.Service{
+		Name:        {{printf "%q" .Name}},
+		DisplayName: {{printf "%q" .DisplayName}},
+		Description: {{printf "%q" .Description}},
+		Group:       {{printf "%q" .Group}},
+		Sort:        {{.Sort}},
+		SkipAuth:    {{.SkipAuth}},
+		Handler:     mod.MakeHandler({{.Type}}),
+	})
+}
+`))
+
+var serviceTestTemplate = template.Must(template.New("service_test").Parse(`package {{.Package}}
+
+import (
+	"testing"
+
+	"github.com/iamdanielyin/mod"
+	"github.com/iamdanielyin/mod/modtest"
+)
+
+func Test{{.Type}}(t *testing.T) {
+	app := mod.New()
+	if err := Register{{.Type}}(app); err != nil {
+		t.Fatal(err)
+	}
+
+	h := modtest.New(app, modtest.WithoutAuth(), modtest.WithMock(false))
+
+	resp, _, err := modtest.Invoke[{{.Type}}Request, {{.Type}}Response](h, "{{.Name}}", &{{.Type}}Request{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp // TODO: 断言响应数据
+}
+`))
+
+// appData 项目模板数据
+type appData struct {
+	Name        string
+	Module      string
+	DisplayName string
+	GoVersion   string
+}
+
+var appMainTemplate = template.Must(template.New("main").Parse(`package main
+
+import (
+	"github.com/iamdanielyin/mod"
+)
+
+func main() {
+	app := mod.New()
+
+	// 注册服务
+	for _, register := range []func(*mod.App) error{
+		RegisterHello,
+	} {
+		if err := register(app); err != nil {
+			app.WithError(err).Fatal("Failed to register service")
+		}
+	}
+
+	app.Run()
+}
+`))
+
+var appConfigTemplate = template.Must(template.New("mod.yml").Parse(`# mod.yml - 完整配置项参考 https://github.com/iamdanielyin/mod/blob/main/mod.yml.example
+
+app:
+  name: "{{.Name}}"
+  display_name: "{{.DisplayName}}"
+  description: ""
+  version: "0.1.0"
+  service_base: "/services"
+
+server:
+  host: "0.0.0.0"
+  port: 8080
+
+logging:
+  console:
+    enabled: true
+    level: "info"
+
+mock:
+  global:
+    enabled: false
+`))
+
+var appGoModTemplate = template.Must(template.New("go.mod").Parse(`module {{.Module}}
+
+go {{.GoVersion}}
+`))
+
+func runNew(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("用法: mod new app <name> | mod new service <name>")
+	}
+	switch args[0] {
+	case "app":
+		return runNewApp(args[1:])
+	case "service":
+		return runNewService(args[1:])
+	default:
+		return fmt.Errorf("未知类型 %s，可选 app、service", args[0])
+	}
+}
+
+func runNewService(args []string) error {
+	fs := flag.NewFlagSet("mod new service", flag.ContinueOnError)
+	group := fs.String("group", "", "文档分组")
+	display := fs.String("display", "", "显示名称，默认为服务名称")
+	desc := fs.String("desc", "", "服务描述")
+	sortValue := fs.Int("sort", 0, "文档排序值")
+	skipAuth := fs.Bool("skip-auth", false, "跳过身份验证")
+	dir := fs.String("dir", ".", "生成目录")
+	pkg := fs.String("package", "", "包名，默认沿用目录中已有文件的包名，没有则为 main")
+	noTest := fs.Bool("no-test", false, "不生成测试文件")
+	force := fs.Bool("force", false, "覆盖已存在的文件")
+
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return fmt.Errorf("用法: mod new service <name> [--group 分组] [--display 显示名称]")
+	}
+
+	name := positional[0]
+	if !serviceNamePattern.MatchString(name) {
+		return fmt.Errorf("服务名称 %s 无效，只能包含小写字母、数字和下划线，且以字母开头", name)
+	}
+
+	data := serviceData{
+		Package:     *pkg,
+		Name:        name,
+		Type:        toCamel(name),
+		DisplayName: *display,
+		Description: *desc,
+		Group:       *group,
+		Sort:        *sortValue,
+		SkipAuth:    *skipAuth,
+	}
+	if data.DisplayName == "" {
+		data.DisplayName = name
+	}
+	if data.Package == "" {
+		data.Package = detectPackage(*dir)
+	}
+
+	if err := os.MkdirAll(*dir, 0755); err != nil {
+		return err
+	}
+	files := map[string]*template.Template{name + ".go": serviceTemplate}
+	if !*noTest {
+		files[name+"_test.go"] = serviceTestTemplate
+	}
+	if err := writeTemplates(*dir, files, data, *force); err != nil {
+		return err
+	}
+
+	fmt.Printf("已创建服务 %s，请在 main.go 中调用 Register%s(app) 完成注册\n", name, data.Type)
+	return nil
+}
+
+func runNewApp(args []string) error {
+	fs := flag.NewFlagSet("mod new app", flag.ContinueOnError)
+	module := fs.String("module", "", "Go模块路径，默认为项目名称")
+	display := fs.String("display", "", "显示名称，默认为项目名称")
+	dir := fs.String("dir", "", "生成目录，默认为项目名称")
+	force := fs.Bool("force", false, "覆盖已存在的文件")
+
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return fmt.Errorf("用法: mod new app <name> [--module 模块路径]")
+	}
+
+	name := positional[0]
+	data := appData{
+		Name:        name,
+		Module:      *module,
+		DisplayName: *display,
+		GoVersion:   "1.24",
+	}
+	if data.Module == "" {
+		data.Module = name
+	}
+	if data.DisplayName == "" {
+		data.DisplayName = name
+	}
+	target := *dir
+	if target == "" {
+		target = name
+	}
+
+	if err := os.MkdirAll(target, 0755); err != nil {
+		return err
+	}
+	if err := writeTemplates(target, map[string]*template.Template{
+		"main.go": appMainTemplate,
+		"mod.yml": appConfigTemplate,
+		"go.mod":  appGoModTemplate,
+	}, data, *force); err != nil {
+		return err
+	}
+
+	hello := serviceData{
+		Package:     "main",
+		Name:        "hello",
+		Type:        "Hello",
+		DisplayName: "示例服务",
+		Description: "由 mod new app 生成的示例服务",
+		Group:       "示例",
+		Sort:        1,
+		SkipAuth:    true,
+	}
+	if err := writeTemplates(target, map[string]*template.Template{
+		"hello.go":      serviceTemplate,
+		"hello_test.go": serviceTestTemplate,
+	}, hello, *force); err != nil {
+		return err
+	}
+
+	fmt.Printf("已创建项目 %s\n\n  cd %s\n  go mod tidy\n  go run .\n\n访问 http://localhost:8080/services/docs 查看接口文档\n", name, target)
+	return nil
+}
+
+// writeTemplates 渲染模板并写入目录，Go文件会经过 gofmt 格式化
+func writeTemplates(dir string, files map[string]*template.Template, data any, force bool) error {
+	contents := make(map[string][]byte, len(files))
+	for filename, tmpl := range files {
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, data); err != nil {
+			return fmt.Errorf("生成 %s 失败: %w", filename, err)
+		}
+		contents[filename] = buf.Bytes()
+	}
+	return writeFiles(dir, contents, force)
+}
+
+// writeFiles 将文件写入目录，已存在的文件需要 force 才会覆盖，Go文件会经过 gofmt 格式化
+func writeFiles(dir string, files map[string][]byte, force bool) error {
+	for filename := range files {
+		path := filepath.Join(dir, filename)
+		if _, err := os.Stat(path); err == nil && !force {
+			return fmt.Errorf("文件 %s 已存在，使用 --force 覆盖", path)
+		}
+	}
+
+	names := make([]string, 0, len(files))
+	for filename := range files {
+		names = append(names, filename)
+	}
+	sort.Strings(names)
+
+	for _, filename := range names {
+		content := files[filename]
+		if filepath.Ext(filename) == ".go" {
+			formatted, err := format.Source(content)
+			if err != nil {
+				return fmt.Errorf("格式化 %s 失败: %w", filename, err)
+			}
+			content = formatted
+		}
+
+		path := filepath.Join(dir, filename)
+		if err := os.WriteFile(path, content, 0644); err != nil {
+			return err
+		}
+		fmt.Println("  创建", path)
+	}
+	return nil
+}
+
+// detectPackage 读取目录中已有Go文件的包名
+func detectPackage(dir string) string {
+	matches, _ := filepath.Glob(filepath.Join(dir, "*.go"))
+	fset := token.NewFileSet()
+	for _, path := range matches {
+		file, err := parser.ParseFile(fset, path, nil, parser.PackageClauseOnly)
+		if err == nil {
+			if name := file.Name.Name; !strings.HasSuffix(name, "_test") {
+				return name
+			}
+		}
+	}
+	return "main"
+}
@@ -0,0 +1,771 @@
+package mod
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+)
+
+// 以下接口由 mod gen 生成的代码实现（使用 -tags modgen 构建时生效），
+// 注册服务时检测请求/响应类型是否实现了这些接口，实现了则跳过基于反射的绑定和校验
+
+// ParamBinder 请求参数绑定，替代基于反射的请求体解析和参数读取
+type ParamBinder interface {
+	BindParams(c *fiber.Ctx) error
+}
+
+// RequestValidator 请求参数校验，替代基于反射的 validate 标签校验
+type RequestValidator interface {
+	ValidateRequest() error
+}
+
+// ParamValue 按 mod 标签的规则读取参数，from 为空时依次尝试 query、form、header（与反射绑定的规则一致）
+func ParamValue(c *fiber.Ctx, from, name, fieldName string) string {
+	switch from {
+	case "query":
+		return c.Query(name)
+	case "header":
+		return c.Get(name)
+	case "form":
+		return c.FormValue(name)
+	case "param":
+		return pathParamValue(c, name)
+	case "body":
+		// 请求体字段已由请求体解析赋值
+		return ""
+	case "":
+		for _, key := range []string{name, fieldName} {
+			if v := c.Query(key); v != "" {
+				return v
+			}
+			if v := c.FormValue(key); v != "" {
+				return v
+			}
+			if v := c.Get(key); v != "" {
+				return v
+			}
+		}
+		return ""
+	default:
+		return c.Query(name)
+	}
+}
+
+// ValidationErrors 生成代码的校验错误，格式与 validator 一致
+type ValidationErrors []string
+
+func (e ValidationErrors) Error() string {
+	return strings.Join(e, "\n")
+}
+
+// ValidationFailure 生成与 validator 格式一致的单条校验错误
+func ValidationFailure(namespace, field, tag string) string {
+	return fmt.Sprintf("Key: '%s' Error:Field validation for '%s' failed on the '%s' tag", namespace, field, tag)
+}
+
+// ValidateVar 使用 validator 校验单个字段，用于生成代码不支持的校验规则；
+// dive 规则产生的元素错误保留元素的命名空间（如 Items[0].Name），与结构体校验一致
+func ValidateVar(namespace, field string, value any, tag string) []string {
+	err := validate.Var(value, tag)
+	if err == nil {
+		return nil
+	}
+
+	var fieldErrors validator.ValidationErrors
+	if !errors.As(err, &fieldErrors) {
+		return []string{err.Error()}
+	}
+	failures := make([]string, 0, len(fieldErrors))
+	for _, fe := range fieldErrors {
+		ns, name := namespace, field
+		// validate.Var 的元素错误命名空间以 [下标] 开头，元素本身的字段名为 [下标]
+		if sub := fe.Namespace(); strings.HasPrefix(sub, "[") {
+			ns, name = namespace+sub, fe.Field()
+			if strings.HasPrefix(name, "[") {
+				name = field + name
+			}
+		}
+		failures = append(failures, ValidationFailure(ns, name, fe.Tag()))
+	}
+	return failures
+}
+
+// ValidateStruct 使用 validator 校验结构体，用于未生成校验代码的嵌套类型
+func ValidateStruct(value any) []string {
+	if err := validate.Struct(value); err != nil {
+		return strings.Split(err.Error(), "\n")
+	}
+	return nil
+}
+
+// JSONBodyError 返回生成代码解析JSON请求体失败时的错误：使用 encoding/json 重新解析（忽略生成的解析方法），
+// 使错误信息与 BindBody 一致；v 为请求结构体指针，err 为生成代码返回的错误，无法重新解析时使用
+func JSONBodyError(body []byte, v any, err error) error {
+	rt := reflect.TypeOf(v)
+	if rt != nil && rt.Kind() == reflect.Pointer && rt.Elem().Kind() == reflect.Struct {
+		plain := reflect.New(plainType(rt.Elem()))
+		if jsonErr := json.Unmarshal(body, plain.Interface()); jsonErr != nil {
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(jsonErr, &typeErr) {
+				typeErr.Struct = rt.Elem().Name()
+				if origin, ok := plainOrigins.Load(typeErr.Type); ok {
+					typeErr.Type = origin.(reflect.Type)
+				}
+			}
+			err = jsonErr
+		}
+	}
+	return fmt.Errorf("failed to parse JSON body: %w", err)
+}
+
+var (
+	plainTypes   sync.Map // 原类型 -> 不含生成方法的类型
+	plainOrigins sync.Map // 不含生成方法的类型 -> 原类型
+)
+
+// plainType 返回与 t 的JSON结构相同、但其中生成了代码的结构体替换为不含方法的结构体的类型，
+// 使 encoding/json 按反射解析；无法构造时返回 t
+func plainType(t reflect.Type) (plain reflect.Type) {
+	if cached, ok := plainTypes.Load(t); ok {
+		return cached.(reflect.Type)
+	}
+	defer func() {
+		if recover() != nil {
+			plain = t
+		}
+		plainTypes.Store(t, plain)
+		if plain != t {
+			plainOrigins.Store(plain, t)
+		}
+	}()
+	return buildPlainType(t, map[reflect.Type]bool{})
+}
+
+func buildPlainType(t reflect.Type, seen map[reflect.Type]bool) reflect.Type {
+	switch t.Kind() {
+	case reflect.Pointer, reflect.Slice, reflect.Array, reflect.Map:
+		elem := buildPlainType(t.Elem(), seen)
+		if elem == t.Elem() {
+			return t
+		}
+		var plain reflect.Type
+		switch t.Kind() {
+		case reflect.Pointer:
+			plain = reflect.PointerTo(elem)
+		case reflect.Slice:
+			plain = reflect.SliceOf(elem)
+		case reflect.Array:
+			plain = reflect.ArrayOf(t.Len(), elem)
+		default:
+			plain = reflect.MapOf(t.Key(), elem)
+		}
+		plainOrigins.Store(plain, t)
+		return plain
+	case reflect.Struct:
+		// 只替换 mod gen 生成了解析方法的结构体，递归类型保留原类型
+		if _, ok := reflect.PointerTo(t).MethodByName("ModDecodeJSON"); !ok || seen[t] {
+			return t
+		}
+		seen[t] = true
+		fields := make([]reflect.StructField, 0, t.NumField())
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			if !f.Anonymous {
+				f.Type = buildPlainType(f.Type, seen)
+			}
+			fields = append(fields, f)
+		}
+		plain := reflect.StructOf(fields)
+		plainOrigins.Store(plain, t)
+		return plain
+	}
+	return t
+}
+
+// JSONFieldIndex 返回JSON成员名称对应的字段序号，优先精确匹配，其次忽略大小写匹配（与 encoding/json 一致），未匹配返回-1
+func JSONFieldIndex(key []byte, names []string) int {
+	for i, name := range names {
+		if name == string(key) {
+			return i
+		}
+	}
+	for i, name := range names {
+		if bytes.EqualFold([]byte(name), key) {
+			return i
+		}
+	}
+	return -1
+}
+
+// IsEmptyValue 判断值是否为 omitempty 意义上的空值，用于生成代码不支持的字段类型
+func IsEmptyValue(v any) bool {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Invalid:
+		return true
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return rv.Len() == 0
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64,
+		reflect.Interface, reflect.Pointer:
+		return rv.IsZero()
+	}
+	return false
+}
+
+// JSONLexer 生成代码使用的JSON读取器，遇到错误后所有读取均返回零值，通过 Err 获取错误
+type JSONLexer struct {
+	data []byte
+	pos  int
+	err  error
+}
+
+// NewJSONLexer 创建JSON读取器
+func NewJSONLexer(data []byte) *JSONLexer {
+	return &JSONLexer{data: data}
+}
+
+// Err 返回读取过程中的第一个错误
+func (l *JSONLexer) Err() error {
+	return l.err
+}
+
+// AddError 记录错误，已有错误时忽略
+func (l *JSONLexer) AddError(err error) {
+	if l.err == nil {
+		l.err = err
+	}
+}
+
+func (l *JSONLexer) errorf(format string, args ...any) {
+	l.AddError(fmt.Errorf("json: "+format+" at offset %d", append(args, l.pos)...))
+}
+
+func (l *JSONLexer) skipSpace() {
+	for l.pos < len(l.data) {
+		switch l.data[l.pos] {
+		case ' ', '\t', '\n', '\r':
+			l.pos++
+		default:
+			return
+		}
+	}
+}
+
+func (l *JSONLexer) peek() byte {
+	l.skipSpace()
+	if l.pos >= len(l.data) {
+		return 0
+	}
+	return l.data[l.pos]
+}
+
+func (l *JSONLexer) expect(c byte) bool {
+	if l.err != nil {
+		return false
+	}
+	if l.peek() != c {
+		l.errorf("expected %q", c)
+		return false
+	}
+	l.pos++
+	return true
+}
+
+func (l *JSONLexer) literal(word string) bool {
+	if l.err != nil {
+		return false
+	}
+	l.skipSpace()
+	if !bytes.HasPrefix(l.data[l.pos:], []byte(word)) {
+		l.errorf("invalid literal")
+		return false
+	}
+	l.pos += len(word)
+	return true
+}
+
+// End 确认数据已读取完毕，仅允许剩余空白字符
+func (l *JSONLexer) End() {
+	if l.err == nil && l.peek() != 0 {
+		l.errorf("unexpected data after top-level value")
+	}
+}
+
+// IsNull 当前值为null时读取并返回true
+func (l *JSONLexer) IsNull() bool {
+	if l.err != nil || l.peek() != 'n' {
+		return false
+	}
+	return l.literal("null")
+}
+
+// Object 读取对象，对每个成员调用 fn，fn 必须读取该成员的值；key 仅在 fn 执行期间有效
+func (l *JSONLexer) Object(fn func(key []byte)) {
+	if !l.expect('{') {
+		return
+	}
+	if l.peek() == '}' {
+		l.pos++
+		return
+	}
+	for l.err == nil {
+		key := l.stringBytes()
+		if !l.expect(':') {
+			return
+		}
+		fn(key)
+		if l.err != nil {
+			return
+		}
+		switch l.peek() {
+		case ',':
+			l.pos++
+		case '}':
+			l.pos++
+			return
+		default:
+			l.errorf("expected ',' or '}'")
+		}
+	}
+}
+
+// Array 读取数组，对每个元素调用 fn，fn 必须读取该元素
+func (l *JSONLexer) Array(fn func()) {
+	if !l.expect('[') {
+		return
+	}
+	if l.peek() == ']' {
+		l.pos++
+		return
+	}
+	for l.err == nil {
+		fn()
+		if l.err != nil {
+			return
+		}
+		switch l.peek() {
+		case ',':
+			l.pos++
+		case ']':
+			l.pos++
+			return
+		default:
+			l.errorf("expected ',' or ']'")
+		}
+	}
+}
+
+// String 读取字符串，非法的UTF-8字节替换为 U+FFFD（与 encoding/json 一致）
+func (l *JSONLexer) String() string {
+	return string(l.stringBytes())
+}
+
+// stringBytes 读取字符串内容，不含转义时直接返回输入数据的切片，避免内存分配
+func (l *JSONLexer) stringBytes() []byte {
+	if !l.expect('"') {
+		return nil
+	}
+
+	start := l.pos
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		if c == '"' {
+			l.pos++
+			return l.data[start : l.pos-1]
+		}
+		if c == '\\' || c < 0x20 || c >= utf8.RuneSelf {
+			break
+		}
+		l.pos++
+	}
+
+	buf := append([]byte(nil), l.data[start:l.pos]...)
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		switch {
+		case c == '"':
+			l.pos++
+			return buf
+		case c < 0x20:
+			l.errorf("invalid character in string")
+			return nil
+		case c == '\\':
+			if l.pos+1 >= len(l.data) {
+				l.errorf("unexpected end of string")
+				return nil
+			}
+			l.pos++
+			switch esc := l.data[l.pos]; esc {
+			case '"', '\\', '/':
+				buf = append(buf, esc)
+			case 'b':
+				buf = append(buf, '\b')
+			case 'f':
+				buf = append(buf, '\f')
+			case 'n':
+				buf = append(buf, '\n')
+			case 'r':
+				buf = append(buf, '\r')
+			case 't':
+				buf = append(buf, '\t')
+			case 'u':
+				r := l.hex4()
+				if utf16.IsSurrogate(r) {
+					r2 := rune(-1)
+					if l.pos+2 < len(l.data) && l.data[l.pos+1] == '\\' && l.data[l.pos+2] == 'u' {
+						saved := l.pos
+						l.pos += 2
+						r2 = l.hex4()
+						if dec := utf16.DecodeRune(r, r2); dec != utf8.RuneError {
+							r = dec
+						} else {
+							l.pos = saved
+							r = utf8.RuneError
+						}
+					} else {
+						r = utf8.RuneError
+					}
+				}
+				if l.err != nil {
+					return nil
+				}
+				buf = utf8.AppendRune(buf, r)
+			default:
+				l.errorf("invalid escape character %q", esc)
+				return nil
+			}
+			l.pos++
+		case c < utf8.RuneSelf:
+			buf = append(buf, c)
+			l.pos++
+		default:
+			r, size := utf8.DecodeRune(l.data[l.pos:])
+			buf = utf8.AppendRune(buf, r)
+			l.pos += size
+		}
+	}
+	l.errorf("unexpected end of string")
+	return nil
+}
+
+// hex4 读取 \u 之后的4位十六进制数，结束时 pos 指向最后一位
+func (l *JSONLexer) hex4() rune {
+	if l.pos+4 >= len(l.data) {
+		l.errorf("invalid unicode escape")
+		return 0
+	}
+	v, err := strconv.ParseUint(string(l.data[l.pos+1:l.pos+5]), 16, 32)
+	if err != nil {
+		l.errorf("invalid unicode escape")
+		return 0
+	}
+	l.pos += 4
+	return rune(v)
+}
+
+// number 读取数字字面量
+func (l *JSONLexer) number() string {
+	if l.err != nil {
+		return ""
+	}
+	l.skipSpace()
+	start := l.pos
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		if (c >= '0' && c <= '9') || c == '-' || c == '+' || c == '.' || c == 'e' || c == 'E' {
+			l.pos++
+			continue
+		}
+		break
+	}
+	if start == l.pos {
+		l.errorf("expected number")
+		return ""
+	}
+	return string(l.data[start:l.pos])
+}
+
+// Int 读取整数，bits 为目标类型的位数
+func (l *JSONLexer) Int(bits int) int64 {
+	s := l.number()
+	if l.err != nil {
+		return 0
+	}
+	v, err := strconv.ParseInt(s, 10, bits)
+	if err != nil {
+		l.errorf("cannot unmarshal number %s into int%d", s, bits)
+	}
+	return v
+}
+
+// Uint 读取无符号整数，bits 为目标类型的位数
+func (l *JSONLexer) Uint(bits int) uint64 {
+	s := l.number()
+	if l.err != nil {
+		return 0
+	}
+	v, err := strconv.ParseUint(s, 10, bits)
+	if err != nil {
+		l.errorf("cannot unmarshal number %s into uint%d", s, bits)
+	}
+	return v
+}
+
+// Float 读取浮点数，bits 为32或64
+func (l *JSONLexer) Float(bits int) float64 {
+	s := l.number()
+	if l.err != nil {
+		return 0
+	}
+	v, err := strconv.ParseFloat(s, bits)
+	if err != nil {
+		l.errorf("cannot unmarshal number %s into float%d", s, bits)
+	}
+	return v
+}
+
+// Bool 读取布尔值
+func (l *JSONLexer) Bool() bool {
+	switch l.peek() {
+	case 't':
+		return l.literal("true")
+	case 'f':
+		l.literal("false")
+		return false
+	default:
+		l.errorf("expected boolean")
+		return false
+	}
+}
+
+// Bytes 读取base64编码的字节数组
+func (l *JSONLexer) Bytes() []byte {
+	s := l.String()
+	if l.err != nil {
+		return nil
+	}
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		l.AddError(err)
+	}
+	return b
+}
+
+// Raw 跳过当前值并返回其原始内容
+func (l *JSONLexer) Raw() []byte {
+	if l.err != nil {
+		return nil
+	}
+	l.skipSpace()
+	start := l.pos
+	l.Skip()
+	if l.err != nil {
+		return nil
+	}
+	return l.data[start:l.pos]
+}
+
+// Skip 跳过当前值
+func (l *JSONLexer) Skip() {
+	switch l.peek() {
+	case '{':
+		l.Object(func([]byte) { l.Skip() })
+	case '[':
+		l.Array(l.Skip)
+	case '"':
+		l.stringBytes()
+	case 't':
+		l.literal("true")
+	case 'f':
+		l.literal("false")
+	case 'n':
+		l.literal("null")
+	default:
+		l.number()
+	}
+}
+
+// Unmarshal 使用 encoding/json 读取当前值，用于生成代码不支持的字段类型
+func (l *JSONLexer) Unmarshal(v any) {
+	raw := l.Raw()
+	if l.err != nil {
+		return
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		l.AddError(err)
+	}
+}
+
+// JSONWriter 生成代码使用的JSON写入器，输出与 encoding/json 一致
+type JSONWriter struct {
+	buf []byte
+	err error
+}
+
+// Bytes 返回写入的内容及第一个错误
+func (w *JSONWriter) Bytes() ([]byte, error) {
+	return w.buf, w.err
+}
+
+// RawByte 写入单个字节
+func (w *JSONWriter) RawByte(c byte) {
+	w.buf = append(w.buf, c)
+}
+
+// RawString 原样写入字符串
+func (w *JSONWriter) RawString(s string) {
+	w.buf = append(w.buf, s...)
+}
+
+// Key 写入对象成员名称及冒号，非对象第一个成员时先写入逗号
+func (w *JSONWriter) Key(name string) {
+	if n := len(w.buf); n > 0 && w.buf[n-1] != '{' {
+		w.buf = append(w.buf, ',')
+	}
+	w.String(name)
+	w.buf = append(w.buf, ':')
+}
+
+// Null 写入null
+func (w *JSONWriter) Null() {
+	w.buf = append(w.buf, "null"...)
+}
+
+// Bool 写入布尔值
+func (w *JSONWriter) Bool(v bool) {
+	w.buf = strconv.AppendBool(w.buf, v)
+}
+
+// Int 写入整数
+func (w *JSONWriter) Int(v int64) {
+	w.buf = strconv.AppendInt(w.buf, v, 10)
+}
+
+// Uint 写入无符号整数
+func (w *JSONWriter) Uint(v uint64) {
+	w.buf = strconv.AppendUint(w.buf, v, 10)
+}
+
+// Float 写入浮点数，格式与 encoding/json 一致，bits 为32或64
+func (w *JSONWriter) Float(f float64, bits int) {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		if w.err == nil {
+			w.err = errors.New("json: unsupported value: " + strconv.FormatFloat(f, 'g', -1, bits))
+		}
+		w.Null()
+		return
+	}
+
+	format := byte('f')
+	if abs := math.Abs(f); abs != 0 {
+		if bits == 64 && (abs < 1e-6 || abs >= 1e21) || bits == 32 && (float32(abs) < 1e-6 || float32(abs) >= 1e21) {
+			format = 'e'
+		}
+	}
+	w.buf = strconv.AppendFloat(w.buf, f, format, -1, bits)
+	if format == 'e' {
+		// 将 e-09 转换为 e-9
+		n := len(w.buf)
+		if n >= 4 && w.buf[n-4] == 'e' && w.buf[n-3] == '-' && w.buf[n-2] == '0' {
+			w.buf[n-2] = w.buf[n-1]
+			w.buf = w.buf[:n-1]
+		}
+	}
+}
+
+// Base64 写入base64编码的字节数组
+func (w *JSONWriter) Base64(v []byte) {
+	if v == nil {
+		w.Null()
+		return
+	}
+	w.buf = append(w.buf, '"')
+	w.buf = base64.StdEncoding.AppendEncode(w.buf, v)
+	w.buf = append(w.buf, '"')
+}
+
+const jsonHex = "0123456789abcdef"
+
+// String 写入字符串，转义规则与 encoding/json（含HTML转义）一致
+func (w *JSONWriter) String(s string) {
+	w.buf = append(w.buf, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		if c := s[i]; c < utf8.RuneSelf {
+			if c >= 0x20 && c != '"' && c != '\\' && c != '<' && c != '>' && c != '&' {
+				i++
+				continue
+			}
+			w.buf = append(w.buf, s[start:i]...)
+			switch c {
+			case '"', '\\':
+				w.buf = append(w.buf, '\\', c)
+			case '\b':
+				w.buf = append(w.buf, '\\', 'b')
+			case '\f':
+				w.buf = append(w.buf, '\\', 'f')
+			case '\n':
+				w.buf = append(w.buf, '\\', 'n')
+			case '\r':
+				w.buf = append(w.buf, '\\', 'r')
+			case '\t':
+				w.buf = append(w.buf, '\\', 't')
+			default:
+				w.buf = append(w.buf, '\\', 'u', '0', '0', jsonHex[c>>4], jsonHex[c&0xF])
+			}
+			i++
+			start = i
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			w.buf = append(w.buf, s[start:i]...)
+			w.buf = append(w.buf, `\ufffd`...)
+			i += size
+			start = i
+			continue
+		}
+		if r == '\u2028' || r == '\u2029' {
+			w.buf = append(w.buf, s[start:i]...)
+			w.buf = append(w.buf, '\\', 'u', '2', '0', '2', jsonHex[r&0xF])
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	w.buf = append(w.buf, s[start:]...)
+	w.buf = append(w.buf, '"')
+}
+
+// Marshal 使用 encoding/json 写入值，用于生成代码不支持的字段类型
+func (w *JSONWriter) Marshal(v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		if w.err == nil {
+			w.err = err
+		}
+		w.Null()
+		return
+	}
+	w.buf = append(w.buf, data...)
+}
@@ -0,0 +1,242 @@
+package mod
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+)
+
+// 执行槽位排队失败的原因
+var (
+	errExecutionQueueFull    = errors.New("execution queue is full")
+	errExecutionQueueTimeout = errors.New("timed out waiting for an execution slot")
+)
+
+// ConcurrencyStatus 服务执行槽位的使用情况
+type ConcurrencyStatus struct {
+	Limit   int            `json:"limit"`   // 同时执行的服务处理函数上限
+	Running int            `json:"running"` // 正在执行的数量
+	Queued  map[string]int `json:"queued"`  // 各优先级排队等待的数量
+}
+
+// executionWaiter 排队等待执行槽位的请求
+type executionWaiter struct {
+	priority string
+	rank     int
+	seq      uint64
+	index    int
+	ready    chan error
+}
+
+// executionQueue 等待队列，优先级高的先出队，同优先级先到先出
+type executionQueue []*executionWaiter
+
+func (q executionQueue) Len() int { return len(q) }
+func (q executionQueue) Less(i, j int) bool {
+	if q[i].rank != q[j].rank {
+		return q[i].rank > q[j].rank
+	}
+	return q[i].seq < q[j].seq
+}
+func (q executionQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+func (q *executionQueue) Push(x any) {
+	w := x.(*executionWaiter)
+	w.index = len(*q)
+	*q = append(*q, w)
+}
+func (q *executionQueue) Pop() any {
+	old := *q
+	w := old[len(old)-1]
+	old[len(old)-1] = nil
+	*q = old[:len(old)-1]
+	w.index = -1
+	return w
+}
+
+// executionLimiter 限制同时执行的服务处理函数数量，槽位已满时请求按优先级排队
+type executionLimiter struct {
+	mu        sync.Mutex
+	limit     int
+	queueSize int
+	timeout   time.Duration
+	ranks     map[string]int
+	running   int
+	seq       uint64
+	queue     executionQueue
+}
+
+// defaultPriorityRanks 内置优先级的排序值，越大越先获得执行槽位
+var defaultPriorityRanks = map[string]int{
+	PriorityCritical: 300,
+	PriorityHigh:     200,
+	PriorityNormal:   100,
+	PriorityLow:      0,
+}
+
+// configureConcurrencyLimit 启用并发限制时创建执行槽位
+func (app *App) configureConcurrencyLimit() {
+	config := app.cfg.ModConfig.ConcurrencyLimit
+	if !config.Enabled {
+		return
+	}
+	if config.MaxInFlight <= 0 {
+		app.logger.Warn("Concurrency limit is enabled but max_in_flight is not set, ignored")
+		return
+	}
+
+	limiter := &executionLimiter{
+		limit:     config.MaxInFlight,
+		queueSize: config.QueueSize,
+		timeout:   3 * time.Second,
+		ranks:     map[string]int{},
+	}
+	if limiter.queueSize <= 0 {
+		limiter.queueSize = 1000
+	}
+	if config.QueueTimeout != "" {
+		if d, err := time.ParseDuration(config.QueueTimeout); err == nil && d > 0 {
+			limiter.timeout = d
+		} else {
+			app.logger.WithField("queue_timeout", config.QueueTimeout).Warn("Invalid concurrency_limit queue_timeout, using 3s")
+		}
+	}
+	for class, rank := range defaultPriorityRanks {
+		limiter.ranks[class] = rank
+	}
+	for class, rank := range config.Classes {
+		limiter.ranks[class] = rank
+	}
+	app.limiter = limiter
+}
+
+// rank 返回优先级的排序值，未配置的自定义优先级按 normal 处理
+func (l *executionLimiter) rank(priority string) int {
+	if rank, ok := l.ranks[priority]; ok {
+		return rank
+	}
+	return l.ranks[PriorityNormal]
+}
+
+// acquire 获取执行槽位；队列已满时挤出优先级最低（同优先级中最晚到达）且低于本请求的排队请求，
+// 否则返回 errExecutionQueueFull
+func (l *executionLimiter) acquire(ctx context.Context, priority string) error {
+	l.mu.Lock()
+	if l.running < l.limit && len(l.queue) == 0 {
+		l.running++
+		l.mu.Unlock()
+		return nil
+	}
+
+	rank := l.rank(priority)
+	if len(l.queue) >= l.queueSize {
+		lowest := l.lowest()
+		if lowest == nil || lowest.rank >= rank {
+			l.mu.Unlock()
+			return errExecutionQueueFull
+		}
+		heap.Remove(&l.queue, lowest.index)
+		lowest.ready <- errExecutionQueueFull
+	}
+	l.seq++
+	waiter := &executionWaiter{priority: priority, rank: rank, seq: l.seq, ready: make(chan error, 1)}
+	heap.Push(&l.queue, waiter)
+	l.mu.Unlock()
+
+	timer := time.NewTimer(l.timeout)
+	defer timer.Stop()
+	var cause error
+	select {
+	case err := <-waiter.ready:
+		return err
+	case <-timer.C:
+		cause = errExecutionQueueTimeout
+	case <-ctx.Done():
+		cause = context.Cause(ctx)
+	}
+
+	l.mu.Lock()
+	if waiter.index >= 0 {
+		heap.Remove(&l.queue, waiter.index)
+		l.mu.Unlock()
+		return cause
+	}
+	l.mu.Unlock()
+	// 超时的同时已获得槽位或被挤出
+	if err := <-waiter.ready; err != nil {
+		return err
+	}
+	l.release()
+	return cause
+}
+
+// lowest 返回队列中最先被挤出的请求，调用方须持有锁
+func (l *executionLimiter) lowest() *executionWaiter {
+	var lowest *executionWaiter
+	for _, w := range l.queue {
+		if lowest == nil || w.rank < lowest.rank || (w.rank == lowest.rank && w.seq > lowest.seq) {
+			lowest = w
+		}
+	}
+	return lowest
+}
+
+// release 归还执行槽位，有排队请求时直接交给优先级最高的请求
+func (l *executionLimiter) release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.queue) > 0 {
+		waiter := heap.Pop(&l.queue).(*executionWaiter)
+		waiter.ready <- nil
+		return
+	}
+	l.running--
+}
+
+// acquireExecution 获取服务的执行槽位，返回的函数在处理函数返回后调用；排队失败时返回 StdReply 错误
+func (app *App) acquireExecution(ctx *Context, svc *Service) (func(), error) {
+	if app.limiter == nil {
+		return func() {}, nil
+	}
+	priority := app.servicePriority(svc)
+	err := app.limiter.acquire(ctx.UserContext(), priority)
+	if err == nil {
+		return app.limiter.release, nil
+	}
+
+	app.logger.WithFields(logrus.Fields{
+		"service":  svc.Name,
+		"priority": priority,
+		"error":    err.Error(),
+		"rid":      ctx.GetRequestID(),
+	}).Warn("Request rejected by concurrency limit")
+	if errors.Is(err, errExecutionQueueFull) || errors.Is(err, errExecutionQueueTimeout) {
+		ctx.Ctx.Set(fiber.HeaderRetryAfter, "1")
+		return nil, ReplyWithDetail(fiber.StatusServiceUnavailable, "Server busy", err.Error())
+	}
+	// 客户端断开连接或服务超时，由调用方按取消原因响应
+	return nil, err
+}
+
+// ConcurrencyStatus 返回执行槽位的使用情况，未启用并发限制时返回 false
+func (app *App) ConcurrencyStatus() (ConcurrencyStatus, bool) {
+	if app.limiter == nil {
+		return ConcurrencyStatus{}, false
+	}
+	l := app.limiter
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	status := ConcurrencyStatus{Limit: l.limit, Running: l.running, Queued: map[string]int{}}
+	for _, w := range l.queue {
+		status.Queued[w.priority]++
+	}
+	return status, true
+}
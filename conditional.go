@@ -0,0 +1,93 @@
+package mod
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// SetETag 设置响应的ETag，未加引号时自动加上，弱校验值使用 W/"..." 形式
+func (c *Context) SetETag(etag string) {
+	if !strings.HasPrefix(etag, `"`) && !strings.HasPrefix(etag, `W/"`) {
+		etag = `"` + etag + `"`
+	}
+	c.Ctx.Set(fiber.HeaderETag, etag)
+}
+
+// SetLastModified 设置响应的 Last-Modified，精确到秒
+func (c *Context) SetLastModified(t time.Time) {
+	c.Ctx.Set(fiber.HeaderLastModified, t.UTC().Format(http.TimeFormat))
+}
+
+// NotModified 按已设置的 ETag、Last-Modified 判断客户端缓存是否仍然有效，
+// 有效时处理函数可以直接返回而不必构造响应数据，框架响应304；只对设置了 ETag 的服务的 GET、HEAD 请求生效
+func (c *Context) NotModified() bool {
+	if c.service == nil || !c.service.ETag || !safeMethod(c.Ctx) {
+		return false
+	}
+	return requestFresh(c.Ctx)
+}
+
+// safeMethod 是否为可以响应304的 GET、HEAD 请求
+func safeMethod(c *fiber.Ctx) bool {
+	method := c.Method()
+	return method == fiber.MethodGet || method == fiber.MethodHead
+}
+
+// requestFresh 按 If-None-Match、If-Modified-Since 请求头判断客户端缓存是否与响应的 ETag、Last-Modified 一致，
+// 同时携带时只使用 If-None-Match
+func requestFresh(c *fiber.Ctx) bool {
+	if c.Get(fiber.HeaderIfNoneMatch) != "" {
+		return etagMatches(c)
+	}
+
+	modifiedSince := c.Get(fiber.HeaderIfModifiedSince)
+	lastModified := c.GetRespHeader(fiber.HeaderLastModified)
+	if modifiedSince == "" || lastModified == "" {
+		return false
+	}
+	since, err := http.ParseTime(modifiedSince)
+	if err != nil {
+		return false
+	}
+	modified, err := http.ParseTime(lastModified)
+	if err != nil {
+		return false
+	}
+	return !modified.After(since)
+}
+
+// etagMatches If-None-Match 是否与响应的 ETag 一致，使用弱比较，* 匹配任意 ETag
+func etagMatches(c *fiber.Ctx) bool {
+	noneMatch := c.Get(fiber.HeaderIfNoneMatch)
+	etag := c.GetRespHeader(fiber.HeaderETag)
+	if noneMatch == "" || etag == "" {
+		return false
+	}
+	if strings.TrimSpace(noneMatch) == "*" {
+		return true
+	}
+	for _, candidate := range strings.Split(noneMatch, ",") {
+		// 弱比较：忽略 W/ 前缀
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// responseETag 根据响应数据计算弱ETag，响应中的请求ID每次不同，因此只计算数据部分
+func responseETag(out any) (string, error) {
+	data, err := json.Marshal(out)
+	if err != nil {
+		return "", err
+	}
+	h := fnv.New64a()
+	h.Write(data)
+	return fmt.Sprintf(`W/"%x"`, h.Sum64()), nil
+}
@@ -0,0 +1,122 @@
+package mod
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestConditionalRequests(t *testing.T) {
+	app := newTestApp(t, "")
+	calls := 0
+	for _, etag := range []bool{true, false} {
+		name := "report_plain"
+		if etag {
+			name = "report_etag"
+		}
+		err := app.Register(Service{
+			Name:        name,
+			DisplayName: name,
+			SkipAuth:    true,
+			ETag:        etag,
+			Handler: MakeHandler(func(ctx *Context, req *pingRequest, resp *pingResponse) error {
+				calls++
+				resp.Value = req.Value
+				return nil
+			}),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	path := app.ServicePath("report_etag")
+
+	resp, err := app.TestClient().Get(path + "?value=x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var data pingResponse
+	resp.AssertStatus(t, 200).AssertSuccess(t, &data)
+	if data.Value != "x" {
+		t.Fatalf("expected value from query, got %q", data.Value)
+	}
+	etag := resp.Header.Get("ETag")
+	if etag == "" {
+		t.Fatal("expected ETag header")
+	}
+
+	resp, err = app.TestClient().WithHeader("If-None-Match", etag).Get(path + "?value=x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.AssertStatus(t, http.StatusNotModified)
+	if len(resp.Body) != 0 {
+		t.Fatalf("expected empty body, got %s", resp.Body)
+	}
+
+	// POST 不响应304，前置条件不成立时响应412
+	resp, err = app.TestClient().WithHeader("If-None-Match", etag).Call("report_etag", pingRequest{Value: "x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.AssertStatus(t, http.StatusPreconditionFailed).AssertCode(t, http.StatusPreconditionFailed)
+
+	resp, err = app.TestClient().WithHeader("If-None-Match", etag).Call("report_etag", pingRequest{Value: "y"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.AssertStatus(t, 200).AssertSuccess(t)
+
+	// 未启用 ETag 的服务忽略条件请求头
+	resp, err = app.TestClient().WithHeader("If-None-Match", "*").Call("report_plain", pingRequest{Value: "x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.AssertStatus(t, 200).AssertSuccess(t)
+	resp, err = app.TestClient().Get(app.ServicePath("report_plain"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode == http.StatusOK {
+		t.Fatal("expected GET to be rejected for services without ETag")
+	}
+
+	if calls != 5 {
+		t.Fatalf("expected 5 handler calls, got %d", calls)
+	}
+}
+
+func TestNotModifiedOnlyForSafeMethods(t *testing.T) {
+	app := newTestApp(t, "")
+	err := app.Register(Service{
+		Name:        "versioned",
+		DisplayName: "versioned",
+		SkipAuth:    true,
+		ETag:        true,
+		Handler: MakeHandler(func(ctx *Context, req *pingRequest, resp *pingResponse) error {
+			ctx.SetETag("v1")
+			if ctx.NotModified() {
+				return nil
+			}
+			resp.Value = "built"
+			return nil
+		}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := app.TestClient().WithHeader("If-None-Match", `"v1"`).Get(app.ServicePath("versioned"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.AssertStatus(t, http.StatusNotModified)
+
+	resp, err = app.TestClient().WithHeader("If-None-Match", `"v1"`).Call("versioned", pingRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode == http.StatusNotModified {
+		t.Fatal("POST must not reply 304")
+	}
+	resp.AssertStatus(t, http.StatusPreconditionFailed)
+}
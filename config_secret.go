@@ -0,0 +1,150 @@
+package mod
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// encryptedConfigTag mod.yml 中加密值的YAML标签，如 password: !enc AES:...
+	encryptedConfigTag = "!enc"
+	// encryptedConfigPrefix 加密值的算法前缀
+	encryptedConfigPrefix = "AES:"
+)
+
+// MasterKeyProvider 返回解密配置加密值的主密钥，用于从KMS、密钥管理服务等外部系统获取
+type MasterKeyProvider func() (string, error)
+
+var (
+	masterKeyMu       sync.RWMutex
+	masterKeyProvider MasterKeyProvider
+)
+
+// SetMasterKeyProvider 设置配置主密钥的来源，须在 New() 之前调用；未设置时依次读取
+// MOD_MASTER_KEY 环境变量和 MOD_MASTER_KEY_FILE 指定的文件
+func SetMasterKeyProvider(provider MasterKeyProvider) {
+	masterKeyMu.Lock()
+	defer masterKeyMu.Unlock()
+	masterKeyProvider = provider
+}
+
+// configMasterKey 返回配置主密钥
+func configMasterKey() (string, error) {
+	masterKeyMu.RLock()
+	provider := masterKeyProvider
+	masterKeyMu.RUnlock()
+	if provider != nil {
+		key, err := provider()
+		if err != nil {
+			return "", fmt.Errorf("failed to get master key: %w", err)
+		}
+		if key == "" {
+			return "", fmt.Errorf("master key provider returned an empty key")
+		}
+		return key, nil
+	}
+
+	if key := os.Getenv("MOD_MASTER_KEY"); key != "" {
+		return key, nil
+	}
+	if path := os.Getenv("MOD_MASTER_KEY_FILE"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read master key file: %w", err)
+		}
+		if key := strings.TrimSpace(string(data)); key != "" {
+			return key, nil
+		}
+		return "", fmt.Errorf("master key file %s is empty", path)
+	}
+	return "", fmt.Errorf("MOD_MASTER_KEY or MOD_MASTER_KEY_FILE is not set")
+}
+
+// configCipher 使用主密钥的SHA-256摘要作为AES-256-GCM密钥
+func configCipher(masterKey string) (cipher.AEAD, error) {
+	key := sha256.Sum256([]byte(masterKey))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// EncryptConfigValue 加密配置值，返回 AES:<base64> 形式的密文，在 mod.yml 中写作 !enc AES:<base64>
+func EncryptConfigValue(masterKey, plaintext string) (string, error) {
+	gcm, err := configCipher(masterKey)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+	return encryptedConfigPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// DecryptConfigValue 解密 EncryptConfigValue 生成的密文
+func DecryptConfigValue(masterKey, value string) (string, error) {
+	encoded, ok := strings.CutPrefix(strings.TrimSpace(value), encryptedConfigPrefix)
+	if !ok {
+		return "", fmt.Errorf("encrypted value must start with %q", encryptedConfigPrefix)
+	}
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", fmt.Errorf("invalid encrypted value: %w", err)
+	}
+	gcm, err := configCipher(masterKey)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return "", fmt.Errorf("invalid encrypted value: too short")
+	}
+	plaintext, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt value, wrong master key?")
+	}
+	return string(plaintext), nil
+}
+
+// decryptConfigNode 将配置中 !enc 标签的值替换为明文，仅在存在加密值时获取主密钥
+func decryptConfigNode(node *yaml.Node) error {
+	var masterKey string
+	var walk func(n *yaml.Node) error
+	walk = func(n *yaml.Node) error {
+		if n.Kind == yaml.ScalarNode && n.Tag == encryptedConfigTag {
+			if masterKey == "" {
+				key, err := configMasterKey()
+				if err != nil {
+					return fmt.Errorf("line %d: encrypted value requires a master key: %w", n.Line, err)
+				}
+				masterKey = key
+			}
+			plaintext, err := DecryptConfigValue(masterKey, n.Value)
+			if err != nil {
+				return fmt.Errorf("line %d: %w", n.Line, err)
+			}
+			n.Tag = "!!str"
+			n.Value = plaintext
+			n.Style = 0
+			return nil
+		}
+		for _, child := range n.Content {
+			if err := walk(child); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return walk(node)
+}
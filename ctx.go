@@ -1,10 +1,12 @@
 package mod
 
 import (
+	"context"
 	"fmt"
 	"github.com/gofiber/fiber/v2"
 	"github.com/sirupsen/logrus"
 	"reflect"
+	"time"
 )
 
 type Context struct {
@@ -12,15 +14,47 @@ type Context struct {
 	RequestID string
 	logger    *logrus.Logger
 	app       *App
+	service   *Service // 当前处理的服务
+	input     any      // 已校验的请求参数，供服务中间件使用
+	output    any      // 处理函数的响应数据
 }
 
 func (c *Context) GetRequestID() string {
 	if c.RequestID == "" {
-		c.RequestID = NextSnowflakeStringID()
+		if c.Ctx != nil {
+			if rid, ok := c.Locals(requestIDKey{}).(string); ok {
+				c.RequestID = rid
+				return rid
+			}
+		}
+		if c.Ctx != nil && validRequestID(c.Get("X-Request-ID")) {
+			// 上游 mod 服务通过 mod.Client 透传的请求ID，沿用以便跨服务关联日志
+			c.RequestID = c.Get("X-Request-ID")
+		} else if c.app != nil {
+			c.RequestID = c.app.NextID()
+		} else {
+			c.RequestID = NextSnowflakeStringID()
+		}
+		if c.Ctx != nil {
+			c.Locals(requestIDKey{}, c.RequestID)
+		}
 	}
 	return c.RequestID
 }
 
+// validRequestID 判断透传的请求ID是否可以沿用：不超过128个字符，只包含字母、数字和 - _ . :
+func validRequestID(rid string) bool {
+	if rid == "" || len(rid) > 128 {
+		return false
+	}
+	for _, r := range rid {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.' || r == ':') {
+			return false
+		}
+	}
+	return true
+}
+
 // GetLogger returns the logger instance
 func (c *Context) GetLogger() *logrus.Logger {
 	return c.logger
@@ -101,6 +135,32 @@ type Handler struct {
 	Func       func(ctx *Context, args, reply any) error
 	InputType  reflect.Type
 	OutputType reflect.Type
+
+	// call 由处理函数构造并返回响应（MakeHandler2），设置后优先于 Func，返回 nil 时响应数据为 null
+	call func(ctx *Context, args any) (any, error)
+
+	// ndjson 批量导入服务（MakeImportHandler），InputType 为每行的数据类型，请求体逐行读取而不绑定参数
+	ndjson bool
+
+	// newInput、newOutput 创建输入和输出参数实例（RegisterFunc），为空时通过反射创建
+	newInput  func() any
+	newOutput func() any
+}
+
+// input 创建输入参数实例
+func (h *Handler) input() any {
+	if h.newInput != nil {
+		return h.newInput()
+	}
+	return reflect.New(h.InputType).Interface()
+}
+
+// output 创建输出参数实例
+func (h *Handler) output() any {
+	if h.newOutput != nil {
+		return h.newOutput()
+	}
+	return reflect.New(h.OutputType).Interface()
 }
 
 // PermissionRule 权限规则
@@ -127,13 +187,104 @@ type Service struct {
 	Handler     Handler `validate:"required"`
 
 	Description string
-	SkipAuth    bool
+	SkipAuth    bool         // 不需要认证，等同于 Auth: AuthNone
+	Auth        AuthStrategy // 认证方式，为空时依次使用 auth.services、分组及 auth.default 配置，默认 token_cache
 	ReturnRaw   bool
 	Group       string // 在文档中的分组
 	Sort        int    // 在文档中的排序值，从小到大排列
+	Hidden      bool   // 不出现在文档、OpenAPI 和 TypeScript SDK 中，仍可正常调用；按环境隐藏使用 mod.yml 中 docs.hide 配置
+
+	// 服务前缀下的路径模板，如 users/:id/orders，路径参数通过 mod:"from=param" 绑定；为空时使用服务名称
+	Path string
+
+	// 完整的访问路径，如 /api/v1/users/query，不使用服务前缀和服务名称，用于将已有接口迁移到 mod 而不影响已部署的客户端；
+	// 支持 :id 形式的路径参数，不能与 Path 同时设置。挂载的子应用服务仍带挂载前缀
+	PathOverride string
+
+	// 服务版本，同名服务的多个版本共用一个路由，按 canary 配置的权重或 X-Canary 请求头分发
+	Version string
+
+	// 处理超时，超时后 ctx.UserContext() 被取消；处理函数因此返回错误时响应504。0 表示不限制
+	Timeout time.Duration
+
+	// 异步执行：请求通过认证和参数校验后进入任务队列，立即响应202和任务ID，由后台工作协程执行处理函数；
+	// 通过 /services/jobs/status 查询状态，/services/jobs/result 获取处理函数的响应。用于导出等耗时较长的服务
+	Async bool
+
+	// 处理函数直接接收原始请求体（*mod.RawRequest），不做参数绑定和校验，用于需要对原始字节验签的 Webhook 回调
+	RawBody bool
+
+	// 启用条件请求：服务同时接受 GET、HEAD 请求，根据响应数据自动计算ETag，GET、HEAD 请求的 If-None-Match 与之一致时响应304，
+	// 其他方法响应412；处理函数也可以通过 ctx.SetETag、ctx.SetLastModified 自行设置
+	ETag bool
 
 	// 权限控制配置
 	Permission *PermissionConfig `json:"permission,omitempty"`
+
+	// 优先级（critical、high、normal、low 或自定义），启用负载卸载时实例过载后先拒绝低优先级的服务，默认 normal
+	Priority string
+
+	// 防刷限流规则，用于登录、发送验证码等敏感服务；mod.yml 中 throttle.services 的同名配置优先
+	Throttle *ThrottleRule
+
+	// 请求限流规则，与 rate_limit.global、rate_limit.groups 同时生效；mod.yml 中 rate_limit.services 的同名配置优先
+	RateLimit *RateLimit
+
+	// Webhook 签名校验（Stripe、GitHub、微信支付、支付宝）和防重放；mod.yml 中 webhook.services 的同名配置优先
+	Webhook *WebhookConfig
+
+	// SLO目标，启用 slo 配置时统计滚动窗口内的达成情况；mod.yml 中 slo.services 的同名配置优先
+	SLO *SLOTarget
+
+	// 请求体大小上限（可以低于或高于 server.body_limit）和接受的格式（json、xml、msgpack、form、multipart），
+	// 超过上限时响应413，格式不符时响应415；mod.yml 中 request_body.services 的同名配置优先
+	RequestBody *RequestBody `json:"request_body,omitempty"`
+
+	// 响应大小上限，超过时截断列表或返回错误；mod.yml 中 response_limit.services 的同名配置优先
+	ResponseLimit *ResponseLimit `json:"response_limit,omitempty"`
+
+	// 始终返回JSON，不按请求的 Accept 返回 XML 或 MessagePack，用于发送了其他 Accept 但只能解析JSON的旧客户端；
+	// mod.yml 中 response_format.json_only 同样生效
+	JSONOnly bool `json:"json_only,omitempty"`
+
+	// 响应缓存，命中时直接返回缓存的响应数据，不调用处理函数；mod.yml 中 response_cache.services 的同名配置优先
+	Cache *ResponseCache `json:"cache,omitempty"`
+
+	// 链路追踪的 span 属性和写入 baggage 的请求字段；mod.yml 中 tracing.services 的同名配置优先
+	Trace *ServiceTrace `json:"trace,omitempty"`
+
+	// 调用所需的Token权限范围（如 orders:read），Token的 scope 声明须包含全部权限范围，未满足时响应403
+	RequiredScopes []string `json:"required_scopes,omitempty"`
+
+	// 可能返回的错误码名称（须已在 mod.ErrorRegistry 中注册），在文档和 OpenAPI 中展示
+	Errors []string `json:"errors,omitempty"`
+
+	// 必需的请求头和固定的响应头，与 mod.yml 中 headers 的默认、分组配置合并；headers.services 的同名配置优先
+	Headers *HeaderPolicy `json:"headers,omitempty"`
+
+	// 服务中间件，在 UseServiceMiddleware、UseGroupMiddleware 注册的中间件之后、处理函数之前按顺序执行
+	Middlewares []Middleware `json:"-"`
+
+	path    string     // 注册后的完整访问路径，挂载的子应用服务包含挂载前缀
+	owner   *App       // 注册服务的应用
+	methods []string   // 路由的请求方法，为空时为 POST；RegisterDownload 注册为 GET、HEAD，RegisterWS 注册为 GET
+	ws      *wsService // RegisterWS 注册的 WebSocket 服务的消息类型
+	builtin bool       // 框架注册的服务（管理服务、文件服务），不受Mock开关影响
+
+	headerPolicy  *HeaderPolicy        // 注册时合并的请求头和响应头策略
+	responseLimit *responseLimitPolicy // 注册时解析的响应大小上限
+	requestBody   *requestBodyPolicy   // 注册时解析的请求体大小上限和格式
+	responseCache *responseCachePolicy // 注册时解析的响应缓存配置
+	tracePolicy   *tracePolicy         // 注册时解析的链路追踪配置
+	negotiate     bool                 // 注册时解析：是否按 Accept 协商响应格式
+}
+
+// httpMethod 返回服务在文档中展示的请求方法
+func (s *Service) httpMethod() string {
+	if len(s.methods) > 0 {
+		return s.methods[0]
+	}
+	return fiber.MethodPost
 }
 
 // MakeHandler 创建带类型信息的 Handler
@@ -155,10 +306,82 @@ func MakeHandler[I any, O any](handler func(ctx *Context, args *I, reply *O) err
 	}
 }
 
+// MakeHandler2 创建由处理函数返回响应的 Handler，可直接返回 nil（响应数据为 null）或提前返回，
+// ctx 在客户端断开连接或超过 Service.Timeout 时被取消
+//
+//	mod.MakeHandler2(func(ctx context.Context, mctx *mod.Context, req *GetUserRequest) (*GetUserResponse, error) {
+//		return repo.FindUser(ctx, req.ID)
+//	})
+func MakeHandler2[I any, O any](handler func(ctx context.Context, mctx *Context, args *I) (*O, error)) Handler {
+	call := func(mctx *Context, args any) (any, error) {
+		a, ok := args.(*I)
+		if !ok {
+			return nil, fmt.Errorf("invalid args type")
+		}
+		return handler(mctx.UserContext(), mctx, a)
+	}
+	return Handler{
+		Func: func(mctx *Context, args any, reply any) error {
+			r, ok := reply.(*O)
+			if !ok {
+				return fmt.Errorf("invalid reply type")
+			}
+			out, err := call(mctx, args)
+			if err != nil {
+				return err
+			}
+			if o := out.(*O); o != nil {
+				*r = *o
+			}
+			return nil
+		},
+		InputType:  reflect.TypeOf((*I)(nil)).Elem(),
+		OutputType: reflect.TypeOf((*O)(nil)).Elem(),
+		call:       call,
+	}
+}
+
+// ServiceOptions RegisterFunc 注册服务的配置，Handler 字段由 RegisterFunc 设置
+type ServiceOptions = Service
+
+// RegisterFunc 注册带类型的服务处理函数，输入输出类型由类型参数确定：
+// 请求和响应实例直接通过 new 创建，不经过 reflect.New，处理函数无需类型断言
+//
+//	mod.RegisterFunc(app, mod.ServiceOptions{Name: "get_user", DisplayName: "查询用户"},
+//		func(ctx *mod.Context, req *GetUserRequest, resp *GetUserResponse) error {
+//			...
+//		})
+func RegisterFunc[Req any, Resp any](app *App, opts ServiceOptions, handler func(ctx *Context, req *Req, resp *Resp) error) error {
+	opts.Handler = Handler{
+		Func: func(ctx *Context, args any, reply any) error {
+			req, ok := args.(*Req)
+			if !ok {
+				return fmt.Errorf("invalid args type")
+			}
+			resp, ok := reply.(*Resp)
+			if !ok {
+				return fmt.Errorf("invalid reply type")
+			}
+			return handler(ctx, req, resp)
+		},
+		InputType:  reflect.TypeFor[Req](),
+		OutputType: reflect.TypeFor[Resp](),
+		newInput:   func() any { return new(Req) },
+		newOutput:  func() any { return new(Resp) },
+	}
+	return app.Register(opts)
+}
+
 type StdReply struct {
 	code   int
 	msg    string
 	detail string
+
+	// mod.ReplyCode 创建的错误：HTTP状态码、错误码名称及消息参数，响应时按请求语言选择消息
+	status   int
+	name     string
+	args     []any
+	registry *ErrorCodeRegistry
 }
 
 func (r StdReply) Error() string {
@@ -177,6 +400,19 @@ func (r StdReply) Detail() string {
 	return r.detail
 }
 
+// Name 返回 mod.ReplyCode 使用的错误码名称，其他错误为空
+func (r StdReply) Name() string {
+	return r.name
+}
+
+// httpStatus 返回响应的HTTP状态码，错误码声明了状态码时使用声明的状态码
+func (r StdReply) httpStatus() int {
+	if r.status != 0 {
+		return r.status
+	}
+	return replyStatus(r.code)
+}
+
 func Reply(code int, msg string) error {
 	return &StdReply{code: code, msg: msg}
 }
@@ -204,11 +440,11 @@ func NewSuccessResponse(ctx *Context, data any) *ApiResponse {
 	}
 }
 
-// 生成错误响应
+// 生成错误响应，msg 为国际化消息键时返回当前请求语言的消息
 func NewErrorResponse(ctx *Context, code int, msg string, detail ...string) *ApiResponse {
 	resp := &ApiResponse{
 		Code: code,
-		Msg:  msg,
+		Msg:  ctx.localizeMessage(msg),
 		Rid:  ctx.GetRequestID(),
 	}
 	if len(detail) > 0 && detail[0] != "" {
@@ -236,6 +472,8 @@ func (c *Context) GetJWTToken() string {
 }
 
 // GetUserID returns the user ID from JWT claims
+//
+// Deprecated: 未使用JWT中间件时返回空字符串，请使用 User()，同时支持JWT声明和Token缓存数据
 func (c *Context) GetUserID() string {
 	if userID, ok := c.Locals("user_id").(string); ok {
 		return userID
@@ -244,6 +482,8 @@ func (c *Context) GetUserID() string {
 }
 
 // GetUsername returns the username from JWT claims
+//
+// Deprecated: 未使用JWT中间件时返回空字符串，请使用 User()，同时支持JWT声明和Token缓存数据
 func (c *Context) GetUsername() string {
 	if username, ok := c.Locals("username").(string); ok {
 		return username
@@ -252,6 +492,8 @@ func (c *Context) GetUsername() string {
 }
 
 // GetUserEmail returns the user email from JWT claims
+//
+// Deprecated: 未使用JWT中间件时返回空字符串，请使用 User()，同时支持JWT声明和Token缓存数据
 func (c *Context) GetUserEmail() string {
 	if email, ok := c.Locals("user_email").(string); ok {
 		return email
@@ -260,6 +502,8 @@ func (c *Context) GetUserEmail() string {
 }
 
 // GetUserRole returns the user role from JWT claims
+//
+// Deprecated: 未使用JWT中间件时返回空字符串，请使用 User()，同时支持JWT声明和Token缓存数据
 func (c *Context) GetUserRole() string {
 	if role, ok := c.Locals("user_role").(string); ok {
 		return role
@@ -274,14 +518,18 @@ func (c *Context) IsAuthenticated() bool {
 
 // HasRole checks if the current user has the specified role
 func (c *Context) HasRole(role string) bool {
-	return c.GetUserRole() == role
+	user, ok := c.User()
+	return ok && user.Role == role
 }
 
 // HasAnyRole checks if the current user has any of the specified roles
 func (c *Context) HasAnyRole(roles ...string) bool {
-	userRole := c.GetUserRole()
+	user, ok := c.User()
+	if !ok {
+		return false
+	}
 	for _, role := range roles {
-		if userRole == role {
+		if user.Role == role {
 			return true
 		}
 	}
@@ -0,0 +1,10 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+package mod
+
+import "net"
+
+// 当前平台不支持检测客户端断开连接，仅超时会取消请求上下文
+func canDetectClose(net.Conn) bool { return false }
+
+func connClosed(net.Conn) bool { return false }
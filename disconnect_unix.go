@@ -0,0 +1,55 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+
+package mod
+
+import (
+	"crypto/tls"
+	"errors"
+	"net"
+	"syscall"
+)
+
+// rawConn 返回底层支持系统调用的连接，TLS 连接取其内部连接
+func rawConn(conn net.Conn) (syscall.RawConn, bool) {
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		conn = tlsConn.NetConn()
+	}
+	sc, ok := conn.(syscall.Conn)
+	if !ok {
+		return nil, false
+	}
+	raw, err := sc.SyscallConn()
+	if err != nil {
+		return nil, false
+	}
+	return raw, true
+}
+
+func canDetectClose(conn net.Conn) bool {
+	_, ok := rawConn(conn)
+	return ok
+}
+
+// connClosed 以 MSG_PEEK 非阻塞读取一个字节判断对端是否已关闭，不会消耗连接上的数据
+func connClosed(conn net.Conn) bool {
+	raw, ok := rawConn(conn)
+	if !ok {
+		return false
+	}
+
+	closed := false
+	var buf [1]byte
+	_ = raw.Read(func(fd uintptr) bool {
+		n, _, err := syscall.Recvfrom(int(fd), buf[:], syscall.MSG_PEEK|syscall.MSG_DONTWAIT)
+		switch {
+		case err == nil:
+			closed = n == 0
+		case errors.Is(err, syscall.EAGAIN), errors.Is(err, syscall.EWOULDBLOCK), errors.Is(err, syscall.EINTR):
+		default:
+			closed = true
+		}
+		// 返回 true 表示不等待可读事件
+		return true
+	})
+	return closed
+}
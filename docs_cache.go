@@ -0,0 +1,53 @@
+package mod
+
+import (
+	"fmt"
+	"hash/fnv"
+)
+
+// docsSnapshot 渲染后的文档页面，服务注册、子应用挂载、Mock开关或模板变化后失效
+type docsSnapshot struct {
+	generation       uint64 // 生成时的服务注册版本
+	mockRevision     uint64 // 生成时的Mock开关版本
+	templateRevision uint64 // 生成时的模板版本
+	data             DocData
+	html             string
+	etag             string
+}
+
+// invalidateDocs 使挂载树根应用的文档缓存失效
+func (app *App) invalidateDocs() {
+	app.root().docsGeneration.Add(1)
+}
+
+// renderedDocs 返回当前的文档数据和HTML，缓存有效时直接复用，避免每次请求反射解析全部服务和渲染模板
+func (app *App) renderedDocs() *docsSnapshot {
+	generation := app.docsGeneration.Load()
+	mockRevision := app.mockOverrides.currentRevision()
+	templateRevision := app.templates.currentRevision("docs.html")
+
+	app.docsMu.Lock()
+	defer app.docsMu.Unlock()
+	if cached := app.docsCache; cached != nil && cached.generation == generation && cached.mockRevision == mockRevision && cached.templateRevision == templateRevision {
+		return cached
+	}
+
+	docData := app.docData()
+	// 启用运行时Mock管理时在文档页面提供切换按钮
+	if app.mockOverrides != nil {
+		docData.MockTogglePath = app.cfg.ModConfig.App.ServiceBase + "/mock_toggle"
+	}
+	html := app.generateDocsHTML(docData)
+
+	h := fnv.New64a()
+	h.Write([]byte(html))
+	app.docsCache = &docsSnapshot{
+		generation:       generation,
+		mockRevision:     mockRevision,
+		templateRevision: templateRevision,
+		data:             docData,
+		html:             html,
+		etag:             fmt.Sprintf(`"%x"`, h.Sum64()),
+	}
+	return app.docsCache
+}
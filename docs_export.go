@@ -0,0 +1,54 @@
+package mod
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// offlineDocsExcludedGroups 离线文档中不包含的管理服务分组
+var offlineDocsExcludedGroups = map[string]bool{
+	mockAdminGroup: true,
+	captureGroup:   true,
+}
+
+// DocsHTML 生成离线文档：单个HTML文件，样式和脚本内联，包含请求和响应示例。
+// 不包含Mock状态、Mock开关和管理服务等依赖运行中服务的内容，也不包含隐藏的服务（Service.Hidden、docs.hide）
+func (app *App) DocsHTML() string {
+	docData := app.docData()
+
+	groups := make([]DocGroup, 0, len(docData.Groups))
+	for _, group := range docData.Groups {
+		if offlineDocsExcludedGroups[group.Name] {
+			continue
+		}
+		// 离线文档提供给外部合作方，始终不包含隐藏的服务
+		services := group.Services[:0]
+		for _, svc := range group.Services {
+			if svc.Hidden {
+				continue
+			}
+			svc.MockEnabled = false
+			svc.MockToggle = false
+			services = append(services, svc)
+		}
+		if len(services) == 0 {
+			continue
+		}
+		group.Services = services
+		groups = append(groups, group)
+	}
+	docData.Groups = groups
+	docData.MockTogglePath = ""
+
+	return app.generateDocsHTML(docData)
+}
+
+// ExportDocsHTML 将离线文档写入文件，可在构建或启动时生成，提供给无法访问服务的外部合作方
+func (app *App) ExportDocsHTML(path string) error {
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(path, []byte(app.DocsHTML()), 0644)
+}
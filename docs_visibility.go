@@ -0,0 +1,36 @@
+package mod
+
+// serviceHidden 服务是否对外隐藏：设置了 Service.Hidden，或匹配当前运行环境的 docs.hide 规则
+func (app *App) serviceHidden(svc *Service) bool {
+	if svc.Hidden {
+		return true
+	}
+	if app.cfg.ModConfig == nil {
+		return false
+	}
+	env := app.Env()
+	for _, rule := range app.cfg.ModConfig.Docs.Hide {
+		if len(rule.Env) > 0 && !envIn(env, rule.Env) {
+			continue
+		}
+		for _, group := range rule.Groups {
+			if group == svc.Group {
+				return true
+			}
+		}
+		for _, pattern := range rule.Services {
+			if pattern != "" && matchURLPattern(pattern, svc.Name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// docVisible 服务是否出现在文档、OpenAPI 和 TypeScript SDK 中：未隐藏，或当前运行环境在 docs.show_hidden_in 中
+func (app *App) docVisible(svc *Service) bool {
+	if !app.serviceHidden(svc) {
+		return true
+	}
+	return app.cfg.ModConfig != nil && envIn(app.Env(), app.cfg.ModConfig.Docs.ShowHiddenIn)
+}
@@ -0,0 +1,397 @@
+package mod
+
+import (
+	"context"
+	"crypto/hkdf"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/aliyun/alibabacloud-oss-go-sdk-v2/oss"
+	"github.com/gofiber/fiber/v2"
+	"github.com/minio/minio-go/v7"
+)
+
+// downloadSecretLabel 由JWT密钥派生下载签名密钥时使用的 HKDF info
+const downloadSecretLabel = "mod download"
+
+// configureFileDownload 配置文件下载路由
+func (app *App) configureFileDownload() {
+	config := app.cfg.ModConfig.FileUpload.Download
+	if !config.Enabled {
+		return
+	}
+
+	// 签名密钥：优先使用下载配置，其次由JWT密钥按 HKDF 派生（不直接复用JWT密钥），均未配置时随机生成（重启后链接失效）
+	jwtKey, jwtErr := app.Key(KeyJWT)
+	switch {
+	case config.Secret != "":
+		app.downloadSecret = []byte(config.Secret)
+	case jwtErr == nil:
+		secret, err := hkdf.Key(sha256.New, jwtKey.Material, nil, downloadSecretLabel, sha256.Size)
+		if err != nil {
+			app.logger.WithError(err).Error("Failed to derive download signing secret")
+			return
+		}
+		app.downloadSecret = secret
+	default:
+		app.downloadSecret = make([]byte, 32)
+		if _, err := rand.Read(app.downloadSecret); err != nil {
+			app.logger.WithError(err).Error("Failed to generate download signing secret")
+			return
+		}
+		app.logger.Warn("Download signing secret not configured, using a random secret (links become invalid after restart)")
+	}
+
+	app.Get(app.downloadPath()+"/:id", app.handleFileDownload)
+
+	app.logger.WithFields(map[string]interface{}{
+		"path": app.downloadPath(),
+		"mode": app.downloadMode(),
+	}).Info("File download configured")
+}
+
+// downloadPath 返回下载路由前缀
+func (app *App) downloadPath() string {
+	path := strings.TrimSuffix(app.cfg.ModConfig.FileUpload.Download.Path, "/")
+	if path == "" {
+		return "/download"
+	}
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return path
+}
+
+// downloadMode 返回下载方式
+func (app *App) downloadMode() string {
+	if app.cfg.ModConfig.FileUpload.Download.Mode == "proxy" {
+		return "proxy"
+	}
+	return "redirect"
+}
+
+// downloadExpire 返回下载链接默认有效期
+func (app *App) downloadExpire() time.Duration {
+	if expire := app.cfg.ModConfig.FileUpload.Download.Expire; expire != "" {
+		if d, err := parseDurationWithDays(expire); err == nil && d > 0 {
+			return d
+		}
+		app.logger.WithField("expire", expire).Warn("Invalid download expire, using default 1h")
+	}
+	return time.Hour
+}
+
+// SignDownloadURL 生成文件的限时下载链接（相对路径），ttl<=0 时使用配置的默认有效期
+func (app *App) SignDownloadURL(id string, ttl time.Duration) (string, time.Time, error) {
+	if len(app.downloadSecret) == 0 {
+		return "", time.Time{}, fmt.Errorf("file download not enabled")
+	}
+	if ttl <= 0 {
+		ttl = app.downloadExpire()
+	}
+
+	expiresAt := time.Now().Add(ttl)
+	expires := strconv.FormatInt(expiresAt.Unix(), 10)
+
+	query := url.Values{}
+	query.Set("expires", expires)
+	query.Set("sig", app.signDownload(id, expires))
+	return app.downloadPath() + "/" + url.PathEscape(id) + "?" + query.Encode(), expiresAt, nil
+}
+
+// signDownload 计算下载链接签名
+func (app *App) signDownload(id, expires string) string {
+	mac := hmac.New(sha256.New, app.downloadSecret)
+	mac.Write([]byte(id + "\n" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// verifyDownload 校验下载链接签名和有效期，返回剩余有效时长
+func (app *App) verifyDownload(id, expires, sig string) (time.Duration, error) {
+	if expires == "" || sig == "" {
+		return 0, Reply(403, "缺少下载签名")
+	}
+	if !hmac.Equal([]byte(sig), []byte(app.signDownload(id, expires))) {
+		return 0, Reply(403, "下载签名无效")
+	}
+
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return 0, Reply(403, "下载签名无效")
+	}
+	remaining := time.Until(time.Unix(unix, 0))
+	if remaining <= 0 {
+		return 0, Reply(403, "下载链接已过期")
+	}
+	return remaining, nil
+}
+
+// handleFileDownload 处理文件下载请求
+func (app *App) handleFileDownload(c *fiber.Ctx) error {
+	app.reload.uploadMu.RLock()
+	defer app.reload.uploadMu.RUnlock()
+
+	id, err := url.PathUnescape(c.Params("id"))
+	if err != nil {
+		id = c.Params("id")
+	}
+
+	remaining, err := app.verifyDownload(id, c.Query("expires"), c.Query("sig"))
+	if err != nil {
+		return app.downloadError(c, err)
+	}
+
+	meta, err := app.GetFile(id)
+	if err != nil {
+		if err == ErrFileNotFound {
+			return app.downloadError(c, Reply(404, "文件不存在"))
+		}
+		return app.downloadError(c, err)
+	}
+
+	disposition := contentDisposition(meta.OriginalName, c.QueryBool("inline"))
+
+	// 重定向模式：远程存储生成预签名地址，失败时回退到代理模式
+	if app.downloadMode() == "redirect" && meta.Backend != "local" {
+		presigned, err := app.presignStoredObject(meta, remaining, disposition)
+		if err == nil {
+			return c.Redirect(app.rewriteFileURL(meta.Backend, presigned, true), fiber.StatusFound)
+		}
+		app.logger.WithError(err).WithFields(map[string]interface{}{
+			"file_id": meta.ID,
+			"backend": meta.Backend,
+		}).Warn("Failed to presign download URL, falling back to proxy")
+	}
+
+	return app.proxyStoredObject(c, meta, disposition)
+}
+
+// downloadError 输出下载错误响应，非 StdReply 错误不向调用方暴露错误详情
+func (app *App) downloadError(c *fiber.Ctx, err error) error {
+	ctx := &Context{Ctx: c, logger: app.logger, app: app}
+	var reply *StdReply
+	if errors.As(err, &reply) {
+		return replyError(ctx, reply)
+	}
+
+	app.logger.WithError(err).Error("File download failed")
+	return c.Status(500).JSON(NewErrorResponse(ctx, 500, "文件下载失败"))
+}
+
+// contentDisposition 生成Content-Disposition头，使用RFC 5987编码支持中文文件名
+func contentDisposition(filename string, inline bool) string {
+	disposition := "attachment"
+	if inline {
+		disposition = "inline"
+	}
+	if filename == "" {
+		return disposition
+	}
+
+	fallback := strings.Map(func(r rune) rune {
+		if r < 0x20 || r > 0x7e || r == '"' || r == '\\' {
+			return '_'
+		}
+		return r
+	}, filename)
+	return fmt.Sprintf(`%s; filename="%s"; filename*=UTF-8''%s`, disposition, fallback, url.PathEscape(filename))
+}
+
+// presignStoredObject 为远程存储对象生成预签名GET地址
+func (app *App) presignStoredObject(meta *FileMetadata, expire time.Duration, disposition string) (string, error) {
+	ctx := context.Background()
+	config := app.cfg.ModConfig.FileUpload
+
+	switch meta.Backend {
+	case "s3", "cos", "qiniu":
+		client, bucket, err := app.s3CompatibleClientFor(meta)
+		if err != nil {
+			return "", err
+		}
+		params := url.Values{}
+		params.Set("response-content-disposition", disposition)
+		u, err := client.PresignedGetObject(ctx, bucket, meta.ObjectKey, expire, params)
+		if err != nil {
+			return "", err
+		}
+		return u.String(), nil
+	case "oss":
+		client := app.ossClient
+		if client == nil {
+			var err error
+			if client, err = app.newOSSClient(); err != nil {
+				return "", err
+			}
+		}
+		result, err := client.Presign(ctx, &oss.GetObjectRequest{
+			Bucket:                     oss.Ptr(app.fileBucket(meta, config.OSS.Bucket)),
+			Key:                        oss.Ptr(meta.ObjectKey),
+			ResponseContentDisposition: oss.Ptr(disposition),
+		}, oss.PresignExpires(expire))
+		if err != nil {
+			return "", err
+		}
+		return result.URL, nil
+	case "gcs":
+		if app.gcsClient == nil {
+			return "", fmt.Errorf("GCS client is not initialized")
+		}
+		// 需要服务账号凭证才能签名
+		return app.gcsClient.Bucket(app.fileBucket(meta, config.GCS.Bucket)).SignedURL(meta.ObjectKey, &storage.SignedURLOptions{
+			Method:          "GET",
+			Expires:         time.Now().Add(expire),
+			Scheme:          storage.SigningSchemeV4,
+			QueryParameters: url.Values{"response-content-disposition": {disposition}},
+		})
+	default:
+		return "", fmt.Errorf("presign not supported for backend: %s", meta.Backend)
+	}
+}
+
+// s3CompatibleClientFor 返回S3兼容后端的客户端和存储桶
+func (app *App) s3CompatibleClientFor(meta *FileMetadata) (*minio.Client, string, error) {
+	config := app.cfg.ModConfig.FileUpload
+
+	switch meta.Backend {
+	case "s3":
+		client, err := app.newS3Client()
+		if err != nil {
+			return nil, "", err
+		}
+		return client, app.fileBucket(meta, config.S3.Bucket), nil
+	case "cos":
+		if app.cosClient == nil {
+			return nil, "", fmt.Errorf("COS client is not initialized")
+		}
+		return app.cosClient, app.fileBucket(meta, config.COS.Bucket), nil
+	case "qiniu":
+		if app.qiniuClient == nil {
+			return nil, "", fmt.Errorf("Qiniu client is not initialized")
+		}
+		return app.qiniuClient, app.fileBucket(meta, config.Qiniu.Bucket), nil
+	default:
+		return nil, "", fmt.Errorf("backend %s is not S3 compatible", meta.Backend)
+	}
+}
+
+// proxyStoredObject 由服务端读取存储对象并输出，支持单段Range请求
+func (app *App) proxyStoredObject(c *fiber.Ctx, meta *FileMetadata, disposition string) error {
+	c.Set(fiber.HeaderContentDisposition, disposition)
+
+	// 本地文件由Fiber处理Range和Content-Type，加密存储的文件与远程对象一样按区间读取并解密
+	if meta.Backend == "local" {
+		if encrypted, err := isEncryptedLocalFile(meta.ObjectKey); err != nil || !encrypted {
+			if meta.MIME != "" {
+				c.Set(fiber.HeaderContentType, meta.MIME)
+			}
+			return c.SendFile(meta.ObjectKey)
+		}
+	}
+
+	start, end := int64(0), meta.Size-1
+	partial := false
+	if c.Get(fiber.HeaderRange) != "" && meta.Size > 0 {
+		ranges, err := c.Range(int(meta.Size))
+		if err != nil {
+			c.Set(fiber.HeaderContentRange, fmt.Sprintf("bytes */%d", meta.Size))
+			return c.SendStatus(fiber.StatusRequestedRangeNotSatisfiable)
+		}
+		// 仅支持单段Range，多段时返回完整内容
+		if ranges.Type == "bytes" && len(ranges.Ranges) == 1 {
+			start, end = int64(ranges.Ranges[0].Start), int64(ranges.Ranges[0].End)
+			partial = true
+		}
+	}
+
+	reader, err := app.openStoredObject(meta, start, end, partial)
+	if err != nil {
+		return app.downloadError(c, err)
+	}
+
+	if meta.MIME != "" {
+		c.Set(fiber.HeaderContentType, meta.MIME)
+	}
+	c.Set(fiber.HeaderAcceptRanges, "bytes")
+	if partial {
+		c.Set(fiber.HeaderContentRange, fmt.Sprintf("bytes %d-%d/%d", start, end, meta.Size))
+		c.Status(fiber.StatusPartialContent)
+	}
+
+	// 响应结束后由fasthttp关闭reader
+	return c.SendStream(reader, int(end-start+1))
+}
+
+// openStoredObject 打开远程存储对象，partial 为 true 时只读取 [start, end] 区间
+func (app *App) openStoredObject(meta *FileMetadata, start, end int64, partial bool) (io.ReadCloser, error) {
+	ctx := context.Background()
+	config := app.cfg.ModConfig.FileUpload
+
+	switch meta.Backend {
+	case "s3", "cos", "qiniu":
+		client, bucket, err := app.s3CompatibleClientFor(meta)
+		if err != nil {
+			return nil, err
+		}
+		opts := minio.GetObjectOptions{}
+		if partial {
+			if err := opts.SetRange(start, end); err != nil {
+				return nil, err
+			}
+		}
+		return client.GetObject(ctx, bucket, meta.ObjectKey, opts)
+	case "oss":
+		client := app.ossClient
+		if client == nil {
+			var err error
+			if client, err = app.newOSSClient(); err != nil {
+				return nil, err
+			}
+		}
+		request := &oss.GetObjectRequest{
+			Bucket: oss.Ptr(app.fileBucket(meta, config.OSS.Bucket)),
+			Key:    oss.Ptr(meta.ObjectKey),
+		}
+		if partial {
+			request.Range = oss.Ptr(fmt.Sprintf("bytes=%d-%d", start, end))
+		}
+		result, err := client.GetObject(ctx, request)
+		if err != nil {
+			return nil, err
+		}
+		return result.Body, nil
+	case "gcs":
+		if app.gcsClient == nil {
+			return nil, fmt.Errorf("GCS client is not initialized")
+		}
+		length := int64(-1)
+		if partial {
+			length = end - start + 1
+		}
+		return app.gcsClient.Bucket(app.fileBucket(meta, config.GCS.Bucket)).Object(meta.ObjectKey).NewRangeReader(ctx, start, length)
+	case "local":
+		file, err := app.OpenLocalFile(meta.ObjectKey)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := file.Seek(start, io.SeekStart); err != nil {
+			file.Close()
+			return nil, err
+		}
+		return struct {
+			io.Reader
+			io.Closer
+		}{io.LimitReader(file, end-start+1), file}, nil
+	default:
+		return nil, fmt.Errorf("unsupported upload backend: %s", meta.Backend)
+	}
+}
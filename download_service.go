@@ -0,0 +1,240 @@
+package mod
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"path/filepath"
+	"reflect"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+)
+
+// DownloadInfo 下载文件的信息
+type DownloadInfo struct {
+	Filename    string    // 下载时的文件名，支持中文
+	ContentType string    // 为空时按文件名的扩展名推断，无法推断时为 application/octet-stream
+	Size        int64     // 文件大小，<=0 时通过 io.Seeker 计算（从当前位置到末尾）；大小未知时不支持Range请求
+	ModTime     time.Time // 修改时间，设置后输出 Last-Modified 并支持 If-Modified-Since
+	ETag        string    // 设置后支持 If-None-Match
+	Inline      bool      // 在浏览器中直接打开而不是作为附件下载
+}
+
+// DownloadResolver 根据请求找到要下载的文件，返回的 reader 实现 io.Closer 时在发送完成后关闭；
+// 返回 mod.Reply(404, ...) 等错误时按标准错误响应输出
+type DownloadResolver func(ctx *Context) (io.Reader, DownloadInfo, error)
+
+// Download 文件下载服务，注册为需要认证的 GET/HEAD 接口
+type Download struct {
+	Name        string           `validate:"required"`
+	DisplayName string           `validate:"required"`
+	Resolver    DownloadResolver `validate:"required"`
+
+	Description string
+	Group       string
+	Sort        int
+	Path        string            // 服务前缀下的路径模板，如 files/:id，路径参数通过 ctx.Params 读取；为空时使用服务名称
+	SkipAuth    bool              // 不需要认证
+	Auth        AuthStrategy      // 认证方式，为空时使用默认配置
+	Permission  *PermissionConfig // 权限规则
+	Scopes      []string          // 所需的Token权限范围
+	Throttle    *ThrottleRule     // 防刷限流规则，如限制每个IP的下载次数
+	Timeout     time.Duration     // 查找文件的超时时间，不包括发送文件内容
+}
+
+// downloadRequest 下载服务没有请求参数，路径和查询参数由 Resolver 读取
+type downloadRequest struct{}
+
+// downloadResponse 下载服务的响应为文件内容
+type downloadResponse struct{}
+
+// RegisterDownload 注册文件下载服务：与普通服务一样经过认证、权限、限流等检查，支持单段Range请求（断点续传）、
+// 条件请求（ETag、Last-Modified），并为每次下载记录审计日志（audit=true，action=file_download）
+func (app *App) RegisterDownload(download Download) error {
+	if err := validate.Struct(download); err != nil {
+		return fmt.Errorf("download %q: %w", download.Name, err)
+	}
+	resolver := download.Resolver
+	name := download.Name
+	description := download.Description
+	if description == "" {
+		description = "文件下载（GET，支持Range请求）"
+	}
+	return app.Register(Service{
+		Name:           download.Name,
+		DisplayName:    download.DisplayName,
+		Description:    description,
+		Group:          download.Group,
+		Sort:           download.Sort,
+		Path:           download.Path,
+		SkipAuth:       download.SkipAuth,
+		Auth:           download.Auth,
+		Permission:     download.Permission,
+		RequiredScopes: download.Scopes,
+		Throttle:       download.Throttle,
+		Timeout:        download.Timeout,
+		Handler: Handler{
+			Func: func(ctx *Context, args any, reply any) error {
+				return app.serveDownload(ctx, name, resolver)
+			},
+			InputType:  reflect.TypeOf(downloadRequest{}),
+			OutputType: reflect.TypeOf(downloadResponse{}),
+		},
+		methods: []string{fiber.MethodGet, fiber.MethodHead},
+	})
+}
+
+// serveDownload 调用 Resolver 并输出文件内容
+func (app *App) serveDownload(ctx *Context, name string, resolver DownloadResolver) error {
+	audit := logrus.Fields{
+		"audit":   true,
+		"action":  "file_download",
+		"service": name,
+		"ip":      ctx.IP(),
+		"rid":     ctx.GetRequestID(),
+	}
+	if user, ok := ctx.User(); ok {
+		audit["user_id"] = user.ID
+	}
+
+	reader, info, err := resolver(ctx)
+	if err != nil {
+		audit["error"] = err.Error()
+		app.logger.WithFields(audit).Warn("File download rejected")
+		return err
+	}
+	closeReader := func() {
+		if closer, ok := reader.(io.Closer); ok {
+			closer.Close()
+		}
+	}
+
+	size := downloadSize(reader, info.Size)
+	audit["file"] = info.Filename
+	audit["size"] = size
+
+	contentType := info.ContentType
+	if contentType == "" {
+		contentType = mime.TypeByExtension(filepath.Ext(info.Filename))
+	}
+	if contentType == "" {
+		contentType = fiber.MIMEOctetStream
+	}
+
+	c := ctx.Ctx
+	c.Set(fiber.HeaderContentType, contentType)
+	c.Set(fiber.HeaderContentDisposition, contentDisposition(info.Filename, info.Inline))
+	if info.ETag != "" {
+		ctx.SetETag(info.ETag)
+	}
+	if !info.ModTime.IsZero() {
+		ctx.SetLastModified(info.ModTime)
+	}
+	if requestFresh(c) {
+		closeReader()
+		audit["status"] = fiber.StatusNotModified
+		app.logger.WithFields(audit).Info("File downloaded")
+		markResponseSent(c)
+		c.Status(fiber.StatusNotModified)
+		return nil
+	}
+
+	// 大小已知且可以定位时支持单段Range请求，偏移量相对于 reader 的当前位置
+	seeker, seekable := reader.(io.Seeker)
+	var base int64
+	if seekable {
+		if base, err = seeker.Seek(0, io.SeekCurrent); err != nil {
+			seekable = false
+		}
+	}
+	start, length := int64(0), size
+	status := fiber.StatusOK
+	if seekable && size >= 0 {
+		c.Set(fiber.HeaderAcceptRanges, "bytes")
+		if c.Get(fiber.HeaderRange) != "" && size > 0 {
+			ranges, err := c.Range(int(size))
+			if err != nil {
+				closeReader()
+				audit["status"] = fiber.StatusRequestedRangeNotSatisfiable
+				app.logger.WithFields(audit).Warn("File download rejected")
+				c.Set(fiber.HeaderContentRange, fmt.Sprintf("bytes */%d", size))
+				markResponseSent(c)
+				return c.SendStatus(fiber.StatusRequestedRangeNotSatisfiable)
+			}
+			// 多段Range时返回完整内容
+			if ranges.Type == "bytes" && len(ranges.Ranges) == 1 {
+				start = int64(ranges.Ranges[0].Start)
+				length = int64(ranges.Ranges[0].End) - start + 1
+				status = fiber.StatusPartialContent
+				c.Set(fiber.HeaderContentRange, fmt.Sprintf("bytes %d-%d/%d", start, start+length-1, size))
+				audit["range"] = c.Get(fiber.HeaderRange)
+			}
+		}
+		if _, err := seeker.Seek(base+start, io.SeekStart); err != nil {
+			closeReader()
+			c.Response().Header.Del(fiber.HeaderContentDisposition)
+			return fmt.Errorf("failed to seek download: %w", err)
+		}
+	} else {
+		c.Set(fiber.HeaderAcceptRanges, "none")
+	}
+
+	audit["status"] = status
+	app.logger.WithFields(audit).Info("File downloaded")
+	markResponseSent(c)
+	c.Status(status)
+	if c.Method() == fiber.MethodHead {
+		closeReader()
+		if length >= 0 {
+			c.Response().Header.SetContentLength(int(length))
+		}
+		return nil
+	}
+
+	// 响应结束后由fasthttp关闭reader
+	body := reader
+	if status == fiber.StatusPartialContent {
+		body = limitedReadCloser{Reader: io.LimitReader(reader, length), closer: reader}
+	}
+	if length < 0 {
+		return c.SendStream(body)
+	}
+	return c.SendStream(body, int(length))
+}
+
+// downloadSize 返回文件大小，未设置时通过 io.Seeker 计算，无法计算时返回-1
+func downloadSize(reader io.Reader, size int64) int64 {
+	if size > 0 {
+		return size
+	}
+	if seeker, ok := reader.(io.Seeker); ok {
+		current, err := seeker.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return -1
+		}
+		end, err := seeker.Seek(0, io.SeekEnd)
+		if err != nil {
+			return -1
+		}
+		if _, err := seeker.Seek(current, io.SeekStart); err != nil {
+			return -1
+		}
+		return end - current
+	}
+	return -1
+}
+
+// limitedReadCloser 只读取Range区间，发送完成后关闭原始 reader
+type limitedReadCloser struct {
+	io.Reader
+	closer io.Reader
+}
+
+func (r limitedReadCloser) Close() error {
+	if closer, ok := r.closer.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
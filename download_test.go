@@ -0,0 +1,120 @@
+package mod
+
+import (
+	"bytes"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// downloadConfig 启用本地上传、内存元数据存储和代理下载的配置
+func downloadConfig(t *testing.T) string {
+	return `
+token:
+  jwt:
+    enabled: true
+    secret_key: "test-secret-key-for-downloads"
+file_upload:
+  local:
+    enabled: true
+    upload_dir: "` + t.TempDir() + `"
+  metadata:
+    enabled: true
+    in_memory: true
+  download:
+    enabled: true
+    mode: proxy
+`
+}
+
+func TestDownloadSecretDerivedFromJWTKey(t *testing.T) {
+	app := newTestApp(t, downloadConfig(t))
+	key, err := app.Key(KeyJWT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(app.downloadSecret) == 0 {
+		t.Fatal("download secret not configured")
+	}
+	if bytes.Equal(app.downloadSecret, key.Material) {
+		t.Fatal("download secret must not reuse the JWT signing key")
+	}
+}
+
+func TestSignedDownload(t *testing.T) {
+	app := newTestApp(t, downloadConfig(t))
+	path := filepath.Join(t.TempDir(), "report.txt")
+	if err := os.WriteFile(path, []byte("report body"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	err := app.GetFileMetadataStore().Save(&FileMetadata{
+		ID:           "f1",
+		OriginalName: "report.txt",
+		Filename:     "report.txt",
+		Size:         11,
+		MIME:         "text/plain",
+		Backend:      "local",
+		ObjectKey:    path,
+		CreatedAt:    time.Now(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	link, _, err := app.SignDownloadURL("f1", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := app.TestClient().Get(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.AssertStatus(t, 200)
+	if string(resp.Body) != "report body" {
+		t.Fatalf("unexpected body %q", resp.Body)
+	}
+
+	// 篡改文件ID或签名、缺少签名时拒绝，错误使用标准响应格式
+	parsed, err := url.Parse(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := parsed.Query()
+	for _, tc := range []struct {
+		link string
+		msg  string
+	}{
+		{strings.Replace(link, "/f1?", "/f2?", 1), "下载签名无效"},
+		{parsed.Path + "?expires=" + query.Get("expires") + "&sig=" + strings.Repeat("0", 64), "下载签名无效"},
+		{parsed.Path, "缺少下载签名"},
+	} {
+		resp, err := app.TestClient().Get(tc.link)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.AssertStatus(t, 403).AssertCode(t, 403).AssertMsg(t, tc.msg)
+	}
+
+	// 已过期的链接
+	expires := strconv.FormatInt(time.Now().Add(-time.Minute).Unix(), 10)
+	expired := parsed.Path + "?expires=" + expires + "&sig=" + app.signDownload("f1", expires)
+	resp, err = app.TestClient().Get(expired)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.AssertStatus(t, 403).AssertMsg(t, "下载链接已过期")
+
+	link, _, err = app.SignDownloadURL("missing", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err = app.TestClient().Get(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.AssertStatus(t, 404).AssertCode(t, 404)
+}
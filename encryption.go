@@ -34,6 +34,7 @@ func NewEncryptionManager(config *ModConfig) *EncryptionManager {
 type SymmetricEncryption struct {
 	Algorithm string
 	Key       []byte
+	previous  [][]byte // 轮换前的旧密钥，解密失败时依次尝试
 }
 
 // NewSymmetricEncryption 创建对称加密实例
@@ -79,8 +80,20 @@ func (s *SymmetricEncryption) Encrypt(plaintext []byte) ([]byte, error) {
 	}
 }
 
-// Decrypt 对称解密
+// Decrypt 对称解密，当前密钥解密失败时依次尝试轮换前的旧密钥
 func (s *SymmetricEncryption) Decrypt(ciphertext []byte) ([]byte, error) {
+	plaintext, err := s.decrypt(ciphertext)
+	for i := 0; err != nil && i < len(s.previous); i++ {
+		old := &SymmetricEncryption{Algorithm: s.Algorithm, Key: s.previous[i]}
+		if p, e := old.decrypt(ciphertext); e == nil {
+			return p, nil
+		}
+	}
+	return plaintext, err
+}
+
+// decrypt 使用当前密钥解密
+func (s *SymmetricEncryption) decrypt(ciphertext []byte) ([]byte, error) {
 	switch s.Algorithm {
 	case "AES256-GCM":
 		return s.decryptAESGCM(ciphertext)
@@ -178,6 +191,7 @@ type AsymmetricEncryption struct {
 	Algorithm  string
 	PublicKey  *rsa.PublicKey
 	PrivateKey *rsa.PrivateKey
+	previous   []*rsa.PrivateKey // 轮换前的旧私钥，解密失败时依次尝试
 }
 
 // NewAsymmetricEncryption 创建非对称加密实例
@@ -239,7 +253,7 @@ func (a *AsymmetricEncryption) Encrypt(plaintext []byte) ([]byte, error) {
 	}
 }
 
-// Decrypt 非对称解密（使用私钥）
+// Decrypt 非对称解密（使用私钥），当前私钥解密失败时依次尝试轮换前的旧私钥
 func (a *AsymmetricEncryption) Decrypt(ciphertext []byte) ([]byte, error) {
 	if a.PrivateKey == nil {
 		return nil, errors.New("private key not available")
@@ -247,7 +261,13 @@ func (a *AsymmetricEncryption) Decrypt(ciphertext []byte) ([]byte, error) {
 
 	switch a.Algorithm {
 	case "RSA-OAEP":
-		return rsa.DecryptOAEP(sha256.New(), rand.Reader, a.PrivateKey, ciphertext, nil)
+		plaintext, err := rsa.DecryptOAEP(sha256.New(), rand.Reader, a.PrivateKey, ciphertext, nil)
+		for i := 0; err != nil && i < len(a.previous); i++ {
+			if p, e := rsa.DecryptOAEP(sha256.New(), rand.Reader, a.previous[i], ciphertext, nil); e == nil {
+				return p, nil
+			}
+		}
+		return plaintext, err
 	default:
 		return nil, fmt.Errorf("unsupported algorithm: %s", a.Algorithm)
 	}
@@ -320,6 +340,7 @@ func parsePrivateKeyFromPEM(pemData string) (*rsa.PrivateKey, error) {
 type SignatureVerification struct {
 	Algorithm string
 	Key       []byte
+	previous  [][]byte // 轮换前的旧密钥，验证失败时依次尝试
 }
 
 // NewSignatureVerification 创建签名验证实例
@@ -367,11 +388,16 @@ func (s *SignatureVerification) Sign(data []byte) ([]byte, error) {
 func (s *SignatureVerification) Verify(data []byte, signature []byte) error {
 	switch s.Algorithm {
 	case "HMAC-SHA256":
-		expectedSig := s.signHMAC(data)
-		if !hmac.Equal(signature, expectedSig) {
-			return errors.New("signature verification failed")
+		if hmac.Equal(signature, s.signHMAC(data)) {
+			return nil
 		}
-		return nil
+		for _, key := range s.previous {
+			old := &SignatureVerification{Algorithm: s.Algorithm, Key: key}
+			if hmac.Equal(signature, old.signHMAC(data)) {
+				return nil
+			}
+		}
+		return errors.New("signature verification failed")
 	default:
 		return fmt.Errorf("unsupported signature algorithm: %s", s.Algorithm)
 	}
@@ -384,6 +410,73 @@ func (s *SignatureVerification) signHMAC(data []byte) []byte {
 	return h.Sum(nil)
 }
 
+// symmetricEncryption 使用密钥提供者的 encryption.symmetric 密钥创建对称加密实例
+func (app *App) symmetricEncryption() (*SymmetricEncryption, error) {
+	config := app.GetModConfig()
+	if config == nil {
+		return nil, errors.New("config is nil")
+	}
+	keys, err := app.keys.versions(KeyEncryptionSymmetric)
+	if err != nil {
+		return nil, err
+	}
+	sym := &SymmetricEncryption{Algorithm: config.Encryption.Symmetric.Algorithm, Key: keys[0].Material}
+	for _, key := range keys[1:] {
+		sym.previous = append(sym.previous, key.Material)
+	}
+	return sym, nil
+}
+
+// asymmetricEncryption 使用密钥提供者的 encryption.public_key、encryption.private_key 创建非对称加密实例，
+// 未配置的密钥为 nil
+func (app *App) asymmetricEncryption() (*AsymmetricEncryption, error) {
+	config := app.GetModConfig()
+	if config == nil {
+		return nil, errors.New("config is nil")
+	}
+	asym := &AsymmetricEncryption{Algorithm: config.Encryption.Asymmetric.Algorithm}
+	if key, err := app.keys.get(KeyEncryptionPublicKey); err == nil {
+		if asym.PublicKey, err = parsePublicKeyFromPEM(string(key.Material)); err != nil {
+			return nil, fmt.Errorf("failed to parse public key: %w", err)
+		}
+	} else if !errors.Is(err, ErrKeyNotFound) {
+		return nil, err
+	}
+	keys, err := app.keys.versions(KeyEncryptionPrivateKey)
+	if err != nil && !errors.Is(err, ErrKeyNotFound) {
+		return nil, err
+	}
+	for i, key := range keys {
+		privateKey, err := parsePrivateKeyFromPEM(string(key.Material))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse private key: %w", err)
+		}
+		if i == 0 {
+			asym.PrivateKey = privateKey
+		} else {
+			asym.previous = append(asym.previous, privateKey)
+		}
+	}
+	return asym, nil
+}
+
+// signatureVerification 使用密钥提供者的 encryption.signature 密钥创建签名验证实例
+func (app *App) signatureVerification() (*SignatureVerification, error) {
+	config := app.GetModConfig()
+	if config == nil {
+		return nil, errors.New("config is nil")
+	}
+	keys, err := app.keys.versions(KeyEncryptionSignature)
+	if err != nil {
+		return nil, err
+	}
+	sig := &SignatureVerification{Algorithm: config.Encryption.Signature.Algorithm, Key: keys[0].Material}
+	for _, key := range keys[1:] {
+		sig.previous = append(sig.previous, key.Material)
+	}
+	return sig, nil
+}
+
 // CheckEncryption 检查是否需要加密
 func CheckEncryption(config *ModConfig, serviceName, groupName string) bool {
 	if config == nil || !config.Encryption.Global.Enabled {
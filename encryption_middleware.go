@@ -4,6 +4,7 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"mime"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -39,9 +40,12 @@ func EncryptionMiddleware(app *App) fiber.Handler {
 			return c.Next()
 		}
 
-		// 解密请求
-		if err := decryptRequest(c, config); err != nil {
-			return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("Failed to decrypt request: %v", err))
+		// 解密请求：JSON使用 EncryptedRequest 格式，二进制和 multipart 使用加密信封；
+		// 异步任务保存的是已解密的请求，重放时无需再解密
+		if c.Locals(jobReplayKey{}) == nil {
+			if err := decryptRequestBody(c, app, config); err != nil {
+				return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("Failed to decrypt request: %v", err))
+			}
 		}
 
 		// 继续处理
@@ -49,8 +53,8 @@ func EncryptionMiddleware(app *App) fiber.Handler {
 			return err
 		}
 
-		// 加密响应
-		if err := encryptResponse(c, config); err != nil {
+		// 加密响应：JSON使用 EncryptedResponse 格式，文件等其他响应使用加密信封
+		if err := encryptResponseBody(c, app, config); err != nil {
 			return fiber.NewError(fiber.StatusInternalServerError, fmt.Sprintf("Failed to encrypt response: %v", err))
 		}
 
@@ -58,66 +62,66 @@ func EncryptionMiddleware(app *App) fiber.Handler {
 	}
 }
 
+// decryptRequestBody 按请求的 Content-Type 选择解密方式
+func decryptRequestBody(c *fiber.Ctx, app *App, config *ModConfig) error {
+	// 启用 import.stream 时仍按 server.body_limit 限制请求体大小
+	if err := app.limitRequestBody(c, app.cfg.Config.BodyLimit); err != nil {
+		return err
+	}
+	// 没有请求体（如文件下载的GET请求）时无需解密
+	if len(c.Body()) == 0 {
+		return nil
+	}
+	mediaType, _, _ := mime.ParseMediaType(c.Get(fiber.HeaderContentType))
+	switch mediaType {
+	case EncryptedStreamContentType:
+		return decryptStreamRequest(c, app)
+	case fiber.MIMEMultipartForm:
+		return decryptMultipartRequest(c, app)
+	default:
+		return decryptRequest(c, app, config)
+	}
+}
+
+// encryptResponseBody 按响应的 Content-Type 选择加密方式
+func encryptResponseBody(c *fiber.Ctx, app *App, config *ModConfig) error {
+	if isJSONContentType(string(c.Response().Header.ContentType())) {
+		return encryptResponse(c, app, config)
+	}
+	return encryptStreamResponse(c, app, config.Encryption.Global.Mode)
+}
+
 // 解密请求
-func decryptRequest(c *fiber.Ctx, config *ModConfig) error {
+func decryptRequest(c *fiber.Ctx, app *App, config *ModConfig) error {
 	var encReq EncryptedRequest
 	if err := c.BodyParser(&encReq); err != nil {
 		return err
 	}
 
+	encryptedData, err := base64.StdEncoding.DecodeString(encReq.Data)
+	if err != nil {
+		return fmt.Errorf("failed to decode encrypted data: %w", err)
+	}
+
 	// 验证签名
 	if config.Encryption.Signature.Enabled {
-		sigVerification := NewSignatureVerification(config)
-		if sigVerification != nil {
-			dataBytes, err := base64.StdEncoding.DecodeString(encReq.Data)
-			if err != nil {
-				return fmt.Errorf("failed to decode data for signature verification: %w", err)
-			}
-
-			signatureBytes, err := base64.StdEncoding.DecodeString(encReq.Signature)
-			if err != nil {
-				return fmt.Errorf("failed to decode signature: %w", err)
-			}
-
-			if err := sigVerification.Verify(dataBytes, signatureBytes); err != nil {
-				return fmt.Errorf("signature verification failed: %w", err)
-			}
+		signatureBytes, err := base64.StdEncoding.DecodeString(encReq.Signature)
+		if err != nil {
+			return fmt.Errorf("failed to decode signature: %w", err)
+		}
+		if err := app.VerifySignature(encryptedData, signatureBytes); err != nil {
+			return fmt.Errorf("signature verification failed: %w", err)
 		}
 	}
 
 	// 解密数据
-	encryptedData, err := base64.StdEncoding.DecodeString(encReq.Data)
-	if err != nil {
-		return fmt.Errorf("failed to decode encrypted data: %w", err)
-	}
-
-	var decryptedData []byte
 	mode := encReq.Mode
 	if mode == "" {
 		mode = config.Encryption.Global.Mode
 	}
-
-	switch mode {
-	case "symmetric":
-		symEncryption, err := NewSymmetricEncryption(config)
-		if err != nil {
-			return fmt.Errorf("failed to create symmetric encryption: %w", err)
-		}
-		decryptedData, err = symEncryption.Decrypt(encryptedData)
-		if err != nil {
-			return fmt.Errorf("symmetric decryption failed: %w", err)
-		}
-	case "asymmetric":
-		asymEncryption, err := NewAsymmetricEncryption(config)
-		if err != nil {
-			return fmt.Errorf("failed to create asymmetric encryption: %w", err)
-		}
-		decryptedData, err = asymEncryption.Decrypt(encryptedData)
-		if err != nil {
-			return fmt.Errorf("asymmetric decryption failed: %w", err)
-		}
-	default:
-		return fmt.Errorf("unsupported encryption mode: %s", mode)
+	decryptedData, err := app.DecryptData(encryptedData, mode)
+	if err != nil {
+		return fmt.Errorf("%s decryption failed: %w", mode, err)
 	}
 
 	// 替换请求体
@@ -127,48 +131,24 @@ func decryptRequest(c *fiber.Ctx, config *ModConfig) error {
 }
 
 // 加密响应
-func encryptResponse(c *fiber.Ctx, config *ModConfig) error {
+func encryptResponse(c *fiber.Ctx, app *App, config *ModConfig) error {
 	originalBody := c.Response().Body()
 	if len(originalBody) == 0 {
 		return nil
 	}
 
 	mode := config.Encryption.Global.Mode
-	var encryptedData []byte
-	var err error
-
-	switch mode {
-	case "symmetric":
-		symEncryption, err := NewSymmetricEncryption(config)
-		if err != nil {
-			return fmt.Errorf("failed to create symmetric encryption: %w", err)
-		}
-		encryptedData, err = symEncryption.Encrypt(originalBody)
-		if err != nil {
-			return fmt.Errorf("symmetric encryption failed: %w", err)
-		}
-	case "asymmetric":
-		asymEncryption, err := NewAsymmetricEncryption(config)
-		if err != nil {
-			return fmt.Errorf("failed to create asymmetric encryption: %w", err)
-		}
-		encryptedData, err = asymEncryption.Encrypt(originalBody)
-		if err != nil {
-			return fmt.Errorf("asymmetric encryption failed: %w", err)
-		}
-	default:
-		return fmt.Errorf("unsupported encryption mode: %s", mode)
+	encryptedData, err := app.EncryptData(originalBody, mode)
+	if err != nil {
+		return fmt.Errorf("%s encryption failed: %w", mode, err)
 	}
 
 	// 生成签名
 	var signature []byte
 	if config.Encryption.Signature.Enabled {
-		sigVerification := NewSignatureVerification(config)
-		if sigVerification != nil {
-			signature, err = sigVerification.Sign(encryptedData)
-			if err != nil {
-				return fmt.Errorf("failed to sign response: %w", err)
-			}
+		signature, err = app.SignData(encryptedData)
+		if err != nil {
+			return fmt.Errorf("failed to sign response: %w", err)
 		}
 	}
 
@@ -0,0 +1,575 @@
+package mod
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// 二进制加密信封，用于文件上传、下载等非JSON的请求和响应
+const (
+	// EncryptedStreamContentType 加密信封的 Content-Type
+	EncryptedStreamContentType = "application/vnd.mod.encrypted-stream"
+	// HeaderEncryptedContentType 加密前的 Content-Type，用于请求头、响应头和 multipart 分段头
+	HeaderEncryptedContentType = "X-Encrypted-Content-Type"
+)
+
+// 信封格式：
+//
+//	头部   "MODENC" | 版本(1) | 模式(1) | 标志(1) | 密钥长度(uint16) | 密钥
+//	数据帧 长度(uint32) | nonce | 密文，附加数据为 帧序号(uint64) | 是否最后一帧(1)
+//	签名   长度(uint16) | HMAC-SHA256(头部和全部数据帧)，仅在启用签名时存在
+//
+// 对称模式直接使用 encryption.symmetric 的密钥和算法；非对称模式为每个信封生成随机的
+// AES256-GCM 数据密钥，使用 RSA-OAEP 公钥加密后写入头部。数据帧独立加密，
+// 接收方可以边读边解密，帧被截断、重排或篡改时解密失败。
+const (
+	streamMagic     = "MODENC"
+	streamVersion   = 1
+	streamChunkSize = 64 * 1024
+	streamMaxFrame  = streamChunkSize + 64
+
+	streamModeSymmetric  = 1
+	streamModeAsymmetric = 2
+
+	streamFlagSigned = 1
+)
+
+// streamCipher 创建信封的数据帧加密器，返回写入头部的密钥（对称模式为空）
+func (app *App) streamCipher(mode string) (cipher.AEAD, byte, []byte, error) {
+	switch mode {
+	case "symmetric":
+		sym, err := app.symmetricEncryption()
+		if err != nil {
+			return nil, 0, nil, err
+		}
+		aead, err := newStreamAEAD(sym.Algorithm, sym.Key)
+		return aead, streamModeSymmetric, nil, err
+	case "asymmetric":
+		asym, err := app.asymmetricEncryption()
+		if err != nil {
+			return nil, 0, nil, err
+		}
+		if asym.PublicKey == nil {
+			return nil, 0, nil, errors.New("public key not available")
+		}
+		key := make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, 0, nil, err
+		}
+		wrapped, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, asym.PublicKey, key, nil)
+		if err != nil {
+			return nil, 0, nil, err
+		}
+		aead, err := newStreamAEAD("AES256-GCM", key)
+		return aead, streamModeAsymmetric, wrapped, err
+	default:
+		return nil, 0, nil, fmt.Errorf("unsupported encryption mode: %s", mode)
+	}
+}
+
+// newStreamAEAD 按算法创建 AEAD
+func newStreamAEAD(algorithm string, key []byte) (cipher.AEAD, error) {
+	switch algorithm {
+	case "AES256-GCM":
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		return cipher.NewGCM(block)
+	case "ChaCha20-Poly1305":
+		return chacha20poly1305.New(key)
+	default:
+		return nil, fmt.Errorf("unsupported algorithm: %s", algorithm)
+	}
+}
+
+// streamSigners 启用签名时返回当前密钥和轮换前旧密钥的 HMAC，未启用时返回 nil
+func (app *App) streamSigners(config *ModConfig) ([]hash.Hash, error) {
+	if !config.Encryption.Signature.Enabled {
+		return nil, nil
+	}
+	sig, err := app.signatureVerification()
+	if err != nil {
+		return nil, err
+	}
+	if sig.Algorithm != "HMAC-SHA256" {
+		return nil, fmt.Errorf("unsupported signature algorithm: %s", sig.Algorithm)
+	}
+	macs := []hash.Hash{hmac.New(sha256.New, sig.Key)}
+	for _, key := range sig.previous {
+		macs = append(macs, hmac.New(sha256.New, key))
+	}
+	return macs, nil
+}
+
+// streamAAD 数据帧的附加数据
+func streamAAD(seq uint64, final bool) []byte {
+	aad := make([]byte, 9)
+	binary.BigEndian.PutUint64(aad, seq)
+	if final {
+		aad[8] = 1
+	}
+	return aad
+}
+
+// encryptWriter 按帧加密写入的数据，Close 时写入最后一帧和签名
+type encryptWriter struct {
+	w      io.Writer
+	aead   cipher.AEAD
+	mac    hash.Hash
+	header []byte
+	buf    []byte
+	seq    uint64
+	closed bool
+}
+
+// EncryptStream 返回加密写入器，写入的明文按信封格式加密后写入 w，必须调用 Close 完成信封；
+// mode 为 symmetric 或 asymmetric，为空时使用全局配置
+func (app *App) EncryptStream(w io.Writer, mode string) (io.WriteCloser, error) {
+	ew, err := app.newEncryptWriter(mode)
+	if err != nil {
+		return nil, err
+	}
+	ew.w = w
+	return ew, nil
+}
+
+// newEncryptWriter 创建未绑定输出的加密写入器，头部在写出第一帧时输出
+func (app *App) newEncryptWriter(mode string) (*encryptWriter, error) {
+	config := app.GetModConfig()
+	if config == nil {
+		return nil, fmt.Errorf("no configuration available")
+	}
+	if mode == "" {
+		mode = config.Encryption.Global.Mode
+	}
+	aead, modeByte, key, err := app.streamCipher(mode)
+	if err != nil {
+		return nil, err
+	}
+	macs, err := app.streamSigners(config)
+	if err != nil {
+		return nil, err
+	}
+
+	ew := &encryptWriter{aead: aead, buf: make([]byte, 0, streamChunkSize)}
+	if len(macs) > 0 {
+		ew.mac = macs[0]
+	}
+	var flags byte
+	if ew.mac != nil {
+		flags |= streamFlagSigned
+	}
+	ew.header = append([]byte(streamMagic), streamVersion, modeByte, flags, 0, 0)
+	binary.BigEndian.PutUint16(ew.header[len(ew.header)-2:], uint16(len(key)))
+	ew.header = append(ew.header, key...)
+	return ew, nil
+}
+
+// write 写入底层 writer 并计入签名
+func (ew *encryptWriter) write(data []byte) error {
+	if ew.mac != nil {
+		ew.mac.Write(data)
+	}
+	_, err := ew.w.Write(data)
+	return err
+}
+
+// Write 缓冲明文，每满一帧加密写出
+func (ew *encryptWriter) Write(p []byte) (int, error) {
+	if ew.closed {
+		return 0, errors.New("encrypt stream is closed")
+	}
+	n := len(p)
+	for len(p) > 0 {
+		free := streamChunkSize - len(ew.buf)
+		if free > len(p) {
+			free = len(p)
+		}
+		ew.buf = append(ew.buf, p[:free]...)
+		p = p[free:]
+		// 满帧时暂不写出，留到确定是否为最后一帧
+		if len(ew.buf) == streamChunkSize && len(p) > 0 {
+			if err := ew.flush(false); err != nil {
+				return n - len(p), err
+			}
+		}
+	}
+	return n, nil
+}
+
+// flush 加密并写出当前缓冲的数据帧
+func (ew *encryptWriter) flush(final bool) error {
+	if ew.header != nil {
+		if err := ew.write(ew.header); err != nil {
+			return err
+		}
+		ew.header = nil
+	}
+	nonce := make([]byte, ew.aead.NonceSize(), ew.aead.NonceSize()+len(ew.buf)+ew.aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	sealed := ew.aead.Seal(nonce, nonce, ew.buf, streamAAD(ew.seq, final))
+	ew.seq++
+	ew.buf = ew.buf[:0]
+
+	frame := make([]byte, 4, 4+len(sealed))
+	binary.BigEndian.PutUint32(frame, uint32(len(sealed)))
+	return ew.write(append(frame, sealed...))
+}
+
+// Close 写出最后一帧和签名，不关闭底层 writer
+func (ew *encryptWriter) Close() error {
+	if ew.closed {
+		return nil
+	}
+	ew.closed = true
+	if err := ew.flush(true); err != nil {
+		return err
+	}
+	if ew.mac == nil {
+		return nil
+	}
+	signature := ew.mac.Sum(nil)
+	trailer := make([]byte, 2, 2+len(signature))
+	binary.BigEndian.PutUint16(trailer, uint16(len(signature)))
+	_, err := ew.w.Write(append(trailer, signature...))
+	return err
+}
+
+// decryptReader 逐帧解密信封，读到最后一帧并校验签名后返回 io.EOF
+type decryptReader struct {
+	r      *bufio.Reader
+	aead   cipher.AEAD
+	aeads  []cipher.AEAD // 当前密钥和轮换前的旧密钥，第一帧解密成功后确定 aead
+	macs   []hash.Hash   // 当前签名密钥和轮换前的旧密钥，任一匹配即通过
+	signed bool
+	plain  []byte
+	seq    uint64
+	done   bool
+	err    error
+}
+
+// DecryptStream 返回解密读取器，从 r 读取信封并逐帧解密；加密模式从信封头部读取，
+// 启用签名时要求信封带有签名。信封被截断或篡改时 Read 返回错误，调用方应丢弃已读取的数据
+func (app *App) DecryptStream(r io.Reader) (io.Reader, error) {
+	config := app.GetModConfig()
+	if config == nil {
+		return nil, fmt.Errorf("no configuration available")
+	}
+
+	br := bufio.NewReader(r)
+	header := make([]byte, len(streamMagic)+5)
+	if _, err := io.ReadFull(br, header); err != nil {
+		return nil, fmt.Errorf("invalid encrypted stream header: %w", err)
+	}
+	if string(header[:len(streamMagic)]) != streamMagic {
+		return nil, errors.New("invalid encrypted stream header")
+	}
+	fields := header[len(streamMagic):]
+	if fields[0] != streamVersion {
+		return nil, fmt.Errorf("unsupported encrypted stream version: %d", fields[0])
+	}
+	key := make([]byte, binary.BigEndian.Uint16(fields[3:]))
+	if _, err := io.ReadFull(br, key); err != nil {
+		return nil, fmt.Errorf("invalid encrypted stream header: %w", err)
+	}
+
+	// 启用签名时要求信封带有签名，未启用时跳过信封中的签名
+	dr := &decryptReader{r: br, signed: fields[2]&streamFlagSigned != 0}
+	macs, err := app.streamSigners(config)
+	if err != nil {
+		return nil, err
+	}
+	if dr.macs = macs; len(macs) > 0 {
+		if !dr.signed {
+			return nil, errors.New("encrypted stream is not signed")
+		}
+		dr.writeMAC(header, key)
+	}
+
+	switch fields[1] {
+	case streamModeSymmetric:
+		sym, err := app.symmetricEncryption()
+		if err != nil {
+			return nil, err
+		}
+		for _, k := range append([][]byte{sym.Key}, sym.previous...) {
+			aead, err := newStreamAEAD(sym.Algorithm, k)
+			if err != nil {
+				return nil, err
+			}
+			dr.aeads = append(dr.aeads, aead)
+		}
+	case streamModeAsymmetric:
+		asym, err := app.asymmetricEncryption()
+		if err != nil {
+			return nil, err
+		}
+		if asym.PrivateKey == nil {
+			return nil, errors.New("private key not available")
+		}
+		// 依次使用当前私钥和轮换前的旧私钥解开数据密钥
+		for _, privateKey := range append([]*rsa.PrivateKey{asym.PrivateKey}, asym.previous...) {
+			if dataKey, err := rsa.DecryptOAEP(sha256.New(), rand.Reader, privateKey, key, nil); err == nil {
+				aead, err := newStreamAEAD("AES256-GCM", dataKey)
+				if err != nil {
+					return nil, err
+				}
+				dr.aeads = []cipher.AEAD{aead}
+				break
+			}
+		}
+		if len(dr.aeads) == 0 {
+			return nil, errors.New("failed to decrypt encrypted stream data key")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported encrypted stream mode: %d", fields[1])
+	}
+	return dr, nil
+}
+
+// writeMAC 计入签名
+func (dr *decryptReader) writeMAC(data ...[]byte) {
+	for _, mac := range dr.macs {
+		for _, d := range data {
+			mac.Write(d)
+		}
+	}
+}
+
+// Read 返回已解密的数据，缓冲为空时解密下一帧
+func (dr *decryptReader) Read(p []byte) (int, error) {
+	for len(dr.plain) == 0 {
+		if dr.err != nil {
+			return 0, dr.err
+		}
+		if dr.done {
+			dr.err = dr.finish()
+			continue
+		}
+		dr.err = dr.next()
+	}
+	n := copy(p, dr.plain)
+	dr.plain = dr.plain[n:]
+	return n, nil
+}
+
+// next 读取并解密一帧
+func (dr *decryptReader) next() error {
+	var size [4]byte
+	if _, err := io.ReadFull(dr.r, size[:]); err != nil {
+		return fmt.Errorf("encrypted stream truncated: %w", err)
+	}
+	length := binary.BigEndian.Uint32(size[:])
+	first := dr.aeads[0]
+	if length < uint32(first.NonceSize()+first.Overhead()) || length > streamMaxFrame {
+		return errors.New("invalid encrypted stream frame")
+	}
+	sealed := make([]byte, length)
+	if _, err := io.ReadFull(dr.r, sealed); err != nil {
+		return fmt.Errorf("encrypted stream truncated: %w", err)
+	}
+	dr.writeMAC(size[:], sealed)
+
+	// 第一帧依次尝试当前密钥和轮换前的旧密钥，之后的帧使用同一个密钥
+	candidates := dr.aeads
+	if dr.aead != nil {
+		candidates = []cipher.AEAD{dr.aead}
+	}
+	for _, aead := range candidates {
+		nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+		// 解密失败时 AEAD 会清空输出，不能原地解密
+		plain, err := aead.Open(nil, nonce, ciphertext, streamAAD(dr.seq, false))
+		if err != nil {
+			// 不是中间帧时按最后一帧解密
+			if plain, err = aead.Open(nil, nonce, ciphertext, streamAAD(dr.seq, true)); err != nil {
+				continue
+			}
+			dr.done = true
+		}
+		dr.aead = aead
+		dr.seq++
+		dr.plain = plain
+		return nil
+	}
+	return errors.New("encrypted stream frame authentication failed")
+}
+
+// finish 最后一帧之后校验签名，并确认没有多余的数据
+func (dr *decryptReader) finish() error {
+	if dr.signed {
+		var size [2]byte
+		if _, err := io.ReadFull(dr.r, size[:]); err != nil {
+			return errors.New("encrypted stream signature missing")
+		}
+		signature := make([]byte, binary.BigEndian.Uint16(size[:]))
+		if _, err := io.ReadFull(dr.r, signature); err != nil {
+			return errors.New("encrypted stream signature missing")
+		}
+		if len(dr.macs) > 0 && !dr.verifySignature(signature) {
+			return errors.New("signature verification failed")
+		}
+	}
+	if _, err := dr.r.ReadByte(); err != io.EOF {
+		return errors.New("unexpected data after encrypted stream")
+	}
+	return io.EOF
+}
+
+// verifySignature 签名与任一密钥计算的结果一致即通过
+func (dr *decryptReader) verifySignature(signature []byte) bool {
+	for _, mac := range dr.macs {
+		if hmac.Equal(signature, mac.Sum(nil)) {
+			return true
+		}
+	}
+	return false
+}
+
+// isJSONContentType 判断是否为JSON请求或响应
+func isJSONContentType(contentType string) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	return mediaType == "" || mediaType == fiber.MIMEApplicationJSON || strings.HasSuffix(mediaType, "+json")
+}
+
+// decryptStreamRequest 解密信封格式的请求体，并恢复加密前的 Content-Type
+func decryptStreamRequest(c *fiber.Ctx, app *App) error {
+	plain, err := app.decryptStreamBytes(c.Body())
+	if err != nil {
+		return err
+	}
+	contentType := c.Get(HeaderEncryptedContentType)
+	if contentType == "" {
+		contentType = fiber.MIMEOctetStream
+	}
+	c.Request().SetBody(plain)
+	c.Request().Header.SetContentType(contentType)
+	c.Request().Header.Del(HeaderEncryptedContentType)
+	return nil
+}
+
+// decryptMultipartRequest 逐个解密 multipart 中信封格式的分段并重新组装请求体；
+// 文件分段必须加密，普通字段可以加密也可以是明文
+func decryptMultipartRequest(c *fiber.Ctx, app *App) error {
+	_, params, err := mime.ParseMediaType(c.Get(fiber.HeaderContentType))
+	if err != nil || params["boundary"] == "" {
+		return errors.New("invalid multipart content type")
+	}
+
+	reader := multipart.NewReader(bytes.NewReader(c.Body()), params["boundary"])
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	if err := writer.SetBoundary(params["boundary"]); err != nil {
+		return err
+	}
+	for {
+		part, err := reader.NextRawPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("invalid multipart body: %w", err)
+		}
+		data, err := io.ReadAll(part)
+		if err != nil {
+			return fmt.Errorf("invalid multipart body: %w", err)
+		}
+
+		header := textproto.MIMEHeader{}
+		for k, v := range part.Header {
+			header[k] = v
+		}
+		if mediaType, _, _ := mime.ParseMediaType(header.Get(fiber.HeaderContentType)); mediaType == EncryptedStreamContentType {
+			if data, err = app.decryptStreamBytes(data); err != nil {
+				return fmt.Errorf("part %q: %w", part.FormName(), err)
+			}
+			contentType := header.Get(HeaderEncryptedContentType)
+			if contentType == "" {
+				contentType = fiber.MIMEOctetStream
+			}
+			header.Set(fiber.HeaderContentType, contentType)
+			header.Del(HeaderEncryptedContentType)
+		} else if part.FileName() != "" {
+			return fmt.Errorf("file part %q is not encrypted", part.FormName())
+		}
+
+		w, err := writer.CreatePart(header)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	c.Request().SetBody(body.Bytes())
+	return nil
+}
+
+// decryptStreamBytes 解密完整的信封
+func (app *App) decryptStreamBytes(data []byte) ([]byte, error) {
+	reader, err := app.DecryptStream(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(reader)
+}
+
+// encryptStreamResponse 将非JSON响应（文件下载、二进制数据等）按信封格式加密，边加密边输出；
+// 以流方式设置的响应体（SendFile、SendStream）会先读取完整内容
+func encryptStreamResponse(c *fiber.Ctx, app *App, mode string) error {
+	resp := c.Response()
+	if !resp.IsBodyStream() && len(resp.Body()) == 0 {
+		return nil
+	}
+	contentType := string(resp.Header.ContentType())
+	// 设置新的响应体时原响应体的缓冲区会被回收，需要取得明文的所有权
+	var plain []byte
+	if resp.IsBodyStream() {
+		plain = resp.SwapBody(nil)
+	} else {
+		plain = bytes.Clone(resp.Body())
+	}
+
+	// 在切换响应体前创建加密器，配置有误时保留原响应体并返回错误
+	ew, err := app.newEncryptWriter(mode)
+	if err != nil {
+		resp.SetBodyRaw(plain)
+		return err
+	}
+
+	resp.Header.Set(HeaderEncryptedContentType, contentType)
+	resp.Header.SetContentType(EncryptedStreamContentType)
+	resp.SetBodyStreamWriter(func(w *bufio.Writer) {
+		ew.w = w
+		if _, err := ew.Write(plain); err != nil {
+			return
+		}
+		if err := ew.Close(); err == nil {
+			w.Flush()
+		}
+	})
+	return nil
+}
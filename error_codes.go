@@ -0,0 +1,163 @@
+package mod
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// ErrorCode 错误码定义
+type ErrorCode struct {
+	Name         string            `json:"name"`                   // 错误名称，如 USER_NOT_FOUND
+	Code         int               `json:"code"`                   // 响应中的错误码，不能为0
+	Message      string            `json:"message"`                // 默认消息模板，支持 {name} 占位符或 fmt 格式化参数
+	Translations map[string]string `json:"translations,omitempty"` // 语言（如 en、zh-CN） -> 消息模板
+	Status       int               `json:"status,omitempty"`       // HTTP状态码，为0时错误码在100-599之间使用错误码，否则为400
+	Description  string            `json:"description,omitempty"`  // 在文档中展示的说明，如出现场景和处理建议
+}
+
+// HTTPStatus 返回错误码响应的HTTP状态码
+func (e ErrorCode) HTTPStatus() int {
+	if e.Status != 0 {
+		return e.Status
+	}
+	return replyStatus(e.Code)
+}
+
+// ErrorCodeRegistry 错误码注册表，按名称查找错误码定义
+type ErrorCodeRegistry struct {
+	mu     sync.RWMutex
+	byName map[string]ErrorCode
+	byCode map[int]string // 错误码 -> 名称，用于检查重复
+}
+
+// ErrorRegistry 全局错误码注册表，mod.ReplyCode 在其中查找错误码
+var ErrorRegistry = NewErrorCodeRegistry()
+
+// NewErrorCodeRegistry 创建错误码注册表
+func NewErrorCodeRegistry() *ErrorCodeRegistry {
+	return &ErrorCodeRegistry{byName: map[string]ErrorCode{}, byCode: map[int]string{}}
+}
+
+// Register 注册错误码，名称或错误码重复时返回错误，已注册的错误码不受影响
+func (r *ErrorCodeRegistry) Register(codes ...ErrorCode) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, code := range codes {
+		if code.Name == "" {
+			return fmt.Errorf("error code %d: name is required", code.Code)
+		}
+		if code.Code == 0 {
+			return fmt.Errorf("error code %q: code 0 is reserved for success", code.Name)
+		}
+		if _, exists := r.byName[code.Name]; exists {
+			return fmt.Errorf("error code %q: already registered", code.Name)
+		}
+		if name, exists := r.byCode[code.Code]; exists {
+			return fmt.Errorf("error code %q: code %d already registered by %q", code.Name, code.Code, name)
+		}
+		translations := make(map[string]string, len(code.Translations))
+		for locale, message := range code.Translations {
+			translations[normalizeLocale(locale)] = message
+		}
+		code.Translations = translations
+		r.byName[code.Name] = code
+		r.byCode[code.Code] = code.Name
+	}
+	return nil
+}
+
+// MustRegister 注册错误码，失败时 panic，用于包初始化
+func (r *ErrorCodeRegistry) MustRegister(codes ...ErrorCode) {
+	if err := r.Register(codes...); err != nil {
+		panic(err)
+	}
+}
+
+// Lookup 按名称查找错误码
+func (r *ErrorCodeRegistry) Lookup(name string) (ErrorCode, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	code, ok := r.byName[name]
+	return code, ok
+}
+
+// Codes 返回全部错误码，按错误码从小到大排列
+func (r *ErrorCodeRegistry) Codes() []ErrorCode {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	codes := make([]ErrorCode, 0, len(r.byName))
+	for _, code := range r.byName {
+		codes = append(codes, code)
+	}
+	sort.Slice(codes, func(i, j int) bool { return codes[i].Code < codes[j].Code })
+	return codes
+}
+
+// Reply 返回名为 name 的错误码对应的错误，args 用于填充消息模板（单个 map[string]any 替换 {name} 占位符，否则按 fmt 格式化）；
+// 响应时按请求语言选择消息。错误码未注册时返回500错误
+func (r *ErrorCodeRegistry) Reply(name string, args ...any) error {
+	code, ok := r.Lookup(name)
+	if !ok {
+		return ReplyWithDetail(500, "Unknown error code", name)
+	}
+	return &StdReply{
+		code:     code.Code,
+		msg:      formatMessage(code.Message, args),
+		status:   code.HTTPStatus(),
+		name:     name,
+		args:     args,
+		registry: r,
+	}
+}
+
+// ReplyCode 返回全局错误码注册表中名为 name 的错误码对应的错误，如 mod.ReplyCode("USER_NOT_FOUND", map[string]any{"id": id})
+func ReplyCode(name string, args ...any) error {
+	return ErrorRegistry.Reply(name, args...)
+}
+
+// RegisterErrorCodes 在全局错误码注册表中注册错误码
+func RegisterErrorCodes(codes ...ErrorCode) error {
+	return ErrorRegistry.Register(codes...)
+}
+
+// translate 返回错误码在 locale 下的消息模板：依次查找该语言、基础语言和消息目录中的 errors.<名称>，都不存在时返回默认消息
+func (e ErrorCode) translate(app *App, locale string) string {
+	if locale != "" {
+		for _, l := range []string{normalizeLocale(locale), baseLanguage(locale)} {
+			if message, ok := e.Translations[l]; ok {
+				return message
+			}
+		}
+		if app != nil {
+			if message, ok := app.lookupMessage(locale, "errors."+e.Name); ok {
+				return message
+			}
+		}
+	}
+	return e.Message
+}
+
+// replyMessage 返回错误响应的消息，注册的错误码按当前请求语言选择消息模板
+func (c *Context) replyMessage(reply *StdReply) string {
+	if reply.name == "" || reply.registry == nil {
+		return reply.msg
+	}
+	code, ok := reply.registry.Lookup(reply.name)
+	if !ok {
+		return reply.msg
+	}
+	return formatMessage(code.translate(c.app, c.Locale()), reply.args)
+}
+
+// serviceErrorCodes 返回服务声明的错误码，未注册的名称被忽略
+func serviceErrorCodes(svc *Service) []ErrorCode {
+	codes := make([]ErrorCode, 0, len(svc.Errors))
+	for _, name := range svc.Errors {
+		if code, ok := ErrorRegistry.Lookup(name); ok {
+			codes = append(codes, code)
+		}
+	}
+	return codes
+}
@@ -0,0 +1,101 @@
+package mod
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+)
+
+// requestIDKey 请求ID在 Fiber locals 中的键，同一请求中创建的多个 Context 共用一个请求ID
+type requestIDKey struct{}
+
+// PanicError 处理请求时发生的 panic
+type PanicError struct {
+	Value any    // recover() 的返回值
+	Stack []byte // panic 时的调用栈
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// ErrorEvent 框架错误处理器处理的一次错误，钩子可以修改状态码和响应内容
+type ErrorEvent struct {
+	Err      error        // 原始错误，panic 时为 *PanicError
+	Status   int          // HTTP状态码
+	Response *ApiResponse // 将要返回的标准响应
+}
+
+// OnError 注册错误处理钩子，在框架错误处理器写入响应前调用，用于上报错误或调整响应（如隐藏内部错误信息）。
+// 处理非服务路由、中间件返回的错误以及 panic；服务处理函数返回的错误仍按服务的响应格式处理。
+// 挂载的子应用中的错误同样会调用父应用的钩子
+func (app *App) OnError(hook func(ctx *Context, event *ErrorEvent)) {
+	app.errorHooks = append(app.errorHooks, hook)
+}
+
+// recoverMiddleware 将 panic 转换为 *PanicError 交由错误处理器处理
+func recoverMiddleware(c *fiber.Ctx) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &PanicError{Value: r, Stack: debug.Stack()}
+		}
+	}()
+	return c.Next()
+}
+
+// handleError 框架的 Fiber ErrorHandler：将 fiber.Error、StdReply、panic 及其他错误统一为标准响应格式
+func (app *App) handleError(c *fiber.Ctx, err error) error {
+	ctx := &Context{Ctx: c, logger: app.logger, app: app}
+	event := &ErrorEvent{Err: err, Status: fiber.StatusInternalServerError}
+
+	var fiberErr *fiber.Error
+	var reply *StdReply
+	var panicErr *PanicError
+	switch {
+	case errors.As(err, &reply):
+		event.Status = reply.httpStatus()
+		event.Response = NewErrorResponse(ctx, reply.code, ctx.replyMessage(reply), reply.detail)
+	case errors.As(err, &fiberErr):
+		event.Status = fiberErr.Code
+		event.Response = NewErrorResponse(ctx, fiberErr.Code, fiberErr.Message)
+	default:
+		// 未知错误和 panic 不向客户端暴露内部信息
+		event.Response = NewErrorResponse(ctx, fiber.StatusInternalServerError, http.StatusText(fiber.StatusInternalServerError))
+	}
+
+	fields := logrus.Fields{
+		"method": c.Method(),
+		"path":   c.Path(),
+		"status": event.Status,
+		"error":  err.Error(),
+		"rid":    ctx.GetRequestID(),
+	}
+	switch {
+	case errors.As(err, &panicErr):
+		fields["stack"] = string(panicErr.Stack)
+		app.logger.WithFields(fields).Error("Panic recovered")
+	case event.Status >= fiber.StatusInternalServerError:
+		app.logger.WithFields(fields).Error("Request failed")
+	default:
+		app.logger.WithFields(fields).Debug("Request failed")
+	}
+
+	// 子应用的钩子先于父应用的钩子执行
+	for owner := app; owner != nil; owner = owner.parent {
+		for _, hook := range owner.errorHooks {
+			hook(ctx, event)
+		}
+	}
+
+	// 中间件可能已写入部分响应，统一替换为标准响应；浏览器的页面请求按配置渲染HTML错误页
+	c.Response().ResetBody()
+	if page, ok := app.renderErrorPage(c, event); ok {
+		c.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+		return c.Status(event.Status).Send(page)
+	}
+	return c.Status(event.Status).JSON(event.Response)
+}
@@ -0,0 +1,221 @@
+package mod
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrorPageBrand 错误页展示的品牌信息
+type ErrorPageBrand struct {
+	Name     string `yaml:"name"`     // 名称，默认使用 app.display_name
+	Logo     string `yaml:"logo"`     // Logo 地址
+	Color    string `yaml:"color"`    // 主题色，默认 #1677ff
+	HomeURL  string `yaml:"home_url"` // 返回首页的链接，默认 /
+	Support  string `yaml:"support"`  // 联系方式等补充说明
+	Footer   string `yaml:"footer"`   // 页脚文字
+	Language string `yaml:"language"` // 页面语言，默认 zh-CN
+}
+
+// ErrorPageGroup 按URL前缀划分的错误页分组，未设置的字段使用全局配置
+type ErrorPageGroup struct {
+	Prefix      string         `yaml:"prefix"`       // URL前缀，如 /admin，匹配最长的前缀
+	TemplateDir string         `yaml:"template_dir"` // 模板目录
+	Brand       ErrorPageBrand `yaml:"brand"`        // 品牌信息
+}
+
+// ErrorPageData 错误页模板的数据
+type ErrorPageData struct {
+	Status    int            // HTTP状态码
+	Title     string         // 状态码对应的标题，如 Not Found
+	Message   string         // 面向用户的提示，业务错误（mod.Reply）使用其消息，其余使用按状态码的默认提示
+	RequestID string         // 请求ID，便于用户反馈问题时定位日志
+	Path      string         // 请求路径
+	Brand     ErrorPageBrand // 品牌信息
+}
+
+// errorPageSet 一个分组的模板和品牌信息
+type errorPageSet struct {
+	prefix    string
+	brand     ErrorPageBrand
+	templates map[string]string // 文件名（404.html、5xx.html、error.html）-> 模板注册表中的名称
+}
+
+// errorPages 解析后的错误页配置，首次渲染时加载
+type errorPages struct {
+	once   sync.Once
+	global *errorPageSet
+	groups []*errorPageSet
+}
+
+// errorPageMessage 按状态码返回默认的用户提示，不暴露框架的错误信息
+func errorPageMessage(status int) string {
+	switch {
+	case status == fiber.StatusNotFound:
+		return "您访问的页面不存在或已被移除"
+	case status == fiber.StatusUnauthorized || status == fiber.StatusForbidden:
+		return "您没有访问该页面的权限"
+	case status == fiber.StatusMethodNotAllowed:
+		return "不支持该请求方式"
+	case status == fiber.StatusTooManyRequests:
+		return "访问过于频繁，请稍后再试"
+	case status >= 500:
+		return "服务暂时不可用，请稍后再试"
+	}
+	return "请求无法完成"
+}
+
+// wantsHTML 判断是否为浏览器访问的页面请求：GET/HEAD 且 Accept 包含 text/html，服务调用（POST）始终返回JSON
+func wantsHTML(c *fiber.Ctx) bool {
+	if c.Method() != fiber.MethodGet && c.Method() != fiber.MethodHead {
+		return false
+	}
+	return strings.Contains(c.Get(fiber.HeaderAccept), fiber.MIMETextHTML)
+}
+
+// loadErrorPages 加载全局和各分组的模板
+func (app *App) loadErrorPages() {
+	config := app.cfg.ModConfig.ErrorPages
+	brand := config.Brand
+	if brand.Name == "" {
+		brand.Name = app.cfg.ModConfig.App.DisplayName
+	}
+	if brand.Color == "" {
+		brand.Color = "#1677ff"
+	}
+	if brand.HomeURL == "" {
+		brand.HomeURL = "/"
+	}
+	if brand.Language == "" {
+		brand.Language = "zh-CN"
+	}
+
+	app.errorPages.global = &errorPageSet{brand: brand, templates: app.parseErrorTemplates(config.TemplateDir)}
+	for _, group := range config.Groups {
+		if group.Prefix == "" {
+			app.logger.Warn("Error page group without prefix, ignored")
+			continue
+		}
+		set := &errorPageSet{
+			prefix:    "/" + strings.Trim(group.Prefix, "/"),
+			brand:     mergeErrorPageBrand(brand, group.Brand),
+			templates: app.parseErrorTemplates(group.TemplateDir),
+		}
+		app.errorPages.groups = append(app.errorPages.groups, set)
+	}
+}
+
+// mergeErrorPageBrand 分组未设置的品牌字段使用全局配置
+func mergeErrorPageBrand(base, override ErrorPageBrand) ErrorPageBrand {
+	if override.Name != "" {
+		base.Name = override.Name
+	}
+	if override.Logo != "" {
+		base.Logo = override.Logo
+	}
+	if override.Color != "" {
+		base.Color = override.Color
+	}
+	if override.HomeURL != "" {
+		base.HomeURL = override.HomeURL
+	}
+	if override.Support != "" {
+		base.Support = override.Support
+	}
+	if override.Footer != "" {
+		base.Footer = override.Footer
+	}
+	if override.Language != "" {
+		base.Language = override.Language
+	}
+	return base
+}
+
+// parseErrorTemplates 将模板目录中的 *.html 注册到模板注册表（名称为 error_pages/<文件路径>），模板有误时记录日志并跳过，使用内置模板
+func (app *App) parseErrorTemplates(dir string) map[string]string {
+	templates := map[string]string{}
+	if dir == "" {
+		return templates
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*.html"))
+	if err != nil || len(files) == 0 {
+		app.logger.WithField("template_dir", dir).Warn("No error page templates found, using built-in page")
+		return templates
+	}
+	for _, file := range files {
+		name := "error_pages/" + filepath.ToSlash(filepath.Clean(file))
+		err := app.templates.RegisterFile(name, file)
+		if err == nil {
+			templates[filepath.Base(file)] = name
+			continue
+		}
+		app.logger.WithFields(logrus.Fields{"file": file, "error": err.Error()}).Error("Failed to load error page template")
+	}
+	return templates
+}
+
+// errorPageSet 返回请求路径匹配的分组，未匹配时返回全局配置
+func (app *App) errorPageSet(path string) *errorPageSet {
+	matched := app.errorPages.global
+	length := -1
+	for _, group := range app.errorPages.groups {
+		if (path == group.prefix || strings.HasPrefix(path, group.prefix+"/") || group.prefix == "/") && len(group.prefix) > length {
+			matched, length = group, len(group.prefix)
+		}
+	}
+	return matched
+}
+
+// lookup 按 <状态码>.html、<状态码类别>xx.html、error.html 的顺序查找模板，分组未提供时依次使用全局模板和注册表中的 error.html
+func (set *errorPageSet) lookup(status int, fallback *errorPageSet) string {
+	names := []string{strconv.Itoa(status) + ".html", strconv.Itoa(status/100) + "xx.html", "error.html"}
+	for _, candidate := range []*errorPageSet{set, fallback} {
+		for _, name := range names {
+			if tmpl, ok := candidate.templates[name]; ok {
+				return tmpl
+			}
+		}
+	}
+	return "error.html"
+}
+
+// renderErrorPage 启用错误页时为浏览器的页面请求渲染HTML错误页，返回 false 时按标准JSON响应
+func (app *App) renderErrorPage(c *fiber.Ctx, event *ErrorEvent) ([]byte, bool) {
+	if !app.cfg.ModConfig.ErrorPages.Enabled || !wantsHTML(c) {
+		return nil, false
+	}
+	app.errorPages.once.Do(app.loadErrorPages)
+
+	set := app.errorPageSet(c.Path())
+	data := ErrorPageData{
+		Status:  event.Status,
+		Title:   http.StatusText(event.Status),
+		Message: errorPageMessage(event.Status),
+		Path:    c.Path(),
+		Brand:   set.brand,
+	}
+	if event.Response != nil {
+		data.RequestID = event.Response.Rid
+	}
+	// 业务错误的消息面向用户，可以直接展示
+	var reply *StdReply
+	if errors.As(event.Err, &reply) && reply.msg != "" {
+		data.Message = reply.msg
+	}
+
+	var buf bytes.Buffer
+	if err := app.templates.Render(&buf, set.lookup(event.Status, app.errorPages.global), data); err != nil {
+		app.logger.WithFields(logrus.Fields{"path": c.Path(), "error": err.Error()}).Error("Failed to render error page")
+		if err := app.templates.Render(&buf, "error.html", data); err != nil {
+			return nil, false
+		}
+	}
+	return buf.Bytes(), true
+}
@@ -146,7 +146,7 @@ func main() {
 		Name:        "logout",
 		DisplayName: "用户登出",
 		Description: "注销JWT令牌",
-		SkipAuth:    true,
+		Auth:        mod.AuthJWT,
 		Handler: mod.MakeHandler(func(ctx *mod.Context, req *LogoutRequest, resp *LogoutResponse) error {
 			// Get JWT token from context
 			token := ctx.GetJWTToken()
@@ -221,29 +221,24 @@ func main() {
 		Name:        "user_info",
 		DisplayName: "获取用户信息",
 		Description: "获取当前登录用户的信息",
-		SkipAuth:    true,
+		Auth:        mod.AuthJWT, // 框架校验JWT，处理函数中无需再检查
 		Handler: mod.MakeHandler(func(ctx *mod.Context, req *UserInfoRequest, resp *UserInfoResponse) error {
-			// Check if user is authenticated
-			if !ctx.IsAuthenticated() {
-				return mod.Reply(401, "需要身份认证")
-			}
-
 			// Get user information from JWT claims
-			userID := ctx.GetUserID()
-			username := ctx.GetUsername()
-			email := ctx.GetUserEmail()
-			role := ctx.GetUserRole()
+			user, err := ctx.RequireUser()
+			if err != nil {
+				return err
+			}
 
 			resp.User = User{
-				ID:       userID,
-				Username: username,
-				Email:    email,
-				Role:     role,
+				ID:       user.ID,
+				Username: user.Username,
+				Email:    user.Email,
+				Role:     user.Role,
 			}
 			resp.Message = "用户信息获取成功"
 
 			ctx.WithFields(map[string]any{
-				"user_id": userID,
+				"user_id": user.ID,
 			}).Info("获取用户信息")
 
 			return nil
@@ -11,9 +11,10 @@ type GetUserRequest struct {
 
 // 用户信息响应
 type GetUserResponse struct {
-	ID    string `json:"id" desc:"用户ID"`
-	Name  string `json:"name" desc:"用户名"`
-	Email string `json:"email" desc:"邮箱"`
+	ID    string `json:"id" desc:"用户ID" mock:"uuid"`
+	Name  string `json:"name" desc:"用户名" mock:"name"`
+	Email string `json:"email" desc:"邮箱" mock:"email"`
+	Age   int    `json:"age" desc:"年龄" mock:"range:18-60"`
 }
 
 // 用户列表请求
@@ -24,8 +25,8 @@ type ListUsersRequest struct {
 
 // 用户列表响应
 type ListUsersResponse struct {
-	Users []GetUserResponse `json:"users" desc:"用户列表"`
-	Total int               `json:"total" desc:"总数"`
+	Users []GetUserResponse `json:"users" desc:"用户列表" mock:"range:3-5"`
+	Total int               `json:"total" desc:"总数" mock:"range:3-100"`
 }
 
 // 订单信息请求
@@ -37,7 +38,7 @@ type GetOrderRequest struct {
 type GetOrderResponse struct {
 	ID     string  `json:"id" desc:"订单ID"`
 	Amount float64 `json:"amount" desc:"金额"`
-	Status string  `json:"status" desc:"状态"`
+	Status string  `json:"status" desc:"状态" mock:"enum:paid,pending,refunded"`
 }
 
 func main() {
@@ -0,0 +1,461 @@
+package mod
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// 导出文件的 Content-Type
+const (
+	MIMETextCSV = "text/csv; charset=utf-8"
+	MIMEExcel   = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	MIMEPDF     = "application/pdf"
+)
+
+// RowIterator 逐行产生导出数据，用于不便一次加载到内存的大量数据（如分页查询数据库），
+// yield 返回错误时应停止迭代并返回该错误
+type RowIterator func(yield func(row []any) error) error
+
+// Sheet 导出的表格，SendExcel 中的一个工作表
+type Sheet struct {
+	Name   string   // 工作表名称，默认 Sheet1、Sheet2……
+	Header []string // 表头；为空且 Rows 为结构体切片时使用字段的 desc 标签，其次为 json 名称
+	Rows   any      // [][]string、[][]any、结构体（或结构体指针）切片，或 RowIterator
+}
+
+// exportSentKey 处理函数已自行发送响应体的标记，服务不再输出 JSON 响应
+type exportSentKey struct{}
+
+// responseSent 处理函数是否已通过 SendCSV 等方法发送响应体
+func responseSent(c *fiber.Ctx) bool {
+	sent, _ := c.Locals(exportSentKey{}).(bool)
+	return sent
+}
+
+// markResponseSent 标记响应体已发送
+func markResponseSent(c *fiber.Ctx) {
+	c.Locals(exportSentKey{}, true)
+}
+
+// SendCSV 以附件形式流式发送CSV文件，rows 可以是 [][]string、[][]any、结构体切片、RowIterator 或 Sheet（使用其表头）。
+// 文件以 UTF-8 BOM 开头以便 Excel 正确识别中文，以 = + - @ 开头的文本单元格前添加单引号，防止公式注入。
+// 数据在处理函数返回后写出，RowIterator 不应依赖在处理函数返回时取消的 ctx.UserContext()
+func (c *Context) SendCSV(filename string, rows any) error {
+	sheet, ok := rows.(Sheet)
+	if !ok {
+		sheet = Sheet{Rows: rows}
+	}
+	header, iterate, err := sheetRows(sheet)
+	if err != nil {
+		return err
+	}
+	loc := c.Location()
+	c.sendExport(filename, MIMETextCSV, func(w io.Writer) error {
+		if _, err := w.Write([]byte("\xEF\xBB\xBF")); err != nil {
+			return err
+		}
+		return writeCSV(w, header, iterate, loc)
+	})
+	return nil
+}
+
+// SendExcel 以附件形式流式发送 Excel（xlsx）文件，每个 Sheet 为一个工作表，表头加粗；
+// 数字和布尔值写为对应类型的单元格，时间按调用方时区格式化为 2006-01-02 15:04:05
+func (c *Context) SendExcel(filename string, sheets ...Sheet) error {
+	if len(sheets) == 0 {
+		sheets = []Sheet{{}}
+	}
+	for _, sheet := range sheets {
+		if _, _, err := sheetRows(sheet); err != nil {
+			return err
+		}
+	}
+	loc := c.Location()
+	c.sendExport(filename, MIMEExcel, func(w io.Writer) error {
+		return writeExcel(w, sheets, loc)
+	})
+	return nil
+}
+
+// SendPDF 发送由 reader 产生的PDF文件（如PDF生成库的输出），reader 实现 io.Closer 时在发送完成后关闭
+func (c *Context) SendPDF(filename string, reader io.Reader) error {
+	c.sendExport(filename, MIMEPDF, func(w io.Writer) error {
+		if closer, ok := reader.(io.Closer); ok {
+			defer closer.Close()
+		}
+		_, err := io.Copy(w, reader)
+		return err
+	})
+	return nil
+}
+
+// sendExport 设置下载响应头，并在响应写出时调用 write 流式生成文件内容
+func (c *Context) sendExport(filename, contentType string, write func(w io.Writer) error) {
+	c.Ctx.Set(fiber.HeaderContentType, contentType)
+	c.Ctx.Set(fiber.HeaderContentDisposition, contentDisposition(filename, false))
+	c.Ctx.Set(fiber.HeaderCacheControl, "no-store")
+	markResponseSent(c.Ctx)
+
+	logger, rid := c.logger, c.GetRequestID()
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		// 响应头已发送，失败时只能中断响应并记录日志
+		if err := write(w); err != nil && logger != nil {
+			logger.WithError(err).WithFields(map[string]any{"file": filename, "rid": rid}).Error("Export failed")
+		}
+		w.Flush()
+	})
+}
+
+// WriteCSV 将 rows 写为CSV（不含BOM），用于在后台任务中生成导出文件，rows 的类型同 SendCSV
+func WriteCSV(w io.Writer, rows any) error {
+	sheet, ok := rows.(Sheet)
+	if !ok {
+		sheet = Sheet{Rows: rows}
+	}
+	header, iterate, err := sheetRows(sheet)
+	if err != nil {
+		return err
+	}
+	return writeCSV(w, header, iterate, time.Local)
+}
+
+// WriteExcel 将工作表写为 xlsx 文件，用于在后台任务中生成导出文件
+func WriteExcel(w io.Writer, sheets ...Sheet) error {
+	if len(sheets) == 0 {
+		sheets = []Sheet{{}}
+	}
+	for _, sheet := range sheets {
+		if _, _, err := sheetRows(sheet); err != nil {
+			return err
+		}
+	}
+	return writeExcel(w, sheets, time.Local)
+}
+
+func writeCSV(w io.Writer, header []string, iterate RowIterator, loc *time.Location) error {
+	cw := csv.NewWriter(w)
+	if len(header) > 0 {
+		if err := cw.Write(header); err != nil {
+			return err
+		}
+	}
+	record := []string{}
+	err := iterate(func(row []any) error {
+		record = record[:0]
+		for _, value := range row {
+			text := exportText(value, loc)
+			if _, isString := indirectValue(value).(string); isString && text != "" && strings.ContainsRune("=+-@\t\r", rune(text[0])) {
+				text = "'" + text
+			}
+			record = append(record, text)
+		}
+		return cw.Write(record)
+	})
+	if err != nil {
+		return err
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// sheetRows 返回表头和逐行读取数据的函数
+func sheetRows(sheet Sheet) ([]string, RowIterator, error) {
+	switch rows := sheet.Rows.(type) {
+	case nil:
+		return sheet.Header, func(func([]any) error) error { return nil }, nil
+	case RowIterator:
+		return sheet.Header, rows, nil
+	case func(yield func(row []any) error) error:
+		return sheet.Header, rows, nil
+	case [][]string:
+		return sheet.Header, func(yield func([]any) error) error {
+			row := []any{}
+			for _, cells := range rows {
+				row = row[:0]
+				for _, cell := range cells {
+					row = append(row, cell)
+				}
+				if err := yield(row); err != nil {
+					return err
+				}
+			}
+			return nil
+		}, nil
+	case [][]any:
+		return sheet.Header, func(yield func([]any) error) error {
+			for _, row := range rows {
+				if err := yield(row); err != nil {
+					return err
+				}
+			}
+			return nil
+		}, nil
+	}
+
+	rv := reflect.ValueOf(sheet.Rows)
+	if rv.Kind() != reflect.Slice {
+		return nil, nil, fmt.Errorf("unsupported export rows type %T", sheet.Rows)
+	}
+	elem := rv.Type().Elem()
+	if elem.Kind() == reflect.Pointer {
+		elem = elem.Elem()
+	}
+	if elem.Kind() != reflect.Struct {
+		return nil, nil, fmt.Errorf("unsupported export rows type %T", sheet.Rows)
+	}
+	fields, names := exportFields(elem)
+	header := sheet.Header
+	if len(header) == 0 {
+		header = names
+	}
+	return header, func(yield func([]any) error) error {
+		row := make([]any, len(fields))
+		for i := 0; i < rv.Len(); i++ {
+			item := rv.Index(i)
+			if item.Kind() == reflect.Pointer {
+				if item.IsNil() {
+					continue
+				}
+				item = item.Elem()
+			}
+			for j, index := range fields {
+				row[j] = item.FieldByIndex(index).Interface()
+			}
+			if err := yield(row); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, nil
+}
+
+// exportFields 返回结构体中导出的字段（跳过 json:"-"）及其列名：desc 标签，其次为 json 名称和字段名
+func exportFields(t reflect.Type) ([][]int, []string) {
+	var fields [][]int
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		jsonName, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if jsonName == "-" {
+			continue
+		}
+		name := field.Tag.Get("desc")
+		if name == "" {
+			name = jsonName
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields = append(fields, field.Index)
+		names = append(names, name)
+	}
+	return fields, names
+}
+
+// indirectValue 解引用指针，nil 指针返回 nil
+func indirectValue(value any) any {
+	rv := reflect.ValueOf(value)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	if !rv.IsValid() {
+		return nil
+	}
+	return rv.Interface()
+}
+
+// exportText 将单元格的值格式化为文本：时间按 loc 格式化，切片、映射和结构体输出为JSON
+func exportText(value any, loc *time.Location) string {
+	switch v := indirectValue(value).(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case time.Time:
+		if v.IsZero() {
+			return ""
+		}
+		return v.In(loc).Format("2006-01-02 15:04:05")
+	case fmt.Stringer:
+		return v.String()
+	case bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64, json.Number:
+		return fmt.Sprint(v)
+	default:
+		switch reflect.ValueOf(v).Kind() {
+		case reflect.Slice, reflect.Array, reflect.Map, reflect.Struct:
+			if data, err := json.Marshal(v); err == nil {
+				return string(data)
+			}
+		}
+		return fmt.Sprint(v)
+	}
+}
+
+// xlsx 文件的固定部分
+const (
+	xlsxContentTypesHead = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>`
+	xlsxRootRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`
+	xlsxStyles = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts><fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills><borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders><cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs><cellXfs count="2"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/><xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/></cellXfs></styleSheet>`
+	xlsxSheetHead = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`
+	xlsxSheetTail = `</sheetData></worksheet>`
+)
+
+// writeExcel 生成 xlsx 文件：单元格使用内联字符串，不依赖共享字符串表，可以逐行写出
+func writeExcel(w io.Writer, sheets []Sheet, loc *time.Location) error {
+	zw := zip.NewWriter(w)
+	names := make([]string, len(sheets))
+	used := map[string]bool{}
+	for i, sheet := range sheets {
+		names[i] = excelSheetName(sheet.Name, i, used)
+	}
+
+	var types, workbook, rels strings.Builder
+	types.WriteString(xlsxContentTypesHead)
+	workbook.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>`)
+	rels.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`)
+	for i, name := range names {
+		n := strconv.Itoa(i + 1)
+		types.WriteString(`<Override PartName="/xl/worksheets/sheet` + n + `.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`)
+		workbook.WriteString(`<sheet name="` + xmlEscape(name) + `" sheetId="` + n + `" r:id="rId` + n + `"/>`)
+		rels.WriteString(`<Relationship Id="rId` + n + `" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet` + n + `.xml"/>`)
+	}
+	types.WriteString(`</Types>`)
+	workbook.WriteString(`</sheets></workbook>`)
+	styles := strconv.Itoa(len(names) + 1)
+	rels.WriteString(`<Relationship Id="rId` + styles + `" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/></Relationships>`)
+
+	for _, part := range []struct{ name, content string }{
+		{"[Content_Types].xml", types.String()},
+		{"_rels/.rels", xlsxRootRels},
+		{"xl/workbook.xml", workbook.String()},
+		{"xl/_rels/workbook.xml.rels", rels.String()},
+		{"xl/styles.xml", xlsxStyles},
+	} {
+		fw, err := zw.Create(part.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(fw, part.content); err != nil {
+			return err
+		}
+	}
+
+	for i, sheet := range sheets {
+		fw, err := zw.Create("xl/worksheets/sheet" + strconv.Itoa(i+1) + ".xml")
+		if err != nil {
+			return err
+		}
+		if err := writeExcelSheet(fw, sheet, loc); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// writeExcelSheet 写出一个工作表，表头使用加粗样式
+func writeExcelSheet(w io.Writer, sheet Sheet, loc *time.Location) error {
+	header, iterate, err := sheetRows(sheet)
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(w)
+	bw.WriteString(xlsxSheetHead)
+	rowNum := 0
+	writeRow := func(row []any, style string) error {
+		rowNum++
+		r := strconv.Itoa(rowNum)
+		bw.WriteString(`<row r="` + r + `">`)
+		for col, value := range row {
+			ref := excelColumn(col) + r
+			switch v := indirectValue(value).(type) {
+			case nil:
+				continue
+			case bool:
+				b := "0"
+				if v {
+					b = "1"
+				}
+				bw.WriteString(`<c r="` + ref + `"` + style + ` t="b"><v>` + b + `</v></c>`)
+			case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64, json.Number:
+				bw.WriteString(`<c r="` + ref + `"` + style + `><v>` + fmt.Sprint(v) + `</v></c>`)
+			default:
+				bw.WriteString(`<c r="` + ref + `"` + style + ` t="inlineStr"><is><t xml:space="preserve">` + xmlEscape(exportText(v, loc)) + `</t></is></c>`)
+			}
+		}
+		_, err := bw.WriteString(`</row>`)
+		return err
+	}
+	if len(header) > 0 {
+		cells := make([]any, len(header))
+		for i, name := range header {
+			cells[i] = name
+		}
+		if err := writeRow(cells, ` s="1"`); err != nil {
+			return err
+		}
+	}
+	if err := iterate(func(row []any) error { return writeRow(row, "") }); err != nil {
+		return err
+	}
+	bw.WriteString(xlsxSheetTail)
+	return bw.Flush()
+}
+
+// excelColumn 返回从0开始的列号对应的列名：A、B……Z、AA……
+func excelColumn(col int) string {
+	name := ""
+	for col++; col > 0; col = (col - 1) / 26 {
+		name = string(rune('A'+(col-1)%26)) + name
+	}
+	return name
+}
+
+// excelSheetName 返回合法且不重复的工作表名称：去除 []:*?/\，最长31个字符
+func excelSheetName(name string, index int, used map[string]bool) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return '_'
+		}
+		return r
+	}, strings.TrimSpace(name))
+	if runes := []rune(name); len(runes) > 31 {
+		name = string(runes[:31])
+	}
+	if name == "" || used[strings.ToLower(name)] {
+		name = "Sheet" + strconv.Itoa(index+1)
+	}
+	used[strings.ToLower(name)] = true
+	return name
+}
+
+// xmlEscape 转义XML文本，无效字符替换为 U+FFFD
+func xmlEscape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
@@ -0,0 +1,320 @@
+package mod
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// 本地上传文件的加密格式：
+//
+//	头部   "MODFILE" | 版本(1) | 密钥版本长度(uint16) | 密钥版本 | 数据密钥长度(uint16) | nonce | 数据密钥密文
+//	数据帧 长度(uint32) | nonce | 密文，附加数据为 帧序号(uint64) | 是否最后一帧(1)
+//
+// 每个文件生成随机的 AES256-GCM 数据密钥，使用密钥提供者的 file_upload.local 密钥加密后写入头部。
+// 除最后一帧外每帧的明文固定为 64KB，读取时可以按偏移定位到帧，支持下载路由的 Range 请求；
+// 最后一帧单独标记，文件被截断时解密失败。
+const (
+	fileEncMagic   = "MODFILE"
+	fileEncVersion = 1
+
+	fileEncOverhead  = 4 + 12 + 16 // 帧长度、nonce 和认证标签
+	fileEncFrameSize = streamChunkSize + fileEncOverhead
+)
+
+// ErrFileKeyUnavailable 加密文件使用的密钥版本已不在保留的旧版本中
+var ErrFileKeyUnavailable = errors.New("file encryption key version not available")
+
+// saveEncryptedFile 加密写入上传的文件
+func (app *App) saveEncryptedFile(dst io.Writer, src io.Reader) error {
+	w, err := app.newFileEncryptWriter(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(w, src); err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+// newFileEncryptWriter 使用当前的 file_upload.local 密钥创建加密写入器并写入头部，Close 时写入最后一帧
+func (app *App) newFileEncryptWriter(w io.Writer) (io.WriteCloser, error) {
+	key, err := app.keys.get(KeyFileEncryption)
+	if err != nil {
+		return nil, err
+	}
+	kek, err := newStreamAEAD("AES256-GCM", key.Material)
+	if err != nil {
+		return nil, fmt.Errorf("key %s: %w", KeyFileEncryption, err)
+	}
+	if len(key.Version) > 0xffff {
+		return nil, fmt.Errorf("key %s: version too long", KeyFileEncryption)
+	}
+
+	dataKey := make([]byte, 32)
+	nonce := make([]byte, kek.NonceSize())
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, err
+	}
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	// 密钥版本作为附加数据，防止替换头部中的版本
+	wrapped := kek.Seal(nonce, nonce, dataKey, []byte(key.Version))
+	aead, err := newStreamAEAD("AES256-GCM", dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	header := append([]byte(fileEncMagic), fileEncVersion)
+	header = binary.BigEndian.AppendUint16(header, uint16(len(key.Version)))
+	header = append(header, key.Version...)
+	header = binary.BigEndian.AppendUint16(header, uint16(len(wrapped)))
+	header = append(header, wrapped...)
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+	return &fileEncryptWriter{w: w, aead: aead}, nil
+}
+
+// fileEncryptWriter 按帧加密写入的数据
+type fileEncryptWriter struct {
+	w    io.Writer
+	aead cipher.AEAD
+	buf  []byte
+	seq  uint64
+}
+
+func (fw *fileEncryptWriter) Write(p []byte) (int, error) {
+	fw.buf = append(fw.buf, p...)
+	// 缓冲超过一帧时才写出，剩余的数据留给 Close 写入最后一帧
+	for len(fw.buf) > streamChunkSize {
+		if err := fw.writeFrame(fw.buf[:streamChunkSize], false); err != nil {
+			return 0, err
+		}
+		fw.buf = fw.buf[streamChunkSize:]
+	}
+	return len(p), nil
+}
+
+// Close 写入最后一帧，不关闭底层的 Writer
+func (fw *fileEncryptWriter) Close() error {
+	err := fw.writeFrame(fw.buf, true)
+	fw.buf = nil
+	return err
+}
+
+func (fw *fileEncryptWriter) writeFrame(plain []byte, final bool) error {
+	nonce := make([]byte, fw.aead.NonceSize(), fw.aead.NonceSize()+len(plain)+fw.aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	frame := fw.aead.Seal(nonce, nonce, plain, streamAAD(fw.seq, final))
+	fw.seq++
+	if _, err := fw.w.Write(binary.BigEndian.AppendUint32(nil, uint32(len(frame)))); err != nil {
+		return err
+	}
+	_, err := fw.w.Write(frame)
+	return err
+}
+
+// OpenLocalFile 打开本地上传的文件，按 file_upload.local.encryption 加密存储的文件透明解密，
+// 未加密的文件（如启用加密前上传的文件）直接读取。返回的 Reader 支持 Seek
+func (app *App) OpenLocalFile(path string) (io.ReadSeekCloser, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	magic := make([]byte, len(fileEncMagic))
+	if _, err := io.ReadFull(f, magic); err != nil || string(magic) != fileEncMagic {
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			f.Close()
+			return nil, err
+		}
+		return f, nil
+	}
+
+	ef, err := app.openEncryptedFile(f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return ef, nil
+}
+
+// isEncryptedLocalFile 文件是否为加密格式
+func isEncryptedLocalFile(path string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	magic := make([]byte, len(fileEncMagic))
+	if _, err := io.ReadFull(f, magic); err != nil {
+		return false, nil
+	}
+	return string(magic) == fileEncMagic, nil
+}
+
+// encryptedFile 按帧解密的本地文件
+type encryptedFile struct {
+	f         *os.File
+	aead      cipher.AEAD
+	dataStart int64 // 第一帧的偏移
+	frames    int64
+	lastLen   int64 // 最后一帧的明文长度
+	size      int64 // 明文长度
+	pos       int64
+	frame     int64 // 已解密的帧序号，-1 表示没有
+	plain     []byte
+}
+
+// openEncryptedFile 解析头部，使用对应版本的密钥解密数据密钥，并校验最后一帧以发现截断的文件
+func (app *App) openEncryptedFile(f *os.File) (*encryptedFile, error) {
+	header := make([]byte, 3)
+	if _, err := io.ReadFull(f, header); err != nil {
+		return nil, err
+	}
+	if header[0] != fileEncVersion {
+		return nil, fmt.Errorf("unsupported file encryption version %d", header[0])
+	}
+	version := make([]byte, binary.BigEndian.Uint16(header[1:]))
+	if _, err := io.ReadFull(f, version); err != nil {
+		return nil, err
+	}
+	length := make([]byte, 2)
+	if _, err := io.ReadFull(f, length); err != nil {
+		return nil, err
+	}
+	wrapped := make([]byte, binary.BigEndian.Uint16(length))
+	if _, err := io.ReadFull(f, wrapped); err != nil {
+		return nil, err
+	}
+
+	keys, err := app.keys.versions(KeyFileEncryption)
+	if err != nil {
+		return nil, err
+	}
+	var kek *Key
+	for _, key := range keys {
+		if key.Version == string(version) {
+			kek = key
+			break
+		}
+	}
+	if kek == nil {
+		return nil, fmt.Errorf("%w: %s", ErrFileKeyUnavailable, version)
+	}
+	wrapAEAD, err := newStreamAEAD("AES256-GCM", kek.Material)
+	if err != nil {
+		return nil, err
+	}
+	if len(wrapped) < wrapAEAD.NonceSize() {
+		return nil, errors.New("invalid encrypted data key")
+	}
+	dataKey, err := wrapAEAD.Open(nil, wrapped[:wrapAEAD.NonceSize()], wrapped[wrapAEAD.NonceSize():], version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt data key: %w", err)
+	}
+	aead, err := newStreamAEAD("AES256-GCM", dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	ef := &encryptedFile{f: f, aead: aead, frame: -1}
+	if ef.dataStart, err = f.Seek(0, io.SeekCurrent); err != nil {
+		return nil, err
+	}
+	stat, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	// 除最后一帧外每帧长度固定，按文件大小计算帧数和明文长度
+	body := stat.Size() - ef.dataStart - fileEncOverhead
+	if body < 0 || body%fileEncFrameSize > streamChunkSize {
+		return nil, errors.New("encrypted file is truncated")
+	}
+	ef.frames = body/fileEncFrameSize + 1
+	ef.lastLen = body % fileEncFrameSize
+	ef.size = (ef.frames-1)*streamChunkSize + ef.lastLen
+	if err := ef.load(ef.frames - 1); err != nil {
+		return nil, err
+	}
+	return ef, nil
+}
+
+// load 读取并解密指定的帧
+func (ef *encryptedFile) load(index int64) error {
+	final := index == ef.frames-1
+	want := int64(streamChunkSize)
+	if final {
+		want = ef.lastLen
+	}
+	frame := make([]byte, fileEncOverhead+want)
+	if _, err := ef.f.ReadAt(frame, ef.dataStart+index*fileEncFrameSize); err != nil {
+		return fmt.Errorf("failed to read frame %d: %w", index, err)
+	}
+	if int64(binary.BigEndian.Uint32(frame)) != int64(len(frame)-4) {
+		return fmt.Errorf("invalid frame %d", index)
+	}
+	nonceSize := ef.aead.NonceSize()
+	plain, err := ef.aead.Open(ef.plain[:0], frame[4:4+nonceSize], frame[4+nonceSize:], streamAAD(uint64(index), final))
+	if err != nil {
+		return fmt.Errorf("failed to decrypt frame %d: %w", index, err)
+	}
+	ef.plain, ef.frame = plain, index
+	return nil
+}
+
+func (ef *encryptedFile) Read(p []byte) (int, error) {
+	if ef.pos >= ef.size {
+		return 0, io.EOF
+	}
+	index := ef.pos / streamChunkSize
+	if index != ef.frame {
+		if err := ef.load(index); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, ef.plain[ef.pos-index*streamChunkSize:])
+	ef.pos += int64(n)
+	return n, nil
+}
+
+// Seek 按明文偏移定位
+func (ef *encryptedFile) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += ef.pos
+	case io.SeekEnd:
+		offset += ef.size
+	default:
+		return 0, errors.New("invalid whence")
+	}
+	if offset < 0 {
+		return 0, errors.New("negative position")
+	}
+	ef.pos = offset
+	return offset, nil
+}
+
+func (ef *encryptedFile) Close() error {
+	return ef.f.Close()
+}
+
+// checkLocalEncryption 启用本地文件加密时校验密钥可用
+func (app *App) checkLocalEncryption() error {
+	key, err := app.keys.get(KeyFileEncryption)
+	if err != nil {
+		return err
+	}
+	if _, err := newStreamAEAD("AES256-GCM", key.Material); err != nil {
+		return fmt.Errorf("key %s: %w", KeyFileEncryption, err)
+	}
+	return nil
+}
@@ -0,0 +1,673 @@
+package mod
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrFileNotFound 文件元数据不存在
+var ErrFileNotFound = errors.New("file not found")
+
+// FileMetadata 上传文件的元数据
+type FileMetadata struct {
+	ID           string    `json:"id" desc:"文件ID"`
+	OriginalName string    `json:"original_name" desc:"原始文件名"`
+	Filename     string    `json:"filename" desc:"存储文件名"`
+	Hash         string    `json:"hash" desc:"文件内容SHA256"`
+	Size         int64     `json:"size" desc:"文件大小（字节）"`
+	MIME         string    `json:"mime" desc:"文件MIME类型"`
+	Owner        string    `json:"owner" desc:"上传者用户ID"`
+	Tenant       string    `json:"tenant,omitempty" desc:"上传者所属租户ID"`
+	Backend      string    `json:"backend" desc:"存储后端"`
+	Bucket       string    `json:"bucket,omitempty" desc:"存储桶"`
+	ObjectKey    string    `json:"object_key" desc:"对象键（本地存储为文件路径）"`
+	URL          string    `json:"url" desc:"访问URL"` // 存储后端生成的原始地址，文件管理服务按 url_rewrite 改写后返回
+	Category     string    `json:"category,omitempty" desc:"上传时指定的分类，如 temp"`
+	CreatedAt    time.Time `json:"created_at" desc:"上传时间"`
+
+	Variants []FileVariant `json:"variants,omitempty" desc:"图片的缩略图、尺寸预设和格式转换版本"` // 删除文件时一并删除
+}
+
+// FileQuery 文件元数据查询条件
+type FileQuery struct {
+	Owner    string    // 上传者，为空时不过滤
+	Tenant   string    // 租户，为空时不过滤
+	Keyword  string    // 按原始文件名模糊匹配
+	MIME     string    // MIME类型前缀，如 image/
+	Backend  string    // 存储后端
+	Category string    // 上传分类
+	Before   time.Time // 仅返回该时间之前上传的文件
+	Offset   int
+	Limit    int
+}
+
+// FileMetadataStore 文件元数据存储接口，默认使用BadgerDB实现，可替换为SQL等实现
+type FileMetadataStore interface {
+	Save(meta *FileMetadata) error
+	Get(id string) (*FileMetadata, error)
+	Delete(id string) error
+	// List 返回满足条件的元数据（按上传时间倒序）以及总数
+	List(query FileQuery) ([]*FileMetadata, int, error)
+	Close() error
+}
+
+// badgerFileStore 基于BadgerDB的文件元数据存储
+type badgerFileStore struct {
+	db     *badger.DB
+	prefix string
+}
+
+// NewBadgerFileStore 创建基于BadgerDB的文件元数据存储
+func NewBadgerFileStore(db *badger.DB, prefix string) FileMetadataStore {
+	if prefix == "" {
+		prefix = "file:"
+	}
+	return &badgerFileStore{db: db, prefix: prefix}
+}
+
+func (s *badgerFileStore) Save(meta *FileMetadata) error {
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return fmt.Errorf("failed to marshal file metadata: %w", err)
+	}
+	return s.db.Update(func(txn *badger.Txn) error {
+		return txn.Set([]byte(s.prefix+meta.ID), data)
+	})
+}
+
+func (s *badgerFileStore) Get(id string) (*FileMetadata, error) {
+	var meta FileMetadata
+	err := s.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(s.prefix + id))
+		if err != nil {
+			return err
+		}
+		return item.Value(func(val []byte) error {
+			return json.Unmarshal(val, &meta)
+		})
+	})
+	if err != nil {
+		if err == badger.ErrKeyNotFound {
+			return nil, ErrFileNotFound
+		}
+		return nil, err
+	}
+	return &meta, nil
+}
+
+func (s *badgerFileStore) Delete(id string) error {
+	return s.db.Update(func(txn *badger.Txn) error {
+		return txn.Delete([]byte(s.prefix + id))
+	})
+}
+
+func (s *badgerFileStore) List(query FileQuery) ([]*FileMetadata, int, error) {
+	var matched []*FileMetadata
+
+	err := s.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.Prefix = []byte(s.prefix)
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Rewind(); it.Valid(); it.Next() {
+			var meta FileMetadata
+			if err := it.Item().Value(func(val []byte) error {
+				return json.Unmarshal(val, &meta)
+			}); err != nil {
+				return err
+			}
+			if query.matches(&meta) {
+				matched = append(matched, &meta)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	sort.Slice(matched, func(i, j int) bool {
+		return matched[i].CreatedAt.After(matched[j].CreatedAt)
+	})
+
+	total := len(matched)
+	return query.paginate(matched), total, nil
+}
+
+func (s *badgerFileStore) Close() error {
+	return s.db.Close()
+}
+
+// matches 判断元数据是否满足查询条件
+func (q FileQuery) matches(meta *FileMetadata) bool {
+	if q.Owner != "" && meta.Owner != q.Owner {
+		return false
+	}
+	if q.Tenant != "" && meta.Tenant != q.Tenant {
+		return false
+	}
+	if q.Backend != "" && meta.Backend != q.Backend {
+		return false
+	}
+	if q.MIME != "" && !strings.HasPrefix(meta.MIME, q.MIME) {
+		return false
+	}
+	if q.Keyword != "" && !strings.Contains(strings.ToLower(meta.OriginalName), strings.ToLower(q.Keyword)) {
+		return false
+	}
+	if q.Category != "" && meta.Category != q.Category {
+		return false
+	}
+	if !q.Before.IsZero() && !meta.CreatedAt.Before(q.Before) {
+		return false
+	}
+	return true
+}
+
+// paginate 对结果进行分页
+func (q FileQuery) paginate(items []*FileMetadata) []*FileMetadata {
+	if q.Offset >= len(items) {
+		return []*FileMetadata{}
+	}
+	items = items[q.Offset:]
+	if q.Limit > 0 && q.Limit < len(items) {
+		items = items[:q.Limit]
+	}
+	return items
+}
+
+// configureFileMetadata 配置文件元数据存储及文件管理服务
+func (app *App) configureFileMetadata() {
+	config := app.cfg.ModConfig.FileUpload.Metadata
+	if !config.Enabled {
+		return
+	}
+
+	if app.fileStore == nil {
+		path := config.Path
+		if path == "" {
+			path = "./data/files" // 默认路径
+		}
+
+		opts := badger.DefaultOptions(path)
+		opts.Logger = &badgerLogger{logger: app.logger}
+		opts.InMemory = config.InMemory
+		if config.InMemory {
+			opts.Dir = ""
+			opts.ValueDir = ""
+		}
+
+		db, err := badger.Open(opts)
+		if err != nil {
+			app.logger.WithError(err).WithField("path", path).Error("Failed to initialize file metadata store")
+			return
+		}
+		app.fileStore = NewBadgerFileStore(db, config.KeyPrefix)
+		app.logger.WithField("path", path).Info("File metadata store initialized successfully")
+	}
+
+	app.registerFileServices()
+	if app.quotaEnabled() {
+		app.registerQuotaService()
+	}
+	app.configureFileDownload()
+	app.startFileLifecycle()
+}
+
+// SetFileMetadataStore 设置自定义的文件元数据存储（如SQL实现）
+func (app *App) SetFileMetadataStore(store FileMetadataStore) {
+	app.fileStore = store
+}
+
+// GetFileMetadataStore 返回文件元数据存储，未启用时返回nil
+func (app *App) GetFileMetadataStore() FileMetadataStore {
+	return app.fileStore
+}
+
+// GetFile 根据ID获取文件元数据
+func (app *App) GetFile(id string) (*FileMetadata, error) {
+	if app.fileStore == nil {
+		return nil, fmt.Errorf("file metadata store not enabled")
+	}
+	return app.fileStore.Get(id)
+}
+
+// ListFiles 查询文件元数据
+func (app *App) ListFiles(query FileQuery) ([]*FileMetadata, int, error) {
+	if app.fileStore == nil {
+		return nil, 0, fmt.Errorf("file metadata store not enabled")
+	}
+	return app.fileStore.List(query)
+}
+
+// DeleteFile 删除文件：同时删除存储对象和元数据，并记录审计日志
+func (app *App) DeleteFile(id string) error {
+	meta, err := app.GetFile(id)
+	if err != nil {
+		return err
+	}
+	return app.deleteFile(meta, "manual", "")
+}
+
+// deleteFile 删除存储对象和元数据，reason 为删除原因（manual、lifecycle）
+func (app *App) deleteFile(meta *FileMetadata, reason, operator string) error {
+	auditFields := logrus.Fields{
+		"audit":      true,
+		"action":     "file_delete",
+		"reason":     reason,
+		"operator":   operator,
+		"file_id":    meta.ID,
+		"owner":      meta.Owner,
+		"backend":    meta.Backend,
+		"bucket":     meta.Bucket,
+		"object_key": meta.ObjectKey,
+		"size":       meta.Size,
+	}
+
+	// 先删除存储对象，失败时保留元数据以便重试
+	app.reload.uploadMu.RLock()
+	err := app.deleteStoredObject(meta)
+	app.reload.uploadMu.RUnlock()
+	if err != nil {
+		app.logger.WithFields(auditFields).WithError(err).Error("Failed to delete stored file object")
+		return fmt.Errorf("failed to delete stored object: %w", err)
+	}
+
+	if err := app.fileStore.Delete(meta.ID); err != nil {
+		app.logger.WithFields(auditFields).WithError(err).Error("Failed to delete file metadata")
+		return fmt.Errorf("failed to delete file metadata: %w", err)
+	}
+
+	app.logger.WithFields(auditFields).Info("File deleted")
+	return nil
+}
+
+// startFileLifecycle 启动文件生命周期清理任务
+func (app *App) startFileLifecycle() {
+	config := app.cfg.ModConfig.FileUpload.Metadata.Lifecycle
+	if !config.Enabled || len(config.Rules) == 0 || app.fileLifecycleStop != nil {
+		return
+	}
+
+	interval := time.Hour
+	if config.Interval != "" {
+		if d, err := parseDurationWithDays(config.Interval); err == nil && d > 0 {
+			interval = d
+		} else {
+			app.logger.WithField("interval", config.Interval).Warn("Invalid file lifecycle interval, using default 1h")
+		}
+	}
+
+	stop := make(chan struct{})
+	app.fileLifecycleStop = stop
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		app.RunFileLifecycle()
+		for {
+			select {
+			case <-ticker.C:
+				app.RunFileLifecycle()
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	app.logger.WithFields(logrus.Fields{
+		"interval": interval.String(),
+		"rules":    len(config.Rules),
+	}).Info("File lifecycle cleanup started")
+}
+
+// RunFileLifecycle 立即执行一次生命周期规则，返回删除的文件数量
+func (app *App) RunFileLifecycle() int {
+	if app.fileStore == nil {
+		return 0
+	}
+
+	deleted := 0
+	for _, rule := range app.cfg.ModConfig.FileUpload.Metadata.Lifecycle.Rules {
+		maxAge, err := parseDurationWithDays(rule.MaxAge)
+		if err != nil || maxAge <= 0 {
+			app.logger.WithFields(logrus.Fields{
+				"rule":    rule.Name,
+				"max_age": rule.MaxAge,
+			}).Warn("Invalid file lifecycle max_age, skipping rule")
+			continue
+		}
+
+		items, _, err := app.fileStore.List(FileQuery{
+			Category: rule.Category,
+			MIME:     rule.MIME,
+			Backend:  rule.Backend,
+			Before:   time.Now().Add(-maxAge),
+		})
+		if err != nil {
+			app.logger.WithError(err).WithField("rule", rule.Name).Error("Failed to list files for lifecycle rule")
+			continue
+		}
+
+		for _, meta := range items {
+			if err := app.deleteFile(meta, "lifecycle", rule.Name); err == nil {
+				deleted++
+			}
+		}
+	}
+
+	if deleted > 0 {
+		app.logger.WithField("deleted", deleted).Info("File lifecycle cleanup completed")
+	}
+	return deleted
+}
+
+// recordUploadFile 记录上传文件的元数据，并将文件ID写回上传结果
+func (app *App) recordUploadFile(c *fiber.Ctx, file *multipart.FileHeader, backend string, result fiber.Map) {
+	if app.fileStore == nil || result == nil {
+		return
+	}
+
+	hash, mimeType, err := app.inspectUploadFile(file)
+	if err != nil {
+		app.logger.WithError(err).WithField("filename", file.Filename).Warn("Failed to inspect uploaded file for metadata")
+	}
+
+	meta := &FileMetadata{
+		ID:           app.NextID(),
+		OriginalName: file.Filename,
+		Hash:         hash,
+		Size:         file.Size,
+		MIME:         mimeType,
+		Owner:        app.resolveFileOwner(c),
+		Tenant:       app.resolveFileTenant(c),
+		Backend:      backend,
+		Category:     c.FormValue("category"),
+		CreatedAt:    time.Now(),
+	}
+	meta.applyUploadResult(result)
+
+	if err := app.fileStore.Save(meta); err != nil {
+		app.logger.WithError(err).WithField("filename", file.Filename).Error("Failed to save file metadata")
+		return
+	}
+
+	result["id"] = meta.ID
+	result["hash"] = meta.Hash
+}
+
+// applyUploadResult 从上传结果中提取文件名、URL及存储位置
+func (meta *FileMetadata) applyUploadResult(result fiber.Map) {
+	if v, ok := result["filename"].(string); ok {
+		meta.Filename = v
+	}
+	if v, ok := result["url"].(string); ok {
+		meta.URL = v
+	}
+	if v, ok := result["bucket"].(string); ok {
+		meta.Bucket = v
+	}
+	if v, ok := result["object_key"].(string); ok {
+		meta.ObjectKey = v
+	} else if v, ok := result["path"].(string); ok {
+		meta.ObjectKey = v
+	}
+	if v, ok := result["variants"].([]FileVariant); ok {
+		meta.Variants = v
+	}
+}
+
+// inspectUploadFile 计算文件的SHA256并检测MIME类型
+func (app *App) inspectUploadFile(file *multipart.FileHeader) (string, string, error) {
+	mimeType := mime.TypeByExtension(filepath.Ext(file.Filename))
+
+	src, err := file.Open()
+	if err != nil {
+		return "", mimeType, err
+	}
+	defer src.Close()
+
+	head := make([]byte, 512)
+	n, _ := io.ReadFull(src, head)
+	if mimeType == "" {
+		mimeType = http.DetectContentType(head[:n])
+	}
+
+	h := sha256.New()
+	h.Write(head[:n])
+	if _, err := io.Copy(h, src); err != nil {
+		return "", mimeType, err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), mimeType, nil
+}
+
+// resolveFileOwner 解析当前请求的用户ID，依次尝试JWT上下文、JWT令牌和Token缓存数据
+func (app *App) resolveFileOwner(c *fiber.Ctx) string {
+	if userID, ok := c.Locals("user_id").(string); ok && userID != "" {
+		return userID
+	}
+
+	token := parseToken(c, app.tokenKeys)
+	if token == "" {
+		return ""
+	}
+
+	if jwtManager := app.GetJWTManager(); jwtManager.IsEnabled() {
+		if claims, err := jwtManager.ValidateToken(token); err == nil {
+			return claims.UserID
+		}
+	}
+
+	if tokenData, err := app.tokenDataMap(c, token); err == nil {
+		for _, key := range []string{"user_id", "uid", "id"} {
+			if v := getNestedValue(tokenData, key); v != nil {
+				return fmt.Sprintf("%v", v)
+			}
+		}
+	}
+
+	return ""
+}
+
+// ListFilesRequest 文件列表请求
+type ListFilesRequest struct {
+	Keyword  string `json:"keyword" desc:"按原始文件名搜索"`
+	MIME     string `json:"mime" desc:"MIME类型前缀，如 image/"`
+	Backend  string `json:"backend" desc:"存储后端"`
+	Page     int    `json:"page" desc:"页码，从1开始"`
+	PageSize int    `json:"page_size" validate:"omitempty,max=100" desc:"每页数量，默认20"`
+}
+
+// ListFilesResponse 文件列表响应
+type ListFilesResponse struct {
+	Total int             `json:"total" desc:"总数"`
+	Items []*FileMetadata `json:"items" desc:"文件列表"`
+}
+
+// FileIDRequest 按文件ID操作的请求
+type FileIDRequest struct {
+	ID string `json:"id" validate:"required" desc:"文件ID"`
+}
+
+// DownloadURLRequest 生成下载链接请求
+type DownloadURLRequest struct {
+	ID     string `json:"id" validate:"required" desc:"文件ID"`
+	Expire string `json:"expire" desc:"链接有效期，如 30m、24h、7d，默认使用配置值"`
+}
+
+// DownloadURLResponse 生成下载链接响应
+type DownloadURLResponse struct {
+	URL       string    `json:"url" desc:"限时下载链接（相对路径）"`
+	ExpiresAt time.Time `json:"expires_at" desc:"过期时间"`
+}
+
+// DeleteFileResponse 删除文件响应
+type DeleteFileResponse struct {
+	ID      string `json:"id" desc:"文件ID"`
+	Deleted bool   `json:"deleted" desc:"是否已删除"`
+}
+
+// registerFileServices 注册文件管理服务
+func (app *App) registerFileServices() {
+	services := []Service{
+		{
+			Name:        "files_list",
+			DisplayName: "文件列表",
+			Description: "分页查询当前用户上传的文件，支持按文件名、MIME类型和存储后端过滤",
+			Group:       "文件管理",
+			Sort:        1,
+			Handler: MakeHandler(func(ctx *Context, req *ListFilesRequest, resp *ListFilesResponse) error {
+				owner := app.resolveFileOwner(ctx.Ctx)
+				if owner == "" {
+					return Reply(401, "无法识别当前用户")
+				}
+
+				pageSize := req.PageSize
+				if pageSize <= 0 {
+					pageSize = 20
+				}
+				page := req.Page
+				if page <= 0 {
+					page = 1
+				}
+
+				items, total, err := app.ListFiles(FileQuery{
+					Owner:   owner,
+					Keyword: req.Keyword,
+					MIME:    req.MIME,
+					Backend: req.Backend,
+					Offset:  (page - 1) * pageSize,
+					Limit:   pageSize,
+				})
+				if err != nil {
+					return err
+				}
+
+				// 访问URL按当前的改写规则返回，不修改存储中的元数据
+				for i, item := range items {
+					copied := *item
+					copied.URL = app.FileURL(item)
+					copied.Variants = app.rewriteFileVariants(item.Backend, item.Variants)
+					items[i] = &copied
+				}
+				resp.Total = total
+				resp.Items = items
+				return nil
+			}),
+		},
+		{
+			Name:        "files_get",
+			DisplayName: "文件详情",
+			Description: "根据文件ID获取当前用户上传的文件元数据",
+			Group:       "文件管理",
+			Sort:        2,
+			Handler: MakeHandler(func(ctx *Context, req *FileIDRequest, resp *FileMetadata) error {
+				meta, err := app.getOwnedFile(ctx, req.ID)
+				if err != nil {
+					return err
+				}
+				*resp = *meta
+				resp.URL = app.FileURL(meta)
+				resp.Variants = app.rewriteFileVariants(meta.Backend, meta.Variants)
+				return nil
+			}),
+		},
+		{
+			Name:        "files_delete",
+			DisplayName: "删除文件",
+			Description: "删除当前用户上传的文件，同时删除存储对象和元数据记录",
+			Group:       "文件管理",
+			Sort:        3,
+			Handler: MakeHandler(func(ctx *Context, req *FileIDRequest, resp *DeleteFileResponse) error {
+				meta, err := app.getOwnedFile(ctx, req.ID)
+				if err != nil {
+					return err
+				}
+				if err := app.deleteFile(meta, "manual", meta.Owner); err != nil {
+					return err
+				}
+
+				resp.ID = req.ID
+				resp.Deleted = true
+				return nil
+			}),
+		},
+	}
+
+	if app.cfg.ModConfig.FileUpload.Download.Enabled {
+		services = append(services, Service{
+			Name:        "files_download_url",
+			DisplayName: "生成下载链接",
+			Description: "为当前用户上传的文件生成带签名的限时下载链接，可分享给未登录用户",
+			Group:       "文件管理",
+			Sort:        4,
+			Handler: MakeHandler(func(ctx *Context, req *DownloadURLRequest, resp *DownloadURLResponse) error {
+				meta, err := app.getOwnedFile(ctx, req.ID)
+				if err != nil {
+					return err
+				}
+
+				var ttl time.Duration
+				if req.Expire != "" {
+					if ttl, err = parseDurationWithDays(req.Expire); err != nil || ttl <= 0 {
+						return Reply(400, "无效的有效期")
+					}
+				}
+
+				link, expiresAt, err := app.SignDownloadURL(meta.ID, ttl)
+				if err != nil {
+					return err
+				}
+				resp.URL = link
+				resp.ExpiresAt = expiresAt
+				return nil
+			}),
+		})
+	}
+
+	for _, svc := range services {
+		svc.builtin = true
+		if err := app.Register(svc); err != nil {
+			app.logger.WithError(err).WithField("service", svc.Name).Error("Failed to register file service")
+		}
+	}
+}
+
+// getOwnedFile 获取属于当前用户的文件元数据
+func (app *App) getOwnedFile(ctx *Context, id string) (*FileMetadata, error) {
+	owner := app.resolveFileOwner(ctx.Ctx)
+	if owner == "" {
+		return nil, Reply(401, "无法识别当前用户")
+	}
+
+	meta, err := app.GetFile(id)
+	if err != nil {
+		if err == ErrFileNotFound {
+			return nil, Reply(404, "文件不存在")
+		}
+		return nil, err
+	}
+	if meta.Owner != owner {
+		// 不暴露其他用户文件的存在性
+		return nil, Reply(404, "文件不存在")
+	}
+	return meta, nil
+}
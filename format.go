@@ -0,0 +1,289 @@
+package mod
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// locationKey 调用方时区在 Fiber locals 中的缓存键
+type locationKey struct{}
+
+// locationCache 已解析的时区，避免每次请求读取时区数据库
+var locationCache sync.Map
+
+// timeType time.Time 的反射类型
+var timeType = reflect.TypeOf(time.Time{})
+
+// LoadLocation 解析时区名称（如 Asia/Shanghai、UTC）或UTC偏移（如 +08:00、-0530），结果会被缓存
+func LoadLocation(name string) (*time.Location, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, fmt.Errorf("empty timezone")
+	}
+	if cached, ok := locationCache.Load(name); ok {
+		return cached.(*time.Location), nil
+	}
+
+	var loc *time.Location
+	if name[0] == '+' || name[0] == '-' {
+		offset, err := parseUTCOffset(name)
+		if err != nil {
+			return nil, err
+		}
+		loc = time.FixedZone("UTC"+name, offset)
+	} else {
+		var err error
+		if loc, err = time.LoadLocation(name); err != nil {
+			return nil, err
+		}
+	}
+	locationCache.Store(name, loc)
+	return loc, nil
+}
+
+// parseUTCOffset 解析 +08:00、+0800、+8 形式的UTC偏移，返回秒数
+func parseUTCOffset(s string) (int, error) {
+	sign := 1
+	if s[0] == '-' {
+		sign = -1
+	}
+	hh, mm := strings.ReplaceAll(s[1:], ":", ""), ""
+	if len(hh) > 2 {
+		hh, mm = hh[:len(hh)-2], hh[len(hh)-2:]
+	}
+	hours, err := strconv.Atoi(hh)
+	if err != nil || hours > 14 {
+		return 0, fmt.Errorf("invalid UTC offset %q", s)
+	}
+	minutes := 0
+	if mm != "" {
+		if minutes, err = strconv.Atoi(mm); err != nil || minutes >= 60 {
+			return 0, fmt.Errorf("invalid UTC offset %q", s)
+		}
+	}
+	return sign * (hours*3600 + minutes*60), nil
+}
+
+// Location 返回调用方时区，依次使用请求头（默认 X-Timezone）、查询参数（默认 tz）、
+// 用户声明（默认 timezone）中的有效时区，都没有时使用 timezone.default 配置，默认 UTC
+func (c *Context) Location() *time.Location {
+	if loc, ok := c.Locals(locationKey{}).(*time.Location); ok {
+		return loc
+	}
+	loc := c.resolveLocation()
+	c.Locals(locationKey{}, loc)
+	return loc
+}
+
+func (c *Context) resolveLocation() *time.Location {
+	if c.app == nil {
+		return time.UTC
+	}
+	config := c.app.cfg.ModConfig.Timezone
+	header, queryKey, claimKey := config.Header, config.QueryKey, config.ClaimKey
+	if header == "" {
+		header = "X-Timezone"
+	}
+	if queryKey == "" {
+		queryKey = "tz"
+	}
+	if claimKey == "" {
+		claimKey = "timezone"
+	}
+
+	candidates := []string{c.Get(header), c.Query(queryKey)}
+	if user, ok := c.User(); ok {
+		if value, ok := user.Extra[claimKey].(string); ok {
+			candidates = append(candidates, value)
+		}
+	}
+	for _, name := range candidates {
+		if name == "" {
+			continue
+		}
+		if loc, err := LoadLocation(name); err == nil {
+			return loc
+		}
+		c.Debugf("Ignoring invalid timezone %q", name)
+	}
+
+	if config.Default != "" {
+		if loc, err := LoadLocation(config.Default); err == nil {
+			return loc
+		}
+	}
+	return time.UTC
+}
+
+// LocalTime 将时间转换为调用方时区
+func (c *Context) LocalTime(t time.Time) time.Time {
+	return t.In(c.Location())
+}
+
+// FormatTime 按调用方时区格式化时间，layout 默认为 time.RFC3339
+func (c *Context) FormatTime(t time.Time, layout ...string) string {
+	l := time.RFC3339
+	if len(layout) > 0 && layout[0] != "" {
+		l = layout[0]
+	}
+	return c.LocalTime(t).Format(l)
+}
+
+// ToLocation 返回 v 的副本，其中所有 time.Time 和 *time.Time（包括结构体导出字段、切片、数组和 map 值中的）转换为 loc 时区，
+// 序列化为JSON时输出该时区的时间和偏移。v 本身不被修改，不含时间的部分与 v 共享
+func ToLocation[T any](v T, loc *time.Location) T {
+	rv := reflect.ValueOf(v)
+	if loc == nil || !rv.IsValid() || !mayContainTime(rv.Type()) {
+		return v
+	}
+	return convertTimes(rv, loc, 0).Interface().(T)
+}
+
+// convertTimes 返回转换了时间的副本，depth 防止循环引用导致无限递归
+func convertTimes(v reflect.Value, loc *time.Location, depth int) reflect.Value {
+	if depth > 32 || !mayContainTime(v.Type()) {
+		return v
+	}
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.New(v.Type().Elem())
+		c.Elem().Set(convertTimes(v.Elem(), loc, depth+1))
+		return c
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.New(v.Type()).Elem()
+		c.Set(convertTimes(v.Elem(), loc, depth+1))
+		return c
+	case reflect.Struct:
+		if v.Type() == timeType {
+			return reflect.ValueOf(v.Interface().(time.Time).In(loc))
+		}
+		c := reflect.New(v.Type()).Elem()
+		c.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).IsExported() {
+				c.Field(i).Set(convertTimes(v.Field(i), loc, depth+1))
+			}
+		}
+		return c
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(convertTimes(v.Index(i), loc, depth+1))
+		}
+		return c
+	case reflect.Array:
+		c := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(convertTimes(v.Index(i), loc, depth+1))
+		}
+		return c
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			c.SetMapIndex(iter.Key(), convertTimes(iter.Value(), loc, depth+1))
+		}
+		return c
+	}
+	return v
+}
+
+// timeTypes 类型是否可能包含时间的缓存
+var timeTypes sync.Map
+
+// mayContainTime 类型的值中是否可能包含 time.Time，接口类型按可能包含处理
+func mayContainTime(t reflect.Type) bool {
+	if cached, ok := timeTypes.Load(t); ok {
+		return cached.(bool)
+	}
+	result := containsTime(t, map[reflect.Type]bool{})
+	timeTypes.Store(t, result)
+	return result
+}
+
+func containsTime(t reflect.Type, seen map[reflect.Type]bool) bool {
+	if t == timeType {
+		return true
+	}
+	if seen[t] {
+		return false
+	}
+	seen[t] = true
+	switch t.Kind() {
+	case reflect.Interface:
+		return true
+	case reflect.Pointer, reflect.Slice, reflect.Array, reflect.Map:
+		return containsTime(t.Elem(), seen)
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if f := t.Field(i); f.IsExported() && containsTime(f.Type, seen) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// numberSeparators 各语言的千分位和小数点分隔符
+var numberSeparators = map[string][2]string{
+	"de": {".", ","}, "es": {".", ","}, "it": {".", ","}, "nl": {".", ","}, "pt": {".", ","},
+	"id": {".", ","}, "tr": {".", ","}, "da": {".", ","},
+	"fr": {" ", ","}, "ru": {" ", ","}, "pl": {" ", ","}, "cs": {" ", ","},
+	"sv": {" ", ","}, "nb": {" ", ","}, "fi": {" ", ","}, "uk": {" ", ","},
+	"de-ch": {"’", "."},
+}
+
+// FormatNumber 按语言习惯格式化数字（千分位和小数点），decimals 为保留的小数位数，
+// 未知语言使用 1,234.56 格式
+func FormatNumber(locale string, v float64, decimals int) string {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	group, point := ",", "."
+	if sep, ok := numberSeparators[normalizeLocale(locale)]; ok {
+		group, point = sep[0], sep[1]
+	} else if sep, ok := numberSeparators[baseLanguage(locale)]; ok {
+		group, point = sep[0], sep[1]
+	}
+
+	s := strconv.FormatFloat(math.Abs(v), 'f', max(decimals, 0), 64)
+	integer, fraction, _ := strings.Cut(s, ".")
+
+	var b strings.Builder
+	if v < 0 && strings.Trim(s, "0.") != "" {
+		b.WriteByte('-')
+	}
+	for i, digit := range integer {
+		if i > 0 && (len(integer)-i)%3 == 0 {
+			b.WriteString(group)
+		}
+		b.WriteRune(digit)
+	}
+	if fraction != "" {
+		b.WriteString(point)
+		b.WriteString(fraction)
+	}
+	return b.String()
+}
+
+// FormatNumber 按调用方语言格式化数字
+func (c *Context) FormatNumber(v float64, decimals int) string {
+	return FormatNumber(c.Locale(), v, decimals)
+}
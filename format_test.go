@@ -0,0 +1,100 @@
+package mod
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+type timedItem struct {
+	At   time.Time  `json:"at"`
+	Ptr  *time.Time `json:"ptr"`
+	Tags []string   `json:"tags"`
+}
+
+type timedReport struct {
+	Items  []timedItem          `json:"items"`
+	ByKey  map[string]timedItem `json:"by_key"`
+	Extra  map[string]any       `json:"extra"`
+	Nested *timedItem           `json:"nested"`
+	Plain  map[string]int       `json:"plain"`
+}
+
+func TestToLocationReturnsCopy(t *testing.T) {
+	loc, err := LoadLocation("+08:00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	ptr := at
+	report := &timedReport{
+		Items:  []timedItem{{At: at, Ptr: &ptr, Tags: []string{"a"}}},
+		ByKey:  map[string]timedItem{"k": {At: at}},
+		Extra:  map[string]any{"at": at, "list": []any{at}},
+		Nested: &timedItem{At: at},
+		Plain:  map[string]int{"n": 1},
+	}
+
+	converted := ToLocation(report, loc)
+	if converted == report {
+		t.Fatal("expected a copy")
+	}
+	for name, got := range map[string]time.Time{
+		"items":  converted.Items[0].At,
+		"ptr":    *converted.Items[0].Ptr,
+		"by_key": converted.ByKey["k"].At,
+		"extra":  converted.Extra["at"].(time.Time),
+		"list":   converted.Extra["list"].([]any)[0].(time.Time),
+		"nested": converted.Nested.At,
+	} {
+		if got.Location() != loc || !got.Equal(at) {
+			t.Errorf("%s: got %v, want %v in %v", name, got, at, loc)
+		}
+	}
+
+	for name, got := range map[string]time.Time{
+		"items":  report.Items[0].At,
+		"ptr":    *report.Items[0].Ptr,
+		"by_key": report.ByKey["k"].At,
+		"extra":  report.Extra["at"].(time.Time),
+		"list":   report.Extra["list"].([]any)[0].(time.Time),
+		"nested": report.Nested.At,
+	} {
+		if got.Location() != time.UTC {
+			t.Errorf("%s: original modified to %v", name, got.Location())
+		}
+	}
+}
+
+func TestConvertResponseKeepsSharedOutput(t *testing.T) {
+	app := newTestApp(t, `
+timezone:
+  convert_response: true
+`)
+	at := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	shared := &timedItem{At: at}
+	err := app.Register(Service{
+		Name:        "shared_time",
+		DisplayName: "shared_time",
+		SkipAuth:    true,
+		Handler: MakeHandler2(func(_ context.Context, ctx *Context, req *struct{}) (*timedItem, error) {
+			return shared, nil
+		}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := app.TestClient().WithHeader("X-Timezone", "+08:00").Call("shared_time", struct{}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.AssertStatus(t, 200)
+	if !strings.Contains(string(resp.Body), "2026-01-02T11:04:05+08:00") {
+		t.Fatalf("response not converted: %s", resp.Body)
+	}
+	if shared.At.Location() != time.UTC {
+		t.Fatalf("shared output modified to %v", shared.At.Location())
+	}
+}
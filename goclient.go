@@ -0,0 +1,279 @@
+package mod
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// goReservedNames 生成代码中客户端自身使用的名称，服务方法或类型与之重名时追加后缀
+var goReservedNames = map[string]bool{
+	"Client": true, "NewClient": true, "ServiceBase": true,
+	"Call": true, "Invoke": true, "WithToken": true, "WithHeader": true, "WithHTTPClient": true,
+}
+
+// goClientGenerator Go 客户端代码生成器，类型收集和命名与 TypeScript SDK 一致
+type goClientGenerator struct {
+	*tsGenerator
+	imports map[string]bool
+}
+
+// GoClient 根据已注册的服务生成调用本应用的 Go 客户端代码（类型定义和每个服务的调用方法），
+// 其他 mod 应用引入后通过 ctx.Outgoing() 调用，自动透传请求ID、链路追踪头、租户和token
+func (app *App) GoClient(pkg string) (string, error) {
+	if pkg == "" {
+		pkg = "client"
+	}
+	g := &goClientGenerator{
+		tsGenerator: &tsGenerator{
+			structs: map[reflect.Type]*tsStruct{},
+			names:   map[string]reflect.Type{},
+		},
+		imports: map[string]bool{"context": true},
+	}
+
+	// 与 TypeScript SDK 相同，原始请求体服务、文件下载和 WebSocket 服务不生成调用方法；
+	// Go 客户端用于内部服务间调用，包含隐藏的服务
+	var services []Service
+	seen := map[string]bool{}
+	for _, svc := range app.allServices() {
+		if !seen[svc.Name] && !svc.RawBody && len(svc.methods) == 0 {
+			seen[svc.Name] = true
+			services = append(services, svc)
+		}
+	}
+	sort.Slice(services, func(i, j int) bool { return services[i].Name < services[j].Name })
+	for _, svc := range services {
+		if svc.Handler.InputType != nil {
+			g.collect(svc.Handler.InputType, false)
+		}
+		if svc.Handler.OutputType != nil {
+			g.collect(svc.Handler.OutputType, true)
+		}
+	}
+	// 类型名称首字母大写以便调用方使用，与客户端名称或其他类型重名时追加后缀
+	structs := make([]*tsStruct, 0, len(g.structs))
+	for _, s := range g.structs {
+		structs = append(structs, s)
+	}
+	sort.Slice(structs, func(i, j int) bool { return structs[i].name < structs[j].name })
+	taken := map[string]bool{}
+	for _, s := range structs {
+		name := strings.ToUpper(s.name[:1]) + s.name[1:]
+		for goReservedNames[name] || taken[name] {
+			name += "Data"
+		}
+		taken[name] = true
+		s.name = name
+	}
+
+	config := app.cfg.ModConfig
+	title := config.App.DisplayName
+	if title == "" {
+		title = config.App.Name
+	}
+
+	var body strings.Builder
+	fmt.Fprintf(&body, "\n// ServiceBase 服务前缀\nconst ServiceBase = %q\n", config.App.ServiceBase)
+
+	// 类型定义
+	for _, s := range structs {
+		fmt.Fprintf(&body, "\ntype %s %s\n", s.name, g.goStruct(s.typ))
+	}
+
+	// 服务方法
+	fmt.Fprintf(&body, "\n// Client 调用%s的客户端\ntype Client struct {\n\t*mod.Client\n}\n", title)
+	body.WriteString("\n// NewClient 创建客户端，baseURL 为服务前缀的完整地址，如 http://host:8080" + config.App.ServiceBase + "\n")
+	body.WriteString("func NewClient(baseURL string) *Client {\n\treturn &Client{Client: mod.NewClient(baseURL)}\n}\n")
+	methods := map[string]bool{}
+	for _, svc := range services {
+		method := goMethodName(svc.Name)
+		for goReservedNames[method] || methods[method] {
+			method += "Service"
+		}
+		methods[method] = true
+
+		input, output := "struct{}", "struct{}"
+		if svc.Handler.InputType != nil {
+			input = g.goType(svc.Handler.InputType)
+		}
+		if svc.Handler.OutputType != nil {
+			output = g.goType(svc.Handler.OutputType)
+		}
+		input = strings.TrimPrefix(input, "*")
+		output = strings.TrimPrefix(output, "*")
+
+		doc := svc.DisplayName
+		if svc.Description != "" && svc.Description != doc {
+			doc += " - " + svc.Description
+		}
+		fmt.Fprintf(&body, "\n// %s %s\n", method, strings.ReplaceAll(doc, "\n", " "))
+		fmt.Fprintf(&body, "func (c *Client) %s(ctx context.Context, req *%s) (*%s, error) {\n", method, input, output)
+		fmt.Fprintf(&body, "\treply := new(%s)\n", output)
+		fmt.Fprintf(&body, "\tif err := c.Invoke(ctx, %s, req, reply); err != nil {\n\t\treturn nil, err\n\t}\n\treturn reply, nil\n}\n", g.callMeta(app, svc))
+	}
+
+	var b strings.Builder
+	b.WriteString("// Code generated by mod. DO NOT EDIT.\n")
+	if title != "" {
+		fmt.Fprintf(&b, "// %s %s\n", title, config.App.Version)
+	}
+	fmt.Fprintf(&b, "\npackage %s\n\nimport (\n", pkg)
+	imports := make([]string, 0, len(g.imports))
+	for imp := range g.imports {
+		imports = append(imports, imp)
+	}
+	sort.Strings(imports)
+	for _, imp := range imports {
+		fmt.Fprintf(&b, "\t%q\n", imp)
+	}
+	b.WriteString("\n\t\"github.com/iamdanielyin/mod\"\n)\n")
+	b.WriteString(body.String())
+
+	source, err := format.Source([]byte(b.String()))
+	if err != nil {
+		return b.String(), fmt.Errorf("format generated go client: %w", err)
+	}
+	return string(source), nil
+}
+
+// WriteGoClient 将 Go 客户端写入文件，便于在调用方的构建流程中生成
+func (app *App) WriteGoClient(path, pkg string) error {
+	source, err := app.GoClient(pkg)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(source), 0644)
+}
+
+// handleGoClient 下载 Go 客户端，package 查询参数指定包名，默认 client
+func (app *App) handleGoClient(c *fiber.Ctx) error {
+	source, err := app.GoClient(c.Query("package"))
+	if err != nil {
+		return err
+	}
+	c.Set("Content-Type", "text/x-go; charset=utf-8")
+	c.Set("Content-Disposition", "attachment; filename=client.go")
+	return c.SendString(source)
+}
+
+// callMeta 返回服务的 mod.ClientCall 字面量，参数规则与 TypeScript SDK 的 serviceMeta 一致
+func (g *goClientGenerator) callMeta(app *App, svc Service) string {
+	parts := []string{fmt.Sprintf("Service: %q", svc.Name)}
+	if svc.ReturnRaw {
+		parts = append(parts, "Raw: true")
+	}
+	var params []string
+	if t := svc.Handler.InputType; t != nil {
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if t.Kind() == reflect.Struct {
+			for i := 0; i < t.NumField(); i++ {
+				field := t.Field(i)
+				modTag := field.Tag.Get("mod")
+				from := modTagValue(modTag, "from", "query")
+				if !field.IsExported() || modTag == "" || (from != "query" && from != "header" && from != "param") {
+					continue
+				}
+				key, _, ok := tsProperty(field)
+				if !ok {
+					continue
+				}
+				params = append(params, fmt.Sprintf("{Key: %q, In: %q, Name: %q}",
+					key, from, modTagValue(modTag, "name", strings.ToLower(field.Name))))
+			}
+		}
+	}
+	if len(params) > 0 {
+		parts = append(parts, "Params: []mod.CallParam{"+strings.Join(params, ", ")+"}")
+	}
+	// 挂载的子应用服务不在服务前缀下，路径模板服务不使用服务名称，需要携带完整路径
+	if svc.path != app.ServicePath(svc.Name) {
+		parts = append(parts, fmt.Sprintf("Path: %q", svc.path), "Base: ServiceBase")
+	}
+	return "mod.ClientCall{" + strings.Join(parts, ", ") + "}"
+}
+
+// goStruct 输出结构体定义，字段按JSON名称序列化，去掉绑定和校验标签
+func (g *goClientGenerator) goStruct(t reflect.Type) string {
+	fields := tsFields(t)
+	if len(fields) == 0 {
+		return "struct{}"
+	}
+	var b strings.Builder
+	b.WriteString("struct {\n")
+	for _, field := range fields {
+		name, omitempty, ok := tsProperty(field)
+		if !ok {
+			continue
+		}
+		if desc := field.Tag.Get("desc"); desc != "" {
+			fmt.Fprintf(&b, "\t// %s\n", strings.ReplaceAll(desc, "\n", " "))
+		}
+		tag := name
+		if omitempty {
+			tag += ",omitempty"
+		}
+		fmt.Fprintf(&b, "\t%s %s `json:%q`\n", field.Name, g.goType(field.Type), tag)
+	}
+	b.WriteString("}")
+	return b.String()
+}
+
+// goType 返回类型在生成代码中的写法，具名结构体使用生成的类型，其他具名类型使用其基础类型
+func (g *goClientGenerator) goType(t reflect.Type) string {
+	switch t {
+	case reflect.TypeOf(time.Time{}):
+		g.imports["time"] = true
+		return "time.Time"
+	case reflect.TypeOf(time.Duration(0)):
+		g.imports["time"] = true
+		return "time.Duration"
+	case reflect.TypeOf(json.RawMessage{}), reflect.TypeOf(json.Number("")):
+		g.imports["encoding/json"] = true
+		if t.Kind() == reflect.String {
+			return "json.Number"
+		}
+		return "json.RawMessage"
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return "*" + g.goType(t.Elem())
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return t.Kind().String()
+	case reflect.Slice:
+		return "[]" + g.goType(t.Elem())
+	case reflect.Array:
+		return fmt.Sprintf("[%d]%s", t.Len(), g.goType(t.Elem()))
+	case reflect.Map:
+		return "map[" + g.goType(t.Key()) + "]" + g.goType(t.Elem())
+	case reflect.Struct:
+		if s, ok := g.structs[t]; ok {
+			return s.name
+		}
+		return g.goStruct(t)
+	}
+	return "any"
+}
+
+// goMethodName 将服务名称转换为导出的方法名，如 get_user -> GetUser
+func goMethodName(name string) string {
+	method := strings.TrimPrefix(tsMethodName(name), "_")
+	if method == "" || !unicode.IsLetter([]rune(method)[0]) {
+		method = "Call" + method
+	}
+	return strings.ToUpper(method[:1]) + method[1:]
+}
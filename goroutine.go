@@ -0,0 +1,116 @@
+package mod
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// goroutineState 通过 Go 启动的后台任务，关闭服务时等待其结束；挂载的子应用与父应用共用
+type goroutineState struct {
+	mu      sync.Mutex
+	running int
+	idle    chan struct{} // 所有任务结束时关闭
+	panics  uint64
+}
+
+// configureGoroutines 初始化后台任务状态，并在 app.Shutdown() 停止接收请求后等待任务结束
+func (app *App) configureGoroutines() {
+	app.goroutines = &goroutineState{}
+	app.Hooks().OnShutdown(func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), app.shutdownTimeout())
+		defer cancel()
+		return app.WaitGoroutines(ctx)
+	})
+}
+
+// shutdownTimeout 返回关闭服务时等待后台任务的最长时间，默认30s
+func (app *App) shutdownTimeout() time.Duration {
+	if value := app.cfg.ModConfig.Server.ShutdownTimeout; value != "" {
+		if d, err := time.ParseDuration(value); err == nil && d > 0 {
+			return d
+		}
+		app.logger.WithField("shutdown_timeout", value).Warn("Invalid server shutdown_timeout, using 30s")
+	}
+	return 30 * time.Second
+}
+
+// Go 在新的 goroutine 中执行后台任务：捕获 panic 并记录日志（不会导致进程退出），关闭服务时等待任务结束
+func (app *App) Go(fn func()) {
+	app.spawn(app.logger.WithField("source", "app"), fn)
+}
+
+// Go 在新的 goroutine 中执行处理函数派生的后台任务，同 app.Go，日志中带有请求ID和服务名称。
+// 任务可能在请求结束后继续运行：不应再访问 ctx，需要上下文时使用 context.WithoutCancel(ctx.UserContext())
+func (c *Context) Go(fn func()) {
+	fields := logrus.Fields{"rid": c.GetRequestID()}
+	if c.service != nil {
+		fields["service"] = c.service.Name
+	}
+	c.app.spawn(c.app.logger.WithFields(fields), fn)
+}
+
+// spawn 登记并启动后台任务
+func (app *App) spawn(log *logrus.Entry, fn func()) {
+	state := app.goroutines
+	state.mu.Lock()
+	if state.running == 0 {
+		state.idle = make(chan struct{})
+	}
+	state.running++
+	state.mu.Unlock()
+
+	go func() {
+		defer func() {
+			state.mu.Lock()
+			state.running--
+			if state.running == 0 {
+				close(state.idle)
+			}
+			state.mu.Unlock()
+		}()
+		defer func() {
+			if r := recover(); r != nil {
+				state.mu.Lock()
+				state.panics++
+				state.mu.Unlock()
+				log.WithFields(logrus.Fields{"panic": fmt.Sprint(r), "stack": string(debug.Stack())}).Error("Goroutine panicked")
+			}
+		}()
+		fn()
+	}()
+}
+
+// RunningGoroutines 返回正在运行的后台任务数和累计 panic 次数
+func (app *App) RunningGoroutines() (running int, panics uint64) {
+	state := app.goroutines
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	return state.running, state.panics
+}
+
+// WaitGoroutines 等待通过 Go 启动的后台任务全部结束，ctx 结束时返回错误；
+// app.Shutdown() 会按 server.shutdown_timeout 自动等待，未使用 Shutdown 关闭服务时可以手动调用
+func (app *App) WaitGoroutines(ctx context.Context) error {
+	state := app.goroutines
+	state.mu.Lock()
+	if state.running == 0 {
+		state.mu.Unlock()
+		return nil
+	}
+	idle := state.idle
+	state.mu.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		running, _ := app.RunningGoroutines()
+		app.logger.WithField("running", running).Warn("Goroutines still running after shutdown timeout")
+		return fmt.Errorf("%d goroutines still running: %w", running, ctx.Err())
+	}
+}
@@ -0,0 +1,117 @@
+package mod
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// HeaderPolicy 服务的请求头和响应头策略
+type HeaderPolicy struct {
+	Required []string          `yaml:"required" json:"required,omitempty"` // 必须携带的请求头，缺失时响应400
+	Response map[string]string `yaml:"response" json:"response,omitempty"` // 固定添加的响应头，如缓存策略；值为空表示移除该响应头（包括安全基线响应头）
+}
+
+// securityHeaders 安全基线响应头，启用 headers.security 时添加到所有响应
+var securityHeaders = [][2]string{
+	{fiber.HeaderXContentTypeOptions, "nosniff"},
+	{fiber.HeaderXFrameOptions, "DENY"},
+	{fiber.HeaderReferrerPolicy, "strict-origin-when-cross-origin"},
+	{fiber.HeaderXXSSProtection, "0"},
+}
+
+// configureSecurityHeaders 启用 headers.security 时为所有响应添加安全基线响应头
+func (app *App) configureSecurityHeaders() {
+	config := app.cfg.ModConfig.Headers
+	if !config.Security {
+		return
+	}
+	maxAge := 365 * 24 * time.Hour
+	if config.HSTSMaxAge != "" {
+		d, err := time.ParseDuration(config.HSTSMaxAge)
+		if err != nil {
+			app.logger.WithError(err).Warnf("Invalid headers.hsts_max_age %q, using default %s", config.HSTSMaxAge, maxAge)
+		} else {
+			maxAge = d
+		}
+	}
+	hsts := fmt.Sprintf("max-age=%d; includeSubDomains", int64(maxAge.Seconds()))
+
+	app.Use(func(c *fiber.Ctx) error {
+		c.Set(fiber.HeaderStrictTransportSecurity, hsts)
+		for _, header := range securityHeaders {
+			c.Set(header[0], header[1])
+		}
+		return c.Next()
+	})
+	app.logger.WithField("hsts", hsts).Info("Security headers configured")
+}
+
+// headerPolicy 合并服务生效的请求头和响应头策略，按 headers.default、headers.groups、Service.Headers、headers.services 的顺序，
+// 必需请求头取并集，响应头由后者覆盖；没有任何策略时返回 nil
+func (app *App) headerPolicy(svc *Service) *HeaderPolicy {
+	config := app.cfg.ModConfig.Headers
+	var policies []*HeaderPolicy
+	policies = append(policies, &config.Default)
+	if policy, ok := config.Groups[svc.Group]; ok {
+		policies = append(policies, &policy)
+	}
+	if svc.Headers != nil {
+		policies = append(policies, svc.Headers)
+	}
+	if policy, ok := config.Services[svc.Name]; ok {
+		policies = append(policies, &policy)
+	}
+
+	// 请求头名称不区分大小写，保留配置中的写法
+	merged := &HeaderPolicy{Response: map[string]string{}}
+	required := map[string]bool{}
+	response := map[string]string{}
+	for _, policy := range policies {
+		for _, name := range policy.Required {
+			name = strings.TrimSpace(name)
+			if key := strings.ToLower(name); name != "" && !required[key] {
+				required[key] = true
+				merged.Required = append(merged.Required, name)
+			}
+		}
+		for name, value := range policy.Response {
+			key := strings.ToLower(name)
+			if previous, ok := response[key]; ok {
+				delete(merged.Response, previous)
+			}
+			response[key] = name
+			merged.Response[name] = value
+		}
+	}
+	if len(merged.Required) == 0 && len(merged.Response) == 0 {
+		return nil
+	}
+	return merged
+}
+
+// applyHeaderPolicy 设置策略中的响应头（错误响应同样生效），并检查必需的请求头，缺失时返回 StdReply 错误
+func applyHeaderPolicy(c *fiber.Ctx, policy *HeaderPolicy) error {
+	if policy == nil {
+		return nil
+	}
+	for name, value := range policy.Response {
+		if value == "" {
+			c.Response().Header.Del(name)
+		} else {
+			c.Set(name, value)
+		}
+	}
+	var missing []string
+	for _, name := range policy.Required {
+		if c.Get(name) == "" {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return ReplyWithDetail(400, "Missing required header", "missing headers: "+strings.Join(missing, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,397 @@
+package mod
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ErrCircuitOpen 目标主机处于熔断状态，请求未发出
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// HTTPHostStats 外部请求按主机统计的指标
+type HTTPHostStats struct {
+	Host         string        `json:"host"`
+	Requests     int64         `json:"requests"`      // 请求次数（不含重试）
+	Failures     int64         `json:"failures"`      // 最终失败次数（网络错误或5xx）
+	Retries      int64         `json:"retries"`       // 重试次数
+	Rejected     int64         `json:"rejected"`      // 因熔断被拒绝的次数
+	CircuitOpens int64         `json:"circuit_opens"` // 熔断次数
+	CircuitState string        `json:"circuit_state"` // closed、open、half_open
+	AvgLatency   time.Duration `json:"avg_latency"`   // 平均耗时（含重试）
+}
+
+// outboundClient 应用内共享的外部请求组件
+type outboundClient struct {
+	transport        *http.Transport
+	timeout          time.Duration
+	maxRetries       int
+	backoff          time.Duration
+	maxBackoff       time.Duration
+	retryStatuses    map[int]bool
+	failureThreshold int
+	openDuration     time.Duration
+	propagate        []string
+
+	mu    sync.Mutex
+	hosts map[string]*hostState
+}
+
+// hostState 单个主机的熔断状态和统计
+type hostState struct {
+	mu          sync.Mutex
+	failures    int // 连续失败次数
+	openUntil   time.Time
+	halfOpen    bool // 熔断到期后已放行探测请求
+	probing     bool
+	requests    atomic.Int64
+	failed      atomic.Int64
+	retries     atomic.Int64
+	rejected    atomic.Int64
+	opens       atomic.Int64
+	latencyNano atomic.Int64
+}
+
+// HTTP 返回用于调用外部依赖的HTTP客户端，按 http_client 配置设置超时、幂等请求重试和按主机熔断，
+// 自动透传请求ID（X-Request-ID）和链路追踪头，未指定上下文的请求随服务调用一起取消。
+// 启用外部依赖模拟时（mock.upstream.enabled 或服务配置 upstream: true），请求按 mod.yml 中的规则返回预设响应
+func (c *Context) HTTP() *http.Client {
+	if c.app == nil {
+		return &http.Client{Timeout: 30 * time.Second}
+	}
+	client := c.app.outboundClient()
+
+	var transport http.RoundTripper = client.transport
+	serviceName := ""
+	if c.service != nil {
+		serviceName = c.service.Name
+	}
+	if c.app.isUpstreamMockEnabled(c.service) {
+		transport = &upstreamMockTransport{
+			app:     c.app,
+			service: serviceName,
+			rid:     c.GetRequestID(),
+			next:    transport,
+		}
+	}
+
+	headers := make(http.Header)
+	for _, key := range client.propagate {
+		if value := c.Get(key); value != "" {
+			headers.Set(key, value)
+		}
+	}
+	// 当前 span 和服务写入的 baggage，上下文中没有 span 时保留透传的 traceparent
+	injectTraceHeaders(c.UserContext(), headers)
+
+	return &http.Client{
+		Transport: &managedTransport{
+			client:  client,
+			logger:  c.app.logger,
+			ctx:     c.UserContext(),
+			headers: headers,
+			service: serviceName,
+			rid:     c.GetRequestID(),
+			next:    transport,
+		},
+		Timeout: client.timeout,
+	}
+}
+
+// outboundClient 按 http_client 配置初始化共享组件
+func (app *App) outboundClient() *outboundClient {
+	app.httpClientOnce.Do(func() {
+		config := app.cfg.ModConfig.HTTPClient
+		duration := func(value string, def time.Duration) time.Duration {
+			if value == "" {
+				return def
+			}
+			d, err := time.ParseDuration(value)
+			if err != nil {
+				app.logger.WithError(err).Warnf("Invalid http_client duration %q, using default %s", value, def)
+				return def
+			}
+			return d
+		}
+
+		dialer := &net.Dialer{
+			Timeout:   duration(config.DialTimeout, 5*time.Second),
+			KeepAlive: 30 * time.Second,
+		}
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.DialContext = dialer.DialContext
+		transport.TLSHandshakeTimeout = duration(config.TLSHandshakeTimeout, 5*time.Second)
+		transport.ResponseHeaderTimeout = duration(config.ResponseHeaderTimeout, 10*time.Second)
+		transport.IdleConnTimeout = duration(config.IdleConnTimeout, 90*time.Second)
+		transport.MaxIdleConnsPerHost = 16
+		if config.MaxIdleConnsPerHost > 0 {
+			transport.MaxIdleConnsPerHost = config.MaxIdleConnsPerHost
+		}
+
+		client := &outboundClient{
+			transport:        transport,
+			timeout:          duration(config.Timeout, 30*time.Second),
+			maxRetries:       config.Retry.MaxRetries,
+			backoff:          duration(config.Retry.Backoff, 100*time.Millisecond),
+			maxBackoff:       duration(config.Retry.MaxBackoff, 2*time.Second),
+			retryStatuses:    map[int]bool{},
+			failureThreshold: config.CircuitBreaker.FailureThreshold,
+			openDuration:     duration(config.CircuitBreaker.OpenDuration, 30*time.Second),
+			propagate:        config.PropagateHeaders,
+			hosts:            map[string]*hostState{},
+		}
+		if client.maxRetries == 0 {
+			client.maxRetries = 2
+		}
+		if client.failureThreshold == 0 {
+			client.failureThreshold = 5
+		}
+		statuses := config.Retry.Statuses
+		if len(statuses) == 0 {
+			statuses = []int{http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout}
+		}
+		for _, status := range statuses {
+			client.retryStatuses[status] = true
+		}
+		if len(client.propagate) == 0 {
+			client.propagate = []string{"traceparent", "tracestate"}
+		}
+		app.httpClient = client
+	})
+	return app.httpClient
+}
+
+// HTTPClientStats 返回 ctx.HTTP() 按主机统计的外部请求指标，按主机名排序
+func (app *App) HTTPClientStats() []HTTPHostStats {
+	client := app.outboundClient()
+	client.mu.Lock()
+	hosts := make([]string, 0, len(client.hosts))
+	for host := range client.hosts {
+		hosts = append(hosts, host)
+	}
+	client.mu.Unlock()
+	sort.Strings(hosts)
+
+	stats := make([]HTTPHostStats, 0, len(hosts))
+	for _, host := range hosts {
+		h := client.host(host)
+		s := HTTPHostStats{
+			Host:         host,
+			Requests:     h.requests.Load(),
+			Failures:     h.failed.Load(),
+			Retries:      h.retries.Load(),
+			Rejected:     h.rejected.Load(),
+			CircuitOpens: h.opens.Load(),
+			CircuitState: h.state(),
+		}
+		if s.Requests > 0 {
+			s.AvgLatency = time.Duration(h.latencyNano.Load() / s.Requests)
+		}
+		stats = append(stats, s)
+	}
+	return stats
+}
+
+func (c *outboundClient) host(name string) *hostState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	h, ok := c.hosts[name]
+	if !ok {
+		h = &hostState{}
+		c.hosts[name] = h
+	}
+	return h
+}
+
+// allow 判断主机是否允许发出请求，熔断到期后仅放行一个探测请求
+func (c *outboundClient) allow(h *hostState) bool {
+	if c.failureThreshold < 0 {
+		return true
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.openUntil.IsZero() {
+		return true
+	}
+	if time.Now().Before(h.openUntil) || h.probing {
+		return false
+	}
+	h.halfOpen = true
+	h.probing = true
+	return true
+}
+
+// record 记录请求结果，连续失败达到阈值或探测失败时熔断
+func (c *outboundClient) record(h *hostState, failed bool) (opened bool) {
+	if c.failureThreshold < 0 {
+		return false
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.probing = false
+	if !failed {
+		h.failures = 0
+		h.halfOpen = false
+		h.openUntil = time.Time{}
+		return false
+	}
+	h.failures++
+	if h.halfOpen || h.failures >= c.failureThreshold {
+		h.halfOpen = false
+		h.failures = 0
+		h.openUntil = time.Now().Add(c.openDuration)
+		h.opens.Add(1)
+		return true
+	}
+	return false
+}
+
+// abort 请求被调用方取消，释放探测名额但不改变熔断状态
+func (c *outboundClient) abort(h *hostState) {
+	h.mu.Lock()
+	h.probing = false
+	h.mu.Unlock()
+}
+
+func (h *hostState) state() string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	switch {
+	case h.openUntil.IsZero():
+		return "closed"
+	case time.Now().Before(h.openUntil):
+		return "open"
+	default:
+		return "half_open"
+	}
+}
+
+// managedTransport 为外部请求添加请求ID/链路头透传、重试、熔断和统计
+type managedTransport struct {
+	client  *outboundClient
+	logger  *logrus.Logger
+	ctx     context.Context // 当前服务调用的上下文，请求未指定上下文时使用
+	headers http.Header     // 需要透传的请求头
+	service string
+	rid     string
+	next    http.RoundTripper
+}
+
+func (t *managedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	if ctx == context.Background() && t.ctx != nil {
+		// http.Get 等未指定上下文的请求随服务调用一起取消
+		ctx = t.ctx
+	}
+	req = req.Clone(ctx)
+	if req.Header.Get("X-Request-ID") == "" && t.rid != "" {
+		req.Header.Set("X-Request-ID", t.rid)
+	}
+	for key, values := range t.headers {
+		if req.Header.Get(key) == "" {
+			req.Header[key] = values
+		}
+	}
+
+	host := req.URL.Host
+	h := t.client.host(host)
+	h.requests.Add(1)
+	start := time.Now()
+	defer func() { h.latencyNano.Add(int64(time.Since(start))) }()
+
+	retryable := isIdempotent(req) && (req.Body == nil || req.Body == http.NoBody || req.GetBody != nil)
+	for attempt := 0; ; attempt++ {
+		if !t.client.allow(h) {
+			h.rejected.Add(1)
+			return nil, fmt.Errorf("%s %s: %w", req.Method, host, ErrCircuitOpen)
+		}
+
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+
+		resp, err := t.next.RoundTrip(req)
+		if err != nil && ctx.Err() != nil {
+			// 调用方取消不计入主机失败
+			t.client.abort(h)
+			return nil, err
+		}
+		failed := err != nil || resp.StatusCode >= 500
+		if t.client.record(h, failed) {
+			t.logger.WithFields(logrus.Fields{
+				"service": t.service,
+				"host":    host,
+				"rid":     t.rid,
+			}).Warn("Outbound circuit breaker opened")
+		}
+
+		shouldRetry := retryable && attempt < t.client.maxRetries && ctx.Err() == nil &&
+			((err != nil && !errors.Is(err, ErrCircuitOpen)) || (err == nil && t.client.retryStatuses[resp.StatusCode]))
+		if !shouldRetry {
+			if failed {
+				h.failed.Add(1)
+			}
+			return resp, err
+		}
+
+		wait := t.client.retryDelay(attempt, resp)
+		if resp != nil {
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+		}
+		h.retries.Add(1)
+		t.logger.WithFields(logrus.Fields{
+			"service": t.service,
+			"method":  req.Method,
+			"url":     req.URL.String(),
+			"attempt": attempt + 1,
+			"wait":    wait.String(),
+			"rid":     t.rid,
+		}).Debug("Retrying outbound request")
+
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			h.failed.Add(1)
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// retryDelay 指数退避加随机抖动，响应携带 Retry-After（秒）时优先使用
+func (c *outboundClient) retryDelay(attempt int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds >= 0 {
+			return min(time.Duration(seconds)*time.Second, c.maxBackoff)
+		}
+	}
+	backoff := c.backoff << attempt
+	if backoff <= 0 || backoff > c.maxBackoff {
+		backoff = c.maxBackoff
+	}
+	return backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+}
+
+// isIdempotent 判断请求是否可以安全重试
+func isIdempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete, http.MethodTrace:
+		return true
+	}
+	return req.Header.Get("Idempotency-Key") != ""
+}
@@ -0,0 +1,131 @@
+package mod
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// isUpstreamMockEnabled 检查服务是否启用了外部依赖模拟
+func (app *App) isUpstreamMockEnabled(service *Service) bool {
+	config := app.GetModConfig()
+	if config == nil {
+		return false
+	}
+	if service != nil {
+		if serviceConfig, exists := config.Mock.Services[service.Name]; exists && serviceConfig.Upstream {
+			return true
+		}
+	}
+	return config.Mock.Upstream.Enabled
+}
+
+// upstreamMockTransport 按规则拦截外部请求并返回预设响应
+type upstreamMockTransport struct {
+	app     *App
+	service string
+	rid     string
+	next    http.RoundTripper
+}
+
+func (t *upstreamMockTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	config := t.app.GetModConfig().Mock.Upstream
+	url := req.URL.String()
+
+	for _, rule := range config.Rules {
+		if rule.Service != "" && rule.Service != t.service {
+			continue
+		}
+		if rule.Method != "" && !strings.EqualFold(rule.Method, req.Method) {
+			continue
+		}
+		if !matchURLPattern(rule.URL, url) {
+			continue
+		}
+
+		if rule.Delay != "" {
+			if delay, err := time.ParseDuration(rule.Delay); err == nil {
+				select {
+				case <-time.After(delay):
+				case <-req.Context().Done():
+					return nil, req.Context().Err()
+				}
+			}
+		}
+
+		body := []byte(rule.Body)
+		if rule.BodyFile != "" {
+			content, err := os.ReadFile(rule.BodyFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read upstream mock body file %s: %v", rule.BodyFile, err)
+			}
+			body = content
+		}
+
+		status := rule.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		header := make(http.Header)
+		for k, v := range rule.Headers {
+			header.Set(k, v)
+		}
+
+		t.app.logger.WithFields(logrus.Fields{
+			"service": t.service,
+			"method":  req.Method,
+			"url":     url,
+			"status":  status,
+			"rid":     t.rid,
+		}).Info("Upstream request mocked")
+
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+			StatusCode:    status,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        header,
+			Body:          io.NopCloser(bytes.NewReader(body)),
+			ContentLength: int64(len(body)),
+			Request:       req,
+		}, nil
+	}
+
+	if config.Passthrough {
+		return t.next.RoundTrip(req)
+	}
+
+	t.app.logger.WithFields(logrus.Fields{
+		"service": t.service,
+		"method":  req.Method,
+		"url":     url,
+		"rid":     t.rid,
+	}).Warn("No upstream mock rule matched")
+	return nil, fmt.Errorf("no upstream mock rule matched: %s %s", req.Method, url)
+}
+
+// matchURLPattern 判断URL是否匹配规则，* 匹配任意字符
+func matchURLPattern(pattern, url string) bool {
+	if pattern == "" {
+		return true
+	}
+	if !strings.Contains(pattern, "*") {
+		return pattern == url
+	}
+
+	parts := strings.Split(pattern, "*")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	matched, _ := regexp.MatchString("^"+strings.Join(parts, ".*")+"$", url)
+	return matched
+}
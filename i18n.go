@@ -0,0 +1,309 @@
+package mod
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// i18nCatalog 各语言的消息目录
+type i18nCatalog struct {
+	mu            sync.RWMutex
+	messages      map[string]map[string]string // 语言 -> 消息键 -> 消息
+	defaultLocale string
+}
+
+// localeKey 当前请求语言在 Fiber locals 中的缓存键
+type localeKey struct{}
+
+// configureI18n 按配置加载消息目录
+func (app *App) configureI18n() {
+	config := app.cfg.ModConfig.I18n
+	app.i18n = &i18nCatalog{
+		messages:      map[string]map[string]string{},
+		defaultLocale: config.DefaultLocale,
+	}
+	if config.Dir == "" {
+		return
+	}
+	start := time.Now()
+	err := app.LoadLocales(config.Dir)
+	if err != nil {
+		app.logger.WithError(err).WithField("dir", config.Dir).Warn("Failed to load i18n message catalogs")
+	}
+	app.recordStartup("i18n", config.Dir, start, err)
+}
+
+// LoadLocales 加载目录下的消息目录文件，文件名为语言（如 zh-CN.yml、en.yml），
+// 嵌套的键以点号连接，如 order.not_found
+func (app *App) LoadLocales(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		ext := filepath.Ext(entry.Name())
+		if entry.IsDir() || (ext != ".yml" && ext != ".yaml") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return err
+		}
+		var tree map[string]any
+		if err := yaml.Unmarshal(data, &tree); err != nil {
+			return fmt.Errorf("failed to parse %s: %w", entry.Name(), err)
+		}
+		messages := map[string]string{}
+		flattenMessages("", tree, messages)
+
+		locale := strings.TrimSuffix(entry.Name(), ext)
+		app.AddMessages(locale, messages)
+		app.logger.WithFields(map[string]any{
+			"locale":   locale,
+			"messages": len(messages),
+		}).Info("I18n messages loaded")
+	}
+	return nil
+}
+
+// flattenMessages 将嵌套的消息展开为点号连接的键
+func flattenMessages(prefix string, tree map[string]any, out map[string]string) {
+	for key, value := range tree {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		switch v := value.(type) {
+		case map[string]any:
+			flattenMessages(key, v, out)
+		case nil:
+		default:
+			out[key] = fmt.Sprint(v)
+		}
+	}
+}
+
+// AddMessages 添加或覆盖指定语言的消息
+func (app *App) AddMessages(locale string, messages map[string]string) {
+	catalog := app.i18n
+	locale = normalizeLocale(locale)
+
+	catalog.mu.Lock()
+	defer catalog.mu.Unlock()
+	if catalog.messages[locale] == nil {
+		catalog.messages[locale] = map[string]string{}
+	}
+	for key, message := range messages {
+		catalog.messages[locale][key] = message
+	}
+	if catalog.defaultLocale == "" {
+		catalog.defaultLocale = locale
+	}
+}
+
+// empty 是否尚未加载任何消息
+func (catalog *i18nCatalog) empty() bool {
+	catalog.mu.RLock()
+	defer catalog.mu.RUnlock()
+	return len(catalog.messages) == 0
+}
+
+// Locales 返回已加载的语言
+func (app *App) Locales() []string {
+	catalog := app.i18n
+	catalog.mu.RLock()
+	defer catalog.mu.RUnlock()
+	locales := make([]string, 0, len(catalog.messages))
+	for locale := range catalog.messages {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// T 返回指定语言的消息，依次查找该语言、默认语言，都不存在时返回 key 本身。
+// args 为单个 map[string]any 时替换消息中的 {name} 占位符，否则按 fmt.Sprintf 格式化
+func (app *App) T(locale, key string, args ...any) string {
+	message, ok := app.lookupMessage(locale, key)
+	if !ok {
+		message = key
+	}
+	return formatMessage(message, args)
+}
+
+// lookupMessage 查找消息，未找到时 ok 为 false
+func (app *App) lookupMessage(locale, key string) (string, bool) {
+	catalog := app.i18n
+	if catalog == nil {
+		return "", false
+	}
+	catalog.mu.RLock()
+	defer catalog.mu.RUnlock()
+	for _, l := range []string{normalizeLocale(locale), baseLanguage(locale), normalizeLocale(catalog.defaultLocale)} {
+		if message, ok := catalog.messages[l][key]; ok {
+			return message, true
+		}
+	}
+	return "", false
+}
+
+// formatMessage 替换命名占位符或按格式化参数格式化消息
+func formatMessage(message string, args []any) string {
+	if len(args) == 0 {
+		return message
+	}
+	if named, ok := args[0].(map[string]any); ok && len(args) == 1 {
+		pairs := make([]string, 0, len(named)*2)
+		for name, value := range named {
+			pairs = append(pairs, "{"+name+"}", fmt.Sprint(value))
+		}
+		return strings.NewReplacer(pairs...).Replace(message)
+	}
+	return fmt.Sprintf(message, args...)
+}
+
+// matchLocale 返回已加载的语言中与 locale 匹配的语言（完全匹配优先，其次匹配基础语言），未匹配时返回空字符串；
+// 未加载任何消息目录时返回规范化后的 locale
+func (app *App) matchLocale(locale string) string {
+	catalog := app.i18n
+	if catalog == nil || locale == "" {
+		return ""
+	}
+	catalog.mu.RLock()
+	defer catalog.mu.RUnlock()
+	normalized := normalizeLocale(locale)
+	// 未加载消息目录时直接使用调用方的语言，用于数字格式等
+	if len(catalog.messages) == 0 {
+		return normalized
+	}
+	if _, ok := catalog.messages[normalized]; ok {
+		return normalized
+	}
+	base := baseLanguage(locale)
+	if _, ok := catalog.messages[base]; ok {
+		return base
+	}
+	for available := range catalog.messages {
+		if baseLanguage(available) == base {
+			return available
+		}
+	}
+	return ""
+}
+
+// normalizeLocale 统一语言标识格式，如 zh_cn -> zh-cn
+func normalizeLocale(locale string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+}
+
+// baseLanguage 返回基础语言，如 zh-CN -> zh
+func baseLanguage(locale string) string {
+	locale = normalizeLocale(locale)
+	if i := strings.IndexByte(locale, '-'); i > 0 {
+		return locale[:i]
+	}
+	return locale
+}
+
+// Locale 返回当前请求的语言，依次使用查询参数（默认 lang）、用户声明（默认 locale）、
+// Accept-Language 请求头中已加载的语言，都不匹配时使用默认语言
+func (c *Context) Locale() string {
+	if locale, ok := c.Locals(localeKey{}).(string); ok {
+		return locale
+	}
+	locale := c.resolveLocale()
+	c.Locals(localeKey{}, locale)
+	return locale
+}
+
+func (c *Context) resolveLocale() string {
+	if c.app == nil || c.app.i18n == nil {
+		return ""
+	}
+	config := c.app.cfg.ModConfig.I18n
+	queryKey, claimKey := config.QueryKey, config.ClaimKey
+	if queryKey == "" {
+		queryKey = "lang"
+	}
+	if claimKey == "" {
+		claimKey = "locale"
+	}
+
+	if locale := c.app.matchLocale(c.Query(queryKey)); locale != "" {
+		return locale
+	}
+	if user, ok := c.User(); ok {
+		if value, ok := user.Extra[claimKey].(string); ok {
+			if locale := c.app.matchLocale(value); locale != "" {
+				return locale
+			}
+		}
+	}
+	for _, tag := range parseAcceptLanguage(c.Get("Accept-Language")) {
+		if locale := c.app.matchLocale(tag); locale != "" {
+			return locale
+		}
+	}
+	return normalizeLocale(c.app.i18n.defaultLocale)
+}
+
+// parseAcceptLanguage 按权重从高到低返回 Accept-Language 中的语言
+func parseAcceptLanguage(header string) []string {
+	type tag struct {
+		name string
+		q    float64
+	}
+	var tags []tag
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if name == "" || name == "*" {
+			continue
+		}
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+				q = parsed
+			}
+		}
+		if q > 0 {
+			tags = append(tags, tag{name: name, q: q})
+		}
+	}
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].q > tags[j].q })
+	names := make([]string, len(tags))
+	for i, t := range tags {
+		names[i] = t.name
+	}
+	return names
+}
+
+// T 返回当前请求语言的消息，如 ctx.T("order.not_found", map[string]any{"id": id})
+func (c *Context) T(key string, args ...any) string {
+	if c.app == nil {
+		return formatMessage(key, args)
+	}
+	return c.app.T(c.Locale(), key, args...)
+}
+
+// ReplyT 返回当前请求语言的业务错误，如 return ctx.ReplyT(404, "order.not_found", map[string]any{"id": id})
+func (c *Context) ReplyT(code int, key string, args ...any) error {
+	return Reply(code, c.T(key, args...))
+}
+
+// localizeMessage 消息为目录中的键时翻译为当前请求语言，Reply(404, "order.not_found") 等响应消息因此自动本地化
+func (c *Context) localizeMessage(message string) string {
+	if c.app == nil || c.app.i18n == nil || message == "" || c.app.i18n.empty() {
+		return message
+	}
+	if translated, ok := c.app.lookupMessage(c.Locale(), message); ok {
+		return translated
+	}
+	return message
+}
@@ -0,0 +1,154 @@
+package mod
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// IDGenerator ID生成函数，用于请求ID、文件ID和自动生成的文件名
+type IDGenerator func() string
+
+// 内置ID生成策略
+const (
+	IDGeneratorSnowflake = "snowflake" // 雪花ID（默认），趋势递增的数字字符串
+	IDGeneratorULID      = "ulid"      // ULID，26位按时间排序的Crockford Base32字符串
+	IDGeneratorUUIDv7    = "uuidv7"    // UUIDv7，按时间排序的标准UUID
+)
+
+// newIDGenerator 根据 id_generator.type 创建ID生成函数，ID中的时间戳取自应用时钟
+func (app *App) newIDGenerator(typ string) (IDGenerator, error) {
+	switch strings.ToLower(strings.TrimSpace(typ)) {
+	case IDGeneratorSnowflake:
+		return app.nextSnowflakeID, nil
+	case IDGeneratorULID:
+		return app.nextULID, nil
+	case IDGeneratorUUIDv7:
+		return app.nextUUIDv7, nil
+	default:
+		return nil, fmt.Errorf("unsupported id_generator type %q, expected snowflake, ulid or uuidv7", typ)
+	}
+}
+
+// SetIDGenerator 设置自定义ID生成函数，覆盖 id_generator.type 配置，同时用于自动生成的文件名
+func (app *App) SetIDGenerator(generator IDGenerator) {
+	app.idGenerator = generator
+}
+
+// NextID 使用当前策略生成ID
+func (app *App) NextID() string {
+	if app.idGenerator == nil {
+		return app.nextSnowflakeID()
+	}
+	return app.idGenerator()
+}
+
+// nextSnowflakeID 生成雪花ID，设置了应用时钟时按应用时钟的时间生成
+func (app *App) nextSnowflakeID() string {
+	if !app.customClock() {
+		return NextSnowflakeStringID()
+	}
+	return nextSnowflakeAt(app.now()).String()
+}
+
+// nextULID 生成时间戳取自应用时钟的ULID
+func (app *App) nextULID() string {
+	return nextULIDAt(uint64(app.now().UnixMilli()))
+}
+
+// nextUUIDv7 生成时间戳取自应用时钟的UUIDv7
+func (app *App) nextUUIDv7() string {
+	if !app.customClock() {
+		return NextUUIDv7()
+	}
+	return nextUUIDv7At(app.now())
+}
+
+// configureIDGenerator 按配置初始化ID生成策略，未配置时请求ID使用雪花ID、文件名保持随机
+func (app *App) configureIDGenerator() {
+	typ := app.cfg.ModConfig.IDGenerator.Type
+	if typ == "" {
+		return
+	}
+	generator, err := app.newIDGenerator(typ)
+	if err != nil {
+		app.logger.WithError(err).Warn("Invalid id_generator config, using default strategy")
+		return
+	}
+	app.idGenerator = generator
+}
+
+// NextUUIDv7 生成UUIDv7字符串
+func NextUUIDv7() string {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return uuid.NewString()
+	}
+	return id.String()
+}
+
+// crockfordBase32 ULID使用的Crockford Base32字母表
+const crockfordBase32 = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ulidGenerator 单调ULID生成器：同一毫秒内随机部分递增，保证进程内严格有序
+var ulidGenerator struct {
+	sync.Mutex
+	lastMs  uint64
+	entropy [10]byte
+}
+
+// NextULID 生成ULID（48位毫秒时间戳 + 80位随机数）
+func NextULID() string {
+	return nextULIDAt(uint64(time.Now().UnixMilli()))
+}
+
+// nextULIDAt 生成时间戳为 ms 的ULID
+func nextULIDAt(ms uint64) string {
+	g := &ulidGenerator
+	g.Lock()
+	if ms <= g.lastMs {
+		// 时钟未前进或回拨时沿用上一个时间戳并递增随机部分
+		ms = g.lastMs
+		for i := len(g.entropy) - 1; i >= 0; i-- {
+			g.entropy[i]++
+			if g.entropy[i] != 0 {
+				break
+			}
+		}
+	} else {
+		g.lastMs = ms
+		if _, err := rand.Read(g.entropy[:]); err != nil {
+			binary.BigEndian.PutUint64(g.entropy[2:], uint64(time.Now().UnixNano()))
+		}
+	}
+	var id [16]byte
+	id[0] = byte(ms >> 40)
+	id[1] = byte(ms >> 32)
+	id[2] = byte(ms >> 24)
+	id[3] = byte(ms >> 16)
+	id[4] = byte(ms >> 8)
+	id[5] = byte(ms)
+	copy(id[6:], g.entropy[:])
+	g.Unlock()
+
+	return encodeULID(id)
+}
+
+// encodeULID 将128位ID编码为26位Crockford Base32字符串
+func encodeULID(id [16]byte) string {
+	hi := binary.BigEndian.Uint64(id[:8])
+	lo := binary.BigEndian.Uint64(id[8:])
+
+	var out [26]byte
+	for i := 25; i >= 0; i-- {
+		out[i] = crockfordBase32[lo&0x1f]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out[:])
+}
@@ -0,0 +1,587 @@
+package mod
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultImageQuality   = 85
+	defaultImageMaxPixels = 50_000_000
+)
+
+// ImagePreset 上传图片的尺寸预设
+type ImagePreset struct {
+	Name   string `yaml:"name"`   // 预设名称，返回在 variants[].name 中并作为文件名后缀
+	Width  int    `yaml:"width"`  // 最大宽度，为0时按高度等比缩放
+	Height int    `yaml:"height"` // 最大高度，为0时按宽度等比缩放
+	Fit    string `yaml:"fit"`    // contain（默认，等比缩放到宽高以内）、cover（等比缩放并居中裁剪为宽高比例）
+	Format string `yaml:"format"` // 输出格式，默认使用 image.format，未设置时与原图一致
+}
+
+// FileVariant 上传图片的派生版本（缩略图、尺寸预设、格式转换），与原图保存在同一存储后端
+type FileVariant struct {
+	Name      string `json:"name" desc:"派生版本名称，如 thumbnail 或转换后的格式 webp"`
+	Filename  string `json:"filename" desc:"存储文件名"`
+	Bucket    string `json:"bucket,omitempty" desc:"存储桶"`
+	ObjectKey string `json:"object_key" desc:"对象键（本地存储为文件路径）"`
+	URL       string `json:"url" desc:"访问URL"`
+	MIME      string `json:"mime" desc:"图片MIME类型"`
+	Size      int64  `json:"size" desc:"文件大小（字节）"`
+	Width     int    `json:"width" desc:"宽度（像素）"`
+	Height    int    `json:"height" desc:"高度（像素）"`
+}
+
+// ImageEncoder 将图片编码为注册的格式，quality 为 1-100 的质量，无损格式可以忽略
+type ImageEncoder func(w io.Writer, img image.Image, quality int) error
+
+// imageEncoding 图片格式的编码器、MIME类型和扩展名
+type imageEncoding struct {
+	mime   string
+	ext    string
+	encode ImageEncoder
+}
+
+var (
+	imageEncodersMu sync.RWMutex
+	imageEncoders   = map[string]imageEncoding{
+		"jpeg": {mime: "image/jpeg", ext: ".jpg", encode: func(w io.Writer, img image.Image, quality int) error {
+			return jpeg.Encode(w, img, &jpeg.Options{Quality: quality})
+		}},
+		"png": {mime: "image/png", ext: ".png", encode: func(w io.Writer, img image.Image, quality int) error {
+			return png.Encode(w, img)
+		}},
+		"gif": {mime: "image/gif", ext: ".gif", encode: func(w io.Writer, img image.Image, quality int) error {
+			return gif.Encode(w, img, nil)
+		}},
+	}
+)
+
+// RegisterImageEncoder 注册图片格式的编码器，用于 file_upload.image 的格式转换，须在 New() 之前调用。
+// 内置 jpeg、png、gif；标准库没有 WebP 编码器，转换为 webp 时需要注册，如
+// mod.RegisterImageEncoder("webp", "image/webp", func(w io.Writer, img image.Image, q int) error { return webp.Encode(w, img, &webp.Options{Quality: float32(q)}) })
+func RegisterImageEncoder(format, mimeType string, encoder ImageEncoder) {
+	imageEncodersMu.Lock()
+	defer imageEncodersMu.Unlock()
+	format = strings.ToLower(format)
+	imageEncoders[format] = imageEncoding{mime: mimeType, ext: "." + format, encode: encoder}
+}
+
+// lookupImageEncoder 返回格式的编码器，jpg 视为 jpeg
+func lookupImageEncoder(format string) (imageEncoding, bool) {
+	format = strings.ToLower(format)
+	if format == "jpg" {
+		format = "jpeg"
+	}
+	imageEncodersMu.RLock()
+	defer imageEncodersMu.RUnlock()
+	encoding, ok := imageEncoders[format]
+	return encoding, ok
+}
+
+// imageVariant 待保存的派生图片
+type imageVariant struct {
+	name   string
+	file   *multipart.FileHeader
+	mime   string
+	width  int
+	height int
+}
+
+// uploadImage 处理后的上传图片
+type uploadImage struct {
+	width    int
+	height   int
+	variants []imageVariant
+}
+
+// checkImageConfig 启动时检查图片处理配置，未注册编码器的格式在处理时回退为原图格式
+func (app *App) checkImageConfig() {
+	config := app.cfg.ModConfig.FileUpload.Image
+	if !config.Enabled {
+		return
+	}
+	presets := imagePresets(config.Thumbnail, config.Presets)
+	formats := []string{config.Format}
+	names := map[string]bool{}
+	for _, preset := range presets {
+		formats = append(formats, preset.Format)
+		if names[preset.Name] {
+			app.logger.WithField("preset", preset.Name).Warn("Duplicate image preset name")
+		}
+		names[preset.Name] = true
+		if preset.Fit != "" && preset.Fit != "contain" && preset.Fit != "cover" {
+			app.logger.WithFields(logrus.Fields{"preset": preset.Name, "fit": preset.Fit}).Warn("Unknown image preset fit, using contain")
+		}
+	}
+	for _, format := range formats {
+		if _, ok := lookupImageEncoder(format); format != "" && !ok {
+			app.logger.WithField("format", format).Warn("No image encoder registered for format, register one with mod.RegisterImageEncoder; images keep their original format")
+		}
+	}
+	app.logger.WithFields(logrus.Fields{
+		"strip_exif": config.StripEXIF,
+		"format":     config.Format,
+		"presets":    len(presets),
+	}).Info("Image processing configured")
+}
+
+// imagePresets 返回缩略图（设置了宽或高时）和尺寸预设，缩略图的名称默认为 thumbnail
+func imagePresets(thumbnail ImagePreset, presets []ImagePreset) []ImagePreset {
+	var all []ImagePreset
+	if thumbnail.Width > 0 || thumbnail.Height > 0 {
+		if thumbnail.Name == "" {
+			thumbnail.Name = "thumbnail"
+		}
+		all = append(all, thumbnail)
+	}
+	for _, preset := range presets {
+		if preset.Width > 0 || preset.Height > 0 {
+			all = append(all, preset)
+		}
+	}
+	return all
+}
+
+// prepareUploadImage 按 file_upload.image 配置处理上传的图片：去除原图的EXIF，生成缩略图、尺寸预设和格式转换版本。
+// 返回要保存的原图；无法识别为图片的文件原样返回，img 为 nil
+func (app *App) prepareUploadImage(file *multipart.FileHeader) (*multipart.FileHeader, *uploadImage, error) {
+	config := app.cfg.ModConfig.FileUpload.Image
+	if !config.Enabled {
+		return file, nil, nil
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open uploaded file: %v", err)
+	}
+	data, err := io.ReadAll(src)
+	src.Close()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read uploaded file: %v", err)
+	}
+
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return file, nil, nil
+	}
+	maxPixels := config.MaxPixels
+	if maxPixels <= 0 {
+		maxPixels = defaultImageMaxPixels
+	}
+	if cfg.Width*cfg.Height > maxPixels {
+		return nil, nil, fmt.Errorf("图片像素数 %dx%d 超过限制 %d", cfg.Width, cfg.Height, maxPixels)
+	}
+	quality := config.Quality
+	if quality <= 0 || quality > 100 {
+		quality = defaultImageQuality
+	}
+
+	decoded, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, fmt.Errorf("图片解码失败: %v", err)
+	}
+	orientation := 1
+	if format == "jpeg" {
+		orientation = jpegOrientation(data)
+	}
+	img := orientImage(toRGBA(decoded), orientation)
+	bounds := img.Bounds()
+	result := &uploadImage{width: bounds.Dx(), height: bounds.Dy()}
+
+	// 去除原图的元数据：需要按方向旋转的照片重新编码，否则直接删除元数据段，不损失画质
+	if config.StripEXIF {
+		var stripped []byte
+		switch {
+		case format == "jpeg" && orientation != 1:
+			if stripped, err = encodeImage(img, "jpeg", quality); err != nil {
+				return nil, nil, fmt.Errorf("图片编码失败: %v", err)
+			}
+		case format == "jpeg":
+			stripped = stripJPEGMetadata(data)
+		case format == "png":
+			stripped = stripPNGMetadata(data)
+		}
+		if stripped != nil {
+			if file, err = memoryFileHeader(file.Filename, file.Header.Get(fiber.HeaderContentType), stripped); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	base := strings.TrimSuffix(file.Filename, filepath.Ext(file.Filename))
+	addVariant := func(name string, variant *image.RGBA, targetFormat string) error {
+		encoding, ok := lookupImageEncoder(targetFormat)
+		if !ok {
+			targetFormat = format
+			if encoding, ok = lookupImageEncoder(format); !ok {
+				// 通过第三方解码器识别的格式没有对应的编码器时使用 png
+				targetFormat = "png"
+				encoding, _ = lookupImageEncoder(targetFormat)
+			}
+		}
+		encoded, err := encodeImage(variant, targetFormat, quality)
+		if err != nil {
+			return fmt.Errorf("图片编码失败: %v", err)
+		}
+		header, err := memoryFileHeader(base+"_"+name+encoding.ext, encoding.mime, encoded)
+		if err != nil {
+			return err
+		}
+		b := variant.Bounds()
+		result.variants = append(result.variants, imageVariant{name: name, file: header, mime: encoding.mime, width: b.Dx(), height: b.Dy()})
+		return nil
+	}
+
+	// 原尺寸的格式转换版本
+	if target := strings.ToLower(config.Format); target != "" && target != format && !(target == "jpg" && format == "jpeg") {
+		if _, ok := lookupImageEncoder(target); ok {
+			if err := addVariant(target, img, target); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	for _, preset := range imagePresets(config.Thumbnail, config.Presets) {
+		target := preset.Format
+		if target == "" {
+			target = config.Format
+		}
+		if target == "" {
+			target = format
+		}
+		if err := addVariant(preset.Name, resizeImage(img, preset), target); err != nil {
+			return nil, nil, err
+		}
+	}
+	return file, result, nil
+}
+
+// saveImageVariants 将派生图片保存到原图所在的后端，写入上传结果的 width、height 和 variants；
+// 任一派生图片保存失败时删除已保存的派生图片
+func (app *App) saveImageVariants(img *uploadImage, backend string, result fiber.Map) error {
+	if img == nil {
+		return nil
+	}
+	result["width"] = img.width
+	result["height"] = img.height
+	variants := make([]FileVariant, 0, len(img.variants))
+	for _, v := range img.variants {
+		saved, err := app.saveUploadFile(v.file, backend)
+		if err != nil {
+			for _, variant := range variants {
+				meta := &FileMetadata{Backend: backend, Bucket: variant.Bucket, ObjectKey: variant.ObjectKey}
+				if err := app.deleteStoredObject(meta); err != nil {
+					app.logger.WithError(err).WithField("variant", variant.Name).Error("Failed to clean up image variant")
+				}
+			}
+			return fmt.Errorf("failed to save image variant %s: %w", v.name, err)
+		}
+		meta := &FileMetadata{}
+		meta.applyUploadResult(saved)
+		variants = append(variants, FileVariant{
+			Name:      v.name,
+			Filename:  meta.Filename,
+			Bucket:    meta.Bucket,
+			ObjectKey: meta.ObjectKey,
+			URL:       meta.URL,
+			MIME:      v.mime,
+			Size:      v.file.Size,
+			Width:     v.width,
+			Height:    v.height,
+		})
+	}
+	result["variants"] = variants
+	return nil
+}
+
+// memoryFileHeader 将内存中的内容包装为 multipart.FileHeader，以便复用各存储后端的保存逻辑
+func memoryFileHeader(filename, contentType string, data []byte) (*multipart.FileHeader, error) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	header := textproto.MIMEHeader{}
+	header.Set(fiber.HeaderContentDisposition, mime.FormatMediaType("form-data", map[string]string{"name": "file", "filename": filename}))
+	if contentType != "" {
+		header.Set(fiber.HeaderContentType, contentType)
+	}
+	part, err := writer.CreatePart(header)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := part.Write(data); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	form, err := multipart.NewReader(&body, writer.Boundary()).ReadForm(int64(len(data)) + 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(form.File["file"]) == 0 {
+		return nil, errors.New("failed to build file header")
+	}
+	return form.File["file"][0], nil
+}
+
+// encodeImage 按格式编码图片，不支持透明的 jpeg 以白色背景合成
+func encodeImage(img *image.RGBA, format string, quality int) ([]byte, error) {
+	encoding, ok := lookupImageEncoder(format)
+	if !ok {
+		return nil, fmt.Errorf("no image encoder registered for %s", format)
+	}
+	var target image.Image = img
+	if encoding.mime == "image/jpeg" && !img.Opaque() {
+		flattened := image.NewRGBA(img.Bounds())
+		draw.Draw(flattened, flattened.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+		draw.Draw(flattened, flattened.Bounds(), img, img.Bounds().Min, draw.Over)
+		target = flattened
+	}
+	var buf bytes.Buffer
+	if err := encoding.encode(&buf, target, quality); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// toRGBA 将图片转换为从 (0,0) 开始的 RGBA
+func toRGBA(img image.Image) *image.RGBA {
+	b := img.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), img, b.Min, draw.Src)
+	return rgba
+}
+
+// resizeImage 按预设缩小图片，不放大；cover 先居中裁剪为预设的宽高比例
+func resizeImage(img *image.RGBA, preset ImagePreset) *image.RGBA {
+	sw, sh := img.Bounds().Dx(), img.Bounds().Dy()
+	w, h := preset.Width, preset.Height
+	if w <= 0 {
+		w = max(1, sw*h/sh)
+	}
+	if h <= 0 {
+		h = max(1, sh*w/sw)
+	}
+
+	if preset.Fit == "cover" {
+		// 裁剪为目标比例的最大居中区域
+		cw, ch := sw, sw*h/w
+		if ch > sh {
+			cw, ch = sh*w/h, sh
+		}
+		x0, y0 := (sw-cw)/2, (sh-ch)/2
+		img = img.SubImage(image.Rect(x0, y0, x0+cw, y0+ch)).(*image.RGBA)
+		sw, sh = cw, ch
+		if w > sw || h > sh {
+			w, h = sw, sh
+		}
+	} else if w*sh > h*sw {
+		w = max(1, sw*h/sh)
+	} else {
+		h = max(1, sh*w/sw)
+	}
+	if w >= sw && h >= sh {
+		return toRGBA(img)
+	}
+	return scaleRGBA(img, w, h)
+}
+
+// scaleRGBA 按区域平均（box filter）缩小图片，用于缩略图时比最近邻采样平滑
+func scaleRGBA(src *image.RGBA, w, h int) *image.RGBA {
+	sb := src.Bounds()
+	sw, sh := sb.Dx(), sb.Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		y0 := y * sh / h
+		y1 := max((y+1)*sh/h, y0+1)
+		for x := 0; x < w; x++ {
+			x0 := x * sw / w
+			x1 := max((x+1)*sw/w, x0+1)
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				i := src.PixOffset(sb.Min.X+x0, sb.Min.Y+sy)
+				for sx := x0; sx < x1; sx++ {
+					r += uint64(src.Pix[i])
+					g += uint64(src.Pix[i+1])
+					b += uint64(src.Pix[i+2])
+					a += uint64(src.Pix[i+3])
+					i += 4
+					n++
+				}
+			}
+			o := dst.PixOffset(x, y)
+			dst.Pix[o] = uint8(r / n)
+			dst.Pix[o+1] = uint8(g / n)
+			dst.Pix[o+2] = uint8(b / n)
+			dst.Pix[o+3] = uint8(a / n)
+		}
+	}
+	return dst
+}
+
+// orientImage 按 EXIF Orientation（1-8）旋转或翻转图片，使其按正常方向显示
+func orientImage(img *image.RGBA, orientation int) *image.RGBA {
+	if orientation < 2 || orientation > 8 {
+		return img
+	}
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		for x := 0; x < dw; x++ {
+			var sx, sy int
+			switch orientation {
+			case 2:
+				sx, sy = w-1-x, y
+			case 3:
+				sx, sy = w-1-x, h-1-y
+			case 4:
+				sx, sy = x, h-1-y
+			case 5:
+				sx, sy = y, x
+			case 6:
+				sx, sy = y, h-1-x
+			case 7:
+				sx, sy = w-1-y, h-1-x
+			case 8:
+				sx, sy = w-1-y, x
+			}
+			copy(dst.Pix[dst.PixOffset(x, y):dst.PixOffset(x, y)+4], img.Pix[img.PixOffset(sx, sy):img.PixOffset(sx, sy)+4])
+		}
+	}
+	return dst
+}
+
+// jpegSegments 遍历 JPEG 在图像数据（SOS）之前的标记段，fn 返回 false 时停止；返回 SOS 的位置，格式无效时返回 -1
+func jpegSegments(data []byte, fn func(marker byte, start, end int) bool) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return -1
+	}
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xFF {
+			return -1
+		}
+		marker := data[i+1]
+		if marker == 0xFF {
+			i++
+			continue
+		}
+		if marker == 0xDA {
+			return i
+		}
+		end := i + 2 + int(binary.BigEndian.Uint16(data[i+2:i+4]))
+		if end > len(data) {
+			return -1
+		}
+		if !fn(marker, i, end) {
+			return i
+		}
+		i = end
+	}
+	return -1
+}
+
+// jpegOrientation 读取 JPEG 的 EXIF Orientation，不存在或无法解析时返回1
+func jpegOrientation(data []byte) int {
+	orientation := 1
+	jpegSegments(data, func(marker byte, start, end int) bool {
+		segment := data[start+4 : end]
+		if marker != 0xE1 || !bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return true
+		}
+		tiff := segment[6:]
+		if len(tiff) < 8 {
+			return false
+		}
+		var order binary.ByteOrder
+		switch string(tiff[:2]) {
+		case "II":
+			order = binary.LittleEndian
+		case "MM":
+			order = binary.BigEndian
+		default:
+			return false
+		}
+		ifd := int(order.Uint32(tiff[4:8]))
+		if ifd+2 > len(tiff) {
+			return false
+		}
+		count := int(order.Uint16(tiff[ifd:]))
+		for i := 0; i < count; i++ {
+			entry := ifd + 2 + i*12
+			if entry+12 > len(tiff) {
+				break
+			}
+			if order.Uint16(tiff[entry:]) == 0x0112 {
+				orientation = int(order.Uint16(tiff[entry+8:]))
+				break
+			}
+		}
+		return false
+	})
+	return orientation
+}
+
+// stripJPEGMetadata 删除 JPEG 的 EXIF/XMP（APP1）、IPTC（APP13）和注释段，保留 JFIF、ICC 色彩配置和 Adobe 段；
+// 格式无效时返回 nil
+func stripJPEGMetadata(data []byte) []byte {
+	out := make([]byte, 0, len(data))
+	out = append(out, data[:2]...)
+	sos := jpegSegments(data, func(marker byte, start, end int) bool {
+		if marker != 0xE1 && marker != 0xED && marker != 0xFE {
+			out = append(out, data[start:end]...)
+		}
+		return true
+	})
+	if sos < 0 {
+		return nil
+	}
+	return append(out, data[sos:]...)
+}
+
+// stripPNGMetadata 删除 PNG 的 eXIf、tEXt、zTXt、iTXt 和 tIME 块；格式无效时返回 nil
+func stripPNGMetadata(data []byte) []byte {
+	const signature = "\x89PNG\r\n\x1a\n"
+	if !bytes.HasPrefix(data, []byte(signature)) {
+		return nil
+	}
+	out := make([]byte, 0, len(data))
+	out = append(out, signature...)
+	for i := len(signature); i < len(data); {
+		if i+12 > len(data) {
+			return nil
+		}
+		end := i + 12 + int(binary.BigEndian.Uint32(data[i:i+4]))
+		if end > len(data) || end < i {
+			return nil
+		}
+		switch string(data[i+4 : i+8]) {
+		case "eXIf", "tEXt", "zTXt", "iTXt", "tIME":
+		default:
+			out = append(out, data[i:end]...)
+		}
+		i = end
+	}
+	return out
+}
@@ -0,0 +1,583 @@
+package mod
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+	"github.com/valyala/fasthttp"
+)
+
+// 异步任务状态
+const (
+	JobQueued    = "queued"    // 等待执行
+	JobRunning   = "running"   // 执行中
+	JobSucceeded = "succeeded" // 执行成功，可获取结果
+	JobFailed    = "failed"    // 执行失败，结果为错误响应
+)
+
+// 异步任务的存储后端
+const (
+	JobBackendMemory = "memory" // 进程内存（默认），重启后未完成的任务丢失
+	JobBackendRedis  = "redis"  // 使用 cache.redis 配置的 Redis，多实例共享队列
+)
+
+// errJobQueueFull 内存队列已满
+var errJobQueueFull = errors.New("job queue is full")
+
+// Job 异步任务的状态
+type Job struct {
+	ID         string     `json:"id"`
+	Service    string     `json:"service"`
+	Status     string     `json:"status"`          // queued、running、succeeded、failed
+	Error      string     `json:"error,omitempty"` // 失败原因（错误响应的 msg）
+	CreatedAt  time.Time  `json:"created_at"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// JobAccepted 异步服务的响应（202），通过 status_url 查询状态，完成后通过 result_url 获取处理函数的响应
+type JobAccepted struct {
+	JobID     string `json:"job_id"`
+	Status    string `json:"status"`
+	StatusURL string `json:"status_url"`
+	ResultURL string `json:"result_url"`
+}
+
+// jobRecord 存储的任务：状态、提交者和待执行的请求
+type jobRecord struct {
+	Job
+	Owner   string `json:"owner,omitempty"`   // 提交任务的用户ID，查询状态和结果时须为同一用户
+	Request []byte `json:"request,omitempty"` // 原始请求（HTTP/1.1 格式），开始执行后删除
+}
+
+// jobResult 任务执行后处理函数的响应，原样返回给 result_url 的请求
+type jobResult struct {
+	Status             int    `json:"status"`
+	ContentType        string `json:"content_type"`
+	ContentDisposition string `json:"content_disposition,omitempty"`
+	Body               []byte `json:"body"`
+}
+
+// jobStore 任务队列及任务状态、结果的存储
+type jobStore interface {
+	push(ctx context.Context, rec *jobRecord) error
+	pop(stop <-chan struct{}) (*jobRecord, error) // 阻塞到有任务或 stop 关闭，停止时返回 nil
+	save(ctx context.Context, rec *jobRecord) error
+	load(ctx context.Context, id string) (*jobRecord, error) // 不存在时返回 nil
+	saveResult(ctx context.Context, id string, result *jobResult) error
+	loadResult(ctx context.Context, id string) (*jobResult, error)
+}
+
+// jobReplayKey 工作协程重放任务请求时的标记，值为任务ID
+type jobReplayKey struct{}
+
+// jobState 异步任务的队列和工作协程，挂载的子应用与父应用共用；任务请求由父应用的路由重放
+type jobState struct {
+	root    *App
+	store   jobStore
+	backend string
+	workers int
+	ttl     time.Duration
+
+	startOnce sync.Once
+	stop      chan struct{}
+	wg        sync.WaitGroup
+}
+
+// configureJobs 初始化任务存储，关闭服务时停止工作协程并等待执行中的任务结束
+func (app *App) configureJobs() {
+	config := app.cfg.ModConfig.Jobs
+	state := &jobState{root: app, backend: strings.ToLower(config.Backend), workers: config.Workers, ttl: 24 * time.Hour, stop: make(chan struct{})}
+	if state.workers <= 0 {
+		state.workers = 4
+	}
+	if config.ResultTTL != "" {
+		if d, err := time.ParseDuration(config.ResultTTL); err == nil && d > 0 {
+			state.ttl = d
+		} else {
+			app.logger.WithField("result_ttl", config.ResultTTL).Warn("Invalid jobs result_ttl, using 24h")
+		}
+	}
+	switch state.backend {
+	case JobBackendRedis:
+		if app.redisClient != nil {
+			state.store = &redisJobStore{client: app.redisClient, ttl: state.ttl}
+			break
+		}
+		app.logger.Warn("Jobs backend redis requires cache.redis, using memory")
+		fallthrough
+	default:
+		if state.backend != "" && state.backend != JobBackendMemory && state.backend != JobBackendRedis {
+			app.logger.WithField("backend", config.Backend).Warn("Unknown jobs backend, using memory")
+		}
+		state.backend = JobBackendMemory
+		size := config.QueueSize
+		if size <= 0 {
+			size = 1000
+		}
+		state.store = newMemoryJobStore(size, state.ttl, app.now)
+	}
+	app.jobs = state
+	app.Hooks().OnShutdown(func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), app.shutdownTimeout())
+		defer cancel()
+		return state.shutdown(ctx)
+	})
+}
+
+// start 首次注册异步服务时注册任务状态和结果路由并启动工作协程
+func (s *jobState) start() {
+	s.startOnce.Do(func() {
+		app := s.root
+		base := app.cfg.ModConfig.App.ServiceBase
+		app.Get(base+"/jobs/status", app.handleJobStatus)
+		app.Get(base+"/jobs/result", app.handleJobResult)
+		for i := 0; i < s.workers; i++ {
+			s.wg.Add(1)
+			go s.work()
+		}
+		app.logger.WithFields(logrus.Fields{"backend": s.backend, "workers": s.workers}).Info("Job workers started")
+	})
+}
+
+// shutdown 停止领取新任务，等待执行中的任务结束
+func (s *jobState) shutdown(ctx context.Context) error {
+	select {
+	case <-s.stop:
+	default:
+		close(s.stop)
+	}
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("jobs still running after shutdown timeout: %w", ctx.Err())
+	}
+}
+
+// work 工作协程：依次领取并执行任务
+func (s *jobState) work() {
+	defer s.wg.Done()
+	for {
+		rec, err := s.store.pop(s.stop)
+		if err != nil {
+			s.root.logger.WithError(err).Error("Failed to fetch job")
+			select {
+			case <-s.stop:
+				return
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+		if rec == nil {
+			return
+		}
+		s.run(rec)
+	}
+}
+
+// run 重放任务请求并保存响应
+func (s *jobState) run(rec *jobRecord) {
+	app := s.root
+	ctx := context.Background()
+	started := app.now()
+	request := rec.Request
+	rec.Status, rec.StartedAt, rec.Request = JobRunning, &started, nil
+	if err := s.store.save(ctx, rec); err != nil {
+		app.logger.WithError(err).WithField("job", rec.ID).Error("Failed to update job")
+	}
+
+	result, err := s.execute(rec.ID, request)
+	finished := app.now()
+	rec.FinishedAt = &finished
+	switch {
+	case err != nil:
+		rec.Status, rec.Error = JobFailed, err.Error()
+	case result.Status >= 400:
+		rec.Status, rec.Error = JobFailed, jobErrorMessage(result)
+	default:
+		rec.Status = JobSucceeded
+	}
+	if result != nil {
+		if err := s.store.saveResult(ctx, rec.ID, result); err != nil {
+			app.logger.WithError(err).WithField("job", rec.ID).Error("Failed to save job result")
+		}
+	}
+	if err := s.store.save(ctx, rec); err != nil {
+		app.logger.WithError(err).WithField("job", rec.ID).Error("Failed to update job")
+	}
+	app.recordJob(rec.Service, rec.Status)
+
+	fields := logrus.Fields{"job": rec.ID, "service": rec.Service, "status": rec.Status, "duration": time.Since(started).String()}
+	if rec.Error != "" {
+		fields["error"] = rec.Error
+	}
+	app.logger.WithFields(fields).Info("Job finished")
+}
+
+// execute 通过父应用的路由重放请求，中间件、路径参数和身份验证与同步调用相同；
+// 处理函数通过 ctx.SendCSV 等方法流式发送的文件被完整读取后保存
+func (s *jobState) execute(id string, raw []byte) (result *jobResult, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			result, err = nil, fmt.Errorf("panic: %v", r)
+		}
+	}()
+	var req fasthttp.Request
+	if err := req.Read(bufio.NewReader(bytes.NewReader(raw))); err != nil {
+		return nil, fmt.Errorf("invalid job request: %w", err)
+	}
+	var fctx fasthttp.RequestCtx
+	fctx.Init(&req, nil, nil)
+	fctx.SetUserValue(jobReplayKey{}, id)
+	s.root.Handler()(&fctx)
+
+	resp := &fctx.Response
+	return &jobResult{
+		Status:             resp.StatusCode(),
+		ContentType:        string(resp.Header.ContentType()),
+		ContentDisposition: string(resp.Header.Peek(fiber.HeaderContentDisposition)),
+		Body:               append([]byte(nil), resp.Body()...),
+	}, nil
+}
+
+// jobErrorMessage 失败任务的原因：标准错误响应的 msg，其他响应使用状态码说明
+func jobErrorMessage(result *jobResult) string {
+	var resp ApiResponse
+	if json.Unmarshal(result.Body, &resp) == nil && resp.Msg != "" {
+		return resp.Msg
+	}
+	return http.StatusText(result.Status)
+}
+
+// JobID 当前请求作为异步任务执行时返回任务ID，否则返回空字符串
+func (c *Context) JobID() string {
+	id, _ := c.Locals(jobReplayKey{}).(string)
+	return id
+}
+
+// enqueueJob 保存已通过校验的请求并加入任务队列，返回任务ID及查询地址
+func (app *App) enqueueJob(ctx *Context, svc *Service) (*JobAccepted, error) {
+	// 重放时沿用提交请求的请求ID，便于关联日志
+	ctx.Request().Header.Set("X-Request-ID", ctx.GetRequestID())
+	// 重放的 span 作为提交请求 span 的子 span，并带上提交时的 baggage
+	tracePropagator.Inject(ctx.UserContext(), requestHeaderCarrier{&ctx.Request().Header})
+	var raw bytes.Buffer
+	if _, err := ctx.Request().WriteTo(&raw); err != nil {
+		return nil, ReplyWithDetail(500, "Failed to enqueue job", err.Error())
+	}
+	rec := &jobRecord{
+		Job:     Job{ID: uuid.NewString(), Service: svc.Name, Status: JobQueued, CreatedAt: app.now()},
+		Request: raw.Bytes(),
+	}
+	if user, ok := ctx.User(); ok {
+		rec.Owner = user.ID
+	}
+	if err := app.jobs.store.push(ctx.UserContext(), rec); err != nil {
+		app.logger.WithFields(logrus.Fields{"service": svc.Name, "rid": ctx.GetRequestID(), "error": err.Error()}).Error("Failed to enqueue job")
+		if errors.Is(err, errJobQueueFull) {
+			return nil, Reply(503, "Job queue is full")
+		}
+		return nil, ReplyWithDetail(500, "Failed to enqueue job", err.Error())
+	}
+	app.logger.WithFields(logrus.Fields{"service": svc.Name, "job": rec.ID, "rid": ctx.GetRequestID()}).Info("Job enqueued")
+
+	base := app.jobs.root.cfg.ModConfig.App.ServiceBase
+	return &JobAccepted{
+		JobID:     rec.ID,
+		Status:    JobQueued,
+		StatusURL: base + "/jobs/status?id=" + rec.ID,
+		ResultURL: base + "/jobs/result?id=" + rec.ID,
+	}, nil
+}
+
+// authorizeJob 查找请求的任务，并按任务所属服务的认证方式校验请求；任务有提交者时只允许同一用户查询
+func (app *App) authorizeJob(ctx *Context) (*jobRecord, error) {
+	id := ctx.Query("id")
+	if id == "" {
+		return nil, Reply(400, "Missing job id")
+	}
+	rec, err := app.jobs.store.load(ctx.UserContext(), id)
+	if err != nil {
+		return nil, ReplyWithDetail(500, "Failed to load job", err.Error())
+	}
+	if rec == nil {
+		return nil, Reply(404, "Job not found")
+	}
+	svc, ok := app.GetService(rec.Service)
+	if !ok {
+		return nil, Reply(404, "Job not found")
+	}
+	ctx.service = &svc
+	if _, err := app.authenticate(ctx, &svc, app.authStrategy(&svc)); err != nil {
+		return nil, err
+	}
+	if rec.Owner != "" {
+		// 不向其他用户暴露任务是否存在
+		if user, ok := ctx.User(); !ok || user.ID != rec.Owner {
+			return nil, Reply(404, "Job not found")
+		}
+	}
+	return rec, nil
+}
+
+// handleJobStatus 查询任务状态
+func (app *App) handleJobStatus(fc *fiber.Ctx) error {
+	ctx := &Context{Ctx: fc, logger: app.logger, app: app}
+	rec, err := app.authorizeJob(ctx)
+	if err != nil {
+		return replyError(ctx, err)
+	}
+	return fc.JSON(NewSuccessResponse(ctx, rec.Job))
+}
+
+// handleJobResult 返回已完成任务的响应（状态码、Content-Type 和响应体与同步调用相同），未完成时响应409
+func (app *App) handleJobResult(fc *fiber.Ctx) error {
+	ctx := &Context{Ctx: fc, logger: app.logger, app: app}
+	result, err := app.jobResult(ctx)
+	if err != nil {
+		return replyError(ctx, err)
+	}
+	fc.Status(result.Status)
+	fc.Set(fiber.HeaderContentType, result.ContentType)
+	if result.ContentDisposition != "" {
+		fc.Set(fiber.HeaderContentDisposition, result.ContentDisposition)
+	}
+	return fc.Send(result.Body)
+}
+
+// jobResult 读取已完成任务的响应
+func (app *App) jobResult(ctx *Context) (*jobResult, error) {
+	rec, err := app.authorizeJob(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if rec.Status != JobSucceeded && rec.Status != JobFailed {
+		return nil, ReplyWithDetail(409, "Job is not finished", "job status is "+rec.Status)
+	}
+	result, err := app.jobs.store.loadResult(ctx.UserContext(), rec.ID)
+	if err != nil {
+		return nil, ReplyWithDetail(500, "Failed to load job result", err.Error())
+	}
+	if result == nil {
+		return nil, Reply(404, "Job result not found")
+	}
+	return result, nil
+}
+
+// memoryJobStore 进程内存中的任务队列，完成的任务保留 result_ttl 后删除
+type memoryJobStore struct {
+	pending chan string
+	ttl     time.Duration
+	now     func() time.Time
+
+	mu        sync.Mutex
+	records   map[string]jobRecord
+	results   map[string]*jobResult
+	expires   map[string]time.Time
+	lastSweep time.Time
+}
+
+func newMemoryJobStore(size int, ttl time.Duration, now func() time.Time) *memoryJobStore {
+	return &memoryJobStore{
+		pending: make(chan string, size),
+		ttl:     ttl,
+		now:     now,
+		records: map[string]jobRecord{},
+		results: map[string]*jobResult{},
+		expires: map[string]time.Time{},
+	}
+}
+
+func (s *memoryJobStore) push(_ context.Context, rec *jobRecord) error {
+	s.mu.Lock()
+	s.sweep()
+	s.records[rec.ID] = *rec
+	s.mu.Unlock()
+	select {
+	case s.pending <- rec.ID:
+		return nil
+	default:
+		s.mu.Lock()
+		delete(s.records, rec.ID)
+		s.mu.Unlock()
+		return errJobQueueFull
+	}
+}
+
+func (s *memoryJobStore) pop(stop <-chan struct{}) (*jobRecord, error) {
+	for {
+		select {
+		case <-stop:
+			return nil, nil
+		case id := <-s.pending:
+			if rec, _ := s.load(context.Background(), id); rec != nil {
+				return rec, nil
+			}
+		}
+	}
+}
+
+func (s *memoryJobStore) save(_ context.Context, rec *jobRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records[rec.ID] = *rec
+	if rec.FinishedAt != nil {
+		s.expires[rec.ID] = s.now().Add(s.ttl)
+	}
+	return nil
+}
+
+func (s *memoryJobStore) load(_ context.Context, id string) (*jobRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rec, ok := s.records[id]
+	if !ok || s.expired(id) {
+		return nil, nil
+	}
+	return &rec, nil
+}
+
+func (s *memoryJobStore) saveResult(_ context.Context, id string, result *jobResult) error {
+	s.mu.Lock()
+	s.results[id] = result
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *memoryJobStore) loadResult(_ context.Context, id string) (*jobResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.expired(id) {
+		return nil, nil
+	}
+	return s.results[id], nil
+}
+
+// expired 完成的任务是否已超过保留时间
+func (s *memoryJobStore) expired(id string) bool {
+	expires, ok := s.expires[id]
+	return ok && !s.now().Before(expires)
+}
+
+// sweep 每分钟最多一次删除超过保留时间的任务
+func (s *memoryJobStore) sweep() {
+	now := s.now()
+	if now.Sub(s.lastSweep) < time.Minute {
+		return
+	}
+	s.lastSweep = now
+	for id, expires := range s.expires {
+		if !now.Before(expires) {
+			delete(s.records, id)
+			delete(s.results, id)
+			delete(s.expires, id)
+		}
+	}
+}
+
+// redisJobStore Redis 中的任务队列（列表），任务状态和结果保留 result_ttl
+type redisJobStore struct {
+	client *redis.Client
+	ttl    time.Duration
+}
+
+const redisJobQueue = "mod:jobs:queue"
+
+func redisJobKey(id string) string {
+	return "mod:jobs:" + id
+}
+
+func (s *redisJobStore) push(ctx context.Context, rec *jobRecord) error {
+	if err := s.save(ctx, rec); err != nil {
+		return err
+	}
+	return s.client.RPush(ctx, redisJobQueue, rec.ID).Err()
+}
+
+func (s *redisJobStore) pop(stop <-chan struct{}) (*jobRecord, error) {
+	for {
+		select {
+		case <-stop:
+			return nil, nil
+		default:
+		}
+		values, err := s.client.BLPop(context.Background(), time.Second, redisJobQueue).Result()
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		rec, err := s.load(context.Background(), values[1])
+		if err != nil {
+			return nil, err
+		}
+		if rec != nil {
+			return rec, nil
+		}
+	}
+}
+
+func (s *redisJobStore) save(ctx context.Context, rec *jobRecord) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	return s.client.Set(ctx, redisJobKey(rec.ID), data, s.ttl).Err()
+}
+
+func (s *redisJobStore) load(ctx context.Context, id string) (*jobRecord, error) {
+	data, err := s.client.Get(ctx, redisJobKey(id)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	rec := &jobRecord{}
+	if err := json.Unmarshal(data, rec); err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
+
+func (s *redisJobStore) saveResult(ctx context.Context, id string, result *jobResult) error {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	return s.client.Set(ctx, redisJobKey(id)+":result", data, s.ttl).Err()
+}
+
+func (s *redisJobStore) loadResult(ctx context.Context, id string) (*jobResult, error) {
+	data, err := s.client.Get(ctx, redisJobKey(id)+":result").Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	result := &jobResult{}
+	if err := json.Unmarshal(data, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -56,11 +56,12 @@ func (j *JWTManager) GenerateTokens(userID, username, email, role string, extra
 	}
 
 	jwtConfig := j.config.Token.JWT
-	if jwtConfig.SecretKey == "" {
-		return nil, errors.New("JWT secret key is not configured")
+	key, err := j.app.Key(KeyJWT)
+	if err != nil {
+		return nil, fmt.Errorf("JWT secret key is not configured: %w", err)
 	}
 
-	now := time.Now()
+	now := j.app.Clock().Now()
 
 	// Parse expiration durations
 	accessExpire, err := time.ParseDuration(jwtConfig.ExpireDuration)
@@ -91,7 +92,7 @@ func (j *JWTManager) GenerateTokens(userID, username, email, role string, extra
 		},
 	}
 
-	accessToken, err := j.generateToken(accessClaims, jwtConfig.SecretKey, jwtConfig.Algorithm)
+	accessToken, err := j.generateToken(accessClaims, key, jwtConfig.Algorithm)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate access token: %w", err)
 	}
@@ -111,7 +112,7 @@ func (j *JWTManager) GenerateTokens(userID, username, email, role string, extra
 		},
 	}
 
-	refreshToken, err := j.generateToken(refreshClaims, jwtConfig.SecretKey, jwtConfig.Algorithm)
+	refreshToken, err := j.generateToken(refreshClaims, key, jwtConfig.Algorithm)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate refresh token: %w", err)
 	}
@@ -141,8 +142,9 @@ func (j *JWTManager) ValidateToken(tokenString string) (*JWTClaims, error) {
 	}
 
 	jwtConfig := j.config.Token.JWT
-	if jwtConfig.SecretKey == "" {
-		return nil, errors.New("JWT secret key is not configured")
+	keys, err := j.app.keys.versions(KeyJWT)
+	if err != nil {
+		return nil, fmt.Errorf("JWT secret key is not configured: %w", err)
 	}
 
 	// Parse and validate token
@@ -152,8 +154,17 @@ func (j *JWTManager) ValidateToken(tokenString string) (*JWTClaims, error) {
 		if token.Method != expectedMethod {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
-		return []byte(jwtConfig.SecretKey), nil
-	})
+		// kid 对应的密钥版本仍保留时直接使用，否则依次尝试当前密钥和轮换前的旧密钥
+		kid, _ := token.Header["kid"].(string)
+		set := jwt.VerificationKeySet{}
+		for _, key := range keys {
+			if key.Version == kid {
+				return key.Material, nil
+			}
+			set.Keys = append(set.Keys, key.Material)
+		}
+		return set, nil
+	}, jwt.WithTimeFunc(j.app.Clock().Now))
 
 	if err != nil {
 		j.logger.WithError(err).Debug("Token validation failed")
@@ -213,7 +224,7 @@ func (j *JWTManager) RevokeToken(tokenString string) error {
 
 		// Store in cache until token expires
 		err := j.app.SetToken(blacklistKey, map[string]any{
-			"revoked_at": time.Now(),
+			"revoked_at": j.app.Clock().Now(),
 			"user_id":    claims.UserID,
 		})
 		if err != nil {
@@ -245,11 +256,12 @@ func (j *JWTManager) IsTokenBlacklisted(tokenString string) bool {
 	return err == nil // Token exists in blacklist
 }
 
-// generateToken generates a JWT token with the specified claims
-func (j *JWTManager) generateToken(claims *JWTClaims, secretKey, algorithm string) (string, error) {
+// generateToken generates a JWT token with the specified claims, recording the key version in the kid header
+func (j *JWTManager) generateToken(claims *JWTClaims, key *Key, algorithm string) (string, error) {
 	signingMethod := j.getSigningMethod(algorithm)
 	token := jwt.NewWithClaims(signingMethod, claims)
-	return token.SignedString([]byte(secretKey))
+	token.Header["kid"] = key.Version
+	return token.SignedString(key.Material)
 }
 
 // getSigningMethod returns the appropriate signing method for the algorithm
@@ -38,12 +38,7 @@ func JWTMiddleware(app *App) fiber.Handler {
 		}
 
 		// Store claims in context for later use
-		c.Locals("jwt_claims", claims)
-		c.Locals("jwt_token", tokenString)
-		c.Locals("user_id", claims.UserID)
-		c.Locals("username", claims.Username)
-		c.Locals("user_email", claims.Email)
-		c.Locals("user_role", claims.Role)
+		setJWTLocals(c, claims, tokenString)
 
 		app.logger.WithFields(logrus.Fields{
 			"user_id":  claims.UserID,
@@ -90,12 +85,7 @@ func OptionalJWTMiddleware(app *App) fiber.Handler {
 		}
 
 		// Store claims in context for later use
-		c.Locals("jwt_claims", claims)
-		c.Locals("jwt_token", tokenString)
-		c.Locals("user_id", claims.UserID)
-		c.Locals("username", claims.Username)
-		c.Locals("user_email", claims.Email)
-		c.Locals("user_role", claims.Role)
+		setJWTLocals(c, claims, tokenString)
 
 		app.logger.WithFields(logrus.Fields{
 			"user_id":  claims.UserID,
@@ -107,6 +97,16 @@ func OptionalJWTMiddleware(app *App) fiber.Handler {
 	}
 }
 
+// setJWTLocals stores validated JWT claims in the request locals
+func setJWTLocals(c *fiber.Ctx, claims *JWTClaims, tokenString string) {
+	c.Locals("jwt_claims", claims)
+	c.Locals("jwt_token", tokenString)
+	c.Locals("user_id", claims.UserID)
+	c.Locals("username", claims.Username)
+	c.Locals("user_email", claims.Email)
+	c.Locals("user_role", claims.Role)
+}
+
 // RoleMiddleware creates a role-based authorization middleware
 func RoleMiddleware(requiredRoles ...string) fiber.Handler {
 	return func(c *fiber.Ctx) error {
@@ -0,0 +1,446 @@
+package mod
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// 框架使用的密钥名称，未在 keys.entries 中配置时从原有的配置项读取
+const (
+	KeyJWT                  = "jwt"                    // JWT签名密钥，默认读取 token.jwt.secret_key
+	KeyEncryptionSymmetric  = "encryption.symmetric"   // 服务加解密的对称密钥，默认读取 encryption.symmetric
+	KeyEncryptionSignature  = "encryption.signature"   // 服务加解密的签名密钥，默认读取 encryption.signature
+	KeyEncryptionPublicKey  = "encryption.public_key"  // 服务加解密的RSA公钥（PEM），默认读取 encryption.asymmetric
+	KeyEncryptionPrivateKey = "encryption.private_key" // 服务加解密的RSA私钥（PEM），默认读取 encryption.asymmetric
+	KeyFileEncryption       = "file_upload.local"      // 本地上传文件的加密密钥，默认读取 file_upload.local.encryption
+)
+
+// 密钥提供者
+const (
+	KeyProviderStatic    = "static"     // 配置中的密钥
+	KeyProviderFile      = "file"       // 密钥文件，刷新时重新读取
+	KeyProviderVault     = "vault"      // HashiCorp Vault transit 引擎导出的密钥
+	KeyProviderAliyunKMS = "aliyun_kms" // 阿里云KMS解密的数据密钥
+	KeyProviderAWSKMS    = "aws_kms"    // AWS KMS解密的数据密钥
+)
+
+// ErrKeyNotFound 密钥未配置
+var ErrKeyNotFound = errors.New("key not found")
+
+// Key 密钥材料
+type Key struct {
+	Name     string
+	Version  string // 版本，提供者未返回时使用密钥内容的摘要，密钥内容变化即视为轮换
+	Material []byte
+}
+
+// KeyProvider 密钥来源，加解密、JWT签名等功能通过它获取密钥，返回的密钥由框架缓存并定时刷新
+type KeyProvider interface {
+	GetKey(ctx context.Context, name string) (*Key, error)
+}
+
+// KeyProviderFunc 函数形式的 KeyProvider
+type KeyProviderFunc func(ctx context.Context, name string) (*Key, error)
+
+// GetKey 调用函数获取密钥
+func (f KeyProviderFunc) GetKey(ctx context.Context, name string) (*Key, error) {
+	return f(ctx, name)
+}
+
+// KeyRotation 密钥轮换事件
+type KeyRotation struct {
+	Name       string
+	OldVersion string
+	NewVersion string
+}
+
+// KeyConfig mod.yml 中单个密钥的配置
+type KeyConfig struct {
+	Provider string `yaml:"provider"` // static, file, vault, aliyun_kms, aws_kms
+	Value    string `yaml:"value"`    // static：密钥内容
+	File     string `yaml:"file"`     // file：密钥文件路径
+	Encoding string `yaml:"encoding"` // 密钥内容的编码：raw、base64、hex；static、file 默认 raw，aliyun_kms 默认 base64
+
+	// vault：通过 transit 引擎的 export 接口读取最新版本的密钥，密钥须设置为可导出
+	Vault struct {
+		Address   string `yaml:"address"`    // 地址，默认读取 VAULT_ADDR
+		Token     string `yaml:"token"`      // 访问令牌，默认读取 VAULT_TOKEN
+		TokenFile string `yaml:"token_file"` // 访问令牌文件
+		Namespace string `yaml:"namespace"`  // 命名空间（企业版）
+		Mount     string `yaml:"mount"`      // transit 引擎的挂载路径，默认 transit
+		Key       string `yaml:"key"`        // 密钥名称
+		Type      string `yaml:"type"`       // 导出类型：encryption-key（默认）、hmac-key、signing-key
+	} `yaml:"vault"`
+
+	// aliyun_kms、aws_kms：调用 Decrypt 解密 GenerateDataKey 生成的数据密钥密文
+	KMS struct {
+		Region            string            `yaml:"region"`
+		Endpoint          string            `yaml:"endpoint"`          // 默认按地域生成
+		AccessKeyID       string            `yaml:"access_key_id"`     // 默认读取云厂商的标准环境变量
+		AccessKeySecret   string            `yaml:"access_key_secret"` // AWS 的 Secret Access Key
+		SecurityToken     string            `yaml:"security_token"`    // STS临时凭证
+		CiphertextBlob    string            `yaml:"ciphertext_blob"`   // 数据密钥密文（base64）
+		EncryptionContext map[string]string `yaml:"encryption_context"`
+	} `yaml:"kms"`
+}
+
+// keyEntry 缓存的密钥及轮换前的旧版本
+type keyEntry struct {
+	current  *Key
+	previous []*Key
+}
+
+// keyring 集中管理密钥：按名称选择提供者，缓存获取的密钥，定时刷新并在版本变化时通知
+type keyring struct {
+	mu        sync.RWMutex
+	providers map[string]KeyProvider
+	fallback  KeyProvider
+	entries   map[string]*keyEntry
+	hooks     []func(KeyRotation)
+	keep      int
+	interval  time.Duration
+	logger    *logrus.Logger
+	stop      chan struct{}
+}
+
+// configureKeys 创建密钥提供者，校验 keys.entries 中的密钥并启动定时刷新
+func (app *App) configureKeys() {
+	config := app.cfg.ModConfig.Keys
+	ring := &keyring{
+		providers: map[string]KeyProvider{},
+		fallback:  configKeyProvider{app: app},
+		entries:   map[string]*keyEntry{},
+		keep:      config.KeepVersions,
+		interval:  5 * time.Minute,
+		logger:    app.logger,
+		stop:      make(chan struct{}),
+	}
+	if ring.keep <= 0 {
+		ring.keep = 2
+	}
+	if config.RefreshInterval != "" {
+		if d, err := time.ParseDuration(config.RefreshInterval); err == nil && d > 0 {
+			ring.interval = d
+		} else {
+			app.logger.WithField("refresh_interval", config.RefreshInterval).Warn("Invalid keys refresh_interval, using 5m")
+		}
+	}
+	app.keys = ring
+
+	for name, entry := range config.Entries {
+		start := time.Now()
+		provider, err := newConfiguredKeyProvider(entry)
+		if err == nil {
+			ring.providers[name] = provider
+			_, err = ring.get(name)
+		}
+		app.recordStartup("keys."+name, entry.Provider, start, err)
+	}
+	go ring.run()
+}
+
+// provider 返回名称对应的提供者
+func (r *keyring) provider(name string) KeyProvider {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if provider, ok := r.providers[name]; ok {
+		return provider
+	}
+	return r.fallback
+}
+
+// fetch 从提供者获取密钥，补全名称和版本
+func (r *keyring) fetch(name string) (*Key, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	key, err := r.provider(name).GetKey(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if key == nil || len(key.Material) == 0 {
+		return nil, fmt.Errorf("key %s: %w", name, ErrKeyNotFound)
+	}
+	key.Name = name
+	if key.Version == "" {
+		sum := sha256.Sum256(key.Material)
+		key.Version = hex.EncodeToString(sum[:4])
+	}
+	return key, nil
+}
+
+// get 返回缓存的密钥，首次使用时从提供者获取
+func (r *keyring) get(name string) (*Key, error) {
+	r.mu.RLock()
+	entry, ok := r.entries[name]
+	r.mu.RUnlock()
+	if ok {
+		return entry.current, nil
+	}
+
+	key, err := r.fetch(name)
+	if err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if entry, ok := r.entries[name]; ok {
+		return entry.current, nil
+	}
+	r.entries[name] = &keyEntry{current: key}
+	return key, nil
+}
+
+// versions 返回当前密钥和保留的旧版本，用于解密或验证轮换前的数据
+func (r *keyring) versions(name string) ([]*Key, error) {
+	current, err := r.get(name)
+	if err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	keys := []*Key{current}
+	if entry, ok := r.entries[name]; ok {
+		keys = append(keys, entry.previous...)
+	}
+	return keys, nil
+}
+
+// run 按刷新间隔重新获取已使用的密钥，直到应用关闭
+func (r *keyring) run() {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	stop := r.stop
+	for {
+		select {
+		case <-ticker.C:
+			r.refresh()
+		case <-stop:
+			return
+		}
+	}
+}
+
+// refresh 重新获取已使用的密钥，版本变化时保留旧版本并调用轮换钩子；获取失败时继续使用缓存的密钥
+func (r *keyring) refresh() error {
+	r.mu.RLock()
+	names := make([]string, 0, len(r.entries))
+	for name := range r.entries {
+		names = append(names, name)
+	}
+	r.mu.RUnlock()
+	return r.refreshKeys(names)
+}
+
+// refreshKeys 重新获取指定的密钥
+func (r *keyring) refreshKeys(names []string) error {
+	var errs []error
+	for _, name := range names {
+		key, err := r.fetch(name)
+		if err != nil {
+			r.logger.WithFields(logrus.Fields{"key": name, "error": err.Error()}).Warn("Failed to refresh key, keeping cached version")
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			continue
+		}
+		r.update(key)
+	}
+	return errors.Join(errs...)
+}
+
+// update 替换缓存的密钥，版本变化时调用轮换钩子
+func (r *keyring) update(key *Key) {
+	r.mu.Lock()
+	entry, ok := r.entries[key.Name]
+	if !ok {
+		r.entries[key.Name] = &keyEntry{current: key}
+		r.mu.Unlock()
+		return
+	}
+	if entry.current.Version == key.Version {
+		r.mu.Unlock()
+		return
+	}
+	rotation := KeyRotation{Name: key.Name, OldVersion: entry.current.Version, NewVersion: key.Version}
+	entry.previous = append([]*Key{entry.current}, entry.previous...)
+	if len(entry.previous) > r.keep {
+		entry.previous = entry.previous[:r.keep]
+	}
+	entry.current = key
+	hooks := append([]func(KeyRotation){}, r.hooks...)
+	r.mu.Unlock()
+
+	r.logger.WithFields(logrus.Fields{
+		"key":         rotation.Name,
+		"old_version": rotation.OldVersion,
+		"new_version": rotation.NewVersion,
+	}).Info("Key rotated")
+	for _, hook := range hooks {
+		hook(rotation)
+	}
+}
+
+// Key 返回名称对应的当前密钥
+func (app *App) Key(name string) (*Key, error) {
+	return app.keys.get(name)
+}
+
+// SetKeyProvider 设置密钥的提供者，优先于 keys.entries 的配置；name 为空时作为所有未单独配置的密钥的提供者，
+// 替代从原有配置项读取。已使用的密钥立即从新的提供者获取，密钥变化时按轮换处理，旧版本仍可用于解密和验证
+func (app *App) SetKeyProvider(name string, provider KeyProvider) {
+	r := app.keys
+	r.mu.Lock()
+	var names []string
+	if name == "" {
+		r.fallback = provider
+		for cached := range r.entries {
+			if _, ok := r.providers[cached]; !ok {
+				names = append(names, cached)
+			}
+		}
+	} else {
+		r.providers[name] = provider
+		if _, ok := r.entries[name]; ok {
+			names = append(names, name)
+		}
+	}
+	r.mu.Unlock()
+	r.refreshKeys(names)
+}
+
+// OnKeyRotate 注册密钥轮换的回调，刷新时发现密钥版本变化后调用
+func (app *App) OnKeyRotate(hook func(rotation KeyRotation)) {
+	app.keys.mu.Lock()
+	defer app.keys.mu.Unlock()
+	app.keys.hooks = append(app.keys.hooks, hook)
+}
+
+// RefreshKeys 立即重新获取已使用的密钥，用于在外部轮换密钥后无需等待刷新间隔
+func (app *App) RefreshKeys() error {
+	return app.keys.refresh()
+}
+
+// configKeyProvider 从 token.jwt、encryption 等原有配置项读取密钥
+type configKeyProvider struct {
+	app *App
+}
+
+// GetKey 按密钥名称读取对应的配置项
+func (p configKeyProvider) GetKey(ctx context.Context, name string) (*Key, error) {
+	config := p.app.cfg.ModConfig
+	if config == nil {
+		return nil, ErrKeyNotFound
+	}
+
+	var material []byte
+	var err error
+	switch name {
+	case KeyJWT:
+		material = []byte(config.Token.JWT.SecretKey)
+	case KeyEncryptionSymmetric:
+		switch sym := config.Encryption.Symmetric; {
+		case sym.KeyFile != "":
+			material, err = os.ReadFile(sym.KeyFile)
+		case sym.Key != "":
+			material, err = base64.StdEncoding.DecodeString(sym.Key)
+		}
+	case KeyEncryptionSignature:
+		switch sig := config.Encryption.Signature; {
+		case sig.KeyFile != "":
+			material, err = os.ReadFile(sig.KeyFile)
+		case sig.Key != "":
+			// 不是base64时直接使用原始字符串
+			if material, err = base64.StdEncoding.DecodeString(sig.Key); err != nil {
+				material, err = []byte(sig.Key), nil
+			}
+		}
+	case KeyEncryptionPublicKey:
+		switch asym := config.Encryption.Asymmetric; {
+		case asym.PublicKeyFile != "":
+			material, err = os.ReadFile(asym.PublicKeyFile)
+		case asym.PublicKey != "":
+			material = []byte(asym.PublicKey)
+		}
+	case KeyEncryptionPrivateKey:
+		switch asym := config.Encryption.Asymmetric; {
+		case asym.PrivateKeyFile != "":
+			material, err = os.ReadFile(asym.PrivateKeyFile)
+		case asym.PrivateKey != "":
+			material = []byte(asym.PrivateKey)
+		}
+	case KeyFileEncryption:
+		switch enc := config.FileUpload.Local.Encryption; {
+		case enc.KeyFile != "":
+			material, err = os.ReadFile(enc.KeyFile)
+		case enc.Key != "":
+			material, err = base64.StdEncoding.DecodeString(enc.Key)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("key %s: %w", name, err)
+	}
+	if len(material) == 0 {
+		return nil, fmt.Errorf("key %s: %w", name, ErrKeyNotFound)
+	}
+	return &Key{Material: material}, nil
+}
+
+// newConfiguredKeyProvider 按 keys.entries 的配置创建提供者
+func newConfiguredKeyProvider(config KeyConfig) (KeyProvider, error) {
+	switch config.Provider {
+	case KeyProviderStatic:
+		if config.Value == "" {
+			return nil, errors.New("static key requires value")
+		}
+		return KeyProviderFunc(func(ctx context.Context, name string) (*Key, error) {
+			material, err := decodeKeyMaterial(config.Value, config.Encoding)
+			return &Key{Material: material}, err
+		}), nil
+	case KeyProviderFile:
+		if config.File == "" {
+			return nil, errors.New("file key requires file")
+		}
+		return KeyProviderFunc(func(ctx context.Context, name string) (*Key, error) {
+			data, err := os.ReadFile(config.File)
+			if err != nil {
+				return nil, err
+			}
+			value := string(data)
+			if config.Encoding != "" && config.Encoding != "raw" {
+				value = strings.TrimSpace(value)
+			}
+			material, err := decodeKeyMaterial(value, config.Encoding)
+			return &Key{Material: material}, err
+		}), nil
+	case KeyProviderVault:
+		return newVaultKeyProvider(config)
+	case KeyProviderAliyunKMS:
+		return newAliyunKMSKeyProvider(config)
+	case KeyProviderAWSKMS:
+		return newAWSKMSKeyProvider(config)
+	default:
+		return nil, fmt.Errorf("unknown key provider %q", config.Provider)
+	}
+}
+
+// decodeKeyMaterial 按编码解析密钥内容
+func decodeKeyMaterial(value, encoding string) ([]byte, error) {
+	switch encoding {
+	case "", "raw":
+		return []byte(value), nil
+	case "base64":
+		return base64.StdEncoding.DecodeString(value)
+	case "hex":
+		return hex.DecodeString(value)
+	default:
+		return nil, fmt.Errorf("unknown key encoding %q", encoding)
+	}
+}
@@ -0,0 +1,307 @@
+package mod
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// kmsHTTPClient 访问 Vault 和云厂商KMS的HTTP客户端
+var kmsHTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+// doKMSRequest 发送请求并解析JSON响应，非2xx响应返回包含响应内容的错误
+func doKMSRequest(req *http.Request, out any) error {
+	resp, err := kmsHTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return json.Unmarshal(body, out)
+}
+
+// firstNonEmpty 返回第一个非空字符串
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// newVaultKeyProvider 通过 Vault transit 引擎的 export 接口读取最新版本的密钥
+func newVaultKeyProvider(config KeyConfig) (KeyProvider, error) {
+	vault := config.Vault
+	address := strings.TrimSuffix(firstNonEmpty(vault.Address, os.Getenv("VAULT_ADDR")), "/")
+	if address == "" || vault.Key == "" {
+		return nil, errors.New("vault key requires address and key")
+	}
+	mount := strings.Trim(firstNonEmpty(vault.Mount, "transit"), "/")
+	keyType := firstNonEmpty(vault.Type, "encryption-key")
+	endpoint := fmt.Sprintf("%s/v1/%s/export/%s/%s/latest", address, mount, keyType, url.PathEscape(vault.Key))
+
+	return KeyProviderFunc(func(ctx context.Context, name string) (*Key, error) {
+		token := firstNonEmpty(vault.Token, os.Getenv("VAULT_TOKEN"))
+		if vault.TokenFile != "" {
+			data, err := os.ReadFile(vault.TokenFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read vault token file: %w", err)
+			}
+			token = strings.TrimSpace(string(data))
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("X-Vault-Token", token)
+		if vault.Namespace != "" {
+			req.Header.Set("X-Vault-Namespace", vault.Namespace)
+		}
+		var result struct {
+			Data struct {
+				Keys map[string]string `json:"keys"`
+			} `json:"data"`
+		}
+		if err := doKMSRequest(req, &result); err != nil {
+			return nil, fmt.Errorf("vault export %s: %w", vault.Key, err)
+		}
+		for version, value := range result.Data.Keys {
+			// signing-key 导出的是PEM格式的私钥，其余类型为base64
+			material := []byte(value)
+			if keyType != "signing-key" {
+				if material, err = base64.StdEncoding.DecodeString(value); err != nil {
+					return nil, fmt.Errorf("vault export %s: %w", vault.Key, err)
+				}
+			}
+			return &Key{Version: version, Material: material}, nil
+		}
+		return nil, fmt.Errorf("vault export %s: %w", vault.Key, ErrKeyNotFound)
+	}), nil
+}
+
+// kmsCiphertext 校验数据密钥密文
+func kmsCiphertext(config KeyConfig) error {
+	if config.KMS.CiphertextBlob == "" {
+		return fmt.Errorf("%s key requires kms.ciphertext_blob", config.Provider)
+	}
+	return nil
+}
+
+// newAliyunKMSKeyProvider 调用阿里云KMS的 Decrypt 接口解密数据密钥
+func newAliyunKMSKeyProvider(config KeyConfig) (KeyProvider, error) {
+	if err := kmsCiphertext(config); err != nil {
+		return nil, err
+	}
+	kms := config.KMS
+	endpoint := kms.Endpoint
+	if endpoint == "" {
+		if kms.Region == "" {
+			return nil, errors.New("aliyun_kms key requires kms.region or kms.endpoint")
+		}
+		endpoint = "https://kms." + kms.Region + ".aliyuncs.com"
+	}
+	if !strings.Contains(endpoint, "://") {
+		endpoint = "https://" + endpoint
+	}
+	encoding := firstNonEmpty(config.Encoding, "base64")
+
+	return KeyProviderFunc(func(ctx context.Context, name string) (*Key, error) {
+		accessKeyID := firstNonEmpty(kms.AccessKeyID, os.Getenv("ALIBABA_CLOUD_ACCESS_KEY_ID"))
+		accessKeySecret := firstNonEmpty(kms.AccessKeySecret, os.Getenv("ALIBABA_CLOUD_ACCESS_KEY_SECRET"))
+		securityToken := firstNonEmpty(kms.SecurityToken, os.Getenv("ALIBABA_CLOUD_SECURITY_TOKEN"))
+		if accessKeyID == "" || accessKeySecret == "" {
+			return nil, errors.New("aliyun_kms: access key is not configured")
+		}
+
+		nonce := make([]byte, 16)
+		if _, err := rand.Read(nonce); err != nil {
+			return nil, err
+		}
+		params := map[string]string{
+			"Action":           "Decrypt",
+			"Version":          "2016-01-20",
+			"Format":           "JSON",
+			"CiphertextBlob":   kms.CiphertextBlob,
+			"AccessKeyId":      accessKeyID,
+			"SignatureMethod":  "HMAC-SHA1",
+			"SignatureVersion": "1.0",
+			"SignatureNonce":   hex.EncodeToString(nonce),
+			"Timestamp":        time.Now().UTC().Format("2006-01-02T15:04:05Z"),
+		}
+		if securityToken != "" {
+			params["SecurityToken"] = securityToken
+		}
+		if len(kms.EncryptionContext) > 0 {
+			data, _ := json.Marshal(kms.EncryptionContext)
+			params["EncryptionContext"] = string(data)
+		}
+		query := aliyunCanonicalQuery(params)
+		mac := hmac.New(sha1.New, []byte(accessKeySecret+"&"))
+		mac.Write([]byte("POST&" + aliyunPercentEncode("/") + "&" + aliyunPercentEncode(query)))
+		query += "&Signature=" + aliyunPercentEncode(base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint+"/", strings.NewReader(query))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		var result struct {
+			Plaintext string `json:"Plaintext"`
+		}
+		if err := doKMSRequest(req, &result); err != nil {
+			return nil, fmt.Errorf("aliyun_kms decrypt: %w", err)
+		}
+		material, err := decodeKeyMaterial(result.Plaintext, encoding)
+		if err != nil {
+			return nil, fmt.Errorf("aliyun_kms decrypt: %w", err)
+		}
+		return &Key{Material: material}, nil
+	}), nil
+}
+
+// aliyunCanonicalQuery 按参数名排序并编码，用于RPC签名
+func aliyunCanonicalQuery(params map[string]string) string {
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, aliyunPercentEncode(k)+"="+aliyunPercentEncode(params[k]))
+	}
+	return strings.Join(pairs, "&")
+}
+
+// aliyunPercentEncode 阿里云RPC签名要求的URL编码
+func aliyunPercentEncode(s string) string {
+	s = url.QueryEscape(s)
+	s = strings.ReplaceAll(s, "+", "%20")
+	s = strings.ReplaceAll(s, "*", "%2A")
+	return strings.ReplaceAll(s, "%7E", "~")
+}
+
+// newAWSKMSKeyProvider 调用 AWS KMS 的 Decrypt 接口解密数据密钥
+func newAWSKMSKeyProvider(config KeyConfig) (KeyProvider, error) {
+	if err := kmsCiphertext(config); err != nil {
+		return nil, err
+	}
+	kms := config.KMS
+	region := firstNonEmpty(kms.Region, os.Getenv("AWS_REGION"), os.Getenv("AWS_DEFAULT_REGION"))
+	if region == "" {
+		return nil, errors.New("aws_kms key requires kms.region")
+	}
+	endpoint := firstNonEmpty(kms.Endpoint, "https://kms."+region+".amazonaws.com")
+	if !strings.Contains(endpoint, "://") {
+		endpoint = "https://" + endpoint
+	}
+
+	return KeyProviderFunc(func(ctx context.Context, name string) (*Key, error) {
+		accessKeyID := firstNonEmpty(kms.AccessKeyID, os.Getenv("AWS_ACCESS_KEY_ID"))
+		secretAccessKey := firstNonEmpty(kms.AccessKeySecret, os.Getenv("AWS_SECRET_ACCESS_KEY"))
+		sessionToken := firstNonEmpty(kms.SecurityToken, os.Getenv("AWS_SESSION_TOKEN"))
+		if accessKeyID == "" || secretAccessKey == "" {
+			return nil, errors.New("aws_kms: access key is not configured")
+		}
+
+		payload := map[string]any{"CiphertextBlob": kms.CiphertextBlob}
+		if len(kms.EncryptionContext) > 0 {
+			payload["EncryptionContext"] = kms.EncryptionContext
+		}
+		body, _ := json.Marshal(payload)
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint+"/", bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+		req.Header.Set("X-Amz-Target", "TrentService.Decrypt")
+		if sessionToken != "" {
+			req.Header.Set("X-Amz-Security-Token", sessionToken)
+		}
+		signAWSRequestV4(req, body, accessKeyID, secretAccessKey, region, "kms", time.Now().UTC())
+
+		var result struct {
+			Plaintext []byte `json:"Plaintext"`
+		}
+		if err := doKMSRequest(req, &result); err != nil {
+			return nil, fmt.Errorf("aws_kms decrypt: %w", err)
+		}
+		material, err := decodeKeyMaterial(string(result.Plaintext), config.Encoding)
+		if err != nil {
+			return nil, fmt.Errorf("aws_kms decrypt: %w", err)
+		}
+		return &Key{Material: material}, nil
+	}), nil
+}
+
+// signAWSRequestV4 使用 AWS Signature Version 4 签名请求，签名所有已设置的请求头和 Host
+func signAWSRequestV4(req *http.Request, body []byte, accessKeyID, secretAccessKey, region, service string, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := sha256.Sum256(body)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(payloadHash[:]))
+
+	headers := map[string]string{"host": req.URL.Host}
+	for k, v := range req.Header {
+		headers[strings.ToLower(k)] = strings.TrimSpace(strings.Join(v, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, k := range names {
+		canonicalHeaders.WriteString(k + ":" + headers[k] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	canonicalRequest := strings.Join([]string{
+		req.Method, path, req.URL.Query().Encode(), canonicalHeaders.String(), signedHeaders, hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	hmacSHA256 := func(key []byte, data string) []byte {
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(data))
+		return mac.Sum(nil)
+	}
+	signingKey := hmacSHA256([]byte("AWS4"+secretAccessKey), date)
+	signingKey = hmacSHA256(signingKey, region)
+	signingKey = hmacSHA256(signingKey, service)
+	signingKey = hmacSHA256(signingKey, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		accessKeyID, scope, signedHeaders, signature))
+}
@@ -0,0 +1,162 @@
+package mod
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"syscall"
+)
+
+// portAutoAttempts 启用 server.port_auto 时在配置端口之后依次尝试的端口数，均被占用时由系统分配
+const portAutoAttempts = 10
+
+// listenState 服务实际监听的地址和启动回调
+type listenState struct {
+	addr    *net.TCPAddr
+	started []func(addr *net.TCPAddr)
+}
+
+// OnStarted 注册启动回调，端口绑定成功、开始接收请求前调用，参数为实际监听的地址。
+// 用于测试工具等需要获取最终端口的场景（如配置 port: 0 或启用 port_auto 时）
+func (app *App) OnStarted(fn func(addr *net.TCPAddr)) {
+	app.listenMu.Lock()
+	defer app.listenMu.Unlock()
+	app.listen.started = append(app.listen.started, fn)
+}
+
+// Addr 返回服务实际监听的地址，服务未启动时返回 nil
+func (app *App) Addr() *net.TCPAddr {
+	app.listenMu.Lock()
+	defer app.listenMu.Unlock()
+	return app.listen.addr
+}
+
+// Start 校验监听地址并启动服务，阻塞直到服务关闭。地址格式错误、端口被占用或没有权限时返回可读的错误；
+// 启用 server.port_auto 时端口被占用会自动改用其他端口
+func (app *App) Start(addr ...string) error {
+	a := app.listenAddress(addr...)
+	if err := validateListenAddress(a); err != nil {
+		return err
+	}
+	if err := app.checkMockGuard(); err != nil {
+		return err
+	}
+	if err := app.checkSchemaBaseline(); err != nil {
+		return err
+	}
+
+	ln, err := net.Listen("tcp", a)
+	if err != nil && app.cfg.ModConfig != nil && app.cfg.ModConfig.Server.PortAuto && errors.Is(err, syscall.EADDRINUSE) {
+		ln, err = app.fallbackListen(a)
+	}
+	if err != nil {
+		return listenError(a, err)
+	}
+	bound := ln.Addr().(*net.TCPAddr)
+	if bound.String() != a {
+		app.logger.WithField("requested", a).Info("Listening on " + bound.String())
+	}
+
+	app.listenMu.Lock()
+	app.listen.addr = bound
+	started := append([]func(addr *net.TCPAddr){}, app.listen.started...)
+	app.listenMu.Unlock()
+
+	app.logStartupSummary(bound)
+	for _, fn := range started {
+		fn(bound)
+	}
+
+	// 多进程模式下由各子进程分别绑定端口，这里只用于校验
+	if app.Config().Prefork {
+		ln.Close()
+		return app.Listen(bound.String())
+	}
+	return app.Listener(ln)
+}
+
+// listenAddress 返回监听地址，未指定时使用配置文件中的主机和端口，默认 :8080
+func (app *App) listenAddress(addr ...string) string {
+	if len(addr) > 0 {
+		return addr[0]
+	}
+	host := ""
+	port := 8080 // 默认端口
+	if app.cfg.ModConfig != nil {
+		if app.cfg.ModConfig.Server.Host != "" {
+			host = app.cfg.ModConfig.Server.Host
+		}
+		if app.cfg.ModConfig.Server.Port > 0 {
+			port = app.cfg.ModConfig.Server.Port
+		}
+	}
+	if host == "" || host == "localhost" || host == "127.0.0.1" {
+		return fmt.Sprintf(":%d", port)
+	}
+	return net.JoinHostPort(host, strconv.Itoa(port))
+}
+
+// validateListenAddress 在绑定端口前检查地址格式、端口范围和主机名
+func validateListenAddress(addr string) error {
+	host, portText, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("invalid listen address %q: expected host:port or :port", addr)
+	}
+	port, err := strconv.Atoi(portText)
+	if err != nil || port < 0 || port > 65535 {
+		return fmt.Errorf("invalid listen address %q: port must be a number between 0 and 65535", addr)
+	}
+	if host == "" || net.ParseIP(host) != nil {
+		return nil
+	}
+	if _, err := net.LookupHost(host); err != nil {
+		return fmt.Errorf("invalid listen address %q: cannot resolve host %q; check server.host", addr, host)
+	}
+	return nil
+}
+
+// fallbackListen 依次尝试配置端口之后的端口，均被占用时由系统分配
+func (app *App) fallbackListen(addr string) (net.Listener, error) {
+	host, portText, _ := net.SplitHostPort(addr)
+	port, _ := strconv.Atoi(portText)
+	for next := port + 1; next <= port+portAutoAttempts && next <= 65535; next++ {
+		ln, err := net.Listen("tcp", net.JoinHostPort(host, strconv.Itoa(next)))
+		if err == nil {
+			app.logger.WithField("port", port).Warnf("Port %d is already in use, falling back to %d (server.port_auto)", port, next)
+			return ln, nil
+		}
+		if !errors.Is(err, syscall.EADDRINUSE) {
+			return nil, err
+		}
+	}
+	ln, err := net.Listen("tcp", net.JoinHostPort(host, "0"))
+	if err == nil {
+		app.logger.WithField("port", port).Warnf("Ports %d-%d are already in use, falling back to %s (server.port_auto)",
+			port, port+portAutoAttempts, ln.Addr())
+	}
+	return ln, err
+}
+
+// listenError 将绑定失败的系统错误转换为可读的提示
+func listenError(addr string, err error) error {
+	switch {
+	case errors.Is(err, syscall.EADDRINUSE):
+		return fmt.Errorf("cannot listen on %s: the port is already in use by another process; stop it, change server.port, or set server.port_auto: true in development: %w", addr, err)
+	case errors.Is(err, syscall.EACCES):
+		return fmt.Errorf("cannot listen on %s: permission denied; ports below 1024 require elevated privileges: %w", addr, err)
+	case errors.Is(err, syscall.EADDRNOTAVAIL):
+		return fmt.Errorf("cannot listen on %s: the address is not assigned to this machine; check server.host: %w", addr, err)
+	default:
+		return fmt.Errorf("cannot listen on %s: %w", addr, err)
+	}
+}
+
+// docsHost 返回访问文档页面使用的地址，监听所有网卡时使用 127.0.0.1
+func docsHost(addr *net.TCPAddr) string {
+	host := addr.IP.String()
+	if addr.IP == nil || addr.IP.IsUnspecified() {
+		host = "127.0.0.1"
+	}
+	return net.JoinHostPort(host, strconv.Itoa(addr.Port))
+}
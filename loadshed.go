@@ -0,0 +1,237 @@
+package mod
+
+import (
+	"math"
+	"runtime"
+	"runtime/metrics"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+)
+
+// 服务优先级，实例过载时按优先级从低到高拒绝请求
+const (
+	PriorityCritical = "critical" // 关键服务，任何负载下都不拒绝
+	PriorityHigh     = "high"
+	PriorityNormal   = "normal" // 未设置优先级的服务
+	PriorityLow      = "low"
+)
+
+// heapMetric 堆上存活对象占用的内存，通过 runtime/metrics 读取，不会暂停程序
+const heapMetric = "/memory/classes/heap/objects:bytes"
+
+// LoadStatus 实例的负载状态
+type LoadStatus struct {
+	Load       float64   `json:"load"`       // 负载系数：各项指标与其上限之比的最大值，1表示达到上限
+	Goroutines int       `json:"goroutines"` // goroutine 数量
+	HeapBytes  uint64    `json:"heap_bytes"` // 堆内存
+	Latency    string    `json:"latency"`    // 调度延迟（新 goroutine 开始执行前的等待时间，平滑后）
+	Shedding   []string  `json:"shedding"`   // 正在拒绝请求的优先级
+	Updated    time.Time `json:"updated"`    // 采样时间
+}
+
+// loadShedder 定时采样负载指标，计算各优先级是否拒绝请求
+type loadShedder struct {
+	mu         sync.RWMutex
+	status     LoadStatus
+	shedding   map[string]bool
+	latency    time.Duration
+	thresholds map[string]float64
+
+	interval      time.Duration
+	maxGoroutines int
+	maxHeap       uint64
+	maxLatency    time.Duration
+	retryAfter    time.Duration
+	logger        *logrus.Logger
+	stop          chan struct{}
+}
+
+// configureLoadShedding 启用负载卸载时启动负载采样
+func (app *App) configureLoadShedding() {
+	config := app.cfg.ModConfig.LoadShedding
+	if !config.Enabled {
+		return
+	}
+
+	shedder := &loadShedder{
+		thresholds:    map[string]float64{PriorityLow: 0.8, PriorityNormal: 1},
+		shedding:      map[string]bool{},
+		interval:      time.Second,
+		maxGoroutines: config.MaxGoroutines,
+		retryAfter:    5 * time.Second,
+		logger:        app.logger,
+		stop:          make(chan struct{}),
+	}
+	if len(config.Classes) > 0 {
+		shedder.thresholds = config.Classes
+	}
+	if d, err := time.ParseDuration(config.Interval); err == nil && d > 0 {
+		shedder.interval = d
+	}
+	if d, err := time.ParseDuration(config.RetryAfter); err == nil && d > 0 {
+		shedder.retryAfter = d
+	}
+	if config.MaxLatency != "" {
+		if d, err := time.ParseDuration(config.MaxLatency); err == nil && d > 0 {
+			shedder.maxLatency = d
+		} else {
+			app.logger.WithField("max_latency", config.MaxLatency).Warn("Invalid load_shedding max_latency, ignored")
+		}
+	}
+	if config.MaxMemory != "" {
+		if size, err := parseSize(config.MaxMemory); err == nil && size > 0 {
+			shedder.maxHeap = uint64(size)
+		} else {
+			app.logger.WithField("max_memory", config.MaxMemory).Warn("Invalid load_shedding max_memory, ignored")
+		}
+	}
+	if shedder.maxGoroutines <= 0 && shedder.maxHeap == 0 && shedder.maxLatency == 0 {
+		app.logger.Warn("Load shedding is enabled but no limit is configured (max_goroutines, max_memory, max_latency)")
+		return
+	}
+
+	app.shedder = shedder
+	go shedder.run()
+}
+
+// run 按采样间隔更新负载状态，直到应用关闭
+func (s *loadShedder) run() {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	samples := []metrics.Sample{{Name: heapMetric}}
+	for {
+		select {
+		case <-ticker.C:
+			s.sample(samples)
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// sample 采样一次负载指标
+func (s *loadShedder) sample(samples []metrics.Sample) {
+	// 调度延迟：新建的 goroutine 等待多久才开始执行，反映 CPU 和调度器的饱和程度
+	start := time.Now()
+	scheduled := make(chan time.Duration, 1)
+	go func() { scheduled <- time.Since(start) }()
+	delay := <-scheduled
+
+	metrics.Read(samples)
+	var heap uint64
+	if samples[0].Value.Kind() == metrics.KindUint64 {
+		heap = samples[0].Value.Uint64()
+	}
+	goroutines := runtime.NumGoroutine()
+
+	s.mu.Lock()
+	// 平滑调度延迟，避免单次抖动触发拒绝
+	s.latency = (s.latency + delay) / 2
+	load := 0.0
+	if s.maxGoroutines > 0 {
+		load = math.Max(load, float64(goroutines)/float64(s.maxGoroutines))
+	}
+	if s.maxHeap > 0 {
+		load = math.Max(load, float64(heap)/float64(s.maxHeap))
+	}
+	if s.maxLatency > 0 {
+		load = math.Max(load, float64(s.latency)/float64(s.maxLatency))
+	}
+
+	var changed []string
+	for class, threshold := range s.thresholds {
+		if class == PriorityCritical || threshold <= 0 {
+			continue
+		}
+		// 负载回落到阈值的90%以下才恢复，避免在阈值附近反复切换
+		shed := load >= threshold || (s.shedding[class] && load >= threshold*0.9)
+		if shed != s.shedding[class] {
+			s.shedding[class] = shed
+			changed = append(changed, class)
+		}
+	}
+	shedding := make([]string, 0, len(s.shedding))
+	for class, shed := range s.shedding {
+		if shed {
+			shedding = append(shedding, class)
+		}
+	}
+	sort.Strings(shedding)
+	s.status = LoadStatus{
+		Load:       roundTo(load, 3),
+		Goroutines: goroutines,
+		HeapBytes:  heap,
+		Latency:    s.latency.String(),
+		Shedding:   shedding,
+		Updated:    time.Now(),
+	}
+	s.mu.Unlock()
+
+	if len(changed) > 0 {
+		entry := s.logger.WithFields(logrus.Fields{
+			"load":       roundTo(load, 3),
+			"goroutines": goroutines,
+			"heap_bytes": heap,
+			"latency":    s.latency.String(),
+			"shedding":   shedding,
+		})
+		if len(shedding) > 0 {
+			entry.Warn("Instance overloaded, shedding low-priority services")
+		} else {
+			entry.Info("Instance load recovered, load shedding stopped")
+		}
+	}
+}
+
+// servicePriority 返回服务的优先级，mod.yml 中 load_shedding.services 的配置优先于 Service.Priority
+func (app *App) servicePriority(svc *Service) string {
+	if priority, ok := app.cfg.ModConfig.LoadShedding.Services[svc.Name]; ok {
+		return priority
+	}
+	if svc.Priority != "" {
+		return svc.Priority
+	}
+	return PriorityNormal
+}
+
+// checkLoadShedding 实例过载且服务的优先级正在被卸载时设置 Retry-After 响应头并返回 StdReply 错误
+func (app *App) checkLoadShedding(ctx *Context, svc *Service) error {
+	if app.shedder == nil {
+		return nil
+	}
+	priority := app.servicePriority(svc)
+	app.shedder.mu.RLock()
+	shed := app.shedder.shedding[priority]
+	load := app.shedder.status.Load
+	app.shedder.mu.RUnlock()
+	if !shed {
+		return nil
+	}
+
+	// 过载时逐条记录会加重负载，仅在 Debug 级别输出
+	app.logger.WithFields(logrus.Fields{
+		"service":  svc.Name,
+		"priority": priority,
+		"load":     load,
+		"rid":      ctx.GetRequestID(),
+	}).Debug("Request shed")
+	ctx.Ctx.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(app.shedder.retryAfter.Seconds()))))
+	return ReplyWithDetail(fiber.StatusServiceUnavailable, "Service overloaded", "load shedding: priority "+priority)
+}
+
+// LoadStatus 返回最近一次采样的负载状态，未启用负载卸载时返回 false
+func (app *App) LoadStatus() (LoadStatus, bool) {
+	if app.shedder == nil {
+		return LoadStatus{}, false
+	}
+	app.shedder.mu.RLock()
+	defer app.shedder.mu.RUnlock()
+	status := app.shedder.status
+	status.Shedding = append([]string(nil), status.Shedding...)
+	return status, true
+}
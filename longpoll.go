@@ -0,0 +1,278 @@
+package mod
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// HeaderChangeToken 长轮询请求携带的变更令牌，响应中返回最新的变更令牌
+const HeaderChangeToken = "X-Change-Token"
+
+// Change 长轮询的结果
+type Change struct {
+	Key     string          `json:"key"`
+	Token   string          `json:"token"`          // 变更令牌，客户端在下一次长轮询中携带
+	Changed bool            `json:"changed"`        // 是否有新的变更，等待超时时为 false
+	Data    json.RawMessage `json:"data,omitempty"` // 最近一次发布的数据
+}
+
+// Decode 将发布的数据解析到 v
+func (c *Change) Decode(v any) error {
+	if len(c.Data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(c.Data, v)
+}
+
+// longPollEntry 单个键的最新版本和数据，ch 在版本变化时关闭以唤醒等待者
+type longPollEntry struct {
+	version uint64
+	data    json.RawMessage
+	updated time.Time
+	waiters int
+	ch      chan struct{}
+}
+
+// longPollState 各键的最新版本；配置 Redis 时版本和数据保存在 Redis 中，通过发布订阅通知所有实例
+type longPollState struct {
+	mu            sync.Mutex
+	entries       map[string]*longPollEntry
+	lastSweep     time.Time
+	subscribeOnce sync.Once
+}
+
+// longPollRecord 保存在 Redis 中的版本和数据
+type longPollRecord struct {
+	Version uint64          `json:"version"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+// longPollVersionScript 生成键的新版本：取当前时间（微秒）和上一版本加一中较大的值，
+// 版本号在数据过期或实例重启后仍然递增，客户端持有的旧令牌不会与新版本相同
+var longPollVersionScript = redis.NewScript(`
+local version = tonumber(ARGV[1])
+local previous = tonumber(redis.call('GET', KEYS[1]) or '0')
+if version <= previous then
+	version = previous + 1
+end
+redis.call('SET', KEYS[1], version, 'PX', ARGV[2])
+return version
+`)
+
+// nextLongPollVersion 进程内的新版本，规则同 longPollVersionScript
+func nextLongPollVersion(previous uint64) uint64 {
+	version := uint64(time.Now().UnixMicro())
+	if version <= previous {
+		version = previous + 1
+	}
+	return version
+}
+
+// configureLongPoll 初始化长轮询状态，挂载的子应用与父应用共用
+func (app *App) configureLongPoll() {
+	app.longPoll = &longPollState{entries: map[string]*longPollEntry{}}
+}
+
+// longPollPrefix 返回 Redis 中的键前缀
+func (app *App) longPollPrefix() string {
+	if prefix := app.cfg.ModConfig.LongPoll.CacheKeyPrefix; prefix != "" {
+		return prefix
+	}
+	return "mod:longpoll:"
+}
+
+// longPollTTL 返回变更数据的保留时间，默认24h
+func (app *App) longPollTTL() time.Duration {
+	if value := app.cfg.ModConfig.LongPoll.TTL; value != "" {
+		if d, err := time.ParseDuration(value); err == nil && d > 0 {
+			return d
+		}
+		app.logger.WithField("ttl", value).Warn("Invalid long_poll ttl, using 24h")
+	}
+	return 24 * time.Hour
+}
+
+// longPollMaxTimeout 返回单次长轮询的最长等待时间，默认60s
+func (app *App) longPollMaxTimeout() time.Duration {
+	if value := app.cfg.ModConfig.LongPoll.MaxTimeout; value != "" {
+		if d, err := time.ParseDuration(value); err == nil && d > 0 {
+			return d
+		}
+		app.logger.WithField("max_timeout", value).Warn("Invalid long_poll max_timeout, using 60s")
+	}
+	return time.Minute
+}
+
+// Publish 发布键的变更，唤醒正在等待该键的长轮询请求，返回新的变更令牌；data 按JSON保存，可以为 nil。
+// 配置 Redis 时变更通过发布订阅通知所有实例，否则只在当前进程内生效
+func (app *App) Publish(key string, data any) (string, error) {
+	var raw json.RawMessage
+	if data != nil {
+		var err error
+		if raw, err = json.Marshal(data); err != nil {
+			return "", ReplyWithDetail(400, "Change data cannot be serialized", err.Error())
+		}
+	}
+
+	if app.redisClient == nil {
+		state := app.longPoll
+		state.mu.Lock()
+		version := nextLongPollVersion(state.entry(key).version)
+		state.update(key, version, raw)
+		state.mu.Unlock()
+		return strconv.FormatUint(version, 10), nil
+	}
+
+	app.subscribeLongPoll()
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	prefix := app.longPollPrefix()
+	ttl := app.longPollTTL()
+	version, err := longPollVersionScript.Run(ctx, app.redisClient, []string{prefix + key + ":version"},
+		time.Now().UnixMicro(), ttl.Milliseconds()).Int64()
+	if err != nil {
+		app.logger.WithError(err).WithField("key", key).Error("Failed to publish change")
+		return "", err
+	}
+	record, _ := json.Marshal(longPollRecord{Version: uint64(version), Data: raw})
+	pipe := app.redisClient.TxPipeline()
+	pipe.Set(ctx, prefix+key, record, ttl)
+	pipe.Publish(ctx, prefix+"changed", key)
+	if _, err := pipe.Exec(ctx); err != nil {
+		app.logger.WithError(err).WithField("key", key).Error("Failed to publish change")
+		return "", err
+	}
+
+	app.longPoll.mu.Lock()
+	app.longPoll.update(key, uint64(version), raw)
+	app.longPoll.mu.Unlock()
+	return strconv.FormatUint(uint64(version), 10), nil
+}
+
+// LongPoll 等待键的变更：请求的变更令牌（X-Change-Token 请求头或 change_token 查询参数）与最新令牌不同时立即返回，
+// 否则等待到发布新的变更或超时，超时时返回 Changed 为 false 的结果。最新令牌同时写入 X-Change-Token 响应头。
+// timeout 不超过 long_poll.max_timeout；客户端断开或超过 Service.Timeout 时返回错误
+func (c *Context) LongPoll(key string, timeout time.Duration) (*Change, error) {
+	app := c.app
+	if limit := app.longPollMaxTimeout(); timeout <= 0 || timeout > limit {
+		timeout = limit
+	}
+	token := c.Get(HeaderChangeToken)
+	if token == "" {
+		token = c.Query("change_token")
+	}
+
+	state := app.longPoll
+	state.mu.Lock()
+	entry := state.entry(key)
+	entry.waiters++
+	state.mu.Unlock()
+	defer func() {
+		state.mu.Lock()
+		entry.waiters--
+		state.mu.Unlock()
+	}()
+
+	if app.redisClient != nil {
+		app.subscribeLongPoll()
+		app.loadLongPoll(c.UserContext(), key)
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		state.mu.Lock()
+		change := &Change{Key: key, Token: strconv.FormatUint(entry.version, 10), Data: entry.data}
+		ch := entry.ch
+		state.mu.Unlock()
+
+		if token == "" || token != change.Token {
+			change.Changed = true
+			c.Ctx.Set(HeaderChangeToken, change.Token)
+			return change, nil
+		}
+		select {
+		case <-ch:
+		case <-timer.C:
+			c.Ctx.Set(HeaderChangeToken, change.Token)
+			return change, nil
+		case <-c.UserContext().Done():
+			return nil, c.UserContext().Err()
+		}
+	}
+}
+
+// entry 返回键的状态，不存在时创建；调用方须持有 s.mu
+func (s *longPollState) entry(key string) *longPollEntry {
+	s.sweep(time.Now())
+	entry, ok := s.entries[key]
+	if !ok {
+		entry = &longPollEntry{ch: make(chan struct{}), updated: time.Now()}
+		s.entries[key] = entry
+	}
+	return entry
+}
+
+// update 版本比当前新时更新键的数据并唤醒等待者；调用方须持有 s.mu
+func (s *longPollState) update(key string, version uint64, data json.RawMessage) {
+	entry := s.entry(key)
+	if version <= entry.version {
+		return
+	}
+	entry.version = version
+	entry.data = data
+	entry.updated = time.Now()
+	close(entry.ch)
+	entry.ch = make(chan struct{})
+}
+
+// sweep 每分钟清理一次超过一小时未更新且没有等待者的键；调用方须持有 s.mu
+func (s *longPollState) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < time.Minute {
+		return
+	}
+	s.lastSweep = now
+	for key, entry := range s.entries {
+		if entry.waiters == 0 && now.Sub(entry.updated) > time.Hour {
+			delete(s.entries, key)
+		}
+	}
+}
+
+// loadLongPoll 从 Redis 读取键的最新版本，用于本实例启动后或错过通知时同步
+func (app *App) loadLongPoll(ctx context.Context, key string) {
+	value, err := app.redisClient.Get(ctx, app.longPollPrefix()+key).Bytes()
+	if err != nil {
+		if err != redis.Nil {
+			app.logger.WithError(err).WithField("key", key).Warn("Failed to load long poll state")
+		}
+		return
+	}
+	var record longPollRecord
+	if err := json.Unmarshal(value, &record); err != nil {
+		return
+	}
+	app.longPoll.mu.Lock()
+	app.longPoll.update(key, record.Version, record.Data)
+	app.longPoll.mu.Unlock()
+}
+
+// subscribeLongPoll 首次使用时订阅其他实例发布的变更
+func (app *App) subscribeLongPoll() {
+	app.longPoll.subscribeOnce.Do(func() {
+		pubsub := app.redisClient.Subscribe(context.Background(), app.longPollPrefix()+"changed")
+		go func() {
+			defer pubsub.Close()
+			for msg := range pubsub.Channel() {
+				ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+				app.loadLongPoll(ctx, msg.Payload)
+				cancel()
+			}
+		}()
+	})
+}
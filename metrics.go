@@ -0,0 +1,446 @@
+package mod
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// 默认的延迟（秒）和大小（字节）直方图分桶
+var (
+	defaultLatencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+	defaultSizeBuckets    = []float64{100, 1000, 10000, 100000, 1000000, 10000000, 100000000}
+)
+
+// MetricsCollector 指标采集器，每次抓取指标路由时调用 Collect，以 Prometheus 文本格式输出指标
+type MetricsCollector interface {
+	Collect(w *MetricsWriter)
+}
+
+// MetricsCollectorFunc 将函数适配为 MetricsCollector，适合在抓取时读取连接池、队列长度等瞬时值
+type MetricsCollectorFunc func(w *MetricsWriter)
+
+// Collect 实现 MetricsCollector
+func (f MetricsCollectorFunc) Collect(w *MetricsWriter) {
+	f(w)
+}
+
+// MetricsWriter 输出 Prometheus 文本格式（0.0.4）的指标，同一指标的多个序列须连续输出
+type MetricsWriter struct {
+	b       strings.Builder
+	written map[string]bool
+}
+
+// Counter 输出计数器，labels 为标签名和标签值交替排列，如 "backend", "s3"
+func (w *MetricsWriter) Counter(name, help string, value float64, labels ...string) {
+	w.header(name, help, "counter")
+	w.sample(name, labels, value)
+}
+
+// Gauge 输出仪表盘
+func (w *MetricsWriter) Gauge(name, help string, value float64, labels ...string) {
+	w.header(name, help, "gauge")
+	w.sample(name, labels, value)
+}
+
+// header 首次输出指标时写入 HELP 和 TYPE
+func (w *MetricsWriter) header(name, help, kind string) {
+	if w.written == nil {
+		w.written = map[string]bool{}
+	}
+	if w.written[name] {
+		return
+	}
+	w.written[name] = true
+	if help != "" {
+		help = strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(help)
+		fmt.Fprintf(&w.b, "# HELP %s %s\n", name, help)
+	}
+	fmt.Fprintf(&w.b, "# TYPE %s %s\n", name, kind)
+}
+
+// sample 输出一个序列的值
+func (w *MetricsWriter) sample(name string, labels []string, value float64) {
+	w.b.WriteString(name)
+	if len(labels) > 1 {
+		w.b.WriteByte('{')
+		for i := 0; i+1 < len(labels); i += 2 {
+			if i > 0 {
+				w.b.WriteByte(',')
+			}
+			w.b.WriteString(labels[i])
+			w.b.WriteString(`="`)
+			w.b.WriteString(strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(labels[i+1]))
+			w.b.WriteByte('"')
+		}
+		w.b.WriteByte('}')
+	}
+	w.b.WriteByte(' ')
+	w.b.WriteString(formatMetricValue(value))
+	w.b.WriteByte('\n')
+}
+
+// formatMetricValue 按 Prometheus 的格式输出数值
+func formatMetricValue(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// metricSeries 一个标签组合的取值
+type metricSeries struct {
+	labels  []string
+	value   float64
+	buckets []uint64
+	count   uint64
+}
+
+// metricVec 按标签组合保存序列
+type metricVec struct {
+	name   string
+	help   string
+	labels []string
+	mu     sync.Mutex
+	series map[string]*metricSeries
+}
+
+// get 返回标签组合对应的序列，调用方须持有锁；标签值数量与标签名不一致时多余的忽略，缺少的为空
+func (v *metricVec) get(values []string, buckets int) *metricSeries {
+	key := strings.Join(values, "\xff")
+	if s, ok := v.series[key]; ok {
+		return s
+	}
+	s := &metricSeries{labels: make([]string, 0, len(v.labels)*2)}
+	for i, name := range v.labels {
+		value := ""
+		if i < len(values) {
+			value = values[i]
+		}
+		s.labels = append(s.labels, name, value)
+	}
+	if buckets > 0 {
+		s.buckets = make([]uint64, buckets)
+	}
+	if v.series == nil {
+		v.series = map[string]*metricSeries{}
+	}
+	v.series[key] = s
+	return s
+}
+
+// sorted 返回按标签排序的序列，调用方须持有锁
+func (v *metricVec) sorted() []*metricSeries {
+	keys := make([]string, 0, len(v.series))
+	for key := range v.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	series := make([]*metricSeries, len(keys))
+	for i, key := range keys {
+		series[i] = v.series[key]
+	}
+	return series
+}
+
+// CounterVec 带标签的计数器，通过 app.RegisterCollector 注册后在指标路由中输出
+type CounterVec struct {
+	vec metricVec
+}
+
+// NewCounterVec 创建带标签的计数器，名称建议以 _total 结尾
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	return &CounterVec{vec: metricVec{name: name, help: help, labels: labels}}
+}
+
+// Inc 计数加一，labelValues 与创建时的标签名按顺序对应
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add 计数增加 value，value 不能为负数
+func (c *CounterVec) Add(value float64, labelValues ...string) {
+	if value < 0 {
+		return
+	}
+	c.vec.mu.Lock()
+	c.vec.get(labelValues, 0).value += value
+	c.vec.mu.Unlock()
+}
+
+// Collect 实现 MetricsCollector
+func (c *CounterVec) Collect(w *MetricsWriter) {
+	c.vec.mu.Lock()
+	defer c.vec.mu.Unlock()
+	for _, s := range c.vec.sorted() {
+		w.Counter(c.vec.name, c.vec.help, s.value, s.labels...)
+	}
+}
+
+// HistogramVec 带标签的直方图，输出各分桶的累计计数、_sum 和 _count
+type HistogramVec struct {
+	vec     metricVec
+	buckets []float64
+}
+
+// NewHistogramVec 创建带标签的直方图，buckets 为各分桶的上限（升序），为空时使用默认的延迟分桶（秒）
+func NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	if len(buckets) == 0 {
+		buckets = defaultLatencyBuckets
+	}
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+	return &HistogramVec{vec: metricVec{name: name, help: help, labels: labels}, buckets: buckets}
+}
+
+// Observe 记录一个观测值
+func (h *HistogramVec) Observe(value float64, labelValues ...string) {
+	h.vec.mu.Lock()
+	defer h.vec.mu.Unlock()
+	s := h.vec.get(labelValues, len(h.buckets))
+	for i, bound := range h.buckets {
+		if value <= bound {
+			s.buckets[i]++
+		}
+	}
+	s.value += value
+	s.count++
+}
+
+// Collect 实现 MetricsCollector
+func (h *HistogramVec) Collect(w *MetricsWriter) {
+	h.vec.mu.Lock()
+	defer h.vec.mu.Unlock()
+	series := h.vec.sorted()
+	if len(series) == 0 {
+		return
+	}
+	w.header(h.vec.name, h.vec.help, "histogram")
+	for _, s := range series {
+		for i, bound := range h.buckets {
+			w.sample(h.vec.name+"_bucket", append(s.labels[:len(s.labels):len(s.labels)], "le", formatMetricValue(bound)), float64(s.buckets[i]))
+		}
+		w.sample(h.vec.name+"_bucket", append(s.labels[:len(s.labels):len(s.labels)], "le", "+Inf"), float64(s.count))
+		w.sample(h.vec.name+"_sum", s.labels, s.value)
+		w.sample(h.vec.name+"_count", s.labels, float64(s.count))
+	}
+}
+
+// metricsState 内置指标和注册的采集器，挂载的子应用与父应用共用
+type metricsState struct {
+	enabled    bool
+	mu         sync.RWMutex
+	collectors []MetricsCollector
+
+	requests       *CounterVec   // 服务请求数
+	errors         *CounterVec   // 服务端错误（5xx）数
+	duration       *HistogramVec // 服务处理耗时
+	requestSize    *HistogramVec // 请求体大小
+	responseSize   *HistogramVec // 响应体大小
+	tokenLookups   *CounterVec   // Token缓存查询结果
+	tokenDegraded  *CounterVec   // Token缓存故障时的降级处理
+	limited        *CounterVec   // 超过响应大小上限的响应
+	responseCache  *CounterVec   // 响应缓存查询结果
+	jobs           *CounterVec   // 执行完成的异步任务
+	schedules      *CounterVec   // 定时任务的执行结果
+	uploads        *CounterVec   // 文件上传次数
+	uploadBytes    *CounterVec   // 文件上传字节数
+	uploadDuration *HistogramVec // 文件上传耗时
+}
+
+// configureMetrics 初始化内置指标，启用 metrics 时注册指标路由
+func (app *App) configureMetrics() {
+	config := app.cfg.ModConfig.Metrics
+	latency := config.LatencyBuckets
+	if len(latency) == 0 {
+		latency = defaultLatencyBuckets
+	}
+	size := config.SizeBuckets
+	if len(size) == 0 {
+		size = defaultSizeBuckets
+	}
+	app.metrics = &metricsState{
+		enabled:        config.Enabled,
+		requests:       NewCounterVec("mod_requests_total", "Service requests by HTTP status.", "service", "group", "status"),
+		errors:         NewCounterVec("mod_request_errors_total", "Service requests answered with a 5xx status.", "service", "group"),
+		duration:       NewHistogramVec("mod_request_duration_seconds", "Service request latency in seconds.", latency, "service", "group"),
+		requestSize:    NewHistogramVec("mod_request_size_bytes", "Service request body size in bytes.", size, "service"),
+		responseSize:   NewHistogramVec("mod_response_size_bytes", "Service response body size in bytes.", size, "service"),
+		tokenLookups:   NewCounterVec("mod_token_cache_lookups_total", "Token cache lookups by result (hit, miss, error).", "result"),
+		tokenDegraded:  NewCounterVec("mod_token_cache_degraded_total", "Token validations decided by the degradation policy during cache outages.", "policy", "outcome"),
+		limited:        NewCounterVec("mod_responses_limited_total", "Service responses over the size limit by action (truncate, error).", "service", "action"),
+		responseCache:  NewCounterVec("mod_response_cache_total", "Service response cache lookups by result (hit, miss, error).", "service", "result"),
+		jobs:           NewCounterVec("mod_jobs_total", "Async service jobs finished by status (succeeded, failed).", "service", "status"),
+		schedules:      NewCounterVec("mod_schedule_runs_total", "Scheduled task runs by status (succeeded, failed, skipped).", "task", "status"),
+		uploads:        NewCounterVec("mod_uploads_total", "File uploads by backend and result.", "backend", "result"),
+		uploadBytes:    NewCounterVec("mod_upload_bytes_total", "Bytes uploaded by backend.", "backend"),
+		uploadDuration: NewHistogramVec("mod_upload_duration_seconds", "File upload duration in seconds by backend.", latency, "backend"),
+	}
+	if !config.Enabled {
+		return
+	}
+	path := config.Path
+	if path == "" {
+		path = "/metrics"
+	}
+	app.Get(path, app.handleMetrics)
+	app.logger.WithField("path", path).Info("Metrics endpoint configured")
+}
+
+// RegisterCollector 注册自定义指标采集器（如 NewCounterVec、NewHistogramVec 创建的指标或 MetricsCollectorFunc），
+// 与内置指标一起在指标路由中输出
+func (app *App) RegisterCollector(collectors ...MetricsCollector) {
+	app.metrics.mu.Lock()
+	defer app.metrics.mu.Unlock()
+	app.metrics.collectors = append(app.metrics.collectors, collectors...)
+}
+
+// Metrics 返回 Prometheus 文本格式的全部指标：内置的服务、Token缓存、文件上传、重试和运行时指标，以及注册的采集器
+func (app *App) Metrics() string {
+	m := app.metrics
+	w := &MetricsWriter{}
+	for _, c := range []MetricsCollector{m.requests, m.errors, m.duration, m.requestSize, m.responseSize,
+		m.tokenLookups, m.tokenDegraded, m.limited, m.responseCache, m.jobs, m.schedules, m.uploads, m.uploadBytes, m.uploadDuration} {
+		c.Collect(w)
+	}
+
+	app.collectRetryMetrics(w)
+
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	w.Gauge("go_goroutines", "Number of goroutines that currently exist.", float64(runtime.NumGoroutine()))
+	w.Gauge("go_memstats_heap_alloc_bytes", "Number of heap bytes allocated and still in use.", float64(mem.HeapAlloc))
+
+	m.mu.RLock()
+	collectors := append([]MetricsCollector(nil), m.collectors...)
+	m.mu.RUnlock()
+	for _, c := range collectors {
+		c.Collect(w)
+	}
+	return w.b.String()
+}
+
+// handleMetrics 指标路由，未配置 metrics.skip_auth 时按 auth.admin 配置校验请求（Prometheus 可通过 bearer_token 配置）
+func (app *App) handleMetrics(c *fiber.Ctx) error {
+	if !app.cfg.ModConfig.Metrics.SkipAuth {
+		ctx := &Context{Ctx: c, logger: app.logger, app: app}
+		if err := app.authorizeAdmin(ctx, "metrics"); err != nil {
+			return replyError(ctx, err)
+		}
+	}
+	c.Set(fiber.HeaderContentType, "text/plain; version=0.0.4; charset=utf-8")
+	return c.SendString(app.Metrics())
+}
+
+// recordMetrics 记录服务请求的状态码、耗时和请求/响应大小，WebSocket 连接只记录握手
+func (app *App) recordMetrics(ctx *Context, start time.Time) {
+	m := app.metrics
+	if m == nil || !m.enabled || ctx.service == nil {
+		return
+	}
+	svc := ctx.service
+	status := ctx.Response().StatusCode()
+	m.requests.Inc(svc.Name, svc.Group, strconv.Itoa(status))
+	if status >= 500 {
+		m.errors.Inc(svc.Name, svc.Group)
+	}
+	m.duration.Observe(time.Since(start).Seconds(), svc.Name, svc.Group)
+	if size := ctx.Request().Header.ContentLength(); size >= 0 {
+		m.requestSize.Observe(float64(size), svc.Name)
+	}
+	// 流式响应（文件下载、导出）按声明的长度记录，长度未知时不记录，读取 Body() 会消耗响应流
+	if !ctx.Response().IsBodyStream() {
+		m.responseSize.Observe(float64(len(ctx.Response().Body())), svc.Name)
+	} else if size := ctx.Response().Header.ContentLength(); size > 0 {
+		m.responseSize.Observe(float64(size), svc.Name)
+	}
+}
+
+// recordTokenLookup 记录Token缓存查询结果
+func (app *App) recordTokenLookup(err error) {
+	m := app.metrics
+	if m == nil || !m.enabled {
+		return
+	}
+	switch {
+	case err == nil:
+		m.tokenLookups.Inc("hit")
+	case errors.Is(err, ErrTokenNotFound):
+		m.tokenLookups.Inc("miss")
+	default:
+		m.tokenLookups.Inc("error")
+	}
+}
+
+// recordTokenDegradation 记录Token缓存故障时的降级处理结果（allowed、denied、fallback）
+func (app *App) recordTokenDegradation(policy, outcome string) {
+	m := app.metrics
+	if m == nil || !m.enabled {
+		return
+	}
+	m.tokenDegraded.Inc(policy, outcome)
+}
+
+// recordResponseLimited 记录超过响应大小上限的响应及处理方式
+func (app *App) recordResponseLimited(svc *Service, action string) {
+	m := app.metrics
+	if m == nil || !m.enabled {
+		return
+	}
+	m.limited.Inc(svc.Name, action)
+}
+
+// recordResponseCache 记录响应缓存的查询结果
+func (app *App) recordResponseCache(svc *Service, result string) {
+	m := app.metrics
+	if m == nil || !m.enabled {
+		return
+	}
+	m.responseCache.Inc(svc.Name, result)
+}
+
+// recordJob 记录执行完成的异步任务
+func (app *App) recordJob(service, status string) {
+	m := app.metrics
+	if m == nil || !m.enabled {
+		return
+	}
+	m.jobs.Inc(service, status)
+}
+
+// recordSchedule 记录定时任务的执行结果
+func (app *App) recordSchedule(task, status string) {
+	m := app.metrics
+	if m == nil || !m.enabled {
+		return
+	}
+	m.schedules.Inc(task, status)
+}
+
+// recordUpload 记录文件上传的后端、大小、耗时和结果
+func (app *App) recordUpload(backend string, size int64, start time.Time, err error) {
+	m := app.metrics
+	if m == nil || !m.enabled {
+		return
+	}
+	if err != nil {
+		m.uploads.Inc(backend, "error")
+		return
+	}
+	m.uploads.Inc(backend, "ok")
+	m.uploadBytes.Add(float64(size), backend)
+	m.uploadDuration.Observe(time.Since(start).Seconds(), backend)
+}
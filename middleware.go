@@ -0,0 +1,79 @@
+package mod
+
+// Middleware 服务中间件，在参数解析和校验之后、处理函数之前执行；
+// 调用 next 执行后续的中间件和处理函数，不调用 next 则直接返回，返回的错误按处理函数的错误响应
+type Middleware func(ctx *Context, next func() error) error
+
+// serviceMiddlewareState 应用注册的服务中间件
+type serviceMiddlewareState struct {
+	global []Middleware            // 作用于应用的全部服务
+	groups map[string][]Middleware // 按服务分组
+}
+
+// UseServiceMiddleware 注册作用于全部服务的服务中间件（包括挂载的子应用的服务），按注册顺序执行，
+// 用于审计、埋点等横切逻辑；与 app.Use 注册的 Fiber 中间件不同，服务中间件可以通过 ctx.Input 获取已校验的请求参数。
+// 须在启动前调用
+func (app *App) UseServiceMiddleware(mw ...Middleware) {
+	app.serviceMiddlewares.global = append(app.serviceMiddlewares.global, mw...)
+}
+
+// UseGroupMiddleware 注册作用于指定分组（Service.Group）服务的服务中间件，在全局服务中间件之后执行。须在启动前调用
+func (app *App) UseGroupMiddleware(group string, mw ...Middleware) {
+	if app.serviceMiddlewares.groups == nil {
+		app.serviceMiddlewares.groups = map[string][]Middleware{}
+	}
+	app.serviceMiddlewares.groups[group] = append(app.serviceMiddlewares.groups[group], mw...)
+}
+
+// serviceMiddlewareChain 返回服务生效的中间件：从根应用到注册服务的应用，依次为各应用的全局和分组中间件，最后为 Service.Middlewares
+func (app *App) serviceMiddlewareChain(svc *Service) []Middleware {
+	var apps []*App
+	for a := app; a != nil; a = a.parent {
+		apps = append([]*App{a}, apps...)
+	}
+	var chain []Middleware
+	for _, a := range apps {
+		chain = append(chain, a.serviceMiddlewares.global...)
+		chain = append(chain, a.serviceMiddlewares.groups[svc.Group]...)
+	}
+	return append(chain, svc.Middlewares...)
+}
+
+// runServiceMiddlewares 依次执行服务中间件，最内层调用 handler
+func (app *App) runServiceMiddlewares(ctx *Context, svc *Service, handler func() error) error {
+	chain := app.serviceMiddlewareChain(svc)
+	var next func(i int) error
+	next = func(i int) error {
+		if i == len(chain) {
+			return handler()
+		}
+		return chain[i](ctx, func() error { return next(i + 1) })
+	}
+	return next(0)
+}
+
+// Input 返回已解析和校验的请求参数（处理函数的输入类型指针），供服务中间件使用
+func (c *Context) Input() any {
+	return c.input
+}
+
+// Output 返回处理函数的响应数据，服务中间件在 next 返回后获取；处理函数失败或未执行时可能为空值
+func (c *Context) Output() any {
+	return c.output
+}
+
+// ServiceName 返回当前处理的服务名称
+func (c *Context) ServiceName() string {
+	if c.service == nil {
+		return ""
+	}
+	return c.service.Name
+}
+
+// ServiceGroup 返回当前处理的服务分组
+func (c *Context) ServiceGroup() string {
+	if c.service == nil {
+		return ""
+	}
+	return c.service.Group
+}
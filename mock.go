@@ -1,9 +1,14 @@
 package mod
 
 import (
+	"encoding/json"
 	"fmt"
+	"hash/fnv"
 	"math/rand"
+	"os"
+	"path/filepath"
 	"reflect"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -11,12 +16,25 @@ import (
 // MockGenerator 负责根据结构体定义生成Mock数据
 type MockGenerator struct {
 	rand *rand.Rand
+	now  time.Time // 生成日期时间的基准时间
 }
 
+// mockSeedBaseTime 指定种子时使用的固定基准时间，保证日期类数据可复现
+var mockSeedBaseTime = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
 // NewMockGenerator 创建一个新的Mock数据生成器
 func NewMockGenerator() *MockGenerator {
 	return &MockGenerator{
 		rand: rand.New(rand.NewSource(time.Now().UnixNano())),
+		now:  time.Now(),
+	}
+}
+
+// NewMockGeneratorWithSeed 创建使用固定种子的Mock数据生成器，相同种子和类型生成相同的数据
+func NewMockGeneratorWithSeed(seed int64) *MockGenerator {
+	return &MockGenerator{
+		rand: rand.New(rand.NewSource(seed)),
+		now:  mockSeedBaseTime,
 	}
 }
 
@@ -221,6 +239,16 @@ func (m *MockGenerator) generateStructValue(t reflect.Type) any {
 
 // generateFieldMockValue 根据字段信息生成特定的Mock值
 func (m *MockGenerator) generateFieldMockValue(field reflect.StructField, fieldType reflect.Type) any {
+	// mock标签优先级最高
+	if mockTag, ok := field.Tag.Lookup("mock"); ok && mockTag != "" {
+		if mockTag == "-" {
+			return nil
+		}
+		if mockValue, ok := m.generateTaggedMockValue(mockTag, fieldType); ok {
+			return mockValue
+		}
+	}
+
 	fieldName := strings.ToLower(field.Name)
 	jsonTag := field.Tag.Get("json")
 	descTag := field.Tag.Get("desc")
@@ -234,6 +262,13 @@ func (m *MockGenerator) generateFieldMockValue(field reflect.StructField, fieldT
 		}
 	}
 
+	// 遵循validate标签中的约束，保证生成的数据符合文档声明
+	if validateTag := field.Tag.Get("validate"); validateTag != "" {
+		if mockValue, ok := m.generateValidatedValue(validateTag, fieldName, descTag, fieldType); ok {
+			return mockValue
+		}
+	}
+
 	// 根据字段名生成特定类型的数据
 	if mockValue := m.generateSpecificMockValue(fieldName, descTag, fieldType); mockValue != nil {
 		return mockValue
@@ -296,6 +331,303 @@ func (m *MockGenerator) generateSpecificMockValue(fieldName, desc string, fieldT
 	}
 }
 
+// generateTaggedMockValue 根据mock标签生成值，支持：
+//   - 预设类型：name、email、phone、url、id、uuid、address、message、status、date、datetime、ip、image
+//   - range:min-max 数值范围；字符串为长度范围；切片为元素个数范围
+//   - enum:a,b,c 从枚举值中随机选择
+//   - value:xxx 固定值
+func (m *MockGenerator) generateTaggedMockValue(tag string, fieldType reflect.Type) (any, bool) {
+	// 指针类型生成元素后取地址
+	if fieldType.Kind() == reflect.Ptr {
+		value, ok := m.generateTaggedMockValue(tag, fieldType.Elem())
+		if !ok || value == nil {
+			return nil, false
+		}
+		result := reflect.New(fieldType.Elem())
+		result.Elem().Set(reflect.ValueOf(value))
+		return result.Interface(), true
+	}
+
+	kind, arg, _ := strings.Cut(tag, ":")
+	switch strings.ToLower(strings.TrimSpace(kind)) {
+	case "range":
+		return m.generateRangeValue(arg, fieldType)
+
+	case "enum":
+		options := strings.Split(arg, ",")
+		return convertMockValue(strings.TrimSpace(options[m.rand.Intn(len(options))]), fieldType)
+
+	case "value":
+		return convertMockValue(arg, fieldType)
+
+	default:
+		if fieldType.Kind() != reflect.String {
+			return nil, false
+		}
+		value := m.generateNamedValue(strings.ToLower(strings.TrimSpace(kind)))
+		if value == "" {
+			return nil, false
+		}
+		return reflect.ValueOf(value).Convert(fieldType).Interface(), true
+	}
+}
+
+// validateFormats validate格式规则与预设Mock类型的对应关系
+var validateFormats = map[string]string{
+	"email": "email",
+	"url":   "url",
+	"uri":   "url",
+	"uuid":  "uuid",
+	"uuid4": "uuid",
+	"ip":    "ip",
+	"ipv4":  "ip",
+}
+
+// parseValidateRules 解析validate标签，dive之后的规则作用于元素，不参与解析
+func parseValidateRules(tag string) map[string]string {
+	rules := make(map[string]string)
+	for _, part := range strings.Split(tag, ",") {
+		name, param, _ := strings.Cut(strings.TrimSpace(part), "=")
+		if name == "dive" {
+			break
+		}
+		if name != "" {
+			rules[name] = param
+		}
+	}
+	return rules
+}
+
+// validateBounds 根据 len、min、max、gt、gte、lt、lte 计算取值范围
+func validateBounds(rules map[string]string) (lo, hi float64, hasLo, hasHi bool) {
+	parse := func(key string) (float64, bool) {
+		value, ok := rules[key]
+		if !ok {
+			return 0, false
+		}
+		f, err := strconv.ParseFloat(value, 64)
+		return f, err == nil
+	}
+
+	if v, ok := parse("len"); ok {
+		return v, v, true, true
+	}
+	if v, ok := parse("min"); ok {
+		lo, hasLo = v, true
+	}
+	if v, ok := parse("gte"); ok {
+		lo, hasLo = v, true
+	}
+	if v, ok := parse("gt"); ok {
+		lo, hasLo = v+1, true
+	}
+	if v, ok := parse("max"); ok {
+		hi, hasHi = v, true
+	}
+	if v, ok := parse("lte"); ok {
+		hi, hasHi = v, true
+	}
+	if v, ok := parse("lt"); ok {
+		hi, hasHi = v-1, true
+	}
+	return lo, hi, hasLo, hasHi
+}
+
+// generateValidatedValue 根据validate约束生成值，没有可用约束时返回false
+func (m *MockGenerator) generateValidatedValue(validateTag, fieldName, desc string, fieldType reflect.Type) (any, bool) {
+	if fieldType.Kind() == reflect.Ptr {
+		value, ok := m.generateValidatedValue(validateTag, fieldName, desc, fieldType.Elem())
+		if !ok || value == nil {
+			return nil, false
+		}
+		result := reflect.New(fieldType.Elem())
+		result.Elem().Set(reflect.ValueOf(value))
+		return result.Interface(), true
+	}
+
+	rules := parseValidateRules(validateTag)
+
+	// 枚举约束
+	if oneof, ok := rules["oneof"]; ok && oneof != "" {
+		options := strings.Fields(oneof)
+		return convertMockValue(options[m.rand.Intn(len(options))], fieldType)
+	}
+
+	lo, hi, hasLo, hasHi := validateBounds(rules)
+
+	switch fieldType.Kind() {
+	case reflect.String:
+		// 格式约束
+		for format, name := range validateFormats {
+			if _, ok := rules[format]; ok {
+				return reflect.ValueOf(m.generateNamedValue(name)).Convert(fieldType).Interface(), true
+			}
+		}
+		if !hasLo && !hasHi {
+			return nil, false
+		}
+		if !hasHi {
+			hi = max(lo, 16)
+		}
+		if !hasLo || lo < 0 {
+			lo = 0
+		}
+
+		// 字段名推断的值满足长度约束时优先使用
+		if value, ok := m.generateSpecificMockValue(fieldName, desc, fieldType).(string); ok {
+			if length := float64(len([]rune(value))); length >= lo && length <= hi {
+				return reflect.ValueOf(value).Convert(fieldType).Interface(), true
+			}
+		}
+		length := int(lo) + m.rand.Intn(int(hi)-int(lo)+1)
+		return reflect.ValueOf(m.generateRandomString(length)).Convert(fieldType).Interface(), true
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		if !hasLo && !hasHi {
+			return nil, false
+		}
+		if !hasLo {
+			lo = min(hi-1000, 1)
+		}
+		if !hasHi {
+			hi = lo + 1000
+		}
+		if fieldType.Kind() >= reflect.Uint && fieldType.Kind() <= reflect.Uint64 && lo < 0 {
+			lo = 0
+		}
+		return m.generateBoundedValue(lo, hi, fieldType)
+
+	case reflect.Slice:
+		if !hasLo && !hasHi {
+			return nil, false
+		}
+		if !hasHi {
+			hi = max(lo, 5)
+		}
+		if !hasLo || lo < 0 {
+			lo = 0
+		}
+		return m.generateBoundedValue(lo, hi, fieldType)
+
+	default:
+		return nil, false
+	}
+}
+
+// generateNamedValue 生成预设类型的字符串值
+func (m *MockGenerator) generateNamedValue(name string) string {
+	switch name {
+	case "uuid":
+		b := make([]byte, 16)
+		m.rand.Read(b)
+		b[6] = (b[6] & 0x0f) | 0x40
+		b[8] = (b[8] & 0x3f) | 0x80
+		return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+	case "date":
+		return m.now.AddDate(0, 0, -m.rand.Intn(365)).Format("2006-01-02")
+	case "datetime":
+		return m.now.Add(-time.Duration(m.rand.Intn(365*24*3600)) * time.Second).Format(time.RFC3339)
+	case "ip":
+		return fmt.Sprintf("192.168.%d.%d", m.rand.Intn(256), m.rand.Intn(254)+1)
+	case "image":
+		return fmt.Sprintf("https://example.com/mock/images/%d.png", m.rand.Intn(10000))
+	case "id", "name", "email", "phone", "url", "token", "address", "message", "status":
+		if value, ok := m.generateSpecificMockValue(name, "", reflect.TypeOf("")).(string); ok {
+			return value
+		}
+	}
+	return ""
+}
+
+// generateRangeValue 根据 range:min-max 生成值
+func (m *MockGenerator) generateRangeValue(arg string, fieldType reflect.Type) (any, bool) {
+	minStr, maxStr, found := strings.Cut(arg, "-")
+	if !found {
+		return nil, false
+	}
+	minValue, err1 := strconv.ParseFloat(strings.TrimSpace(minStr), 64)
+	maxValue, err2 := strconv.ParseFloat(strings.TrimSpace(maxStr), 64)
+	if err1 != nil || err2 != nil {
+		return nil, false
+	}
+	return m.generateBoundedValue(minValue, maxValue, fieldType)
+}
+
+// generateBoundedValue 生成 [minValue, maxValue] 范围内的值；字符串为长度范围；切片为元素个数范围
+func (m *MockGenerator) generateBoundedValue(minValue, maxValue float64, fieldType reflect.Type) (any, bool) {
+	if maxValue < minValue {
+		return nil, false
+	}
+
+	switch fieldType.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		value := int64(minValue) + m.rand.Int63n(int64(maxValue)-int64(minValue)+1)
+		return reflect.ValueOf(value).Convert(fieldType).Interface(), true
+
+	case reflect.Float32, reflect.Float64:
+		value := minValue + m.rand.Float64()*(maxValue-minValue)
+		return reflect.ValueOf(value).Convert(fieldType).Interface(), true
+
+	case reflect.String:
+		length := int(minValue) + m.rand.Intn(int(maxValue)-int(minValue)+1)
+		return reflect.ValueOf(m.generateRandomString(length)).Convert(fieldType).Interface(), true
+
+	case reflect.Slice:
+		length := int(minValue) + m.rand.Intn(int(maxValue)-int(minValue)+1)
+		slice := reflect.MakeSlice(fieldType, length, length)
+		for i := 0; i < length; i++ {
+			if elem := m.GenerateMockData(fieldType.Elem()); elem != nil {
+				slice.Index(i).Set(reflect.ValueOf(elem))
+			}
+		}
+		return slice.Interface(), true
+
+	default:
+		return nil, false
+	}
+}
+
+// convertMockValue 将标签中的字符串值转换为字段类型
+func convertMockValue(value string, fieldType reflect.Type) (any, bool) {
+	result := reflect.New(fieldType).Elem()
+
+	switch fieldType.Kind() {
+	case reflect.String:
+		result.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, false
+		}
+		result.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, false
+		}
+		result.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return nil, false
+		}
+		result.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, false
+		}
+		result.SetFloat(f)
+	default:
+		return nil, false
+	}
+
+	return result.Interface(), true
+}
+
 // generateRandomString 生成指定长度的随机字符串
 func (m *MockGenerator) generateRandomString(length int) string {
 	const charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
@@ -306,37 +638,206 @@ func (m *MockGenerator) generateRandomString(length int) string {
 	return string(result)
 }
 
-// isMockEnabled 检查给定的服务是否启用了Mock
+// isMockEnabled 检查给定的服务是否启用了Mock，当前环境在 mock.forbid_in 中时始终为 false
 func (app *App) isMockEnabled(service *Service) bool {
+	return app.mockConfigured(service) && !app.mockForbidden()
+}
+
+// mockConfigured 检查给定的服务是否按配置和运行时开关启用了Mock
+// 优先级：服务运行时覆盖 > 服务配置 > 分组运行时覆盖 > 分组配置 > 全局运行时覆盖 > 全局配置
+func (app *App) mockConfigured(service *Service) bool {
 	config := app.GetModConfig()
 	if config == nil {
 		return false
 	}
 
+	// 框架注册的管理服务和文件服务不受Mock开关影响，WebSocket 服务没有可以Mock的响应
+	if service.builtin || service.ws != nil {
+		return false
+	}
+
 	mockConfig := &config.Mock
 
 	// 1. 检查服务级别的Mock设置（最高优先级）
+	if enabled, exists := app.mockOverride("service", service.Name); exists {
+		return enabled
+	}
 	if serviceConfig, exists := mockConfig.Services[service.Name]; exists {
 		return serviceConfig.Enabled
 	}
 
 	// 2. 检查分组级别的Mock设置
 	if service.Group != "" {
+		if enabled, exists := app.mockOverride("group", service.Group); exists {
+			return enabled
+		}
 		if groupConfig, exists := mockConfig.Groups[service.Group]; exists {
 			return groupConfig.Enabled
 		}
 	}
 
 	// 3. 检查全局Mock设置（最低优先级）
+	if enabled, exists := app.mockOverride("global", ""); exists {
+		return enabled
+	}
 	return mockConfig.Global.Enabled
 }
 
-// generateMockResponse 为服务生成Mock响应
-func (app *App) generateMockResponse(service *Service) any {
+// generateMockResponse 为服务生成Mock响应，配置了fixture时优先使用文件中的数据
+func (app *App) generateMockResponse(ctx *Context, service *Service) any {
 	if service.Handler.OutputType == nil {
 		return nil
 	}
 
+	if data, err := app.loadMockFixture(ctx, service); err != nil {
+		app.logger.WithError(err).WithField("service", service.Name).Warn("Failed to load mock fixture, using generated data")
+	} else if data != nil {
+		return data
+	}
+
+	return app.newMockGenerator(ctx).GenerateMockData(service.Handler.OutputType)
+}
+
+// newMockGenerator 根据请求头 X-Mock-Seed 或全局 mock.seed 创建Mock数据生成器
+func (app *App) newMockGenerator(ctx *Context) *MockGenerator {
+	if ctx != nil {
+		if header := ctx.Get("X-Mock-Seed"); header != "" {
+			seed, err := strconv.ParseInt(header, 10, 64)
+			if err != nil {
+				// 非数字种子取哈希，便于使用测试用例名等字符串
+				h := fnv.New64a()
+				h.Write([]byte(header))
+				seed = int64(h.Sum64())
+			}
+			return NewMockGeneratorWithSeed(seed)
+		}
+	}
+
+	if config := app.GetModConfig(); config != nil && config.Mock.Seed != 0 {
+		return NewMockGeneratorWithSeed(config.Mock.Seed)
+	}
+	// 日期时间以应用时钟为基准，冻结时间后生成的日期可预期
 	generator := NewMockGenerator()
-	return generator.GenerateMockData(service.Handler.OutputType)
+	generator.now = app.now()
+	return generator
+}
+
+// loadMockFixture 加载服务的Mock数据文件，未配置时返回nil
+// fixture为目录时，按 X-Mock-Scenario 请求头、配置的scenario、default 的顺序选择场景文件
+func (app *App) loadMockFixture(ctx *Context, service *Service) (any, error) {
+	config := app.GetModConfig()
+	if config == nil {
+		return nil, nil
+	}
+	serviceConfig, exists := config.Mock.Services[service.Name]
+	if !exists || serviceConfig.Fixture == "" {
+		return nil, nil
+	}
+
+	path := serviceConfig.Fixture
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if info.IsDir() {
+		scenario := ""
+		if ctx != nil {
+			scenario = ctx.Get("X-Mock-Scenario")
+		}
+		if scenario == "" {
+			scenario = serviceConfig.Scenario
+		}
+		if scenario == "" {
+			scenario = "default"
+		}
+		// 防止通过场景名访问目录外的文件
+		if strings.ContainsAny(scenario, `/\`) || strings.Contains(scenario, "..") {
+			return nil, fmt.Errorf("invalid mock scenario: %s", scenario)
+		}
+		path = filepath.Join(path, scenario+".json")
+	}
+
+	// 每次请求重新读取，修改fixture后无需重启
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	result := reflect.New(service.Handler.OutputType)
+	if err := json.Unmarshal(content, result.Interface()); err != nil {
+		return nil, fmt.Errorf("failed to parse mock fixture %s: %v", path, err)
+	}
+	return result.Elem().Interface(), nil
+}
+
+// docExampleRID 文档示例中使用的请求ID
+const docExampleRID = "1800000000000000000"
+
+// generateDocExamples 使用Mock数据为文档生成请求体示例和响应示例（格式化的JSON）
+// 使用固定种子（mock.seed 或服务名哈希），保证每次打开文档看到的示例一致
+func (app *App) generateDocExamples(service *Service) (request, response string) {
+	seed := int64(0)
+	if config := app.GetModConfig(); config != nil {
+		seed = config.Mock.Seed
+	}
+	if seed == 0 {
+		h := fnv.New64a()
+		h.Write([]byte(service.Name))
+		seed = int64(h.Sum64())
+	}
+	generator := NewMockGeneratorWithSeed(seed)
+
+	// 请求示例：仅保留从JSON body读取的字段
+	if inputType := service.Handler.InputType; inputType != nil && inputType.Kind() == reflect.Struct && inputType.NumField() > 0 {
+		if data, err := json.Marshal(generator.GenerateMockData(inputType)); err == nil {
+			var body map[string]any
+			if json.Unmarshal(data, &body) == nil {
+				for i := 0; i < inputType.NumField(); i++ {
+					field := inputType.Field(i)
+					modTag := field.Tag.Get("mod")
+					if modTag == "" || app.parseModTagFrom(modTag) == "body" {
+						continue
+					}
+					name := field.Name
+					if jsonName, _, _ := strings.Cut(field.Tag.Get("json"), ","); jsonName != "" {
+						name = jsonName
+					}
+					delete(body, name)
+				}
+				if len(body) > 0 {
+					if pretty, err := json.MarshalIndent(body, "", "  "); err == nil {
+						request = string(pretty)
+					}
+				}
+			}
+		}
+	}
+
+	// 响应示例：优先使用fixture，按服务的返回格式包装
+	var data any
+	if service.Handler.OutputType != nil {
+		if fixture, err := app.loadMockFixture(nil, service); err == nil && fixture != nil {
+			data = fixture
+		} else {
+			data = generator.GenerateMockData(service.Handler.OutputType)
+		}
+	}
+
+	var output any = data
+	if !service.ReturnRaw {
+		output = &ApiResponse{
+			Code: 0,
+			Data: data,
+			Msg:  "success",
+			Rid:  docExampleRID,
+		}
+	}
+	if output != nil {
+		if pretty, err := json.MarshalIndent(output, "", "  "); err == nil {
+			response = string(pretty)
+		}
+	}
+
+	return request, response
 }
@@ -0,0 +1,344 @@
+package mod
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/redis/go-redis/v9"
+)
+
+// mockAdminGroup Mock管理服务所在的分组
+const mockAdminGroup = "Mock管理"
+
+// MockOverrides 运行时Mock开关覆盖，优先级高于mod.yml中同级别的配置
+type MockOverrides struct {
+	Global   *bool           `json:"global,omitempty" desc:"全局开关覆盖"`
+	Groups   map[string]bool `json:"groups" desc:"分组开关覆盖"`
+	Services map[string]bool `json:"services" desc:"服务开关覆盖"`
+}
+
+// mockOverrideStore 运行时Mock开关，内存保存并写入缓存（Redis或BadgerDB）持久化
+type mockOverrideStore struct {
+	mu        sync.RWMutex
+	overrides MockOverrides
+	revision  atomic.Uint64 // 每次修改递增，用于判断文档缓存是否过期
+
+	alertHooks []func(MockAlert) // OnMockAlert 注册的告警回调
+}
+
+// currentRevision 返回开关的修改次数
+func (s *mockOverrideStore) currentRevision() uint64 {
+	if s == nil {
+		return 0
+	}
+	return s.revision.Load()
+}
+
+// MockToggleRequest 切换Mock开关请求
+type MockToggleRequest struct {
+	Scope   string `json:"scope" validate:"required,oneof=global group service" desc:"作用范围：global、group、service"`
+	Name    string `json:"name" desc:"分组名或服务名（scope为global时忽略）"`
+	Enabled *bool  `json:"enabled" desc:"是否启用Mock，为空时清除覆盖恢复mod.yml配置"`
+}
+
+// MockServiceStatus 服务的Mock状态
+type MockServiceStatus struct {
+	Name    string `json:"name" desc:"服务名称"`
+	Group   string `json:"group" desc:"服务分组"`
+	Enabled bool   `json:"enabled" desc:"当前是否启用Mock"`
+}
+
+// MockStatusRequest 查询Mock状态请求
+type MockStatusRequest struct{}
+
+// MockStatusResponse Mock状态响应
+type MockStatusResponse struct {
+	Overrides MockOverrides       `json:"overrides" desc:"运行时覆盖"`
+	Services  []MockServiceStatus `json:"services" desc:"各服务当前的Mock状态"`
+}
+
+// configureMockAdmin 初始化运行时Mock开关，启用Mock管理时加载持久化的开关并注册Mock管理服务
+func (app *App) configureMockAdmin() {
+	app.mockOverrides = &mockOverrideStore{}
+	if app.cfg.ModConfig == nil || !app.cfg.ModConfig.Mock.Admin.Enabled {
+		return
+	}
+
+	if err := app.loadMockOverrides(); err != nil {
+		app.logger.WithError(err).Warn("Failed to load mock overrides from cache")
+	}
+
+	skipAuth := app.cfg.ModConfig.Mock.Admin.SkipAuth
+	services := []Service{
+		{
+			Name:        "mock_status",
+			DisplayName: "Mock状态",
+			Description: "查询运行时Mock开关覆盖以及各服务当前的Mock状态",
+			Group:       mockAdminGroup,
+			Sort:        1,
+			SkipAuth:    skipAuth,
+			Handler: MakeHandler(func(ctx *Context, req *MockStatusRequest, resp *MockStatusResponse) error {
+				resp.Overrides = app.GetMockOverrides()
+				resp.Services = app.mockServiceStatuses()
+				return nil
+			}),
+		},
+		{
+			Name:        "mock_toggle",
+			DisplayName: "切换Mock开关",
+			Description: "在运行时开启或关闭全局、分组或服务的Mock，无需修改mod.yml和重启",
+			Group:       mockAdminGroup,
+			Sort:        2,
+			SkipAuth:    skipAuth,
+			Handler: MakeHandler(func(ctx *Context, req *MockToggleRequest, resp *MockStatusResponse) error {
+				if req.Scope != "global" && req.Name == "" {
+					return Reply(400, "分组名或服务名不能为空")
+				}
+				if err := app.SetMockOverride(req.Scope, req.Name, req.Enabled); err != nil {
+					return err
+				}
+				resp.Overrides = app.GetMockOverrides()
+				resp.Services = app.mockServiceStatuses()
+				return nil
+			}),
+		},
+		{
+			Name:        "mock_reset",
+			DisplayName: "重置Mock开关",
+			Description: "清除全部运行时Mock开关覆盖，恢复mod.yml中的配置",
+			Group:       mockAdminGroup,
+			Sort:        3,
+			SkipAuth:    skipAuth,
+			Handler: MakeHandler(func(ctx *Context, req *MockStatusRequest, resp *MockStatusResponse) error {
+				if err := app.ResetMockOverrides(); err != nil {
+					return err
+				}
+				resp.Overrides = app.GetMockOverrides()
+				resp.Services = app.mockServiceStatuses()
+				return nil
+			}),
+		},
+	}
+
+	for _, svc := range services {
+		if err := app.Register(app.adminService(svc)); err != nil {
+			app.logger.WithError(err).WithField("service", svc.Name).Error("Failed to register mock admin service")
+		}
+	}
+}
+
+// GetMockOverrides 返回当前的运行时Mock开关覆盖
+func (app *App) GetMockOverrides() MockOverrides {
+	result := MockOverrides{Groups: map[string]bool{}, Services: map[string]bool{}}
+	if app.mockOverrides == nil {
+		return result
+	}
+
+	app.mockOverrides.mu.RLock()
+	defer app.mockOverrides.mu.RUnlock()
+
+	if app.mockOverrides.overrides.Global != nil {
+		enabled := *app.mockOverrides.overrides.Global
+		result.Global = &enabled
+	}
+	for k, v := range app.mockOverrides.overrides.Groups {
+		result.Groups[k] = v
+	}
+	for k, v := range app.mockOverrides.overrides.Services {
+		result.Services[k] = v
+	}
+	return result
+}
+
+// SetMockOverride 设置运行时Mock开关，scope为global、group或service，enabled为nil时清除覆盖
+func (app *App) SetMockOverride(scope, name string, enabled *bool) error {
+	if app.mockOverrides == nil {
+		return Reply(400, "Mock开关未初始化")
+	}
+	enabling := enabled != nil && *enabled
+	if enabling && app.mockForbidden() {
+		return ReplyWithDetail(403, "当前环境禁止开启Mock", "mock is forbidden in the "+app.Env()+" environment (mock.forbid_in)")
+	}
+
+	app.mockOverrides.mu.Lock()
+	overrides := &app.mockOverrides.overrides
+	switch scope {
+	case "global":
+		overrides.Global = enabled
+	case "group", "service":
+		target := &overrides.Groups
+		if scope == "service" {
+			target = &overrides.Services
+		}
+		if *target == nil {
+			*target = map[string]bool{}
+		}
+		if enabled == nil {
+			delete(*target, name)
+		} else {
+			(*target)[name] = *enabled
+		}
+	default:
+		app.mockOverrides.mu.Unlock()
+		return Reply(400, "无效的作用范围")
+	}
+	app.mockOverrides.revision.Add(1)
+	app.mockOverrides.mu.Unlock()
+
+	app.logger.WithFields(map[string]any{
+		"scope":   scope,
+		"name":    name,
+		"enabled": enabled,
+	}).Info("Mock override changed")
+
+	if enabling && app.mockWarned() {
+		if services := app.root().mockedServices(); len(services) > 0 {
+			app.alertMock(MockAlert{Env: app.Env(), Services: services, Source: "override", Time: time.Now()})
+		}
+	}
+	return app.saveMockOverrides()
+}
+
+// ResetMockOverrides 清除全部运行时Mock开关
+func (app *App) ResetMockOverrides() error {
+	if app.mockOverrides == nil {
+		return Reply(400, "Mock开关未初始化")
+	}
+
+	app.mockOverrides.mu.Lock()
+	app.mockOverrides.overrides = MockOverrides{}
+	app.mockOverrides.revision.Add(1)
+	app.mockOverrides.mu.Unlock()
+
+	app.logger.Info("Mock overrides reset")
+	return app.saveMockOverrides()
+}
+
+// mockOverride 查询指定级别的运行时覆盖
+func (app *App) mockOverride(scope, name string) (enabled bool, exists bool) {
+	if app.mockOverrides == nil {
+		return false, false
+	}
+
+	app.mockOverrides.mu.RLock()
+	defer app.mockOverrides.mu.RUnlock()
+
+	switch scope {
+	case "global":
+		if app.mockOverrides.overrides.Global != nil {
+			return *app.mockOverrides.overrides.Global, true
+		}
+	case "group":
+		enabled, exists = app.mockOverrides.overrides.Groups[name]
+	case "service":
+		enabled, exists = app.mockOverrides.overrides.Services[name]
+	}
+	return enabled, exists
+}
+
+// mockServiceStatuses 返回所有服务当前的Mock状态
+func (app *App) mockServiceStatuses() []MockServiceStatus {
+	services := app.allServices()
+	statuses := make([]MockServiceStatus, 0, len(services))
+	for i := range services {
+		svc := &services[i]
+		if svc.builtin {
+			continue
+		}
+		statuses = append(statuses, MockServiceStatus{
+			Name:    svc.Name,
+			Group:   svc.Group,
+			Enabled: svc.owner.isMockEnabled(svc),
+		})
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Name < statuses[j].Name
+	})
+	return statuses
+}
+
+// mockOverridesCacheKey 返回Mock开关在缓存中的键
+func (app *App) mockOverridesCacheKey() string {
+	if key := app.cfg.ModConfig.Mock.Admin.CacheKey; key != "" {
+		return key
+	}
+	return "mod:mock:overrides"
+}
+
+// loadMockOverrides 从缓存加载Mock开关
+func (app *App) loadMockOverrides() error {
+	key := app.mockOverridesCacheKey()
+
+	var data []byte
+	switch {
+	case app.redisClient != nil:
+		value, err := app.redisClient.Get(context.Background(), key).Bytes()
+		if err == redis.Nil {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		data = value
+	case app.badgerDB != nil:
+		err := app.badgerDB.View(func(txn *badger.Txn) error {
+			item, err := txn.Get([]byte(key))
+			if err != nil {
+				return err
+			}
+			data, err = item.ValueCopy(nil)
+			return err
+		})
+		if err == badger.ErrKeyNotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	default:
+		// 未配置持久化缓存，仅在内存中生效
+		return nil
+	}
+
+	var overrides MockOverrides
+	if err := json.Unmarshal(data, &overrides); err != nil {
+		return err
+	}
+
+	app.mockOverrides.mu.Lock()
+	app.mockOverrides.overrides = overrides
+	app.mockOverrides.revision.Add(1)
+	app.mockOverrides.mu.Unlock()
+	return nil
+}
+
+// saveMockOverrides 将Mock开关写入缓存，未启用Mock管理时仅在内存中生效
+func (app *App) saveMockOverrides() error {
+	if app.cfg.ModConfig == nil || !app.cfg.ModConfig.Mock.Admin.Enabled {
+		return nil
+	}
+
+	data, err := json.Marshal(app.GetMockOverrides())
+	if err != nil {
+		return err
+	}
+	key := app.mockOverridesCacheKey()
+
+	switch {
+	case app.redisClient != nil:
+		err = app.redisClient.Set(context.Background(), key, data, 0).Err()
+	case app.badgerDB != nil:
+		err = app.badgerDB.Update(func(txn *badger.Txn) error {
+			return txn.Set([]byte(key), data)
+		})
+	}
+	if err != nil {
+		app.logger.WithError(err).Error("Failed to persist mock overrides")
+	}
+	return err
+}
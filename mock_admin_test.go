@@ -0,0 +1,69 @@
+package mod
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMockAdminRequiresAdmin(t *testing.T) {
+	app := newTestApp(t, tokenCacheConfig+`
+mock:
+  admin:
+    enabled: true
+`)
+	assertAdminService(t, app, "mock_status", MockStatusRequest{})
+	assertAdminService(t, app, "mock_toggle", MockToggleRequest{Scope: "global"})
+	assertAdminService(t, app, "mock_reset", MockStatusRequest{})
+}
+
+func TestGlobalMockSkipsFrameworkServices(t *testing.T) {
+	app := newTestApp(t, tokenCacheConfig+`
+    admin:
+      enabled: true
+mock:
+  global:
+    enabled: true
+  admin:
+    enabled: true
+settings:
+  admin:
+    enabled: true
+reload:
+  admin:
+    enabled: true
+capture:
+  enabled: true
+  in_memory: true
+`)
+	registerPing(t, app, "business", AuthNone)
+	if err := app.SetToken("admin-token", map[string]any{"scope": "admin"}); err != nil {
+		t.Fatal(err)
+	}
+
+	builtin := 0
+	for _, svc := range app.allServices() {
+		mocked := app.isMockEnabled(&svc)
+		if svc.Name == "business" && !mocked {
+			t.Fatal("expected business service to be mocked under global mock")
+		}
+		if svc.builtin {
+			builtin++
+			if mocked {
+				t.Fatalf("framework service %s must not be mocked", svc.Name)
+			}
+		}
+	}
+	if builtin < 15 {
+		t.Fatalf("expected framework services to be marked builtin, got %d", builtin)
+	}
+
+	// 全局Mock下管理服务返回真实数据
+	resp, err := app.TestClient().WithToken("admin-token").Call("token_list", TokenListRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.AssertStatus(t, 200)
+	if !strings.Contains(string(resp.Body), `"admin-token"`) {
+		t.Fatalf("expected real token list, got %s", resp.Body)
+	}
+}
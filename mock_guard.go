@@ -0,0 +1,136 @@
+package mod
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// MockAlert 告警环境（mock.warn_in）中存在启用Mock的服务时的告警
+type MockAlert struct {
+	Env      string    `json:"env"`      // 运行环境
+	Services []string  `json:"services"` // 启用Mock的服务
+	Source   string    `json:"source"`   // startup（启动检查）或 override（运行时开关）
+	Time     time.Time `json:"time"`
+}
+
+// Env 返回运行环境，MOD_ENV 环境变量优先，其次为 app.env 配置
+func (app *App) Env() string {
+	if env := os.Getenv("MOD_ENV"); env != "" {
+		return env
+	}
+	if app.cfg.ModConfig != nil {
+		return app.cfg.ModConfig.App.Env
+	}
+	return ""
+}
+
+// OnMockAlert 注册告警环境中存在启用Mock的服务时的告警回调（启动时及运行时开启Mock时），回调在独立的 goroutine 中执行
+func (app *App) OnMockAlert(hook func(MockAlert)) {
+	app.mockOverrides.mu.Lock()
+	defer app.mockOverrides.mu.Unlock()
+	app.mockOverrides.alertHooks = append(app.mockOverrides.alertHooks, hook)
+}
+
+// mockForbidden 当前环境是否在 mock.forbid_in 中
+func (app *App) mockForbidden() bool {
+	return app.cfg.ModConfig != nil && envIn(app.Env(), app.cfg.ModConfig.Mock.ForbidIn)
+}
+
+// mockWarned 当前环境是否在 mock.warn_in 中
+func (app *App) mockWarned() bool {
+	return app.cfg.ModConfig != nil && envIn(app.Env(), app.cfg.ModConfig.Mock.WarnIn)
+}
+
+// envIn 判断环境是否在列表中（不区分大小写），环境为空时返回 false
+func envIn(env string, envs []string) bool {
+	if env == "" {
+		return false
+	}
+	for _, value := range envs {
+		if strings.EqualFold(value, env) {
+			return true
+		}
+	}
+	return false
+}
+
+// useMock 判断本次请求是否返回Mock数据：禁止Mock的环境中记录错误并执行真实的处理函数，
+// 告警环境中为每次Mock响应记录审计日志（audit=true，action=mock_response）
+func (app *App) useMock(ctx *Context, svc *Service) bool {
+	if !app.mockConfigured(svc) {
+		return false
+	}
+	forbidden, warned := app.mockForbidden(), app.mockWarned()
+	if !forbidden && !warned {
+		return true
+	}
+	audit := app.logger.WithFields(logrus.Fields{
+		"audit":   true,
+		"action":  "mock_response",
+		"service": svc.Name,
+		"group":   svc.Group,
+		"env":     app.Env(),
+		"rid":     ctx.GetRequestID(),
+	})
+	if forbidden {
+		audit.Error("Mock is forbidden in this environment (mock.forbid_in), executing the real handler")
+		return false
+	}
+	audit.Warn("Service responded with mock data in a protected environment (mock.warn_in)")
+	return true
+}
+
+// mockedServices 返回按配置和运行时开关启用Mock的服务名称，不考虑 mock.forbid_in
+func (app *App) mockedServices() []string {
+	var names []string
+	seen := map[string]bool{}
+	for _, svc := range app.allServices() {
+		if !seen[svc.Name] && svc.owner.mockConfigured(&svc) {
+			seen[svc.Name] = true
+			names = append(names, svc.Name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// checkMockGuard 启动前检查受保护环境中启用Mock的服务：mock.forbid_in 中的环境返回错误拒绝启动，
+// mock.warn_in 中的环境记录错误日志并触发告警回调
+func (app *App) checkMockGuard() error {
+	forbidden, warned := app.mockForbidden(), app.mockWarned()
+	if !forbidden && !warned {
+		return nil
+	}
+	services := app.mockedServices()
+	if len(services) == 0 {
+		return nil
+	}
+	env := app.Env()
+	if forbidden {
+		return fmt.Errorf("mock is forbidden in the %s environment (mock.forbid_in) but enabled for services: %s; "+
+			"turn off mock.global, mock.groups and mock.services or reset the runtime overrides", env, strings.Join(services, ", "))
+	}
+	app.alertMock(MockAlert{Env: env, Services: services, Source: "startup", Time: time.Now()})
+	return nil
+}
+
+// alertMock 记录错误日志并在独立的 goroutine 中调用告警回调
+func (app *App) alertMock(alert MockAlert) {
+	app.logger.WithFields(logrus.Fields{
+		"env":      alert.Env,
+		"services": alert.Services,
+		"source":   alert.Source,
+	}).Error("Services are running in mock mode in a protected environment (mock.warn_in)")
+
+	app.mockOverrides.mu.RLock()
+	hooks := app.mockOverrides.alertHooks
+	app.mockOverrides.mu.RUnlock()
+	for _, hook := range hooks {
+		go hook(alert)
+	}
+}
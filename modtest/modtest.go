@@ -0,0 +1,355 @@
+// Package modtest 提供服务的进程内测试工具
+//
+// Invoke 通过 fiber 的 App.Test 在内存中发起请求，完整经过中间件、参数绑定、参数验证、
+// 身份验证、权限检查和Mock逻辑，无需监听真实端口：
+//
+//	h := modtest.New(app, modtest.WithoutAuth())
+//	resp, _, err := modtest.Invoke[GetUserRequest, GetUserResponse](h, "get_user", &GetUserRequest{UserID: "1"})
+package modtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+
+	"github.com/iamdanielyin/mod"
+)
+
+// Harness 服务测试工具，持有被测应用及默认调用选项
+type Harness struct {
+	app  *mod.App
+	opts []Option
+
+	// mockMu 串行化修改Mock开关的调用，避免并发测试互相覆盖
+	mockMu sync.Mutex
+
+	// snapshots 已加载的契约快照，按路径缓存
+	snapshotMu sync.Mutex
+	snapshots  map[string]*mod.OpenAPISpec
+}
+
+// UpdateContractEnv 设置该环境变量为1时，WithContract 指定的快照文件会被当前生成的 OpenAPI 文档覆盖
+const UpdateContractEnv = "MOD_UPDATE_CONTRACT"
+
+// Option 调用选项
+type Option func(*callOptions)
+
+type callOptions struct {
+	headers      map[string]string
+	token        string
+	bypassAuth   bool
+	tokenData    any
+	mock         *bool
+	timeout      int
+	rawBody      []byte
+	rawBodyIsSet bool
+	contract     bool
+	snapshot     string
+}
+
+// Response 服务调用的原始响应
+type Response struct {
+	Status int         // HTTP状态码
+	Header http.Header // 响应头
+	Body   []byte      // 响应体
+
+	Code   int    // 业务状态码，ReturnRaw 服务为0
+	Msg    string // 响应消息
+	Detail string // 错误详情
+	Rid    string // 请求ID
+}
+
+// New 创建服务测试工具，opts 作为每次调用的默认选项
+func New(app *mod.App, opts ...Option) *Harness {
+	return &Harness{app: app, opts: opts}
+}
+
+// App 返回被测应用
+func (h *Harness) App() *mod.App {
+	return h.app
+}
+
+// WithHeader 设置请求头
+func WithHeader(key, value string) Option {
+	return func(o *callOptions) {
+		if o.headers == nil {
+			o.headers = map[string]string{}
+		}
+		o.headers[key] = value
+	}
+}
+
+// WithToken 使用指定的token发起请求
+func WithToken(token string) Option {
+	return func(o *callOptions) {
+		o.token = token
+		o.bypassAuth = false
+	}
+}
+
+// WithoutAuth 为本次调用签发临时token并写入缓存，调用结束后移除，用于跳过身份验证
+// 签发方式与 mod.TestClient 的 Login 相同，启用JWT时签发的是有效的JWT访问令牌
+func WithoutAuth() Option {
+	return func(o *callOptions) {
+		o.bypassAuth = true
+		o.token = ""
+	}
+}
+
+// WithTokenData 与 WithoutAuth 相同，并将 data 作为token缓存数据，用于通过权限检查
+func WithTokenData(data any) Option {
+	return func(o *callOptions) {
+		o.bypassAuth = true
+		o.token = ""
+		o.tokenData = data
+	}
+}
+
+// WithMock 在本次调用期间强制开启或关闭服务的Mock，调用结束后恢复原有的运行时开关
+func WithMock(enabled bool) Option {
+	return func(o *callOptions) {
+		o.mock = &enabled
+	}
+}
+
+// WithMockSeed 指定Mock数据的随机种子（X-Mock-Seed）
+func WithMockSeed(seed int64) Option {
+	return WithHeader("X-Mock-Seed", strconv.FormatInt(seed, 10))
+}
+
+// WithMockScenario 指定Mock夹具场景（X-Mock-Scenario）
+func WithMockScenario(scenario string) Option {
+	return WithHeader("X-Mock-Scenario", scenario)
+}
+
+// WithRawBody 使用原始请求体代替JSON序列化后的请求参数，用于测试参数解析错误等场景
+func WithRawBody(body []byte) Option {
+	return func(o *callOptions) {
+		o.rawBody = body
+		o.rawBodyIsSet = true
+	}
+}
+
+// WithTimeout 设置调用超时（毫秒），默认不超时
+func WithTimeout(ms int) Option {
+	return func(o *callOptions) {
+		o.timeout = ms
+	}
+}
+
+// WithContract 校验响应是否符合当前生成的 OpenAPI 文档，snapshot 非空时同时校验是否符合已提交的契约快照
+// 快照不存在或设置了 MOD_UPDATE_CONTRACT=1 时写入当前文档，结构体改动破坏已发布的契约（删除字段、修改类型等）时调用返回 *mod.ContractError
+func WithContract(snapshot string) Option {
+	return func(o *callOptions) {
+		o.contract = true
+		o.snapshot = snapshot
+	}
+}
+
+// Invoke 调用名为 name 的服务并将响应数据解析为 TResp
+// 业务处理失败（响应code非0或HTTP状态码非2xx）时返回 mod.Reply 生成的错误，可通过 Code() 获取错误码；
+// Response 始终返回（请求未能发出时除外），便于断言状态码、响应头等细节
+func Invoke[TReq, TResp any](h *Harness, name string, req *TReq, opts ...Option) (*TResp, *Response, error) {
+	resp, err := h.Do(name, req, opts...)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	out := new(TResp)
+	svc, _ := h.app.GetService(name)
+	if svc.ReturnRaw && resp.Status < 400 {
+		if len(resp.Body) > 0 {
+			if err := json.Unmarshal(resp.Body, out); err != nil {
+				return nil, resp, fmt.Errorf("modtest: failed to decode response of %s: %w", name, err)
+			}
+		}
+		return out, resp, nil
+	}
+
+	var envelope struct {
+		Code   int             `json:"code"`
+		Data   json.RawMessage `json:"data"`
+		Msg    string          `json:"msg"`
+		Detail string          `json:"detail"`
+		Rid    string          `json:"rid"`
+	}
+	if err := json.Unmarshal(resp.Body, &envelope); err != nil {
+		return nil, resp, fmt.Errorf("modtest: failed to decode response of %s (status %d): %w", name, resp.Status, err)
+	}
+	resp.Code = envelope.Code
+	resp.Msg = envelope.Msg
+	resp.Detail = envelope.Detail
+	resp.Rid = envelope.Rid
+
+	if envelope.Code != 0 || resp.Status >= 400 {
+		code := envelope.Code
+		if code == 0 {
+			code = resp.Status
+		}
+		return nil, resp, mod.ReplyWithDetail(code, envelope.Msg, envelope.Detail)
+	}
+
+	if len(envelope.Data) > 0 && string(envelope.Data) != "null" {
+		if err := json.Unmarshal(envelope.Data, out); err != nil {
+			return nil, resp, fmt.Errorf("modtest: failed to decode response data of %s: %w", name, err)
+		}
+	}
+	return out, resp, nil
+}
+
+// Do 调用名为 name 的服务并返回原始响应，不解析响应体
+func (h *Harness) Do(name string, req any, opts ...Option) (*Response, error) {
+	o := &callOptions{timeout: -1}
+	for _, opt := range h.opts {
+		opt(o)
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	body := o.rawBody
+	if !o.rawBodyIsSet {
+		if req == nil {
+			body = []byte("{}")
+		} else {
+			data, err := json.Marshal(req)
+			if err != nil {
+				return nil, fmt.Errorf("modtest: failed to encode request of %s: %w", name, err)
+			}
+			body = data
+		}
+	}
+
+	if o.mock != nil {
+		restore, err := h.overrideMock(name, *o.mock)
+		if err != nil {
+			return nil, err
+		}
+		defer restore()
+	}
+
+	client := h.app.TestClient().WithTimeout(o.timeout)
+	for k, v := range o.headers {
+		client.WithHeader(k, v)
+	}
+	if o.bypassAuth {
+		data, err := tokenDataMap(o.tokenData)
+		if err != nil {
+			return nil, err
+		}
+		client.WithUserFactory(func(*mod.App) (*mod.TestUser, error) {
+			return &mod.TestUser{ID: "modtest", Username: "modtest", Data: data}, nil
+		})
+		defer client.Logout()
+	} else if o.token != "" {
+		client.WithToken(o.token)
+	}
+
+	resp, err := client.Call(name, body)
+	if err != nil {
+		return nil, fmt.Errorf("modtest: failed to invoke %s: %w", name, err)
+	}
+
+	result := &Response{
+		Status: resp.StatusCode,
+		Header: resp.Header,
+		Body:   resp.Body,
+	}
+
+	if o.contract {
+		if err := h.checkContract(name, result, o.snapshot); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// checkContract 校验响应是否符合生成的文档及契约快照
+func (h *Harness) checkContract(name string, resp *Response, snapshot string) error {
+	if err := h.app.ValidateResponse(nil, name, resp.Status, resp.Body); err != nil {
+		return err
+	}
+	if snapshot == "" {
+		return nil
+	}
+
+	spec, err := h.loadSnapshot(snapshot)
+	if err != nil {
+		return err
+	}
+	if err := h.app.ValidateResponse(spec, name, resp.Status, resp.Body); err != nil {
+		return fmt.Errorf("modtest: contract snapshot %s: %w", snapshot, err)
+	}
+	return nil
+}
+
+// loadSnapshot 加载契约快照，快照不存在或需要更新时写入当前生成的文档
+func (h *Harness) loadSnapshot(path string) (*mod.OpenAPISpec, error) {
+	h.snapshotMu.Lock()
+	defer h.snapshotMu.Unlock()
+
+	if spec, ok := h.snapshots[path]; ok {
+		return spec, nil
+	}
+
+	_, statErr := os.Stat(path)
+	if os.IsNotExist(statErr) || os.Getenv(UpdateContractEnv) == "1" {
+		if err := h.app.WriteOpenAPI(path); err != nil {
+			return nil, fmt.Errorf("modtest: failed to write contract snapshot %s: %w", path, err)
+		}
+	}
+
+	spec, err := mod.LoadOpenAPISpec(path)
+	if err != nil {
+		return nil, fmt.Errorf("modtest: failed to load contract snapshot: %w", err)
+	}
+	if h.snapshots == nil {
+		h.snapshots = map[string]*mod.OpenAPISpec{}
+	}
+	h.snapshots[path] = spec
+	return spec, nil
+}
+
+// tokenDataMap 将token数据转换为map
+func tokenDataMap(data any) (map[string]any, error) {
+	if data == nil {
+		return nil, nil
+	}
+	if m, ok := data.(map[string]any); ok {
+		return m, nil
+	}
+
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("modtest: failed to encode token data: %w", err)
+	}
+	var m map[string]any
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return nil, fmt.Errorf("modtest: token data must be an object: %w", err)
+	}
+	return m, nil
+}
+
+// overrideMock 临时设置服务的运行时Mock开关，返回恢复函数
+func (h *Harness) overrideMock(name string, enabled bool) (func(), error) {
+	h.mockMu.Lock()
+
+	var previous *bool
+	if v, ok := h.app.GetMockOverrides().Services[name]; ok {
+		previous = &v
+	}
+	if err := h.app.SetMockOverride("service", name, &enabled); err != nil {
+		h.mockMu.Unlock()
+		return nil, fmt.Errorf("modtest: failed to override mock: %w", err)
+	}
+
+	return func() {
+		defer h.mockMu.Unlock()
+		if err := h.app.SetMockOverride("service", name, previous); err != nil {
+			h.app.WithError(err).WithField("service", name).Warn("modtest: failed to restore mock override")
+		}
+	}, nil
+}
@@ -0,0 +1,160 @@
+package mod
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// mountedApp 挂载到父应用的子应用
+type mountedApp struct {
+	prefix string
+	app    *App
+}
+
+// SubApp 创建名为 name 的子应用，用于按团队或业务模块拆分服务（模块化单体）。
+// 子应用拥有独立的服务、分组、中间件和配置（父应用配置叠加 mod.yml 中 apps.<name> 的配置），
+// 与父应用共享日志、Token缓存、文件存储、Mock开关和ID生成器，通过 app.Mount 挂载后生效
+func (app *App) SubApp(name string) *App {
+	cfg := app.cfg
+	cfg.ModConfig = app.subAppConfig(name)
+
+	// 子应用使用自身的错误处理器，以便执行子应用注册的错误处理钩子
+	var sub *App
+	if app.frameworkErrors {
+		cfg.Config.ErrorHandler = func(c *fiber.Ctx, err error) error {
+			return sub.handleError(c, err)
+		}
+	}
+
+	sub = &App{
+		App:              fiber.New(cfg.Config),
+		cfg:              cfg,
+		logger:           app.logger,
+		tokenKeys:        cfg.ModConfig.App.TokenKeys,
+		tokenCache:       app.tokenCache,
+		badgerDB:         app.badgerDB,
+		redisClient:      app.redisClient,
+		ossClient:        app.ossClient,
+		gcsClient:        app.gcsClient,
+		cosClient:        app.cosClient,
+		qiniuClient:      app.qiniuClient,
+		fileStore:        app.fileStore,
+		downloadSecret:   app.downloadSecret,
+		mockOverrides:    app.mockOverrides,
+		settings:         app.settings,
+		keys:             app.keys,
+		risk:             app.risk,
+		longPoll:         app.longPoll,
+		websocket:        app.websocket,
+		goroutines:       app.goroutines,
+		templates:        app.templates,
+		captureDB:        app.captureDB,
+		slo:              app.slo,
+		shedder:          app.shedder,
+		limiter:          app.limiter,
+		rateLimit:        app.rateLimit,
+		metrics:          app.metrics,
+		tokenDegradation: app.tokenDegradation,
+		retry:            app.retry,
+		responseCache:    app.responseCache,
+		jobs:             app.jobs,
+		schedule:         app.schedule,
+		frameworkErrors:  app.frameworkErrors,
+		i18n:             app.i18n,
+		idGenerator:      app.idGenerator,
+		clock:            app.clock,
+		subName:          name,
+	}
+	if cfg.ModConfig.IDGenerator.Type != app.cfg.ModConfig.IDGenerator.Type {
+		sub.configureIDGenerator()
+	}
+	if len(sub.tokenKeys) == 0 {
+		sub.tokenKeys = app.tokenKeys
+	}
+	return sub
+}
+
+// subAppConfig 复制父应用配置并叠加 apps.<name> 中的配置
+func (app *App) subAppConfig(name string) *ModConfig {
+	config := &ModConfig{}
+	data, err := yaml.Marshal(app.cfg.ModConfig)
+	if err == nil {
+		err = yaml.Unmarshal(data, config)
+	}
+	if err != nil {
+		app.logger.WithError(err).WithField("app", name).Warn("Failed to copy config for sub app, using parent config")
+		copied := *app.cfg.ModConfig
+		config = &copied
+	}
+
+	if node, ok := app.cfg.ModConfig.Apps[name]; ok {
+		if err := node.Decode(config); err != nil {
+			app.logger.WithError(err).WithField("app", name).Warn("Invalid apps config for sub app, ignored")
+		}
+	}
+	if config.App.ServiceBase == "" {
+		config.App.ServiceBase = app.cfg.ModConfig.App.ServiceBase
+	}
+	return config
+}
+
+// Mount 将子应用挂载到 prefix 下，子应用的服务访问路径为 prefix + 子应用的服务路径，
+// 如 /billing/services/create_invoice，并合并到父应用的文档、OpenAPI 和 TypeScript SDK 中。
+// 服务名称在父子应用间必须唯一
+func (app *App) Mount(prefix string, sub *App) error {
+	prefix = "/" + strings.Trim(strings.TrimSpace(prefix), "/")
+	if prefix == "/" {
+		return fmt.Errorf("mount prefix must not be empty")
+	}
+	if sub == nil || sub == app {
+		return fmt.Errorf("mount %s: invalid sub app", prefix)
+	}
+	if sub.parent != nil {
+		return fmt.Errorf("mount %s: sub app is already mounted", prefix)
+	}
+	for _, m := range app.mounts {
+		if m.prefix == prefix {
+			return fmt.Errorf("mount %s: prefix is already in use", prefix)
+		}
+	}
+	for _, svc := range sub.allServices() {
+		if _, exists := app.root().GetService(svc.Name); exists {
+			return fmt.Errorf("mount %s: service %q is already registered", prefix, svc.Name)
+		}
+	}
+
+	app.App.Mount(prefix, sub.App)
+	sub.parent = app
+	app.mounts = append(app.mounts, mountedApp{prefix: prefix, app: sub})
+	app.invalidateDocs()
+
+	app.logger.WithFields(map[string]any{
+		"app":      sub.subName,
+		"prefix":   prefix,
+		"services": len(sub.allServices()),
+	}).Info("Sub app mounted")
+	return nil
+}
+
+// root 返回挂载树的根应用
+func (app *App) root() *App {
+	for app.parent != nil {
+		app = app.parent
+	}
+	return app
+}
+
+// allServices 返回本应用及挂载的子应用中的全部服务，子应用服务的路径包含挂载前缀
+func (app *App) allServices() []Service {
+	services := append([]Service(nil), app.services...)
+	for _, m := range app.mounts {
+		for _, svc := range m.app.allServices() {
+			svc.path = m.prefix + svc.path
+			services = append(services, svc)
+		}
+	}
+	return services
+}
@@ -0,0 +1,125 @@
+package mod
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"reflect"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+var (
+	fileHeaderType  = reflect.TypeOf((*multipart.FileHeader)(nil))
+	fileHeadersType = reflect.TypeOf([]*multipart.FileHeader(nil))
+)
+
+// IsMultipart 请求是否为 multipart/form-data
+func IsMultipart(c *fiber.Ctx) bool {
+	return BodyFormat(c) == BodyMultipart
+}
+
+// BindMultipart 将 multipart/form-data 请求绑定到结构体：mod:"from=file" 的 *multipart.FileHeader、
+// []*multipart.FileHeader 字段读取上传的文件（name 默认为小写字段名）；没有 mod 标签的字段按 json 字段名读取表单值，
+// 相当于 JSON 请求体，结构体、切片等类型的表单值按 JSON 解析。mod gen 生成的绑定代码同样调用此函数
+func BindMultipart(c *fiber.Ctx, in any) error {
+	rv := reflect.ValueOf(in)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("input parameter must be a pointer to struct")
+	}
+	form, err := c.MultipartForm()
+	if err != nil {
+		return fmt.Errorf("failed to parse multipart form: %w", err)
+	}
+
+	rv = rv.Elem()
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field, fieldType := rv.Field(i), rt.Field(i)
+		if !field.CanSet() {
+			continue
+		}
+
+		if modTag := fieldType.Tag.Get("mod"); modTagValue(modTag, "from", "") == "file" {
+			files := form.File[modTagValue(modTag, "name", strings.ToLower(fieldType.Name))]
+			switch fieldType.Type {
+			case fileHeaderType:
+				if len(files) > 0 {
+					field.Set(reflect.ValueOf(files[0]))
+				}
+			case fileHeadersType:
+				field.Set(reflect.ValueOf(files))
+			default:
+				return fmt.Errorf("field %s: from=file requires *multipart.FileHeader or []*multipart.FileHeader", fieldType.Name)
+			}
+		}
+	}
+	return bindFormValues(rv, form.Value)
+}
+
+// bindFormValues 将表单值按 json 字段名绑定到没有 mod 标签的字段
+func bindFormValues(rv reflect.Value, form map[string][]string) error {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field, fieldType := rv.Field(i), rt.Field(i)
+		if !field.CanSet() || fieldType.Tag.Get("mod") != "" {
+			continue
+		}
+
+		name, _, skip := openAPIFieldName(fieldType)
+		if skip {
+			continue
+		}
+		values := form[name]
+		if len(values) == 0 {
+			continue
+		}
+		// 字符串不要求写成 JSON 格式，字符串切片也可以重复同名的表单值
+		switch {
+		case field.Kind() == reflect.String:
+			field.SetString(values[0])
+			continue
+		case field.Kind() == reflect.Ptr && field.Type().Elem().Kind() == reflect.String:
+			v := reflect.New(field.Type().Elem())
+			v.Elem().SetString(values[0])
+			field.Set(v)
+			continue
+		case field.Kind() == reflect.Slice && field.Type().Elem().Kind() == reflect.String && !strings.HasPrefix(strings.TrimSpace(values[0]), "["):
+			v := reflect.MakeSlice(field.Type(), len(values), len(values))
+			for i, value := range values {
+				v.Index(i).SetString(value)
+			}
+			field.Set(v)
+			continue
+		}
+		if err := json.Unmarshal([]byte(values[0]), field.Addr().Interface()); err != nil {
+			return fmt.Errorf("failed to parse form field %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// requestBodyForLog 参数解析失败时记录的请求体，multipart 请求不记录上传的文件内容
+func requestBodyForLog(c *fiber.Ctx) string {
+	if IsMultipart(c) {
+		return fmt.Sprintf("[multipart/form-data, %d bytes]", len(c.Body()))
+	}
+	return string(c.Body())
+}
+
+// multipartInput 请求结构体是否包含 mod:"from=file" 的文件字段，包含时文档以 multipart/form-data 描述请求体
+func multipartInput(t reflect.Type) bool {
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return false
+	}
+	for i := 0; i < t.NumField(); i++ {
+		if modTagValue(t.Field(i).Tag.Get("mod"), "from", "") == "file" {
+			return true
+		}
+	}
+	return false
+}
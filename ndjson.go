@@ -0,0 +1,259 @@
+package mod
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sync"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// errImportStopped 处理函数返回时请求体尚未读完
+var errImportStopped = errors.New("import handler returned before the body was read")
+
+// MIMEApplicationNDJSON 批量导入服务的请求体类型，每行一个 JSON 对象
+const MIMEApplicationNDJSON = "application/x-ndjson"
+
+// ImportRow 批量导入的一行数据，已通过参数校验
+type ImportRow[T any] struct {
+	Line int // 在请求体中的行号，从1开始
+	Data *T
+}
+
+// ImportError 导入失败的行
+type ImportError struct {
+	Line  int    `json:"line"`  // 行号，从1开始
+	Error string `json:"error"` // 失败原因
+}
+
+// ImportResult 批量导入结果，解析或校验失败的行由框架记录，处理失败的行由处理函数调用 Fail 记录
+type ImportResult struct {
+	Total     int           `json:"total"`            // 处理的数据行数，不含空行和处理函数提前返回时未处理的行
+	Succeeded int           `json:"succeeded"`        // 成功的行数
+	Failed    int           `json:"failed"`           // 失败的行数
+	Errors    []ImportError `json:"errors,omitempty"` // 失败的行，最多记录 import.max_errors 条
+
+	mu        sync.Mutex
+	maxErrors int
+}
+
+// Fail 记录处理失败的行，可在多个 goroutine 中调用
+func (r *ImportResult) Fail(line int, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Failed++
+	if r.maxErrors <= 0 || len(r.Errors) < r.maxErrors {
+		r.Errors = append(r.Errors, ImportError{Line: line, Error: err.Error()})
+	}
+}
+
+// read 记录读取的数据行
+func (r *ImportResult) read() {
+	r.mu.Lock()
+	r.Total++
+	r.mu.Unlock()
+}
+
+// importRequest 批量导入服务的请求体及读取配置
+type importRequest struct {
+	body        io.Reader
+	maxLineSize int
+	maxErrors   int
+	buffer      int
+}
+
+// String 日志中只输出读取配置
+func (r *importRequest) String() string {
+	return fmt.Sprintf("ndjson (max line %d bytes)", r.maxLineSize)
+}
+
+// MakeImportHandler 创建批量导入服务的 Handler：框架逐行读取 NDJSON 请求体，解析并校验为 T 后通过 rows 传给处理函数，
+// 不将整个请求体读入内存（需启用 import.stream）。解析或校验失败的行记入 result.Errors 并继续读取，
+// 处理函数返回后响应 result
+//
+//	mod.MakeImportHandler(func(ctx *mod.Context, rows <-chan mod.ImportRow[User], result *mod.ImportResult) error {
+//		for row := range rows {
+//			if err := repo.Save(row.Data); err != nil {
+//				result.Fail(row.Line, err)
+//			}
+//		}
+//		return nil
+//	})
+func MakeImportHandler[T any](handler func(ctx *Context, rows <-chan ImportRow[T], result *ImportResult) error) Handler {
+	return Handler{
+		Func: func(ctx *Context, args any, reply any) error {
+			req, ok := args.(*importRequest)
+			if !ok {
+				return fmt.Errorf("invalid args type")
+			}
+			result, ok := reply.(*ImportResult)
+			if !ok {
+				return fmt.Errorf("invalid reply type")
+			}
+			return runImport(ctx, req, result, handler)
+		},
+		InputType:  reflect.TypeOf((*T)(nil)).Elem(),
+		OutputType: reflect.TypeOf(ImportResult{}),
+		ndjson:     true,
+	}
+}
+
+// runImport 在单独的 goroutine 中读取请求体，处理函数返回后停止读取并等待其结束
+func runImport[T any](ctx *Context, req *importRequest, result *ImportResult, handler func(*Context, <-chan ImportRow[T], *ImportResult) error) error {
+	result.maxErrors = req.maxErrors
+	rows := make(chan ImportRow[T], req.buffer)
+	done := make(chan struct{})
+	var readErr error
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(rows)
+		defer func() {
+			if r := recover(); r != nil {
+				readErr = fmt.Errorf("panic: %v", r)
+			}
+		}()
+		readErr = readImportRows(ctx, req, result, rows, done)
+	}()
+
+	err := handler(ctx, rows, result)
+	close(done)
+	wg.Wait()
+	// 处理函数提前返回时通道中未处理的行不计入结果
+	for range rows {
+		result.Total--
+	}
+	if readErr != nil {
+		// 未读取的请求体留在连接中，关闭连接以免被当作下一个请求解析
+		ctx.Context().SetConnectionClose()
+		if errors.Is(readErr, errImportStopped) {
+			readErr = nil
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if readErr != nil {
+		return ReplyWithDetail(400, "Import body read error", readErr.Error())
+	}
+	result.Succeeded = result.Total - result.Failed
+	return nil
+}
+
+// readImportRows 逐行解析和校验请求体，空行被忽略；处理函数提前返回（done 关闭）时停止读取
+func readImportRows[T any](ctx *Context, req *importRequest, result *ImportResult, rows chan<- ImportRow[T], done <-chan struct{}) error {
+	decode := ctx.App().Config().JSONDecoder
+	r := bufio.NewReaderSize(req.body, 64*1024)
+	var buf []byte
+	for line := 1; ; line++ {
+		var tooLong bool
+		var err error
+		buf, tooLong, err = readImportLine(r, req.maxLineSize, buf)
+		if err != nil && err != io.EOF {
+			return err
+		}
+		data := bytes.TrimSpace(buf)
+		if len(data) > 0 || tooLong {
+			if row, rowErr := parseImportRow[T](decode, data, tooLong, req.maxLineSize); rowErr != nil {
+				result.read()
+				result.Fail(line, rowErr)
+			} else {
+				select {
+				case rows <- ImportRow[T]{Line: line, Data: row}:
+					result.read()
+				case <-done:
+					return errImportStopped
+				}
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+	}
+}
+
+// parseImportRow 解析并校验一行数据，校验规则与普通服务的参数相同
+func parseImportRow[T any](decode func([]byte, any) error, data []byte, tooLong bool, maxLineSize int) (*T, error) {
+	if tooLong {
+		return nil, fmt.Errorf("line exceeds %d bytes", maxLineSize)
+	}
+	row := new(T)
+	if err := decode(data, row); err != nil {
+		return nil, err
+	}
+	var err error
+	if rv, ok := any(row).(RequestValidator); ok {
+		err = rv.ValidateRequest()
+	} else if reflect.TypeOf(row).Elem().Kind() == reflect.Struct {
+		err = validate.Struct(row)
+	}
+	return row, err
+}
+
+// readImportLine 读取一行（不含换行符），超过 maxSize 的行读到行尾后丢弃并返回 tooLong；
+// 最后一行没有换行符时同时返回数据和 io.EOF
+func readImportLine(r *bufio.Reader, maxSize int, buf []byte) (line []byte, tooLong bool, err error) {
+	buf = buf[:0]
+	for {
+		chunk, err := r.ReadSlice('\n')
+		if !tooLong {
+			if len(buf)+len(bytes.TrimRight(chunk, "\r\n")) > maxSize {
+				tooLong, buf = true, buf[:0]
+			} else {
+				buf = append(buf, chunk...)
+			}
+		}
+		if errors.Is(err, bufio.ErrBufferFull) {
+			continue
+		}
+		return buf, tooLong, err
+	}
+}
+
+// bindImportRequest 批量导入服务的请求体：启用 import.stream 时直接读取请求流，否则读取已接收的请求体
+func (app *App) bindImportRequest(fc *fiber.Ctx) *importRequest {
+	config := app.cfg.ModConfig.Import
+	req := &importRequest{maxLineSize: 1024 * 1024, maxErrors: config.MaxErrors, buffer: config.Buffer}
+	if config.MaxLineSize != "" {
+		if size, err := parseSize(config.MaxLineSize); err == nil && size > 0 {
+			req.maxLineSize = int(size)
+		}
+	}
+	if req.maxErrors == 0 {
+		req.maxErrors = 1000
+	}
+	if req.buffer <= 0 {
+		req.buffer = 100
+	}
+	if stream := fc.Request().BodyStream(); stream != nil {
+		req.body = stream
+	} else {
+		req.body = bytes.NewReader(fc.Body())
+	}
+	return req
+}
+
+// limitRequestBody 启用 import.stream 后 Fiber 不再拒绝超过 server.body_limit 的请求体，
+// 非批量导入的服务在读取请求体前按 limit（server.body_limit 或服务的 request_body.max_size）读取并检查
+func (app *App) limitRequestBody(fc *fiber.Ctx, limit int) error {
+	stream := fc.Request().BodyStream()
+	if stream == nil {
+		return nil
+	}
+	body, err := io.ReadAll(io.LimitReader(stream, int64(limit)+1))
+	if err != nil {
+		return ReplyWithDetail(400, "Request body read error", err.Error())
+	}
+	if len(body) > limit {
+		// 未读取的请求体留在连接中，关闭连接以免被当作下一个请求解析
+		fc.Context().SetConnectionClose()
+		return Reply(413, "Request body too large")
+	}
+	fc.Request().SetBody(body)
+	return nil
+}
@@ -0,0 +1,798 @@
+package mod
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OpenAPISpec OpenAPI 3.0 文档
+type OpenAPISpec struct {
+	OpenAPI    string                      `json:"openapi"`
+	Info       OpenAPIInfo                 `json:"info"`
+	Paths      map[string]*OpenAPIPathItem `json:"paths"`
+	Components *OpenAPIComponents          `json:"components,omitempty"`
+}
+
+// OpenAPIInfo 文档基本信息
+type OpenAPIInfo struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+// OpenAPIPathItem 路径项，服务为POST接口，文件下载和 WebSocket 服务为GET接口
+type OpenAPIPathItem struct {
+	Get  *OpenAPIOperation `json:"get,omitempty"`
+	Post *OpenAPIOperation `json:"post,omitempty"`
+}
+
+// operation 返回路径项的接口定义
+func (item *OpenAPIPathItem) operation() *OpenAPIOperation {
+	if item.Post != nil {
+		return item.Post
+	}
+	return item.Get
+}
+
+// OpenAPIOperation 接口定义
+type OpenAPIOperation struct {
+	OperationID string                      `json:"operationId"`
+	Summary     string                      `json:"summary,omitempty"`
+	Description string                      `json:"description,omitempty"`
+	Tags        []string                    `json:"tags,omitempty"`
+	Parameters  []*OpenAPIParameter         `json:"parameters,omitempty"`
+	RequestBody *OpenAPIRequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*OpenAPIResponse `json:"responses"`
+	Security    []map[string][]string       `json:"security,omitempty"`
+	Scopes      []string                    `json:"x-mod-required-scopes,omitempty"`
+	ErrorCodes  []ErrorCode                 `json:"x-mod-error-codes,omitempty"`
+	ReturnRaw   bool                        `json:"x-mod-return-raw,omitempty"`
+	WebSocket   *OpenAPIWebSocket           `json:"x-mod-websocket,omitempty"`
+}
+
+// OpenAPIWebSocket WebSocket 服务双向的消息结构（JSON文本消息）
+type OpenAPIWebSocket struct {
+	Client *OpenAPISchema `json:"client,omitempty"` // 客户端发送的消息
+	Server *OpenAPISchema `json:"server,omitempty"` // 服务端推送的消息
+}
+
+// OpenAPIParameter 通过 mod 标签从查询参数或请求头获取的参数
+type OpenAPIParameter struct {
+	Name        string         `json:"name"`
+	In          string         `json:"in"`
+	Description string         `json:"description,omitempty"`
+	Required    bool           `json:"required,omitempty"`
+	Schema      *OpenAPISchema `json:"schema"`
+}
+
+// OpenAPIRequestBody 请求体
+type OpenAPIRequestBody struct {
+	Description string                       `json:"description,omitempty"`
+	Required    bool                         `json:"required,omitempty"`
+	Content     map[string]*OpenAPIMediaType `json:"content"`
+}
+
+// OpenAPIResponse 响应
+type OpenAPIResponse struct {
+	Description string                       `json:"description"`
+	Content     map[string]*OpenAPIMediaType `json:"content,omitempty"`
+}
+
+// OpenAPIMediaType 媒体类型
+type OpenAPIMediaType struct {
+	Schema *OpenAPISchema `json:"schema"`
+}
+
+// OpenAPIComponents 公共组件
+type OpenAPIComponents struct {
+	SecuritySchemes map[string]*OpenAPISecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+// OpenAPISecurityScheme 认证方式
+type OpenAPISecurityScheme struct {
+	Type        string `json:"type"`
+	Scheme      string `json:"scheme,omitempty"`
+	In          string `json:"in,omitempty"`
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// OpenAPISchema JSON Schema（OpenAPI 3.0 子集），结构体均内联展开
+type OpenAPISchema struct {
+	Type                 string                    `json:"type,omitempty"`
+	Format               string                    `json:"format,omitempty"`
+	Description          string                    `json:"description,omitempty"`
+	Nullable             bool                      `json:"nullable,omitempty"`
+	Enum                 []any                     `json:"enum,omitempty"`
+	Properties           map[string]*OpenAPISchema `json:"properties,omitempty"`
+	Required             []string                  `json:"required,omitempty"`
+	Items                *OpenAPISchema            `json:"items,omitempty"`
+	AdditionalProperties *OpenAPISchema            `json:"additionalProperties,omitempty"`
+}
+
+// 各认证方式在 OpenAPI 中的名称
+const (
+	openAPIBearerScheme    = "bearerAuth"
+	openAPIAPIKeyScheme    = "apiKeyAuth"
+	openAPISignatureScheme = "signatureAuth"
+)
+
+// OpenAPI 根据已注册的服务生成 OpenAPI 3.0 文档
+func (app *App) OpenAPI() *OpenAPISpec {
+	config := app.cfg.ModConfig
+	spec := &OpenAPISpec{
+		OpenAPI: "3.0.3",
+		Info: OpenAPIInfo{
+			Title:       config.App.DisplayName,
+			Description: config.App.Description,
+			Version:     config.App.Version,
+		},
+		Paths: map[string]*OpenAPIPathItem{},
+		Components: &OpenAPIComponents{
+			SecuritySchemes: map[string]*OpenAPISecurityScheme{
+				openAPIBearerScheme: {Type: "http", Scheme: "bearer"},
+			},
+		},
+	}
+	if spec.Info.Title == "" {
+		spec.Info.Title = config.App.Name
+	}
+	if spec.Info.Version == "" {
+		spec.Info.Version = "1.0.0"
+	}
+
+	for _, svc := range app.allServices() {
+		// 隐藏的服务仅在 docs.show_hidden_in 的环境中导出
+		if !svc.owner.docVisible(&svc) {
+			continue
+		}
+		// 多版本服务共用一个路径，使用最先注册的版本
+		path := openAPIPath(svc.path)
+		if _, exists := spec.Paths[path]; exists {
+			continue
+		}
+		if svc.ws != nil {
+			spec.Paths[path] = &OpenAPIPathItem{Get: app.openAPIWebSocketOperation(svc)}
+			continue
+		}
+		if len(svc.methods) > 0 {
+			spec.Paths[path] = &OpenAPIPathItem{Get: app.openAPIDownloadOperation(svc)}
+			continue
+		}
+		spec.Paths[path] = &OpenAPIPathItem{Post: app.openAPIOperation(svc)}
+	}
+
+	// 仅在有服务使用时声明 API Key 和请求签名认证方式
+	for _, item := range spec.Paths {
+		for _, requirement := range item.operation().Security {
+			if _, ok := requirement[openAPIAPIKeyScheme]; ok {
+				header := config.Auth.APIKeyHeader
+				if header == "" {
+					header = "X-API-Key"
+				}
+				spec.Components.SecuritySchemes[openAPIAPIKeyScheme] = &OpenAPISecurityScheme{Type: "apiKey", In: "header", Name: header}
+			}
+			if _, ok := requirement[openAPISignatureScheme]; ok {
+				header := config.Auth.Signature.Header
+				if header == "" {
+					header = "X-Signature"
+				}
+				spec.Components.SecuritySchemes[openAPISignatureScheme] = &OpenAPISecurityScheme{
+					Type:        "apiKey",
+					In:          "header",
+					Name:        header,
+					Description: "HMAC-SHA256(secret, timestamp + \"\\n\" + path + \"\\n\" + body)，时间戳通过时间戳请求头传递",
+				}
+			}
+		}
+	}
+	return spec
+}
+
+// openAPIOperation 生成单个服务的接口定义
+func (app *App) openAPIOperation(svc Service) *OpenAPIOperation {
+	op := &OpenAPIOperation{
+		OperationID: svc.Name,
+		Summary:     svc.DisplayName,
+		Description: svc.Description,
+		ReturnRaw:   svc.ReturnRaw,
+		Scopes:      svc.RequiredScopes,
+		ErrorCodes:  serviceErrorCodes(&svc),
+		Responses:   map[string]*OpenAPIResponse{},
+	}
+	if svc.Group != "" {
+		op.Tags = []string{svc.Group}
+	}
+	switch svc.owner.authStrategy(&svc) {
+	case AuthAPIKey:
+		op.Security = []map[string][]string{{openAPIAPIKeyScheme: {}}}
+	case AuthSignature:
+		op.Security = []map[string][]string{{openAPISignatureScheme: {}}}
+	case AuthNone:
+		if svc.Permission != nil || len(svc.RequiredScopes) > 0 {
+			op.Security = []map[string][]string{{openAPIBearerScheme: {}}}
+		}
+	default:
+		op.Security = []map[string][]string{{openAPIBearerScheme: {}}}
+	}
+
+	if svc.RawBody {
+		op.RequestBody = &OpenAPIRequestBody{
+			Required: true,
+			Content:  map[string]*OpenAPIMediaType{"*/*": {Schema: &OpenAPISchema{Type: "string", Format: "binary"}}},
+		}
+	} else if svc.Handler.ndjson {
+		body, _ := app.openAPIRequest(svc.Handler.InputType)
+		op.RequestBody = &OpenAPIRequestBody{
+			Required:    true,
+			Description: "NDJSON, one object per line",
+			Content:     map[string]*OpenAPIMediaType{MIMEApplicationNDJSON: {Schema: body}},
+		}
+	} else if svc.Handler.InputType != nil {
+		body, params := app.openAPIRequest(svc.Handler.InputType)
+		op.Parameters = params
+		op.RequestBody = &OpenAPIRequestBody{
+			Required: len(body.Required) > 0,
+			Content:  map[string]*OpenAPIMediaType{},
+		}
+		for _, mediaType := range requestMediaTypes(&svc) {
+			op.RequestBody.Content[mediaType] = &OpenAPIMediaType{Schema: body}
+		}
+	}
+
+	// 请求头策略中的必需请求头，请求参数中已有同名请求头时标记为必需
+	if svc.headerPolicy != nil {
+	required:
+		for _, name := range svc.headerPolicy.Required {
+			for _, param := range op.Parameters {
+				if param.In == "header" && strings.EqualFold(param.Name, name) {
+					param.Required = true
+					continue required
+				}
+			}
+			op.Parameters = append(op.Parameters, &OpenAPIParameter{Name: name, In: "header", Required: true, Schema: &OpenAPISchema{Type: "string"}})
+		}
+	}
+
+	data := &OpenAPISchema{}
+	if svc.Handler.OutputType != nil {
+		data = openAPISchemaOf(svc.Handler.OutputType, true, nil)
+	}
+	success := data
+	if !svc.ReturnRaw {
+		success = openAPIEnvelope(data)
+	}
+	op.Responses["200"] = &OpenAPIResponse{Description: "成功", Content: map[string]*OpenAPIMediaType{}}
+	op.Responses["default"] = &OpenAPIResponse{Description: "失败", Content: map[string]*OpenAPIMediaType{}}
+	failure := openAPIEnvelope(nil)
+	for _, mediaType := range serviceResponseMediaTypes(&svc) {
+		op.Responses["200"].Content[mediaType] = &OpenAPIMediaType{Schema: success}
+		op.Responses["default"].Content[mediaType] = &OpenAPIMediaType{Schema: failure}
+	}
+	return op
+}
+
+// openAPIDownloadOperation 文件下载服务的接口定义：路径参数来自路径模板，成功时响应文件内容
+func (app *App) openAPIDownloadOperation(svc Service) *OpenAPIOperation {
+	op := app.openAPIGetOperation(svc)
+	op.Parameters = append(op.Parameters, &OpenAPIParameter{
+		Name:        "Range",
+		In:          "header",
+		Description: "单段字节范围，如 bytes=0-1023",
+		Schema:      &OpenAPISchema{Type: "string"},
+	})
+	file := map[string]*OpenAPIMediaType{"application/octet-stream": {Schema: &OpenAPISchema{Type: "string", Format: "binary"}}}
+	op.Responses["200"] = &OpenAPIResponse{Description: "文件内容", Content: file}
+	op.Responses["206"] = &OpenAPIResponse{Description: "Range请求的部分内容", Content: file}
+	op.Responses["304"] = &OpenAPIResponse{Description: "文件未修改"}
+	op.Responses["416"] = &OpenAPIResponse{Description: "Range超出文件大小"}
+	return op
+}
+
+// openAPIWebSocketOperation WebSocket 服务的接口定义：握手成功时响应101，消息结构通过 x-mod-websocket 扩展描述
+func (app *App) openAPIWebSocketOperation(svc Service) *OpenAPIOperation {
+	op := app.openAPIGetOperation(svc)
+	delete(op.Responses, "200")
+	op.Responses["101"] = &OpenAPIResponse{Description: "升级为 WebSocket 连接"}
+	op.Responses["426"] = &OpenAPIResponse{Description: "不是 WebSocket 握手请求"}
+	op.WebSocket = &OpenAPIWebSocket{}
+	if svc.ws.clientType != nil {
+		op.WebSocket.Client = openAPISchemaOf(svc.ws.clientType, false, nil)
+	}
+	if svc.ws.serverType != nil {
+		op.WebSocket.Server = openAPISchemaOf(svc.ws.serverType, true, nil)
+	}
+	return op
+}
+
+// openAPIGetOperation GET 服务的接口定义：没有请求体，路径参数来自路径模板
+func (app *App) openAPIGetOperation(svc Service) *OpenAPIOperation {
+	op := app.openAPIOperation(svc)
+	op.RequestBody = nil
+	var headers []*OpenAPIParameter
+	for _, param := range op.Parameters {
+		if param.In == "header" {
+			headers = append(headers, param)
+		}
+	}
+	op.Parameters = nil
+	for _, segment := range strings.Split(svc.path, "/") {
+		if name, ok := strings.CutPrefix(segment, ":"); ok {
+			op.Parameters = append(op.Parameters, &OpenAPIParameter{
+				Name:     strings.TrimSuffix(name, "?"),
+				In:       "path",
+				Required: true,
+				Schema:   &OpenAPISchema{Type: "string"},
+			})
+		}
+	}
+	op.Parameters = append(op.Parameters, headers...)
+	return op
+}
+
+// openAPIEnvelope 标准响应格式，data 为空时表示错误响应
+func openAPIEnvelope(data *OpenAPISchema) *OpenAPISchema {
+	schema := &OpenAPISchema{
+		Type: "object",
+		Properties: map[string]*OpenAPISchema{
+			"code":   {Type: "integer", Description: "状态码，0表示成功"},
+			"msg":    {Type: "string", Description: "响应消息"},
+			"detail": {Type: "string", Description: "错误详情"},
+			"rid":    {Type: "string", Description: "请求ID"},
+		},
+		Required: []string{"code", "msg", "rid"},
+	}
+	if data != nil {
+		schema.Properties["data"] = data
+	}
+	return schema
+}
+
+// openAPIRequest 拆分请求结构体：mod 标签指定来源的字段作为参数，其余字段作为JSON请求体
+func (app *App) openAPIRequest(t reflect.Type) (*OpenAPISchema, []*OpenAPIParameter) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	body := openAPISchemaOf(t, false, nil)
+	if t.Kind() != reflect.Struct {
+		return body, nil
+	}
+
+	var params []*OpenAPIParameter
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		modTag := field.Tag.Get("mod")
+		from := modTagValue(modTag, "from", "query")
+		if !field.IsExported() || modTag == "" || from == "body" {
+			continue
+		}
+
+		name, _, skip := openAPIFieldName(field)
+		if from == "file" {
+			// 上传的文件作为 multipart 请求体中的二进制字段
+			if !skip {
+				delete(body.Properties, name)
+				body.Required = removeString(body.Required, name)
+			}
+			schema := &OpenAPISchema{Type: "string", Format: "binary"}
+			if field.Type == fileHeadersType {
+				schema = &OpenAPISchema{Type: "array", Items: schema}
+			}
+			schema.Description = field.Tag.Get("desc")
+			formName := modTagValue(modTag, "name", strings.ToLower(field.Name))
+			if body.Properties == nil {
+				body.Properties = map[string]*OpenAPISchema{}
+			}
+			body.Properties[formName] = schema
+			if hasValidateRule(field.Tag.Get("validate"), "required") {
+				body.Required = append(body.Required, formName)
+			}
+			continue
+		}
+		if skip {
+			continue
+		}
+		param := &OpenAPIParameter{
+			Name:        modTagValue(modTag, "name", strings.ToLower(field.Name)),
+			In:          "query",
+			Description: field.Tag.Get("desc"),
+			Required:    hasValidateRule(field.Tag.Get("validate"), "required"),
+			Schema:      openAPISchemaOf(field.Type, false, nil),
+		}
+		switch from {
+		case "header":
+			param.In = "header"
+		case "param":
+			// 路径参数总是必填
+			param.In = "path"
+			param.Required = true
+		}
+		params = append(params, param)
+
+		delete(body.Properties, name)
+		body.Required = removeString(body.Required, name)
+	}
+	return body, params
+}
+
+// modTagValue 读取 mod 标签中的配置项，支持 , 和 ; 分隔，如 "from=header;name=X-Token"
+func modTagValue(modTag, key, fallback string) string {
+	for _, part := range strings.FieldsFunc(modTag, func(r rune) bool { return r == ',' || r == ';' }) {
+		kv := strings.SplitN(strings.TrimSpace(part), "=", 2)
+		if len(kv) == 2 && strings.TrimSpace(kv[0]) == key {
+			return strings.TrimSpace(kv[1])
+		}
+	}
+	return fallback
+}
+
+// openAPIFieldName 按 encoding/json 的规则解析字段名
+func openAPIFieldName(field reflect.StructField) (name string, omitempty bool, skip bool) {
+	name = field.Name
+	if jsonTag := field.Tag.Get("json"); jsonTag != "" {
+		parts := strings.Split(jsonTag, ",")
+		if parts[0] == "-" && len(parts) == 1 {
+			return "", false, true
+		}
+		if parts[0] != "" {
+			name = parts[0]
+		}
+		for _, opt := range parts[1:] {
+			if opt == "omitempty" || opt == "omitzero" {
+				omitempty = true
+			}
+		}
+	}
+	return name, omitempty, false
+}
+
+// openAPISchemaOf 生成类型的Schema
+// response 为true时按序列化结果描述：未设置 omitempty 的字段总会输出，标记为必填；指针、切片、map可能输出null
+// 请求中仅 validate:"required" 的字段标记为必填
+func openAPISchemaOf(t reflect.Type, response bool, visiting map[reflect.Type]bool) *OpenAPISchema {
+	nullable := false
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+		nullable = response
+	}
+
+	if t == reflect.TypeOf(time.Time{}) {
+		return &OpenAPISchema{Type: "string", Format: "date-time", Nullable: nullable}
+	}
+	if t == reflect.TypeOf(json.RawMessage{}) {
+		return &OpenAPISchema{Nullable: nullable}
+	}
+
+	schema := &OpenAPISchema{Nullable: nullable}
+	switch t.Kind() {
+	case reflect.String:
+		schema.Type = "string"
+	case reflect.Bool:
+		schema.Type = "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		schema.Type = "integer"
+		schema.Format = "int32"
+	case reflect.Int64, reflect.Uint64:
+		schema.Type = "integer"
+		schema.Format = "int64"
+	case reflect.Float32:
+		schema.Type = "number"
+		schema.Format = "float"
+	case reflect.Float64:
+		schema.Type = "number"
+		schema.Format = "double"
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 && t.Kind() == reflect.Slice {
+			schema.Type = "string"
+			schema.Format = "byte"
+			schema.Nullable = response
+			break
+		}
+		schema.Type = "array"
+		schema.Items = openAPISchemaOf(t.Elem(), response, visiting)
+		schema.Nullable = response && t.Kind() == reflect.Slice
+	case reflect.Map:
+		schema.Type = "object"
+		schema.AdditionalProperties = openAPISchemaOf(t.Elem(), response, visiting)
+		schema.Nullable = response
+	case reflect.Struct:
+		schema.Type = "object"
+		schema.Properties = map[string]*OpenAPISchema{}
+		if visiting[t] {
+			// 递归类型不再展开
+			return schema
+		}
+		if visiting == nil {
+			visiting = map[reflect.Type]bool{}
+		}
+		visiting[t] = true
+		openAPIStructFields(t, response, visiting, schema)
+		delete(visiting, t)
+	case reflect.Interface:
+		// 任意类型
+		schema.Nullable = response
+	}
+	return schema
+}
+
+// openAPIStructFields 填充结构体字段，匿名嵌入的结构体按 encoding/json 的规则展开
+func openAPIStructFields(t reflect.Type, response bool, visiting map[reflect.Type]bool, schema *OpenAPISchema) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, omitempty, skip := openAPIFieldName(field)
+		if skip {
+			continue
+		}
+
+		if field.Anonymous && field.Tag.Get("json") == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				openAPIStructFields(embedded, response, visiting, schema)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+
+		prop := openAPISchemaOf(field.Type, response, visiting)
+		prop.Description = field.Tag.Get("desc")
+
+		validateTag := field.Tag.Get("validate")
+		if rules := parseValidateRules(validateTag); rules["oneof"] != "" && (prop.Type == "string" || prop.Type == "integer" || prop.Type == "number") {
+			for _, v := range strings.Fields(rules["oneof"]) {
+				if prop.Type == "string" {
+					prop.Enum = append(prop.Enum, v)
+				} else if n, err := strconv.ParseFloat(v, 64); err == nil {
+					prop.Enum = append(prop.Enum, n)
+				}
+			}
+		}
+
+		schema.Properties[name] = prop
+		if (response && !omitempty) || (!response && hasValidateRule(validateTag, "required")) {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+	sort.Strings(schema.Required)
+}
+
+// hasValidateRule 判断 validate 标签是否包含指定规则（dive之后的规则作用于元素，不计入）
+func hasValidateRule(validateTag, rule string) bool {
+	_, ok := parseValidateRules(validateTag)[rule]
+	return ok
+}
+
+// removeString 从切片中移除指定字符串
+func removeString(items []string, target string) []string {
+	result := items[:0]
+	for _, item := range items {
+		if item != target {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// WriteOpenAPI 将 OpenAPI 文档写入文件，通常用于生成需要提交到仓库的契约快照
+func (app *App) WriteOpenAPI(path string) error {
+	data, err := json.MarshalIndent(app.OpenAPI(), "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// LoadOpenAPISpec 从文件加载 OpenAPI 文档
+func LoadOpenAPISpec(path string) (*OpenAPISpec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var spec OpenAPISpec
+	if err := json.Unmarshal(data, &spec); err != nil {
+		return nil, fmt.Errorf("failed to parse openapi spec %s: %w", path, err)
+	}
+	return &spec, nil
+}
+
+// Operation 按服务名查找接口定义
+func (spec *OpenAPISpec) Operation(service string) *OpenAPIOperation {
+	for _, item := range spec.Paths {
+		if op := item.operation(); op != nil && op.OperationID == service {
+			return op
+		}
+	}
+	return nil
+}
+
+// ContractError 响应不符合 OpenAPI 契约
+type ContractError struct {
+	Service    string
+	Violations []string
+}
+
+func (e *ContractError) Error() string {
+	return fmt.Sprintf("response of %s violates contract:\n  %s", e.Service, strings.Join(e.Violations, "\n  "))
+}
+
+// ValidateResponse 校验服务的成功响应体是否符合 spec 中的响应定义，spec 为nil时使用当前生成的文档
+// 错误响应（HTTP状态码非2xx）按标准响应格式校验
+func (app *App) ValidateResponse(spec *OpenAPISpec, service string, status int, body []byte) error {
+	if spec == nil {
+		spec = app.OpenAPI()
+	}
+	op := spec.Operation(service)
+	if op == nil {
+		return &ContractError{Service: service, Violations: []string{"service not found in spec"}}
+	}
+
+	key := "200"
+	if status >= 300 {
+		key = "default"
+	}
+	resp := op.Responses[key]
+	if resp == nil || resp.Content["application/json"] == nil {
+		return nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return &ContractError{Service: service, Violations: []string{"invalid json: " + err.Error()}}
+	}
+
+	if violations := resp.Content["application/json"].Schema.Validate(value); len(violations) > 0 {
+		return &ContractError{Service: service, Violations: violations}
+	}
+	return nil
+}
+
+// Validate 校验由 encoding/json（UseNumber）解码的值是否符合Schema，返回全部违规项
+// 未在Schema中定义的对象属性视为兼容的新增字段，不作为违规
+func (s *OpenAPISchema) Validate(value any) []string {
+	var violations []string
+	s.validate("$", value, &violations)
+	return violations
+}
+
+func (s *OpenAPISchema) validate(path string, value any, violations *[]string) {
+	if s == nil {
+		return
+	}
+	if value == nil {
+		if !s.Nullable && s.Type != "" {
+			*violations = append(*violations, fmt.Sprintf("%s: expected %s, got null", path, s.Type))
+		}
+		return
+	}
+
+	fail := func(got string) {
+		*violations = append(*violations, fmt.Sprintf("%s: expected %s, got %s", path, s.Type, got))
+	}
+
+	switch s.Type {
+	case "object":
+		obj, ok := value.(map[string]any)
+		if !ok {
+			fail(jsonKind(value))
+			return
+		}
+		for _, name := range s.Required {
+			if _, exists := obj[name]; !exists {
+				*violations = append(*violations, fmt.Sprintf("%s.%s: required field missing", path, name))
+			}
+		}
+		for name, prop := range s.Properties {
+			if v, exists := obj[name]; exists {
+				prop.validate(path+"."+name, v, violations)
+			}
+		}
+		if s.AdditionalProperties != nil {
+			keys := make([]string, 0, len(obj))
+			for k := range obj {
+				if _, defined := s.Properties[k]; !defined {
+					keys = append(keys, k)
+				}
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				s.AdditionalProperties.validate(path+"."+k, obj[k], violations)
+			}
+		}
+	case "array":
+		items, ok := value.([]any)
+		if !ok {
+			fail(jsonKind(value))
+			return
+		}
+		for i, item := range items {
+			s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item, violations)
+		}
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			fail(jsonKind(value))
+			return
+		}
+		if s.Format == "date-time" {
+			if _, err := time.Parse(time.RFC3339Nano, str); err != nil {
+				*violations = append(*violations, fmt.Sprintf("%s: invalid date-time %q", path, str))
+			}
+		}
+	case "integer":
+		num, ok := value.(json.Number)
+		if !ok {
+			fail(jsonKind(value))
+			return
+		}
+		if _, err := num.Int64(); err != nil {
+			if _, err := strconv.ParseUint(num.String(), 10, 64); err != nil {
+				fail("number " + num.String())
+				return
+			}
+		}
+	case "number":
+		if _, ok := value.(json.Number); !ok {
+			fail(jsonKind(value))
+			return
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			fail(jsonKind(value))
+			return
+		}
+	}
+
+	if len(s.Enum) > 0 && !enumContains(s.Enum, value) {
+		*violations = append(*violations, fmt.Sprintf("%s: value %v not in enum %v", path, value, s.Enum))
+	}
+}
+
+// jsonKind 返回解码后JSON值的类型名称
+func jsonKind(value any) string {
+	switch value.(type) {
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case json.Number:
+		return "number"
+	case bool:
+		return "boolean"
+	case nil:
+		return "null"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+// enumContains 判断值是否在枚举中，数字按数值比较
+func enumContains(enum []any, value any) bool {
+	for _, e := range enum {
+		if num, ok := value.(json.Number); ok {
+			f, err := num.Float64()
+			if ef, isFloat := e.(float64); err == nil && isFloat && f == ef {
+				return true
+			}
+			continue
+		}
+		if e == value {
+			return true
+		}
+	}
+	return false
+}
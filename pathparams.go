@@ -0,0 +1,200 @@
+package mod
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// serviceRoute 返回服务的路由路径：设置了 Service.PathOverride 时为该路径，设置了 Service.Path 时为服务前缀下的路径模板，
+// 否则为服务前缀加服务名称
+func (app *App) serviceRoute(svc *Service) string {
+	if svc.PathOverride != "" {
+		return "/" + strings.Trim(svc.PathOverride, "/")
+	}
+	if svc.Path == "" {
+		return app.ServicePath(svc.Name)
+	}
+	return app.cfg.ModConfig.App.ServiceBase + "/" + strings.Trim(svc.Path, "/")
+}
+
+// checkServicePath 检查路径模板：各段非空，路径参数以 : 开头且名称不重复，不支持通配符和可选参数
+func checkServicePath(path string) error {
+	trimmed := strings.Trim(path, "/")
+	if trimmed == "" {
+		return fmt.Errorf("path %q is empty", path)
+	}
+	seen := map[string]bool{}
+	for _, segment := range strings.Split(trimmed, "/") {
+		if segment == "" {
+			return fmt.Errorf("path %q contains an empty segment", path)
+		}
+		if strings.ContainsAny(segment, "*+?") {
+			return fmt.Errorf("path %q: wildcard and optional parameters are not supported", path)
+		}
+		name, ok := strings.CutPrefix(segment, ":")
+		if !ok {
+			continue
+		}
+		if name == "" || strings.Contains(name, ":") {
+			return fmt.Errorf("path %q: invalid parameter %q", path, segment)
+		}
+		if seen[name] {
+			return fmt.Errorf("path %q: parameter %q is used more than once", path, name)
+		}
+		seen[name] = true
+	}
+	return nil
+}
+
+// pathParamNames 返回路径模板中的参数名称
+func pathParamNames(path string) []string {
+	var names []string
+	for _, segment := range strings.Split(path, "/") {
+		if name, ok := strings.CutPrefix(segment, ":"); ok {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// routeShape 去掉参数名称后的路由，用于判断 users/:id 与 users/:uid 这类等价路由的冲突
+func routeShape(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") {
+			segments[i] = ":"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// openAPIPath 将 :id 形式的路径参数转换为 OpenAPI 的 {id} 形式
+func openAPIPath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if name, ok := strings.CutPrefix(segment, ":"); ok {
+			segments[i] = "{" + name + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// pathParamValue 读取路径参数，未开启 server.unescape_path 时对参数值做URL解码
+func pathParamValue(c *fiber.Ctx, name string) string {
+	value := c.Params(name)
+	if c.App().Config().UnescapePath {
+		return value
+	}
+	if unescaped, err := url.PathUnescape(value); err == nil {
+		return unescaped
+	}
+	return value
+}
+
+// requestPath 返回调用服务的请求路径，路径模板中的参数取自请求中 mod:"from=param" 字段的值
+func (svc *Service) requestPath(body any) string {
+	names := pathParamNames(svc.path)
+	if len(names) == 0 {
+		return svc.path
+	}
+	values := svc.pathParamValues(body)
+	segments := strings.Split(svc.path, "/")
+	for i, segment := range segments {
+		if name, ok := strings.CutPrefix(segment, ":"); ok {
+			if value, ok := values[name]; ok {
+				segments[i] = url.PathEscape(value)
+			}
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// pathParamValues 从请求（结构体、map 或 JSON）中读取路径参数字段的值
+func (svc *Service) pathParamValues(body any) map[string]string {
+	values := map[string]string{}
+	if body == nil || svc.Handler.InputType == nil {
+		return values
+	}
+
+	// 统一转换为请求结构体，路径参数字段可能未出现在JSON中
+	var raw []byte
+	switch v := body.(type) {
+	case []byte:
+		raw = v
+	case string:
+		raw = []byte(v)
+	default:
+		rv := reflect.ValueOf(body)
+		for rv.Kind() == reflect.Pointer && !rv.IsNil() {
+			rv = rv.Elem()
+		}
+		if rv.Type() == svc.Handler.InputType {
+			return collectPathParams(rv)
+		}
+		var err error
+		if raw, err = json.Marshal(body); err != nil {
+			return values
+		}
+	}
+	in := reflect.New(svc.Handler.InputType)
+	if err := json.Unmarshal(raw, in.Interface()); err != nil {
+		return values
+	}
+	return collectPathParams(in.Elem())
+}
+
+// collectPathParams 读取结构体中 mod:"from=param" 字段的值
+func collectPathParams(rv reflect.Value) map[string]string {
+	values := map[string]string{}
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		modTag := field.Tag.Get("mod")
+		if modTagValue(modTag, "from", "") != "param" {
+			continue
+		}
+		name := modTagValue(modTag, "name", strings.ToLower(field.Name))
+		value := rv.Field(i)
+		if value.Kind() == reflect.Pointer {
+			if value.IsNil() {
+				continue
+			}
+			value = value.Elem()
+		}
+		values[name] = fmt.Sprint(value.Interface())
+	}
+	return values
+}
+
+// pathParamRef 权限规则中对路径参数的引用
+type pathParamRef string
+
+// PathParam 在权限规则的 Value 中引用路径参数，检查时替换为请求路径中的参数值，
+// 如 {Field: "user.id", Operator: "eq", Value: mod.PathParam("id")} 限制只能访问自己的资源
+func PathParam(name string) any {
+	return pathParamRef(name)
+}
+
+// resolvePermission 将权限规则中的路径参数引用替换为请求中的值，没有引用时返回原配置
+func resolvePermission(c *fiber.Ctx, permission *PermissionConfig) *PermissionConfig {
+	resolved := permission
+	for i, rule := range permission.Rules {
+		name, ok := rule.Value.(pathParamRef)
+		if !ok {
+			continue
+		}
+		if resolved == permission {
+			resolved = &PermissionConfig{Logic: permission.Logic, Rules: append([]PermissionRule(nil), permission.Rules...)}
+		}
+		resolved.Rules[i].Value = pathParamValue(c, string(name))
+	}
+	return resolved
+}
@@ -14,20 +14,17 @@ func (app *App) CheckServicePermission(token string, permission *PermissionConfi
 		return true // 没有配置权限规则，默认允许访问
 	}
 
-	// 获取Token缓存数据
-	tokenData, err := app.GetTokenData(token)
+	// 获取并解析Token缓存数据
+	data, err := app.tokenDataMap(nil, token)
 	if err != nil {
 		app.logger.WithField("error", err.Error()).Debug("Failed to get token data for permission check")
 		return false
 	}
+	return app.evaluatePermission(data, permission)
+}
 
-	// 解析Token数据为map
-	var data map[string]any
-	if err := json.Unmarshal(tokenData, &data); err != nil {
-		app.logger.WithField("error", err.Error()).Debug("Failed to unmarshal token data for permission check")
-		return false
-	}
-
+// evaluatePermission 按 AND/OR 逻辑评估权限规则
+func (app *App) evaluatePermission(data map[string]any, permission *PermissionConfig) bool {
 	// 默认逻辑为AND
 	logic := permission.Logic
 	if logic == "" {
@@ -197,6 +194,10 @@ func toFloat64(value any) (float64, bool) {
 	switch v := value.(type) {
 	case float64:
 		return v, true
+	case json.Number:
+		if f, err := v.Float64(); err == nil {
+			return f, true
+		}
 	case float32:
 		return float64(v), true
 	case int:
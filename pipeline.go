@@ -0,0 +1,482 @@
+package mod
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+)
+
+// PipelineStep 流水线的一个步骤：调用已注册的服务，或并行执行多个分支
+type PipelineStep struct {
+	Name    string // 步骤名称，在数据映射中通过 $<名称> 引用步骤的响应，默认为服务名称
+	Service string // 调用的服务名称，与 Parallel 二选一
+
+	// 服务的请求参数：键为请求字段（可用 a.b 表示嵌套字段），以 $ 开头的字符串为引用
+	// （$input.x 引用流水线的请求，$<步骤>.x 引用已完成步骤的响应，$$ 开头表示以 $ 开头的字面量），其他值原样传入；
+	// 为 nil 时传入流水线的完整请求
+	Input map[string]any
+
+	// 并行执行的分支，全部完成后继续；任一分支失败时等待其他分支结束后开始补偿
+	Parallel []PipelineStep
+
+	Compensate      string         // 后续步骤失败时调用的补偿服务，如预留库存对应释放库存
+	CompensateInput map[string]any // 补偿服务的请求参数，规则同 Input，为 nil 时传入本步骤的响应
+
+	Optional bool // 失败时记录日志并继续执行，步骤的响应为 null
+}
+
+// Pipeline 由多个已注册服务组成的流水线，通过 app.RegisterPipeline 注册为服务
+type Pipeline struct {
+	// 流水线服务的名称、认证、超时等配置，Handler 由框架生成，无需设置
+	Service Service
+
+	Steps []PipelineStep
+
+	// 流水线的请求和响应类型（结构体的零值，如 PlaceOrderRequest{}），用于参数绑定、校验和生成文档；
+	// 为 nil 时使用 PipelineData，请求为任意JSON对象
+	Input  any
+	Output any
+
+	// 响应字段映射，规则同 PipelineStep.Input；为 nil 时响应包含各步骤的响应，键为步骤名称
+	Result map[string]any
+}
+
+// PipelineData 未指定请求或响应类型时流水线使用的任意JSON对象
+type PipelineData struct {
+	Values map[string]any `json:"-"`
+}
+
+// BindParams 将JSON请求体解析为任意对象
+func (d *PipelineData) BindParams(c *fiber.Ctx) error {
+	d.Values = map[string]any{}
+	if body := c.Body(); len(body) > 0 {
+		if err := json.Unmarshal(body, &d.Values); err != nil {
+			return fmt.Errorf("failed to parse JSON body: %w", err)
+		}
+	}
+	return nil
+}
+
+// MarshalJSON 输出 Values
+func (d PipelineData) MarshalJSON() ([]byte, error) {
+	if d.Values == nil {
+		return []byte("{}"), nil
+	}
+	return json.Marshal(d.Values)
+}
+
+// UnmarshalJSON 解析为 Values
+func (d *PipelineData) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, &d.Values)
+}
+
+// pipelineRun 一次流水线执行：请求、各步骤的响应和待补偿的步骤
+type pipelineRun struct {
+	app      *App
+	ctx      *Context
+	pipeline string
+
+	mu        sync.Mutex
+	data      map[string]any
+	completed []*PipelineStep // 按完成顺序记录已成功且设置了补偿服务的步骤
+}
+
+// RegisterPipeline 将流水线注册为服务。流水线按顺序调用步骤中的服务，步骤之间通过数据映射传递参数，
+// 任一步骤失败时按完成顺序的倒序调用已完成步骤的补偿服务，然后返回该步骤的错误，适用于
+// “创建订单 → 预留库存 → 扣款”等简单的 Saga。步骤服务在进程内调用，不经过认证、权限检查等处理，
+// 由流水线服务自身的配置控制；步骤服务可以晚于流水线注册
+func (app *App) RegisterPipeline(pipeline Pipeline) error {
+	svc := pipeline.Service
+	if svc.Handler.Func != nil || svc.Handler.call != nil {
+		return fmt.Errorf("pipeline %q: handler is generated from steps and must not be set", svc.Name)
+	}
+	if len(pipeline.Steps) == 0 {
+		return fmt.Errorf("pipeline %q: no steps", svc.Name)
+	}
+	steps := clonePipelineSteps(pipeline.Steps)
+	names := map[string]bool{"input": true}
+	if err := checkPipelineSteps(steps, names); err != nil {
+		return fmt.Errorf("pipeline %q: %w", svc.Name, err)
+	}
+	if err := checkPipelineRefs(pipeline.Result, names); err != nil {
+		return fmt.Errorf("pipeline %q: result: %w", svc.Name, err)
+	}
+
+	inputType := reflect.TypeOf(PipelineData{})
+	if pipeline.Input != nil {
+		inputType = reflect.TypeOf(pipeline.Input)
+	}
+	outputType := reflect.TypeOf(PipelineData{})
+	if pipeline.Output != nil {
+		outputType = reflect.TypeOf(pipeline.Output)
+	}
+	result := pipeline.Result
+	svc.Handler = Handler{
+		Func: func(ctx *Context, args any, reply any) error {
+			return app.runPipeline(ctx, svc.Name, steps, result, args, reply)
+		},
+		InputType:  inputType,
+		OutputType: outputType,
+	}
+	if svc.Description == "" {
+		svc.Description = "流水线：" + describePipeline(steps)
+	}
+	return app.Register(svc)
+}
+
+// clonePipelineSteps 复制步骤，注册时补全的默认值不影响调用方的配置
+func clonePipelineSteps(steps []PipelineStep) []PipelineStep {
+	cloned := make([]PipelineStep, len(steps))
+	for i, step := range steps {
+		step.Parallel = clonePipelineSteps(step.Parallel)
+		cloned[i] = step
+	}
+	return cloned
+}
+
+// checkPipelineSteps 校验步骤配置、步骤名称是否重复以及引用是否指向之前的步骤，names 记录已出现的步骤名称
+func checkPipelineSteps(steps []PipelineStep, names map[string]bool) error {
+	for i := range steps {
+		step := &steps[i]
+		if len(step.Parallel) > 0 {
+			if step.Service != "" || step.Compensate != "" || step.Input != nil {
+				return fmt.Errorf("step %d: parallel step must not set service, input or compensate", i)
+			}
+			// 并行分支之间不能相互引用，分支完成后其步骤才可被后续步骤引用
+			added := map[string]bool{}
+			for j := range step.Parallel {
+				branchNames := make(map[string]bool, len(names))
+				for name := range names {
+					branchNames[name] = true
+				}
+				if err := checkPipelineSteps(step.Parallel[j:j+1], branchNames); err != nil {
+					return err
+				}
+				for name := range branchNames {
+					if names[name] {
+						continue
+					}
+					if added[name] {
+						return fmt.Errorf("step %q: duplicate step name", name)
+					}
+					added[name] = true
+				}
+			}
+			for name := range added {
+				names[name] = true
+			}
+			continue
+		}
+		if step.Service == "" {
+			return fmt.Errorf("step %d: service or parallel is required", i)
+		}
+		if step.Name == "" {
+			step.Name = step.Service
+		}
+		if names[step.Name] {
+			return fmt.Errorf("step %q: duplicate step name", step.Name)
+		}
+		if err := checkPipelineRefs(step.Input, names); err != nil {
+			return fmt.Errorf("step %q: %w", step.Name, err)
+		}
+		names[step.Name] = true
+		if err := checkPipelineRefs(step.CompensateInput, names); err != nil {
+			return fmt.Errorf("step %q: compensate: %w", step.Name, err)
+		}
+	}
+	return nil
+}
+
+// checkPipelineRefs 校验映射中的引用是否指向请求或已出现的步骤
+func checkPipelineRefs(mapping map[string]any, names map[string]bool) error {
+	for field, value := range mapping {
+		ref, ok := pipelineRef(value)
+		if !ok {
+			continue
+		}
+		root, _, _ := strings.Cut(ref, ".")
+		if !names[root] {
+			return fmt.Errorf("field %q references unknown step %q", field, root)
+		}
+	}
+	return nil
+}
+
+// pipelineRef 返回映射值中的引用路径，非引用时返回 false
+func pipelineRef(value any) (string, bool) {
+	s, ok := value.(string)
+	if !ok || !strings.HasPrefix(s, "$") || strings.HasPrefix(s, "$$") {
+		return "", false
+	}
+	return s[1:], true
+}
+
+// describePipeline 返回步骤的文字描述，如 create_order → [reserve_stock | reserve_coupon] → charge
+func describePipeline(steps []PipelineStep) string {
+	parts := make([]string, len(steps))
+	for i, step := range steps {
+		if len(step.Parallel) > 0 {
+			branches := make([]string, len(step.Parallel))
+			for j := range step.Parallel {
+				branches[j] = describePipeline(step.Parallel[j : j+1])
+			}
+			parts[i] = "[" + strings.Join(branches, " | ") + "]"
+		} else {
+			parts[i] = step.Name
+		}
+	}
+	return strings.Join(parts, " → ")
+}
+
+// runPipeline 执行流水线，失败时补偿已完成的步骤
+func (app *App) runPipeline(ctx *Context, name string, steps []PipelineStep, result map[string]any, args any, reply any) error {
+	input, err := toPipelineValue(args)
+	if err != nil {
+		return err
+	}
+	run := &pipelineRun{app: app, ctx: ctx, pipeline: name, data: map[string]any{"input": input}}
+
+	if err := run.steps(steps); err != nil {
+		run.compensate()
+		return err
+	}
+
+	var out any
+	if result != nil {
+		out = run.resolve(result)
+	} else {
+		values := make(map[string]any, len(run.data))
+		for key, value := range run.data {
+			if key != "input" {
+				values[key] = value
+			}
+		}
+		out = values
+	}
+	data, err := json.Marshal(out)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, reply)
+}
+
+// steps 按顺序执行步骤
+func (r *pipelineRun) steps(steps []PipelineStep) error {
+	for i := range steps {
+		if err := r.ctx.UserContext().Err(); err != nil {
+			return err
+		}
+		var err error
+		if len(steps[i].Parallel) > 0 {
+			err = r.parallel(steps[i].Parallel)
+		} else {
+			err = r.step(&steps[i])
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parallel 并行执行分支，返回第一个失败分支的错误
+func (r *pipelineRun) parallel(branches []PipelineStep) error {
+	errs := make([]error, len(branches))
+	var wg sync.WaitGroup
+	for i := range branches {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() {
+				if p := recover(); p != nil {
+					errs[i] = fmt.Errorf("pipeline step %q panicked: %v", branches[i].Name, p)
+				}
+			}()
+			errs[i] = r.steps(branches[i : i+1])
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// step 调用步骤的服务并记录响应
+func (r *pipelineRun) step(step *PipelineStep) error {
+	r.mu.Lock()
+	input := r.data["input"]
+	if step.Input != nil {
+		input = r.resolve(step.Input)
+	}
+	r.mu.Unlock()
+
+	out, err := r.app.invokeService(r.ctx, step.Service, input)
+	if err != nil {
+		fields := logrus.Fields{
+			"pipeline": r.pipeline,
+			"step":     step.Name,
+			"service":  step.Service,
+			"error":    err.Error(),
+			"rid":      r.ctx.GetRequestID(),
+		}
+		if !step.Optional {
+			r.app.logger.WithFields(fields).Warn("Pipeline step failed")
+			var reply *StdReply
+			if errors.As(err, &reply) {
+				return err
+			}
+			return fmt.Errorf("pipeline step %q: %w", step.Name, err)
+		}
+		r.app.logger.WithFields(fields).Info("Optional pipeline step failed, continuing")
+		out = nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.data[step.Name] = out
+	if err == nil && step.Compensate != "" {
+		r.completed = append(r.completed, step)
+	}
+	return nil
+}
+
+// compensate 按完成顺序的倒序调用补偿服务，补偿失败时记录错误日志并继续补偿其余步骤
+func (r *pipelineRun) compensate() {
+	if len(r.completed) == 0 {
+		return
+	}
+	// 请求已取消或超时时仍需完成补偿
+	r.ctx.SetUserContext(context.WithoutCancel(r.ctx.UserContext()))
+	for i := len(r.completed) - 1; i >= 0; i-- {
+		step := r.completed[i]
+		input := r.data[step.Name]
+		if step.CompensateInput != nil {
+			input = r.resolve(step.CompensateInput)
+		}
+		fields := logrus.Fields{
+			"pipeline":   r.pipeline,
+			"step":       step.Name,
+			"compensate": step.Compensate,
+			"rid":        r.ctx.GetRequestID(),
+		}
+		if _, err := r.app.invokeService(r.ctx, step.Compensate, input); err != nil {
+			r.app.logger.WithFields(fields).WithError(err).Error("Pipeline compensation failed")
+			continue
+		}
+		r.app.logger.WithFields(fields).Info("Pipeline step compensated")
+	}
+}
+
+// resolve 按映射构造参数，调用方须持有 r.mu
+func (r *pipelineRun) resolve(mapping map[string]any) map[string]any {
+	values := map[string]any{}
+	for field, value := range mapping {
+		if ref, ok := pipelineRef(value); ok {
+			value = lookupPath(r.data, ref)
+		} else if s, ok := value.(string); ok && strings.HasPrefix(s, "$$") {
+			value = s[1:]
+		}
+		setPath(values, field, value)
+	}
+	return values
+}
+
+// lookupPath 按 a.b.c 路径读取嵌套对象中的值，路径不存在时返回 nil
+func lookupPath(data map[string]any, path string) any {
+	var current any = data
+	for _, key := range strings.Split(path, ".") {
+		m, ok := current.(map[string]any)
+		if !ok {
+			return nil
+		}
+		current = m[key]
+	}
+	return current
+}
+
+// setPath 按 a.b.c 路径写入嵌套对象，中间对象不存在时创建
+func setPath(data map[string]any, path string, value any) {
+	keys := strings.Split(path, ".")
+	for _, key := range keys[:len(keys)-1] {
+		next, ok := data[key].(map[string]any)
+		if !ok {
+			next = map[string]any{}
+			data[key] = next
+		}
+		data = next
+	}
+	data[keys[len(keys)-1]] = value
+}
+
+// toPipelineValue 将请求或响应转换为JSON对象（map）或其他JSON值，用于数据映射
+func toPipelineValue(v any) (any, error) {
+	if v == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// invokeService 在进程内调用已注册的服务：按服务的请求类型解析并校验参数，返回转换为JSON值的响应
+func (app *App) invokeService(ctx *Context, name string, input any) (any, error) {
+	svc, ok := app.root().GetService(name)
+	if !ok {
+		return nil, ReplyWithDetail(500, "Pipeline step service not found", name)
+	}
+	if svc.RawBody {
+		return nil, ReplyWithDetail(500, "Pipeline step service not supported", name+" uses RawBody")
+	}
+	handler := svc.Handler
+
+	var in, out any
+	if handler.InputType != nil {
+		in = reflect.New(handler.InputType).Interface()
+		data, err := json.Marshal(input)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, in); err != nil {
+			return nil, ReplyWithDetail(400, "Parameter parsing error", err.Error())
+		}
+		if rv, ok := in.(RequestValidator); ok {
+			err = rv.ValidateRequest()
+		} else {
+			err = validate.Struct(in)
+		}
+		if err != nil {
+			return nil, ReplyWithDetail(400, "Parameter validation error", err.Error())
+		}
+	}
+	if handler.OutputType != nil {
+		out = reflect.New(handler.OutputType).Interface()
+	}
+
+	sctx := &Context{Ctx: ctx.Ctx, RequestID: ctx.GetRequestID(), logger: app.logger, app: app, service: &svc}
+	var err error
+	if handler.call != nil {
+		out, err = handler.call(sctx, in)
+	} else {
+		err = handler.Func(sctx, in, out)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return toPipelineValue(out)
+}
@@ -0,0 +1,258 @@
+package mod
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/allegro/bigcache/v3"
+	"github.com/dgraph-io/badger/v4"
+	"github.com/gofiber/fiber/v2"
+)
+
+// PoolStats 连接池和依赖的容量统计，用于容量规划
+type PoolStats struct {
+	Server      ServerPoolStats    `json:"server"`                // Fiber 连接和并发
+	Concurrency *ConcurrencyStatus `json:"concurrency,omitempty"` // 服务执行槽位，启用 concurrency_limit 时返回
+	Redis       *RedisPoolStats    `json:"redis,omitempty"`       // Redis 连接池，配置了 Redis 时返回
+	Badger      []BadgerStats      `json:"badger,omitempty"`      // BadgerDB 实例（Token缓存、请求捕获）
+	BigCache    []BigCacheStats    `json:"bigcache,omitempty"`    // BigCache 实例（Token缓存、响应缓存）
+	Storage     []StorageStats     `json:"storage,omitempty"`     // 文件上传后端（S3、OSS、GCS、COS、七牛、本地）
+	HTTPClient  []HTTPHostStats    `json:"http_client,omitempty"` // ctx.HTTP() 按主机统计的外部请求
+}
+
+// ServerPoolStats Fiber（fasthttp）的连接和并发
+type ServerPoolStats struct {
+	OpenConnections    int32   `json:"open_connections"`    // 当前打开的连接数
+	CurrentConcurrency uint32  `json:"current_concurrency"` // 正在处理的请求数
+	MaxConcurrency     int     `json:"max_concurrency"`     // 并发上限（Fiber Config.Concurrency）
+	Usage              float64 `json:"usage"`               // current_concurrency / max_concurrency
+}
+
+// RedisPoolStats Redis 连接池的使用情况，计数为启动以来的累计值
+type RedisPoolStats struct {
+	PoolSize   int     `json:"pool_size"`   // 连接池上限
+	TotalConns uint32  `json:"total_conns"` // 当前连接数
+	IdleConns  uint32  `json:"idle_conns"`  // 空闲连接数
+	StaleConns uint32  `json:"stale_conns"` // 因过期被关闭的连接数
+	Hits       uint32  `json:"hits"`        // 从池中取到空闲连接的次数
+	Misses     uint32  `json:"misses"`      // 需要新建连接的次数
+	Timeouts   uint32  `json:"timeouts"`    // 等待连接超时的次数
+	Usage      float64 `json:"usage"`       // 使用中的连接数 / pool_size
+}
+
+// BadgerStats BadgerDB 的磁盘占用，Badger 约每分钟更新一次
+type BadgerStats struct {
+	Name      string `json:"name"`       // token_cache 或 capture
+	LSMBytes  int64  `json:"lsm_bytes"`  // LSM 树文件大小
+	VLogBytes int64  `json:"vlog_bytes"` // 值日志文件大小
+	Tables    int    `json:"tables"`     // SST 文件数
+}
+
+// BigCacheStats BigCache 的条目数、容量和命中统计
+type BigCacheStats struct {
+	Name       string  `json:"name"`          // token_cache 或 response_cache
+	Entries    int     `json:"entries"`       // 条目数
+	Capacity   int     `json:"capacity"`      // 已分配的内存（字节）
+	Hits       int64   `json:"hits"`          // 命中次数
+	Misses     int64   `json:"misses"`        // 未命中次数
+	Collisions int64   `json:"collisions"`    // 键哈希冲突次数
+	HitRate    float64 `json:"hit_rate"`      // hits / (hits + misses)
+	DelHits    int64   `json:"delete_hits"`   // 删除成功次数
+	DelMisses  int64   `json:"delete_misses"` // 删除不存在的键的次数
+}
+
+// StorageStats 文件上传后端的操作统计，计数为启动以来的累计值
+type StorageStats struct {
+	Backend        string        `json:"backend"`         // s3、oss、gcs、cos、qiniu、local
+	Uploads        int64         `json:"uploads"`         // 上传次数
+	UploadFailures int64         `json:"upload_failures"` // 上传失败次数
+	UploadBytes    int64         `json:"upload_bytes"`    // 上传成功的字节数
+	Deletes        int64         `json:"deletes"`         // 删除次数
+	DeleteFailures int64         `json:"delete_failures"` // 删除失败次数
+	InFlight       int64         `json:"in_flight"`       // 正在进行的上传和删除
+	AvgLatency     time.Duration `json:"avg_latency"`     // 平均耗时
+}
+
+// storageStatsState 各文件上传后端的操作计数
+type storageStatsState struct {
+	mu       sync.Mutex
+	backends map[string]*storageCounters
+}
+
+type storageCounters struct {
+	uploads        atomic.Int64
+	uploadFailures atomic.Int64
+	uploadBytes    atomic.Int64
+	deletes        atomic.Int64
+	deleteFailures atomic.Int64
+	inFlight       atomic.Int64
+	latencyNano    atomic.Int64
+}
+
+// counters 返回后端的计数，不存在时创建
+func (s *storageStatsState) counters(backend string) *storageCounters {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.backends == nil {
+		s.backends = map[string]*storageCounters{}
+	}
+	c, ok := s.backends[backend]
+	if !ok {
+		c = &storageCounters{}
+		s.backends[backend] = c
+	}
+	return c
+}
+
+// trackStorage 开始一次上传或删除，返回的函数在操作结束时记录结果
+func (app *App) trackStorage(backend string, upload bool, size int64) func(err error) {
+	c := app.storageStats.counters(backend)
+	c.inFlight.Add(1)
+	start := time.Now()
+	return func(err error) {
+		c.inFlight.Add(-1)
+		c.latencyNano.Add(int64(time.Since(start)))
+		switch {
+		case upload && err != nil:
+			c.uploads.Add(1)
+			c.uploadFailures.Add(1)
+		case upload:
+			c.uploads.Add(1)
+			c.uploadBytes.Add(size)
+		case err != nil:
+			c.deletes.Add(1)
+			c.deleteFailures.Add(1)
+		default:
+			c.deletes.Add(1)
+		}
+	}
+}
+
+// PoolStats 返回 Fiber 连接、Redis 连接池、BadgerDB、BigCache、文件上传后端和外部请求的统计
+func (app *App) PoolStats() PoolStats {
+	stats := PoolStats{Server: app.serverPoolStats(), HTTPClient: app.HTTPClientStats()}
+	if status, ok := app.ConcurrencyStatus(); ok {
+		stats.Concurrency = &status
+	}
+
+	if app.redisClient != nil {
+		pool := app.redisClient.PoolStats()
+		redis := &RedisPoolStats{
+			PoolSize:   app.redisClient.Options().PoolSize,
+			TotalConns: pool.TotalConns,
+			IdleConns:  pool.IdleConns,
+			StaleConns: pool.StaleConns,
+			Hits:       pool.Hits,
+			Misses:     pool.Misses,
+			Timeouts:   pool.Timeouts,
+		}
+		if redis.PoolSize > 0 {
+			redis.Usage = float64(pool.TotalConns-pool.IdleConns) / float64(redis.PoolSize)
+		}
+		stats.Redis = redis
+	}
+
+	for _, db := range []struct {
+		name string
+		db   *badger.DB
+	}{{"token_cache", app.badgerDB}, {"capture", app.captureDB}} {
+		if db.db == nil {
+			continue
+		}
+		lsm, vlog := db.db.Size()
+		stats.Badger = append(stats.Badger, BadgerStats{Name: db.name, LSMBytes: lsm, VLogBytes: vlog, Tables: len(db.db.Tables())})
+	}
+
+	addCache := func(name string, cache *bigcache.BigCache) {
+		if cache == nil {
+			return
+		}
+		s := cache.Stats()
+		cacheStats := BigCacheStats{
+			Name:       name,
+			Entries:    cache.Len(),
+			Capacity:   cache.Capacity(),
+			Hits:       s.Hits,
+			Misses:     s.Misses,
+			Collisions: s.Collisions,
+			DelHits:    s.DelHits,
+			DelMisses:  s.DelMisses,
+		}
+		if s.Hits+s.Misses > 0 {
+			cacheStats.HitRate = float64(s.Hits) / float64(s.Hits+s.Misses)
+		}
+		stats.BigCache = append(stats.BigCache, cacheStats)
+	}
+	addCache("token_cache", app.tokenCache)
+	if app.responseCache != nil {
+		app.responseCache.mu.Lock()
+		cache := app.responseCache.cache
+		app.responseCache.mu.Unlock()
+		addCache("response_cache", cache)
+	}
+
+	app.storageStats.mu.Lock()
+	backends := make([]string, 0, len(app.storageStats.backends))
+	for backend := range app.storageStats.backends {
+		backends = append(backends, backend)
+	}
+	app.storageStats.mu.Unlock()
+	sort.Strings(backends)
+	for _, backend := range backends {
+		c := app.storageStats.counters(backend)
+		s := StorageStats{
+			Backend:        backend,
+			Uploads:        c.uploads.Load(),
+			UploadFailures: c.uploadFailures.Load(),
+			UploadBytes:    c.uploadBytes.Load(),
+			Deletes:        c.deletes.Load(),
+			DeleteFailures: c.deleteFailures.Load(),
+			InFlight:       c.inFlight.Load(),
+		}
+		if ops := s.Uploads + s.Deletes; ops > 0 {
+			s.AvgLatency = time.Duration(c.latencyNano.Load() / ops)
+		}
+		stats.Storage = append(stats.Storage, s)
+	}
+	return stats
+}
+
+// serverPoolStats 返回 fasthttp 服务的连接数和并发数
+func (app *App) serverPoolStats() ServerPoolStats {
+	server := app.Server()
+	stats := ServerPoolStats{
+		OpenConnections:    server.GetOpenConnectionsCount(),
+		CurrentConcurrency: server.GetCurrentConcurrency(),
+		MaxConcurrency:     app.Config().Concurrency,
+	}
+	if stats.MaxConcurrency > 0 {
+		stats.Usage = float64(stats.CurrentConcurrency) / float64(stats.MaxConcurrency)
+	}
+	return stats
+}
+
+// configurePools 启用 pools 配置时注册连接池统计路由
+func (app *App) configurePools() {
+	config := app.cfg.ModConfig.Pools
+	if !config.Enabled {
+		return
+	}
+	path := config.Path
+	if path == "" {
+		path = "/admin/pools"
+	}
+	app.Get(path, app.handlePools)
+}
+
+// handlePools 返回连接池和依赖的统计
+func (app *App) handlePools(c *fiber.Ctx) error {
+	ctx := &Context{Ctx: c, logger: app.logger, app: app}
+	if !app.cfg.ModConfig.Pools.SkipAuth {
+		if err := app.authorizeAdmin(ctx, "pools"); err != nil {
+			return replyError(ctx, err)
+		}
+	}
+	return c.JSON(NewSuccessResponse(ctx, app.PoolStats()))
+}
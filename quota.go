@@ -0,0 +1,197 @@
+package mod
+
+import (
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// UploadQuota 用户及租户的上传配额使用情况，Limit 为0表示不限制，Remaining 为-1表示不限制
+type UploadQuota struct {
+	UserID          string `json:"user_id" desc:"用户ID"`
+	UserLimit       int64  `json:"user_limit" desc:"用户配额（字节），0表示不限制"`
+	UserUsed        int64  `json:"user_used" desc:"用户已用（字节）"`
+	UserRemaining   int64  `json:"user_remaining" desc:"用户剩余（字节），-1表示不限制"`
+	TenantID        string `json:"tenant_id,omitempty" desc:"租户ID"`
+	TenantLimit     int64  `json:"tenant_limit" desc:"租户配额（字节），0表示不限制"`
+	TenantUsed      int64  `json:"tenant_used" desc:"租户已用（字节）"`
+	TenantRemaining int64  `json:"tenant_remaining" desc:"租户剩余（字节），-1表示不限制"`
+}
+
+// QuotaExceededError 上传超出配额
+type QuotaExceededError struct {
+	Scope string // user 或 tenant
+	Quota *UploadQuota
+}
+
+func (e *QuotaExceededError) Error() string {
+	if e.Scope == "tenant" {
+		return fmt.Sprintf("租户上传配额不足，剩余 %d 字节", e.Quota.TenantRemaining)
+	}
+	return fmt.Sprintf("用户上传配额不足，剩余 %d 字节", e.Quota.UserRemaining)
+}
+
+// UploadQuotaRequest 查询上传配额请求
+type UploadQuotaRequest struct{}
+
+// quotaEnabled 判断是否启用上传配额
+func (app *App) quotaEnabled() bool {
+	return app.cfg.ModConfig != nil && app.cfg.ModConfig.FileUpload.Quota.Enabled && app.fileStore != nil
+}
+
+// resolveFileTenant 解析当前请求的租户ID，依次尝试上下文、JWT扩展字段和Token缓存数据
+func (app *App) resolveFileTenant(c *fiber.Ctx) string {
+	key := app.cfg.ModConfig.FileUpload.Quota.TenantKey
+	if key == "" {
+		key = "tenant_id"
+	}
+
+	if tenantID, ok := c.Locals(key).(string); ok && tenantID != "" {
+		return tenantID
+	}
+	if claims, ok := c.Locals("jwt_claims").(*JWTClaims); ok {
+		if v := getNestedValue(claims.Extra, key); v != nil {
+			return fmt.Sprintf("%v", v)
+		}
+	}
+
+	token := parseToken(c, app.tokenKeys)
+	if token == "" {
+		return ""
+	}
+
+	if jwtManager := app.GetJWTManager(); jwtManager.IsEnabled() {
+		if claims, err := jwtManager.ValidateToken(token); err == nil {
+			if v := getNestedValue(claims.Extra, key); v != nil {
+				return fmt.Sprintf("%v", v)
+			}
+			return ""
+		}
+	}
+
+	if tokenData, err := app.tokenDataMap(c, token); err == nil {
+		if v := getNestedValue(tokenData, key); v != nil {
+			return fmt.Sprintf("%v", v)
+		}
+	}
+
+	return ""
+}
+
+// quotaLimit 返回指定用户或租户的配额（字节），0表示不限制
+func (app *App) quotaLimit(overrides map[string]string, id, fallback string) int64 {
+	limit := fallback
+	if v, ok := overrides[id]; ok {
+		limit = v
+	}
+	if limit == "" {
+		return 0
+	}
+
+	size, err := parseSize(limit)
+	if err != nil {
+		app.logger.WithError(err).WithField("limit", limit).Warn("Invalid upload quota, treated as unlimited")
+		return 0
+	}
+	return size
+}
+
+// fileUsage 统计满足条件的文件总大小
+func (app *App) fileUsage(query FileQuery) (int64, error) {
+	items, _, err := app.ListFiles(query)
+	if err != nil {
+		return 0, err
+	}
+
+	var used int64
+	for _, item := range items {
+		used += item.Size
+	}
+	return used, nil
+}
+
+// GetUploadQuota 查询用户及租户的上传配额使用情况，userID或tenantID为空时跳过对应统计
+func (app *App) GetUploadQuota(userID, tenantID string) (*UploadQuota, error) {
+	config := app.cfg.ModConfig.FileUpload.Quota
+	quota := &UploadQuota{
+		UserID:          userID,
+		TenantID:        tenantID,
+		UserRemaining:   -1,
+		TenantRemaining: -1,
+	}
+
+	if userID != "" {
+		used, err := app.fileUsage(FileQuery{Owner: userID})
+		if err != nil {
+			return nil, err
+		}
+		quota.UserUsed = used
+		quota.UserLimit = app.quotaLimit(config.Users, userID, config.UserLimit)
+		if quota.UserLimit > 0 {
+			quota.UserRemaining = max(quota.UserLimit-used, 0)
+		}
+	}
+
+	if tenantID != "" {
+		used, err := app.fileUsage(FileQuery{Tenant: tenantID})
+		if err != nil {
+			return nil, err
+		}
+		quota.TenantUsed = used
+		quota.TenantLimit = app.quotaLimit(config.Tenants, tenantID, config.TenantLimit)
+		if quota.TenantLimit > 0 {
+			quota.TenantRemaining = max(quota.TenantLimit-used, 0)
+		}
+	}
+
+	return quota, nil
+}
+
+// checkUploadQuota 检查当前请求的用户及租户是否还能上传 size 字节
+func (app *App) checkUploadQuota(c *fiber.Ctx, size int64) error {
+	if !app.quotaEnabled() {
+		return nil
+	}
+
+	quota, err := app.GetUploadQuota(app.resolveFileOwner(c), app.resolveFileTenant(c))
+	if err != nil {
+		return err
+	}
+
+	if quota.UserRemaining >= 0 && size > quota.UserRemaining {
+		return &QuotaExceededError{Scope: "user", Quota: quota}
+	}
+	if quota.TenantRemaining >= 0 && size > quota.TenantRemaining {
+		return &QuotaExceededError{Scope: "tenant", Quota: quota}
+	}
+	return nil
+}
+
+// registerQuotaService 注册上传配额查询服务
+func (app *App) registerQuotaService() {
+	svc := Service{
+		Name:        "files_quota",
+		DisplayName: "上传配额",
+		Description: "查询当前用户及所属租户的上传配额、已用空间和剩余空间",
+		Group:       "文件管理",
+		Sort:        5,
+		Handler: MakeHandler(func(ctx *Context, req *UploadQuotaRequest, resp *UploadQuota) error {
+			owner := app.resolveFileOwner(ctx.Ctx)
+			if owner == "" {
+				return Reply(401, "无法识别当前用户")
+			}
+
+			quota, err := app.GetUploadQuota(owner, app.resolveFileTenant(ctx.Ctx))
+			if err != nil {
+				return err
+			}
+			*resp = *quota
+			return nil
+		}),
+		builtin: true,
+	}
+
+	if err := app.Register(svc); err != nil {
+		app.logger.WithError(err).WithField("service", svc.Name).Error("Failed to register file service")
+	}
+}
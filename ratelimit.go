@@ -0,0 +1,353 @@
+package mod
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+)
+
+// 限流计数维度
+const (
+	RateLimitByIP     = "ip"     // 客户端IP
+	RateLimitByUser   = "user"   // 当前用户，未认证的请求按客户端IP计数
+	RateLimitByGlobal = "global" // 所有请求共用一个计数
+)
+
+// RateLimit 请求限流规则：每个时间窗口允许 Requests 个请求，额度匀速恢复，最多累积 Burst 个请求的突发额度
+type RateLimit struct {
+	Requests int    `yaml:"requests" json:"requests"`       // 每个时间窗口允许的请求数，0 表示不限制
+	Window   string `yaml:"window" json:"window,omitempty"` // 时间窗口，默认1s
+	Burst    int    `yaml:"burst" json:"burst,omitempty"`   // 突发容量，默认等于 requests
+	By       string `yaml:"by" json:"by,omitempty"`         // 计数维度：ip（默认）、user、global
+}
+
+// RateLimitKey 一次限流检查中的一个计数键
+type RateLimitKey struct {
+	Key      string        // 计数键，如 service:create_order:ip:10.0.0.1
+	Interval time.Duration // 恢复一个请求额度的间隔，即 window / requests
+	Burst    int           // 突发容量
+}
+
+// RateLimitResult 限流检查结果
+type RateLimitResult struct {
+	Allowed    bool          // 是否允许本次请求
+	Denied     int           // 超限的计数键下标，允许时为 -1
+	Limit      int           // 剩余额度最少的计数键的突发容量
+	Remaining  int           // 剩余额度最少的计数键在本次请求后剩余的请求数
+	RetryAfter time.Duration // 被拒绝时需要等待的时间
+}
+
+// RateLimitStore 限流计数后端。Take 对全部计数键各计一次请求，任一计数键超限时不计数并返回拒绝；
+// 内置进程内存和 Redis 两种实现，可通过 app.SetRateLimitStore 替换
+type RateLimitStore interface {
+	Take(ctx context.Context, keys []RateLimitKey, now time.Time) (RateLimitResult, error)
+}
+
+// rateLimitPolicy 解析后的单层限流规则
+type rateLimitPolicy struct {
+	scope    string // global、group:<分组>、service:<服务>
+	by       string
+	requests int
+	window   time.Duration
+	interval time.Duration
+	burst    int
+}
+
+// rateLimitState 限流后端和各服务解析后的规则，挂载的子应用与父应用共用
+type rateLimitState struct {
+	mu       sync.RWMutex
+	store    RateLimitStore
+	backend  string
+	policies map[string][]rateLimitPolicy
+}
+
+// configureRateLimit 选择限流后端：backend 为空时配置了 Redis 使用 redis，否则使用进程内存
+func (app *App) configureRateLimit() {
+	config := app.cfg.ModConfig.RateLimit
+	state := &rateLimitState{policies: map[string][]rateLimitPolicy{}}
+	switch config.Backend {
+	case "", "redis":
+		if app.redisClient != nil {
+			prefix := config.CacheKeyPrefix
+			if prefix == "" {
+				prefix = "mod:ratelimit:"
+			}
+			state.store, state.backend = &redisRateLimitStore{client: app.redisClient, prefix: prefix}, "redis"
+			break
+		}
+		if config.Backend == "redis" {
+			app.logger.Warn("Rate limit backend is redis but Redis is not configured, using memory")
+		}
+		state.store, state.backend = newMemoryRateLimitStore(), "memory"
+	case "memory":
+		state.store, state.backend = newMemoryRateLimitStore(), "memory"
+	default:
+		app.logger.WithField("backend", config.Backend).Warn("Unknown rate_limit backend, using memory")
+		state.store, state.backend = newMemoryRateLimitStore(), "memory"
+	}
+	app.rateLimit = state
+}
+
+// SetRateLimitStore 替换限流计数后端，如使用其他分布式存储；须在启动前调用
+func (app *App) SetRateLimitStore(store RateLimitStore) {
+	app.rateLimit.mu.Lock()
+	defer app.rateLimit.mu.Unlock()
+	app.rateLimit.store, app.rateLimit.backend = store, "custom"
+}
+
+// rateLimitPolicies 解析并缓存服务生效的限流规则：rate_limit.global、rate_limit.groups 中服务分组的规则，
+// 以及 rate_limit.services 中的同名规则（优先于 Service.RateLimit），三层规则同时生效
+func (app *App) rateLimitPolicies(svc *Service) []rateLimitPolicy {
+	app.rateLimit.mu.RLock()
+	policies, ok := app.rateLimit.policies[svc.Name]
+	app.rateLimit.mu.RUnlock()
+	if ok {
+		return policies
+	}
+
+	config := app.cfg.ModConfig.RateLimit
+	add := func(scope string, rule *RateLimit) {
+		if policy, ok := app.parseRateLimit(scope, rule); ok {
+			policies = append(policies, policy)
+		}
+	}
+	add("global", &config.Global)
+	if rule, ok := config.Groups[svc.Group]; ok {
+		add("group:"+svc.Group, &rule)
+	}
+	if rule, ok := config.Services[svc.Name]; ok {
+		add("service:"+svc.Name, &rule)
+	} else if svc.RateLimit != nil {
+		add("service:"+svc.Name, svc.RateLimit)
+	}
+
+	app.rateLimit.mu.Lock()
+	app.rateLimit.policies[svc.Name] = policies
+	app.rateLimit.mu.Unlock()
+	return policies
+}
+
+// parseRateLimit 解析限流规则，requests 不大于0时表示不限制
+func (app *App) parseRateLimit(scope string, rule *RateLimit) (rateLimitPolicy, bool) {
+	if rule.Requests <= 0 {
+		return rateLimitPolicy{}, false
+	}
+	policy := rateLimitPolicy{scope: scope, by: rule.By, requests: rule.Requests, window: time.Second, burst: rule.Burst}
+	if rule.Window != "" {
+		if d, err := time.ParseDuration(rule.Window); err == nil && d > 0 {
+			policy.window = d
+		} else {
+			app.logger.WithFields(logrus.Fields{"scope": scope, "window": rule.Window}).Warn("Invalid rate_limit window, using 1s")
+		}
+	}
+	switch policy.by {
+	case RateLimitByIP, RateLimitByUser, RateLimitByGlobal:
+	case "":
+		policy.by = RateLimitByIP
+	default:
+		app.logger.WithFields(logrus.Fields{"scope": scope, "by": rule.By}).Warn("Unknown rate_limit dimension, using ip")
+		policy.by = RateLimitByIP
+	}
+	if policy.burst <= 0 {
+		policy.burst = policy.requests
+	}
+	policy.interval = max(policy.window/time.Duration(policy.requests), time.Microsecond)
+	return policy, true
+}
+
+// rateLimitKey 返回请求在该规则下的计数键
+func (p *rateLimitPolicy) rateLimitKey(ctx *Context) RateLimitKey {
+	var value string
+	switch p.by {
+	case RateLimitByGlobal:
+		value = "*"
+	case RateLimitByUser:
+		if user, ok := ctx.User(); ok && user.ID != "" {
+			value = "user:" + user.ID
+		} else if ok {
+			value = "user:" + user.Username
+		} else {
+			value = "ip:" + ctx.IP()
+		}
+	default:
+		value = "ip:" + ctx.IP()
+	}
+	return RateLimitKey{Key: p.scope + ":" + value, Interval: p.interval, Burst: p.burst}
+}
+
+// checkRateLimit 按服务生效的限流规则计数，设置 X-RateLimit-Limit、X-RateLimit-Remaining 响应头；
+// 超限时设置 Retry-After 并返回429错误，后端不可用时记录警告并放行
+func (app *App) checkRateLimit(ctx *Context, svc *Service) error {
+	policies := app.rateLimitPolicies(svc)
+	if len(policies) == 0 {
+		return nil
+	}
+	keys := make([]RateLimitKey, len(policies))
+	for i := range policies {
+		keys[i] = policies[i].rateLimitKey(ctx)
+	}
+
+	app.rateLimit.mu.RLock()
+	store := app.rateLimit.store
+	app.rateLimit.mu.RUnlock()
+	result, err := store.Take(ctx.UserContext(), keys, time.Now())
+	if err != nil {
+		app.logger.WithError(err).WithFields(logrus.Fields{
+			"service": svc.Name,
+			"rid":     ctx.GetRequestID(),
+		}).Warn("Rate limit backend failed, request allowed")
+		return nil
+	}
+
+	ctx.Ctx.Set("X-RateLimit-Limit", strconv.Itoa(result.Limit))
+	ctx.Ctx.Set("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	if result.Allowed {
+		return nil
+	}
+
+	policy := policies[result.Denied]
+	retryAfter := time.Duration(math.Ceil(result.RetryAfter.Seconds())) * time.Second
+	app.logger.WithFields(logrus.Fields{
+		"service":     svc.Name,
+		"scope":       policy.scope,
+		"key":         keys[result.Denied].Key,
+		"retry_after": retryAfter.String(),
+		"rid":         ctx.GetRequestID(),
+	}).Warn("Request rate limited")
+	ctx.Ctx.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(retryAfter.Seconds())))
+	return ReplyWithDetail(fiber.StatusTooManyRequests, "Too many requests",
+		fmt.Sprintf("rate limit %s (%d requests per %s by %s) exceeded, retry after %s",
+			policy.scope, policy.requests, policy.window, policy.by, retryAfter))
+}
+
+// memoryRateLimitStore 进程内存中的限流计数，使用 GCRA 算法，每个计数键只保存理论到达时间
+type memoryRateLimitStore struct {
+	mu        sync.Mutex
+	tats      map[string]time.Time
+	lastSweep time.Time
+}
+
+func newMemoryRateLimitStore() *memoryRateLimitStore {
+	return &memoryRateLimitStore{tats: map[string]time.Time{}}
+}
+
+// Take 实现 RateLimitStore
+func (s *memoryRateLimitStore) Take(_ context.Context, keys []RateLimitKey, now time.Time) (RateLimitResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sweep(now)
+
+	result := RateLimitResult{Allowed: true, Denied: -1, Remaining: -1}
+	tats := make([]time.Time, len(keys))
+	for i, key := range keys {
+		tat := s.tats[key.Key]
+		if tat.Before(now) {
+			tat = now
+		}
+		tats[i] = tat.Add(key.Interval)
+		allowAt := tats[i].Add(-time.Duration(key.Burst) * key.Interval)
+		if now.Before(allowAt) {
+			if wait := allowAt.Sub(now); result.Allowed || wait > result.RetryAfter {
+				result.Allowed, result.Denied, result.RetryAfter = false, i, wait
+			}
+			continue
+		}
+		if left := int(now.Sub(allowAt) / key.Interval); result.Remaining < 0 || left < result.Remaining {
+			result.Limit, result.Remaining = key.Burst, left
+		}
+	}
+	if !result.Allowed {
+		result.Limit, result.Remaining = keys[result.Denied].Burst, 0
+		return result, nil
+	}
+	for i, key := range keys {
+		s.tats[key.Key] = tats[i]
+	}
+	return result, nil
+}
+
+// sweep 每分钟清理一次额度已恢复满的计数键，避免内存随IP、用户数量增长
+func (s *memoryRateLimitStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < time.Minute {
+		return
+	}
+	s.lastSweep = now
+	for key, tat := range s.tats {
+		if !tat.After(now) {
+			delete(s.tats, key)
+		}
+	}
+}
+
+// redisRateLimitStore Redis 中的限流计数，多个实例共享额度
+type redisRateLimitStore struct {
+	client *redis.Client
+	prefix string
+}
+
+// rateLimitScript 与 memoryRateLimitStore 相同的 GCRA 算法，时间单位为微秒；
+// ARGV[1] 为当前时间，之后每个计数键依次为恢复间隔和突发容量。返回 {是否允许, 超限的键下标, 等待时间, 突发容量, 剩余请求数}
+var rateLimitScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local tats = {}
+local denied, retry = 0, 0
+local limit, remaining = 0, -1
+for i = 1, #KEYS do
+	local interval = tonumber(ARGV[i * 2])
+	local burst = tonumber(ARGV[i * 2 + 1])
+	local tat = tonumber(redis.call('GET', KEYS[i]) or '0')
+	if tat < now then
+		tat = now
+	end
+	tats[i] = tat + interval
+	local allowAt = tats[i] - burst * interval
+	if now < allowAt then
+		if denied == 0 or allowAt - now > retry then
+			denied, retry = i, allowAt - now
+		end
+	else
+		local left = math.floor((now - allowAt) / interval)
+		if remaining < 0 or left < remaining then
+			limit, remaining = burst, left
+		end
+	end
+end
+if denied > 0 then
+	return {0, denied - 1, retry, tonumber(ARGV[denied * 2 + 1]), 0}
+end
+for i = 1, #KEYS do
+	redis.call('SET', KEYS[i], tats[i], 'PX', math.ceil((tats[i] - now) / 1000))
+end
+return {1, -1, 0, limit, remaining}
+`)
+
+// Take 实现 RateLimitStore
+func (s *redisRateLimitStore) Take(ctx context.Context, keys []RateLimitKey, now time.Time) (RateLimitResult, error) {
+	redisKeys := make([]string, len(keys))
+	args := []any{now.UnixMicro()}
+	for i, key := range keys {
+		redisKeys[i] = s.prefix + key.Key
+		args = append(args, key.Interval.Microseconds(), key.Burst)
+	}
+	values, err := rateLimitScript.Run(ctx, s.client, redisKeys, args...).Int64Slice()
+	if err != nil {
+		return RateLimitResult{}, err
+	}
+	if len(values) != 5 {
+		return RateLimitResult{}, fmt.Errorf("unexpected rate limit script result: %v", values)
+	}
+	return RateLimitResult{
+		Allowed:    values[0] == 1,
+		Denied:     int(values[1]),
+		RetryAfter: time.Duration(values[2]) * time.Microsecond,
+		Limit:      int(values[3]),
+		Remaining:  int(values[4]),
+	}, nil
+}
@@ -0,0 +1,51 @@
+package mod
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"reflect"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// RawRequest 原始请求体，Service.RawBody 为 true 时作为处理函数的输入类型，
+// 用于 Stripe、微信支付等需要对原始字节验签的 Webhook 回调
+//
+//	mod.MakeHandler(func(ctx *mod.Context, req *mod.RawRequest, resp *WebhookResult) error {
+//		event, err := webhook.ConstructEvent(req.Body, ctx.Get("Stripe-Signature"), secret)
+//		...
+//	})
+type RawRequest struct {
+	Body        []byte `json:"-"` // 请求体的原始字节，未经JSON解析和重新序列化
+	ContentType string `json:"-"` // Content-Type 请求头
+}
+
+// Reader 返回读取请求体的 io.Reader
+func (r *RawRequest) Reader() io.Reader {
+	return bytes.NewReader(r.Body)
+}
+
+// String 日志中只输出请求体的类型和长度
+func (r *RawRequest) String() string {
+	return fmt.Sprintf("%s (%d bytes)", r.ContentType, len(r.Body))
+}
+
+// rawRequestType RawBody 服务的输入类型
+var rawRequestType = reflect.TypeOf(RawRequest{})
+
+// checkRawBody RawBody 服务的输入类型必须是 RawRequest
+func checkRawBody(svc *Service) error {
+	if svc.Handler.InputType != nil && svc.Handler.InputType != rawRequestType {
+		return fmt.Errorf("raw body services must use *mod.RawRequest as input, got %s", svc.Handler.InputType)
+	}
+	return nil
+}
+
+// bindRawRequest 复制请求体，fasthttp 会在请求结束后复用缓冲区，处理函数可能在返回后继续持有请求体
+func bindRawRequest(fc *fiber.Ctx) *RawRequest {
+	return &RawRequest{
+		Body:        bytes.Clone(fc.Body()),
+		ContentType: fc.Get(fiber.HeaderContentType),
+	}
+}
@@ -0,0 +1,174 @@
+package mod
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ServiceModule 按业务模块组织的一组服务，通过 app.RegisterModules 统一注册
+type ServiceModule interface {
+	Services() []Service
+}
+
+// ServiceModuleFunc 将返回服务列表的函数适配为 ServiceModule
+type ServiceModuleFunc func() []Service
+
+// Services 实现 ServiceModule
+func (f ServiceModuleFunc) Services() []Service {
+	return f()
+}
+
+// checkService 注册前检查服务定义：必填字段、处理函数、输入输出类型及其标签以及名称和路径是否重复
+func (app *App) checkService(svc *Service) error {
+	if svc.Handler.Func == nil && svc.Handler.call == nil {
+		return fmt.Errorf("service %q: handler is nil, use mod.MakeHandler to create it", svc.Name)
+	}
+	if err := validate.Struct(svc); err != nil {
+		return fmt.Errorf("service %q: %w", svc.Name, err)
+	}
+	if err := checkHandlerType("input", svc.Handler.InputType); err != nil {
+		return fmt.Errorf("service %q: %w", svc.Name, err)
+	}
+	if err := checkHandlerType("output", svc.Handler.OutputType); err != nil {
+		return fmt.Errorf("service %q: %w", svc.Name, err)
+	}
+	// 每个字段的错误单独一行，均包含服务名称
+	if err := app.checkServiceTags(svc); err != nil {
+		return err
+	}
+	if svc.RawBody {
+		if err := checkRawBody(svc); err != nil {
+			return fmt.Errorf("service %q: %w", svc.Name, err)
+		}
+	}
+	if svc.Handler.ndjson && (svc.RawBody || svc.Webhook != nil) {
+		return fmt.Errorf("service %q: import handlers cannot be combined with RawBody or Webhook", svc.Name)
+	}
+	if svc.Async && (svc.RawBody || svc.Handler.ndjson || svc.Webhook != nil) {
+		return fmt.Errorf("service %q: async services cannot be combined with RawBody, import handlers or Webhook", svc.Name)
+	}
+	if svc.Cache != nil && (svc.RawBody || svc.Handler.ndjson) {
+		return fmt.Errorf("service %q: response cache cannot be combined with RawBody or import handlers", svc.Name)
+	}
+	if svc.Webhook != nil {
+		if _, err := newWebhookVerifier(*svc.Webhook); err != nil {
+			return fmt.Errorf("service %q: %w", svc.Name, err)
+		}
+	}
+	if svc.Path != "" {
+		if err := checkServicePath(svc.Path); err != nil {
+			return fmt.Errorf("service %q: %w", svc.Name, err)
+		}
+	}
+	if svc.PathOverride != "" {
+		if svc.Path != "" {
+			return fmt.Errorf("service %q: Path and PathOverride cannot be set at the same time", svc.Name)
+		}
+		if !strings.HasPrefix(svc.PathOverride, "/") {
+			return fmt.Errorf("service %q: path override %q must start with /", svc.Name, svc.PathOverride)
+		}
+		if err := checkServicePath(svc.PathOverride); err != nil {
+			return fmt.Errorf("service %q: %w", svc.Name, err)
+		}
+	}
+	for _, name := range svc.Errors {
+		if _, ok := ErrorRegistry.Lookup(name); !ok {
+			return fmt.Errorf("service %q: error code %q is not registered", svc.Name, name)
+		}
+	}
+	if svc.Auth != "" && !validAuthStrategy(svc.Auth) {
+		return fmt.Errorf("service %q: unknown auth strategy %q", svc.Name, svc.Auth)
+	}
+
+	// 挂载后的子应用与父应用共享服务名称空间
+	if existing, exists := app.root().GetService(svc.Name); exists {
+		if svc.Version == "" || existing.Version == "" {
+			return fmt.Errorf("service %q: already registered", svc.Name)
+		}
+		// 同名服务的新版本共用已注册的路由
+		route := app.canaryRoute(svc.Name)
+		if route == nil {
+			return fmt.Errorf("service %q: versions must be registered on the same app", svc.Name)
+		}
+		if route.version(svc.Version) != nil {
+			return fmt.Errorf("service %q: version %q already registered", svc.Name, svc.Version)
+		}
+		if strings.Trim(svc.Path, "/") != strings.Trim(existing.Path, "/") ||
+			strings.Trim(svc.PathOverride, "/") != strings.Trim(existing.PathOverride, "/") {
+			return fmt.Errorf("service %q: all versions must use the same path", svc.Name)
+		}
+		return nil
+	}
+	servicePath := app.serviceRoute(svc)
+	for _, route := range app.GetRoutes() {
+		if route.Method == fiber.MethodPost && routeShape(route.Path) == routeShape(servicePath) {
+			return fmt.Errorf("service %q: route POST %s is already in use", svc.Name, servicePath)
+		}
+	}
+	return nil
+}
+
+// checkHandlerType 输入输出类型必须是结构体（或为空），指针类型会导致参数解析为双重指针
+func checkHandlerType(kind string, typ reflect.Type) error {
+	if typ == nil {
+		return nil
+	}
+	if typ.Kind() == reflect.Pointer {
+		return fmt.Errorf("%s type %s must not be a pointer, use MakeHandler(func(ctx *mod.Context, req *%s, resp *...) error)", kind, typ, typ.Elem().Name())
+	}
+	if typ.Kind() != reflect.Struct {
+		return fmt.Errorf("%s type %s must be a struct", kind, typ)
+	}
+	return nil
+}
+
+// RegisterMany 批量注册服务，先检查全部服务（包括批次内的重名），全部通过后再注册，
+// 存在问题时不注册任何服务并返回所有错误
+func (app *App) RegisterMany(services []Service) error {
+	var errs []error
+	seen := make(map[string]bool, len(services))
+	versioned := make(map[string]bool, len(services))
+	for i := range services {
+		svc := &services[i]
+		if err := app.checkService(svc); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		// 同名服务只允许以不同版本出现
+		if prevVersioned, ok := versioned[svc.Name]; ok && (!prevVersioned || svc.Version == "" || seen[svc.Name+"@"+svc.Version]) {
+			errs = append(errs, fmt.Errorf("service %q: registered more than once in the batch", svc.Name))
+		}
+		versioned[svc.Name] = svc.Version != ""
+		seen[svc.Name+"@"+svc.Version] = true
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	for _, svc := range services {
+		if err := app.Register(svc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RegisterModules 依次注册各模块的服务，所有模块的服务作为一个批次检查
+func (app *App) RegisterModules(modules ...ServiceModule) error {
+	var services []Service
+	for _, module := range modules {
+		services = append(services, module.Services()...)
+	}
+	return app.RegisterMany(services)
+}
+
+// MustRegister 注册服务，失败时 panic，适用于 main 中的启动注册
+func (app *App) MustRegister(services ...Service) {
+	if err := app.RegisterMany(services); err != nil {
+		panic(err)
+	}
+}
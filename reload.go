@@ -0,0 +1,541 @@
+package mod
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+	"github.com/sirupsen/logrus"
+	"github.com/valyala/fasthttp"
+)
+
+// reloadAdminGroup 运行时重载管理服务所在的分组
+const reloadAdminGroup = "运行时重载"
+
+// uploadBackends 上传存储后端，按 determineUploadBackend 的优先级排列
+var uploadBackends = []string{"s3", "oss", "gcs", "cos", "qiniu", "local"}
+
+// StaticMount 静态文件挂载
+type StaticMount struct {
+	URLPrefix  string `json:"url_prefix" validate:"required" desc:"URL前缀，如 /assets"`
+	LocalPath  string `json:"local_path" validate:"required" desc:"本地目录路径"`
+	Browseable bool   `json:"browseable" desc:"是否允许目录浏览"`
+	IndexFile  string `json:"index_file" desc:"索引文件，默认 index.html"`
+}
+
+// staticMountEntry 挂载表中的静态文件挂载
+type staticMountEntry struct {
+	StaticMount
+	handler fasthttp.RequestHandler
+	stop    chan struct{} // 关闭后停止文件句柄缓存的清理任务
+}
+
+// reloadState 运行时可替换的静态文件挂载和上传存储后端
+type reloadState struct {
+	staticMu sync.RWMutex
+	mounts   []*staticMountEntry // 按URL前缀长度降序排列，优先匹配最长前缀
+
+	// uploadMu 保护上传存储后端配置和客户端：上传、下载和删除持有读锁，重载持有写锁，
+	// 因此切换会等待进行中的上传完成，切换期间新的请求短暂等待
+	uploadMu      sync.RWMutex
+	uploadMaxSize int64             // 单文件最大大小
+	uploadRoutes  bool              // 是否已注册上传路由
+	retiredGCS    []*storage.Client // 重载后被替换的GCS客户端，关闭应用时释放
+}
+
+// ReloadStatusRequest 查询重载状态请求
+type ReloadStatusRequest struct{}
+
+// ReloadStatusResponse 重载状态响应
+type ReloadStatusResponse struct {
+	StaticMounts  []StaticMount `json:"static_mounts" desc:"当前生效的静态文件挂载"`
+	UploadBackend string        `json:"upload_backend" desc:"当前用于新上传文件的存储后端，未启用上传时为空"`
+}
+
+// StaticMountRemoveRequest 移除静态文件挂载请求
+type StaticMountRemoveRequest struct {
+	URLPrefix string `json:"url_prefix" validate:"required" desc:"要移除的挂载URL前缀"`
+}
+
+// UploadSwitchRequest 切换上传存储后端请求
+type UploadSwitchRequest struct {
+	Backend string `json:"backend" validate:"required,oneof=local s3 oss gcs cos qiniu" desc:"存储后端：local、s3、oss、gcs、cos、qiniu"`
+}
+
+// configureReloadAdmin 启用重载管理时注册运行时重载管理服务
+func (app *App) configureReloadAdmin() {
+	config := app.cfg.ModConfig.Reload.Admin
+	if !config.Enabled {
+		return
+	}
+
+	status := func(resp *ReloadStatusResponse) {
+		resp.StaticMounts = app.StaticMounts()
+		resp.UploadBackend = app.UploadBackend()
+	}
+	services := []Service{
+		{
+			Name:        "reload_status",
+			DisplayName: "重载状态",
+			Description: "查询当前生效的静态文件挂载和上传存储后端",
+			Group:       reloadAdminGroup,
+			Sort:        1,
+			SkipAuth:    config.SkipAuth,
+			Handler: MakeHandler(func(ctx *Context, req *ReloadStatusRequest, resp *ReloadStatusResponse) error {
+				status(resp)
+				return nil
+			}),
+		},
+		{
+			Name:        "static_mount_add",
+			DisplayName: "添加静态文件挂载",
+			Description: "添加静态文件挂载，已存在相同URL前缀的挂载时替换，立即对新请求生效",
+			Group:       reloadAdminGroup,
+			Sort:        2,
+			SkipAuth:    config.SkipAuth,
+			Handler: MakeHandler(func(ctx *Context, req *StaticMount, resp *ReloadStatusResponse) error {
+				if err := app.AddStaticMount(*req); err != nil {
+					return ReplyWithDetail(400, "无效的静态文件挂载", err.Error())
+				}
+				status(resp)
+				return nil
+			}),
+		},
+		{
+			Name:        "static_mount_remove",
+			DisplayName: "移除静态文件挂载",
+			Description: "移除指定URL前缀的静态文件挂载",
+			Group:       reloadAdminGroup,
+			Sort:        3,
+			SkipAuth:    config.SkipAuth,
+			Handler: MakeHandler(func(ctx *Context, req *StaticMountRemoveRequest, resp *ReloadStatusResponse) error {
+				if !app.RemoveStaticMount(req.URLPrefix) {
+					return Reply(404, "静态文件挂载不存在")
+				}
+				status(resp)
+				return nil
+			}),
+		},
+		{
+			Name:        "static_mount_reload",
+			DisplayName: "重新加载静态文件挂载",
+			Description: "重新读取配置文件中的 static_mounts 并替换全部静态文件挂载，任一挂载无效时保持原有挂载",
+			Group:       reloadAdminGroup,
+			Sort:        4,
+			SkipAuth:    config.SkipAuth,
+			Handler: MakeHandler(func(ctx *Context, req *ReloadStatusRequest, resp *ReloadStatusResponse) error {
+				if err := app.ReloadStaticMounts(); err != nil {
+					return ReplyWithDetail(500, "重新加载静态文件挂载失败", err.Error())
+				}
+				status(resp)
+				return nil
+			}),
+		},
+		{
+			Name:        "upload_reload",
+			DisplayName: "重新加载上传后端",
+			Description: "重新读取配置文件中 file_upload 的存储后端配置并重新初始化客户端，初始化失败时保持原配置",
+			Group:       reloadAdminGroup,
+			Sort:        5,
+			SkipAuth:    config.SkipAuth,
+			Handler: MakeHandler(func(ctx *Context, req *ReloadStatusRequest, resp *ReloadStatusResponse) error {
+				if err := app.ReloadFileUpload(); err != nil {
+					return ReplyWithDetail(500, "重新加载上传后端失败", err.Error())
+				}
+				status(resp)
+				return nil
+			}),
+		},
+		{
+			Name:        "upload_switch",
+			DisplayName: "切换上传后端",
+			Description: "将新上传的文件切换到指定的存储后端（使用已加载的后端配置），已上传的文件仍可下载和删除",
+			Group:       reloadAdminGroup,
+			Sort:        6,
+			SkipAuth:    config.SkipAuth,
+			Handler: MakeHandler(func(ctx *Context, req *UploadSwitchRequest, resp *ReloadStatusResponse) error {
+				if err := app.SwitchUploadBackend(req.Backend); err != nil {
+					return ReplyWithDetail(500, "切换上传后端失败", err.Error())
+				}
+				status(resp)
+				return nil
+			}),
+		},
+	}
+
+	for _, svc := range services {
+		if err := app.Register(app.adminService(svc)); err != nil {
+			app.logger.WithError(err).WithField("service", svc.Name).Error("Failed to register reload admin service")
+		}
+	}
+}
+
+// normalizeMountPrefix 规范化挂载的URL前缀：以 / 开头，不以 / 结尾（根路径除外）
+func normalizeMountPrefix(prefix string) string {
+	return "/" + strings.Trim(prefix, "/")
+}
+
+// newStaticMountEntry 校验静态文件挂载并创建文件服务处理器
+func (app *App) newStaticMountEntry(mount StaticMount) (*staticMountEntry, error) {
+	// 参数校验
+	if mount.URLPrefix == "" || mount.LocalPath == "" {
+		return nil, fmt.Errorf("url_prefix and local_path are required")
+	}
+
+	// 路径安全检查
+	if !app.isValidStaticPath(mount.LocalPath) {
+		return nil, fmt.Errorf("invalid local path")
+	}
+
+	// 检查本地路径是否存在
+	if _, err := os.Stat(mount.LocalPath); os.IsNotExist(err) {
+		return nil, fmt.Errorf("local path does not exist")
+	}
+
+	mount.URLPrefix = normalizeMountPrefix(mount.URLPrefix)
+	if !app.Config().CaseSensitive {
+		mount.URLPrefix = utils.ToLower(mount.URLPrefix)
+	}
+	if mount.IndexFile == "" {
+		mount.IndexFile = "index.html" // 默认索引文件
+	}
+
+	prefixLen := len(mount.URLPrefix)
+	if mount.URLPrefix == "/" {
+		prefixLen = 0
+	}
+	stop := make(chan struct{})
+	fs := &fasthttp.FS{
+		Root:                 strings.TrimSuffix(mount.LocalPath, "/"),
+		AllowEmptyRoot:       true,
+		GenerateIndexPages:   mount.Browseable, // 目录浏览
+		AcceptByteRange:      true,             // 支持范围请求
+		Compress:             true,             // 启用压缩
+		CompressedFileSuffix: app.Config().CompressedFileSuffix,
+		CacheDuration:        10 * time.Second,
+		IndexNames:           []string{mount.IndexFile},
+		CleanStop:            stop,
+		PathRewrite: func(fctx *fasthttp.RequestCtx) []byte {
+			path := fctx.Path()
+			if len(path) >= prefixLen {
+				path = path[prefixLen:]
+			}
+			if len(path) == 0 || path[0] != '/' {
+				path = append([]byte("/"), path...)
+			}
+			return path
+		},
+		PathNotFound: func(fctx *fasthttp.RequestCtx) {
+			fctx.Response.SetStatusCode(fiber.StatusNotFound)
+		},
+	}
+
+	return &staticMountEntry{StaticMount: mount, handler: fs.NewRequestHandler(), stop: stop}, nil
+}
+
+// putStaticMount 添加挂载，已存在相同URL前缀的挂载时替换
+func (s *reloadState) putStaticMount(entry *staticMountEntry) {
+	s.staticMu.Lock()
+	defer s.staticMu.Unlock()
+
+	mounts := make([]*staticMountEntry, 0, len(s.mounts)+1)
+	for _, m := range s.mounts {
+		if m.URLPrefix == entry.URLPrefix {
+			close(m.stop)
+			continue
+		}
+		mounts = append(mounts, m)
+	}
+	mounts = append(mounts, entry)
+	sort.SliceStable(mounts, func(i, j int) bool {
+		return len(mounts[i].URLPrefix) > len(mounts[j].URLPrefix)
+	})
+	s.mounts = mounts
+}
+
+// matchStaticMount 返回与请求路径匹配的最长前缀挂载
+func (s *reloadState) matchStaticMount(path string) *staticMountEntry {
+	s.staticMu.RLock()
+	defer s.staticMu.RUnlock()
+
+	for _, m := range s.mounts {
+		if m.URLPrefix == "/" || path == m.URLPrefix || strings.HasPrefix(path, m.URLPrefix+"/") {
+			return m
+		}
+	}
+	return nil
+}
+
+// serveStaticMounts 按挂载表分发静态文件请求，文件不存在或禁止访问时交由后续路由处理
+func (app *App) serveStaticMounts(c *fiber.Ctx) error {
+	if c.Method() != fiber.MethodGet && c.Method() != fiber.MethodHead {
+		return c.Next()
+	}
+
+	path := c.Path()
+	if !app.Config().CaseSensitive {
+		path = utils.ToLower(path)
+	}
+	mount := app.reload.matchStaticMount(path)
+	if mount == nil {
+		return c.Next()
+	}
+
+	mount.handler(c.Context())
+	status := c.Context().Response.StatusCode()
+	if status != fiber.StatusNotFound && status != fiber.StatusForbidden {
+		return nil
+	}
+
+	// 重置响应后交由后续路由处理
+	c.Context().SetContentType("")
+	c.Context().Response.SetStatusCode(fiber.StatusOK)
+	c.Context().Response.SetBodyString("")
+	return c.Next()
+}
+
+// StaticMounts 返回当前生效的静态文件挂载
+func (app *App) StaticMounts() []StaticMount {
+	s := &app.root().reload
+	s.staticMu.RLock()
+	defer s.staticMu.RUnlock()
+
+	mounts := make([]StaticMount, 0, len(s.mounts))
+	for _, m := range s.mounts {
+		mounts = append(mounts, m.StaticMount)
+	}
+	sort.Slice(mounts, func(i, j int) bool { return mounts[i].URLPrefix < mounts[j].URLPrefix })
+	return mounts
+}
+
+// AddStaticMount 在运行时添加静态文件挂载，已存在相同URL前缀的挂载时替换（如CDN回源目录切换），立即对新请求生效
+func (app *App) AddStaticMount(mount StaticMount) error {
+	root := app.root()
+	entry, err := root.newStaticMountEntry(mount)
+	if err != nil {
+		return err
+	}
+	root.reload.putStaticMount(entry)
+
+	root.logger.WithFields(logrus.Fields{
+		"url_prefix": entry.URLPrefix,
+		"local_path": entry.LocalPath,
+		"browseable": entry.Browseable,
+		"index_file": entry.IndexFile,
+	}).Info("Static mount added")
+	return nil
+}
+
+// RemoveStaticMount 在运行时移除指定URL前缀的静态文件挂载，返回挂载是否存在
+func (app *App) RemoveStaticMount(urlPrefix string) bool {
+	root := app.root()
+	prefix := normalizeMountPrefix(urlPrefix)
+	if !root.Config().CaseSensitive {
+		prefix = utils.ToLower(prefix)
+	}
+
+	s := &root.reload
+	s.staticMu.Lock()
+	defer s.staticMu.Unlock()
+	for i, m := range s.mounts {
+		if m.URLPrefix == prefix {
+			close(m.stop)
+			s.mounts = append(s.mounts[:i:i], s.mounts[i+1:]...)
+			root.logger.WithField("url_prefix", prefix).Info("Static mount removed")
+			return true
+		}
+	}
+	return false
+}
+
+// ReloadStaticMounts 重新读取配置文件中的 static_mounts 并替换全部静态文件挂载；
+// 任一挂载无效时返回错误，保持原有挂载不变
+func (app *App) ReloadStaticMounts() error {
+	root := app.root()
+	cfg, err := loadModConfig()
+	if err != nil {
+		return err
+	}
+
+	entries := make([]*staticMountEntry, 0, len(cfg.StaticMounts))
+	for _, mount := range cfg.StaticMounts {
+		entry, err := root.newStaticMountEntry(StaticMount(mount))
+		if err != nil {
+			for _, e := range entries {
+				close(e.stop)
+			}
+			return fmt.Errorf("static mount %s -> %s: %w", mount.URLPrefix, mount.LocalPath, err)
+		}
+		entries = append(entries, entry)
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return len(entries[i].URLPrefix) > len(entries[j].URLPrefix)
+	})
+
+	s := &root.reload
+	s.staticMu.Lock()
+	old := s.mounts
+	s.mounts = entries
+	s.staticMu.Unlock()
+	for _, m := range old {
+		close(m.stop)
+	}
+
+	root.logger.WithField("mounts", len(entries)).Info("Static mounts reloaded")
+	return nil
+}
+
+// uploadMaxSize 返回单文件最大大小，启用本地上传时使用 local.max_size，默认10MB
+func uploadMaxSize(hasLocal bool, maxSize string) int64 {
+	var maxSizeBytes int64 = 10 * 1024 * 1024 // 默认10MB
+	if hasLocal && maxSize != "" {
+		if size, err := parseSize(maxSize); err == nil {
+			maxSizeBytes = size
+		}
+	}
+	return maxSizeBytes
+}
+
+// registerUploadRoutes 注册文件上传路由，上传期间持有存储后端的读锁
+func (app *App) registerUploadRoutes() {
+	if app.reload.uploadRoutes {
+		return
+	}
+	app.reload.uploadRoutes = true
+
+	app.Post("/upload", func(c *fiber.Ctx) error {
+		app.reload.uploadMu.RLock()
+		defer app.reload.uploadMu.RUnlock()
+		return app.handleFileUpload(c, app.reload.uploadMaxSize)
+	})
+
+	// 注册批量文件上传路由
+	app.Post("/upload/batch", func(c *fiber.Ctx) error {
+		app.reload.uploadMu.RLock()
+		defer app.reload.uploadMu.RUnlock()
+		return app.handleBatchFileUpload(c, app.reload.uploadMaxSize)
+	})
+}
+
+// UploadBackend 返回当前用于新上传文件的存储后端，未启用上传时为空
+func (app *App) UploadBackend() string {
+	root := app.root()
+	root.reload.uploadMu.RLock()
+	defer root.reload.uploadMu.RUnlock()
+	return root.determineUploadBackend()
+}
+
+// ReloadFileUpload 重新读取配置文件中 file_upload 的存储后端配置（local、s3、oss、gcs、cos、qiniu）并重新初始化客户端，
+// 用于存储迁移时切换上传后端而无需重启；任一启用的后端初始化失败时返回错误，保持原配置不变。
+// 已上传的文件仍通过原后端的客户端下载和删除；元数据、下载、配额和内容校验配置仅在启动时生效
+func (app *App) ReloadFileUpload() error {
+	cfg, err := loadModConfig()
+	if err != nil {
+		return err
+	}
+
+	return app.root().applyUploadBackends(func(current *ModConfig) {
+		copyUploadBackends(current, cfg)
+	})
+}
+
+// SwitchUploadBackend 将新上传的文件切换到指定的存储后端（local、s3、oss、gcs、cos、qiniu），
+// 使用当前已加载的后端配置并停用其他后端；初始化失败时返回错误，保持原配置不变
+func (app *App) SwitchUploadBackend(backend string) error {
+	found := false
+	for _, b := range uploadBackends {
+		found = found || b == backend
+	}
+	if !found {
+		return fmt.Errorf("unsupported upload backend: %s", backend)
+	}
+
+	return app.root().applyUploadBackends(func(current *ModConfig) {
+		upload := &current.FileUpload
+		upload.Local.Enabled = backend == "local"
+		upload.S3.Enabled = backend == "s3"
+		upload.OSS.Enabled = backend == "oss"
+		upload.GCS.Enabled = backend == "gcs"
+		upload.COS.Enabled = backend == "cos"
+		upload.Qiniu.Enabled = backend == "qiniu"
+	})
+}
+
+// copyUploadBackends 复制上传存储后端配置，不包括元数据、下载、配额等仅在启动时生效的配置
+func copyUploadBackends(dst, src *ModConfig) {
+	dst.FileUpload.Local = src.FileUpload.Local
+	dst.FileUpload.S3 = src.FileUpload.S3
+	dst.FileUpload.OSS = src.FileUpload.OSS
+	dst.FileUpload.GCS = src.FileUpload.GCS
+	dst.FileUpload.COS = src.FileUpload.COS
+	dst.FileUpload.Qiniu = src.FileUpload.Qiniu
+}
+
+// applyUploadBackends 持有写锁修改存储后端配置并初始化启用的后端，失败时恢复原配置和客户端
+func (app *App) applyUploadBackends(update func(current *ModConfig)) error {
+	if !app.reload.uploadRoutes {
+		return fmt.Errorf("upload routes are not registered, enable file_upload or reload.admin at startup")
+	}
+
+	s := &app.reload
+	s.uploadMu.Lock()
+	defer s.uploadMu.Unlock()
+
+	var previous ModConfig
+	copyUploadBackends(&previous, app.cfg.ModConfig)
+	ossClient, gcsClient, cosClient, qiniuClient := app.ossClient, app.gcsClient, app.cosClient, app.qiniuClient
+
+	update(app.cfg.ModConfig)
+	config := app.cfg.ModConfig.FileUpload
+	steps := []struct {
+		name      string
+		enabled   bool
+		configure func() error
+	}{
+		{"local", config.Local.Enabled, app.configureLocalUpload},
+		{"s3", config.S3.Enabled, app.configureS3Upload},
+		{"oss", config.OSS.Enabled, app.configureOSSUpload},
+		{"gcs", config.GCS.Enabled, app.configureGCSUpload},
+		{"cos", config.COS.Enabled, app.configureCOSUpload},
+		{"qiniu", config.Qiniu.Enabled, app.configureQiniuUpload},
+	}
+	var err error
+	for _, step := range steps {
+		if !step.enabled {
+			continue
+		}
+		if err = step.configure(); err != nil {
+			err = fmt.Errorf("upload.%s: %w", step.name, err)
+			break
+		}
+	}
+
+	if err != nil {
+		// 关闭本次创建的GCS客户端，恢复原配置和客户端
+		if app.gcsClient != gcsClient && app.gcsClient != nil {
+			app.gcsClient.Close()
+		}
+		copyUploadBackends(app.cfg.ModConfig, &previous)
+		app.ossClient, app.gcsClient, app.cosClient, app.qiniuClient = ossClient, gcsClient, cosClient, qiniuClient
+		app.logger.WithError(err).Error("Failed to reload file upload backends, keeping previous configuration")
+		return err
+	}
+
+	// 被替换的GCS客户端可能仍在为进行中的下载提供数据，关闭应用时再释放
+	if gcsClient != nil && app.gcsClient != gcsClient {
+		s.retiredGCS = append(s.retiredGCS, gcsClient)
+	}
+	s.uploadMaxSize = uploadMaxSize(config.Local.Enabled, config.Local.MaxSize)
+
+	app.logger.WithFields(logrus.Fields{
+		"backend":  app.determineUploadBackend(),
+		"max_size": s.uploadMaxSize,
+	}).Info("File upload backends reloaded")
+	return nil
+}
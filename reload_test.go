@@ -0,0 +1,17 @@
+package mod
+
+import "testing"
+
+func TestReloadAdminRequiresAdmin(t *testing.T) {
+	app := newTestApp(t, tokenCacheConfig+`
+reload:
+  admin:
+    enabled: true
+`)
+	assertAdminService(t, app, "reload_status", ReloadStatusRequest{})
+	assertAdminService(t, app, "static_mount_add", StaticMount{URLPrefix: "/assets", LocalPath: t.TempDir()})
+	assertAdminService(t, app, "static_mount_remove", StaticMountRemoveRequest{URLPrefix: "/missing"})
+	assertAdminService(t, app, "static_mount_reload", ReloadStatusRequest{})
+	assertAdminService(t, app, "upload_reload", ReloadStatusRequest{})
+	assertAdminService(t, app, "upload_switch", UploadSwitchRequest{Backend: "local"})
+}
@@ -0,0 +1,212 @@
+package mod
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"reflect"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+	"github.com/tinylib/msgp/msgp"
+)
+
+// 请求体格式，用于 RequestBody.Accepts
+const (
+	BodyJSON      = "json"      // application/json，未设置 Content-Type 时按 JSON 处理
+	BodyXML       = "xml"       // application/xml、text/xml
+	BodyMsgpack   = "msgpack"   // application/msgpack、application/x-msgpack、application/vnd.msgpack
+	BodyForm      = "form"      // application/x-www-form-urlencoded
+	BodyMultipart = "multipart" // multipart/form-data
+)
+
+// RequestBody 服务的请求体大小上限和接受的格式
+type RequestBody struct {
+	MaxSize string   `yaml:"max_size" json:"max_size"` // 请求体大小上限，如 64KB、20MB，为空时使用 server.body_limit
+	Accepts []string `yaml:"accepts" json:"accepts"`   // 接受的格式：json、xml、msgpack、form、multipart，为空时接受全部格式
+}
+
+// requestBodyPolicy 注册时解析的请求体配置
+type requestBodyPolicy struct {
+	maxSize int
+	accepts []string // 为空时接受全部格式
+}
+
+// requestBodyPolicy 解析服务生效的请求体配置，依次使用 request_body.services、Service.RequestBody、request_body.default，
+// 未配置时返回 nil
+func (app *App) requestBodyPolicy(svc *Service) *requestBodyPolicy {
+	config := app.cfg.ModConfig.RequestBody
+	rule := config.Default
+	if r, ok := config.Services[svc.Name]; ok {
+		rule = r
+	} else if svc.RequestBody != nil {
+		rule = *svc.RequestBody
+	}
+	if rule.MaxSize == "" && len(rule.Accepts) == 0 {
+		return nil
+	}
+
+	policy := &requestBodyPolicy{}
+	fields := logrus.Fields{"service": svc.Name, "max_size": rule.MaxSize}
+	if rule.MaxSize != "" {
+		if size, err := parseSize(rule.MaxSize); err != nil || size <= 0 {
+			app.logger.WithFields(fields).Warn("Invalid request body max_size, using server.body_limit")
+		} else {
+			policy.maxSize = int(size)
+		}
+	}
+	// 未流式读取请求体时，超过 server.body_limit 的请求在到达服务前已被拒绝
+	if policy.maxSize > app.cfg.Config.BodyLimit && !app.cfg.Config.StreamRequestBody {
+		app.logger.WithFields(fields).Warn("Request body max_size exceeds server.body_limit, larger requests are rejected by the server")
+	}
+	for _, format := range rule.Accepts {
+		format = strings.ToLower(strings.TrimSpace(format))
+		switch format {
+		case BodyJSON, BodyXML, BodyMsgpack, BodyForm, BodyMultipart:
+			policy.accepts = append(policy.accepts, format)
+		default:
+			app.logger.WithFields(logrus.Fields{"service": svc.Name, "format": format}).Warn("Unknown request body format, ignored")
+		}
+	}
+	return policy
+}
+
+// checkRequestBody 按服务的配置检查请求体大小和格式，超过上限时返回413，格式不在 accepts 中时返回415
+func (app *App) checkRequestBody(fc *fiber.Ctx, svc *Service) error {
+	policy := svc.requestBody
+	limit := app.cfg.Config.BodyLimit
+	if policy != nil && policy.maxSize > 0 {
+		limit = policy.maxSize
+	}
+	if err := app.limitRequestBody(fc, limit); err != nil {
+		var reply *StdReply
+		if errors.As(err, &reply) {
+			return reply
+		}
+		return ReplyWithDetail(400, "Request body read error", err.Error())
+	}
+	if len(fc.Body()) > limit {
+		return ReplyWithDetail(413, "Request body too large", fmt.Sprintf("limit is %d bytes", limit))
+	}
+
+	if policy == nil || len(policy.accepts) == 0 || len(fc.Body()) == 0 {
+		return nil
+	}
+	format := BodyFormat(fc)
+	for _, accepted := range policy.accepts {
+		if format == accepted {
+			return nil
+		}
+	}
+	return ReplyWithDetail(415, "Unsupported Media Type", "accepted formats: "+strings.Join(policy.accepts, ", "))
+}
+
+// bodyMediaTypes 请求体格式在文档中使用的 Content-Type
+var bodyMediaTypes = map[string]string{
+	BodyJSON:      fiber.MIMEApplicationJSON,
+	BodyXML:       fiber.MIMEApplicationXML,
+	BodyMsgpack:   "application/msgpack",
+	BodyForm:      fiber.MIMEApplicationForm,
+	BodyMultipart: fiber.MIMEMultipartForm,
+}
+
+// requestMediaTypes 返回 OpenAPI 中服务请求体的 Content-Type：有上传文件字段时为 multipart/form-data，
+// 配置了 accepts 时为接受的格式，否则为 JSON
+func requestMediaTypes(svc *Service) []string {
+	if multipartInput(svc.Handler.InputType) {
+		return []string{fiber.MIMEMultipartForm}
+	}
+	if svc.requestBody == nil || len(svc.requestBody.accepts) == 0 {
+		return []string{fiber.MIMEApplicationJSON}
+	}
+	types := make([]string, 0, len(svc.requestBody.accepts))
+	for _, format := range svc.requestBody.accepts {
+		types = append(types, bodyMediaTypes[format])
+	}
+	return types
+}
+
+// BodyFormat 按 Content-Type 返回请求体格式，未设置 Content-Type 时为 json，无法识别时返回空字符串
+func BodyFormat(c *fiber.Ctx) string {
+	contentType := c.Get(fiber.HeaderContentType)
+	if strings.TrimSpace(contentType) == "" {
+		return BodyJSON
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return ""
+	}
+	switch {
+	case mediaType == fiber.MIMEApplicationJSON || mediaType == "text/json" || strings.HasSuffix(mediaType, "+json"):
+		return BodyJSON
+	case mediaType == fiber.MIMEApplicationXML || mediaType == fiber.MIMETextXML || strings.HasSuffix(mediaType, "+xml"):
+		return BodyXML
+	case mediaType == "application/msgpack" || mediaType == "application/x-msgpack" || mediaType == "application/vnd.msgpack":
+		return BodyMsgpack
+	case mediaType == fiber.MIMEApplicationForm:
+		return BodyForm
+	case mediaType == fiber.MIMEMultipartForm:
+		return BodyMultipart
+	}
+	return ""
+}
+
+// IsJSONBody 请求体是否按 JSON 解析：Content-Type 为 JSON、未设置或无法识别（与之前只支持 JSON 时的行为一致）
+func IsJSONBody(c *fiber.Ctx) bool {
+	format := BodyFormat(c)
+	return format == BodyJSON || format == ""
+}
+
+// BindBody 按 Content-Type 将请求体解析到 in：JSON、XML、MessagePack 的字段名与 JSON 一致，
+// 表单和 multipart 按 json 字段名读取表单值（见 BindMultipart）；没有请求体时不做处理。mod gen 生成的绑定代码同样调用此函数
+func BindBody(c *fiber.Ctx, in any) error {
+	format := BodyFormat(c)
+	if format == BodyMultipart {
+		return BindMultipart(c, in)
+	}
+	body := c.Body()
+	if len(body) == 0 {
+		return nil
+	}
+	switch format {
+	case BodyXML:
+		if err := decodeXML(body, in); err != nil {
+			return fmt.Errorf("failed to parse XML body: %w", err)
+		}
+	case BodyMsgpack:
+		if err := decodeMsgpack(body, in); err != nil {
+			return fmt.Errorf("failed to parse MessagePack body: %w", err)
+		}
+	case BodyForm:
+		rv := reflect.ValueOf(in)
+		if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+			return fmt.Errorf("input parameter must be a pointer to struct")
+		}
+		values := map[string][]string{}
+		c.Request().PostArgs().VisitAll(func(key, value []byte) {
+			values[string(key)] = append(values[string(key)], string(value))
+		})
+		return bindFormValues(rv.Elem(), values)
+	default:
+		if err := json.Unmarshal(body, in); err != nil {
+			return fmt.Errorf("failed to parse JSON body: %w", err)
+		}
+	}
+	return nil
+}
+
+// decodeMsgpack 将 MessagePack 请求体转换为 JSON 后解析，二进制数据按 base64 字符串处理（与 JSON 中的 []byte 一致）
+func decodeMsgpack(data []byte, v any) error {
+	var buf bytes.Buffer
+	rest, err := msgp.UnmarshalAsJSON(&buf, data)
+	if err != nil {
+		return err
+	}
+	if len(rest) > 0 {
+		return errors.New("unexpected data after MessagePack value")
+	}
+	return json.Unmarshal(buf.Bytes(), v)
+}
@@ -0,0 +1,283 @@
+package mod
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ResolveStep 按优先级检查的一层配置
+type ResolveStep struct {
+	Source  string `json:"source"`          // 配置位置，如 mock.services.get_order、Service.Auth
+	Set     bool   `json:"set"`             // 该层是否设置了值
+	Value   any    `json:"value,omitempty"` // 该层设置的值
+	Applied bool   `json:"applied"`         // 是否为生效的配置层
+	Note    string `json:"note,omitempty"`  // 说明，如被更高优先级的配置覆盖
+}
+
+// ResolvedSetting 服务生效的单项配置：Steps 按优先级从高到低列出检查的各层，第一个设置了值的层生效
+// （限流各层同时生效）
+type ResolvedSetting struct {
+	Value  any           `json:"value"`          // 生效的值
+	Source string        `json:"source"`         // 生效的配置层，均未设置时为 default
+	Note   string        `json:"note,omitempty"` // 影响生效值的其他配置，如 mock.forbid_in
+	Steps  []ResolveStep `json:"steps"`          // 检查的各层配置
+}
+
+// ServiceResolution 服务生效的 Mock、加解密、认证、限流和权限配置及其来源
+type ServiceResolution struct {
+	Service    string          `json:"service"`
+	Group      string          `json:"group,omitempty"`
+	Mock       ResolvedSetting `json:"mock"`
+	Encryption ResolvedSetting `json:"encryption"`
+	Auth       ResolvedSetting `json:"auth"`
+	RateLimit  ResolvedSetting `json:"rate_limit"`
+	Permission ResolvedSetting `json:"permission"`
+}
+
+// ResolveService 说明服务生效的 Mock、加解密、认证、限流和权限配置以及各层配置（服务 > 分组 > 全局）的检查过程，
+// 挂载的子应用服务按子应用的配置解析；服务不存在时返回 false
+func (app *App) ResolveService(name string) (*ServiceResolution, bool) {
+	for _, svc := range app.allServices() {
+		if svc.Name == name {
+			return svc.owner.resolveService(&svc), true
+		}
+	}
+	return nil, false
+}
+
+// ResolveServices 返回全部服务的配置解析结果，多版本服务只返回最先注册的版本
+func (app *App) ResolveServices() []*ServiceResolution {
+	var resolutions []*ServiceResolution
+	seen := map[string]bool{}
+	for _, svc := range app.allServices() {
+		if seen[svc.Name] {
+			continue
+		}
+		seen[svc.Name] = true
+		resolutions = append(resolutions, svc.owner.resolveService(&svc))
+	}
+	return resolutions
+}
+
+func (app *App) resolveService(svc *Service) *ServiceResolution {
+	return &ServiceResolution{
+		Service:    svc.Name,
+		Group:      svc.Group,
+		Mock:       app.resolveMock(svc),
+		Encryption: app.resolveEncryption(svc),
+		Auth:       app.resolveAuth(svc),
+		RateLimit:  app.resolveRateLimit(svc),
+		Permission: resolvePermissionSetting(svc),
+	}
+}
+
+// resolveSteps 标记第一个设置了值的层为生效的配置层，之后设置了值的层标记为被覆盖
+func resolveSteps(setting *ResolvedSetting, fallback any) {
+	setting.Source = "default"
+	setting.Value = fallback
+	for i := range setting.Steps {
+		step := &setting.Steps[i]
+		if !step.Set {
+			step.Value = nil
+			continue
+		}
+		if setting.Source == "default" {
+			step.Applied = true
+			setting.Source = step.Source
+			setting.Value = step.Value
+		} else if step.Note == "" {
+			step.Note = "overridden by " + setting.Source
+		}
+	}
+}
+
+// resolveMock 依次检查服务、分组、全局的运行时开关和 mock 配置，与 mockConfigured 的优先级一致
+func (app *App) resolveMock(svc *Service) ResolvedSetting {
+	setting := ResolvedSetting{}
+	if svc.builtin || svc.ws != nil {
+		setting.Value, setting.Source = false, "default"
+		setting.Note = "mock is not supported for framework and WebSocket services"
+		return setting
+	}
+	config := app.cfg.ModConfig.Mock
+	override := func(scope, name, source string) {
+		enabled, exists := app.mockOverride(scope, name)
+		setting.Steps = append(setting.Steps, ResolveStep{Source: source, Set: exists, Value: enabled})
+	}
+
+	override("service", svc.Name, "override.services."+svc.Name)
+	serviceConfig, exists := config.Services[svc.Name]
+	setting.Steps = append(setting.Steps, ResolveStep{Source: "mock.services." + svc.Name, Set: exists, Value: serviceConfig.Enabled})
+	if svc.Group != "" {
+		override("group", svc.Group, "override.groups."+svc.Group)
+		groupConfig, exists := config.Groups[svc.Group]
+		setting.Steps = append(setting.Steps, ResolveStep{Source: "mock.groups." + svc.Group, Set: exists, Value: groupConfig.Enabled})
+	}
+	override("global", "", "override.global")
+	setting.Steps = append(setting.Steps, ResolveStep{Source: "mock.global", Set: true, Value: config.Global.Enabled})
+	resolveSteps(&setting, false)
+
+	if setting.Value == true {
+		if app.mockForbidden() {
+			setting.Note = "mock.forbid_in contains the current environment, requests run the real handler"
+		} else if app.mockWarned() {
+			setting.Note = "mock.warn_in contains the current environment, mock responses are audited"
+		}
+	}
+	return setting
+}
+
+// resolveEncryption 按 CheckEncryption 的规则依次检查全局开关、白名单、服务、分组和全局配置
+func (app *App) resolveEncryption(svc *Service) ResolvedSetting {
+	config := app.cfg.ModConfig.Encryption
+	setting := ResolvedSetting{}
+	if !config.Global.Enabled {
+		setting.Steps = []ResolveStep{{Source: "encryption.global", Set: true, Value: false, Applied: true}}
+		setting.Value, setting.Source = false, "encryption.global"
+		setting.Note = "encryption.global.enabled is off, service and group settings are ignored"
+		return setting
+	}
+
+	serviceWhitelisted := slices.Contains(config.Whitelist.Services, svc.Name)
+	groupWhitelisted := slices.Contains(config.Whitelist.Groups, svc.Group)
+	serviceConfig, serviceSet := config.Services[svc.Name]
+	groupConfig, groupSet := config.Groups[svc.Group]
+	setting.Steps = []ResolveStep{
+		{Source: "encryption.whitelist.services", Set: serviceWhitelisted, Value: false},
+		{Source: "encryption.whitelist.groups", Set: groupWhitelisted, Value: false},
+		{Source: "encryption.services." + svc.Name, Set: serviceSet, Value: serviceConfig.Enabled},
+		{Source: "encryption.groups." + svc.Group, Set: groupSet, Value: groupConfig.Enabled},
+		{Source: "encryption.global", Set: true, Value: true},
+	}
+	resolveSteps(&setting, true)
+	return setting
+}
+
+// resolveAuth 按 authStrategy 的顺序检查 Service.Auth、SkipAuth、auth.services、分组认证方式和 auth.default
+func (app *App) resolveAuth(svc *Service) ResolvedSetting {
+	config := app.cfg.ModConfig.Auth
+	app.authMu.RLock()
+	groupAuth, groupAuthSet := app.groupAuth[svc.Group]
+	app.authMu.RUnlock()
+
+	setting := ResolvedSetting{Steps: []ResolveStep{
+		{Source: "Service.Auth", Set: svc.Auth != "", Value: svc.Auth},
+		{Source: "Service.SkipAuth", Set: svc.SkipAuth, Value: AuthNone},
+		{Source: "auth.services." + svc.Name, Set: config.Services[svc.Name] != "", Value: AuthStrategy(config.Services[svc.Name])},
+		{Source: "SetGroupAuth(" + svc.Group + ")", Set: groupAuthSet, Value: groupAuth},
+		{Source: "auth.groups." + svc.Group, Set: config.Groups[svc.Group] != "", Value: AuthStrategy(config.Groups[svc.Group])},
+		{Source: "auth.default", Set: config.Default != "", Value: AuthStrategy(config.Default)},
+	}}
+	resolveSteps(&setting, AuthTokenCache)
+	return setting
+}
+
+// resolveRateLimit 列出 rate_limit.global、rate_limit.groups、rate_limit.services 和 Service.RateLimit，
+// 三层规则同时生效，服务层的 mod.yml 配置覆盖 Service.RateLimit；requests 不大于0的规则不生效
+func (app *App) resolveRateLimit(svc *Service) ResolvedSetting {
+	config := app.cfg.ModConfig.RateLimit
+	groupRule, groupSet := config.Groups[svc.Group]
+	serviceRule, serviceSet := config.Services[svc.Name]
+	steps := []ResolveStep{
+		{Source: "rate_limit.global", Set: config.Global.Requests > 0, Value: config.Global},
+		{Source: "rate_limit.groups." + svc.Group, Set: groupSet && groupRule.Requests > 0, Value: groupRule},
+		{Source: "rate_limit.services." + svc.Name, Set: serviceSet && serviceRule.Requests > 0, Value: serviceRule},
+		{Source: "Service.RateLimit", Set: svc.RateLimit != nil && svc.RateLimit.Requests > 0, Value: svc.RateLimit},
+	}
+	if serviceSet && steps[3].Set {
+		steps[3].Note = "overridden by rate_limit.services." + svc.Name
+	}
+
+	setting := ResolvedSetting{Value: []RateLimit{}, Source: "default", Steps: steps}
+	var sources []string
+	for i := range steps {
+		step := &steps[i]
+		if !step.Set || step.Note != "" {
+			continue
+		}
+		step.Applied = true
+		sources = append(sources, step.Source)
+		switch rule := step.Value.(type) {
+		case RateLimit:
+			setting.Value = append(setting.Value.([]RateLimit), rule)
+		case *RateLimit:
+			setting.Value = append(setting.Value.([]RateLimit), *rule)
+		}
+	}
+	for i := range steps {
+		if !steps[i].Set {
+			steps[i].Value = nil
+		}
+	}
+	if len(sources) > 0 {
+		setting.Source = strings.Join(sources, " + ")
+	}
+	if app.rateLimit == nil || app.rateLimit.store == nil {
+		setting.Note = "rate limiting is not configured, rules are not enforced"
+	}
+	return setting
+}
+
+// resolvePermissionSetting 权限规则只能通过 Service.Permission 设置，权限范围通过 Service.RequiredScopes 设置
+func resolvePermissionSetting(svc *Service) ResolvedSetting {
+	setting := ResolvedSetting{Steps: []ResolveStep{
+		{Source: "Service.Permission", Set: svc.Permission != nil, Value: svc.Permission},
+		{Source: "Service.RequiredScopes", Set: len(svc.RequiredScopes) > 0, Value: svc.RequiredScopes},
+	}}
+	setting.Value, setting.Source = nil, "default"
+	var sources []string
+	value := map[string]any{}
+	for i := range setting.Steps {
+		step := &setting.Steps[i]
+		if !step.Set {
+			step.Value = nil
+			continue
+		}
+		step.Applied = true
+		sources = append(sources, step.Source)
+		if step.Source == "Service.Permission" {
+			value["permission"] = step.Value
+		} else {
+			value["required_scopes"] = step.Value
+		}
+	}
+	if len(sources) > 0 {
+		setting.Value, setting.Source = value, strings.Join(sources, " + ")
+	}
+	return setting
+}
+
+// configureResolve 启用 resolve 配置时注册配置解析说明路由
+func (app *App) configureResolve() {
+	config := app.cfg.ModConfig.Resolve
+	if !config.Enabled {
+		return
+	}
+	path := config.Path
+	if path == "" {
+		path = "/admin/resolve"
+	}
+	app.Get(path, app.handleResolve)
+}
+
+// handleResolve 返回 service 参数指定的服务的配置解析结果，未指定时返回全部服务
+func (app *App) handleResolve(c *fiber.Ctx) error {
+	ctx := &Context{Ctx: c, logger: app.logger, app: app}
+	if !app.cfg.ModConfig.Resolve.SkipAuth {
+		if err := app.authorizeAdmin(ctx, "resolve"); err != nil {
+			return replyError(ctx, err)
+		}
+	}
+
+	name := c.Query("service")
+	if name == "" {
+		return c.JSON(NewSuccessResponse(ctx, app.ResolveServices()))
+	}
+	resolution, ok := app.ResolveService(name)
+	if !ok {
+		return c.Status(404).JSON(NewErrorResponse(ctx, 404, "Service not found"))
+	}
+	return c.JSON(NewSuccessResponse(ctx, resolution))
+}
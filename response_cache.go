@@ -0,0 +1,425 @@
+package mod
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/allegro/bigcache/v3"
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+)
+
+// 响应缓存的存储后端
+const (
+	ResponseCacheBigCache = "bigcache" // 进程内存（默认），各实例分别缓存
+	ResponseCacheRedis    = "redis"    // 使用 cache.redis 配置的 Redis，各实例共享
+)
+
+// HeaderResponseCache 响应缓存的命中情况：HIT 或 MISS
+const HeaderResponseCache = "X-Cache"
+
+// ResponseCache 服务响应缓存，适用于结果只取决于请求参数的幂等查询服务：命中时直接返回缓存的响应数据，不调用处理函数。
+// 只缓存处理函数成功返回的 JSON 响应数据，不缓存错误、文件下载等响应以及处理函数设置的响应头；Mock 模式下不读写缓存
+type ResponseCache struct {
+	TTL string `yaml:"ttl" json:"ttl"` // 缓存时间，如 30s，为空时不缓存
+
+	// 缓存键模板，{字段} 引用请求参数（json 字段名或结构体字段名），{@user} 引用当前用户ID，如 "{tenant_id}:{id}"；
+	// 为空时使用全部请求参数的哈希，需要认证的服务还包含当前用户ID。自定义模板的响应因用户而异时须包含 {@user} 或用户相关的参数
+	Key string `yaml:"key" json:"key"`
+
+	Backend string `yaml:"backend" json:"backend"` // bigcache（默认）、redis
+}
+
+// responseCachePolicy 注册时解析的响应缓存配置
+type responseCachePolicy struct {
+	ttl     time.Duration
+	backend string
+	parts   []cacheKeyPart // 缓存键模板，为空时使用请求参数的哈希
+}
+
+// cacheKeyPart 缓存键模板的一段：字面文本、请求字段或当前用户
+type cacheKeyPart struct {
+	literal string
+	field   []int // 请求结构体中的字段索引
+	user    bool
+}
+
+// placeholder 是否为需要取值的占位符
+func (p cacheKeyPart) placeholder() bool {
+	return p.field != nil || p.user
+}
+
+// responseCacheState 响应缓存的进程内存储，首次注册使用 bigcache 的服务时创建，挂载的子应用与父应用共用
+type responseCacheState struct {
+	mu    sync.Mutex
+	cache *bigcache.BigCache
+}
+
+// configureResponseCache 初始化响应缓存状态
+func (app *App) configureResponseCache() {
+	app.responseCache = &responseCacheState{}
+}
+
+// responseCachePolicy 解析服务生效的响应缓存配置，response_cache.services 优先于 Service.Cache；
+// 未配置或配置无效时返回 nil
+func (app *App) responseCachePolicy(svc *Service) *responseCachePolicy {
+	config := svc.Cache
+	if rule, ok := app.cfg.ModConfig.ResponseCache.Services[svc.Name]; ok {
+		config = &rule
+	}
+	if config == nil || config.TTL == "" {
+		return nil
+	}
+
+	fields := logrus.Fields{"service": svc.Name, "ttl": config.TTL, "key": config.Key, "backend": config.Backend}
+	if svc.RawBody || svc.Handler.ndjson {
+		app.logger.WithFields(fields).Warn("Response cache is not supported for raw body and import services")
+		return nil
+	}
+	ttl, err := time.ParseDuration(config.TTL)
+	if err != nil || ttl <= 0 {
+		app.logger.WithFields(fields).Warn("Invalid response cache ttl, response is not cached")
+		return nil
+	}
+	policy := &responseCachePolicy{ttl: ttl, backend: strings.ToLower(config.Backend)}
+	switch policy.backend {
+	case "", ResponseCacheBigCache:
+		policy.backend = ResponseCacheBigCache
+		if err := app.responseCache.init(app.cfg.ModConfig.ResponseCache.MaxSize); err != nil {
+			app.logger.WithFields(fields).WithError(err).Warn("Failed to initialize response cache, response is not cached")
+			return nil
+		}
+	case ResponseCacheRedis:
+		if app.redisClient == nil {
+			app.logger.WithFields(fields).Warn("Response cache backend redis requires cache.redis, response is not cached")
+			return nil
+		}
+	default:
+		app.logger.WithFields(fields).Warn("Unknown response cache backend, response is not cached")
+		return nil
+	}
+	if policy.parts, err = parseCacheKey(config.Key, svc.Handler.InputType); err != nil {
+		fields["error"] = err.Error()
+		app.logger.WithFields(fields).Warn("Invalid response cache key, response is not cached")
+		return nil
+	}
+	return policy
+}
+
+// parseCacheKey 解析缓存键模板，字段按 json 字段名或结构体字段名（不区分大小写）匹配请求结构体的字段
+func parseCacheKey(template string, input reflect.Type) ([]cacheKeyPart, error) {
+	var parts []cacheKeyPart
+	for rest := template; rest != ""; {
+		start := strings.IndexByte(rest, '{')
+		if start < 0 {
+			parts = append(parts, cacheKeyPart{literal: rest})
+			break
+		}
+		end := strings.IndexByte(rest[start:], '}')
+		if end < 0 {
+			return nil, fmt.Errorf("unclosed placeholder in %q", template)
+		}
+		if start > 0 {
+			parts = append(parts, cacheKeyPart{literal: rest[:start]})
+		}
+		name := strings.TrimSpace(rest[start+1 : start+end])
+		rest = rest[start+end+1:]
+		if name == "@user" {
+			parts = append(parts, cacheKeyPart{user: true})
+			continue
+		}
+		index := cacheKeyField(input, name)
+		if index == nil {
+			return nil, fmt.Errorf("request has no field %q", name)
+		}
+		parts = append(parts, cacheKeyPart{field: index})
+	}
+	return parts, nil
+}
+
+// cacheKeyField 查找请求结构体中的字段，返回字段索引
+func cacheKeyField(input reflect.Type, name string) []int {
+	for input != nil && input.Kind() == reflect.Pointer {
+		input = input.Elem()
+	}
+	if input == nil || input.Kind() != reflect.Struct || name == "" {
+		return nil
+	}
+	for _, f := range reflect.VisibleFields(input) {
+		if !f.IsExported() || f.Anonymous {
+			continue
+		}
+		jsonName, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if jsonName == name || strings.EqualFold(f.Name, name) {
+			return f.Index
+		}
+	}
+	return nil
+}
+
+// render 按模板生成缓存键，values 依次填入占位符；values 不足时在第一个未填的占位符处截止，complete 为 false
+func (p *responseCachePolicy) render(values []string) (key string, complete bool) {
+	var b strings.Builder
+	i := 0
+	for _, part := range p.parts {
+		if !part.placeholder() {
+			b.WriteString(part.literal)
+			continue
+		}
+		if i >= len(values) {
+			return b.String(), false
+		}
+		// 转义取值，避免取值中的分隔符与模板中的字面文本混淆
+		b.WriteString(url.QueryEscape(values[i]))
+		i++
+	}
+	return b.String(), true
+}
+
+// responseCacheKey 生成请求的缓存键：服务名、按模板生成的键和响应时区
+func (app *App) responseCacheKey(ctx *Context, svc *Service, in any) (string, error) {
+	policy := svc.responseCache
+	var key string
+	if len(policy.parts) == 0 {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return "", err
+		}
+		sum := sha256.Sum256(data)
+		key = hex.EncodeToString(sum[:])
+		// 需要认证的服务按调用方分别缓存，避免一个用户的响应返回给其他用户
+		if app.authStrategy(svc) != AuthNone {
+			caller := ""
+			if user, ok := ctx.User(); ok {
+				caller = user.ID
+			}
+			key = url.QueryEscape(caller) + ":" + key
+		}
+	} else {
+		var values []string
+		for _, part := range policy.parts {
+			switch {
+			case part.user:
+				user, _ := ctx.User()
+				if user == nil {
+					values = append(values, "")
+				} else {
+					values = append(values, user.ID)
+				}
+			case part.field != nil:
+				values = append(values, cacheKeyValue(reflect.ValueOf(in), part.field))
+			}
+		}
+		key, _ = policy.render(values)
+	}
+	// 响应中的时间按调用方时区转换时，不同时区的响应分别缓存
+	variant := ""
+	if app.cfg.ModConfig.Timezone.ConvertResponse {
+		variant = ctx.Location().String()
+	}
+	return responseCachePrefix(svc.Name) + key + "|" + variant, nil
+}
+
+// responseCachePrefix 服务缓存键的前缀
+func responseCachePrefix(service string) string {
+	return "mod:response_cache:" + service + ":"
+}
+
+// cacheKeyValue 读取请求字段的值，空指针为空字符串
+func cacheKeyValue(v reflect.Value, index []int) string {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	field, err := v.FieldByIndexErr(index)
+	if err != nil {
+		return ""
+	}
+	for field.Kind() == reflect.Pointer {
+		if field.IsNil() {
+			return ""
+		}
+		field = field.Elem()
+	}
+	return fmt.Sprint(field.Interface())
+}
+
+// lookupResponseCache 查找请求的缓存响应，命中时返回缓存的响应数据；key 为空表示无法生成缓存键，本次请求不读写缓存
+func (app *App) lookupResponseCache(ctx *Context, svc *Service, in any) (key string, data []byte, hit bool) {
+	key, err := app.responseCacheKey(ctx, svc, in)
+	if err != nil {
+		app.logger.WithFields(logrus.Fields{"service": svc.Name, "rid": ctx.GetRequestID(), "error": err.Error()}).
+			Warn("Failed to build response cache key")
+		return "", nil, false
+	}
+	data, hit, err = app.getResponseCache(ctx.UserContext(), svc.responseCache.backend, key)
+	switch {
+	case err != nil:
+		app.logger.WithFields(logrus.Fields{"service": svc.Name, "rid": ctx.GetRequestID(), "error": err.Error()}).
+			Warn("Response cache lookup failed")
+		app.recordResponseCache(svc, "error")
+	case hit:
+		app.recordResponseCache(svc, "hit")
+		ctx.Ctx.Set(HeaderResponseCache, "HIT")
+	default:
+		app.recordResponseCache(svc, "miss")
+		ctx.Ctx.Set(HeaderResponseCache, "MISS")
+	}
+	return key, data, hit
+}
+
+// storeResponseCache 缓存处理函数返回的响应数据，写入失败时只记录日志
+func (app *App) storeResponseCache(ctx *Context, svc *Service, key string, out any) {
+	data, err := ctx.App().Config().JSONEncoder(out)
+	if err == nil {
+		err = app.setResponseCache(ctx.UserContext(), svc.responseCache.backend, key, data, svc.responseCache.ttl)
+	}
+	if err != nil {
+		app.logger.WithFields(logrus.Fields{"service": svc.Name, "rid": ctx.GetRequestID(), "error": err.Error()}).
+			Warn("Failed to store response cache")
+	}
+}
+
+// getResponseCache 读取缓存的响应数据，bigcache 中的条目带有过期时间（按应用时钟判断）
+func (app *App) getResponseCache(ctx context.Context, backend, key string) ([]byte, bool, error) {
+	if backend == ResponseCacheRedis {
+		data, err := app.redisClient.Get(ctx, key).Bytes()
+		if errors.Is(err, redis.Nil) {
+			return nil, false, nil
+		}
+		return data, err == nil, err
+	}
+
+	entry, err := app.responseCache.cache.Get(key)
+	if errors.Is(err, bigcache.ErrEntryNotFound) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	if len(entry) < 8 || app.now().UnixNano() >= int64(binary.BigEndian.Uint64(entry)) {
+		_ = app.responseCache.cache.Delete(key)
+		return nil, false, nil
+	}
+	return entry[8:], true, nil
+}
+
+// setResponseCache 写入缓存的响应数据
+func (app *App) setResponseCache(ctx context.Context, backend, key string, data []byte, ttl time.Duration) error {
+	if backend == ResponseCacheRedis {
+		return app.redisClient.Set(ctx, key, data, ttl).Err()
+	}
+	entry := make([]byte, 8+len(data))
+	binary.BigEndian.PutUint64(entry, uint64(app.now().Add(ttl).UnixNano()))
+	copy(entry[8:], data)
+	return app.responseCache.cache.Set(key, entry)
+}
+
+// init 创建进程内缓存，maxSize 为内存上限（如 64MB），默认 64MB；条目的过期时间由读取时判断
+func (s *responseCacheState) init(maxSize string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cache != nil {
+		return nil
+	}
+	size := int64(64 << 20)
+	if maxSize != "" {
+		parsed, err := parseSize(maxSize)
+		if err != nil || parsed <= 0 {
+			return fmt.Errorf("invalid response_cache.max_size %q", maxSize)
+		}
+		size = parsed
+	}
+	config := bigcache.DefaultConfig(24 * time.Hour)
+	config.CleanWindow = 10 * time.Minute
+	config.HardMaxCacheSize = int((size + 1<<20 - 1) >> 20)
+	config.Verbose = false
+	cache, err := bigcache.New(context.Background(), config)
+	if err != nil {
+		return err
+	}
+	s.cache = cache
+	return nil
+}
+
+// InvalidateServiceCache 删除服务的缓存响应，返回删除的条目数。keyParts 依次填入缓存键模板的占位符（{@user} 同样占一位）：
+// 填满时删除该键（所有时区的响应），只填前几个时删除以此开头的键，不传时删除服务的全部缓存
+//
+//	// 缓存键为 "{tenant_id}:{id}"
+//	app.InvalidateServiceCache("get_order", "acme", 42) // 删除一个订单的缓存
+//	app.InvalidateServiceCache("get_order", "acme")     // 删除租户的全部订单缓存
+func (app *App) InvalidateServiceCache(name string, keyParts ...any) (int, error) {
+	svc, ok := app.GetService(name)
+	if !ok {
+		return 0, fmt.Errorf("service %q not found", name)
+	}
+	policy := svc.responseCache
+	if policy == nil {
+		return 0, fmt.Errorf("service %q has no response cache", name)
+	}
+	if len(keyParts) > 0 && len(policy.parts) == 0 {
+		return 0, fmt.Errorf("service %q cache key has no placeholders, call without key parts to invalidate all", name)
+	}
+	values := make([]string, len(keyParts))
+	for i, part := range keyParts {
+		values[i] = fmt.Sprint(part)
+	}
+	prefix := responseCachePrefix(name)
+	if len(keyParts) > 0 {
+		key, complete := policy.render(values)
+		prefix += key
+		if complete {
+			prefix += "|"
+		}
+	}
+
+	removed, err := app.removeResponseCache(policy.backend, prefix)
+	fields := logrus.Fields{"service": name, "prefix": prefix, "removed": removed}
+	if err != nil {
+		fields["error"] = err.Error()
+		app.logger.WithFields(fields).Error("Failed to invalidate response cache")
+		return removed, fmt.Errorf("failed to invalidate response cache: %w", err)
+	}
+	app.logger.WithFields(fields).Debug("Response cache invalidated")
+	return removed, nil
+}
+
+// removeResponseCache 删除以 prefix 开头的缓存条目
+func (app *App) removeResponseCache(backend, prefix string) (int, error) {
+	if backend == ResponseCacheRedis {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		return app.unlinkRedisKeys(ctx, escapeRedisPattern(prefix)+"*")
+	}
+
+	var keys []string
+	it := app.responseCache.cache.Iterator()
+	for it.SetNext() {
+		entry, err := it.Value()
+		if err != nil {
+			continue
+		}
+		if strings.HasPrefix(entry.Key(), prefix) {
+			keys = append(keys, entry.Key())
+		}
+	}
+	removed := 0
+	for _, key := range keys {
+		if app.responseCache.cache.Delete(key) == nil {
+			removed++
+		}
+	}
+	return removed, nil
+}
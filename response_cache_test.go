@@ -0,0 +1,49 @@
+package mod
+
+import "testing"
+
+func TestResponseCacheDefaultKeyPerUser(t *testing.T) {
+	app := newTestApp(t, tokenCacheConfig)
+	for token, id := range map[string]string{"token-a": "user-a", "token-b": "user-b"} {
+		if err := app.SetToken(token, map[string]any{"id": id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := app.Register(Service{
+		Name:        "profile",
+		DisplayName: "profile",
+		Cache:       &ResponseCache{TTL: "1m"},
+		Handler: MakeHandler(func(ctx *Context, req *pingRequest, resp *pingResponse) error {
+			user, err := ctx.RequireUser()
+			if err != nil {
+				return err
+			}
+			resp.Value = user.ID
+			return nil
+		}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		token, user, cache string
+	}{
+		{"token-a", "user-a", "MISS"},
+		{"token-b", "user-b", "MISS"},
+		{"token-a", "user-a", "HIT"},
+	} {
+		resp, err := app.TestClient().WithToken(tc.token).Call("profile", pingRequest{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var data pingResponse
+		resp.AssertStatus(t, 200).AssertSuccess(t, &data)
+		if data.Value != tc.user {
+			t.Fatalf("%s: expected response for %s, got %q", tc.token, tc.user, data.Value)
+		}
+		if got := resp.Header.Get(HeaderResponseCache); got != tc.cache {
+			t.Fatalf("%s: expected cache %s, got %q", tc.token, tc.cache, got)
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package mod
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+	"github.com/tinylib/msgp/msgp"
+)
+
+// responseMediaTypes 响应格式协商的候选类型，Accept 中权重相同或为 */* 时使用第一个（JSON）
+var responseMediaTypes = []string{
+	fiber.MIMEApplicationJSON,
+	fiber.MIMEApplicationXML,
+	fiber.MIMETextXML,
+	"application/msgpack",
+	"application/x-msgpack",
+	"application/vnd.msgpack",
+}
+
+// negotiatesResponse 服务是否按 Accept 协商响应格式：response_format.disabled、Service.JSONOnly 或
+// response_format.json_only 中的服务始终返回JSON
+func (app *App) negotiatesResponse(svc *Service) bool {
+	config := app.cfg.ModConfig.ResponseFormat
+	return !config.Disabled && !svc.JSONOnly && !slices.Contains(config.JSONOnly, svc.Name)
+}
+
+// serviceResponseMediaTypes 服务响应的格式，用于 OpenAPI
+func serviceResponseMediaTypes(svc *Service) []string {
+	if !svc.negotiate {
+		return []string{fiber.MIMEApplicationJSON}
+	}
+	return []string{fiber.MIMEApplicationJSON, fiber.MIMEApplicationXML, "application/msgpack"}
+}
+
+// ResponseFormat 按请求的 Accept 返回响应格式：json、xml 或 msgpack，未设置 Accept 或无法满足时为 json
+func ResponseFormat(c *fiber.Ctx) string {
+	switch c.Accepts(responseMediaTypes...) {
+	case fiber.MIMEApplicationXML, fiber.MIMETextXML:
+		return BodyXML
+	case "application/msgpack", "application/x-msgpack", "application/vnd.msgpack":
+		return BodyMsgpack
+	}
+	return BodyJSON
+}
+
+// encodeNegotiatedResponse 服务返回后将JSON响应（包括 NewSuccessResponse、NewErrorResponse 和 ReturnRaw 的数据）
+// 转换为客户端 Accept 的 XML 或 MessagePack；文件、CSV 等非JSON响应不做处理，转换失败时保留JSON响应
+func (app *App) encodeNegotiatedResponse(ctx *Context, svc *Service) {
+	if !svc.negotiate {
+		return
+	}
+	fc := ctx.Ctx
+	fc.Vary(fiber.HeaderAccept)
+	format := ResponseFormat(fc)
+	body := fc.Response().Body()
+	if format == BodyJSON || len(body) == 0 || !isJSONContentType(string(fc.Response().Header.ContentType())) {
+		return
+	}
+
+	var encoded []byte
+	var contentType string
+	var err error
+	switch format {
+	case BodyXML:
+		encoded, err = jsonToXML(body)
+		contentType = fiber.MIMEApplicationXMLCharsetUTF8
+	case BodyMsgpack:
+		encoded, err = jsonToMsgpack(body)
+		contentType = "application/msgpack"
+	}
+	if err != nil {
+		app.logger.WithFields(logrus.Fields{
+			"service": svc.Name,
+			"format":  format,
+			"rid":     ctx.GetRequestID(),
+			"error":   err.Error(),
+		}).Warn("Failed to encode response, sending JSON")
+		return
+	}
+	fc.Response().SetBodyRaw(encoded)
+	fc.Response().Header.SetContentType(contentType)
+}
+
+// jsonToXML 将JSON转换为XML：根元素为 <response>，对象的字段为同名子元素，数组字段为重复的同名元素
+// （与XML请求体的解析规则一致），数组中的数组和顶层数组使用 <item> 元素；字段顺序与JSON一致
+func jsonToXML(data []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	token, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	if err := writeXMLValue(&buf, dec, "response", token); err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errors.New("unexpected data after JSON value")
+	}
+	return buf.Bytes(), nil
+}
+
+// writeXMLValue 写入 token 开始的JSON值，name 为元素名称
+func writeXMLValue(buf *bytes.Buffer, dec *json.Decoder, name string, token json.Token) error {
+	switch t := token.(type) {
+	case json.Delim:
+		writeXMLStart(buf, name)
+		if t == '[' {
+			for dec.More() {
+				item, err := dec.Token()
+				if err != nil {
+					return err
+				}
+				if err := writeXMLValue(buf, dec, "item", item); err != nil {
+					return err
+				}
+			}
+		} else {
+			for dec.More() {
+				key, err := dec.Token()
+				if err != nil {
+					return err
+				}
+				if err := writeXMLField(buf, dec, key.(string)); err != nil {
+					return err
+				}
+			}
+		}
+		if _, err := dec.Token(); err != nil {
+			return err
+		}
+		buf.WriteString("</" + xmlElementName(name) + ">")
+	case nil:
+		buf.WriteString("<")
+		writeXMLName(buf, name)
+		buf.WriteString("/>")
+	default:
+		writeXMLStart(buf, name)
+		if err := xml.EscapeText(buf, []byte(fmt.Sprint(t))); err != nil {
+			return err
+		}
+		buf.WriteString("</" + xmlElementName(name) + ">")
+	}
+	return nil
+}
+
+// writeXMLField 写入对象的字段，数组展开为重复的同名元素，空数组不输出
+func writeXMLField(buf *bytes.Buffer, dec *json.Decoder, key string) error {
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if token != json.Delim('[') {
+		return writeXMLValue(buf, dec, key, token)
+	}
+	for dec.More() {
+		item, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		if err := writeXMLValue(buf, dec, key, item); err != nil {
+			return err
+		}
+	}
+	_, err = dec.Token()
+	return err
+}
+
+func writeXMLStart(buf *bytes.Buffer, name string) {
+	buf.WriteString("<")
+	writeXMLName(buf, name)
+	buf.WriteString(">")
+}
+
+// writeXMLName 写入元素名称，不是合法XML名称的键（如数字开头的 map 键）写为 <item key="...">
+func writeXMLName(buf *bytes.Buffer, name string) {
+	buf.WriteString(xmlElementName(name))
+	if xmlElementName(name) != name {
+		buf.WriteString(` key="`)
+		_ = xml.EscapeText(buf, []byte(name))
+		buf.WriteString(`"`)
+	}
+}
+
+// xmlElementName 返回键对应的元素名称
+func xmlElementName(name string) string {
+	if name == "" || strings.HasPrefix(strings.ToLower(name), "xml") {
+		return "item"
+	}
+	for i, r := range name {
+		letter := r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || r > 0x7f
+		if !letter && (i == 0 || !(r == '-' || r == '.' || (r >= '0' && r <= '9'))) {
+			return "item"
+		}
+	}
+	return name
+}
+
+// jsonToMsgpack 将JSON转换为 MessagePack：整数编码为 int64/uint64，其他数字为 float64
+func jsonToMsgpack(data []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var value any
+	if err := dec.Decode(&value); err != nil {
+		return nil, err
+	}
+	return msgp.AppendIntf(nil, msgpackValue(value))
+}
+
+// msgpackValue 将 json.Number 转换为 msgp 支持的数字类型
+func msgpackValue(value any) any {
+	switch v := value.(type) {
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		var u uint64
+		if _, err := fmt.Sscan(v.String(), &u); err == nil && !strings.ContainsAny(v.String(), ".eE") {
+			return u
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]any:
+		for key, item := range v {
+			v[key] = msgpackValue(item)
+		}
+	case []any:
+		for i, item := range v {
+			v[i] = msgpackValue(item)
+		}
+	}
+	return value
+}
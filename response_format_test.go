@@ -0,0 +1,105 @@
+package mod
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/tinylib/msgp/msgp"
+)
+
+func TestResponseFormatNegotiation(t *testing.T) {
+	app := newTestApp(t, tokenCacheConfig)
+	if err := app.SetToken("valid-token", map[string]any{"role": "user"}); err != nil {
+		t.Fatal(err)
+	}
+	registerPing(t, app, "negotiated", AuthTokenCache)
+	err := app.Register(Service{
+		Name:        "legacy",
+		DisplayName: "legacy",
+		SkipAuth:    true,
+		JSONOnly:    true,
+		Handler: MakeHandler(func(ctx *Context, req *pingRequest, resp *pingResponse) error {
+			resp.Value = req.Value
+			return nil
+		}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// XML
+	resp, err := app.TestClient().WithToken("valid-token").WithHeader("Accept", "application/xml").
+		Call("negotiated", pingRequest{Value: "a<b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.AssertStatus(t, 200)
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "application/xml") {
+		t.Fatalf("expected XML content type, got %q", ct)
+	}
+	if !strings.Contains(resp.Header.Get("Vary"), "Accept") {
+		t.Fatalf("expected Vary: Accept, got %q", resp.Header.Get("Vary"))
+	}
+	body := string(resp.Body)
+	if !strings.Contains(body, "<response>") || !strings.Contains(body, "<value>a&lt;b</value>") || !strings.Contains(body, "<code>0</code>") {
+		t.Fatalf("unexpected XML body %s", body)
+	}
+
+	// MessagePack
+	resp, err = app.TestClient().WithToken("valid-token").WithHeader("Accept", "application/msgpack").
+		Call("negotiated", pingRequest{Value: "x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.AssertStatus(t, 200)
+	if ct := resp.Header.Get("Content-Type"); ct != "application/msgpack" {
+		t.Fatalf("expected msgpack content type, got %q", ct)
+	}
+	decoded, _, err := msgp.ReadIntfBytes(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	envelope, ok := decoded.(map[string]any)
+	if !ok || envelope["code"] != int64(0) {
+		t.Fatalf("unexpected msgpack body %#v", decoded)
+	}
+	if data, ok := envelope["data"].(map[string]any); !ok || data["value"] != "x" {
+		t.Fatalf("unexpected msgpack data %#v", envelope["data"])
+	}
+
+	// 认证失败的错误响应同样按 Accept 转换
+	resp, err = app.TestClient().WithToken("bogus").WithHeader("Accept", "application/xml").
+		Call("negotiated", pingRequest{Value: "x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.AssertStatus(t, 401)
+	if body := string(resp.Body); !strings.Contains(body, "<code>401</code>") || strings.Contains(body, "valid-token") {
+		t.Fatalf("unexpected XML error body %s", body)
+	}
+
+	// 未设置 Accept 或 JSONOnly 的服务返回JSON
+	for _, tc := range []struct {
+		service string
+		accept  string
+	}{
+		{"negotiated", ""},
+		{"negotiated", "*/*"},
+		{"legacy", "application/xml"},
+	} {
+		client := app.TestClient().WithToken("valid-token")
+		if tc.accept != "" {
+			client = client.WithHeader("Accept", tc.accept)
+		}
+		resp, err := client.Call(tc.service, pingRequest{Value: "x"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var data pingResponse
+		resp.AssertStatus(t, 200).AssertSuccess(t, &data)
+		if !json.Valid(resp.Body) || data.Value != "x" {
+			t.Fatalf("%s with Accept %q: expected JSON, got %s", tc.service, tc.accept, resp.Body)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package mod
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+)
+
+// 响应超过大小上限时的处理方式
+const (
+	ResponseLimitError    = "error"    // 返回500错误（默认）
+	ResponseLimitTruncate = "truncate" // 截断响应数据中的列表，仍超过上限时返回错误
+)
+
+// HeaderResponseTruncated 响应数据中的列表被截断时设置的响应头
+const HeaderResponseTruncated = "X-Response-Truncated"
+
+// ResponseLimit 服务响应大小上限，只作用于服务返回的 JSON 响应，不包括文件下载和导出等流式响应
+type ResponseLimit struct {
+	MaxSize string `yaml:"max_size" json:"max_size"` // 响应体大小上限，如 10MB，为空时不限制
+	Action  string `yaml:"action" json:"action"`     // 超过上限时的处理方式：error（默认）、truncate
+}
+
+// responseLimitPolicy 注册时解析的响应大小上限
+type responseLimitPolicy struct {
+	maxSize int64
+	action  string
+}
+
+// responseLimitPolicy 解析服务生效的响应大小上限，依次使用 response_limit.services、Service.ResponseLimit、response_limit.default，
+// 未配置或配置无效时返回 nil
+func (app *App) responseLimitPolicy(svc *Service) *responseLimitPolicy {
+	config := app.cfg.ModConfig.ResponseLimit
+	limit := config.Default
+	if rule, ok := config.Services[svc.Name]; ok {
+		limit = rule
+	} else if svc.ResponseLimit != nil {
+		limit = *svc.ResponseLimit
+	}
+	if limit.MaxSize == "" {
+		return nil
+	}
+
+	fields := logrus.Fields{"service": svc.Name, "max_size": limit.MaxSize, "action": limit.Action}
+	maxSize, err := parseSize(limit.MaxSize)
+	if err != nil || maxSize <= 0 {
+		app.logger.WithFields(fields).Warn("Invalid response limit max_size, response size is not limited")
+		return nil
+	}
+	policy := &responseLimitPolicy{maxSize: maxSize, action: strings.ToLower(limit.Action)}
+	switch policy.action {
+	case "":
+		policy.action = ResponseLimitError
+	case ResponseLimitError, ResponseLimitTruncate:
+	default:
+		app.logger.WithFields(fields).Warn("Unknown response limit action, using error")
+		policy.action = ResponseLimitError
+	}
+	return policy
+}
+
+// sendServiceResponse 返回服务的处理结果，超过响应大小上限时记录警告日志，并按配置截断列表或返回错误
+func (app *App) sendServiceResponse(ctx *Context, svc *Service, out any) error {
+	wrap := func(data any) any {
+		if svc.ReturnRaw {
+			return data
+		}
+		return NewSuccessResponse(ctx, data)
+	}
+	policy := svc.responseLimit
+	if policy == nil {
+		return ctx.JSON(wrap(out))
+	}
+
+	encode := ctx.App().Config().JSONEncoder
+	body, err := encode(wrap(out))
+	if err != nil {
+		return err
+	}
+	size := len(body)
+	if int64(size) <= policy.maxSize {
+		ctx.Ctx.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		return ctx.Send(body)
+	}
+
+	fields := logrus.Fields{
+		"service":  svc.Name,
+		"rid":      ctx.GetRequestID(),
+		"size":     size,
+		"max_size": policy.maxSize,
+		"action":   policy.action,
+	}
+	if policy.action == ResponseLimitTruncate {
+		if truncated, kept, total, ok := truncateResponse(out, func(data any) ([]byte, bool) {
+			b, err := encode(wrap(data))
+			return b, err == nil && int64(len(b)) <= policy.maxSize
+		}); ok {
+			fields["kept"], fields["total"] = kept, total
+			app.logger.WithFields(fields).Warn("Response exceeds size limit, list truncated")
+			app.recordResponseLimited(svc, ResponseLimitTruncate)
+			ctx.Ctx.Set(HeaderResponseTruncated, "true")
+			ctx.Ctx.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+			return ctx.Send(truncated)
+		}
+	}
+	app.logger.WithFields(fields).Warn("Response exceeds size limit, rejected")
+	app.recordResponseLimited(svc, ResponseLimitError)
+	detail := fmt.Sprintf("response size %d bytes exceeds limit %d bytes", size, policy.maxSize)
+	return ctx.Status(500).JSON(NewErrorResponse(ctx, 500, "Response too large", detail))
+}
+
+// truncateResponse 截断响应数据中的列表：数据本身为切片时截断该切片，为结构体时截断其中最长的切片字段；
+// 二分查找不超过上限时保留的最多元素，fits 返回编码结果及是否不超过上限。无法截断到上限以内时 ok 为 false
+func truncateResponse(out any, fits func(data any) ([]byte, bool)) (body []byte, kept, total int, ok bool) {
+	v := reflect.ValueOf(out)
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil, 0, 0, false
+		}
+		v = v.Elem()
+	}
+
+	// with 返回列表只保留前 n 个元素的响应数据
+	var list reflect.Value
+	var with func(n int) any
+	switch v.Kind() {
+	case reflect.Slice:
+		list = v
+		with = func(n int) any { return list.Slice(0, n).Interface() }
+	case reflect.Struct:
+		field := -1
+		for i := 0; i < v.NumField(); i++ {
+			f := v.Type().Field(i)
+			if !f.IsExported() || f.Tag.Get("json") == "-" || v.Field(i).Kind() != reflect.Slice {
+				continue
+			}
+			if field < 0 || v.Field(i).Len() > v.Field(field).Len() {
+				field = i
+			}
+		}
+		if field < 0 {
+			return nil, 0, 0, false
+		}
+		list = v.Field(field)
+		with = func(n int) any {
+			cp := reflect.New(v.Type())
+			cp.Elem().Set(v)
+			cp.Elem().Field(field).Set(list.Slice(0, n))
+			return cp.Interface()
+		}
+	default:
+		return nil, 0, 0, false
+	}
+
+	total = list.Len()
+	lo, hi := 0, total-1
+	for lo <= hi {
+		mid := (lo + hi) / 2
+		if b, fit := fits(with(mid)); fit {
+			body, kept, ok = b, mid, true
+			lo = mid + 1
+		} else {
+			hi = mid - 1
+		}
+	}
+	return body, kept, total, ok
+}
@@ -0,0 +1,273 @@
+package mod
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ErrRetryBudgetExhausted 重试预算不足时停止重试，ctx.Retry 返回的错误同时包含最后一次执行的错误
+var ErrRetryBudgetExhausted = errors.New("retry budget exhausted")
+
+// RetryPolicy 重试策略，未设置的字段使用 mod.yml 中 retry.policies 的同名策略，仍未设置时使用默认值
+type RetryPolicy struct {
+	Name        string           `yaml:"-"`            // 策略名称，同名策略共用重试预算，用于指标和日志，默认 default
+	MaxAttempts int              `yaml:"max_attempts"` // 最多执行次数（含首次），默认3
+	Backoff     string           `yaml:"backoff"`      // 首次重试前的等待时间，默认100ms
+	MaxBackoff  string           `yaml:"max_backoff"`  // 最大等待时间，默认5s
+	Multiplier  float64          `yaml:"multiplier"`   // 等待时间的增长倍数，默认2
+	Jitter      float64          `yaml:"jitter"`       // 随机抖动比例（0-1），实际等待时间在 wait*(1-jitter) 到 wait 之间，默认0.2，小于0表示不抖动
+	RetryIf     func(error) bool `yaml:"-"`            // 判断错误是否可重试，为空时除4xx的 mod.Reply 错误和取消外均重试
+}
+
+// RetryStats 重试策略的调用统计和剩余预算
+type RetryStats struct {
+	Policy    string  `json:"policy"`
+	Calls     int64   `json:"calls"`     // 调用次数
+	Retries   int64   `json:"retries"`   // 重试次数
+	Succeeded int64   `json:"succeeded"` // 最终成功的调用
+	Failed    int64   `json:"failed"`    // 不可重试、达到最多执行次数或被取消的调用
+	Exhausted int64   `json:"exhausted"` // 因重试预算不足停止重试的调用
+	Budget    float64 `json:"budget"`    // 剩余的重试预算
+}
+
+// retryState 各重试策略的预算和统计，挂载的子应用与父应用共用
+type retryState struct {
+	ratio float64 // 每次调用为预算增加的重试次数，小于0表示不限制
+	burst float64 // 预算上限
+
+	mu      sync.Mutex
+	budgets map[string]*retryBudget
+}
+
+// retryBudget 一个重试策略的令牌桶：每次调用存入 ratio 个令牌，每次重试消耗1个
+type retryBudget struct {
+	tokens                                       float64
+	calls, retries, succeeded, failed, exhausted int64
+}
+
+// configureRetry 初始化重试预算
+func (app *App) configureRetry() {
+	config := app.cfg.ModConfig.Retry.Budget
+	state := &retryState{ratio: config.Ratio, burst: float64(config.Burst), budgets: map[string]*retryBudget{}}
+	if state.ratio == 0 {
+		state.ratio = 0.2
+	}
+	if state.burst <= 0 {
+		state.burst = 10
+	}
+	app.retry = state
+}
+
+// Retry 执行 fn，失败时按策略指数退避后重试，适用于调用不稳定的下游服务等幂等步骤；
+// 等待期间 ctx.UserContext() 被取消时立即返回。重试预算不足时停止重试，返回的错误可通过 errors.Is 判断 ErrRetryBudgetExhausted
+//
+//	err := ctx.Retry(mod.RetryPolicy{Name: "inventory", MaxAttempts: 4}, func() error {
+//		return inventory.Reserve(ctx.UserContext(), req.SKU, req.Count)
+//	})
+func (c *Context) Retry(policy RetryPolicy, fn func() error) error {
+	fields := logrus.Fields{"rid": c.GetRequestID()}
+	if c.service != nil {
+		fields["service"] = c.service.Name
+	}
+	return c.app.retryWithLog(c.UserContext(), policy, fn, c.app.logger.WithFields(fields))
+}
+
+// Retry 同 ctx.Retry，用于后台任务等没有请求上下文的场景
+func (app *App) Retry(ctx context.Context, policy RetryPolicy, fn func() error) error {
+	return app.retryWithLog(ctx, policy, fn, app.logger.WithField("source", "app"))
+}
+
+// retryWithLog 按策略执行和重试 fn，重试和预算不足时记录日志
+func (app *App) retryWithLog(ctx context.Context, policy RetryPolicy, fn func() error, log *logrus.Entry) error {
+	policy = app.resolveRetryPolicy(policy)
+	backoff := parseRetryDuration(policy.Backoff, 100*time.Millisecond)
+	maxBackoff := parseRetryDuration(policy.MaxBackoff, 5*time.Second)
+	budget := app.retry.budget(policy.Name)
+
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil {
+			app.retry.finish(budget, func(b *retryBudget) { b.succeeded++ })
+			return nil
+		}
+		if attempt >= policy.MaxAttempts || ctx.Err() != nil || !policy.RetryIf(err) {
+			app.retry.finish(budget, func(b *retryBudget) { b.failed++ })
+			return err
+		}
+		if !app.retry.withdraw(budget) {
+			log.WithFields(logrus.Fields{"policy": policy.Name, "attempt": attempt, "error": err.Error()}).
+				Warn("Retry budget exhausted, giving up")
+			return errors.Join(err, ErrRetryBudgetExhausted)
+		}
+
+		wait := float64(backoff) * math.Pow(policy.Multiplier, float64(attempt-1))
+		wait = math.Min(wait, float64(maxBackoff))
+		wait -= wait * policy.Jitter * rand.Float64()
+		log.WithFields(logrus.Fields{
+			"policy":  policy.Name,
+			"attempt": attempt,
+			"wait":    time.Duration(wait).String(),
+			"error":   err.Error(),
+		}).Debug("Retrying handler step")
+
+		timer := time.NewTimer(time.Duration(wait))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			app.retry.finish(budget, func(b *retryBudget) { b.failed++ })
+			return err
+		}
+	}
+}
+
+// resolveRetryPolicy 使用 retry.policies 中的同名策略和默认值填充未设置的字段
+func (app *App) resolveRetryPolicy(policy RetryPolicy) RetryPolicy {
+	if policy.Name == "" {
+		policy.Name = "default"
+	}
+	if configured, ok := app.cfg.ModConfig.Retry.Policies[policy.Name]; ok {
+		if policy.MaxAttempts == 0 {
+			policy.MaxAttempts = configured.MaxAttempts
+		}
+		if policy.Backoff == "" {
+			policy.Backoff = configured.Backoff
+		}
+		if policy.MaxBackoff == "" {
+			policy.MaxBackoff = configured.MaxBackoff
+		}
+		if policy.Multiplier == 0 {
+			policy.Multiplier = configured.Multiplier
+		}
+		if policy.Jitter == 0 {
+			policy.Jitter = configured.Jitter
+		}
+	}
+	if policy.MaxAttempts <= 0 {
+		policy.MaxAttempts = 3
+	}
+	if policy.Multiplier < 1 {
+		policy.Multiplier = 2
+	}
+	switch {
+	case policy.Jitter == 0:
+		policy.Jitter = 0.2
+	case policy.Jitter < 0:
+		policy.Jitter = 0
+	case policy.Jitter > 1:
+		policy.Jitter = 1
+	}
+	if policy.RetryIf == nil {
+		policy.RetryIf = retryable
+	}
+	return policy
+}
+
+// retryable 默认的可重试判断：参数错误等4xx的 mod.Reply 错误重试后结果不变，取消和超时不再重试
+func retryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var reply *StdReply
+	if errors.As(err, &reply) {
+		status := reply.httpStatus()
+		return status >= 500 || status == 408 || status == 429
+	}
+	return true
+}
+
+// parseRetryDuration 解析策略中的等待时间，为空或无效时使用默认值
+func parseRetryDuration(value string, def time.Duration) time.Duration {
+	if value == "" {
+		return def
+	}
+	if d, err := time.ParseDuration(value); err == nil && d >= 0 {
+		return d
+	}
+	return def
+}
+
+// budget 返回策略的重试预算并记录一次调用，首次使用时预算为上限
+func (s *retryState) budget(name string) *retryBudget {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, ok := s.budgets[name]
+	if !ok {
+		b = &retryBudget{tokens: s.burst}
+		s.budgets[name] = b
+	}
+	b.calls++
+	if s.ratio > 0 {
+		b.tokens = math.Min(b.tokens+s.ratio, s.burst)
+	}
+	return b
+}
+
+// withdraw 消耗一次重试的预算，预算不足时记录并返回 false
+func (s *retryState) withdraw(b *retryBudget) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ratio >= 0 && b.tokens < 1 {
+		b.exhausted++
+		return false
+	}
+	if s.ratio >= 0 {
+		b.tokens--
+	}
+	b.retries++
+	return true
+}
+
+// finish 记录调用结果
+func (s *retryState) finish(b *retryBudget, record func(b *retryBudget)) {
+	s.mu.Lock()
+	record(b)
+	s.mu.Unlock()
+}
+
+// RetryStats 返回各重试策略的调用统计和剩余预算，按策略名称排序
+func (app *App) RetryStats() []RetryStats {
+	s := app.retry
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats := make([]RetryStats, 0, len(s.budgets))
+	for name, b := range s.budgets {
+		stats = append(stats, RetryStats{
+			Policy:    name,
+			Calls:     b.calls,
+			Retries:   b.retries,
+			Succeeded: b.succeeded,
+			Failed:    b.failed,
+			Exhausted: b.exhausted,
+			Budget:    b.tokens,
+		})
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Policy < stats[j].Policy })
+	return stats
+}
+
+// collectRetryMetrics 输出重试调用、重试次数和剩余预算指标
+func (app *App) collectRetryMetrics(w *MetricsWriter) {
+	stats := app.RetryStats()
+	for _, s := range stats {
+		for _, result := range []struct {
+			name  string
+			value int64
+		}{{"success", s.Succeeded}, {"failed", s.Failed}, {"budget_exhausted", s.Exhausted}} {
+			w.Counter("mod_retry_calls_total", "Calls made through ctx.Retry by final result.", float64(result.value), "policy", s.Policy, "result", result.name)
+		}
+	}
+	for _, s := range stats {
+		w.Counter("mod_retry_attempts_total", "Retries performed by ctx.Retry.", float64(s.Retries), "policy", s.Policy)
+	}
+	for _, s := range stats {
+		w.Gauge("mod_retry_budget_tokens", "Remaining retry budget per policy.", s.Budget, "policy", s.Policy)
+	}
+}
@@ -0,0 +1,524 @@
+package mod
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+)
+
+// RiskVerdict 风控回调的判定结果
+type RiskVerdict string
+
+const (
+	RiskAllow     RiskVerdict = "allow"     // 放行
+	RiskChallenge RiskVerdict = "challenge" // 需要额外验证（短信验证码、人机验证等）
+	RiskDeny      RiskVerdict = "deny"      // 拒绝
+)
+
+// 风控回调的调用阶段
+const (
+	RiskStageIssue    = "issue"    // 签发Token（ctx.GenerateJWT、ctx.IssueToken）
+	RiskStageValidate = "validate" // 服务认证通过后校验Token
+)
+
+// riskSeverity 判定结果的严重程度，多个回调的结果取最严重的一个
+var riskSeverity = map[RiskVerdict]int{RiskAllow: 0, RiskChallenge: 1, RiskDeny: 2}
+
+// GeoInfo 客户端IP的地理位置
+type GeoInfo struct {
+	Country string `json:"country,omitempty"`
+	Region  string `json:"region,omitempty"`
+	City    string `json:"city,omitempty"`
+}
+
+// RiskContext 风控回调的输入：调用阶段、用户、设备、IP和地理位置
+type RiskContext struct {
+	Stage       string  // 调用阶段：issue 或 validate
+	Service     string  // 服务名称，签发阶段为空时表示在服务外调用
+	UserID      string  // 用户ID，校验阶段来自JWT声明或Token缓存数据，可能为空
+	Token       string  // 校验阶段为请求携带的Token，签发阶段为空
+	IP          string  // 客户端IP
+	UserAgent   string  // User-Agent 请求头
+	DeviceID    string  // 设备标识请求头（默认 X-Device-ID）
+	Fingerprint string  // 设备指纹，见 ctx.DeviceFingerprint
+	Geo         GeoInfo // 地理位置，来自 risk 配置的请求头或 SetGeoResolver
+	Ctx         *Context
+
+	app     *App
+	trusted *bool
+}
+
+// RiskDecision 风控回调的判定
+type RiskDecision struct {
+	Verdict   RiskVerdict `json:"verdict"`
+	Reason    string      `json:"reason,omitempty"`    // 判定原因，记录在日志中，拒绝时作为错误详情返回
+	Challenge string      `json:"challenge,omitempty"` // 需要的验证方式（如 sms、captcha），通过 X-Risk-Challenge 响应头返回
+}
+
+// RiskHook 风控回调，返回空的 Verdict 视为放行
+type RiskHook func(rc *RiskContext) RiskDecision
+
+// TrustedDevice 用户的可信设备
+type TrustedDevice struct {
+	Fingerprint string    `json:"fingerprint"`
+	IP          string    `json:"ip,omitempty"`
+	UserAgent   string    `json:"user_agent,omitempty"`
+	Geo         GeoInfo   `json:"geo"`
+	TrustedAt   time.Time `json:"trusted_at"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// riskState 风控回调、地理位置解析函数，以及未配置 Redis、BadgerDB 时保存在进程内存中的可信设备
+type riskState struct {
+	mu      sync.RWMutex
+	hooks   []RiskHook
+	geo     func(ip string) GeoInfo
+	devices map[string]map[string]TrustedDevice // 用户ID -> 设备指纹 -> 可信设备
+}
+
+// riskFingerprintKey 设备指纹在 Fiber locals 中的缓存键
+type riskFingerprintKey struct{}
+
+// configureRisk 初始化风控状态，挂载的子应用与父应用共用
+func (app *App) configureRisk() {
+	app.risk = &riskState{}
+}
+
+// OnRiskCheck 注册风控回调，在签发Token和服务认证通过后调用；注册多个回调时取最严重的判定，
+// 出现 deny 后不再调用后续回调。回调在请求的 goroutine 中同步执行，不应长时间阻塞
+func (app *App) OnRiskCheck(hook RiskHook) {
+	app.risk.mu.Lock()
+	defer app.risk.mu.Unlock()
+	app.risk.hooks = append(app.risk.hooks, hook)
+}
+
+// SetGeoResolver 设置IP地理位置解析函数，在 risk 配置的地理位置请求头缺失时调用
+func (app *App) SetGeoResolver(resolver func(ip string) GeoInfo) {
+	app.risk.mu.Lock()
+	defer app.risk.mu.Unlock()
+	app.risk.geo = resolver
+}
+
+// riskHooks 返回已注册的风控回调
+func (app *App) riskHooks() []RiskHook {
+	app.risk.mu.RLock()
+	defer app.risk.mu.RUnlock()
+	return app.risk.hooks
+}
+
+// DeviceID 返回请求携带的设备标识，请求头名称由 risk.device_header 配置，默认 X-Device-ID
+func (c *Context) DeviceID() string {
+	header := "X-Device-ID"
+	if c.app != nil && c.app.cfg.ModConfig.Risk.DeviceHeader != "" {
+		header = c.app.cfg.ModConfig.Risk.DeviceHeader
+	}
+	return c.Get(header)
+}
+
+// DeviceFingerprint 返回设备指纹：携带设备标识时由设备标识计算，否则由 User-Agent、Accept-Language
+// 和客户端提示请求头计算，结果在本次请求内缓存
+func (c *Context) DeviceFingerprint() string {
+	if cached, ok := c.Locals(riskFingerprintKey{}).(string); ok {
+		return cached
+	}
+	h := sha256.New()
+	if id := c.DeviceID(); id != "" {
+		h.Write([]byte("id\n" + id))
+	} else {
+		for _, header := range []string{"User-Agent", "Accept-Language", "Sec-CH-UA", "Sec-CH-UA-Platform", "Sec-CH-UA-Mobile"} {
+			h.Write([]byte(c.Get(header) + "\n"))
+		}
+	}
+	fingerprint := hex.EncodeToString(h.Sum(nil)[:16])
+	c.Locals(riskFingerprintKey{}, fingerprint)
+	return fingerprint
+}
+
+// Geo 返回客户端IP的地理位置，优先读取 risk 配置的请求头（如CDN添加的 CF-IPCountry），
+// 未配置或请求头缺失时调用 SetGeoResolver 设置的解析函数
+func (c *Context) Geo() GeoInfo {
+	if c.app == nil {
+		return GeoInfo{}
+	}
+	config := c.app.cfg.ModConfig.Risk
+	var geo GeoInfo
+	if config.CountryHeader != "" {
+		geo.Country = c.Get(config.CountryHeader)
+	}
+	if config.RegionHeader != "" {
+		geo.Region = c.Get(config.RegionHeader)
+	}
+	if config.CityHeader != "" {
+		geo.City = c.Get(config.CityHeader)
+	}
+	if geo != (GeoInfo{}) {
+		return geo
+	}
+	c.app.risk.mu.RLock()
+	resolver := c.app.risk.geo
+	c.app.risk.mu.RUnlock()
+	if resolver != nil {
+		geo = resolver(c.IP())
+	}
+	return geo
+}
+
+// newRiskContext 收集当前请求的设备、IP和地理位置
+func (c *Context) newRiskContext(stage, userID, token string) *RiskContext {
+	rc := &RiskContext{
+		Stage:       stage,
+		UserID:      userID,
+		Token:       token,
+		IP:          c.IP(),
+		UserAgent:   c.Get("User-Agent"),
+		DeviceID:    c.DeviceID(),
+		Fingerprint: c.DeviceFingerprint(),
+		Geo:         c.Geo(),
+		Ctx:         c,
+		app:         c.app,
+	}
+	if c.service != nil {
+		rc.Service = c.service.Name
+	}
+	return rc
+}
+
+// TrustedDevice 当前设备是否为用户的可信设备，首次调用时查询缓存，用户ID为空时返回 false
+func (rc *RiskContext) TrustedDevice() bool {
+	if rc.trusted == nil {
+		trusted := false
+		if rc.UserID != "" {
+			trusted = rc.app.IsTrustedDevice(rc.UserID, rc.Fingerprint)
+		}
+		rc.trusted = &trusted
+	}
+	return *rc.trusted
+}
+
+// AssessRisk 依次调用风控回调并返回最严重的判定，未注册回调时直接放行
+func (c *Context) AssessRisk(stage, userID, token string) RiskDecision {
+	decision := RiskDecision{Verdict: RiskAllow}
+	if c.app == nil {
+		return decision
+	}
+	hooks := c.app.riskHooks()
+	if len(hooks) == 0 {
+		return decision
+	}
+	rc := c.newRiskContext(stage, userID, token)
+	for _, hook := range hooks {
+		result := hook(rc)
+		if result.Verdict == "" {
+			result.Verdict = RiskAllow
+		}
+		if riskSeverity[result.Verdict] > riskSeverity[decision.Verdict] {
+			decision = result
+		}
+		if decision.Verdict == RiskDeny {
+			break
+		}
+	}
+	if decision.Verdict != RiskAllow {
+		c.app.logger.WithFields(logrus.Fields{
+			"stage":       stage,
+			"service":     rc.Service,
+			"user_id":     userID,
+			"ip":          rc.IP,
+			"fingerprint": rc.Fingerprint,
+			"country":     rc.Geo.Country,
+			"verdict":     decision.Verdict,
+			"reason":      decision.Reason,
+			"rid":         c.GetRequestID(),
+		}).Warn("Risk check did not allow request")
+	}
+	return decision
+}
+
+// checkRisk 调用风控回调，challenge 和 deny 转换为 StdReply 错误；
+// 需要额外验证时通过 X-Risk-Challenge 响应头告知客户端验证方式
+func (c *Context) checkRisk(stage, userID, token string) error {
+	decision := c.AssessRisk(stage, userID, token)
+	switch decision.Verdict {
+	case RiskChallenge:
+		code := c.app.cfg.ModConfig.Risk.ChallengeCode
+		if code == 0 {
+			code = 428
+		}
+		challenge := decision.Challenge
+		if challenge == "" {
+			challenge = "verify"
+		}
+		c.Ctx.Set("X-Risk-Challenge", challenge)
+		return ReplyWithDetail(code, "Additional verification required", challenge)
+	case RiskDeny:
+		code := c.app.cfg.ModConfig.Risk.DenyCode
+		if code == 0 {
+			code = 403
+		}
+		return ReplyWithDetail(code, "Request denied by risk control", decision.Reason)
+	}
+	return nil
+}
+
+// checkTokenRisk 服务认证通过后调用风控回调，用户ID取自JWT声明或Token缓存数据
+func (app *App) checkTokenRisk(ctx *Context, token string) error {
+	if len(app.riskHooks()) == 0 {
+		return nil
+	}
+	var userID string
+	if user, ok := ctx.User(); ok {
+		userID = user.ID
+	}
+	return ctx.checkRisk(RiskStageValidate, userID, token)
+}
+
+// GenerateJWT 调用风控回调后为用户签发JWT，风控要求额外验证或拒绝时返回 StdReply 错误
+func (c *Context) GenerateJWT(userID, username, email, role string, extra map[string]any) (*TokenResponse, error) {
+	if err := c.checkRisk(RiskStageIssue, userID, ""); err != nil {
+		return nil, err
+	}
+	return c.app.GenerateJWT(userID, username, email, role, extra)
+}
+
+// IssueToken 调用风控回调后将 token 写入Token缓存，风控要求额外验证或拒绝时返回 StdReply 错误
+func (c *Context) IssueToken(userID, token string, data any) error {
+	if err := c.checkRisk(RiskStageIssue, userID, ""); err != nil {
+		return err
+	}
+	return c.app.SetToken(token, data)
+}
+
+// TrustDevice 将当前设备记录为用户的可信设备，如用户完成额外验证后调用
+func (c *Context) TrustDevice(userID string) error {
+	return c.app.TrustDevice(userID, TrustedDevice{
+		Fingerprint: c.DeviceFingerprint(),
+		IP:          c.IP(),
+		UserAgent:   c.Get("User-Agent"),
+		Geo:         c.Geo(),
+	})
+}
+
+// devicesKey 返回用户可信设备在缓存中的键（Redis 哈希或 BadgerDB 键前缀）
+func (app *App) devicesKey(userID string) string {
+	prefix := app.cfg.ModConfig.Risk.CacheKeyPrefix
+	if prefix == "" {
+		prefix = "mod:devices:"
+	}
+	return prefix + userID
+}
+
+// trustedTTL 返回可信设备的有效期，默认30天
+func (app *App) trustedTTL() time.Duration {
+	if value := app.cfg.ModConfig.Risk.TrustedTTL; value != "" {
+		if d, err := time.ParseDuration(value); err == nil && d > 0 {
+			return d
+		}
+		app.logger.WithField("trusted_ttl", value).Warn("Invalid risk trusted_ttl, using 720h")
+	}
+	return 30 * 24 * time.Hour
+}
+
+// TrustDevice 记录用户的可信设备，TrustedAt、ExpiresAt 为空时按 risk.trusted_ttl 设置；
+// 依次保存在 Redis、BadgerDB 中，均未配置时保存在进程内存中
+func (app *App) TrustDevice(userID string, device TrustedDevice) error {
+	if userID == "" || device.Fingerprint == "" {
+		return Reply(400, "User ID and device fingerprint are required")
+	}
+	now := time.Now()
+	if device.TrustedAt.IsZero() {
+		device.TrustedAt = now
+	}
+	if device.ExpiresAt.IsZero() {
+		device.ExpiresAt = now.Add(app.trustedTTL())
+	}
+	ttl := time.Until(device.ExpiresAt)
+	if ttl <= 0 {
+		return nil
+	}
+	data, err := json.Marshal(device)
+	if err != nil {
+		return err
+	}
+
+	key := app.devicesKey(userID)
+	switch {
+	case app.redisClient != nil:
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancel()
+		if err = app.redisClient.HSet(ctx, key, device.Fingerprint, data).Err(); err != nil {
+			break
+		}
+		// 哈希的过期时间延长到最晚过期的设备，单个设备的有效期在读取时检查
+		var current time.Duration
+		if current, err = app.redisClient.PTTL(ctx, key).Result(); err == nil && current < ttl {
+			err = app.redisClient.PExpire(ctx, key, ttl).Err()
+		}
+	case app.badgerDB != nil:
+		err = app.badgerDB.Update(func(txn *badger.Txn) error {
+			return txn.SetEntry(badger.NewEntry([]byte(key+":"+device.Fingerprint), data).WithTTL(ttl))
+		})
+	default:
+		app.risk.mu.Lock()
+		if app.risk.devices == nil {
+			app.risk.devices = map[string]map[string]TrustedDevice{}
+		}
+		if app.risk.devices[userID] == nil {
+			app.risk.devices[userID] = map[string]TrustedDevice{}
+		}
+		for fingerprint, existing := range app.risk.devices[userID] {
+			if !now.Before(existing.ExpiresAt) {
+				delete(app.risk.devices[userID], fingerprint)
+			}
+		}
+		app.risk.devices[userID][device.Fingerprint] = device
+		app.risk.mu.Unlock()
+	}
+	if err != nil {
+		app.logger.WithError(err).WithField("user_id", userID).Error("Failed to save trusted device")
+		return err
+	}
+	app.logger.WithFields(logrus.Fields{"user_id": userID, "fingerprint": device.Fingerprint}).Info("Device trusted")
+	return nil
+}
+
+// UntrustDevice 删除用户的可信设备，fingerprint 为空时删除该用户的全部可信设备
+func (app *App) UntrustDevice(userID, fingerprint string) error {
+	key := app.devicesKey(userID)
+	var err error
+	switch {
+	case app.redisClient != nil:
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancel()
+		if fingerprint == "" {
+			err = app.redisClient.Del(ctx, key).Err()
+		} else {
+			err = app.redisClient.HDel(ctx, key, fingerprint).Err()
+		}
+	case app.badgerDB != nil:
+		if fingerprint == "" {
+			err = app.badgerDB.DropPrefix([]byte(key + ":"))
+		} else {
+			err = app.badgerDB.Update(func(txn *badger.Txn) error {
+				return txn.Delete([]byte(key + ":" + fingerprint))
+			})
+		}
+	default:
+		app.risk.mu.Lock()
+		if fingerprint == "" {
+			delete(app.risk.devices, userID)
+		} else {
+			delete(app.risk.devices[userID], fingerprint)
+		}
+		app.risk.mu.Unlock()
+	}
+	if err != nil {
+		app.logger.WithError(err).WithField("user_id", userID).Error("Failed to remove trusted device")
+	}
+	return err
+}
+
+// TrustedDevices 返回用户未过期的可信设备，按信任时间倒序
+func (app *App) TrustedDevices(userID string) ([]TrustedDevice, error) {
+	key := app.devicesKey(userID)
+	var raw [][]byte
+	switch {
+	case app.redisClient != nil:
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancel()
+		all, err := app.redisClient.HGetAll(ctx, key).Result()
+		if err != nil && err != redis.Nil {
+			return nil, err
+		}
+		for _, value := range all {
+			raw = append(raw, []byte(value))
+		}
+	case app.badgerDB != nil:
+		prefix := []byte(key + ":")
+		err := app.badgerDB.View(func(txn *badger.Txn) error {
+			it := txn.NewIterator(badger.DefaultIteratorOptions)
+			defer it.Close()
+			for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+				value, err := it.Item().ValueCopy(nil)
+				if err != nil {
+					return err
+				}
+				raw = append(raw, value)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	default:
+		app.risk.mu.RLock()
+		for _, device := range app.risk.devices[userID] {
+			data, _ := json.Marshal(device)
+			raw = append(raw, data)
+		}
+		app.risk.mu.RUnlock()
+	}
+
+	now := time.Now()
+	devices := make([]TrustedDevice, 0, len(raw))
+	for _, data := range raw {
+		var device TrustedDevice
+		if err := json.Unmarshal(data, &device); err != nil || !now.Before(device.ExpiresAt) {
+			continue
+		}
+		devices = append(devices, device)
+	}
+	sort.Slice(devices, func(i, j int) bool { return devices[i].TrustedAt.After(devices[j].TrustedAt) })
+	return devices, nil
+}
+
+// IsTrustedDevice 设备是否为用户未过期的可信设备，查询失败时返回 false
+func (app *App) IsTrustedDevice(userID, fingerprint string) bool {
+	key := app.devicesKey(userID)
+	var data []byte
+	switch {
+	case app.redisClient != nil:
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancel()
+		value, err := app.redisClient.HGet(ctx, key, fingerprint).Bytes()
+		if err != nil {
+			if err != redis.Nil {
+				app.logger.WithError(err).WithField("user_id", userID).Warn("Failed to query trusted device")
+			}
+			return false
+		}
+		data = value
+	case app.badgerDB != nil:
+		err := app.badgerDB.View(func(txn *badger.Txn) error {
+			item, err := txn.Get([]byte(key + ":" + fingerprint))
+			if err != nil {
+				return err
+			}
+			data, err = item.ValueCopy(nil)
+			return err
+		})
+		if err != nil {
+			if err != badger.ErrKeyNotFound {
+				app.logger.WithError(err).WithField("user_id", userID).Warn("Failed to query trusted device")
+			}
+			return false
+		}
+	default:
+		app.risk.mu.RLock()
+		device, ok := app.risk.devices[userID][fingerprint]
+		app.risk.mu.RUnlock()
+		return ok && time.Now().Before(device.ExpiresAt)
+	}
+	var device TrustedDevice
+	if err := json.Unmarshal(data, &device); err != nil {
+		return false
+	}
+	return time.Now().Before(device.ExpiresAt)
+}
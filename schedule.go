@@ -0,0 +1,380 @@
+package mod
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+	"github.com/valyala/fasthttp"
+)
+
+// 定时任务的执行结果
+const (
+	ScheduleSucceeded = "succeeded" // 执行成功
+	ScheduleFailed    = "failed"    // 返回错误、panic 或超时
+	ScheduleSkipped   = "skipped"   // 上一次执行尚未结束，本次跳过
+)
+
+// ErrScheduleRunning 定时任务的上一次执行尚未结束
+var ErrScheduleRunning = errors.New("scheduled task is already running")
+
+// ScheduleTask mod.yml 中定时任务的配置，优先于 app.Schedule 的参数
+type ScheduleTask struct {
+	Spec     string `yaml:"spec"`     // cron 表达式，覆盖代码中的表达式
+	Disabled bool   `yaml:"disabled"` // 停用任务，仍出现在运行记录中
+	Timeout  string `yaml:"timeout"`  // 单次执行超时，超时后 ctx.UserContext() 被取消，0 或为空表示不限制
+}
+
+// ScheduleRun 定时任务的一次执行记录
+type ScheduleRun struct {
+	StartedAt time.Time `json:"started_at"`
+	Duration  string    `json:"duration"`
+	Status    string    `json:"status"`          // succeeded、failed、skipped
+	Error     string    `json:"error,omitempty"` // 失败原因
+	Manual    bool      `json:"manual,omitempty"`
+}
+
+// ScheduleInfo 定时任务的状态和最近的执行记录
+type ScheduleInfo struct {
+	Name     string        `json:"name"`
+	Spec     string        `json:"spec"`
+	Disabled bool          `json:"disabled"`
+	Running  bool          `json:"running"`
+	NextRun  *time.Time    `json:"next_run,omitempty"`
+	Runs     int64         `json:"runs"`     // 累计执行次数（不含跳过）
+	Failures int64         `json:"failures"` // 累计失败次数
+	Skipped  int64         `json:"skipped"`  // 因上一次执行未结束而跳过的次数
+	History  []ScheduleRun `json:"history"`  // 最近的执行记录，最近结束的在前
+}
+
+// scheduleState 定时任务，挂载的子应用与父应用共用；运行记录路由注册在父应用
+type scheduleState struct {
+	root  *App
+	mu    sync.Mutex
+	tasks map[string]*scheduledTask
+
+	routeOnce sync.Once
+	ctx       context.Context // 关闭服务等待超时后取消，通知执行中的任务退出
+	cancel    context.CancelFunc
+	stop      chan struct{}
+	wg        sync.WaitGroup
+}
+
+// scheduledTask 已注册的定时任务
+type scheduledTask struct {
+	app      *App
+	name     string
+	spec     string
+	schedule *cronSchedule
+	location *time.Location
+	timeout  time.Duration
+	disabled bool
+	fn       func(ctx *Context) error
+	running  atomic.Bool
+
+	mu       sync.Mutex
+	next     time.Time
+	runs     int64
+	failures int64
+	skipped  int64
+	history  []ScheduleRun
+}
+
+// configureSchedule 初始化定时任务状态，关闭服务时停止调度并等待执行中的任务结束
+func (app *App) configureSchedule() {
+	state := &scheduleState{root: app, tasks: map[string]*scheduledTask{}, stop: make(chan struct{})}
+	state.ctx, state.cancel = context.WithCancel(context.Background())
+	app.schedule = state
+	app.Hooks().OnShutdown(func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), app.shutdownTimeout())
+		defer cancel()
+		return state.shutdown(ctx)
+	})
+}
+
+// Schedule 注册按 cron 表达式定时执行的任务，任务名在应用内唯一。
+// spec 为 分 时 日 月 星期 五个字段（如 "0 3 * * *" 每天3点），或 @hourly、@daily、@every 10m 等形式；
+// mod.yml 中 schedule.tasks 的同名配置可以覆盖表达式、停用任务或设置超时，代码中的 spec 可以为空，由配置提供。
+// 上一次执行尚未结束时跳过本次执行；fn 返回错误或 panic 时记录日志，不影响后续执行
+//
+//	app.Schedule("*/10 * * * *", "cleanup_sessions", func(ctx *mod.Context) error {
+//		return repo.DeleteExpiredSessions(ctx.UserContext())
+//	})
+func (app *App) Schedule(spec string, name string, fn func(ctx *Context) error) error {
+	if name == "" {
+		return errors.New("schedule: name is required")
+	}
+	if fn == nil {
+		return fmt.Errorf("schedule %q: function is nil", name)
+	}
+	config := app.cfg.ModConfig.Schedule
+	task := &scheduledTask{app: app, name: name, spec: spec, fn: fn, location: time.Local}
+	if override, ok := config.Tasks[name]; ok {
+		if override.Spec != "" {
+			task.spec = override.Spec
+		}
+		task.disabled = override.Disabled
+		if override.Timeout != "" {
+			d, err := time.ParseDuration(override.Timeout)
+			if err != nil || d < 0 {
+				return fmt.Errorf("schedule %q: invalid timeout %q", name, override.Timeout)
+			}
+			task.timeout = d
+		}
+	}
+	if config.Timezone != "" {
+		loc, err := time.LoadLocation(config.Timezone)
+		if err != nil {
+			return fmt.Errorf("schedule %q: invalid timezone %q: %w", name, config.Timezone, err)
+		}
+		task.location = loc
+	}
+	if task.spec == "" {
+		return fmt.Errorf("schedule %q: spec is required", name)
+	}
+	schedule, err := parseCron(task.spec)
+	if err != nil {
+		return fmt.Errorf("schedule %q: invalid spec %q: %w", name, task.spec, err)
+	}
+	task.schedule = schedule
+
+	state := app.schedule
+	state.mu.Lock()
+	if _, exists := state.tasks[name]; exists {
+		state.mu.Unlock()
+		return fmt.Errorf("schedule %q: already registered", name)
+	}
+	state.tasks[name] = task
+	state.mu.Unlock()
+
+	state.registerRoute()
+	fields := logrus.Fields{"task": name, "spec": task.spec}
+	if task.disabled {
+		app.logger.WithFields(fields).Info("Scheduled task is disabled")
+		return nil
+	}
+	state.wg.Add(1)
+	go state.loop(task)
+	app.logger.WithFields(fields).Info("Scheduled task registered")
+	return nil
+}
+
+// RunSchedule 立即执行一次定时任务并等待结束，用于测试和手动补跑；上一次执行尚未结束时返回 ErrScheduleRunning
+func (app *App) RunSchedule(name string) error {
+	app.schedule.mu.Lock()
+	task := app.schedule.tasks[name]
+	app.schedule.mu.Unlock()
+	if task == nil {
+		return fmt.Errorf("schedule %q: not registered", name)
+	}
+	if !task.running.CompareAndSwap(false, true) {
+		return ErrScheduleRunning
+	}
+	return app.schedule.run(task, true)
+}
+
+// Schedules 返回全部定时任务的状态和最近的执行记录，按任务名排序
+func (app *App) Schedules() []ScheduleInfo {
+	state := app.schedule
+	state.mu.Lock()
+	tasks := make([]*scheduledTask, 0, len(state.tasks))
+	for _, task := range state.tasks {
+		tasks = append(tasks, task)
+	}
+	state.mu.Unlock()
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].name < tasks[j].name })
+
+	infos := make([]ScheduleInfo, 0, len(tasks))
+	for _, task := range tasks {
+		task.mu.Lock()
+		info := ScheduleInfo{
+			Name:     task.name,
+			Spec:     task.spec,
+			Disabled: task.disabled,
+			Running:  task.running.Load(),
+			Runs:     task.runs,
+			Failures: task.failures,
+			Skipped:  task.skipped,
+			History:  append([]ScheduleRun{}, task.history...),
+		}
+		if !task.next.IsZero() {
+			next := task.next
+			info.NextRun = &next
+		}
+		task.mu.Unlock()
+		infos = append(infos, info)
+	}
+	return infos
+}
+
+// registerRoute 首次注册任务时在父应用注册运行记录路由
+func (s *scheduleState) registerRoute() {
+	s.routeOnce.Do(func() {
+		path := s.root.cfg.ModConfig.Schedule.Path
+		if path == "" {
+			path = "/admin/schedules"
+		}
+		s.root.Get(path, s.root.handleSchedules)
+	})
+}
+
+// loop 按表达式等待到下一次执行时间后启动任务，执行在单独的 goroutine 中，不影响后续的计时
+func (s *scheduleState) loop(task *scheduledTask) {
+	defer s.wg.Done()
+	for {
+		next := task.schedule.next(time.Now().In(task.location))
+		task.mu.Lock()
+		task.next = next
+		task.mu.Unlock()
+		if next.IsZero() {
+			task.app.logger.WithField("task", task.name).Warn("Scheduled task has no upcoming run, stopped")
+			return
+		}
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-s.stop:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		if !task.running.CompareAndSwap(false, true) {
+			task.record(ScheduleRun{StartedAt: task.app.now(), Duration: "0s", Status: ScheduleSkipped}, s.history())
+			task.app.recordSchedule(task.name, ScheduleSkipped)
+			task.app.logger.WithField("task", task.name).Warn("Scheduled task is still running, skipped")
+			continue
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			_ = s.run(task, false)
+		}()
+	}
+}
+
+// run 执行任务并记录结果，调用前须已将 running 置为 true
+func (s *scheduleState) run(task *scheduledTask, manual bool) (err error) {
+	app := task.app
+	defer task.running.Store(false)
+
+	var rc fasthttp.RequestCtx
+	fc := app.AcquireCtx(&rc)
+	defer app.ReleaseCtx(fc)
+	runCtx, cancel := context.WithCancel(s.ctx)
+	if task.timeout > 0 {
+		runCtx, cancel = context.WithTimeout(s.ctx, task.timeout)
+	}
+	defer cancel()
+	fc.SetUserContext(runCtx)
+	ctx := &Context{Ctx: fc, logger: app.logger, app: app}
+
+	startedAt := app.now()
+	started := time.Now()
+	log := app.logger.WithFields(logrus.Fields{"task": task.name, "rid": ctx.GetRequestID()})
+	func() {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("panic: %v", r)
+				log.WithFields(logrus.Fields{"panic": fmt.Sprint(r), "stack": string(debug.Stack())}).Error("Scheduled task panicked")
+			}
+		}()
+		err = task.fn(ctx)
+	}()
+	if err == nil && errors.Is(runCtx.Err(), context.DeadlineExceeded) {
+		err = fmt.Errorf("timeout after %s", task.timeout)
+	}
+
+	run := ScheduleRun{StartedAt: startedAt, Duration: time.Since(started).String(), Status: ScheduleSucceeded, Manual: manual}
+	if err != nil {
+		run.Status, run.Error = ScheduleFailed, err.Error()
+	}
+	task.record(run, s.history())
+	app.recordSchedule(task.name, run.Status)
+
+	fields := logrus.Fields{"status": run.Status, "duration": run.Duration}
+	if err != nil {
+		log.WithFields(fields).WithError(err).Error("Scheduled task failed")
+	} else {
+		log.WithFields(fields).Info("Scheduled task finished")
+	}
+	return err
+}
+
+// history 每个任务保留的运行记录数
+func (s *scheduleState) history() int {
+	if n := s.root.cfg.ModConfig.Schedule.History; n > 0 {
+		return n
+	}
+	return 20
+}
+
+// record 保存执行记录并更新计数
+func (t *scheduledTask) record(run ScheduleRun, limit int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	switch run.Status {
+	case ScheduleSkipped:
+		t.skipped++
+	case ScheduleFailed:
+		t.runs++
+		t.failures++
+	default:
+		t.runs++
+	}
+	t.history = append([]ScheduleRun{run}, t.history...)
+	if len(t.history) > limit {
+		t.history = t.history[:limit]
+	}
+}
+
+// shutdown 停止调度，等待执行中的任务结束；超时后取消任务的上下文
+func (s *scheduleState) shutdown(ctx context.Context) error {
+	select {
+	case <-s.stop:
+	default:
+		close(s.stop)
+	}
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		s.cancel()
+		return nil
+	case <-ctx.Done():
+		s.cancel()
+		return fmt.Errorf("scheduled tasks still running after shutdown timeout: %w", ctx.Err())
+	}
+}
+
+// handleSchedules 输出定时任务的状态和运行记录，支持 name 参数筛选；未开启 schedule.skip_auth 时按 auth.admin 配置校验请求
+func (app *App) handleSchedules(c *fiber.Ctx) error {
+	ctx := &Context{Ctx: c, logger: app.logger, app: app}
+	if !app.cfg.ModConfig.Schedule.SkipAuth {
+		if err := app.authorizeAdmin(ctx, "schedules"); err != nil {
+			return replyError(ctx, err)
+		}
+	}
+
+	infos := app.Schedules()
+	if name := c.Query("name"); name != "" {
+		filtered := []ScheduleInfo{}
+		for _, info := range infos {
+			if info.Name == name {
+				filtered = append(filtered, info)
+			}
+		}
+		infos = filtered
+	}
+	return c.JSON(NewSuccessResponse(ctx, infos))
+}
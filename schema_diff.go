@@ -0,0 +1,401 @@
+package mod
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// 接口变更的类型
+const (
+	SchemaServiceRemoved   = "service_removed"    // 服务被删除
+	SchemaServiceAdded     = "service_added"      // 新增服务
+	SchemaPathChanged      = "path_changed"       // 访问路径或请求方法变化
+	SchemaFieldRemoved     = "field_removed"      // 删除字段或参数
+	SchemaFieldAdded       = "field_added"        // 新增可选字段或参数
+	SchemaTypeChanged      = "type_changed"       // 字段类型、格式变化
+	SchemaRequiredAdded    = "required_added"     // 新增必填字段或字段变为必填
+	SchemaRequiredRemoved  = "required_removed"   // 响应字段不再必定返回
+	SchemaNullableChanged  = "nullable_changed"   // 请求字段不再接受 null 或响应字段可能为 null
+	SchemaEnumChanged      = "enum_changed"       // 枚举值变化
+	SchemaMediaTypeRemoved = "media_type_removed" // 不再接受或返回的请求体、响应格式
+	SchemaAuthChanged      = "auth_changed"       // 认证方式或权限范围变化
+)
+
+// SchemaChange 两个版本的 OpenAPI 文档之间的单个接口变更
+type SchemaChange struct {
+	Service  string `json:"service"`            // 服务名称（operationId）
+	Location string `json:"location,omitempty"` // 变更位置，如 body.items[].id、query.page、response.data.name
+	Kind     string `json:"kind"`               // 变更类型，如 field_removed
+	Message  string `json:"message"`
+	Breaking bool   `json:"breaking"` // 是否为不兼容变更，已部署的客户端可能因此调用失败
+}
+
+func (c SchemaChange) String() string {
+	level := "compatible"
+	if c.Breaking {
+		level = "BREAKING"
+	}
+	if c.Location == "" {
+		return fmt.Sprintf("%s %s: %s", level, c.Service, c.Message)
+	}
+	return fmt.Sprintf("%s %s %s: %s", level, c.Service, c.Location, c.Message)
+}
+
+// SchemaDiff 接口变更列表，按服务名称和位置排序
+type SchemaDiff []SchemaChange
+
+// Breaking 返回其中的不兼容变更
+func (d SchemaDiff) Breaking() SchemaDiff {
+	var breaking SchemaDiff
+	for _, change := range d {
+		if change.Breaking {
+			breaking = append(breaking, change)
+		}
+	}
+	return breaking
+}
+
+func (d SchemaDiff) String() string {
+	lines := make([]string, len(d))
+	for i, change := range d {
+		lines[i] = change.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// DiffOpenAPI 按服务名称比较两个版本的 OpenAPI 文档（如上一版本发布时 WriteOpenAPI 导出的快照和当前生成的文档）。
+// 删除服务或字段、修改类型、新增必填字段等已部署客户端无法兼容的变更标记为 Breaking；
+// 请求方向新增可选字段、放宽校验，响应方向新增字段视为兼容
+func DiffOpenAPI(old, new *OpenAPISpec) SchemaDiff {
+	oldOps, newOps := specOperations(old), specOperations(new)
+	d := &schemaDiffer{}
+	for _, name := range sortedKeys(oldOps) {
+		o := oldOps[name]
+		n, ok := newOps[name]
+		if !ok {
+			d.add(name, "", SchemaServiceRemoved, true, "service removed (%s %s)", o.method, o.path)
+			continue
+		}
+		d.diffOperation(name, o, n)
+	}
+	for _, name := range sortedKeys(newOps) {
+		if _, ok := oldOps[name]; !ok {
+			d.add(name, "", SchemaServiceAdded, false, "service added (%s %s)", newOps[name].method, newOps[name].path)
+		}
+	}
+	sort.SliceStable(d.changes, func(i, j int) bool {
+		return d.changes[i].Service < d.changes[j].Service
+	})
+	return d.changes
+}
+
+// specOperation 文档中的单个服务
+type specOperation struct {
+	path   string
+	method string
+	op     *OpenAPIOperation
+}
+
+// specOperations 按 operationId 索引文档中的服务
+func specOperations(spec *OpenAPISpec) map[string]specOperation {
+	ops := map[string]specOperation{}
+	if spec == nil {
+		return ops
+	}
+	for path, item := range spec.Paths {
+		if item.Post != nil {
+			ops[item.Post.OperationID] = specOperation{path: path, method: "POST", op: item.Post}
+		} else if item.Get != nil {
+			ops[item.Get.OperationID] = specOperation{path: path, method: "GET", op: item.Get}
+		}
+	}
+	return ops
+}
+
+type schemaDiffer struct {
+	changes SchemaDiff
+}
+
+func (d *schemaDiffer) add(service, location, kind string, breaking bool, format string, args ...any) {
+	d.changes = append(d.changes, SchemaChange{
+		Service:  service,
+		Location: location,
+		Kind:     kind,
+		Message:  fmt.Sprintf(format, args...),
+		Breaking: breaking,
+	})
+}
+
+// diffOperation 比较同名服务的路径、认证方式、参数、请求体和响应
+func (d *schemaDiffer) diffOperation(name string, o, n specOperation) {
+	if o.path != n.path || o.method != n.method {
+		d.add(name, "", SchemaPathChanged, true, "route changed from %s %s to %s %s", o.method, o.path, n.method, n.path)
+	}
+	d.diffSecurity(name, o.op, n.op)
+	d.diffParameters(name, o.op.Parameters, n.op.Parameters)
+
+	oldBody, newBody := requestBodySchemas(o.op), requestBodySchemas(n.op)
+	for _, mediaType := range sortedKeys(oldBody) {
+		if _, ok := newBody[mediaType]; !ok {
+			d.add(name, "body", SchemaMediaTypeRemoved, true, "request body %s is no longer accepted", mediaType)
+		}
+	}
+	if o.op.RequestBody == nil && n.op.RequestBody != nil && n.op.RequestBody.Required {
+		d.add(name, "body", SchemaRequiredAdded, true, "request body is now required")
+	}
+	if mediaType := commonMediaType(oldBody, newBody); mediaType != "" {
+		d.diffSchema(name, "body", oldBody[mediaType], newBody[mediaType], true)
+	}
+
+	if o.op.Responses != nil && n.op.Responses != nil && o.op.Responses["200"] != nil && n.op.Responses["200"] != nil {
+		oldResp, newResp := o.op.Responses["200"].Content, n.op.Responses["200"].Content
+		for _, mediaType := range sortedKeys(oldResp) {
+			if _, ok := newResp[mediaType]; !ok {
+				d.add(name, "response", SchemaMediaTypeRemoved, true, "response %s is no longer returned", mediaType)
+			}
+		}
+		if mediaType := commonMediaType(oldResp, newResp); mediaType != "" && oldResp[mediaType] != nil && newResp[mediaType] != nil {
+			d.diffSchema(name, "response", oldResp[mediaType].Schema, newResp[mediaType].Schema, false)
+		}
+	}
+
+	// WebSocket 服务：客户端消息按请求方向比较，服务端消息按响应方向比较
+	if o.op.WebSocket != nil && n.op.WebSocket != nil {
+		d.diffSchema(name, "ws.client", o.op.WebSocket.Client, n.op.WebSocket.Client, true)
+		d.diffSchema(name, "ws.server", o.op.WebSocket.Server, n.op.WebSocket.Server, false)
+	}
+}
+
+// diffSecurity 原本无需认证的服务需要认证、认证方式变化或新增权限范围均为不兼容变更
+func (d *schemaDiffer) diffSecurity(name string, o, n *OpenAPIOperation) {
+	oldSchemes, newSchemes := securitySchemes(o.Security), securitySchemes(n.Security)
+	switch {
+	case len(oldSchemes) == 0 && len(newSchemes) > 0:
+		d.add(name, "", SchemaAuthChanged, true, "authentication is now required (%s)", strings.Join(newSchemes, ", "))
+	case len(oldSchemes) > 0 && len(newSchemes) == 0:
+		d.add(name, "", SchemaAuthChanged, false, "authentication is no longer required")
+	case !slices.Equal(oldSchemes, newSchemes):
+		d.add(name, "", SchemaAuthChanged, true, "authentication changed from %s to %s", strings.Join(oldSchemes, ", "), strings.Join(newSchemes, ", "))
+	}
+	for _, scope := range n.Scopes {
+		if !slices.Contains(o.Scopes, scope) {
+			d.add(name, "", SchemaAuthChanged, true, "scope %q is now required", scope)
+		}
+	}
+}
+
+// securitySchemes 返回认证方式名称，已排序
+func securitySchemes(security []map[string][]string) []string {
+	var schemes []string
+	for _, requirement := range security {
+		for scheme := range requirement {
+			if !slices.Contains(schemes, scheme) {
+				schemes = append(schemes, scheme)
+			}
+		}
+	}
+	sort.Strings(schemes)
+	return schemes
+}
+
+// diffParameters 按位置和名称比较查询参数、请求头和路径参数，请求头名称不区分大小写
+func (d *schemaDiffer) diffParameters(name string, old, new []*OpenAPIParameter) {
+	key := func(p *OpenAPIParameter) string {
+		if p.In == "header" {
+			return p.In + "." + strings.ToLower(p.Name)
+		}
+		return p.In + "." + p.Name
+	}
+	newParams := map[string]*OpenAPIParameter{}
+	for _, p := range new {
+		newParams[key(p)] = p
+	}
+	oldParams := map[string]*OpenAPIParameter{}
+	for _, p := range old {
+		oldParams[key(p)] = p
+		location := p.In + "." + p.Name
+		n, ok := newParams[key(p)]
+		if !ok {
+			d.add(name, location, SchemaFieldRemoved, true, "parameter removed")
+			continue
+		}
+		if !p.Required && n.Required {
+			d.add(name, location, SchemaRequiredAdded, true, "parameter is now required")
+		}
+		d.diffSchema(name, location, p.Schema, n.Schema, true)
+	}
+	for _, p := range new {
+		if _, ok := oldParams[key(p)]; ok {
+			continue
+		}
+		if p.Required {
+			d.add(name, p.In+"."+p.Name, SchemaRequiredAdded, true, "required parameter added")
+		} else {
+			d.add(name, p.In+"."+p.Name, SchemaFieldAdded, false, "optional parameter added")
+		}
+	}
+}
+
+// diffSchema 递归比较字段定义，request 为 true 时按请求方向判断兼容性：
+// 请求只能放宽（删除必填、接受更多枚举值），响应只能扩展（新增字段）
+func (d *schemaDiffer) diffSchema(name, location string, old, new *OpenAPISchema, request bool) {
+	if old == nil || new == nil {
+		return
+	}
+	if old.Type != "" && new.Type != "" && old.Type != new.Type {
+		// 整数与数字之间：请求放宽为 number、响应收窄为 integer 仍然兼容
+		widened := request && old.Type == "integer" && new.Type == "number"
+		narrowed := !request && old.Type == "number" && new.Type == "integer"
+		d.add(name, location, SchemaTypeChanged, !widened && !narrowed, "type changed from %s to %s", old.Type, new.Type)
+		return
+	}
+	if old.Format != new.Format && old.Format != "" && new.Format != "" {
+		d.add(name, location, SchemaTypeChanged, true, "format changed from %s to %s", old.Format, new.Format)
+	}
+	if request && old.Nullable && !new.Nullable {
+		d.add(name, location, SchemaNullableChanged, true, "null is no longer accepted")
+	}
+	if !request && !old.Nullable && new.Nullable {
+		d.add(name, location, SchemaNullableChanged, true, "value may now be null")
+	}
+	d.diffEnum(name, location, old.Enum, new.Enum, request)
+
+	for _, field := range sortedKeys(old.Properties) {
+		path := joinSchemaPath(location, field)
+		n, ok := new.Properties[field]
+		if !ok {
+			d.add(name, path, SchemaFieldRemoved, true, "field removed")
+			continue
+		}
+		oldRequired, newRequired := slices.Contains(old.Required, field), slices.Contains(new.Required, field)
+		if request && !oldRequired && newRequired {
+			d.add(name, path, SchemaRequiredAdded, true, "field is now required")
+		}
+		if !request && oldRequired && !newRequired {
+			d.add(name, path, SchemaRequiredRemoved, true, "field may now be omitted")
+		}
+		d.diffSchema(name, path, old.Properties[field], n, request)
+	}
+	for _, field := range sortedKeys(new.Properties) {
+		if _, ok := old.Properties[field]; ok {
+			continue
+		}
+		path := joinSchemaPath(location, field)
+		if request && slices.Contains(new.Required, field) {
+			d.add(name, path, SchemaRequiredAdded, true, "required field added")
+		} else {
+			d.add(name, path, SchemaFieldAdded, false, "field added")
+		}
+	}
+
+	d.diffSchema(name, location+"[]", old.Items, new.Items, request)
+	d.diffSchema(name, location+"{}", old.AdditionalProperties, new.AdditionalProperties, request)
+}
+
+// diffEnum 请求删除枚举值或新增枚举限制、响应新增枚举值均为不兼容变更
+func (d *schemaDiffer) diffEnum(name, location string, old, new []any, request bool) {
+	if len(new) == 0 {
+		return
+	}
+	if len(old) == 0 {
+		d.add(name, location, SchemaEnumChanged, request, "values are now limited to %v", new)
+		return
+	}
+	var removed, added []any
+	for _, value := range old {
+		if !enumContains(new, value) {
+			removed = append(removed, value)
+		}
+	}
+	for _, value := range new {
+		if !enumContains(old, value) {
+			added = append(added, value)
+		}
+	}
+	if len(removed) > 0 {
+		d.add(name, location, SchemaEnumChanged, request, "enum values removed: %v", removed)
+	}
+	if len(added) > 0 {
+		d.add(name, location, SchemaEnumChanged, !request, "enum values added: %v", added)
+	}
+}
+
+// requestBodySchemas 返回请求体各格式的字段定义
+func requestBodySchemas(op *OpenAPIOperation) map[string]*OpenAPISchema {
+	schemas := map[string]*OpenAPISchema{}
+	if op.RequestBody == nil {
+		return schemas
+	}
+	for mediaType, content := range op.RequestBody.Content {
+		if content != nil {
+			schemas[mediaType] = content.Schema
+		}
+	}
+	return schemas
+}
+
+// commonMediaType 返回两个版本共有的格式，优先使用 JSON
+func commonMediaType[T any](old, new map[string]T) string {
+	if _, ok := old["application/json"]; ok {
+		if _, ok := new["application/json"]; ok {
+			return "application/json"
+		}
+	}
+	for _, mediaType := range sortedKeys(old) {
+		if _, ok := new[mediaType]; ok {
+			return mediaType
+		}
+	}
+	return ""
+}
+
+func joinSchemaPath(location, field string) string {
+	if location == "" {
+		return field
+	}
+	return location + "." + field
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// checkSchemaBaseline 配置 schema_check.baseline 时将当前生成的 OpenAPI 文档与基线比较，记录不兼容变更的警告日志；
+// 当前环境在 schema_check.fail_in 中时拒绝启动
+func (app *App) checkSchemaBaseline() error {
+	if app.cfg.ModConfig == nil || app.cfg.ModConfig.SchemaCheck.Baseline == "" {
+		return nil
+	}
+	config := app.cfg.ModConfig.SchemaCheck
+	baseline, err := LoadOpenAPISpec(config.Baseline)
+	if err != nil {
+		app.logger.WithError(err).Warn("Failed to load schema baseline (schema_check.baseline)")
+		return nil
+	}
+	breaking := DiffOpenAPI(baseline, app.OpenAPI()).Breaking()
+	if len(breaking) == 0 {
+		app.logger.WithField("baseline", config.Baseline).Debug("No breaking API changes against schema baseline")
+		return nil
+	}
+	for _, change := range breaking {
+		fields := logrus.Fields{"service": change.Service, "kind": change.Kind, "baseline": config.Baseline}
+		if change.Location != "" {
+			fields["location"] = change.Location
+		}
+		app.logger.WithFields(fields).Warn("Breaking API change: " + change.Message)
+	}
+	if envIn(app.Env(), config.FailIn) {
+		return fmt.Errorf("%d breaking API changes against %s in the %s environment (schema_check.fail_in):\n%s",
+			len(breaking), config.Baseline, app.Env(), breaking)
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package mod
+
+import (
+	"strings"
+)
+
+// scopesKey 当前Token权限范围在 Fiber locals 中的缓存键
+type scopesKey struct{}
+
+// Scopes 返回当前Token被授予的权限范围，来自JWT声明或Token缓存数据中的 scope 字段（token.scope_claim 可修改），
+// 支持空格或逗号分隔的字符串（OAuth 2.0 格式）及字符串数组
+func (c *Context) Scopes() []string {
+	if cached, ok := c.Locals(scopesKey{}).([]string); ok {
+		return cached
+	}
+	scopes := c.loadScopes()
+	if scopes == nil {
+		scopes = []string{}
+	}
+	c.Locals(scopesKey{}, scopes)
+	return scopes
+}
+
+func (c *Context) loadScopes() []string {
+	claim := "scope"
+	if c.app != nil && c.app.cfg.ModConfig != nil && c.app.cfg.ModConfig.Token.ScopeClaim != "" {
+		claim = c.app.cfg.ModConfig.Token.ScopeClaim
+	}
+
+	if claims := c.GetJWTClaims(); claims != nil {
+		return parseScopes(claims.Extra[claim])
+	}
+
+	if c.app == nil {
+		return nil
+	}
+	token := parseToken(c.Ctx, c.app.tokenKeys)
+	if token == "" {
+		return nil
+	}
+	data, err := c.app.tokenDataMap(c.Ctx, token)
+	if err != nil {
+		return nil
+	}
+	return parseScopes(data[claim])
+}
+
+// parseScopes 解析 scope 声明的值
+func parseScopes(value any) []string {
+	var scopes []string
+	switch v := value.(type) {
+	case string:
+		scopes = strings.FieldsFunc(v, func(r rune) bool { return r == ' ' || r == ',' })
+	case []string:
+		scopes = v
+	case []any:
+		for _, item := range v {
+			if s, ok := item.(string); ok && s != "" {
+				scopes = append(scopes, s)
+			}
+		}
+	}
+	return scopes
+}
+
+// HasScope 当前Token是否被授予指定权限范围
+func (c *Context) HasScope(scope string) bool {
+	return scopeGranted(c.Scopes(), scope)
+}
+
+// scopeGranted 检查权限范围是否已授予：完全匹配，或授予了 * 及 orders:* 形式的前缀通配
+func scopeGranted(granted []string, scope string) bool {
+	for _, g := range granted {
+		if g == scope || g == "*" {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(g, "*"); ok && strings.HasPrefix(scope, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// missingScopes 返回 required 中未授予的权限范围
+func missingScopes(granted, required []string) []string {
+	var missing []string
+	for _, scope := range required {
+		if !scopeGranted(granted, scope) {
+			missing = append(missing, scope)
+		}
+	}
+	return missing
+}
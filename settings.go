@@ -0,0 +1,417 @@
+package mod
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/redis/go-redis/v9"
+)
+
+// settingsAdminGroup 设置管理服务所在的分组
+const settingsAdminGroup = "系统设置"
+
+// SettingChange 设置项的一次变更
+type SettingChange struct {
+	Key     string          `json:"key"`
+	Old     json.RawMessage `json:"old,omitempty"` // 变更前的值（JSON），新增时为空
+	New     json.RawMessage `json:"new,omitempty"` // 变更后的值（JSON），删除时为空
+	Deleted bool            `json:"deleted"`
+}
+
+// settingsHook OnChange 注册的回调，key 为空时接收全部变更
+type settingsHook struct {
+	key string
+	fn  func(SettingChange)
+}
+
+// Settings 运行时可调整的业务设置（公告文案、功能参数等），内存中保存全部设置项并持久化到 Redis（哈希）或 BadgerDB；
+// 使用 Redis 时通过发布订阅通知其他实例重新加载变更的设置项
+type Settings struct {
+	app    *App
+	mu     sync.RWMutex
+	values map[string]json.RawMessage
+	hooks  []settingsHook
+}
+
+// SettingEntry 设置项
+type SettingEntry struct {
+	Key   string `json:"key" desc:"设置项名称"`
+	Value any    `json:"value" desc:"设置项的值"`
+}
+
+// SettingsListRequest 查询设置项请求
+type SettingsListRequest struct {
+	Prefix string `json:"prefix" desc:"设置项名称前缀，为空时返回全部"`
+}
+
+// SettingsListResponse 设置项列表
+type SettingsListResponse struct {
+	Items []SettingEntry `json:"items" desc:"设置项，按名称排序"`
+}
+
+// SettingsSetRequest 修改设置项请求
+type SettingsSetRequest struct {
+	Key   string `json:"key" validate:"required" desc:"设置项名称"`
+	Value any    `json:"value" desc:"设置项的值，可以是字符串、数字、布尔值或对象"`
+}
+
+// SettingsDeleteRequest 删除设置项请求
+type SettingsDeleteRequest struct {
+	Key string `json:"key" validate:"required" desc:"设置项名称"`
+}
+
+// Settings 返回应用的业务设置，挂载的子应用与父应用共用
+func (app *App) Settings() *Settings {
+	return app.settings
+}
+
+// settingsCacheKey 返回设置项在缓存中的键（Redis 哈希或 BadgerDB 键前缀）
+func (app *App) settingsCacheKey() string {
+	if key := app.cfg.ModConfig.Settings.CacheKey; key != "" {
+		return key
+	}
+	return "mod:settings"
+}
+
+// configureSettings 加载持久化的设置项，使用 Redis 时订阅其他实例的变更，启用设置管理时注册管理服务
+func (app *App) configureSettings() {
+	app.settings = &Settings{app: app, values: map[string]json.RawMessage{}}
+	switch {
+	case app.redisClient != nil:
+		start := time.Now()
+		app.recordStartup("settings", "redis", start, app.settings.load())
+		go app.settings.subscribe()
+	case app.badgerDB != nil:
+		start := time.Now()
+		app.recordStartup("settings", "badger", start, app.settings.load())
+	}
+
+	config := app.cfg.ModConfig.Settings.Admin
+	if !config.Enabled {
+		return
+	}
+	services := []Service{
+		{
+			Name:        "settings_list",
+			DisplayName: "查询设置",
+			Description: "查询运行时业务设置，可按名称前缀过滤",
+			Group:       settingsAdminGroup,
+			Sort:        1,
+			SkipAuth:    config.SkipAuth,
+			Handler: MakeHandler(func(ctx *Context, req *SettingsListRequest, resp *SettingsListResponse) error {
+				resp.Items = app.settings.entries(req.Prefix)
+				return nil
+			}),
+		},
+		{
+			Name:        "settings_set",
+			DisplayName: "修改设置",
+			Description: "新增或修改设置项，立即生效并通知订阅者",
+			Group:       settingsAdminGroup,
+			Sort:        2,
+			SkipAuth:    config.SkipAuth,
+			Handler: MakeHandler(func(ctx *Context, req *SettingsSetRequest, resp *SettingEntry) error {
+				if err := app.settings.Set(req.Key, req.Value); err != nil {
+					return err
+				}
+				*resp = SettingEntry{Key: req.Key, Value: req.Value}
+				return nil
+			}),
+		},
+		{
+			Name:        "settings_delete",
+			DisplayName: "删除设置",
+			Description: "删除设置项，读取时使用代码中的默认值",
+			Group:       settingsAdminGroup,
+			Sort:        3,
+			SkipAuth:    config.SkipAuth,
+			Handler: MakeHandler(func(ctx *Context, req *SettingsDeleteRequest, resp *SettingsListResponse) error {
+				if err := app.settings.Delete(req.Key); err != nil {
+					return err
+				}
+				resp.Items = app.settings.entries("")
+				return nil
+			}),
+		},
+	}
+	for _, svc := range services {
+		if err := app.Register(app.adminService(svc)); err != nil {
+			app.logger.WithError(err).WithField("service", svc.Name).Error("Failed to register settings admin service")
+		}
+	}
+}
+
+// load 从缓存加载全部设置项
+func (s *Settings) load() error {
+	app := s.app
+	key := app.settingsCacheKey()
+	values := map[string]json.RawMessage{}
+	switch {
+	case app.redisClient != nil:
+		all, err := app.redisClient.HGetAll(context.Background(), key).Result()
+		if err != nil {
+			return err
+		}
+		for name, value := range all {
+			values[name] = json.RawMessage(value)
+		}
+	case app.badgerDB != nil:
+		prefix := []byte(key + ":")
+		err := app.badgerDB.View(func(txn *badger.Txn) error {
+			it := txn.NewIterator(badger.DefaultIteratorOptions)
+			defer it.Close()
+			for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+				value, err := it.Item().ValueCopy(nil)
+				if err != nil {
+					return err
+				}
+				values[string(bytes.TrimPrefix(it.Item().Key(), prefix))] = value
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	s.mu.Lock()
+	s.values = values
+	s.mu.Unlock()
+	return nil
+}
+
+// subscribe 接收其他实例的变更通知，重新加载变更的设置项
+func (s *Settings) subscribe() {
+	app := s.app
+	key := app.settingsCacheKey()
+	pubsub := app.redisClient.Subscribe(context.Background(), key+":changed")
+	defer pubsub.Close()
+	for msg := range pubsub.Channel() {
+		value, err := app.redisClient.HGet(context.Background(), key, msg.Payload).Result()
+		switch {
+		case err == redis.Nil:
+			s.apply(msg.Payload, nil)
+		case err != nil:
+			app.logger.WithError(err).WithField("key", msg.Payload).Warn("Failed to reload setting")
+		default:
+			s.apply(msg.Payload, json.RawMessage(value))
+		}
+	}
+}
+
+// apply 更新内存中的设置项，值发生变化时通知订阅者；value 为 nil 表示删除
+func (s *Settings) apply(key string, value json.RawMessage) {
+	s.mu.Lock()
+	old, exists := s.values[key]
+	if (value == nil && !exists) || (value != nil && exists && bytes.Equal(old, value)) {
+		s.mu.Unlock()
+		return
+	}
+	if value == nil {
+		delete(s.values, key)
+	} else {
+		s.values[key] = value
+	}
+	hooks := s.hooks
+	s.mu.Unlock()
+
+	change := SettingChange{Key: key, Old: old, New: value, Deleted: value == nil}
+	for _, hook := range hooks {
+		if hook.key == "" || hook.key == key {
+			hook.fn(change)
+		}
+	}
+}
+
+// Set 修改设置项，value 按JSON保存；持久化失败时不修改内存中的值
+func (s *Settings) Set(key string, value any) error {
+	if key == "" {
+		return Reply(400, "设置项名称不能为空")
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return ReplyWithDetail(400, "设置项的值无法序列化", err.Error())
+	}
+	if err := s.persist(key, data); err != nil {
+		s.app.logger.WithError(err).WithField("key", key).Error("Failed to persist setting")
+		return err
+	}
+	s.apply(key, data)
+	s.app.logger.WithField("key", key).Info("Setting changed")
+	s.publish(key)
+	return nil
+}
+
+// Delete 删除设置项
+func (s *Settings) Delete(key string) error {
+	if err := s.persist(key, nil); err != nil {
+		s.app.logger.WithError(err).WithField("key", key).Error("Failed to delete setting")
+		return err
+	}
+	s.apply(key, nil)
+	s.app.logger.WithField("key", key).Info("Setting deleted")
+	s.publish(key)
+	return nil
+}
+
+// persist 写入或删除缓存中的设置项，未配置 Redis、BadgerDB 时仅在内存中生效
+func (s *Settings) persist(key string, data []byte) error {
+	app := s.app
+	cacheKey := app.settingsCacheKey()
+	switch {
+	case app.redisClient != nil:
+		if data == nil {
+			return app.redisClient.HDel(context.Background(), cacheKey, key).Err()
+		}
+		return app.redisClient.HSet(context.Background(), cacheKey, key, data).Err()
+	case app.badgerDB != nil:
+		return app.badgerDB.Update(func(txn *badger.Txn) error {
+			if data == nil {
+				return txn.Delete([]byte(cacheKey + ":" + key))
+			}
+			return txn.Set([]byte(cacheKey+":"+key), data)
+		})
+	}
+	return nil
+}
+
+// publish 通知其他实例重新加载设置项
+func (s *Settings) publish(key string) {
+	app := s.app
+	if app.redisClient == nil {
+		return
+	}
+	if err := app.redisClient.Publish(context.Background(), app.settingsCacheKey()+":changed", key).Err(); err != nil {
+		app.logger.WithError(err).WithField("key", key).Warn("Failed to publish setting change")
+	}
+}
+
+// OnChange 注册设置项变更的回调，key 为空时接收全部变更；其他实例修改的设置项同样会触发。
+// 回调在修改设置的 goroutine 中同步执行，不应长时间阻塞
+func (s *Settings) OnChange(key string, fn func(SettingChange)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hooks = append(s.hooks, settingsHook{key: key, fn: fn})
+}
+
+// Raw 返回设置项的JSON值
+func (s *Settings) Raw(key string) (json.RawMessage, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	value, ok := s.values[key]
+	return value, ok
+}
+
+// Has 判断设置项是否存在
+func (s *Settings) Has(key string) bool {
+	_, ok := s.Raw(key)
+	return ok
+}
+
+// Scan 将设置项的值解析到 dst，设置项不存在时返回 false
+func (s *Settings) Scan(key string, dst any) (bool, error) {
+	value, ok := s.Raw(key)
+	if !ok {
+		return false, nil
+	}
+	if err := json.Unmarshal(value, dst); err != nil {
+		return true, fmt.Errorf("setting %q: %w", key, err)
+	}
+	return true, nil
+}
+
+// String 返回字符串设置项，非字符串的值返回其JSON文本，不存在时返回 def
+func (s *Settings) String(key, def string) string {
+	value, ok := s.Raw(key)
+	if !ok {
+		return def
+	}
+	var str string
+	if json.Unmarshal(value, &str) == nil {
+		return str
+	}
+	return string(value)
+}
+
+// Int 返回整数设置项，兼容字符串形式的数字，不存在或无法解析时返回 def
+func (s *Settings) Int(key string, def int) int {
+	if f, ok := s.number(key); ok {
+		return int(f)
+	}
+	return def
+}
+
+// Float 返回浮点数设置项，兼容字符串形式的数字，不存在或无法解析时返回 def
+func (s *Settings) Float(key string, def float64) float64 {
+	if f, ok := s.number(key); ok {
+		return f
+	}
+	return def
+}
+
+// Bool 返回布尔设置项，兼容 "true"、"1" 等字符串，不存在或无法解析时返回 def
+func (s *Settings) Bool(key string, def bool) bool {
+	value, ok := s.Raw(key)
+	if !ok {
+		return def
+	}
+	var b bool
+	if json.Unmarshal(value, &b) == nil {
+		return b
+	}
+	if parsed, err := strconv.ParseBool(s.String(key, "")); err == nil {
+		return parsed
+	}
+	return def
+}
+
+// Duration 返回时长设置项（如 "30s"），不存在或无法解析时返回 def
+func (s *Settings) Duration(key string, def time.Duration) time.Duration {
+	if d, err := time.ParseDuration(s.String(key, "")); err == nil {
+		return d
+	}
+	return def
+}
+
+// number 解析数字设置项
+func (s *Settings) number(key string) (float64, bool) {
+	value, ok := s.Raw(key)
+	if !ok {
+		return 0, false
+	}
+	var f float64
+	if json.Unmarshal(value, &f) == nil {
+		return f, true
+	}
+	if parsed, err := strconv.ParseFloat(strings.TrimSpace(s.String(key, "")), 64); err == nil {
+		return parsed, true
+	}
+	return 0, false
+}
+
+// entries 返回名称以 prefix 开头的设置项，按名称排序
+func (s *Settings) entries(prefix string) []SettingEntry {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	items := []SettingEntry{}
+	for key, value := range s.values {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		var decoded any
+		if json.Unmarshal(value, &decoded) != nil {
+			decoded = string(value)
+		}
+		items = append(items, SettingEntry{Key: key, Value: decoded})
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].Key < items[j].Key })
+	return items
+}
@@ -0,0 +1,14 @@
+package mod
+
+import "testing"
+
+func TestSettingsAdminRequiresAdmin(t *testing.T) {
+	app := newTestApp(t, tokenCacheConfig+`
+settings:
+  admin:
+    enabled: true
+`)
+	assertAdminService(t, app, "settings_list", SettingsListRequest{})
+	assertAdminService(t, app, "settings_set", SettingsSetRequest{Key: "checkout.enabled", Value: true})
+	assertAdminService(t, app, "settings_delete", SettingsDeleteRequest{Key: "checkout.enabled"})
+}
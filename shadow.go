@@ -0,0 +1,382 @@
+package mod
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// HeaderShadow 镜像请求携带的请求头，带有该请求头的请求不会被再次镜像
+const HeaderShadow = "X-Mod-Shadow"
+
+// shadowKey 影子请求在 Fiber locals 中的标记
+type shadowKey struct{}
+
+// ShadowStats 服务流量镜像统计
+type ShadowStats struct {
+	Mirrored   int64 `json:"mirrored"`   // 已镜像的请求数
+	Dropped    int64 `json:"dropped"`    // 超出并发上限被丢弃的请求数
+	Failed     int64 `json:"failed"`     // 影子实现返回错误、非2xx响应或超时的请求数
+	Mismatched int64 `json:"mismatched"` // 启用 compare 时与主实现响应不一致的请求数
+}
+
+// shadowCounters 单个服务的镜像计数
+type shadowCounters struct {
+	mirrored, dropped, failed, mismatched atomic.Int64
+}
+
+// shadowState 影子处理函数、并发控制和统计
+type shadowState struct {
+	mu       sync.RWMutex
+	handlers map[string]Handler
+	stats    map[string]*shadowCounters
+	sem      chan struct{}
+	timeout  time.Duration
+	client   *http.Client
+}
+
+// shadowResult 用于比较的响应：业务码和响应数据
+type shadowResult struct {
+	code int
+	data json.RawMessage
+}
+
+// shadowCall 一次镜像，主处理函数完成后通过 finish 提交主实现的响应用于比较
+type shadowCall struct {
+	primary chan shadowResult
+}
+
+// shadowState 返回流量镜像状态，首次使用时按配置初始化
+func (app *App) shadowState() *shadowState {
+	app.shadowOnce.Do(func() {
+		config := app.cfg.ModConfig.Shadow
+		timeout := 5 * time.Second
+		if config.Timeout != "" {
+			if d, err := time.ParseDuration(config.Timeout); err == nil && d > 0 {
+				timeout = d
+			} else {
+				app.logger.WithField("timeout", config.Timeout).Warn("Invalid shadow timeout, using 5s")
+			}
+		}
+		maxConcurrent := config.MaxConcurrent
+		if maxConcurrent <= 0 {
+			maxConcurrent = 100
+		}
+		app.shadow = &shadowState{
+			handlers: map[string]Handler{},
+			stats:    map[string]*shadowCounters{},
+			sem:      make(chan struct{}, maxConcurrent),
+			timeout:  timeout,
+			client:   &http.Client{Transport: http.DefaultTransport.(*http.Transport).Clone()},
+		}
+	})
+	return app.shadow
+}
+
+// Shadow 为服务注册影子实现（如重写后的新版本），按 mod.yml 中 shadow.services.<name>.percent
+// 的比例接收线上请求的副本。影子实现的响应不会返回给客户端，可通过 ctx.IsShadow() 跳过写操作等副作用
+func (app *App) Shadow(name string, handler Handler) error {
+	if handler.Func == nil && handler.call == nil {
+		return fmt.Errorf("shadow %q: handler is nil, use mod.MakeHandler to create it", name)
+	}
+	if err := checkHandlerType("input", handler.InputType); err != nil {
+		return fmt.Errorf("shadow %q: %w", name, err)
+	}
+	if err := checkHandlerType("output", handler.OutputType); err != nil {
+		return fmt.Errorf("shadow %q: %w", name, err)
+	}
+
+	state := app.shadowState()
+	state.mu.Lock()
+	state.handlers[name] = handler
+	state.mu.Unlock()
+	return nil
+}
+
+// ShadowStats 返回各服务的流量镜像统计
+func (app *App) ShadowStats() map[string]ShadowStats {
+	state := app.shadowState()
+	state.mu.RLock()
+	defer state.mu.RUnlock()
+
+	result := make(map[string]ShadowStats, len(state.stats))
+	for name, c := range state.stats {
+		result[name] = ShadowStats{
+			Mirrored:   c.mirrored.Load(),
+			Dropped:    c.dropped.Load(),
+			Failed:     c.failed.Load(),
+			Mismatched: c.mismatched.Load(),
+		}
+	}
+	return result
+}
+
+// IsShadow 当前请求是否为流量镜像产生的影子请求
+func (c *Context) IsShadow() bool {
+	shadow, _ := c.Locals(shadowKey{}).(bool)
+	return shadow
+}
+
+// counters 返回服务的镜像计数
+func (s *shadowState) counters(name string) *shadowCounters {
+	s.mu.RLock()
+	c, ok := s.stats[name]
+	s.mu.RUnlock()
+	if ok {
+		return c
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if c, ok = s.stats[name]; !ok {
+		c = &shadowCounters{}
+		s.stats[name] = c
+	}
+	return c
+}
+
+// startShadow 按配置的比例将请求复制给影子实现异步执行，未镜像时返回 nil
+func (app *App) startShadow(ctx *Context, svc *Service) *shadowCall {
+	config, ok := app.cfg.ModConfig.Shadow.Services[svc.Name]
+	if !ok || config.Percent <= 0 || ctx.IsShadow() || ctx.Get(HeaderShadow) != "" {
+		return nil
+	}
+	if config.Percent < 100 && rand.Float64()*100 >= config.Percent {
+		return nil
+	}
+
+	state := app.shadowState()
+	state.mu.RLock()
+	handler, hasHandler := state.handlers[svc.Name]
+	state.mu.RUnlock()
+	if !hasHandler && config.URL == "" {
+		return nil
+	}
+
+	counters := state.counters(svc.Name)
+	select {
+	case state.sem <- struct{}{}:
+	default:
+		counters.dropped.Add(1)
+		return nil
+	}
+	counters.mirrored.Add(1)
+
+	// 请求结束后 fiber.Ctx 会被复用，需要在返回前复制请求和 locals
+	req := &fasthttp.Request{}
+	ctx.Request().CopyTo(req)
+	locals := map[any]any{}
+	ctx.Context().VisitUserValuesAll(func(key, value any) {
+		if v, ok := value.(*requestValues); ok {
+			v.mu.RLock()
+			clone := &requestValues{values: make(map[string]any, len(v.values))}
+			for k, val := range v.values {
+				clone.values[k] = val
+			}
+			v.mu.RUnlock()
+			value = clone
+		}
+		locals[key] = value
+	})
+	rid := ctx.GetRequestID()
+	remote := ctx.Context().RemoteAddr()
+
+	call := &shadowCall{}
+	if config.Compare {
+		call.primary = make(chan shadowResult, 1)
+	}
+	log := app.logger.WithFields(map[string]any{"service": svc.Name, "rid": rid})
+
+	go func() {
+		defer func() { <-state.sem }()
+		defer func() {
+			if r := recover(); r != nil {
+				counters.failed.Add(1)
+				log.WithField("panic", r).Error("Shadow handler panicked")
+			}
+		}()
+
+		timeoutCtx, cancel := context.WithTimeout(context.Background(), state.timeout)
+		defer cancel()
+
+		var result shadowResult
+		var err error
+		if config.URL != "" {
+			result, err = app.forwardShadow(timeoutCtx, state.client, config.URL, req, rid, svc.ReturnRaw)
+		} else {
+			result, err = app.runShadowHandler(timeoutCtx, svc, handler, req, remote, locals, rid)
+		}
+		if err != nil {
+			counters.failed.Add(1)
+			log.WithError(err).Warn("Shadow request failed")
+			return
+		}
+		if call.primary == nil {
+			return
+		}
+
+		select {
+		case primary := <-call.primary:
+			if !shadowResultsEqual(primary, result) {
+				counters.mismatched.Add(1)
+				log.WithFields(map[string]any{
+					"primary_code": primary.code,
+					"primary_data": string(primary.data),
+					"shadow_code":  result.code,
+					"shadow_data":  string(result.data),
+				}).Warn("Shadow response mismatch")
+			}
+		case <-timeoutCtx.Done():
+		}
+	}()
+	return call
+}
+
+// finish 提交主实现的响应，未启用比较时忽略
+func (call *shadowCall) finish(out any, err error) {
+	if call == nil || call.primary == nil {
+		return
+	}
+	call.primary <- newShadowResult(out, err)
+}
+
+// runShadowHandler 使用复制的请求执行影子处理函数
+func (app *App) runShadowHandler(ctx context.Context, svc *Service, handler Handler, req *fasthttp.Request, remote net.Addr, locals map[any]any, rid string) (shadowResult, error) {
+	var rc fasthttp.RequestCtx
+	rc.Init(req, remote, nil)
+	for key, value := range locals {
+		rc.SetUserValue(key, value)
+	}
+	fc := app.AcquireCtx(&rc)
+	defer app.ReleaseCtx(fc)
+	fc.Locals(shadowKey{}, true)
+	fc.SetUserContext(ctx)
+
+	sctx := &Context{Ctx: fc, RequestID: rid, logger: app.logger, app: app, service: svc}
+
+	var in, out any
+	if handler.InputType != nil {
+		in = reflect.New(handler.InputType).Interface()
+		var err error
+		if binder, ok := in.(ParamBinder); ok {
+			err = binder.BindParams(fc)
+		} else {
+			err = app.parseRequestParamsToStruct(fc, in)
+		}
+		if err != nil {
+			return shadowResult{}, fmt.Errorf("parameter parsing error: %w", err)
+		}
+		if rv, ok := in.(RequestValidator); ok {
+			err = rv.ValidateRequest()
+		} else {
+			err = validate.Struct(in)
+		}
+		if err != nil {
+			return newShadowResult(nil, ReplyWithDetail(400, "Parameter validation error", err.Error())), nil
+		}
+	}
+	if handler.OutputType != nil {
+		out = reflect.New(handler.OutputType).Interface()
+	}
+
+	var err error
+	if handler.call != nil {
+		out, err = handler.call(sctx, in)
+	} else {
+		err = handler.Func(sctx, in, out)
+	}
+	if err != nil {
+		if _, ok := err.(*StdReply); !ok {
+			return shadowResult{}, err
+		}
+	}
+	return newShadowResult(out, err), nil
+}
+
+// forwardShadow 将复制的请求转发到影子实现地址
+func (app *App) forwardShadow(ctx context.Context, client *http.Client, url string, req *fasthttp.Request, rid string, raw bool) (shadowResult, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(req.Body()))
+	if err != nil {
+		return shadowResult{}, err
+	}
+	req.Header.VisitAll(func(key, value []byte) {
+		switch strings.ToLower(string(key)) {
+		case "host", "content-length", "connection", "accept-encoding":
+			return
+		}
+		httpReq.Header.Add(string(key), string(value))
+	})
+	if len(req.URI().QueryString()) > 0 {
+		httpReq.URL.RawQuery = string(req.URI().QueryString())
+	}
+	httpReq.Header.Set(HeaderShadow, "1")
+	httpReq.Header.Set("X-Request-ID", rid)
+
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return shadowResult{}, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return shadowResult{}, err
+	}
+
+	if !raw {
+		var envelope struct {
+			Code *int            `json:"code"`
+			Data json.RawMessage `json:"data"`
+		}
+		if json.Unmarshal(body, &envelope) == nil && envelope.Code != nil {
+			if resp.StatusCode >= 500 {
+				return shadowResult{}, fmt.Errorf("shadow upstream responded %d", resp.StatusCode)
+			}
+			return shadowResult{code: *envelope.Code, data: envelope.Data}, nil
+		}
+	}
+	if resp.StatusCode >= 300 {
+		return shadowResult{}, fmt.Errorf("shadow upstream responded %d", resp.StatusCode)
+	}
+	return shadowResult{data: body}, nil
+}
+
+// newShadowResult 将处理结果转换为可比较的业务码和JSON数据
+func newShadowResult(out any, err error) shadowResult {
+	if err != nil {
+		if reply, ok := err.(*StdReply); ok {
+			return shadowResult{code: reply.Code()}
+		}
+		return shadowResult{code: 500}
+	}
+	data, marshalErr := json.Marshal(out)
+	if marshalErr != nil {
+		return shadowResult{code: 500}
+	}
+	return shadowResult{data: data}
+}
+
+// shadowResultsEqual 比较业务码和JSON数据（忽略字段顺序和格式差异）
+func shadowResultsEqual(a, b shadowResult) bool {
+	if a.code != b.code {
+		return false
+	}
+	var av, bv any
+	errA := json.Unmarshal(a.data, &av)
+	errB := json.Unmarshal(b.data, &bv)
+	if errA != nil || errB != nil {
+		return bytes.Equal(bytes.TrimSpace(a.data), bytes.TrimSpace(b.data))
+	}
+	return reflect.DeepEqual(av, bv)
+}
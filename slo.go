@@ -0,0 +1,315 @@
+package mod
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+)
+
+// sloSlots 滚动窗口划分的时间片数量
+const sloSlots = 60
+
+// SLO 状态
+const (
+	SLOStatusOK        = "ok"        // 错误预算消耗速度正常
+	SLOStatusBurning   = "burning"   // 消耗速度超过告警阈值
+	SLOStatusExhausted = "exhausted" // 窗口内的错误预算已耗尽
+)
+
+// SLOTarget 服务的SLO目标：请求在目标延迟内成功返回（非5xx）视为达标
+type SLOTarget struct {
+	Latency   string  `yaml:"latency"`   // 目标延迟，如 300ms，为空时只统计错误率
+	Objective float64 `yaml:"objective"` // 达标请求占比（百分比），如 99.9，错误预算为 100-objective，默认99
+}
+
+// SLOReport 服务在滚动窗口内的SLO达成情况
+type SLOReport struct {
+	Service         string  `json:"service"`
+	Latency         string  `json:"latency,omitempty"` // 目标延迟
+	Objective       float64 `json:"objective"`         // 目标达标率（百分比）
+	Window          string  `json:"window"`            // 滚动窗口
+	Total           int64   `json:"total"`             // 窗口内的请求数
+	Good            int64   `json:"good"`              // 达标的请求数
+	Errors          int64   `json:"errors"`            // 5xx 请求数
+	Slow            int64   `json:"slow"`              // 成功但超过目标延迟的请求数
+	Availability    float64 `json:"availability"`      // 实际达标率（百分比），无请求时为100
+	BudgetRemaining float64 `json:"budget_remaining"`  // 剩余错误预算（百分比），耗尽后为负数
+	BurnRate        float64 `json:"burn_rate"`         // 错误预算消耗速度，1表示恰好在窗口结束时耗尽
+	Status          string  `json:"status"`            // ok、burning 或 exhausted
+}
+
+// SLOAlert 错误预算消耗过快时的告警
+type SLOAlert struct {
+	Report SLOReport `json:"report"`
+	Time   time.Time `json:"time"`
+}
+
+// sloSlot 滚动窗口中的一个时间片
+type sloSlot struct {
+	index  int64 // 时间片序号，与当前序号相差超过 sloSlots 时视为过期
+	total  int64
+	errors int64
+	slow   int64
+}
+
+// sloTracker 单个服务的SLO目标和滚动窗口计数
+type sloTracker struct {
+	latency   time.Duration
+	target    SLOTarget
+	slots     [sloSlots]sloSlot
+	lastAlert time.Time
+}
+
+// sloState 各服务的SLO计数和告警回调，挂载的子应用与父应用共享
+type sloState struct {
+	mu       sync.Mutex
+	trackers map[string]*sloTracker // 服务名 -> 计数，未配置SLO的服务为 nil
+	hooks    []func(SLOAlert)
+}
+
+// sloWindow 返回滚动窗口长度，默认1h
+func (app *App) sloWindow() time.Duration {
+	if window := app.cfg.ModConfig.SLO.Window; window != "" {
+		if d, err := time.ParseDuration(window); err == nil && d >= sloSlots*time.Millisecond {
+			return d
+		}
+	}
+	return time.Hour
+}
+
+// sloBurnRate 返回告警的错误预算消耗速度阈值，默认2
+func (app *App) sloBurnRate() float64 {
+	if rate := app.cfg.ModConfig.SLO.BurnRate; rate > 0 {
+		return rate
+	}
+	return 2
+}
+
+// configureSLO 初始化SLO统计并注册报告路由
+func (app *App) configureSLO() {
+	app.slo = &sloState{trackers: map[string]*sloTracker{}}
+	config := app.cfg.ModConfig.SLO
+	if !config.Enabled {
+		return
+	}
+	path := config.Path
+	if path == "" {
+		path = "/admin/slo"
+	}
+	app.Get(path, app.handleSLOReport)
+}
+
+// sloTarget 返回服务的SLO目标，mod.yml 中 slo.services 的配置优先于 Service.SLO
+func (app *App) sloTarget(svc *Service) *SLOTarget {
+	if target, ok := app.cfg.ModConfig.SLO.Services[svc.Name]; ok {
+		return &target
+	}
+	return svc.SLO
+}
+
+// sloTracker 返回服务的SLO计数，未配置SLO时返回 nil，调用方须持有锁
+func (app *App) sloTracker(svc *Service) *sloTracker {
+	if tracker, ok := app.slo.trackers[svc.Name]; ok {
+		return tracker
+	}
+	var tracker *sloTracker
+	if target := app.sloTarget(svc); target != nil {
+		tracker = &sloTracker{target: *target}
+		if tracker.target.Objective <= 0 || tracker.target.Objective >= 100 {
+			tracker.target.Objective = 99
+		}
+		if target.Latency != "" {
+			if d, err := time.ParseDuration(target.Latency); err == nil && d > 0 {
+				tracker.latency = d
+			} else {
+				app.logger.WithFields(logrus.Fields{"service": svc.Name, "latency": target.Latency}).Warn("Invalid SLO latency, only errors are counted")
+			}
+		}
+	}
+	app.slo.trackers[svc.Name] = tracker
+	return tracker
+}
+
+// recordSLO 记录一次服务调用，在服务处理函数返回后调用；请求不达标时检查错误预算的消耗速度
+func (app *App) recordSLO(ctx *Context, start time.Time) {
+	if app.slo == nil || ctx.service == nil || !app.cfg.ModConfig.SLO.Enabled {
+		return
+	}
+	now := time.Now()
+	elapsed := now.Sub(start)
+	failed := ctx.Response().StatusCode() >= 500
+
+	app.slo.mu.Lock()
+	tracker := app.sloTracker(ctx.service)
+	if tracker == nil {
+		app.slo.mu.Unlock()
+		return
+	}
+	window := app.sloWindow()
+	slot := tracker.slot(now, window)
+	slot.total++
+	slow := !failed && tracker.latency > 0 && elapsed > tracker.latency
+	if failed {
+		slot.errors++
+	} else if slow {
+		slot.slow++
+	}
+
+	var alert *SLOAlert
+	if failed || slow {
+		alert = app.checkSLOBurn(ctx.service.Name, tracker, now, window)
+	}
+	hooks := app.slo.hooks
+	app.slo.mu.Unlock()
+
+	if alert != nil {
+		app.logger.WithFields(logrus.Fields{
+			"service":          alert.Report.Service,
+			"burn_rate":        alert.Report.BurnRate,
+			"availability":     alert.Report.Availability,
+			"objective":        alert.Report.Objective,
+			"budget_remaining": alert.Report.BudgetRemaining,
+			"window":           alert.Report.Window,
+		}).Warn("SLO error budget is burning too fast")
+		for _, hook := range hooks {
+			go hook(*alert)
+		}
+	}
+}
+
+// checkSLOBurn 消耗速度达到 slo.burn_rate（默认2）且窗口内请求数不少于 slo.min_requests（默认20）时返回告警，
+// 同一服务在 slo.alert_interval（默认10m）内只告警一次
+func (app *App) checkSLOBurn(service string, tracker *sloTracker, now time.Time, window time.Duration) *SLOAlert {
+	config := app.cfg.ModConfig.SLO
+	threshold := app.sloBurnRate()
+	minRequests := int64(config.MinRequests)
+	if minRequests <= 0 {
+		minRequests = 20
+	}
+	interval := 10 * time.Minute
+	if config.AlertInterval != "" {
+		if d, err := time.ParseDuration(config.AlertInterval); err == nil && d >= 0 {
+			interval = d
+		}
+	}
+
+	report := tracker.report(service, now, window, threshold)
+	if report.Total < minRequests || report.BurnRate < threshold || now.Sub(tracker.lastAlert) < interval {
+		return nil
+	}
+	tracker.lastAlert = now
+	return &SLOAlert{Report: report, Time: now}
+}
+
+// slot 返回当前时间所在的时间片，过期的时间片清零后复用
+func (t *sloTracker) slot(now time.Time, window time.Duration) *sloSlot {
+	index := now.UnixNano() / int64(window/sloSlots)
+	slot := &t.slots[index%sloSlots]
+	if slot.index != index {
+		*slot = sloSlot{index: index}
+	}
+	return slot
+}
+
+// report 汇总滚动窗口内的计数
+func (t *sloTracker) report(service string, now time.Time, window time.Duration, threshold float64) SLOReport {
+	report := SLOReport{
+		Service:   service,
+		Latency:   t.target.Latency,
+		Objective: t.target.Objective,
+		Window:    window.String(),
+		Status:    SLOStatusOK,
+	}
+	current := now.UnixNano() / int64(window/sloSlots)
+	for _, slot := range t.slots {
+		if slot.index > current-sloSlots && slot.index <= current {
+			report.Total += slot.total
+			report.Errors += slot.errors
+			report.Slow += slot.slow
+		}
+	}
+	report.Good = report.Total - report.Errors - report.Slow
+	report.Availability = 100
+	report.BudgetRemaining = 100
+	if report.Total == 0 {
+		return report
+	}
+
+	badRatio := float64(report.Errors+report.Slow) / float64(report.Total)
+	budget := (100 - report.Objective) / 100
+	report.Availability = roundTo(100*(1-badRatio), 4)
+	report.BurnRate = roundTo(badRatio/budget, 2)
+	report.BudgetRemaining = roundTo(100*(1-badRatio/budget), 2)
+	switch {
+	case report.BudgetRemaining <= 0:
+		report.Status = SLOStatusExhausted
+	case report.BurnRate >= threshold:
+		report.Status = SLOStatusBurning
+	}
+	return report
+}
+
+// roundTo 保留指定位数的小数
+func roundTo(value float64, digits int) float64 {
+	scale := math.Pow(10, float64(digits))
+	return math.Round(value*scale) / scale
+}
+
+// SLOReport 返回已配置SLO的服务在滚动窗口内的达成情况，按服务名排序
+func (app *App) SLOReport() []SLOReport {
+	if app.slo == nil {
+		return nil
+	}
+	now := time.Now()
+
+	app.slo.mu.Lock()
+	defer app.slo.mu.Unlock()
+	reports := []SLOReport{}
+	for _, svc := range app.root().allServices() {
+		// 子应用的服务按其自身的配置统计
+		owner := svc.owner
+		if owner == nil || !owner.cfg.ModConfig.SLO.Enabled {
+			continue
+		}
+		tracker := owner.sloTracker(&svc)
+		if tracker == nil {
+			continue
+		}
+		reports = append(reports, tracker.report(svc.Name, now, owner.sloWindow(), owner.sloBurnRate()))
+	}
+	sort.Slice(reports, func(i, j int) bool { return reports[i].Service < reports[j].Service })
+	return reports
+}
+
+// OnSLOAlert 注册错误预算消耗过快时的告警回调（如发送到IM或告警平台），回调在独立的 goroutine 中执行
+func (app *App) OnSLOAlert(hook func(SLOAlert)) {
+	app.slo.mu.Lock()
+	defer app.slo.mu.Unlock()
+	app.slo.hooks = append(app.slo.hooks, hook)
+}
+
+// handleSLOReport 输出SLO报告，未开启 slo.skip_auth 时按 auth.admin 配置校验请求
+func (app *App) handleSLOReport(c *fiber.Ctx) error {
+	ctx := &Context{Ctx: c, logger: app.logger, app: app}
+	if !app.cfg.ModConfig.SLO.SkipAuth {
+		if err := app.authorizeAdmin(ctx, "slo"); err != nil {
+			return replyError(ctx, err)
+		}
+	}
+
+	reports := app.SLOReport()
+	if service := c.Query("service"); service != "" {
+		filtered := []SLOReport{}
+		for _, report := range reports {
+			if report.Service == service {
+				filtered = append(filtered, report)
+			}
+		}
+		reports = filtered
+	}
+	return c.JSON(NewSuccessResponse(ctx, reports))
+}
@@ -0,0 +1,246 @@
+package mod
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// 子系统初始化状态
+const (
+	StartupOK     = "ok"
+	StartupFailed = "failed"
+)
+
+// StartupCheck 启动时单个子系统（配置、Token缓存、文件上传后端等）的初始化结果
+type StartupCheck struct {
+	Name     string        `json:"name"`             // 子系统，如 config、cache.redis、upload.s3
+	Status   string        `json:"status"`           // ok 或 failed
+	Target   string        `json:"target,omitempty"` // 地址、路径等定位信息
+	Error    string        `json:"error,omitempty"`  // 失败原因
+	Duration time.Duration `json:"duration"`         // 初始化耗时
+}
+
+// recordStartup 记录子系统的初始化结果，start 为初始化开始时间
+func (app *App) recordStartup(name, target string, start time.Time, err error) {
+	check := StartupCheck{
+		Name:     name,
+		Status:   StartupOK,
+		Target:   target,
+		Duration: time.Since(start),
+	}
+	if err != nil {
+		check.Status = StartupFailed
+		check.Error = err.Error()
+	}
+	app.startupChecks = append(app.startupChecks, check)
+}
+
+// StartupReport 返回 New() 时各子系统的初始化结果，按初始化顺序排列
+func (app *App) StartupReport() []StartupCheck {
+	return append([]StartupCheck(nil), app.startupChecks...)
+}
+
+// StartupError 返回初始化失败的子系统汇总错误，全部成功时返回 nil
+func (app *App) StartupError() error {
+	var errs []error
+	for _, check := range app.startupChecks {
+		if check.Status == StartupFailed {
+			errs = append(errs, fmt.Errorf("%s: %s", check.Name, check.Error))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// verifyStartup 输出启动报告；启用 startup.fail_fast 时存在失败的子系统则终止进程
+func (app *App) verifyStartup() {
+	if len(app.startupChecks) == 0 {
+		return
+	}
+
+	failed := 0
+	for _, check := range app.startupChecks {
+		entry := app.logger.WithFields(map[string]any{
+			"subsystem": check.Name,
+			"status":    check.Status,
+			"duration":  check.Duration.String(),
+		})
+		if check.Target != "" {
+			entry = entry.WithField("target", check.Target)
+		}
+		if check.Status == StartupFailed {
+			failed++
+			entry.WithField("error", check.Error).Error("Startup check failed")
+		} else {
+			entry.Debug("Startup check passed")
+		}
+	}
+	app.logger.WithFields(map[string]any{
+		"total":  len(app.startupChecks),
+		"ok":     len(app.startupChecks) - failed,
+		"failed": failed,
+	}).Info("Startup verification completed")
+
+	if failed > 0 && app.cfg.ModConfig.Startup.FailFast {
+		app.logger.WithError(app.StartupError()).Fatal("Startup dependency verification failed, exiting (startup.fail_fast)")
+	}
+}
+
+// StartupSummary 服务启动时的汇总信息，便于部署后确认监听地址、启用的子系统和已注册的服务
+type StartupSummary struct {
+	Addr       string            `json:"addr"`               // 实际监听的地址
+	Docs       string            `json:"docs"`               // 文档地址
+	Env        string            `json:"env,omitempty"`      // 运行环境
+	Version    string            `json:"version,omitempty"`  // 应用版本
+	Subsystems map[string]string `json:"subsystems"`         // 启用的子系统，如 cache: redis、upload: local,s3、jwt: on
+	Services   int               `json:"services"`           // 服务总数（包括挂载的子应用），多版本服务计为一个
+	Groups     map[string]int    `json:"groups"`             // 分组 -> 服务数
+	Mocked     []string          `json:"mocked,omitempty"`   // 启用Mock的服务
+	Degraded   []string          `json:"degraded,omitempty"` // 初始化失败的子系统及原因
+}
+
+// StartupSummary 返回当前的启动汇总信息，服务未启动时 Addr 和 Docs 为空
+func (app *App) StartupSummary() StartupSummary {
+	summary := StartupSummary{
+		Env:        app.Env(),
+		Subsystems: app.enabledSubsystems(),
+		Groups:     map[string]int{},
+		Mocked:     app.mockedServices(),
+	}
+	if addr := app.Addr(); addr != nil {
+		summary.Addr = addr.String()
+		summary.Docs = fmt.Sprintf("http://%s/services/docs", docsHost(addr))
+	}
+	if app.cfg.ModConfig != nil {
+		summary.Version = app.cfg.ModConfig.App.Version
+	}
+
+	seen := map[string]bool{}
+	for _, svc := range app.allServices() {
+		if seen[svc.Name] {
+			continue
+		}
+		seen[svc.Name] = true
+		group := svc.Group
+		if group == "" {
+			group = "默认分组"
+		}
+		summary.Services++
+		summary.Groups[group]++
+	}
+
+	for _, check := range app.startupChecks {
+		if check.Status != StartupFailed {
+			continue
+		}
+		name := check.Name
+		if check.Target != "" {
+			name += " (" + check.Target + ")"
+		}
+		summary.Degraded = append(summary.Degraded, name+": "+check.Error)
+	}
+	return summary
+}
+
+// enabledSubsystems 汇总启用的缓存、文件上传后端、认证、加解密、限流等子系统
+func (app *App) enabledSubsystems() map[string]string {
+	subsystems := map[string]string{}
+	var caches []string
+	if app.tokenCache != nil {
+		caches = append(caches, "bigcache")
+	}
+	if app.badgerDB != nil {
+		caches = append(caches, "badger")
+	}
+	if app.redisClient != nil {
+		caches = append(caches, "redis")
+	}
+	if len(caches) > 0 {
+		subsystems["cache"] = strings.Join(caches, ",")
+	}
+
+	var uploads []string
+	for _, check := range app.startupChecks {
+		if name, ok := strings.CutPrefix(check.Name, "upload."); ok && check.Status == StartupOK {
+			uploads = append(uploads, name)
+		}
+	}
+	if len(uploads) > 0 {
+		subsystems["upload"] = strings.Join(uploads, ",")
+	}
+
+	config := app.cfg.ModConfig
+	if config == nil {
+		return subsystems
+	}
+	if config.Token.Validation.Enabled {
+		subsystems["token_validation"] = config.Token.Validation.CacheStrategy + " (" + app.tokenDegradation.policy + ")"
+	}
+	if config.Token.JWT.Enabled {
+		subsystems["jwt"] = "on"
+	}
+	if encryptionConfigured(config) {
+		subsystems["encryption"] = "on"
+	}
+	if app.rateLimit != nil && (config.RateLimit.Global.Requests > 0 || len(config.RateLimit.Groups) > 0 || len(config.RateLimit.Services) > 0) {
+		subsystems["rate_limit"] = app.rateLimit.backend
+	}
+	if app.limiter != nil {
+		subsystems["concurrency_limit"] = fmt.Sprint(config.ConcurrencyLimit.MaxInFlight)
+	}
+	if app.shedder != nil {
+		subsystems["load_shedding"] = "on"
+	}
+	if app.captureDB != nil {
+		subsystems["capture"] = "on"
+	}
+	return subsystems
+}
+
+// encryptionConfigured 是否在全局、分组或服务级别启用了服务加解密
+func encryptionConfigured(config *ModConfig) bool {
+	if config.Encryption.Global.Enabled {
+		return true
+	}
+	for _, group := range config.Encryption.Groups {
+		if group.Enabled {
+			return true
+		}
+	}
+	for _, service := range config.Encryption.Services {
+		if service.Enabled {
+			return true
+		}
+	}
+	return false
+}
+
+// logStartupSummary 端口绑定成功后输出一条汇总日志，存在初始化失败的子系统时以警告级别输出
+func (app *App) logStartupSummary(bound *net.TCPAddr) {
+	summary := app.StartupSummary()
+	entry := app.logger.WithFields(logrus.Fields{
+		"addr":       bound.String(),
+		"docs":       summary.Docs,
+		"services":   summary.Services,
+		"groups":     summary.Groups,
+		"subsystems": summary.Subsystems,
+	})
+	if summary.Env != "" {
+		entry = entry.WithField("env", summary.Env)
+	}
+	if summary.Version != "" {
+		entry = entry.WithField("version", summary.Version)
+	}
+	if len(summary.Mocked) > 0 {
+		entry = entry.WithField("mocked", summary.Mocked)
+	}
+	if len(summary.Degraded) > 0 {
+		entry.WithField("degraded", summary.Degraded).Warn("Server started with degraded subsystems")
+		return
+	}
+	entry.Info("Server started")
+}
@@ -0,0 +1,320 @@
+package mod
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// mod 标签的语法：mod:"from=<来源>;name=<名称>"，选项以分号分隔，name 默认为小写字段名
+const modTagGrammar = `mod:"from=<来源>;name=<名称>"`
+
+// paramSources mod 标签 from 支持的参数来源，按文档中的顺序排列
+var paramSources = []struct {
+	From        string
+	Description string
+}{
+	{"body", "请求体字段（JSON、XML、MessagePack 或表单），字段名为 json 名称；没有 mod 标签时的默认来源"},
+	{"query", "URL 查询参数；设置了 mod 标签但未指定 from 时的默认来源"},
+	{"header", "请求头"},
+	{"form", "表单值（application/x-www-form-urlencoded 或 multipart/form-data）"},
+	{"param", "路径参数，name 与路径模板中的参数名一致"},
+	{"file", "multipart/form-data 上传的文件"},
+}
+
+// modTagKeys mod 标签支持的选项
+var modTagKeys = []string{"from", "name"}
+
+// paramSourceDescription 返回参数来源的说明，用于文档
+func paramSourceDescription(from string) string {
+	for _, source := range paramSources {
+		if source.From == from {
+			return source.Description
+		}
+	}
+	return ""
+}
+
+// parseModTag 按语法解析 mod 标签：选项必须为 key=value，选项名和来源必须是支持的值且不能重复
+func parseModTag(tag string) (map[string]string, error) {
+	options := map[string]string{}
+	for _, part := range strings.Split(tag, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(part, "=")
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if !ok {
+			return nil, fmt.Errorf("option %q must be key=value", part)
+		}
+		if !slices.Contains(modTagKeys, key) {
+			return nil, fmt.Errorf("unknown option %q%s", key, didYouMean(key, modTagKeys))
+		}
+		if _, exists := options[key]; exists {
+			return nil, fmt.Errorf("option %q is set more than once", key)
+		}
+		if value == "" {
+			return nil, fmt.Errorf("option %q has an empty value", key)
+		}
+		// from=header,name=x 这类写法在运行时整体作为 from 的值
+		if strings.ContainsAny(value, ",=") {
+			return nil, fmt.Errorf("option %q has an invalid value %q, separate options with ;", key, value)
+		}
+		if key == "from" && paramSourceDescription(value) == "" {
+			sources := make([]string, len(paramSources))
+			for i, source := range paramSources {
+				sources[i] = source.From
+			}
+			return nil, fmt.Errorf("unknown source from=%s%s", value, didYouMean(value, sources))
+		}
+		options[key] = value
+	}
+	return options, nil
+}
+
+// checkServiceTags 注册时检查输入输出类型中的 mod、validate、desc 标签，返回所有字段的错误。
+// 输入类型顶层字段的 mod 标签还检查字段类型以及路径参数是否在路由中
+func (app *App) checkServiceTags(svc *Service) error {
+	var errs []error
+	params := pathParamNames(app.serviceRoute(svc))
+	for _, typ := range []struct {
+		kind string
+		t    reflect.Type
+	}{{"input", svc.Handler.InputType}, {"output", svc.Handler.OutputType}} {
+		if typ.t == nil || typ.t.Kind() != reflect.Struct {
+			continue
+		}
+		c := &tagChecker{visited: map[reflect.Type]bool{}}
+		c.checkStruct(typ.t, "")
+		if typ.kind == "input" && !svc.RawBody && !svc.Handler.ndjson {
+			c.checkInputSources(typ.t, params)
+		}
+		for _, err := range c.errs {
+			errs = append(errs, fmt.Errorf("service %q: %s type %s: %w", svc.Name, typ.kind, typeName(typ.t), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// tagChecker 递归检查结构体字段的标签，同一类型只检查一次
+type tagChecker struct {
+	visited map[reflect.Type]bool
+	errs    []error
+}
+
+func (c *tagChecker) fail(path, format string, args ...any) {
+	c.errs = append(c.errs, fmt.Errorf("field %s: %s", path, fmt.Sprintf(format, args...)))
+}
+
+// checkStruct 检查结构体字段的标签语法，并通过校验零值发现未定义的 validate 规则
+func (c *tagChecker) checkStruct(t reflect.Type, prefix string) {
+	if c.visited[t] || t == timeType || t == fileHeaderType.Elem() {
+		return
+	}
+	c.visited[t] = true
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() && !field.Anonymous {
+			continue
+		}
+		path := prefix + field.Name
+		if err := checkTagSyntax(field.Tag); err != nil {
+			c.fail(path, "%v", err)
+			continue
+		}
+		if modTag, ok := field.Tag.Lookup("mod"); ok {
+			if _, err := parseModTag(modTag); err != nil {
+				c.fail(path, "mod tag: %v", err)
+			}
+		}
+
+		elem := derefType(field.Type)
+		for elem.Kind() == reflect.Slice || elem.Kind() == reflect.Array || elem.Kind() == reflect.Map {
+			elem = derefType(elem.Elem())
+			path += "[]"
+		}
+		if elem.Kind() == reflect.Struct {
+			c.checkStruct(elem, path+".")
+		}
+	}
+
+	// validator 在解析结构体的规则时遇到未定义的规则会 panic
+	if err := checkValidateTags(t); err != nil {
+		name := strings.TrimSuffix(prefix, ".")
+		if name == "" {
+			c.errs = append(c.errs, fmt.Errorf("validate tag: %w", err))
+		} else {
+			c.fail(name, "validate tag: %v", err)
+		}
+	}
+}
+
+// checkInputSources 检查输入类型顶层字段的来源：from=file 的字段类型、from=param 的参数是否在路由中
+func (c *tagChecker) checkInputSources(t reflect.Type, params []string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		modTag, ok := field.Tag.Lookup("mod")
+		if !field.IsExported() || !ok {
+			continue
+		}
+		options, err := parseModTag(modTag)
+		if err != nil {
+			continue
+		}
+		name := options["name"]
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		switch options["from"] {
+		case "file":
+			if field.Type != fileHeaderType && field.Type != fileHeadersType {
+				c.fail(field.Name, "from=file requires *multipart.FileHeader or []*multipart.FileHeader, got %s", field.Type)
+			}
+		case "param":
+			if !slices.Contains(params, name) {
+				c.fail(field.Name, "path parameter %q is not in the route", name)
+			}
+		}
+	}
+}
+
+// sourceTypeWarnings 返回从查询参数、请求头、表单或路径参数读取但类型不支持的字段，
+// 这类字段只能通过请求体赋值，注册时记录警告
+func sourceTypeWarnings(t reflect.Type) []string {
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil
+	}
+	var fields []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		modTag, ok := field.Tag.Lookup("mod")
+		if !field.IsExported() || !ok {
+			continue
+		}
+		options, err := parseModTag(modTag)
+		if err != nil {
+			continue
+		}
+		switch options["from"] {
+		case "body", "file":
+			continue
+		}
+		switch field.Type.Kind() {
+		case reflect.String, reflect.Bool,
+			reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+			reflect.Float32, reflect.Float64:
+		default:
+			fields = append(fields, field.Name)
+		}
+	}
+	return fields
+}
+
+// checkValidateTags 校验结构体的零值，将 validator 解析规则时的 panic 转换为错误
+func checkValidateTags(t reflect.Type) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	_ = validate.Struct(reflect.New(t).Interface())
+	return nil
+}
+
+// checkTagSyntax 按 reflect.StructTag 的约定检查标签语法，格式错误时 mod、validate、desc 标签会被静默忽略；
+// 只报告涉及这些标签的问题，其他库的标签不做要求
+func checkTagSyntax(tag reflect.StructTag) error {
+	raw := string(tag)
+	if !strings.Contains(raw, "mod") && !strings.Contains(raw, "validate") && !strings.Contains(raw, "desc") {
+		return nil
+	}
+	seen := map[string]bool{}
+	for raw != "" {
+		raw = strings.TrimLeft(raw, " ")
+		if raw == "" {
+			break
+		}
+		i := 0
+		for i < len(raw) && raw[i] > ' ' && raw[i] != ':' && raw[i] != '"' && raw[i] != 0x7f {
+			i++
+		}
+		if i == 0 || i+1 >= len(raw) || raw[i] != ':' || raw[i+1] != '"' {
+			return fmt.Errorf("malformed struct tag %q, expected key:\"value\"", raw)
+		}
+		key := raw[:i]
+		raw = raw[i+1:]
+
+		i = 1
+		for i < len(raw) && raw[i] != '"' {
+			if raw[i] == '\\' {
+				i++
+			}
+			i++
+		}
+		if i >= len(raw) {
+			return fmt.Errorf("struct tag %s has an unterminated value", key)
+		}
+		if _, err := strconv.Unquote(raw[:i+1]); err != nil {
+			return fmt.Errorf("struct tag %s has an invalid value %s", key, raw[:i+1])
+		}
+		raw = raw[i+1:]
+
+		switch key {
+		case "mod", "validate", "desc":
+			if seen[key] {
+				return fmt.Errorf("struct tag %s is set more than once", key)
+			}
+			seen[key] = true
+		}
+	}
+	return nil
+}
+
+// didYouMean 返回与输入最接近的候选值提示，编辑距离超过2时返回空字符串
+func didYouMean(input string, candidates []string) string {
+	best, bestDistance := "", 3
+	for _, candidate := range candidates {
+		if d := editDistance(input, candidate); d < bestDistance {
+			best, bestDistance = candidate, d
+		}
+	}
+	if best == "" {
+		return ""
+	}
+	return fmt.Sprintf(" (did you mean %q?)", best)
+}
+
+// editDistance 字符串的编辑距离
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+// typeName 返回带包路径的类型名称，便于定位定义类型的文件
+func typeName(t reflect.Type) string {
+	if t.PkgPath() == "" || t.Name() == "" {
+		return t.String()
+	}
+	return t.PkgPath() + "." + t.Name()
+}
@@ -0,0 +1,291 @@
+package mod
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// builtinTemplates 内置的页面模板：文档页面（docs.html）和错误页（error.html）
+//
+//go:embed templates/*.html
+var builtinTemplates embed.FS
+
+// TemplateStats 单个模板的渲染统计
+type TemplateStats struct {
+	Name        string        `json:"name"`
+	Source      string        `json:"source"`       // builtin、inline 或模板文件路径
+	Renders     uint64        `json:"renders"`      // 渲染次数
+	Errors      uint64        `json:"errors"`       // 渲染失败次数
+	Reloads     uint64        `json:"reloads"`      // 热加载次数
+	AvgDuration time.Duration `json:"avg_duration"` // 平均渲染耗时
+	LastRender  time.Time     `json:"last_render"`  // 最近一次渲染时间
+}
+
+// templateEntry 注册的模板及其统计
+type templateEntry struct {
+	source string
+	path   string // 模板文件路径，热加载时检查修改时间
+
+	tmpl atomic.Pointer[template.Template]
+
+	mu      sync.Mutex // 保护热加载检查
+	modTime time.Time
+	checked time.Time
+
+	renders    atomic.Uint64
+	errors     atomic.Uint64
+	reloads    atomic.Uint64
+	nanos      atomic.Int64
+	lastRender atomic.Int64
+}
+
+// TemplateRegistry 集中管理HTML模板（文档页面、错误页、邮件等）：注册时预编译，开发模式下模板文件修改后自动重新加载。
+// 模板使用 html/template，输出按上下文转义
+type TemplateRegistry struct {
+	mu        sync.RWMutex
+	entries   map[string]*templateEntry
+	funcs     template.FuncMap
+	hotReload bool
+	revision  atomic.Uint64 // 任一模板重新加载时递增，使渲染结果的缓存失效
+	logger    *logrus.Logger
+}
+
+// configureTemplates 注册内置模板并加载 templates.dir 中的模板，挂载的子应用与父应用共用
+func (app *App) configureTemplates() {
+	config := app.cfg.ModConfig.Templates
+	app.templates = &TemplateRegistry{
+		entries: map[string]*templateEntry{},
+		funcs: template.FuncMap{
+			"add": func(a, b int) int { return a + b },
+			"mul": func(a, b int) int { return a * b },
+		},
+		hotReload: config.HotReload,
+		logger:    app.logger,
+	}
+
+	names, _ := fs.Glob(builtinTemplates, "templates/*.html")
+	for _, file := range names {
+		data, _ := builtinTemplates.ReadFile(file)
+		if err := app.templates.register(filepath.Base(file), "builtin", "", string(data), time.Time{}); err != nil {
+			panic(err)
+		}
+	}
+
+	if config.Dir != "" {
+		start := time.Now()
+		err := app.templates.LoadDir(config.Dir)
+		app.recordStartup("templates", config.Dir, start, err)
+		if err != nil {
+			app.logger.WithError(err).Error("Failed to load templates")
+		}
+	}
+}
+
+// Templates 返回模板注册表
+func (app *App) Templates() *TemplateRegistry {
+	return app.templates
+}
+
+// Funcs 添加模板函数，只对之后注册的模板生效；默认提供 add、mul
+func (r *TemplateRegistry) Funcs(funcs template.FuncMap) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for name, fn := range funcs {
+		r.funcs[name] = fn
+	}
+}
+
+// Register 注册并预编译模板，同名模板被替换
+func (r *TemplateRegistry) Register(name, text string) error {
+	return r.register(name, "inline", "", text, time.Time{})
+}
+
+// RegisterFile 注册模板文件，开启 templates.hot_reload 时文件修改后自动重新加载
+func (r *TemplateRegistry) RegisterFile(name, path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return r.register(name, path, path, string(data), info.ModTime())
+}
+
+// LoadDir 注册目录（含子目录）中的 *.html、*.tmpl 文件，模板名称为相对路径（如 emails/welcome.html），
+// 与内置模板同名时覆盖内置模板；有误的模板被跳过，返回所有错误
+func (r *TemplateRegistry) LoadDir(dir string) error {
+	var errs []error
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || (filepath.Ext(path) != ".html" && filepath.Ext(path) != ".tmpl") {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		if err := r.RegisterFile(filepath.ToSlash(rel), path); err != nil {
+			errs = append(errs, err)
+		}
+		return nil
+	})
+	if err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// register 编译模板并保存，替换同名模板
+func (r *TemplateRegistry) register(name, source, path, text string, modTime time.Time) error {
+	tmpl, err := r.parse(name, text)
+	if err != nil {
+		return err
+	}
+	entry := &templateEntry{source: source, path: path, modTime: modTime, checked: time.Now()}
+	entry.tmpl.Store(tmpl)
+
+	r.mu.Lock()
+	r.entries[name] = entry
+	r.mu.Unlock()
+	r.revision.Add(1)
+	return nil
+}
+
+func (r *TemplateRegistry) parse(name, text string) (*template.Template, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	tmpl, err := template.New(name).Funcs(r.funcs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template %q: %w", name, err)
+	}
+	return tmpl, nil
+}
+
+// Has 判断模板是否已注册
+func (r *TemplateRegistry) Has(name string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	_, ok := r.entries[name]
+	return ok
+}
+
+// Render 渲染模板并写入 w，渲染失败时不写入任何内容
+func (r *TemplateRegistry) Render(w io.Writer, name string, data any) error {
+	var buf bytes.Buffer
+	if err := r.execute(&buf, name, data); err != nil {
+		return err
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// RenderString 渲染模板并返回结果，用于邮件正文等
+func (r *TemplateRegistry) RenderString(name string, data any) (string, error) {
+	var buf strings.Builder
+	if err := r.execute(&buf, name, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+func (r *TemplateRegistry) execute(w io.Writer, name string, data any) error {
+	entry := r.entry(name)
+	if entry == nil {
+		return fmt.Errorf("template %q not found", name)
+	}
+	start := time.Now()
+	err := entry.tmpl.Load().Execute(w, data)
+	entry.renders.Add(1)
+	entry.nanos.Add(int64(time.Since(start)))
+	entry.lastRender.Store(start.UnixNano())
+	if err != nil {
+		entry.errors.Add(1)
+		return fmt.Errorf("failed to render template %q: %w", name, err)
+	}
+	return nil
+}
+
+// entry 返回模板，开启热加载时先检查模板文件是否已修改
+func (r *TemplateRegistry) entry(name string) *templateEntry {
+	r.mu.RLock()
+	entry := r.entries[name]
+	r.mu.RUnlock()
+	if entry != nil && r.hotReload && entry.path != "" {
+		r.reload(name, entry)
+	}
+	return entry
+}
+
+// reload 每秒最多检查一次模板文件的修改时间，修改后重新编译；编译失败时记录日志并继续使用原模板
+func (r *TemplateRegistry) reload(name string, entry *templateEntry) {
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+	if time.Since(entry.checked) < time.Second {
+		return
+	}
+	entry.checked = time.Now()
+	info, err := os.Stat(entry.path)
+	if err != nil || info.ModTime().Equal(entry.modTime) {
+		return
+	}
+	data, err := os.ReadFile(entry.path)
+	if err == nil {
+		var tmpl *template.Template
+		if tmpl, err = r.parse(name, string(data)); err == nil {
+			entry.tmpl.Store(tmpl)
+			entry.modTime = info.ModTime()
+			entry.reloads.Add(1)
+			r.revision.Add(1)
+			r.logger.WithFields(logrus.Fields{"template": name, "path": entry.path}).Info("Template reloaded")
+			return
+		}
+	}
+	r.logger.WithFields(logrus.Fields{"template": name, "path": entry.path, "error": err.Error()}).Error("Failed to reload template")
+}
+
+// currentRevision 检查模板是否需要热加载，返回当前的模板版本
+func (r *TemplateRegistry) currentRevision(name string) uint64 {
+	r.entry(name)
+	return r.revision.Load()
+}
+
+// Stats 返回各模板的渲染统计，按名称排序
+func (r *TemplateRegistry) Stats() []TemplateStats {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	stats := make([]TemplateStats, 0, len(r.entries))
+	for name, entry := range r.entries {
+		s := TemplateStats{
+			Name:    name,
+			Source:  entry.source,
+			Renders: entry.renders.Load(),
+			Errors:  entry.errors.Load(),
+			Reloads: entry.reloads.Load(),
+		}
+		if s.Renders > 0 {
+			s.AvgDuration = time.Duration(entry.nanos.Load() / int64(s.Renders))
+			s.LastRender = time.Unix(0, entry.lastRender.Load())
+		}
+		stats = append(stats, s)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Name < stats[j].Name })
+	return stats
+}
@@ -0,0 +1,403 @@
+package mod
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// TestUser 测试客户端自动登录的用户
+type TestUser struct {
+	ID       string
+	Username string
+	Email    string
+	Role     string
+	Data     map[string]any // 写入token缓存的数据（权限检查使用），启用JWT时同时作为扩展字段
+}
+
+// TestUserFactory 创建测试用户，测试客户端首次发起需要token的请求时调用
+type TestUserFactory func(app *App) (*TestUser, error)
+
+// TestClient 基于 fiber 的 App.Test 的测试客户端，请求在进程内处理，无需监听端口
+type TestClient struct {
+	app         *App
+	token       string
+	issued      bool // token是否由 Login 签发，Logout 时需要从缓存移除
+	headers     map[string]string
+	encryption  string
+	userFactory TestUserFactory
+	timeout     int
+}
+
+// TestResponse 测试客户端的响应
+type TestResponse struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte // 响应体，加密响应已解密
+
+	envelope *ApiResponse
+}
+
+// TestClient 创建测试客户端
+func (app *App) TestClient() *TestClient {
+	return &TestClient{app: app, headers: map[string]string{}, timeout: -1}
+}
+
+// WithToken 使用指定的token发起请求
+func (tc *TestClient) WithToken(token string) *TestClient {
+	tc.token = token
+	tc.issued = false
+	return tc
+}
+
+// WithHeader 设置请求头
+func (tc *TestClient) WithHeader(key, value string) *TestClient {
+	tc.headers[key] = value
+	return tc
+}
+
+// WithUserFactory 设置用户工厂，未设置token时自动登录
+func (tc *TestClient) WithUserFactory(factory TestUserFactory) *TestClient {
+	tc.userFactory = factory
+	return tc
+}
+
+// WithEncryption 按服务加解密的格式加密请求体并解密响应，mode 为 symmetric 或 asymmetric，为空时使用全局配置；
+// 通过 WithHeader 设置了非JSON的 Content-Type 时请求体按加密信封发送
+func (tc *TestClient) WithEncryption(mode string) *TestClient {
+	if mode == "" {
+		if config := tc.app.GetModConfig(); config != nil {
+			mode = config.Encryption.Global.Mode
+		}
+	}
+	tc.encryption = mode
+	return tc
+}
+
+// WithTimeout 设置请求超时（毫秒），默认不超时
+func (tc *TestClient) WithTimeout(ms int) *TestClient {
+	tc.timeout = ms
+	return tc
+}
+
+// Token 返回当前使用的token
+func (tc *TestClient) Token() string {
+	return tc.token
+}
+
+// Login 通过用户工厂创建用户并签发token，启用JWT时签发JWT访问令牌，token同时写入token验证缓存
+func (tc *TestClient) Login() (string, error) {
+	if tc.userFactory == nil {
+		return "", fmt.Errorf("test client: user factory not configured")
+	}
+
+	user, err := tc.userFactory(tc.app)
+	if err != nil {
+		return "", fmt.Errorf("test client: failed to create user: %w", err)
+	}
+
+	var token string
+	if jwtManager := tc.app.GetJWTManager(); jwtManager.IsEnabled() {
+		tokens, err := tc.app.GenerateJWT(user.ID, user.Username, user.Email, user.Role, user.Data)
+		if err != nil {
+			return "", fmt.Errorf("test client: failed to generate jwt: %w", err)
+		}
+		token = tokens.AccessToken
+	} else {
+		buf := make([]byte, 16)
+		if _, err := rand.Read(buf); err != nil {
+			return "", fmt.Errorf("test client: failed to generate token: %w", err)
+		}
+		token = "test-" + hex.EncodeToString(buf)
+	}
+
+	var data any
+	if user.Data != nil {
+		data = user.Data
+	}
+	if err := tc.app.SetToken(token, data); err != nil {
+		return "", fmt.Errorf("test client: failed to store token: %w", err)
+	}
+
+	tc.token = token
+	tc.issued = true
+	return token, nil
+}
+
+// Logout 清除当前token，由 Login 签发的token同时从token验证缓存中移除
+func (tc *TestClient) Logout() error {
+	token, issued := tc.token, tc.issued
+	tc.token = ""
+	tc.issued = false
+
+	if !issued {
+		return nil
+	}
+	if config := tc.app.GetModConfig(); config == nil || !config.Token.Validation.Enabled {
+		return nil
+	}
+	return tc.app.RemoveToken(token)
+}
+
+// Call 调用服务，挂载的子应用中的服务使用其挂载后的路径，路径模板中的参数取自 body 中 mod:"from=param" 字段的值
+func (tc *TestClient) Call(service string, body any) (*TestResponse, error) {
+	path := tc.app.ServicePath(service)
+	if svc, ok := tc.app.GetService(service); ok {
+		path = svc.requestPath(body)
+	}
+	return tc.Do(http.MethodPost, path, body)
+}
+
+// Post 发送POST请求，body 为 []byte 或 string 时原样发送，否则序列化为JSON
+func (tc *TestClient) Post(path string, body any) (*TestResponse, error) {
+	return tc.Do(http.MethodPost, path, body)
+}
+
+// Get 发送GET请求
+func (tc *TestClient) Get(path string) (*TestResponse, error) {
+	return tc.Do(http.MethodGet, path, nil)
+}
+
+// Do 发送请求
+func (tc *TestClient) Do(method, path string, body any) (*TestResponse, error) {
+	var payload []byte
+	switch v := body.(type) {
+	case nil:
+	case []byte:
+		payload = v
+	case string:
+		payload = []byte(v)
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("test client: failed to marshal body: %w", err)
+		}
+		payload = data
+	}
+
+	// JSON请求体使用 EncryptedRequest 格式，其他（文件、multipart 等）使用加密信封
+	var plainContentType string
+	if tc.encryption != "" && payload != nil {
+		contentType := fiber.MIMEApplicationJSON
+		for k, v := range tc.headers {
+			if http.CanonicalHeaderKey(k) == fiber.HeaderContentType {
+				contentType = v
+			}
+		}
+		var encrypted []byte
+		var err error
+		if isJSONContentType(contentType) {
+			encrypted, err = tc.encryptPayload(payload)
+		} else {
+			encrypted, err = tc.encryptStreamPayload(payload)
+			plainContentType = contentType
+		}
+		if err != nil {
+			return nil, err
+		}
+		payload = encrypted
+	}
+
+	if tc.token == "" && tc.userFactory != nil {
+		if _, err := tc.Login(); err != nil {
+			return nil, err
+		}
+	}
+
+	req := httptest.NewRequest(method, path, bytes.NewReader(payload))
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if tc.token != "" {
+		req.Header.Set("Authorization", "Bearer "+tc.token)
+	}
+	for k, v := range tc.headers {
+		req.Header.Set(k, v)
+	}
+	if plainContentType != "" {
+		req.Header.Set(fiber.HeaderContentType, EncryptedStreamContentType)
+		req.Header.Set(HeaderEncryptedContentType, plainContentType)
+	}
+
+	resp, err := tc.app.Test(req, tc.timeout)
+	if err != nil {
+		return nil, fmt.Errorf("test client: request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("test client: failed to read response: %w", err)
+	}
+
+	if tc.encryption != "" {
+		if resp.Header.Get(fiber.HeaderContentType) == EncryptedStreamContentType {
+			reader, err := tc.app.DecryptStream(bytes.NewReader(respBody))
+			if err == nil {
+				respBody, err = io.ReadAll(reader)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("test client: failed to decrypt response: %w", err)
+			}
+			resp.Header.Set(fiber.HeaderContentType, resp.Header.Get(HeaderEncryptedContentType))
+			resp.Header.Del(HeaderEncryptedContentType)
+		} else if respBody, err = tc.decryptPayload(respBody); err != nil {
+			return nil, err
+		}
+	}
+
+	return &TestResponse{StatusCode: resp.StatusCode, Header: resp.Header, Body: respBody}, nil
+}
+
+// encryptPayload 将请求体加密为 EncryptedRequest 格式
+func (tc *TestClient) encryptPayload(payload []byte) ([]byte, error) {
+	encrypted, err := tc.app.EncryptData(payload, tc.encryption)
+	if err != nil {
+		return nil, fmt.Errorf("test client: failed to encrypt body: %w", err)
+	}
+
+	encReq := EncryptedRequest{
+		Data: base64.StdEncoding.EncodeToString(encrypted),
+		Mode: tc.encryption,
+	}
+	if config := tc.app.GetModConfig(); config != nil && config.Encryption.Signature.Enabled {
+		signature, err := tc.app.SignData(encrypted)
+		if err != nil {
+			return nil, fmt.Errorf("test client: failed to sign body: %w", err)
+		}
+		encReq.Signature = base64.StdEncoding.EncodeToString(signature)
+	}
+	return json.Marshal(encReq)
+}
+
+// encryptStreamPayload 将请求体加密为信封格式
+func (tc *TestClient) encryptStreamPayload(payload []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	writer, err := tc.app.EncryptStream(&buf, tc.encryption)
+	if err == nil {
+		if _, err = writer.Write(payload); err == nil {
+			err = writer.Close()
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("test client: failed to encrypt body: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// decryptPayload 解密 EncryptedResponse 格式的响应体，非加密响应原样返回
+func (tc *TestClient) decryptPayload(body []byte) ([]byte, error) {
+	var encResp EncryptedResponse
+	if err := json.Unmarshal(body, &encResp); err != nil || encResp.Data == "" || encResp.Mode == "" {
+		return body, nil
+	}
+
+	encrypted, err := base64.StdEncoding.DecodeString(encResp.Data)
+	if err != nil {
+		return nil, fmt.Errorf("test client: failed to decode encrypted response: %w", err)
+	}
+	if config := tc.app.GetModConfig(); config != nil && config.Encryption.Signature.Enabled && encResp.Signature != "" {
+		signature, err := base64.StdEncoding.DecodeString(encResp.Signature)
+		if err != nil {
+			return nil, fmt.Errorf("test client: failed to decode response signature: %w", err)
+		}
+		if err := tc.app.VerifySignature(encrypted, signature); err != nil {
+			return nil, fmt.Errorf("test client: response signature verification failed: %w", err)
+		}
+	}
+
+	decrypted, err := tc.app.DecryptData(encrypted, encResp.Mode)
+	if err != nil {
+		return nil, fmt.Errorf("test client: failed to decrypt response: %w", err)
+	}
+	return decrypted, nil
+}
+
+// JSON 将响应体解析到 v
+func (r *TestResponse) JSON(v any) error {
+	return json.Unmarshal(r.Body, v)
+}
+
+// Envelope 将响应体解析为标准响应格式
+func (r *TestResponse) Envelope() (*ApiResponse, error) {
+	if r.envelope != nil {
+		return r.envelope, nil
+	}
+
+	var envelope ApiResponse
+	if err := json.Unmarshal(r.Body, &envelope); err != nil {
+		return nil, fmt.Errorf("response is not a standard envelope: %w", err)
+	}
+	r.envelope = &envelope
+	return r.envelope, nil
+}
+
+// Data 将标准响应中的 data 字段解析到 v
+func (r *TestResponse) Data(v any) error {
+	var envelope struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(r.Body, &envelope); err != nil {
+		return fmt.Errorf("response is not a standard envelope: %w", err)
+	}
+	if len(envelope.Data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(envelope.Data, v)
+}
+
+// AssertStatus 断言HTTP状态码
+func (r *TestResponse) AssertStatus(t testing.TB, status int) *TestResponse {
+	t.Helper()
+	if r.StatusCode != status {
+		t.Fatalf("expected status %d, got %d: %s", status, r.StatusCode, r.Body)
+	}
+	return r
+}
+
+// AssertSuccess 断言为成功的标准响应（code为0），v 非空时将 data 字段解析到 v
+func (r *TestResponse) AssertSuccess(t testing.TB, v ...any) *TestResponse {
+	t.Helper()
+	r.AssertCode(t, 0)
+	if len(v) > 0 && v[0] != nil {
+		if err := r.Data(v[0]); err != nil {
+			t.Fatalf("failed to decode response data: %v", err)
+		}
+	}
+	return r
+}
+
+// AssertCode 断言标准响应的业务状态码
+func (r *TestResponse) AssertCode(t testing.TB, code int) *TestResponse {
+	t.Helper()
+	envelope, err := r.Envelope()
+	if err != nil {
+		t.Fatalf("%v: %s", err, r.Body)
+	}
+	if envelope.Code != code {
+		t.Fatalf("expected code %d, got %d (%s %s)", code, envelope.Code, envelope.Msg, envelope.Detail)
+	}
+	return r
+}
+
+// AssertMsg 断言标准响应的消息
+func (r *TestResponse) AssertMsg(t testing.TB, msg string) *TestResponse {
+	t.Helper()
+	envelope, err := r.Envelope()
+	if err != nil {
+		t.Fatalf("%v: %s", err, r.Body)
+	}
+	if envelope.Msg != msg {
+		t.Fatalf("expected msg %q, got %q", msg, envelope.Msg)
+	}
+	return r
+}
@@ -0,0 +1,273 @@
+package mod
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+)
+
+// 防刷限流维度
+const (
+	ThrottleByIP      = "ip"      // 客户端IP
+	ThrottleByAccount = "account" // 请求中的账号（用户名、手机号等）
+	ThrottleByDevice  = "device"  // 设备标识请求头
+)
+
+// ThrottleRule 敏感服务（登录、发送短信验证码等）的防刷规则，每个维度各自维护一个令牌桶，任一维度的令牌耗尽即拒绝请求
+type ThrottleRule struct {
+	Capacity     int      `yaml:"capacity"`      // 令牌桶容量，即允许的连续请求次数，默认5
+	Refill       string   `yaml:"refill"`        // 补充一个令牌的间隔，默认1m
+	Keys         []string `yaml:"keys"`          // 限流维度：ip、account、device，默认 ip
+	AccountField string   `yaml:"account_field"` // 账号所在的请求字段（JSON请求体、表单或查询参数），默认 account
+	DeviceHeader string   `yaml:"device_header"` // 设备标识请求头，默认 X-Device-ID
+	Cooldown     string   `yaml:"cooldown"`      // 令牌耗尽后的冷却时间，冷却期内请求直接拒绝；为空时只需等待下一个令牌
+	Code         int      `yaml:"code"`          // 拒绝时的状态码，默认429
+	Message      string   `yaml:"message"`       // 拒绝时的提示信息，默认 Too many requests
+}
+
+// throttlePolicy 解析后的防刷规则
+type throttlePolicy struct {
+	capacity     float64
+	refill       time.Duration
+	cooldown     time.Duration
+	keys         []string
+	accountField string
+	deviceHeader string
+	code         int
+	message      string
+}
+
+// throttleBucket 单个维度取值的令牌桶
+type throttleBucket struct {
+	policy        *throttlePolicy
+	tokens        float64
+	updated       time.Time
+	cooldownUntil time.Time
+}
+
+// throttleState 各服务的防刷规则和令牌桶，令牌桶保存在进程内存中
+type throttleState struct {
+	mu        sync.Mutex
+	policies  map[string]*throttlePolicy
+	buckets   map[string]*throttleBucket
+	lastSweep time.Time
+}
+
+// throttleKeysKey 当前请求使用的令牌桶在 Fiber locals 中的键
+type throttleKeysKey struct{}
+
+// throttleRule 返回服务的防刷规则，mod.yml 中 throttle.services 的配置优先于 Service.Throttle
+func (app *App) throttleRule(svc *Service) *ThrottleRule {
+	if rule, ok := app.cfg.ModConfig.Throttle.Services[svc.Name]; ok {
+		return &rule
+	}
+	return svc.Throttle
+}
+
+// throttlePolicy 解析并缓存服务的防刷规则，未配置时返回 nil
+func (app *App) throttlePolicy(svc *Service) *throttlePolicy {
+	app.throttle.mu.Lock()
+	defer app.throttle.mu.Unlock()
+	if policy, ok := app.throttle.policies[svc.Name]; ok {
+		return policy
+	}
+
+	var policy *throttlePolicy
+	if rule := app.throttleRule(svc); rule != nil {
+		policy = &throttlePolicy{
+			capacity:     float64(rule.Capacity),
+			refill:       time.Minute,
+			keys:         rule.Keys,
+			accountField: rule.AccountField,
+			deviceHeader: rule.DeviceHeader,
+			code:         rule.Code,
+			message:      rule.Message,
+		}
+		if policy.capacity <= 0 {
+			policy.capacity = 5
+		}
+		if rule.Refill != "" {
+			if d, err := time.ParseDuration(rule.Refill); err == nil && d > 0 {
+				policy.refill = d
+			} else {
+				app.logger.WithFields(logrus.Fields{"service": svc.Name, "refill": rule.Refill}).Warn("Invalid throttle refill, using 1m")
+			}
+		}
+		if rule.Cooldown != "" {
+			if d, err := time.ParseDuration(rule.Cooldown); err == nil && d >= 0 {
+				policy.cooldown = d
+			} else {
+				app.logger.WithFields(logrus.Fields{"service": svc.Name, "cooldown": rule.Cooldown}).Warn("Invalid throttle cooldown, ignored")
+			}
+		}
+		if len(policy.keys) == 0 {
+			policy.keys = []string{ThrottleByIP}
+		}
+		if policy.accountField == "" {
+			policy.accountField = "account"
+		}
+		if policy.deviceHeader == "" {
+			policy.deviceHeader = "X-Device-ID"
+		}
+		if policy.code == 0 {
+			policy.code = fiber.StatusTooManyRequests
+		}
+		if policy.message == "" {
+			policy.message = "Too many requests"
+		}
+	}
+
+	if app.throttle.policies == nil {
+		app.throttle.policies = map[string]*throttlePolicy{}
+	}
+	app.throttle.policies[svc.Name] = policy
+	return policy
+}
+
+// throttleKeys 返回请求在各维度上的令牌桶键，取值为空的维度（如未携带设备标识）不参与限流
+func (p *throttlePolicy) throttleKeys(c *fiber.Ctx, service string) []string {
+	var keys []string
+	for _, dimension := range p.keys {
+		var value string
+		switch dimension {
+		case ThrottleByIP:
+			value = c.IP()
+		case ThrottleByAccount:
+			value = requestField(c, p.accountField)
+		case ThrottleByDevice:
+			value = c.Get(p.deviceHeader)
+		}
+		if value != "" {
+			keys = append(keys, service+"\x00"+dimension+"\x00"+value)
+		}
+	}
+	return keys
+}
+
+// requestField 从JSON请求体、表单或查询参数中读取字段，账号统一转为小写，避免通过大小写绕过限流
+func requestField(c *fiber.Ctx, name string) string {
+	if body := c.Body(); len(body) > 0 && strings.HasPrefix(c.Get(fiber.HeaderContentType), fiber.MIMEApplicationJSON) {
+		var fields map[string]any
+		if json.Unmarshal(body, &fields) == nil {
+			if value, ok := fields[name]; ok && value != nil {
+				return strings.ToLower(strings.TrimSpace(fmt.Sprint(value)))
+			}
+		}
+	}
+	if value := c.FormValue(name); value != "" {
+		return strings.ToLower(strings.TrimSpace(value))
+	}
+	return strings.ToLower(strings.TrimSpace(c.Query(name)))
+}
+
+// take 从各维度的令牌桶中各取一个令牌，任一维度不足时不消耗令牌并返回需要等待的时间
+func (s *throttleState) take(policy *throttlePolicy, keys []string, now time.Time) (bool, time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sweep(now)
+	if s.buckets == nil {
+		s.buckets = map[string]*throttleBucket{}
+	}
+
+	buckets := make([]*throttleBucket, len(keys))
+	var wait time.Duration
+	for i, key := range keys {
+		bucket := s.buckets[key]
+		if bucket == nil {
+			bucket = &throttleBucket{policy: policy, tokens: policy.capacity, updated: now}
+			s.buckets[key] = bucket
+		}
+		bucket.refill(now)
+		buckets[i] = bucket
+
+		if now.Before(bucket.cooldownUntil) {
+			wait = max(wait, bucket.cooldownUntil.Sub(now))
+			continue
+		}
+		if bucket.tokens < 1 {
+			if policy.cooldown > 0 {
+				bucket.cooldownUntil = now.Add(policy.cooldown)
+				wait = max(wait, policy.cooldown)
+			} else {
+				wait = max(wait, time.Duration((1-bucket.tokens)*float64(policy.refill)))
+			}
+		}
+	}
+	if wait > 0 {
+		return false, wait
+	}
+	for _, bucket := range buckets {
+		bucket.tokens--
+	}
+	return true, 0
+}
+
+// refill 按经过的时间补充令牌
+func (b *throttleBucket) refill(now time.Time) {
+	elapsed := now.Sub(b.updated)
+	if elapsed > 0 {
+		b.tokens = math.Min(b.policy.capacity, b.tokens+float64(elapsed)/float64(b.policy.refill))
+		b.updated = now
+	}
+}
+
+// sweep 每分钟清理一次已补满且不在冷却期的令牌桶，避免内存随IP、账号数量增长
+func (s *throttleState) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < time.Minute {
+		return
+	}
+	s.lastSweep = now
+	for key, bucket := range s.buckets {
+		bucket.refill(now)
+		if bucket.tokens >= bucket.policy.capacity && !now.Before(bucket.cooldownUntil) {
+			delete(s.buckets, key)
+		}
+	}
+}
+
+// checkThrottle 检查敏感服务的防刷限流，被拒绝时设置 Retry-After 响应头并返回 StdReply 错误
+func (app *App) checkThrottle(ctx *Context, svc *Service) error {
+	policy := app.throttlePolicy(svc)
+	if policy == nil {
+		return nil
+	}
+	keys := policy.throttleKeys(ctx.Ctx, svc.Name)
+	if len(keys) == 0 {
+		return nil
+	}
+	ctx.Locals(throttleKeysKey{}, keys)
+
+	allowed, wait := app.throttle.take(policy, keys, time.Now())
+	if allowed {
+		return nil
+	}
+	retryAfter := time.Duration(math.Ceil(wait.Seconds())) * time.Second
+	app.logger.WithFields(logrus.Fields{
+		"service":     svc.Name,
+		"ip":          ctx.IP(),
+		"retry_after": retryAfter.String(),
+		"rid":         ctx.GetRequestID(),
+	}).Warn("Request throttled")
+	ctx.Ctx.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(retryAfter.Seconds())))
+	return ReplyWithDetail(policy.code, policy.message, "retry after "+retryAfter.String())
+}
+
+// ResetThrottle 重置当前请求在各维度上的防刷令牌桶，如登录成功后清除该账号和设备的失败计数
+func (c *Context) ResetThrottle() {
+	keys, _ := c.Locals(throttleKeysKey{}).([]string)
+	if len(keys) == 0 || c.app == nil {
+		return
+	}
+	c.app.throttle.mu.Lock()
+	defer c.app.throttle.mu.Unlock()
+	for _, key := range keys {
+		delete(c.app.throttle.buckets, key)
+	}
+}
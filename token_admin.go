@@ -0,0 +1,114 @@
+package mod
+
+import "errors"
+
+// tokenAdminGroup Token管理服务所在的分组
+const tokenAdminGroup = "Token管理"
+
+// TokenListRequest 查询Token请求
+type TokenListRequest struct {
+	Prefix string `json:"prefix" desc:"token 前缀（不含缓存键前缀），为空时返回全部"`
+	Limit  int    `json:"limit" validate:"gte=0,lte=1000" desc:"最多返回的数量，默认100"`
+}
+
+// TokenListResponse Token列表
+type TokenListResponse struct {
+	Items []TokenInfo `json:"items" desc:"token 及其数据和过期时间"`
+}
+
+// TokenTouchRequest 续期Token请求
+type TokenTouchRequest struct {
+	Token string `json:"token" validate:"required" desc:"token（不含缓存键前缀）"`
+}
+
+// TokenTouchResponse 续期结果
+type TokenTouchResponse struct {
+	Token string `json:"token" desc:"token"`
+}
+
+// TokenRemoveRequest 删除Token请求
+type TokenRemoveRequest struct {
+	Token  string `json:"token" validate:"required_without=Prefix" desc:"删除指定的 token"`
+	Prefix string `json:"prefix" desc:"删除以该前缀开头的全部 token，如按用户ID开头生成 token 时强制用户下线"`
+}
+
+// TokenRemoveResponse 删除结果
+type TokenRemoveResponse struct {
+	Removed int `json:"removed" desc:"删除的 token 数量"`
+}
+
+// configureTokenAdmin 启用Token管理时注册管理服务（token_list、token_touch、token_remove），按 auth.admin 校验管理员身份
+func (app *App) configureTokenAdmin() {
+	config := app.cfg.ModConfig.Token.Validation
+	if !config.Enabled || !config.Admin.Enabled {
+		return
+	}
+
+	services := []Service{
+		{
+			Name:        "token_list",
+			DisplayName: "查询Token",
+			Description: "按前缀查询Token缓存中的 token、数据和剩余有效期",
+			Group:       tokenAdminGroup,
+			Sort:        1,
+			SkipAuth:    config.Admin.SkipAuth,
+			Handler: MakeHandler(func(ctx *Context, req *TokenListRequest, resp *TokenListResponse) error {
+				tokens, err := app.ListTokens(req.Prefix, req.Limit)
+				if err != nil {
+					return err
+				}
+				resp.Items = tokens
+				return nil
+			}),
+		},
+		{
+			Name:        "token_touch",
+			DisplayName: "续期Token",
+			Description: "重新开始计算 token 的有效期，数据不变",
+			Group:       tokenAdminGroup,
+			Sort:        2,
+			SkipAuth:    config.Admin.SkipAuth,
+			Handler: MakeHandler(func(ctx *Context, req *TokenTouchRequest, resp *TokenTouchResponse) error {
+				if err := app.TouchToken(req.Token); err != nil {
+					if errors.Is(err, ErrTokenNotFound) {
+						return Reply(404, "Token不存在")
+					}
+					return err
+				}
+				resp.Token = req.Token
+				return nil
+			}),
+		},
+		{
+			Name:        "token_remove",
+			DisplayName: "删除Token",
+			Description: "删除指定的 token 或以前缀开头的全部 token，使会话立即失效",
+			Group:       tokenAdminGroup,
+			Sort:        3,
+			SkipAuth:    config.Admin.SkipAuth,
+			Handler: MakeHandler(func(ctx *Context, req *TokenRemoveRequest, resp *TokenRemoveResponse) error {
+				if req.Token != "" {
+					if _, err := app.GetTokenData(req.Token); err != nil {
+						if errors.Is(err, ErrTokenNotFound) {
+							return nil
+						}
+						return err
+					}
+					if err := app.RemoveToken(req.Token); err != nil {
+						return err
+					}
+					resp.Removed = 1
+					return nil
+				}
+				removed, err := app.RemoveTokensByPrefix(req.Prefix)
+				resp.Removed = removed
+				return err
+			}),
+		},
+	}
+	for _, svc := range services {
+		if err := app.Register(app.adminService(svc)); err != nil {
+			app.logger.WithError(err).WithField("service", svc.Name).Error("Failed to register token admin service")
+		}
+	}
+}
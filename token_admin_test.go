@@ -0,0 +1,13 @@
+package mod
+
+import "testing"
+
+func TestTokenAdminRequiresAdmin(t *testing.T) {
+	app := newTestApp(t, tokenCacheConfig+`
+    admin:
+      enabled: true
+`)
+	assertAdminService(t, app, "token_list", TokenListRequest{})
+	assertAdminService(t, app, "token_touch", TokenTouchRequest{Token: "user-token"})
+	assertAdminService(t, app, "token_remove", TokenRemoveRequest{Token: "user-token"})
+}
@@ -0,0 +1,292 @@
+package mod
+
+import (
+	"container/list"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+)
+
+// Token缓存故障（Redis、BadgerDB 查询出错）时的处理策略
+const (
+	TokenCacheFailOpen      = "fail_open"      // 允许通过（默认，兼容旧版本行为）
+	TokenCacheFailClosed    = "fail_closed"    // 拒绝请求，返回 503
+	TokenCacheLocalFallback = "local_fallback" // 使用本地最近验证成功的Token数据，未命中时拒绝
+)
+
+// TokenCacheDegradation Token缓存故障告警事件
+type TokenCacheDegradation struct {
+	Env      string    `json:"env"`      // 运行环境
+	Policy   string    `json:"policy"`   // 生效的处理策略
+	Strategy string    `json:"strategy"` // Token缓存策略：redis、badger、bigcache
+	Error    string    `json:"error"`    // 缓存查询错误
+	Count    int64     `json:"count"`    // 距上次告警以来的降级次数
+	Time     time.Time `json:"time"`
+}
+
+// tokenDegradationState Token缓存故障处理策略、本地回退缓存和告警状态，与子应用共享
+type tokenDegradationState struct {
+	policy        string
+	alertInterval time.Duration
+	fallback      *tokenFallbackCache // 仅 local_fallback 策略使用
+
+	mu        sync.Mutex
+	degraded  bool      // 最近一次查询是否处于故障状态
+	lastAlert time.Time // 上次告警时间
+	pending   int64     // 距上次告警以来的降级次数
+	hooks     []func(TokenCacheDegradation)
+}
+
+// configureTokenDegradation 按运行环境解析Token缓存故障处理策略
+func (app *App) configureTokenDegradation() {
+	config := app.cfg.ModConfig.Token.Validation.Degradation
+	state := &tokenDegradationState{policy: TokenCacheFailOpen, alertInterval: time.Minute}
+	app.tokenDegradation = state
+	if policy, ok := config.Environments[app.Env()]; ok && policy != "" {
+		state.policy = policy
+	} else if config.Policy != "" {
+		state.policy = config.Policy
+	}
+	if config.AlertInterval != "" {
+		if interval, err := time.ParseDuration(config.AlertInterval); err == nil && interval > 0 {
+			state.alertInterval = interval
+		}
+	}
+
+	if !app.cfg.ModConfig.Token.Validation.Enabled {
+		return
+	}
+	start := time.Now()
+	switch state.policy {
+	case TokenCacheFailOpen, TokenCacheFailClosed:
+	case TokenCacheLocalFallback:
+		size := config.FallbackSize
+		if size <= 0 {
+			size = 10000
+		}
+		ttl := 5 * time.Minute
+		if config.FallbackTTL != "" {
+			if d, err := time.ParseDuration(config.FallbackTTL); err == nil && d > 0 {
+				ttl = d
+			}
+		}
+		state.fallback = newTokenFallbackCache(size, ttl, app.now)
+	default:
+		err := fmt.Errorf("unknown token degradation policy %q", state.policy)
+		app.recordStartup("token.degradation", state.policy, start, err)
+		state.policy = TokenCacheFailOpen
+		return
+	}
+	app.recordStartup("token.degradation", state.policy, start, nil)
+}
+
+// OnTokenCacheDegraded 注册Token缓存故障告警回调，故障期间按 token.validation.degradation.alert_interval 限频调用
+func (app *App) OnTokenCacheDegraded(hook func(TokenCacheDegradation)) {
+	app.tokenDegradation.mu.Lock()
+	defer app.tokenDegradation.mu.Unlock()
+	app.tokenDegradation.hooks = append(app.tokenDegradation.hooks, hook)
+}
+
+// TokenCachePolicy 返回当前运行环境生效的Token缓存故障处理策略
+func (app *App) TokenCachePolicy() string {
+	return app.tokenDegradation.policy
+}
+
+// isTokenCacheOutage 判断查询错误是否为缓存故障（而非Token不存在或未配置缓存）
+func isTokenCacheOutage(err error) bool {
+	return err != nil && !errors.Is(err, ErrTokenNotFound) && !errors.Is(err, errNoTokenStore)
+}
+
+// fetchTokenData 查询Token缓存并按故障处理策略处理查询错误：
+// local_fallback 策略下记录验证成功的Token数据，缓存故障时使用本地数据
+func (app *App) fetchTokenData(token string) ([]byte, error) {
+	raw, err := app.GetTokenData(token)
+	app.recordTokenLookup(err)
+
+	state := app.tokenDegradation
+	switch {
+	case err == nil:
+		state.recovered(app)
+		if state.fallback != nil {
+			state.fallback.put(token, raw)
+		}
+		return raw, nil
+	case !isTokenCacheOutage(err):
+		if state.fallback != nil && errors.Is(err, ErrTokenNotFound) {
+			state.fallback.remove(token)
+		}
+		return raw, err
+	}
+
+	cause, outcome := err, "denied"
+	switch state.policy {
+	case TokenCacheFailOpen:
+		outcome = "allowed"
+	case TokenCacheLocalFallback:
+		if data, ok := state.fallback.get(token); ok {
+			raw, err, outcome = data, nil, "fallback"
+		}
+	}
+	app.recordTokenDegradation(state.policy, outcome)
+	state.alert(app, cause)
+	return raw, err
+}
+
+// alert 记录缓存故障，距上次告警超过 alert_interval 时记录错误日志并调用告警回调
+func (s *tokenDegradationState) alert(app *App, err error) {
+	s.mu.Lock()
+	s.degraded = true
+	s.pending++
+	now := time.Now()
+	if now.Sub(s.lastAlert) < s.alertInterval {
+		s.mu.Unlock()
+		return
+	}
+	s.lastAlert = now
+	event := TokenCacheDegradation{
+		Env:      app.Env(),
+		Policy:   s.policy,
+		Strategy: app.cfg.ModConfig.Token.Validation.CacheStrategy,
+		Error:    err.Error(),
+		Count:    s.pending,
+		Time:     now,
+	}
+	s.pending = 0
+	hooks := append([]func(TokenCacheDegradation){}, s.hooks...)
+	s.mu.Unlock()
+
+	app.logger.WithFields(logrus.Fields{
+		"env":      event.Env,
+		"policy":   event.Policy,
+		"strategy": event.Strategy,
+		"count":    event.Count,
+		"error":    event.Error,
+	}).Error("Token cache unavailable, token validation degraded")
+	for _, hook := range hooks {
+		hook(event)
+	}
+}
+
+// recovered 缓存查询恢复正常时记录日志
+func (s *tokenDegradationState) recovered(app *App) {
+	s.mu.Lock()
+	if !s.degraded {
+		s.mu.Unlock()
+		return
+	}
+	s.degraded = false
+	s.lastAlert = time.Time{}
+	s.pending = 0
+	s.mu.Unlock()
+	app.logger.WithField("policy", s.policy).Info("Token cache recovered")
+}
+
+// forgetTokens 从本地回退缓存中删除 token，在写入或删除Token缓存时调用
+func (app *App) forgetTokens(tokens ...string) {
+	if app.tokenDegradation == nil || app.tokenDegradation.fallback == nil {
+		return
+	}
+	for _, token := range tokens {
+		app.tokenDegradation.fallback.remove(token)
+	}
+}
+
+// forgetTokensByPrefix 从本地回退缓存中删除以 prefix 开头的 token
+func (app *App) forgetTokensByPrefix(prefix string) {
+	if app.tokenDegradation == nil || app.tokenDegradation.fallback == nil {
+		return
+	}
+	app.tokenDegradation.fallback.removePrefix(prefix)
+}
+
+// invalidTokenReply 返回Token验证失败的错误响应：缓存故障导致的拒绝返回 503，避免客户端将其视为登录失效
+func (app *App) invalidTokenReply(c *fiber.Ctx, token string) error {
+	if isTokenCacheOutage(app.lookupToken(c, token).err) {
+		return Reply(503, "Token validation unavailable")
+	}
+	return Reply(401, "Invalid token")
+}
+
+// tokenFallbackCache 最近验证成功的Token数据（LRU），缓存故障时用于 local_fallback 策略
+type tokenFallbackCache struct {
+	mu      sync.Mutex
+	size    int
+	ttl     time.Duration
+	now     func() time.Time // 应用时钟
+	order   *list.List       // 最近使用的在前
+	entries map[string]*list.Element
+}
+
+// tokenFallbackEntry 本地回退缓存条目
+type tokenFallbackEntry struct {
+	token   string
+	data    []byte
+	expires time.Time
+}
+
+func newTokenFallbackCache(size int, ttl time.Duration, now func() time.Time) *tokenFallbackCache {
+	return &tokenFallbackCache{size: size, ttl: ttl, now: now, order: list.New(), entries: map[string]*list.Element{}}
+}
+
+// put 记录 token 的数据，超过容量时淘汰最久未使用的条目
+func (c *tokenFallbackCache) put(token string, data []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := &tokenFallbackEntry{token: token, data: data, expires: c.now().Add(c.ttl)}
+	if el, ok := c.entries[token]; ok {
+		el.Value = entry
+		c.order.MoveToFront(el)
+		return
+	}
+	c.entries[token] = c.order.PushFront(entry)
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*tokenFallbackEntry).token)
+	}
+}
+
+// get 返回未过期的 token 数据
+func (c *tokenFallbackCache) get(token string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[token]
+	if !ok {
+		return nil, false
+	}
+	entry := el.Value.(*tokenFallbackEntry)
+	if c.now().After(entry.expires) {
+		c.order.Remove(el)
+		delete(c.entries, token)
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return entry.data, true
+}
+
+func (c *tokenFallbackCache) remove(token string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[token]; ok {
+		c.order.Remove(el)
+		delete(c.entries, token)
+	}
+}
+
+func (c *tokenFallbackCache) removePrefix(prefix string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for token, el := range c.entries {
+		if strings.HasPrefix(token, prefix) {
+			c.order.Remove(el)
+			delete(c.entries, token)
+		}
+	}
+}
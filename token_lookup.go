@@ -0,0 +1,165 @@
+package mod
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"sync"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrTokenNotFound Token不存在或已过期
+var ErrTokenNotFound = errors.New("token not found")
+
+// errNoTokenStore 配置的缓存策略没有可用的存储
+var errNoTokenStore = errors.New("no valid cache strategy configured for token data retrieval")
+
+// tokenLookupsKey 本次请求的Token缓存查询结果在 Fiber locals 中的缓存键
+type tokenLookupsKey struct{}
+
+// tokenLookups 本次请求中各 token 的查询结果，认证、权限、权限范围和当前用户共用，每个 token 只访问一次缓存
+type tokenLookups struct {
+	mu      sync.Mutex
+	entries map[string]*tokenLookup
+}
+
+// tokenLookup 一个 token 的查询结果，数据在首次需要时解析；同一请求中的多个 goroutine 可能同时读取
+type tokenLookup struct {
+	raw       []byte
+	err       error
+	parseOnce sync.Once
+	data      map[string]any
+	parseErr  error
+
+	mu          sync.Mutex      // 保护 permissions
+	permissions map[string]bool // 权限规则（JSON）-> 判定结果
+}
+
+// lookupToken 返回 token 在本次请求中的查询结果，首次调用时查询Token缓存；c 为 nil 时不缓存
+func (app *App) lookupToken(c *fiber.Ctx, token string) *tokenLookup {
+	if c == nil {
+		raw, err := app.fetchTokenData(token)
+		return &tokenLookup{raw: raw, err: err}
+	}
+	lookups, ok := c.Locals(tokenLookupsKey{}).(*tokenLookups)
+	if !ok {
+		lookups = &tokenLookups{entries: map[string]*tokenLookup{}}
+		c.Locals(tokenLookupsKey{}, lookups)
+	}
+	lookups.mu.Lock()
+	defer lookups.mu.Unlock()
+	if lookup, ok := lookups.entries[token]; ok {
+		return lookup
+	}
+	raw, err := app.fetchTokenData(token)
+	lookup := &tokenLookup{raw: raw, err: err}
+	lookups.entries[token] = lookup
+	return lookup
+}
+
+// tokenData 返回 token 的缓存数据，同一请求内只查询一次
+func (app *App) tokenData(c *fiber.Ctx, token string) ([]byte, error) {
+	lookup := app.lookupToken(c, token)
+	return lookup.raw, lookup.err
+}
+
+// tokenDataMap 返回解析为对象的 token 缓存数据，数字保留为 json.Number；结果为只读，调用方不应修改
+func (app *App) tokenDataMap(c *fiber.Ctx, token string) (map[string]any, error) {
+	lookup := app.lookupToken(c, token)
+	if lookup.err != nil {
+		return nil, lookup.err
+	}
+	lookup.parseOnce.Do(func() {
+		decoder := json.NewDecoder(bytes.NewReader(lookup.raw))
+		decoder.UseNumber()
+		if err := decoder.Decode(&lookup.data); err != nil {
+			lookup.data, lookup.parseErr = nil, err
+		}
+	})
+	return lookup.data, lookup.parseErr
+}
+
+// validateToken 验证 token 是否存在于Token缓存中，查询结果在本次请求内复用；
+// 缓存查询出错时按 token.validation.degradation 的策略处理，fail_open 时允许通过
+func (app *App) validateToken(c *fiber.Ctx, token string) bool {
+	// 如果没有配置 token 验证，或者验证被禁用，则跳过验证
+	if app.cfg.ModConfig == nil || !app.cfg.ModConfig.Token.Validation.Enabled {
+		return true
+	}
+	if token == "" {
+		return false
+	}
+
+	config := app.cfg.ModConfig.Token.Validation
+	fields := logrus.Fields{
+		"token":          token,
+		"cache_key":      config.CacheKeyPrefix + token,
+		"cache_strategy": config.CacheStrategy,
+	}
+	_, err := app.tokenData(c, token)
+	switch {
+	case err == nil:
+		app.logger.WithFields(fields).Debug("Token validated successfully")
+		return true
+	case errors.Is(err, ErrTokenNotFound):
+		app.logger.WithFields(fields).Debug("Token not found in cache")
+		return false
+	case errors.Is(err, errNoTokenStore):
+		app.logger.WithFields(fields).Warn("Token validation failed: no valid cache strategy configured")
+		return false
+	default:
+		fields["error"] = err.Error()
+		fields["policy"] = app.tokenDegradation.policy
+		if app.tokenDegradation.policy == TokenCacheFailOpen {
+			app.logger.WithFields(fields).Warn("Token cache query error, allowing token validation to pass")
+			return true
+		}
+		app.logger.WithFields(fields).Warn("Token cache query error, rejecting token")
+		return false
+	}
+}
+
+// TokenData 返回当前请求的 token 在Token缓存中的数据；认证、权限检查和 User() 共用查询结果，同一请求内只访问一次缓存。
+// 本次请求中修改了Token数据时，使用 app.GetTokenData 读取最新数据
+func (c *Context) TokenData() ([]byte, error) {
+	token := parseToken(c.Ctx, c.app.tokenKeys)
+	if token == "" {
+		return nil, ErrTokenNotFound
+	}
+	return c.app.tokenData(c.Ctx, token)
+}
+
+// CheckPermission 按权限规则检查当前请求的 token，规则与 Service.Permission 相同，判定结果在本次请求内缓存
+func (c *Context) CheckPermission(permission *PermissionConfig) bool {
+	return c.checkPermission(parseToken(c.Ctx, c.app.tokenKeys), permission)
+}
+
+func (c *Context) checkPermission(token string, permission *PermissionConfig) bool {
+	if permission == nil || len(permission.Rules) == 0 {
+		return true
+	}
+	data, err := c.app.tokenDataMap(c.Ctx, token)
+	if err != nil {
+		c.app.logger.WithField("error", err.Error()).Debug("Failed to get token data for permission check")
+		return false
+	}
+
+	lookup := c.app.lookupToken(c.Ctx, token)
+	key, _ := json.Marshal(permission)
+	lookup.mu.Lock()
+	allowed, ok := lookup.permissions[string(key)]
+	lookup.mu.Unlock()
+	if ok {
+		return allowed
+	}
+	allowed = c.app.evaluatePermission(data, permission)
+	lookup.mu.Lock()
+	if lookup.permissions == nil {
+		lookup.permissions = map[string]bool{}
+	}
+	lookup.permissions[string(key)] = allowed
+	lookup.mu.Unlock()
+	return allowed
+}
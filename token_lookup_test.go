@@ -0,0 +1,54 @@
+package mod
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestTokenLookupConcurrentAccess(t *testing.T) {
+	app := newTestApp(t, tokenCacheConfig)
+	if err := app.SetToken("admin-token", map[string]any{"role": "admin", "scope": "reports"}); err != nil {
+		t.Fatal(err)
+	}
+	permission := &PermissionConfig{Rules: []PermissionRule{{Field: "role", Operator: "eq", Value: "admin"}}}
+	err := app.Register(Service{
+		Name:        "concurrent_lookup",
+		DisplayName: "concurrent_lookup",
+		Handler: MakeHandler(func(ctx *Context, req *pingRequest, resp *pingResponse) error {
+			token := parseToken(ctx.Ctx, app.tokenKeys)
+			var wg sync.WaitGroup
+			results := make([]bool, 16)
+			for i := range results {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					data, err := app.tokenDataMap(ctx.Ctx, token)
+					results[i] = err == nil && data["role"] == "admin" && ctx.checkPermission(token, permission)
+				}(i)
+			}
+			wg.Wait()
+			for _, ok := range results {
+				if !ok {
+					return Reply(500, "inconsistent token lookup")
+				}
+			}
+			resp.Value = req.Value
+			return nil
+		}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := app.TestClient().WithToken("admin-token").Call("concurrent_lookup", pingRequest{Value: "x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.AssertStatus(t, 200).AssertSuccess(t)
+
+	resp, err = app.TestClient().WithToken("bogus").Call("concurrent_lookup", pingRequest{Value: "x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.AssertStatus(t, 401)
+}
@@ -0,0 +1,484 @@
+package mod
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/allegro/bigcache/v3"
+	"github.com/dgraph-io/badger/v4"
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+)
+
+// TokenLoader 从数据库等持久化存储中读取有效的会话，返回 token -> 写入Token缓存的数据
+type TokenLoader func(ctx context.Context) (map[string]any, error)
+
+// defaultTokenListLimit ListTokens 未指定数量时最多返回的 token 数
+const defaultTokenListLimit = 100
+
+// TokenInfo Token缓存中的一个 token
+type TokenInfo struct {
+	Token     string     `json:"token" desc:"token（不含缓存键前缀）"`
+	Data      any        `json:"data" desc:"写入Token缓存的数据，无数据时为 1"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty" desc:"过期时间，不过期时为空"`
+	TTL       string     `json:"ttl,omitempty" desc:"剩余有效期，如 23h59m10s"`
+}
+
+// newTokenInfo 解析缓存中的数据和过期时间，数据不是 JSON 时按字符串返回
+func newTokenInfo(token string, value []byte, expiresAt time.Time) TokenInfo {
+	info := TokenInfo{Token: token}
+	if json.Unmarshal(value, &info.Data) != nil {
+		info.Data = string(value)
+	}
+	if !expiresAt.IsZero() {
+		info.ExpiresAt = &expiresAt
+		info.TTL = time.Until(expiresAt).Round(time.Second).String()
+	}
+	return info
+}
+
+// bigCacheLifeWindow 返回 BigCache 的 life_window，无效时与初始化时一样使用24h
+func (app *App) bigCacheLifeWindow() time.Duration {
+	lifeWindow, err := time.ParseDuration(app.cfg.ModConfig.Cache.BigCache.LifeWindow)
+	if err != nil {
+		return 24 * time.Hour
+	}
+	return lifeWindow
+}
+
+// tokenCacheValue 序列化写入Token缓存的数据，与 SetToken 一致：nil 存储简单标记
+func tokenCacheValue(data any) ([]byte, error) {
+	if data == nil {
+		return []byte("1"), nil
+	}
+	value, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal token data: %w", err)
+	}
+	return value, nil
+}
+
+// cacheTTL 解析缓存的 TTL 配置，为空或无效时使用24h
+func (app *App) cacheTTL(value, name string) time.Duration {
+	if value == "" {
+		return 24 * time.Hour
+	}
+	ttl, err := time.ParseDuration(value)
+	if err != nil {
+		app.logger.WithError(err).Warnf("Invalid %s TTL, using default 24h", name)
+		return 24 * time.Hour
+	}
+	return ttl
+}
+
+// SetTokens 批量将 token 写入缓存（token -> 数据），Redis 使用管道、BadgerDB 使用批量写入，
+// 用于从持久化存储恢复会话或批量签发 API Key
+func (app *App) SetTokens(tokens map[string]any) error {
+	if app.cfg.ModConfig == nil || !app.cfg.ModConfig.Token.Validation.Enabled || len(tokens) == 0 {
+		return nil
+	}
+
+	config := app.cfg.ModConfig.Token.Validation
+	values := make(map[string][]byte, len(tokens))
+	for token, data := range tokens {
+		app.forgetTokens(token)
+		value, err := tokenCacheValue(data)
+		if err != nil {
+			return fmt.Errorf("token %q: %w", token, err)
+		}
+		values[config.CacheKeyPrefix+token] = value
+	}
+
+	var err error
+	switch config.CacheStrategy {
+	case "bigcache":
+		if app.tokenCache == nil {
+			return fmt.Errorf("no valid cache strategy configured for token storage")
+		}
+		for key, value := range values {
+			if err = app.tokenCache.Set(key, value); err != nil {
+				err = fmt.Errorf("failed to set tokens in BigCache: %w", err)
+				break
+			}
+		}
+	case "badger":
+		if app.badgerDB == nil {
+			return fmt.Errorf("no valid cache strategy configured for token storage")
+		}
+		ttl := app.cacheTTL(app.cfg.ModConfig.Cache.Badger.TTL, "BadgerDB")
+		batch := app.badgerDB.NewWriteBatch()
+		defer batch.Cancel()
+		for key, value := range values {
+			if err = batch.SetEntry(badger.NewEntry([]byte(key), value).WithTTL(ttl)); err != nil {
+				break
+			}
+		}
+		if err == nil {
+			err = batch.Flush()
+		}
+		if err != nil {
+			err = fmt.Errorf("failed to set tokens in BadgerDB: %w", err)
+		}
+	case "redis":
+		if app.redisClient == nil {
+			return fmt.Errorf("no valid cache strategy configured for token storage")
+		}
+		ttl := app.cacheTTL(app.cfg.ModConfig.Cache.Redis.TTL, "Redis")
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		pipe := app.redisClient.Pipeline()
+		for key, value := range values {
+			pipe.Set(ctx, key, value, ttl)
+		}
+		if _, err = pipe.Exec(ctx); err != nil {
+			err = fmt.Errorf("failed to set tokens in Redis: %w", err)
+		}
+	default:
+		return fmt.Errorf("no valid cache strategy configured for token storage")
+	}
+	if err != nil {
+		app.logger.WithFields(logrus.Fields{"count": len(tokens), "error": err.Error()}).Error("Failed to set tokens")
+		return err
+	}
+
+	app.logger.WithFields(logrus.Fields{
+		"count":          len(tokens),
+		"cache_strategy": config.CacheStrategy,
+	}).Debug("Tokens set successfully")
+	return nil
+}
+
+// RemoveTokensByPrefix 删除以 prefix 开头的全部 token（不含缓存键前缀），返回删除的数量，
+// 如 token 按 "<用户ID>:" 开头生成时可用于强制用户下线；prefix 为空时清空全部 token
+func (app *App) RemoveTokensByPrefix(prefix string) (int, error) {
+	if app.cfg.ModConfig == nil || !app.cfg.ModConfig.Token.Validation.Enabled {
+		return 0, nil
+	}
+
+	app.forgetTokensByPrefix(prefix)
+	config := app.cfg.ModConfig.Token.Validation
+	keyPrefix := config.CacheKeyPrefix + prefix
+	removed := 0
+	var err error
+	switch config.CacheStrategy {
+	case "bigcache":
+		if app.tokenCache == nil {
+			return 0, fmt.Errorf("no valid cache strategy configured for token removal")
+		}
+		var keys []string
+		it := app.tokenCache.Iterator()
+		for it.SetNext() {
+			entry, iterErr := it.Value()
+			if iterErr != nil {
+				continue
+			}
+			if strings.HasPrefix(entry.Key(), keyPrefix) {
+				keys = append(keys, entry.Key())
+			}
+		}
+		for _, key := range keys {
+			if app.tokenCache.Delete(key) == nil {
+				removed++
+			}
+		}
+	case "badger":
+		if app.badgerDB == nil {
+			return 0, fmt.Errorf("no valid cache strategy configured for token removal")
+		}
+		var keys [][]byte
+		err = app.badgerDB.View(func(txn *badger.Txn) error {
+			opts := badger.DefaultIteratorOptions
+			opts.PrefetchValues = false
+			opts.Prefix = []byte(keyPrefix)
+			it := txn.NewIterator(opts)
+			defer it.Close()
+			for it.Rewind(); it.Valid(); it.Next() {
+				keys = append(keys, it.Item().KeyCopy(nil))
+			}
+			return nil
+		})
+		if err != nil {
+			break
+		}
+		batch := app.badgerDB.NewWriteBatch()
+		defer batch.Cancel()
+		for _, key := range keys {
+			if err = batch.Delete(key); err != nil {
+				break
+			}
+		}
+		if err == nil {
+			if err = batch.Flush(); err == nil {
+				removed = len(keys)
+			}
+		}
+	case "redis":
+		if app.redisClient == nil {
+			return 0, fmt.Errorf("no valid cache strategy configured for token removal")
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		removed, err = app.unlinkRedisKeys(ctx, escapeRedisPattern(keyPrefix)+"*")
+	default:
+		return 0, fmt.Errorf("no valid cache strategy configured for token removal")
+	}
+	if err != nil {
+		app.logger.WithFields(logrus.Fields{"prefix": prefix, "removed": removed, "error": err.Error()}).Error("Failed to remove tokens by prefix")
+		return removed, fmt.Errorf("failed to remove tokens by prefix: %w", err)
+	}
+
+	app.logger.WithFields(logrus.Fields{
+		"prefix":  prefix,
+		"removed": removed,
+	}).Info("Tokens removed by prefix")
+	return removed, nil
+}
+
+// ListTokens 返回以 prefix 开头的 token（不含缓存键前缀）及其数据和过期时间，最多返回 limit 个，limit 不大于0时最多返回100个；
+// 已过期但尚未被清理的 token 不返回。BigCache 和 Redis 不保证返回顺序，用于在管理后台或排查问题时查看Token缓存，无需连接 Redis/BadgerDB
+func (app *App) ListTokens(prefix string, limit int) ([]TokenInfo, error) {
+	if app.cfg.ModConfig == nil || !app.cfg.ModConfig.Token.Validation.Enabled {
+		return nil, fmt.Errorf("token validation not enabled")
+	}
+	if limit <= 0 {
+		limit = defaultTokenListLimit
+	}
+
+	config := app.cfg.ModConfig.Token.Validation
+	keyPrefix := config.CacheKeyPrefix + prefix
+	tokens := []TokenInfo{}
+	switch config.CacheStrategy {
+	case "bigcache":
+		if app.tokenCache == nil {
+			return nil, errNoTokenStore
+		}
+		// BigCache 的条目从写入时开始计算 life_window
+		lifeWindow := app.bigCacheLifeWindow()
+		it := app.tokenCache.Iterator()
+		for len(tokens) < limit && it.SetNext() {
+			entry, err := it.Value()
+			if err != nil || !strings.HasPrefix(entry.Key(), keyPrefix) {
+				continue
+			}
+			expiresAt := time.Unix(int64(entry.Timestamp()), 0).Add(lifeWindow)
+			if time.Now().After(expiresAt) {
+				continue
+			}
+			tokens = append(tokens, newTokenInfo(strings.TrimPrefix(entry.Key(), config.CacheKeyPrefix), entry.Value(), expiresAt))
+		}
+	case "badger":
+		if app.badgerDB == nil {
+			return nil, errNoTokenStore
+		}
+		err := app.badgerDB.View(func(txn *badger.Txn) error {
+			opts := badger.DefaultIteratorOptions
+			opts.Prefix = []byte(keyPrefix)
+			it := txn.NewIterator(opts)
+			defer it.Close()
+			for it.Rewind(); it.Valid() && len(tokens) < limit; it.Next() {
+				item := it.Item()
+				value, err := item.ValueCopy(nil)
+				if err != nil {
+					return err
+				}
+				var expiresAt time.Time
+				if item.ExpiresAt() > 0 {
+					expiresAt = time.Unix(int64(item.ExpiresAt()), 0)
+				}
+				tokens = append(tokens, newTokenInfo(strings.TrimPrefix(string(item.Key()), config.CacheKeyPrefix), value, expiresAt))
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list tokens from BadgerDB: %w", err)
+		}
+	case "redis":
+		if app.redisClient == nil {
+			return nil, errNoTokenStore
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		var keys []string
+		iter := app.redisClient.Scan(ctx, 0, escapeRedisPattern(keyPrefix)+"*", 1000).Iterator()
+		for len(keys) < limit && iter.Next(ctx) {
+			keys = append(keys, iter.Val())
+		}
+		if err := iter.Err(); err != nil {
+			return nil, fmt.Errorf("failed to list tokens from Redis: %w", err)
+		}
+		if len(keys) == 0 {
+			break
+		}
+		pipe := app.redisClient.Pipeline()
+		values := make([]*redis.StringCmd, len(keys))
+		ttls := make([]*redis.DurationCmd, len(keys))
+		for i, key := range keys {
+			values[i] = pipe.Get(ctx, key)
+			ttls[i] = pipe.PTTL(ctx, key)
+		}
+		if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+			return nil, fmt.Errorf("failed to list tokens from Redis: %w", err)
+		}
+		now := time.Now()
+		for i, key := range keys {
+			value, err := values[i].Bytes()
+			if err != nil {
+				// 扫描后被删除或已过期
+				continue
+			}
+			var expiresAt time.Time
+			if ttl := ttls[i].Val(); ttl > 0 {
+				expiresAt = now.Add(ttl)
+			}
+			tokens = append(tokens, newTokenInfo(strings.TrimPrefix(key, config.CacheKeyPrefix), value, expiresAt))
+		}
+	default:
+		return nil, errNoTokenStore
+	}
+	return tokens, nil
+}
+
+// TouchToken 重新开始计算 token 的有效期（Redis、BadgerDB 的 TTL 和 BigCache 的 life_window），数据不变，
+// 用于会话的滑动过期或在管理后台延长指定会话；token 不存在或已过期时返回 ErrTokenNotFound
+func (app *App) TouchToken(token string) error {
+	if app.cfg.ModConfig == nil || !app.cfg.ModConfig.Token.Validation.Enabled {
+		return fmt.Errorf("token validation not enabled")
+	}
+
+	config := app.cfg.ModConfig.Token.Validation
+	cacheKey := config.CacheKeyPrefix + token
+	var err error
+	switch config.CacheStrategy {
+	case "bigcache":
+		if app.tokenCache == nil {
+			return errNoTokenStore
+		}
+		// BigCache 没有单独设置过期时间的接口，重新写入时更新写入时间；超过 life_window 尚未清理的条目不再续期
+		value, resp, getErr := app.tokenCache.GetWithInfo(cacheKey)
+		if getErr == bigcache.ErrEntryNotFound || resp.EntryStatus == bigcache.Expired {
+			return ErrTokenNotFound
+		}
+		if err = getErr; err == nil {
+			err = app.tokenCache.Set(cacheKey, value)
+		}
+	case "badger":
+		if app.badgerDB == nil {
+			return errNoTokenStore
+		}
+		ttl := app.cacheTTL(app.cfg.ModConfig.Cache.Badger.TTL, "BadgerDB")
+		err = app.badgerDB.Update(func(txn *badger.Txn) error {
+			item, err := txn.Get([]byte(cacheKey))
+			if err != nil {
+				return err
+			}
+			value, err := item.ValueCopy(nil)
+			if err != nil {
+				return err
+			}
+			return txn.SetEntry(badger.NewEntry([]byte(cacheKey), value).WithTTL(ttl))
+		})
+		if err == badger.ErrKeyNotFound {
+			return ErrTokenNotFound
+		}
+	case "redis":
+		if app.redisClient == nil {
+			return errNoTokenStore
+		}
+		ttl := app.cacheTTL(app.cfg.ModConfig.Cache.Redis.TTL, "Redis")
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancel()
+		var found bool
+		if found, err = app.redisClient.Expire(ctx, cacheKey, ttl).Result(); err == nil && !found {
+			return ErrTokenNotFound
+		}
+	default:
+		return errNoTokenStore
+	}
+	if err != nil {
+		app.logger.WithFields(logrus.Fields{"token": token, "error": err.Error()}).Error("Failed to touch token")
+		return fmt.Errorf("failed to touch token: %w", err)
+	}
+
+	app.logger.WithFields(logrus.Fields{
+		"token":          token,
+		"cache_strategy": config.CacheStrategy,
+	}).Debug("Token touched")
+	return nil
+}
+
+// unlinkRedisKeys 删除匹配 pattern 的 Redis 键，返回删除的键数；使用 SCAN 分批查找，避免 KEYS 阻塞 Redis
+func (app *App) unlinkRedisKeys(ctx context.Context, pattern string) (int, error) {
+	iter := app.redisClient.Scan(ctx, 0, pattern, 1000).Iterator()
+	removed := 0
+	var keys []string
+	flush := func() error {
+		if len(keys) == 0 {
+			return nil
+		}
+		n, err := app.redisClient.Unlink(ctx, keys...).Result()
+		removed += int(n)
+		keys = keys[:0]
+		return err
+	}
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+		if len(keys) >= 1000 {
+			if err := flush(); err != nil {
+				return removed, err
+			}
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return removed, err
+	}
+	err := flush()
+	return removed, err
+}
+
+// escapeRedisPattern 转义 Redis glob 模式中的特殊字符
+func escapeRedisPattern(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch r {
+		case '*', '?', '[', ']', '\\':
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// WarmUpTokens 使用 loader 从持久化存储读取有效会话并写入 BigCache，避免使用进程内缓存时重启服务导致所有用户被登出；
+// 在 New 之后、开始监听之前调用，结果记录在启动报告中（token.warmup）。Token缓存为 Redis、BadgerDB 时数据已持久化，跳过预热。
+// 预热的 token 重新开始计算 cache.bigcache.life_window，loader 应只返回仍然有效的会话
+func (app *App) WarmUpTokens(loader TokenLoader) error {
+	config := app.cfg.ModConfig.Token.Validation
+	if !config.Enabled {
+		return nil
+	}
+	if config.CacheStrategy != "bigcache" {
+		app.logger.WithField("cache_strategy", config.CacheStrategy).Debug("Token cache is persistent, warm-up skipped")
+		return nil
+	}
+
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	tokens, err := loader(ctx)
+	if err == nil {
+		err = app.SetTokens(tokens)
+	}
+	app.recordStartup("token.warmup", "bigcache", start, err)
+	if err != nil {
+		app.logger.WithError(err).Error("Token cache warm-up failed")
+		return err
+	}
+	app.logger.WithFields(logrus.Fields{
+		"count":    len(tokens),
+		"duration": time.Since(start).String(),
+	}).Info("Token cache warmed up")
+	return nil
+}
@@ -151,3 +151,16 @@ func parseFloat(value string) (float64, error) {
 func parseBool(value string) (bool, error) {
 	return strconv.ParseBool(value)
 }
+
+// parseDurationWithDays 解析时长字符串，在 time.ParseDuration 基础上支持天（如 7d）
+func parseDurationWithDays(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if strings.HasSuffix(value, "d") {
+		days, err := strconv.ParseFloat(strings.TrimSuffix(value, "d"), 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration: %s", value)
+		}
+		return time.Duration(days * float64(24*time.Hour)), nil
+	}
+	return time.ParseDuration(value)
+}
@@ -0,0 +1,188 @@
+package mod
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"sort"
+
+	"github.com/sirupsen/logrus"
+	"github.com/valyala/fasthttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/codes"
+	otelpropagation "go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName 框架创建 span 使用的 Tracer 名称
+const tracerName = "github.com/iamdanielyin/mod"
+
+// tracePropagator 解析和透传 W3C traceparent、tracestate 和 baggage 请求头
+var tracePropagator = otelpropagation.NewCompositeTextMapPropagator(otelpropagation.TraceContext{}, otelpropagation.Baggage{})
+
+// ServiceTrace 服务的链路追踪配置，使链路可以按订单号、租户等业务标识检索，无需在每个处理函数中手动设置
+type ServiceTrace struct {
+	Attributes map[string]string `yaml:"attributes" json:"attributes,omitempty"` // 固定的 span 属性，如 domain: billing
+
+	// 记录为 span 属性并写入 baggage 的请求字段（json 字段名或结构体字段名），如 order_id、tenant_id；
+	// @user 为当前用户ID（enduser.id）。baggage 随 ctx.HTTP() 的外部请求传递给下游服务
+	Fields []string `yaml:"fields" json:"fields,omitempty"`
+}
+
+// tracePolicy 注册时解析的链路追踪配置
+type tracePolicy struct {
+	attributes []attribute.KeyValue
+	fields     []traceField
+}
+
+// traceField 写入 span 属性和 baggage 的请求字段
+type traceField struct {
+	key     string
+	field   []int // 请求结构体中的字段索引
+	user    bool
+	baggage bool // 字段名可以作为 baggage 的键
+}
+
+// tracePolicy 解析服务生效的链路追踪配置，tracing.services 优先于 Service.Trace；未配置时返回 nil
+func (app *App) tracePolicy(svc *Service) *tracePolicy {
+	config := svc.Trace
+	if rule, ok := app.cfg.ModConfig.Tracing.Services[svc.Name]; ok {
+		config = &rule
+	}
+	if config == nil || (len(config.Attributes) == 0 && len(config.Fields) == 0) {
+		return nil
+	}
+
+	policy := &tracePolicy{}
+	keys := make([]string, 0, len(config.Attributes))
+	for key := range config.Attributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		policy.attributes = append(policy.attributes, attribute.String(key, config.Attributes[key]))
+	}
+
+	for _, name := range config.Fields {
+		field := traceField{key: name}
+		if name == "@user" {
+			field.key, field.user = "enduser.id", true
+		} else if field.field = cacheKeyField(svc.Handler.InputType, name); field.field == nil {
+			app.logger.WithFields(logrus.Fields{"service": svc.Name, "field": name}).Warn("Trace field not found in request, field is ignored")
+			continue
+		}
+		if _, err := baggage.NewMemberRaw(field.key, ""); err == nil {
+			field.baggage = true
+		} else {
+			app.logger.WithFields(logrus.Fields{"service": svc.Name, "field": name}).Warn("Trace field is not a valid baggage key, recorded as span attribute only")
+		}
+		policy.fields = append(policy.fields, field)
+	}
+	return policy
+}
+
+// startServiceSpan 启用 tracing 时为服务调用创建 span，父 span 为请求上下文中已有的 span 或请求头中的 traceparent；
+// 服务的固定属性写入当前 span。返回的函数在响应完成后记录状态码并结束 span
+func (app *App) startServiceSpan(ctx *Context, svc *Service) func() {
+	if !app.cfg.ModConfig.Tracing.Enabled {
+		if svc.tracePolicy != nil && len(svc.tracePolicy.attributes) > 0 {
+			trace.SpanFromContext(ctx.UserContext()).SetAttributes(svc.tracePolicy.attributes...)
+		}
+		return func() {}
+	}
+
+	uc := ctx.UserContext()
+	if !trace.SpanContextFromContext(uc).IsValid() {
+		uc = tracePropagator.Extract(uc, requestHeaderCarrier{&ctx.Request().Header})
+	}
+	attrs := []attribute.KeyValue{
+		attribute.String("mod.service", svc.Name),
+		attribute.String("mod.request_id", ctx.GetRequestID()),
+		attribute.String("http.request.method", ctx.Method()),
+		attribute.String("http.route", svc.path),
+	}
+	if svc.Group != "" {
+		attrs = append(attrs, attribute.String("mod.group", svc.Group))
+	}
+	if id := ctx.JobID(); id != "" {
+		attrs = append(attrs, attribute.String("mod.job_id", id))
+	}
+	if svc.tracePolicy != nil {
+		attrs = append(attrs, svc.tracePolicy.attributes...)
+	}
+	uc, span := otel.GetTracerProvider().Tracer(tracerName).Start(uc, svc.Name,
+		trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(attrs...))
+	ctx.SetUserContext(uc)
+
+	return func() {
+		status := ctx.Response().StatusCode()
+		span.SetAttributes(attribute.Int("http.response.status_code", status))
+		if status >= 500 {
+			span.SetStatus(codes.Error, "")
+		}
+		span.End()
+	}
+}
+
+// annotateSpan 参数校验通过后将配置的请求字段写入当前 span 的属性和请求上下文的 baggage，空值不写入
+func (app *App) annotateSpan(ctx *Context, svc *Service, in any) {
+	policy := svc.tracePolicy
+	if policy == nil || len(policy.fields) == 0 {
+		return
+	}
+	uc := ctx.UserContext()
+	bag := baggage.FromContext(uc)
+	var attrs []attribute.KeyValue
+	for _, field := range policy.fields {
+		var value string
+		if field.user {
+			if user, ok := ctx.User(); ok {
+				value = user.ID
+			}
+		} else {
+			value = cacheKeyValue(reflect.ValueOf(in), field.field)
+		}
+		if value == "" {
+			continue
+		}
+		attrs = append(attrs, attribute.String(field.key, value))
+		if !field.baggage {
+			continue
+		}
+		if member, err := baggage.NewMemberRaw(field.key, value); err == nil {
+			if b, err := bag.SetMember(member); err == nil {
+				bag = b
+			}
+		}
+	}
+	trace.SpanFromContext(uc).SetAttributes(attrs...)
+	ctx.SetUserContext(baggage.ContextWithBaggage(uc, bag))
+}
+
+// injectTraceHeaders 将上下文中的 span 和 baggage 写入外部请求的请求头，上下文中没有 span 时保留透传的 traceparent
+func injectTraceHeaders(ctx context.Context, header http.Header) {
+	tracePropagator.Inject(ctx, otelpropagation.HeaderCarrier(header))
+}
+
+// requestHeaderCarrier 以 fasthttp 请求头读写链路追踪信息
+type requestHeaderCarrier struct {
+	header *fasthttp.RequestHeader
+}
+
+func (c requestHeaderCarrier) Get(key string) string {
+	return string(c.header.Peek(key))
+}
+
+func (c requestHeaderCarrier) Set(key, value string) {
+	c.header.Set(key, value)
+}
+
+func (c requestHeaderCarrier) Keys() []string {
+	var keys []string
+	c.header.VisitAll(func(key, _ []byte) {
+		keys = append(keys, string(key))
+	})
+	return keys
+}
@@ -0,0 +1,563 @@
+package mod
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// tsIdentPattern 可直接作为 TypeScript 属性名或方法名的标识符
+var tsIdentPattern = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+// tsTypeArgPattern、tsNonWordPattern 用于将泛型实例化类型名转换为合法标识符
+var (
+	tsTypeArgPattern = regexp.MustCompile(`[\w./]*\.`)
+	tsNonWordPattern = regexp.MustCompile(`\W`)
+)
+
+// tsOneofPattern 与 validator 解析 oneof 参数的规则一致，支持 'on hold' 形式的带空格取值
+var tsOneofPattern = regexp.MustCompile(`'[^']*'|\S+`)
+
+// tsReservedMethods 客户端自身的成员名称，服务方法与之重名时追加 Service 后缀
+var tsReservedMethods = map[string]bool{"call": true, "setToken": true, "constructor": true, "options": true, "token": true}
+
+// tsStruct 需要输出为 interface 的结构体
+type tsStruct struct {
+	name     string
+	typ      reflect.Type
+	request  bool // 作为请求参数使用，未标记 required 的字段为可选
+	response bool // 作为响应数据使用，omitempty 的字段为可选
+}
+
+// tsGenerator TypeScript 代码生成器
+type tsGenerator struct {
+	structs map[reflect.Type]*tsStruct
+	names   map[string]reflect.Type
+}
+
+// TypeScriptSDK 根据已注册的服务生成 TypeScript 类型定义和基于 fetch 的客户端
+func (app *App) TypeScriptSDK() string {
+	g := &tsGenerator{
+		structs: map[reflect.Type]*tsStruct{},
+		names:   map[string]reflect.Type{},
+	}
+
+	// 多版本服务共用一个路径，使用最先注册的版本；原始请求体服务（Webhook 回调）由第三方调用，
+	// 文件下载服务直接作为链接使用，WebSocket 服务使用浏览器的 WebSocket，均不生成客户端方法；
+	// 隐藏的服务与文档一致，仅在 docs.show_hidden_in 的环境中生成
+	var services []Service
+	seen := map[string]bool{}
+	for _, svc := range app.allServices() {
+		if !seen[svc.Name] && !svc.RawBody && len(svc.methods) == 0 && svc.owner.docVisible(&svc) {
+			seen[svc.Name] = true
+			services = append(services, svc)
+		}
+	}
+	sort.Slice(services, func(i, j int) bool { return services[i].Name < services[j].Name })
+
+	for _, svc := range services {
+		if svc.Handler.InputType != nil {
+			g.collect(svc.Handler.InputType, false)
+		}
+		if svc.Handler.OutputType != nil {
+			g.collect(svc.Handler.OutputType, true)
+		}
+	}
+
+	config := app.cfg.ModConfig
+	title := config.App.DisplayName
+	if title == "" {
+		title = config.App.Name
+	}
+
+	var b strings.Builder
+	b.WriteString("// Code generated by mod. DO NOT EDIT.\n")
+	if title != "" {
+		fmt.Fprintf(&b, "// %s %s\n", title, config.App.Version)
+	}
+	fmt.Fprintf(&b, "\nexport const DEFAULT_BASE_URL = %s;\n", tsQuote(config.App.ServiceBase))
+	b.WriteString(tsClientRuntime)
+
+	// 类型定义
+	names := make([]string, 0, len(g.names))
+	for name := range g.names {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		g.writeInterface(&b, g.structs[g.names[name]])
+	}
+
+	// 服务方法
+	b.WriteString("\nexport class ModClient extends BaseClient {")
+	methods := map[string]bool{}
+	for _, svc := range services {
+		method := tsMethodName(svc.Name)
+		for tsReservedMethods[method] || methods[method] {
+			method += "Service"
+		}
+		methods[method] = true
+
+		input, output := "Record<string, never>", "unknown"
+		if svc.Handler.InputType != nil {
+			input = g.tsType(svc.Handler.InputType, nil)
+		}
+		if svc.Handler.OutputType != nil {
+			output = g.tsType(svc.Handler.OutputType, nil)
+		}
+
+		doc := svc.DisplayName
+		if svc.Description != "" && svc.Description != doc {
+			doc += " - " + svc.Description
+		}
+		fmt.Fprintf(&b, "\n  /** %s */\n", tsComment(doc))
+		fmt.Fprintf(&b, "  %s(req: %s = {} as %s, options?: RequestOptions): Promise<%s> {\n", method, input, input, output)
+		// 挂载的子应用服务不在 DEFAULT_BASE_URL 下，路径模板服务不使用服务名称，需要携带完整路径
+		path := ""
+		if svc.path != app.ServicePath(svc.Name) {
+			path = svc.path
+		}
+		fmt.Fprintf(&b, "    return this.call<%s>(%s, req, %s, options);\n  }\n", output, tsQuote(svc.Name), g.serviceMeta(svc, path))
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// WriteTypeScriptSDK 将 TypeScript SDK 写入文件，便于在前端构建流程中生成
+func (app *App) WriteTypeScriptSDK(path string) error {
+	return os.WriteFile(path, []byte(app.TypeScriptSDK()), 0644)
+}
+
+// handleTypeScriptSDK 下载 TypeScript SDK
+func (app *App) handleTypeScriptSDK(c *fiber.Ctx) error {
+	filename := app.cfg.ModConfig.App.Name
+	if filename == "" {
+		filename = "api"
+	}
+	c.Set("Content-Type", "application/typescript; charset=utf-8")
+	c.Set("Content-Disposition", "attachment; filename="+filename+".ts")
+	return c.SendString(app.TypeScriptSDK())
+}
+
+// collect 收集需要输出为 interface 的具名结构体，并记录其使用方向
+func (g *tsGenerator) collect(t reflect.Type, response bool) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		g.collect(t.Elem(), response)
+		return
+	case reflect.Struct:
+	default:
+		return
+	}
+	if t == reflect.TypeOf(time.Time{}) {
+		return
+	}
+
+	if t.Name() != "" {
+		s := g.structs[t]
+		if s == nil {
+			s = &tsStruct{name: g.typeName(t), typ: t}
+			g.structs[t] = s
+			g.names[s.name] = t
+		}
+		if (response && s.response) || (!response && s.request) {
+			return
+		}
+		if response {
+			s.response = true
+		} else {
+			s.request = true
+		}
+	}
+
+	for _, field := range tsFields(t) {
+		g.collect(field.Type, response)
+	}
+}
+
+// typeName 返回结构体的 interface 名称，不同包的同名类型追加包名前缀
+func (g *tsGenerator) typeName(t reflect.Type) string {
+	name := t.Name()
+	if i := strings.IndexByte(name, '['); i >= 0 {
+		// 泛型实例化类型，如 Page[main.Item] -> PageItem
+		args := tsTypeArgPattern.ReplaceAllString(name[i:], "")
+		name = name[:i] + tsNonWordPattern.ReplaceAllString(args, "")
+	}
+	if existing, ok := g.names[name]; !ok || existing == t {
+		return name
+	}
+
+	pkg := path.Base(t.PkgPath())
+	prefixed := strings.ToUpper(pkg[:1]) + pkg[1:] + name
+	candidate := prefixed
+	for i := 2; ; i++ {
+		if _, ok := g.names[candidate]; !ok {
+			return candidate
+		}
+		candidate = fmt.Sprintf("%s%d", prefixed, i)
+	}
+}
+
+// tsFields 返回结构体的序列化字段，匿名嵌入的结构体按 encoding/json 的规则展开
+func tsFields(t reflect.Type) []reflect.StructField {
+	var fields []reflect.StructField
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.Tag.Get("json") == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				fields = append(fields, tsFields(embedded)...)
+				continue
+			}
+		}
+		if field.IsExported() {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// tsProperty 返回字段的属性名；json:"-" 但通过 mod 标签从查询参数或请求头获取的字段使用参数名
+func tsProperty(field reflect.StructField) (name string, omitempty bool, ok bool) {
+	name, omitempty, skip := openAPIFieldName(field)
+	if !skip {
+		return name, omitempty, true
+	}
+	if modTag := field.Tag.Get("mod"); modTag != "" && modTagValue(modTag, "from", "query") != "body" {
+		return modTagValue(modTag, "name", strings.ToLower(field.Name)), true, true
+	}
+	return "", false, false
+}
+
+// writeInterface 输出结构体的 interface 定义
+func (g *tsGenerator) writeInterface(b *strings.Builder, s *tsStruct) {
+	fmt.Fprintf(b, "\nexport interface %s {\n", s.name)
+	for _, field := range tsFields(s.typ) {
+		name, omitempty, ok := tsProperty(field)
+		if !ok {
+			continue
+		}
+		optional := omitempty
+		if s.request && !hasValidateRule(field.Tag.Get("validate"), "required") {
+			optional = true
+		}
+
+		if desc := field.Tag.Get("desc"); desc != "" {
+			fmt.Fprintf(b, "  /** %s */\n", tsComment(desc))
+		}
+		mark := ""
+		if optional {
+			mark = "?"
+		}
+		fmt.Fprintf(b, "  %s%s: %s;\n", tsPropertyName(name), mark, g.tsType(field.Type, &field))
+	}
+	b.WriteString("}\n")
+}
+
+// tsType 返回类型对应的 TypeScript 类型，field 不为空时按 validate 标签的 oneof 规则生成字面量联合类型
+func (g *tsGenerator) tsType(t reflect.Type, field *reflect.StructField) string {
+	if t.Kind() == reflect.Ptr {
+		return g.tsType(t.Elem(), field) + " | null"
+	}
+	if t == reflect.TypeOf(time.Time{}) {
+		return "string"
+	}
+	if t == reflect.TypeOf(json.RawMessage{}) {
+		return "any"
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return tsEnum(field, "string", true)
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return tsEnum(field, "number", false)
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			// []byte 序列化为base64字符串
+			return "string | null"
+		}
+		return tsArray(g.tsType(t.Elem(), nil)) + " | null"
+	case reflect.Array:
+		return tsArray(g.tsType(t.Elem(), nil))
+	case reflect.Map:
+		return "Record<string, " + g.tsType(t.Elem(), nil) + "> | null"
+	case reflect.Struct:
+		if s, ok := g.structs[t]; ok {
+			return s.name
+		}
+		// 匿名结构体内联展开
+		var parts []string
+		for _, f := range tsFields(t) {
+			name, omitempty, ok := tsProperty(f)
+			if !ok {
+				continue
+			}
+			mark := ""
+			if omitempty {
+				mark = "?"
+			}
+			parts = append(parts, tsPropertyName(name)+mark+": "+g.tsType(f.Type, &f))
+		}
+		if len(parts) == 0 {
+			return "Record<string, never>"
+		}
+		return "{ " + strings.Join(parts, "; ") + " }"
+	}
+	return "any"
+}
+
+// tsEnum 字段存在 oneof 规则时返回字面量联合类型
+func tsEnum(field *reflect.StructField, base string, quoted bool) string {
+	if field == nil {
+		return base
+	}
+	values := tsOneofPattern.FindAllString(parseValidateRules(field.Tag.Get("validate"))["oneof"], -1)
+	if len(values) == 0 {
+		return base
+	}
+	for i, v := range values {
+		if quoted {
+			values[i] = tsQuote(strings.Trim(v, "'"))
+		} else if _, err := strconv.ParseFloat(v, 64); err != nil {
+			return base
+		}
+	}
+	return strings.Join(values, " | ")
+}
+
+// serviceMeta 返回服务的调用元数据：是否直接返回数据、需要通过查询参数、请求头或路径参数发送的字段，
+// 以及挂载的子应用服务或路径模板服务的完整路径
+func (g *tsGenerator) serviceMeta(svc Service, path string) string {
+	var params []string
+	if t := svc.Handler.InputType; t != nil {
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if t.Kind() == reflect.Struct {
+			for i := 0; i < t.NumField(); i++ {
+				field := t.Field(i)
+				modTag := field.Tag.Get("mod")
+				from := modTagValue(modTag, "from", "query")
+				if !field.IsExported() || modTag == "" || (from != "query" && from != "header" && from != "param") {
+					continue
+				}
+				key, _, ok := tsProperty(field)
+				if !ok {
+					continue
+				}
+				params = append(params, fmt.Sprintf("{ key: %s, in: %s, name: %s }",
+					tsQuote(key), tsQuote(from), tsQuote(modTagValue(modTag, "name", strings.ToLower(field.Name)))))
+			}
+		}
+	}
+	if path != "" {
+		return fmt.Sprintf("{ raw: %t, params: [%s], path: %s }", svc.ReturnRaw, strings.Join(params, ", "), tsQuote(path))
+	}
+	return fmt.Sprintf("{ raw: %t, params: [%s] }", svc.ReturnRaw, strings.Join(params, ", "))
+}
+
+// tsMethodName 将服务名称转换为小驼峰方法名，如 get_user -> getUser
+func tsMethodName(name string) string {
+	var b strings.Builder
+	upper := false
+	for _, r := range name {
+		if r == '_' || r == '-' || r == '.' {
+			upper = b.Len() > 0
+			continue
+		}
+		if upper {
+			b.WriteString(strings.ToUpper(string(r)))
+			upper = false
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	method := b.String()
+	if !tsIdentPattern.MatchString(method) {
+		method = "_" + method
+	}
+	return method
+}
+
+func tsArray(elem string) string {
+	if strings.ContainsAny(elem, " |") {
+		return "(" + elem + ")[]"
+	}
+	return elem + "[]"
+}
+
+func tsPropertyName(name string) string {
+	if tsIdentPattern.MatchString(name) {
+		return name
+	}
+	return tsQuote(name)
+}
+
+func tsQuote(s string) string {
+	data, _ := json.Marshal(s)
+	return string(data)
+}
+
+func tsComment(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, "*/", "*\\/"), "\n", " ")
+}
+
+// tsClientRuntime 客户端公共代码
+const tsClientRuntime = `
+export interface ApiResponse<T = unknown> {
+  code: number;
+  data?: T;
+  msg: string;
+  detail?: string;
+  rid: string;
+}
+
+/** 服务返回非0状态码或HTTP错误时抛出 */
+export class ApiError extends Error {
+  constructor(
+    message: string,
+    public readonly code: number,
+    public readonly status: number,
+    public readonly detail?: string,
+    public readonly rid?: string,
+  ) {
+    super(message);
+    this.name = "ApiError";
+  }
+}
+
+export type TokenProvider = () => string | null | undefined | Promise<string | null | undefined>;
+
+export interface FetchInit {
+  method: string;
+  headers: Record<string, string>;
+  body: string;
+  signal?: AbortSignal;
+}
+
+export type FetchLike = (url: string, init: FetchInit) => Promise<{ status: number; json(): Promise<any> }>;
+
+export interface ClientOptions {
+  /** 服务地址前缀，默认为 DEFAULT_BASE_URL */
+  baseURL?: string;
+  /** 认证token或获取token的函数，以 Authorization: Bearer 发送 */
+  token?: string | TokenProvider;
+  /** 每个请求附加的请求头 */
+  headers?: Record<string, string>;
+  /** 自定义请求实现，默认使用全局 fetch；使用 axios 时传入 axiosFetch(instance) */
+  fetch?: FetchLike;
+}
+
+export interface RequestOptions {
+  headers?: Record<string, string>;
+  signal?: AbortSignal;
+}
+
+export interface ServiceMeta {
+  /** 服务直接返回数据，不使用标准响应格式 */
+  raw: boolean;
+  /** 通过查询参数、请求头或路径参数发送的字段 */
+  params: { key: string; in: string; name: string }[];
+  /** 挂载的子应用服务或路径模板服务的完整路径，基于 baseURL 去掉 DEFAULT_BASE_URL 后的根地址 */
+  path?: string;
+}
+
+/** 将 axios 实例适配为 FetchLike */
+export function axiosFetch(instance: { request(config: any): Promise<{ status: number; data: any }> }): FetchLike {
+  return async (url, init) => {
+    const res = await instance.request({
+      url,
+      method: init.method,
+      headers: init.headers,
+      data: init.body,
+      signal: init.signal,
+      validateStatus: () => true,
+    });
+    return { status: res.status, json: async () => res.data };
+  };
+}
+
+export class BaseClient {
+  private token?: string | TokenProvider;
+
+  constructor(private readonly options: ClientOptions = {}) {
+    this.token = options.token;
+  }
+
+  /** 设置认证token，传入空值时清除 */
+  setToken(token?: string | TokenProvider): void {
+    this.token = token;
+  }
+
+  /** 调用服务，成功时返回响应数据 */
+  async call<T>(service: string, req: object, meta: ServiceMeta, options: RequestOptions = {}): Promise<T> {
+    const headers: Record<string, string> = {
+      "Content-Type": "application/json",
+      ...this.options.headers,
+      ...options.headers,
+    };
+    const token = typeof this.token === "function" ? await this.token() : this.token;
+    if (token) {
+      headers["Authorization"] = "Bearer " + token;
+    }
+
+    const query = new URLSearchParams();
+    const pathParams: Record<string, string> = {};
+    for (const p of meta.params) {
+      const value = (req as Record<string, unknown>)[p.key];
+      if (value === undefined || value === null || value === "") {
+        continue;
+      }
+      if (p.in === "header") {
+        headers[p.name] = String(value);
+      } else if (p.in === "param") {
+        pathParams[p.name] = encodeURIComponent(String(value));
+      } else {
+        query.set(p.name, String(value));
+      }
+    }
+    const qs = query.toString();
+    const base = this.options.baseURL ?? DEFAULT_BASE_URL;
+    const path = meta.path
+      ?.split("/")
+      .map((s) => (s.startsWith(":") && s.slice(1) in pathParams ? pathParams[s.slice(1)] : s))
+      .join("/");
+    const endpoint = path
+      ? (base.endsWith(DEFAULT_BASE_URL) ? base.slice(0, base.length - DEFAULT_BASE_URL.length) : base) + path
+      : base + "/" + service;
+    const url = endpoint + (qs ? "?" + qs : "");
+
+    const doFetch = this.options.fetch ?? (globalThis.fetch as unknown as FetchLike);
+    const res = await doFetch(url, { method: "POST", headers, body: JSON.stringify(req), signal: options.signal });
+    const body = await res.json().catch(() => undefined);
+
+    if (meta.raw && res.status < 400) {
+      return body as T;
+    }
+    const envelope = (body ?? {}) as Partial<ApiResponse<T>>;
+    if (res.status >= 400 || envelope.code !== 0) {
+      throw new ApiError(envelope.msg ?? "HTTP " + res.status, envelope.code ?? res.status, res.status, envelope.detail, envelope.rid);
+    }
+    return envelope.data as T;
+  }
+}
+`
@@ -0,0 +1,319 @@
+package mod
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"path/filepath"
+	"strings"
+)
+
+// magicSignature 文件头签名，offset 为签名在文件中的起始位置
+type magicSignature struct {
+	offset int
+	magic  []byte
+}
+
+// magicSignatures 扩展名对应的文件头签名，命中任意一个即视为一致
+var magicSignatures = map[string][]magicSignature{
+	".jpg":  {{0, []byte{0xFF, 0xD8, 0xFF}}},
+	".jpeg": {{0, []byte{0xFF, 0xD8, 0xFF}}},
+	".png":  {{0, []byte{0x89, 'P', 'N', 'G', 0x0D, 0x0A, 0x1A, 0x0A}}},
+	".gif":  {{0, []byte("GIF87a")}, {0, []byte("GIF89a")}},
+	".webp": {{8, []byte("WEBP")}},
+	".bmp":  {{0, []byte("BM")}},
+	".ico":  {{0, []byte{0x00, 0x00, 0x01, 0x00}}},
+	".tif":  {{0, []byte{'I', 'I', 0x2A, 0x00}}, {0, []byte{'M', 'M', 0x00, 0x2A}}},
+	".tiff": {{0, []byte{'I', 'I', 0x2A, 0x00}}, {0, []byte{'M', 'M', 0x00, 0x2A}}},
+	".pdf":  {{0, []byte("%PDF-")}},
+	".zip":  {{0, []byte{'P', 'K', 0x03, 0x04}}, {0, []byte{'P', 'K', 0x05, 0x06}}},
+	".docx": {{0, []byte{'P', 'K', 0x03, 0x04}}},
+	".xlsx": {{0, []byte{'P', 'K', 0x03, 0x04}}},
+	".pptx": {{0, []byte{'P', 'K', 0x03, 0x04}}},
+	".doc":  {{0, []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1}}},
+	".xls":  {{0, []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1}}},
+	".ppt":  {{0, []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1}}},
+	".gz":   {{0, []byte{0x1F, 0x8B}}},
+	".tgz":  {{0, []byte{0x1F, 0x8B}}},
+	".7z":   {{0, []byte{'7', 'z', 0xBC, 0xAF, 0x27, 0x1C}}},
+	".rar":  {{0, []byte("Rar!\x1A\x07")}},
+	".mp3":  {{0, []byte("ID3")}, {0, []byte{0xFF, 0xFB}}, {0, []byte{0xFF, 0xF3}}, {0, []byte{0xFF, 0xF2}}},
+	".mp4":  {{4, []byte("ftyp")}},
+	".m4a":  {{4, []byte("ftyp")}},
+	".mov":  {{4, []byte("ftyp")}, {4, []byte("moov")}},
+	".wav":  {{8, []byte("WAVE")}},
+	".avi":  {{8, []byte("AVI ")}},
+	".ogg":  {{0, []byte("OggS")}},
+	".flac": {{0, []byte("fLaC")}},
+	".webm": {{0, []byte{0x1A, 0x45, 0xDF, 0xA3}}},
+	".mkv":  {{0, []byte{0x1A, 0x45, 0xDF, 0xA3}}},
+}
+
+// executableSignatures 可执行文件签名，任何非可执行扩展名的文件命中即拒绝
+var executableSignatures = []magicSignature{
+	{0, []byte("MZ")},                   // Windows PE
+	{0, []byte{0x7F, 'E', 'L', 'F'}},    // Linux ELF
+	{0, []byte{0xCF, 0xFA, 0xED, 0xFE}}, // Mach-O 64
+	{0, []byte{0xCE, 0xFA, 0xED, 0xFE}}, // Mach-O 32
+	{0, []byte{0xCA, 0xFE, 0xBA, 0xBE}}, // Mach-O Universal / Java class
+	{0, []byte{0x00, 'a', 's', 'm'}},    // WebAssembly
+}
+
+// dangerousExts 危险扩展名，用于多重扩展名和压缩包条目检查
+var dangerousExts = map[string]bool{
+	".exe": true, ".dll": true, ".com": true, ".bat": true, ".cmd": true, ".msi": true, ".scr": true,
+	".sh": true, ".ps1": true, ".vbs": true, ".js": true, ".jar": true,
+	".php": true, ".phtml": true, ".php5": true, ".jsp": true, ".asp": true, ".aspx": true, ".cgi": true,
+	".html": true, ".htm": true, ".svg": true,
+}
+
+// archiveExts 压缩包扩展名，用于嵌套压缩包检查
+var archiveExts = map[string]bool{
+	".zip": true, ".gz": true, ".tgz": true, ".7z": true, ".rar": true, ".tar": true, ".bz2": true, ".xz": true,
+}
+
+// svgBlockedElements SVG中可以执行脚本或嵌入外部内容的元素（小写）
+var svgBlockedElements = map[string]bool{
+	"script": true, "foreignobject": true, "iframe": true, "embed": true, "object": true,
+}
+
+// inspectUploadContent 对上传文件进行内容深度校验
+func (app *App) inspectUploadContent(file *multipart.FileHeader) error {
+	config := app.cfg.ModConfig.FileUpload.Validation
+	if !config.Enabled {
+		return nil
+	}
+
+	ext := strings.ToLower(filepath.Ext(file.Filename))
+
+	// 扩展名黑名单
+	for _, blocked := range config.BlockedExts {
+		blocked = strings.ToLower(blocked)
+		if !strings.HasPrefix(blocked, ".") {
+			blocked = "." + blocked
+		}
+		if blocked == ext {
+			return fmt.Errorf("文件扩展名 %s 被禁止上传", ext)
+		}
+	}
+
+	// 多重扩展名，如 shell.php.jpg
+	if config.BlockDoubleExt {
+		name := strings.ToLower(strings.TrimSuffix(filepath.Base(file.Filename), filepath.Ext(file.Filename)))
+		for _, part := range strings.Split(name, ".")[1:] {
+			if dangerousExts["."+part] {
+				return fmt.Errorf("文件名 %s 包含危险的多重扩展名", file.Filename)
+			}
+		}
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return fmt.Errorf("无法读取文件内容进行校验")
+	}
+	defer src.Close()
+
+	header := make([]byte, 512)
+	n, err := io.ReadFull(src, header)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return fmt.Errorf("无法读取文件内容进行校验")
+	}
+	header = header[:n]
+
+	// 伪装成其他类型的可执行文件
+	if !dangerousExts[ext] && matchSignatures(header, executableSignatures) {
+		return fmt.Errorf("文件 %s 的内容为可执行文件", file.Filename)
+	}
+
+	// 扩展名与文件头签名一致性
+	if config.StrictMIME {
+		if signatures, ok := magicSignatures[ext]; ok && !matchSignatures(header, signatures) {
+			return fmt.Errorf("文件内容与扩展名 %s 不一致", ext)
+		}
+	}
+
+	// SVG脚本检查
+	if config.BlockSVGScript && (ext == ".svg" || bytes.Contains(bytes.ToLower(header), []byte("<svg"))) {
+		if _, err := src.Seek(0, io.SeekStart); err != nil {
+			return fmt.Errorf("无法读取文件内容进行校验")
+		}
+		if err := inspectSVG(src); err != nil {
+			return err
+		}
+	}
+
+	// 压缩包检查
+	if config.Archive.Enabled {
+		switch {
+		case matchSignatures(header, magicSignatures[".zip"]):
+			return app.inspectZipArchive(src, file.Size)
+		case matchSignatures(header, magicSignatures[".gz"]):
+			if _, err := src.Seek(0, io.SeekStart); err != nil {
+				return fmt.Errorf("无法读取文件内容进行校验")
+			}
+			return app.inspectGzipArchive(src, file.Size)
+		}
+	}
+
+	return nil
+}
+
+// inspectSVG 使用XML解析器逐个检查SVG的元素和属性：拒绝脚本和嵌入外部内容的元素、事件处理器属性（on*）
+// 以及指向文档外的链接。属性值由解析器解码实体后再检查，无法解析的内容（如未加引号的属性）同样拒绝
+func inspectSVG(src io.Reader) error {
+	decoder := xml.NewDecoder(src)
+	decoder.Entity = xml.HTMLEntity
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("SVG文件无法解析: %v", err)
+		}
+		element, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		name := strings.ToLower(element.Name.Local)
+		if svgBlockedElements[name] {
+			return fmt.Errorf("SVG文件包含 <%s> 元素", element.Name.Local)
+		}
+		for _, attr := range element.Attr {
+			attrName := strings.ToLower(attr.Name.Local)
+			if strings.HasPrefix(attrName, "on") {
+				return fmt.Errorf("SVG文件包含事件处理器 %s", attr.Name.Local)
+			}
+			if attrName == "href" && !svgLocalReference(attr.Value) {
+				return fmt.Errorf("SVG文件包含外部链接 %s", attr.Value)
+			}
+			// <set>、<animate> 可以在运行时把 href 或事件处理器改为任意值
+			if (name == "set" || name == "animate") && attrName == "attributename" {
+				target := strings.ToLower(attr.Value[strings.LastIndex(attr.Value, ":")+1:])
+				if target == "href" || strings.HasPrefix(target, "on") {
+					return fmt.Errorf("SVG文件包含修改 %s 的动画", attr.Value)
+				}
+			}
+		}
+	}
+}
+
+// svgLocalReference 链接是否指向文档内的元素（#id），空值视为本地
+func svgLocalReference(value string) bool {
+	value = strings.TrimSpace(value)
+	return value == "" || strings.HasPrefix(value, "#")
+}
+
+// matchSignatures 判断文件头是否命中任意签名
+func matchSignatures(header []byte, signatures []magicSignature) bool {
+	for _, sig := range signatures {
+		end := sig.offset + len(sig.magic)
+		if len(header) >= end && bytes.Equal(header[sig.offset:end], sig.magic) {
+			return true
+		}
+	}
+	return false
+}
+
+// archiveLimits 返回压缩包检查的限制值
+func (app *App) archiveLimits() (maxEntries int, maxUncompressed int64, maxRatio float64) {
+	config := app.cfg.ModConfig.FileUpload.Validation.Archive
+
+	maxEntries = config.MaxEntries
+	if maxEntries <= 0 {
+		maxEntries = 1000
+	}
+	maxUncompressed = 100 * 1024 * 1024
+	if config.MaxUncompressed != "" {
+		if size, err := parseSize(config.MaxUncompressed); err == nil && size > 0 {
+			maxUncompressed = size
+		}
+	}
+	maxRatio = config.MaxRatio
+	if maxRatio <= 0 {
+		maxRatio = 100
+	}
+	return
+}
+
+// inspectZipArchive 检查zip压缩包的条目数量、解压大小、压缩比及条目名称，
+// 条目头中的大小可以伪造，解压大小按实际解压的字节数计算
+func (app *App) inspectZipArchive(src multipart.File, size int64) error {
+	config := app.cfg.ModConfig.FileUpload.Validation.Archive
+	maxEntries, maxUncompressed, maxRatio := app.archiveLimits()
+
+	reader, err := zip.NewReader(src, size)
+	if err != nil {
+		return fmt.Errorf("无法解析压缩包: %v", err)
+	}
+	if len(reader.File) > maxEntries {
+		return fmt.Errorf("压缩包文件数 %d 超过限制 %d", len(reader.File), maxEntries)
+	}
+
+	var total int64
+	for _, entry := range reader.File {
+		name := strings.ReplaceAll(entry.Name, "\\", "/")
+		if strings.HasPrefix(name, "/") || strings.Contains("/"+name+"/", "/../") {
+			return fmt.Errorf("压缩包包含非法路径 %s", entry.Name)
+		}
+
+		entryExt := strings.ToLower(filepath.Ext(name))
+		if config.BlockDangerous && dangerousExts[entryExt] {
+			return fmt.Errorf("压缩包包含危险文件 %s", entry.Name)
+		}
+		if config.BlockNested && archiveExts[entryExt] {
+			return fmt.Errorf("压缩包包含嵌套压缩包 %s", entry.Name)
+		}
+
+		n, err := zipEntrySize(entry, maxUncompressed-total)
+		if err != nil {
+			return fmt.Errorf("无法解析压缩包: %v", err)
+		}
+		total += n
+		if total > maxUncompressed {
+			return fmt.Errorf("压缩包解压后大小超过限制 %d", maxUncompressed)
+		}
+	}
+
+	if size > 0 && float64(total)/float64(size) > maxRatio {
+		return fmt.Errorf("压缩包压缩比超过限制 %.0f", maxRatio)
+	}
+	return nil
+}
+
+// zipEntrySize 解压条目并计数，最多读取 limit+1 字节，超过 limit 时调用方即可判定超限
+func zipEntrySize(entry *zip.File, limit int64) (int64, error) {
+	rc, err := entry.Open()
+	if err != nil {
+		return 0, err
+	}
+	defer rc.Close()
+	return io.Copy(io.Discard, io.LimitReader(rc, limit+1))
+}
+
+// inspectGzipArchive 检查gzip文件的实际解压大小和压缩比（gzip头中的大小不可信，需实际解压计数）
+func (app *App) inspectGzipArchive(src io.Reader, size int64) error {
+	_, maxUncompressed, maxRatio := app.archiveLimits()
+
+	reader, err := gzip.NewReader(src)
+	if err != nil {
+		return fmt.Errorf("无法解析压缩包: %v", err)
+	}
+	defer reader.Close()
+
+	total, err := io.Copy(io.Discard, io.LimitReader(reader, maxUncompressed+1))
+	if err != nil {
+		return fmt.Errorf("无法解析压缩包: %v", err)
+	}
+	if total > maxUncompressed {
+		return fmt.Errorf("压缩包解压后大小超过限制 %d", maxUncompressed)
+	}
+	if size > 0 && float64(total)/float64(size) > maxRatio {
+		return fmt.Errorf("压缩包压缩比超过限制 %.0f", maxRatio)
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package mod
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"hash/crc32"
+	"mime/multipart"
+	"strings"
+	"testing"
+)
+
+const uploadValidationConfig = `
+file_upload:
+  validation:
+    enabled: true
+    block_svg_script: true
+    archive:
+      enabled: true
+      max_uncompressed: "1MB"
+      max_ratio: 100000
+`
+
+// uploadFileHeader 将内容包装为 multipart 上传的文件
+func uploadFileHeader(t *testing.T, filename string, content []byte) *multipart.FileHeader {
+	t.Helper()
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("file", filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(content)
+	writer.Close()
+
+	form, err := multipart.NewReader(&body, writer.Boundary()).ReadForm(1 << 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { form.RemoveAll() })
+	return form.File["file"][0]
+}
+
+func TestInspectSVG(t *testing.T) {
+	app := newTestApp(t, uploadValidationConfig)
+	for _, tc := range []struct {
+		name    string
+		content string
+		ok      bool
+	}{
+		{"plain", `<svg xmlns="http://www.w3.org/2000/svg"><rect width="10" height="10"/></svg>`, true},
+		{"local href", `<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink"><use xlink:href="#icon"/></svg>`, true},
+		{"slash onload", `<svg/onload=alert(1)>`, false},
+		{"onload", `<svg xmlns="http://www.w3.org/2000/svg"><rect onclick="alert(1)"/></svg>`, false},
+		{"script", `<svg xmlns="http://www.w3.org/2000/svg"><SCRIPT>alert(1)</SCRIPT></svg>`, false},
+		{"foreignObject", `<svg xmlns="http://www.w3.org/2000/svg"><foreignObject><p>x</p></foreignObject></svg>`, false},
+		{"encoded javascript", `<svg xmlns="http://www.w3.org/2000/svg"><a href="&#106;avascript:alert(1)"><text>x</text></a></svg>`, false},
+		{"external xlink", `<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink"><image xlink:href="https://evil.example/x.png"/></svg>`, false},
+		{"animated href", `<svg xmlns="http://www.w3.org/2000/svg"><a><set attributeName="href" to="javascript:alert(1)"/></a></svg>`, false},
+	} {
+		err := app.inspectUploadContent(uploadFileHeader(t, "image.svg", []byte(tc.content)))
+		if (err == nil) != tc.ok {
+			t.Errorf("%s: expected ok=%v, got %v", tc.name, tc.ok, err)
+		}
+	}
+}
+
+func TestInspectZipCountsDecompressedBytes(t *testing.T) {
+	app := newTestApp(t, uploadValidationConfig)
+	data := bytes.Repeat([]byte{0}, 4<<20)
+	var compressed bytes.Buffer
+	fw, _ := flate.NewWriter(&compressed, flate.BestCompression)
+	fw.Write(data)
+	fw.Close()
+
+	// 条目头中的解压大小伪造为10字节
+	var archive bytes.Buffer
+	zw := zip.NewWriter(&archive)
+	w, err := zw.CreateRaw(&zip.FileHeader{
+		Name:               "data.bin",
+		Method:             zip.Deflate,
+		CRC32:              crc32.ChecksumIEEE(data),
+		CompressedSize64:   uint64(compressed.Len()),
+		UncompressedSize64: 10,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write(compressed.Bytes())
+	zw.Close()
+
+	err = app.inspectUploadContent(uploadFileHeader(t, "bomb.zip", archive.Bytes()))
+	if err == nil || !strings.Contains(err.Error(), "压缩包") {
+		t.Fatalf("expected forged zip to be rejected, got %v", err)
+	}
+
+	var small bytes.Buffer
+	zw = zip.NewWriter(&small)
+	w, _ = zw.Create("readme.txt")
+	w.Write([]byte("hello"))
+	zw.Close()
+	if err := app.inspectUploadContent(uploadFileHeader(t, "ok.zip", small.Bytes())); err != nil {
+		t.Fatalf("expected small zip to pass, got %v", err)
+	}
+}
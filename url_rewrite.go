@@ -0,0 +1,129 @@
+package mod
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// FileURLRule 文件访问URL的改写规则，将存储后端生成的地址映射到 CDN 或自定义域名
+type FileURLRule struct {
+	Backend     string `yaml:"backend"`      // 匹配的存储后端（s3、oss、gcs、cos、qiniu、local），为空匹配全部
+	Match       string `yaml:"match"`        // 原始URL的前缀，如 https://bucket.s3.amazonaws.com/，为空时匹配该后端的全部URL
+	Domain      string `yaml:"domain"`       // 替换后的域名（可带端口），为空时保留原域名只改写路径
+	Scheme      string `yaml:"scheme"`       // 协议：https（默认）、http、relative（//cdn.example.com/...，跟随页面协议）
+	StripPrefix string `yaml:"strip_prefix"` // 去掉的路径前缀，如路径形式的存储桶名 /bucket
+	PathPrefix  string `yaml:"path_prefix"`  // 添加的路径前缀，如 /static
+	Presigned   bool   `yaml:"presigned"`    // 是否同时改写下载路由重定向的预签名地址，需要 CDN 回源时保留查询参数
+}
+
+// FileURL 返回文件的访问URL：元数据中保存存储后端生成的原始地址，按 file_upload.url_rewrite 改写后返回，
+// 修改规则后已上传的文件同样生效
+func (app *App) FileURL(meta *FileMetadata) string {
+	if meta == nil {
+		return ""
+	}
+	return app.rewriteFileURL(meta.Backend, meta.URL, false)
+}
+
+// rewriteFileURL 按第一条匹配的规则改写URL，没有匹配的规则或URL无法解析时原样返回；
+// presigned 为 true 时只使用开启了 presigned 的规则
+func (app *App) rewriteFileURL(backend, raw string, presigned bool) string {
+	if raw == "" || app.cfg.ModConfig == nil {
+		return raw
+	}
+	for _, rule := range app.cfg.ModConfig.FileUpload.URLRewrite {
+		if rule.Backend != "" && !strings.EqualFold(rule.Backend, backend) {
+			continue
+		}
+		if presigned && !rule.Presigned {
+			continue
+		}
+		if !strings.HasPrefix(raw, rule.Match) {
+			continue
+		}
+		u, err := url.Parse(raw)
+		if err != nil {
+			return raw
+		}
+		return rule.apply(u)
+	}
+	return raw
+}
+
+// apply 按规则生成新的URL，保留转义后的路径和查询参数
+func (rule FileURLRule) apply(u *url.URL) string {
+	host, scheme := u.Host, u.Scheme
+	if rule.Domain != "" {
+		host, scheme = rule.Domain, "https"
+		// 域名中带有协议时以其为准
+		if rest, ok := strings.CutPrefix(host, "http://"); ok {
+			host, scheme = rest, "http"
+		} else if rest, ok := strings.CutPrefix(host, "https://"); ok {
+			host = rest
+		}
+		host = strings.TrimSuffix(host, "/")
+	}
+	switch strings.ToLower(rule.Scheme) {
+	case "http", "https":
+		scheme = strings.ToLower(rule.Scheme)
+	case "relative":
+		scheme = ""
+	}
+
+	path := u.EscapedPath()
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	// 按整段路径去掉前缀，/bucket 不会匹配 /bucket2
+	if strip := strings.Trim(rule.StripPrefix, "/"); strip != "" {
+		if path == "/"+strip {
+			path = "/"
+		} else if rest, ok := strings.CutPrefix(path, "/"+strip+"/"); ok {
+			path = "/" + rest
+		}
+	}
+	if prefix := strings.Trim(rule.PathPrefix, "/"); prefix != "" {
+		path = "/" + prefix + "/" + strings.TrimLeft(path, "/")
+	}
+
+	var b strings.Builder
+	if host != "" {
+		if scheme != "" {
+			b.WriteString(scheme + ":")
+		}
+		b.WriteString("//" + host)
+	}
+	b.WriteString(path)
+	if u.RawQuery != "" {
+		b.WriteString("?" + u.RawQuery)
+	}
+	if u.Fragment != "" {
+		b.WriteString("#" + u.EscapedFragment())
+	}
+	return b.String()
+}
+
+// rewriteUploadResult 改写上传响应中的访问URL，元数据已按原始地址记录
+func (app *App) rewriteUploadResult(backend string, result fiber.Map) {
+	if v, ok := result["url"].(string); ok {
+		result["url"] = app.rewriteFileURL(backend, v, false)
+	}
+	if v, ok := result["variants"].([]FileVariant); ok {
+		result["variants"] = app.rewriteFileVariants(backend, v)
+	}
+}
+
+// rewriteFileVariants 返回按改写规则替换访问URL后的派生图片副本
+func (app *App) rewriteFileVariants(backend string, variants []FileVariant) []FileVariant {
+	if len(variants) == 0 {
+		return variants
+	}
+	rewritten := make([]FileVariant, len(variants))
+	for i, variant := range variants {
+		variant.URL = app.rewriteFileURL(backend, variant.URL, false)
+		rewritten[i] = variant
+	}
+	return rewritten
+}
@@ -0,0 +1,99 @@
+package mod
+
+import (
+	"sync"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// requestValuesKey 请求级存储在 Fiber locals 中的键，使用私有类型避免与业务 locals 冲突
+type requestValuesKey struct{}
+
+// requestValues 请求级键值存储，处理函数中启动的协程也可安全读写
+type requestValues struct {
+	mu     sync.RWMutex
+	values map[string]any
+}
+
+// valuesOf 返回请求的键值存储，create 为 false 且尚未创建时返回 nil
+func valuesOf(c *fiber.Ctx, create bool) *requestValues {
+	if v, ok := c.Locals(requestValuesKey{}).(*requestValues); ok {
+		return v
+	}
+	if !create {
+		return nil
+	}
+	v := &requestValues{values: make(map[string]any)}
+	c.Locals(requestValuesKey{}, v)
+	return v
+}
+
+// SetValue 在 Fiber 中间件中写入请求级数据，处理函数可通过 mod.CtxValue 读取
+func SetValue(c *fiber.Ctx, key string, value any) {
+	v := valuesOf(c, true)
+	v.mu.Lock()
+	v.values[key] = value
+	v.mu.Unlock()
+}
+
+// Value 在 Fiber 中间件中读取请求级数据，不存在或类型不匹配时返回零值和 false
+func Value[T any](c *fiber.Ctx, key string) (T, bool) {
+	var zero T
+	v := valuesOf(c, false)
+	if v == nil {
+		return zero, false
+	}
+	v.mu.RLock()
+	raw, ok := v.values[key]
+	v.mu.RUnlock()
+	if !ok {
+		return zero, false
+	}
+	value, ok := raw.(T)
+	return value, ok
+}
+
+// Set 写入请求级数据（当前用户、租户、功能开关等），仅在本次请求内有效。
+// 注意：该方法覆盖了 fiber.Ctx 的 Set，设置响应头请使用 ctx.Ctx.Set
+func (c *Context) Set(key string, value any) {
+	SetValue(c.Ctx, key, value)
+}
+
+// Delete 删除请求级数据
+func (c *Context) Delete(key string) {
+	if v := valuesOf(c.Ctx, false); v != nil {
+		v.mu.Lock()
+		delete(v.values, key)
+		v.mu.Unlock()
+	}
+}
+
+// Values 返回当前请求全部请求级数据的副本
+func (c *Context) Values() map[string]any {
+	v := valuesOf(c.Ctx, false)
+	if v == nil {
+		return map[string]any{}
+	}
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	values := make(map[string]any, len(v.values))
+	for key, value := range v.values {
+		values[key] = value
+	}
+	return values
+}
+
+// CtxValue 读取请求级数据并转换为指定类型，不存在或类型不匹配时返回零值和 false
+//
+//	user, ok := mod.CtxValue[*User](ctx, "user")
+func CtxValue[T any](ctx *Context, key string) (T, bool) {
+	return Value[T](ctx.Ctx, key)
+}
+
+// CtxValueOr 读取请求级数据，不存在或类型不匹配时返回默认值
+func CtxValueOr[T any](ctx *Context, key string, def T) T {
+	if value, ok := CtxValue[T](ctx, key); ok {
+		return value
+	}
+	return def
+}
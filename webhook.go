@@ -0,0 +1,426 @@
+package mod
+
+import (
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/sirupsen/logrus"
+)
+
+// Webhook 签名方案
+const (
+	WebhookStripe    = "stripe"    // Stripe-Signature: t=<时间戳>,v1=HMAC-SHA256(secret, t + "." + body)
+	WebhookGitHub    = "github"    // X-Hub-Signature-256: sha256=HMAC-SHA256(secret, body)
+	WebhookWeChatPay = "wechatpay" // 微信支付 APIv3：平台公钥校验 Wechatpay-Signature
+	WebhookAlipay    = "alipay"    // 支付宝异步通知：支付宝公钥校验 RSA2 签名
+)
+
+// WebhookConfig 服务的 Webhook 签名校验配置，校验通过后记录投递标识，重复投递的请求被拒绝
+type WebhookConfig struct {
+	Provider      string `yaml:"provider"`        // 签名方案：stripe、github、wechatpay、alipay
+	Secret        string `yaml:"secret"`          // Stripe、GitHub 的签名密钥
+	PublicKey     string `yaml:"public_key"`      // 微信支付平台公钥（证书）或支付宝公钥，PEM 或 base64 编码的 DER
+	PublicKeyFile string `yaml:"public_key_file"` // 公钥文件，未设置 public_key 时使用
+	Tolerance     string `yaml:"tolerance"`       // 签名时间戳允许的偏差，默认5m
+	ReplayWindow  string `yaml:"replay_window"`   // 无签名时间戳（GitHub、支付宝）时投递标识的保留时间，默认24h
+}
+
+// webhookVerifier 解析后的 Webhook 校验配置
+type webhookVerifier struct {
+	provider     string
+	secret       []byte
+	publicKey    *rsa.PublicKey
+	tolerance    time.Duration
+	replayWindow time.Duration
+}
+
+// webhookDelivery 校验通过的一次投递
+type webhookDelivery struct {
+	id        string    // 投递标识，用于防重放
+	timestamp time.Time // 签名时间戳，为零时不检查时间偏差
+}
+
+// webhookState 各服务的 Webhook 校验配置，以及未配置 Redis、BadgerDB 时进程内存中的投递记录
+type webhookState struct {
+	mu        sync.Mutex
+	verifiers map[string]*webhookVerifier
+	errors    map[string]error
+	seen      map[string]time.Time
+	lastSweep time.Time
+}
+
+// webhookConfig 返回服务的 Webhook 配置，mod.yml 中 webhook.services 的配置优先于 Service.Webhook
+func (app *App) webhookConfig(svc *Service) *WebhookConfig {
+	if config, ok := app.cfg.ModConfig.Webhook.Services[svc.Name]; ok {
+		return &config
+	}
+	return svc.Webhook
+}
+
+// webhookVerifier 解析并缓存服务的 Webhook 校验配置，未配置时返回 nil
+func (app *App) webhookVerifier(svc *Service) (*webhookVerifier, error) {
+	app.webhooks.mu.Lock()
+	defer app.webhooks.mu.Unlock()
+	if verifier, ok := app.webhooks.verifiers[svc.Name]; ok {
+		return verifier, app.webhooks.errors[svc.Name]
+	}
+
+	var verifier *webhookVerifier
+	var err error
+	if config := app.webhookConfig(svc); config != nil {
+		verifier, err = newWebhookVerifier(*config)
+	}
+	if app.webhooks.verifiers == nil {
+		app.webhooks.verifiers = map[string]*webhookVerifier{}
+		app.webhooks.errors = map[string]error{}
+	}
+	app.webhooks.verifiers[svc.Name] = verifier
+	app.webhooks.errors[svc.Name] = err
+	return verifier, err
+}
+
+// newWebhookVerifier 检查并解析 Webhook 配置
+func newWebhookVerifier(config WebhookConfig) (*webhookVerifier, error) {
+	verifier := &webhookVerifier{
+		provider:     strings.ToLower(config.Provider),
+		secret:       []byte(config.Secret),
+		tolerance:    5 * time.Minute,
+		replayWindow: 24 * time.Hour,
+	}
+	if config.Tolerance != "" {
+		d, err := time.ParseDuration(config.Tolerance)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid webhook tolerance %q", config.Tolerance)
+		}
+		verifier.tolerance = d
+	}
+	if config.ReplayWindow != "" {
+		d, err := time.ParseDuration(config.ReplayWindow)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid webhook replay_window %q", config.ReplayWindow)
+		}
+		verifier.replayWindow = d
+	}
+
+	switch verifier.provider {
+	case WebhookStripe, WebhookGitHub:
+		if config.Secret == "" {
+			return nil, fmt.Errorf("webhook provider %s requires a secret", verifier.provider)
+		}
+	case WebhookWeChatPay, WebhookAlipay:
+		key := config.PublicKey
+		if key == "" && config.PublicKeyFile != "" {
+			data, err := os.ReadFile(config.PublicKeyFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read webhook public key: %w", err)
+			}
+			key = string(data)
+		}
+		if key == "" {
+			return nil, fmt.Errorf("webhook provider %s requires a public key", verifier.provider)
+		}
+		publicKey, err := parseRSAPublicKey(key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid webhook public key: %w", err)
+		}
+		verifier.publicKey = publicKey
+	default:
+		return nil, fmt.Errorf("unknown webhook provider %q", config.Provider)
+	}
+	return verifier, nil
+}
+
+// parseRSAPublicKey 解析 PEM（公钥或证书）或 base64 编码的 DER 格式的RSA公钥，支付宝开放平台提供的公钥为后者
+func parseRSAPublicKey(data string) (*rsa.PublicKey, error) {
+	data = strings.TrimSpace(data)
+	var der []byte
+	if block, _ := pem.Decode([]byte(data)); block != nil {
+		if block.Type == "CERTIFICATE" {
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return nil, err
+			}
+			if key, ok := cert.PublicKey.(*rsa.PublicKey); ok {
+				return key, nil
+			}
+			return nil, fmt.Errorf("certificate does not contain an RSA public key")
+		}
+		der = block.Bytes
+	} else {
+		decoded, err := base64.StdEncoding.DecodeString(data)
+		if err != nil {
+			return nil, fmt.Errorf("neither PEM nor base64: %w", err)
+		}
+		der = decoded
+	}
+
+	if key, err := x509.ParsePKIXPublicKey(der); err == nil {
+		if rsaKey, ok := key.(*rsa.PublicKey); ok {
+			return rsaKey, nil
+		}
+		return nil, fmt.Errorf("not an RSA public key")
+	}
+	return x509.ParsePKCS1PublicKey(der)
+}
+
+// verify 按签名方案校验请求，返回投递标识和签名时间戳
+func (v *webhookVerifier) verify(ctx *Context) (webhookDelivery, error) {
+	body := ctx.Body()
+	switch v.provider {
+	case WebhookStripe:
+		return verifyStripeWebhook(v.secret, ctx.Get("Stripe-Signature"), body)
+	case WebhookGitHub:
+		return verifyGitHubWebhook(v.secret, ctx.Get("X-Hub-Signature-256"), body)
+	case WebhookWeChatPay:
+		return verifyWeChatPayWebhook(v.publicKey, ctx.Get("Wechatpay-Timestamp"), ctx.Get("Wechatpay-Nonce"), ctx.Get("Wechatpay-Signature"), body)
+	case WebhookAlipay:
+		return verifyAlipayWebhook(v.publicKey, body)
+	}
+	return webhookDelivery{}, fmt.Errorf("unknown webhook provider %q", v.provider)
+}
+
+// verifyStripeWebhook 校验 Stripe-Signature，请求头可能包含多个 v1 签名（密钥轮换期间）
+func verifyStripeWebhook(secret []byte, header string, body []byte) (webhookDelivery, error) {
+	if header == "" {
+		return webhookDelivery{}, fmt.Errorf("missing Stripe-Signature header")
+	}
+	var timestamp string
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return webhookDelivery{}, fmt.Errorf("invalid Stripe-Signature timestamp %q", timestamp)
+	}
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	expected := hex.EncodeToString(mac.Sum(nil))
+	for _, signature := range signatures {
+		if hmac.Equal([]byte(signature), []byte(expected)) {
+			return webhookDelivery{id: "t=" + timestamp + ",v1=" + signature, timestamp: time.Unix(seconds, 0)}, nil
+		}
+	}
+	return webhookDelivery{}, fmt.Errorf("signature mismatch")
+}
+
+// verifyGitHubWebhook 校验 X-Hub-Signature-256，以签名作为投递标识：X-GitHub-Delivery 不在签名范围内，
+// 可以被任意修改，不能用于防重放
+func verifyGitHubWebhook(secret []byte, header string, body []byte) (webhookDelivery, error) {
+	signature, ok := strings.CutPrefix(header, "sha256=")
+	if !ok {
+		return webhookDelivery{}, fmt.Errorf("missing or invalid X-Hub-Signature-256 header")
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	if !hmac.Equal([]byte(signature), []byte(hex.EncodeToString(mac.Sum(nil)))) {
+		return webhookDelivery{}, fmt.Errorf("signature mismatch")
+	}
+	return webhookDelivery{id: signature}, nil
+}
+
+// verifyWeChatPayWebhook 校验微信支付 APIv3 回调签名：SHA256withRSA(时间戳\n随机串\n请求体\n)
+func verifyWeChatPayWebhook(publicKey *rsa.PublicKey, timestamp, nonce, signature string, body []byte) (webhookDelivery, error) {
+	if timestamp == "" || nonce == "" || signature == "" {
+		return webhookDelivery{}, fmt.Errorf("missing Wechatpay-Timestamp, Wechatpay-Nonce or Wechatpay-Signature header")
+	}
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return webhookDelivery{}, fmt.Errorf("invalid Wechatpay-Timestamp %q", timestamp)
+	}
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return webhookDelivery{}, fmt.Errorf("invalid Wechatpay-Signature: %w", err)
+	}
+	message := timestamp + "\n" + nonce + "\n" + string(body) + "\n"
+	digest := sha256.Sum256([]byte(message))
+	if err := rsa.VerifyPKCS1v15(publicKey, crypto.SHA256, digest[:], sig); err != nil {
+		return webhookDelivery{}, fmt.Errorf("signature mismatch")
+	}
+	return webhookDelivery{id: timestamp + "\n" + nonce, timestamp: time.Unix(seconds, 0)}, nil
+}
+
+// verifyAlipayWebhook 校验支付宝异步通知：除 sign、sign_type 外的非空参数按参数名排序后以 k=v&k=v 拼接，
+// 使用支付宝公钥校验 RSA2（SHA256withRSA）签名，以 notify_id 作为投递标识
+func verifyAlipayWebhook(publicKey *rsa.PublicKey, body []byte) (webhookDelivery, error) {
+	params, err := url.ParseQuery(string(body))
+	if err != nil {
+		return webhookDelivery{}, fmt.Errorf("invalid notification body: %w", err)
+	}
+	signature := params.Get("sign")
+	if signature == "" {
+		return webhookDelivery{}, fmt.Errorf("missing sign parameter")
+	}
+	if signType := params.Get("sign_type"); signType != "" && signType != "RSA2" {
+		return webhookDelivery{}, fmt.Errorf("unsupported sign_type %q, only RSA2 is supported", signType)
+	}
+
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		if key != "sign" && key != "sign_type" && params.Get(key) != "" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	pairs := make([]string, len(keys))
+	for i, key := range keys {
+		pairs[i] = key + "=" + params.Get(key)
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return webhookDelivery{}, fmt.Errorf("invalid sign: %w", err)
+	}
+	digest := sha256.Sum256([]byte(strings.Join(pairs, "&")))
+	if err := rsa.VerifyPKCS1v15(publicKey, crypto.SHA256, digest[:], sig); err != nil {
+		return webhookDelivery{}, fmt.Errorf("signature mismatch")
+	}
+	id := params.Get("notify_id")
+	if id == "" {
+		id = signature
+	}
+	return webhookDelivery{id: id}, nil
+}
+
+// verifyWebhook 校验 Webhook 服务的签名和投递时间，并记录投递标识防止重放；
+// 返回的函数在服务处理结束后调用，处理失败（状态码>=400）时删除投递记录，使平台的重试能够再次送达
+func (app *App) verifyWebhook(ctx *Context, svc *Service) (func(), error) {
+	verifier, err := app.webhookVerifier(svc)
+	if err != nil {
+		app.logger.WithFields(logrus.Fields{"service": svc.Name, "error": err.Error()}).Error("Invalid webhook configuration")
+		return nil, Reply(500, "Webhook verification is not configured")
+	}
+	if verifier == nil {
+		return func() {}, nil
+	}
+
+	fields := logrus.Fields{"service": svc.Name, "provider": verifier.provider, "rid": ctx.GetRequestID()}
+	delivery, err := verifier.verify(ctx)
+	if err != nil {
+		fields["error"] = err.Error()
+		app.logger.WithFields(fields).Warn("Webhook signature verification failed")
+		return nil, ReplyWithDetail(401, "Invalid webhook signature", err.Error())
+	}
+
+	ttl := verifier.replayWindow
+	if !delivery.timestamp.IsZero() {
+		if skew := app.now().Sub(delivery.timestamp); skew > verifier.tolerance || skew < -verifier.tolerance {
+			app.logger.WithFields(fields).Warn("Webhook timestamp outside tolerance")
+			return nil, ReplyWithDetail(401, "Invalid webhook signature", "timestamp is outside the allowed window of "+verifier.tolerance.String())
+		}
+		// 超出时间偏差的请求已被拒绝，投递记录只需保留到时间窗口结束
+		ttl = 2 * verifier.tolerance
+	}
+
+	digest := sha256.Sum256([]byte(delivery.id))
+	key := "mod:webhook:" + svc.Name + ":" + hex.EncodeToString(digest[:])
+	fresh, err := app.claimWebhookDelivery(key, ttl)
+	if err != nil {
+		// 缓存不可用时不阻断回调，签名已校验通过
+		app.logger.WithFields(fields).WithError(err).Error("Failed to record webhook delivery")
+		return func() {}, nil
+	}
+	if !fresh {
+		app.logger.WithFields(fields).Warn("Duplicate webhook delivery rejected")
+		return nil, Reply(409, "Duplicate webhook delivery")
+	}
+	return func() {
+		if ctx.Response().StatusCode() >= 400 {
+			app.releaseWebhookDelivery(key)
+		}
+	}, nil
+}
+
+// claimWebhookDelivery 记录投递标识，已存在时返回 false；依次使用 Redis、BadgerDB，均未配置时记录在进程内存中
+func (app *App) claimWebhookDelivery(key string, ttl time.Duration) (bool, error) {
+	switch {
+	case app.redisClient != nil:
+		return app.redisClient.SetNX(context.Background(), key, time.Now().Unix(), ttl).Result()
+	case app.badgerDB != nil:
+		fresh := false
+		err := app.badgerDB.Update(func(txn *badger.Txn) error {
+			if _, err := txn.Get([]byte(key)); err == nil {
+				return nil
+			} else if err != badger.ErrKeyNotFound {
+				return err
+			}
+			fresh = true
+			return txn.SetEntry(badger.NewEntry([]byte(key), []byte(strconv.FormatInt(time.Now().Unix(), 10))).WithTTL(ttl))
+		})
+		if err == badger.ErrConflict {
+			// 并发写入同一投递标识
+			return false, nil
+		}
+		return fresh, err
+	}
+
+	now := app.now()
+	app.webhooks.mu.Lock()
+	defer app.webhooks.mu.Unlock()
+	app.webhooks.sweep(now)
+	if expires, ok := app.webhooks.seen[key]; ok && now.Before(expires) {
+		return false, nil
+	}
+	if app.webhooks.seen == nil {
+		app.webhooks.seen = map[string]time.Time{}
+	}
+	app.webhooks.seen[key] = now.Add(ttl)
+	return true, nil
+}
+
+// releaseWebhookDelivery 删除投递记录
+func (app *App) releaseWebhookDelivery(key string) {
+	var err error
+	switch {
+	case app.redisClient != nil:
+		err = app.redisClient.Del(context.Background(), key).Err()
+	case app.badgerDB != nil:
+		err = app.badgerDB.Update(func(txn *badger.Txn) error {
+			return txn.Delete([]byte(key))
+		})
+	default:
+		app.webhooks.mu.Lock()
+		delete(app.webhooks.seen, key)
+		app.webhooks.mu.Unlock()
+	}
+	if err != nil {
+		app.logger.WithError(err).Warn("Failed to release webhook delivery")
+	}
+}
+
+// sweep 每分钟清理一次过期的投递记录
+func (s *webhookState) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < time.Minute {
+		return
+	}
+	s.lastSweep = now
+	for key, expires := range s.seen {
+		if !now.Before(expires) {
+			delete(s.seen, key)
+		}
+	}
+}
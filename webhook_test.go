@@ -0,0 +1,46 @@
+package mod
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+)
+
+func TestGitHubWebhookRejectsReplayWithNewDeliveryID(t *testing.T) {
+	app := newTestApp(t, "")
+	secret := "github-webhook-secret"
+	err := app.Register(Service{
+		Name:        "github_webhook",
+		DisplayName: "github_webhook",
+		SkipAuth:    true,
+		Webhook:     &WebhookConfig{Provider: WebhookGitHub, Secret: secret},
+		Handler: MakeHandler(func(ctx *Context, req *pingRequest, resp *pingResponse) error {
+			resp.Value = req.Value
+			return nil
+		}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body := `{"value":"push"}`
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	signature := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	send := func(delivery, signature string) *TestResponse {
+		resp, err := app.TestClient().
+			WithHeader("X-Hub-Signature-256", signature).
+			WithHeader("X-GitHub-Delivery", delivery).
+			Post(app.ServicePath("github_webhook"), body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	send("delivery-1", signature).AssertStatus(t, 200).AssertSuccess(t)
+	// 重放同一个已签名的请求体，即使更换投递标识也被拒绝
+	send("delivery-2", signature).AssertStatus(t, 409).AssertMsg(t, "Duplicate webhook delivery")
+	send("delivery-3", "sha256="+hex.EncodeToString(make([]byte, 32))).AssertStatus(t, 401)
+}
@@ -0,0 +1,809 @@
+package mod
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+)
+
+// WebSocket 关闭状态码（RFC 6455），处理函数返回 StdReply 错误时使用 4000+错误码（如 4403）
+const (
+	WSCloseNormal        = 1000 // 正常关闭
+	WSCloseGoingAway     = 1001 // 服务关闭
+	WSCloseProtocolError = 1002 // 协议错误
+	WSCloseInvalidData   = 1007 // 文本消息不是有效的 UTF-8
+	WSClosePolicy        = 1008 // 违反策略，如发送队列已满
+	WSCloseTooLarge      = 1009 // 消息超过 websocket.max_message_size
+	WSCloseInternalError = 1011 // 处理函数返回错误
+)
+
+// ErrWSClosed 连接已关闭，处理函数可以直接返回
+var ErrWSClosed = errors.New("websocket: connection closed")
+
+// wsGUID 计算 Sec-WebSocket-Accept 使用的固定值
+const wsGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// 帧类型
+const (
+	wsOpContinuation = 0x0
+	wsOpText         = 0x1
+	wsOpBinary       = 0x2
+	wsOpClose        = 0x8
+	wsOpPing         = 0x9
+	wsOpPong         = 0xA
+)
+
+// WSHandler WebSocket 处理函数，连接建立后调用，返回时关闭连接
+type WSHandler func(ws *WSContext) error
+
+// WS WebSocket 服务，注册为 GET 接口，握手请求与普通服务一样经过认证、权限、限流等检查
+type WS struct {
+	Name        string    `validate:"required"`
+	DisplayName string    `validate:"required"`
+	Handler     WSHandler `validate:"required"`
+
+	Description string
+	Group       string
+	Sort        int
+	Path        string            // 服务前缀下的路径模板，如 rooms/:id，路径参数通过 ws.Params 读取；为空时使用服务名称
+	SkipAuth    bool              // 不需要认证
+	Auth        AuthStrategy      // 认证方式，为空时使用默认配置；浏览器无法设置请求头，可通过查询参数携带token
+	Permission  *PermissionConfig // 权限规则
+	Scopes      []string          // 所需的Token权限范围
+	Throttle    *ThrottleRule     // 防刷限流规则，如限制每个IP的建立连接次数
+
+	ClientMessage any `validate:"-"` // 客户端发送的消息结构体，用于生成文档
+	ServerMessage any `validate:"-"` // 服务端推送的消息结构体，用于生成文档
+}
+
+// wsService 注册时保存的消息类型，用于生成文档
+type wsService struct {
+	clientType reflect.Type
+	serverType reflect.Type
+}
+
+// wsRequest WebSocket 服务没有请求参数，路径和查询参数通过 ws.Params、ws.Query 读取
+type wsRequest struct{}
+
+// wsResponse WebSocket 服务没有响应数据，消息通过连接发送
+type wsResponse struct{}
+
+// WSContext WebSocket 连接的上下文。握手请求在连接建立前已经结束，这里保存了请求ID、用户、路径和查询参数的副本。
+// Send 和 Broadcast 可以在多个 goroutine 中调用，Receive 只能在一个 goroutine 中调用
+type WSContext struct {
+	app     *App
+	service string
+	rid     string
+	ip      string
+	user    *AuthUser
+	params  map[string]string
+	query   map[string]string
+	logger  *logrus.Entry
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	conn         net.Conn
+	reader       *bufio.Reader
+	in           chan []byte
+	out          chan wsFrame
+	writeMu      sync.Mutex
+	closeOnce    sync.Once
+	closed       chan struct{}
+	maxSize      int64
+	pingInterval time.Duration
+	writeTimeout time.Duration
+
+	rooms map[string]bool // 已加入的房间，由 wsHub.mu 保护
+}
+
+// wsFrame 待发送的帧
+type wsFrame struct {
+	opcode  byte
+	payload []byte
+}
+
+// wsCloseError 读取时发现的协议错误，按对应状态码关闭连接
+type wsCloseError struct {
+	code   int
+	reason string
+}
+
+func (e *wsCloseError) Error() string {
+	return fmt.Sprintf("websocket: %s (%d)", e.reason, e.code)
+}
+
+// wsHub 当前实例的 WebSocket 连接和房间，挂载的子应用与父应用共用；
+// 配置 Redis 时广播通过发布订阅发送到所有实例
+type wsHub struct {
+	mu            sync.Mutex
+	conns         map[*WSContext]struct{}
+	rooms         map[string]map[*WSContext]struct{}
+	instance      string
+	subscribeOnce sync.Once
+}
+
+// wsBroadcast 通过 Redis 发送到其他实例的广播
+type wsBroadcast struct {
+	Instance string          `json:"instance"`
+	Room     string          `json:"room"`
+	Data     json.RawMessage `json:"data"`
+}
+
+// configureWebSocket 初始化 WebSocket 连接状态，并在关闭服务时断开所有连接，使处理函数结束
+func (app *App) configureWebSocket() {
+	app.websocket = &wsHub{
+		conns:    map[*WSContext]struct{}{},
+		rooms:    map[string]map[*WSContext]struct{}{},
+		instance: app.NextID(),
+	}
+	hub := app.websocket
+	app.Hooks().OnShutdown(func() error {
+		hub.mu.Lock()
+		conns := make([]*WSContext, 0, len(hub.conns))
+		for ws := range hub.conns {
+			conns = append(conns, ws)
+		}
+		hub.mu.Unlock()
+		for _, ws := range conns {
+			ws.Close(WSCloseGoingAway, "server shutting down")
+		}
+		return nil
+	})
+}
+
+// RegisterWS 注册 WebSocket 服务：握手请求经过认证、权限、限流等检查后升级为 WebSocket 连接，
+// 处理函数通过 ws.Receive、ws.Send 收发JSON消息，通过 ws.Join 和 app.Broadcast 向房间推送
+func (app *App) RegisterWS(ws WS) error {
+	if err := validate.Struct(ws); err != nil {
+		return fmt.Errorf("websocket %q: %w", ws.Name, err)
+	}
+	info := &wsService{}
+	if ws.ClientMessage != nil {
+		info.clientType = reflect.TypeOf(ws.ClientMessage)
+	}
+	if ws.ServerMessage != nil {
+		info.serverType = reflect.TypeOf(ws.ServerMessage)
+	}
+	handler := ws.Handler
+	description := ws.Description
+	if description == "" {
+		description = "WebSocket 连接（GET 升级）"
+	}
+	return app.Register(Service{
+		Name:           ws.Name,
+		DisplayName:    ws.DisplayName,
+		Description:    description,
+		Group:          ws.Group,
+		Sort:           ws.Sort,
+		Path:           ws.Path,
+		SkipAuth:       ws.SkipAuth,
+		Auth:           ws.Auth,
+		Permission:     ws.Permission,
+		RequiredScopes: ws.Scopes,
+		Throttle:       ws.Throttle,
+		Handler: Handler{
+			Func: func(ctx *Context, args any, reply any) error {
+				return app.upgradeWS(ctx, handler)
+			},
+			InputType:  reflect.TypeOf(wsRequest{}),
+			OutputType: reflect.TypeOf(wsResponse{}),
+		},
+		methods: []string{fiber.MethodGet},
+		ws:      info,
+	})
+}
+
+// upgradeWS 校验握手请求并响应101，连接交给 fasthttp 后在新的 goroutine 中运行处理函数
+func (app *App) upgradeWS(ctx *Context, handler WSHandler) error {
+	c := ctx.Ctx
+	if !headerHasToken(c.Get(fiber.HeaderConnection), "upgrade") || !headerHasToken(c.Get(fiber.HeaderUpgrade), "websocket") {
+		c.Set("Sec-WebSocket-Version", "13")
+		return ReplyWithDetail(fiber.StatusUpgradeRequired, "Upgrade Required", "expected a WebSocket handshake")
+	}
+	if c.Get("Sec-WebSocket-Version") != "13" {
+		c.Set("Sec-WebSocket-Version", "13")
+		return ReplyWithDetail(400, "Unsupported WebSocket version", "only version 13 is supported")
+	}
+	key := c.Get("Sec-WebSocket-Key")
+	if key == "" {
+		return ReplyWithDetail(400, "Invalid WebSocket handshake", "Sec-WebSocket-Key header is missing")
+	}
+	if !app.wsOriginAllowed(c) {
+		return ReplyWithDetail(403, "Origin not allowed", "origin "+c.Get(fiber.HeaderOrigin)+" is not in websocket.allowed_origins")
+	}
+
+	// 握手请求结束后 fiber.Ctx 会被复用，连接中使用的数据需要复制
+	ws := &WSContext{
+		app:          app,
+		service:      ctx.service.Name,
+		rid:          strings.Clone(ctx.GetRequestID()),
+		ip:           strings.Clone(ctx.IP()),
+		params:       map[string]string{},
+		query:        map[string]string{},
+		maxSize:      app.wsMaxMessageSize(),
+		pingInterval: app.wsDuration(app.cfg.ModConfig.WebSocket.PingInterval, "ping_interval", 30*time.Second),
+		writeTimeout: app.wsDuration(app.cfg.ModConfig.WebSocket.WriteTimeout, "write_timeout", 10*time.Second),
+		closed:       make(chan struct{}),
+		rooms:        map[string]bool{},
+	}
+	if user, ok := ctx.User(); ok {
+		copied := *user
+		ws.user = &copied
+	}
+	for name, value := range c.AllParams() {
+		ws.params[strings.Clone(name)] = strings.Clone(value)
+	}
+	c.Context().QueryArgs().VisitAll(func(key, value []byte) {
+		ws.query[string(key)] = string(value)
+	})
+	ws.logger = app.logger.WithFields(logrus.Fields{"rid": ws.rid, "service": ws.service})
+	queue := app.cfg.ModConfig.WebSocket.SendQueue
+	if queue <= 0 {
+		queue = 64
+	}
+	ws.in = make(chan []byte, queue)
+	ws.out = make(chan wsFrame, queue)
+
+	sum := sha1.Sum([]byte(key + wsGUID))
+	c.Status(fiber.StatusSwitchingProtocols)
+	c.Set(fiber.HeaderUpgrade, "websocket")
+	c.Set(fiber.HeaderConnection, "Upgrade")
+	c.Set("Sec-WebSocket-Accept", base64.StdEncoding.EncodeToString(sum[:]))
+	markResponseSent(c)
+
+	c.Context().Hijack(func(conn net.Conn) {
+		// 清除 HTTP 服务设置的读写超时，由心跳检测连接状态
+		conn.SetDeadline(time.Time{})
+		ws.conn = conn
+		ws.reader = bufio.NewReader(conn)
+		ws.ctx, ws.cancel = context.WithCancel(context.Background())
+		app.serveWS(ws, handler)
+	})
+	return nil
+}
+
+// serveWS 运行处理函数直到返回或连接断开，处理函数作为后台任务登记，关闭服务时等待其结束
+func (app *App) serveWS(ws *WSContext, handler WSHandler) {
+	hub := app.websocket
+	hub.mu.Lock()
+	hub.conns[ws] = struct{}{}
+	hub.mu.Unlock()
+	if app.redisClient != nil {
+		app.subscribeWS()
+	}
+	ws.logger.WithField("ip", ws.ip).Info("WebSocket connected")
+	started := time.Now()
+
+	go ws.writeLoop()
+	go ws.readLoop()
+	done := make(chan struct{})
+	app.spawn(ws.logger, func() {
+		defer close(done)
+		defer func() {
+			if r := recover(); r != nil {
+				ws.Close(WSCloseInternalError, "internal error")
+				panic(r)
+			}
+		}()
+		err := handler(ws)
+		var reply *StdReply
+		switch {
+		case err == nil || errors.Is(err, ErrWSClosed):
+			ws.Close(WSCloseNormal, "")
+		case errors.As(err, &reply):
+			code := WSClosePolicy
+			if reply.code >= 100 && reply.code <= 999 {
+				code = 4000 + reply.code
+			}
+			ws.Close(code, reply.msg)
+		default:
+			ws.logger.WithError(err).Error("WebSocket handler failed")
+			ws.Close(WSCloseInternalError, "internal error")
+		}
+	})
+	<-done
+
+	hub.mu.Lock()
+	delete(hub.conns, ws)
+	for room := range ws.rooms {
+		hub.leave(room, ws)
+	}
+	hub.mu.Unlock()
+	ws.logger.WithField("duration", time.Since(started).String()).Info("WebSocket disconnected")
+}
+
+// Context 返回连接的上下文，连接关闭或服务关闭时取消
+func (ws *WSContext) Context() context.Context {
+	return ws.ctx
+}
+
+// App 返回应用实例
+func (ws *WSContext) App() *App {
+	return ws.app
+}
+
+// GetRequestID 返回握手请求的请求ID
+func (ws *WSContext) GetRequestID() string {
+	return ws.rid
+}
+
+// Logger 返回带有请求ID和服务名称的日志记录器
+func (ws *WSContext) Logger() *logrus.Entry {
+	return ws.logger
+}
+
+// User 返回握手请求认证的用户，未认证时返回 nil 和 false
+func (ws *WSContext) User() (*AuthUser, bool) {
+	return ws.user, ws.user != nil
+}
+
+// IP 返回客户端IP
+func (ws *WSContext) IP() string {
+	return ws.ip
+}
+
+// Params 返回握手请求的路径参数
+func (ws *WSContext) Params(key string, defaultValue ...string) string {
+	if value, ok := ws.params[key]; ok && value != "" {
+		return value
+	}
+	if len(defaultValue) > 0 {
+		return defaultValue[0]
+	}
+	return ""
+}
+
+// Query 返回握手请求的查询参数
+func (ws *WSContext) Query(key string, defaultValue ...string) string {
+	if value, ok := ws.query[key]; ok && value != "" {
+		return value
+	}
+	if len(defaultValue) > 0 {
+		return defaultValue[0]
+	}
+	return ""
+}
+
+// Send 将 v 序列化为JSON，作为文本消息发送；发送队列已满时等待，超过 websocket.write_timeout 后断开连接
+func (ws *WSContext) Send(v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return ReplyWithDetail(400, "Message cannot be serialized", err.Error())
+	}
+	return ws.enqueue(wsFrame{opcode: wsOpText, payload: data})
+}
+
+// SendText 发送文本消息
+func (ws *WSContext) SendText(text string) error {
+	return ws.enqueue(wsFrame{opcode: wsOpText, payload: []byte(text)})
+}
+
+// SendBinary 发送二进制消息
+func (ws *WSContext) SendBinary(data []byte) error {
+	return ws.enqueue(wsFrame{opcode: wsOpBinary, payload: data})
+}
+
+// Receive 等待客户端的下一条消息并按JSON解析到 v；连接关闭时返回 ErrWSClosed，
+// 消息不是有效的JSON时返回400错误，处理函数可以回复错误后继续接收
+func (ws *WSContext) Receive(v any) error {
+	data, err := ws.ReceiveBytes()
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return ReplyWithDetail(400, "Invalid message", err.Error())
+	}
+	return nil
+}
+
+// ReceiveBytes 等待客户端的下一条文本或二进制消息，连接关闭时返回 ErrWSClosed
+func (ws *WSContext) ReceiveBytes() ([]byte, error) {
+	select {
+	case data := <-ws.in:
+		return data, nil
+	case <-ws.closed:
+		return nil, ErrWSClosed
+	}
+}
+
+// Join 加入房间，之后可以收到发送到该房间的广播；连接关闭时自动离开所有房间
+func (ws *WSContext) Join(room string) {
+	hub := ws.app.websocket
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+	members, ok := hub.rooms[room]
+	if !ok {
+		members = map[*WSContext]struct{}{}
+		hub.rooms[room] = members
+	}
+	members[ws] = struct{}{}
+	ws.rooms[room] = true
+}
+
+// Leave 离开房间
+func (ws *WSContext) Leave(room string) {
+	hub := ws.app.websocket
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+	hub.leave(room, ws)
+}
+
+// Broadcast 向房间内除自己以外的所有连接发送消息，配置 Redis 时同时发送到其他实例
+func (ws *WSContext) Broadcast(room string, v any) error {
+	return ws.app.broadcast(room, v, ws)
+}
+
+// Close 发送关闭帧并断开连接，可以重复调用
+func (ws *WSContext) Close(code int, reason string) {
+	ws.closeOnce.Do(func() {
+		close(ws.closed)
+		if ws.cancel != nil {
+			ws.cancel()
+		}
+		// 关闭原因不超过123字节（控制帧载荷上限125字节）
+		if len(reason) > 123 {
+			reason = reason[:123]
+		}
+		payload := make([]byte, 2+len(reason))
+		binary.BigEndian.PutUint16(payload, uint16(code))
+		copy(payload[2:], reason)
+		ws.writeFrame(wsOpClose, payload)
+		ws.conn.Close()
+	})
+}
+
+// Broadcast 向房间内的所有连接发送JSON消息，可以在处理函数之外（如后台任务）调用；
+// 配置 Redis 时通过发布订阅发送到所有实例，否则只发送到当前进程内的连接
+func (app *App) Broadcast(room string, v any) error {
+	return app.broadcast(room, v, nil)
+}
+
+// broadcast 发送到本实例房间内的连接（exclude 除外），配置 Redis 时发布给其他实例
+func (app *App) broadcast(room string, v any, exclude *WSContext) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return ReplyWithDetail(400, "Message cannot be serialized", err.Error())
+	}
+	app.websocket.deliver(room, data, exclude)
+	if app.redisClient == nil {
+		return nil
+	}
+	app.subscribeWS()
+	payload, _ := json.Marshal(wsBroadcast{Instance: app.websocket.instance, Room: room, Data: data})
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	if err := app.redisClient.Publish(ctx, app.wsPrefix()+"broadcast", payload).Err(); err != nil {
+		app.logger.WithError(err).WithField("room", room).Error("Failed to publish websocket broadcast")
+		return err
+	}
+	return nil
+}
+
+// deliver 将消息放入房间内各连接的发送队列，队列已满的连接被断开，避免慢客户端阻塞广播
+func (hub *wsHub) deliver(room string, data []byte, exclude *WSContext) {
+	hub.mu.Lock()
+	members := make([]*WSContext, 0, len(hub.rooms[room]))
+	for ws := range hub.rooms[room] {
+		if ws != exclude {
+			members = append(members, ws)
+		}
+	}
+	hub.mu.Unlock()
+	for _, ws := range members {
+		select {
+		case ws.out <- wsFrame{opcode: wsOpText, payload: data}:
+		case <-ws.closed:
+		default:
+			ws.logger.WithField("room", room).Warn("WebSocket send queue is full, closing connection")
+			go ws.Close(WSClosePolicy, "send queue full")
+		}
+	}
+}
+
+// leave 将连接移出房间，房间为空时删除；调用方须持有 hub.mu
+func (hub *wsHub) leave(room string, ws *WSContext) {
+	delete(ws.rooms, room)
+	if members, ok := hub.rooms[room]; ok {
+		delete(members, ws)
+		if len(members) == 0 {
+			delete(hub.rooms, room)
+		}
+	}
+}
+
+// subscribeWS 首次使用时订阅其他实例的广播
+func (app *App) subscribeWS() {
+	hub := app.websocket
+	hub.subscribeOnce.Do(func() {
+		pubsub := app.redisClient.Subscribe(context.Background(), app.wsPrefix()+"broadcast")
+		go func() {
+			defer pubsub.Close()
+			for msg := range pubsub.Channel() {
+				var message wsBroadcast
+				if err := json.Unmarshal([]byte(msg.Payload), &message); err != nil || message.Instance == hub.instance {
+					continue
+				}
+				hub.deliver(message.Room, message.Data, nil)
+			}
+		}()
+	})
+}
+
+// wsPrefix 返回 Redis 频道前缀
+func (app *App) wsPrefix() string {
+	if prefix := app.cfg.ModConfig.WebSocket.CacheKeyPrefix; prefix != "" {
+		return prefix
+	}
+	return "mod:ws:"
+}
+
+// wsMaxMessageSize 返回客户端消息的最大字节数，默认1MB
+func (app *App) wsMaxMessageSize() int64 {
+	if value := app.cfg.ModConfig.WebSocket.MaxMessageSize; value != "" {
+		if size, err := parseSize(value); err == nil && size > 0 {
+			return size
+		}
+		app.logger.WithField("max_message_size", value).Warn("Invalid websocket max_message_size, using 1MB")
+	}
+	return 1 << 20
+}
+
+// wsDuration 解析 websocket 配置中的时长，无效时使用默认值
+func (app *App) wsDuration(value, name string, fallback time.Duration) time.Duration {
+	if value == "" {
+		return fallback
+	}
+	if d, err := time.ParseDuration(value); err == nil && d > 0 {
+		return d
+	}
+	app.logger.WithField(name, value).Warnf("Invalid websocket %s, using %s", name, fallback)
+	return fallback
+}
+
+// wsOriginAllowed 检查浏览器发起的握手请求的 Origin：未配置 websocket.allowed_origins 时只允许同源，
+// "*" 允许所有来源；非浏览器客户端不携带 Origin，不做限制
+func (app *App) wsOriginAllowed(c *fiber.Ctx) bool {
+	origin := c.Get(fiber.HeaderOrigin)
+	if origin == "" {
+		return true
+	}
+	allowed := app.cfg.ModConfig.WebSocket.AllowedOrigins
+	if len(allowed) == 0 {
+		u, err := url.Parse(origin)
+		return err == nil && strings.EqualFold(u.Host, c.Hostname())
+	}
+	for _, value := range allowed {
+		if value == "*" || strings.EqualFold(value, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// headerHasToken 判断逗号分隔的请求头中是否包含指定值（不区分大小写）
+func headerHasToken(header, token string) bool {
+	for _, value := range strings.Split(header, ",") {
+		if strings.EqualFold(strings.TrimSpace(value), token) {
+			return true
+		}
+	}
+	return false
+}
+
+// enqueue 将帧放入发送队列
+func (ws *WSContext) enqueue(frame wsFrame) error {
+	select {
+	case <-ws.closed:
+		return ErrWSClosed
+	default:
+	}
+	timer := time.NewTimer(ws.writeTimeout)
+	defer timer.Stop()
+	select {
+	case ws.out <- frame:
+		return nil
+	case <-ws.closed:
+		return ErrWSClosed
+	case <-timer.C:
+		ws.logger.Warn("WebSocket send queue is full, closing connection")
+		ws.Close(WSClosePolicy, "send queue full")
+		return ErrWSClosed
+	}
+}
+
+// writeLoop 依次发送队列中的消息，并按 websocket.ping_interval 发送心跳
+func (ws *WSContext) writeLoop() {
+	ticker := time.NewTicker(ws.pingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case frame := <-ws.out:
+			if err := ws.writeFrame(frame.opcode, frame.payload); err != nil {
+				ws.Close(WSCloseGoingAway, "")
+				return
+			}
+		case <-ticker.C:
+			if err := ws.writeFrame(wsOpPing, nil); err != nil {
+				ws.Close(WSCloseGoingAway, "")
+				return
+			}
+		case <-ws.closed:
+			return
+		}
+	}
+}
+
+// writeFrame 发送一个完整的帧，服务端发送的帧不加掩码
+func (ws *WSContext) writeFrame(opcode byte, payload []byte) error {
+	ws.writeMu.Lock()
+	defer ws.writeMu.Unlock()
+
+	header := make([]byte, 2, 10)
+	header[0] = 0x80 | opcode
+	switch length := len(payload); {
+	case length < 126:
+		header[1] = byte(length)
+	case length <= 0xFFFF:
+		header[1] = 126
+		header = binary.BigEndian.AppendUint16(header, uint16(length))
+	default:
+		header[1] = 127
+		header = binary.BigEndian.AppendUint64(header, uint64(length))
+	}
+	ws.conn.SetWriteDeadline(time.Now().Add(ws.writeTimeout))
+	buffers := net.Buffers{header, payload}
+	_, err := buffers.WriteTo(ws.conn)
+	return err
+}
+
+// readLoop 在后台持续读取客户端的帧，处理函数不调用 Receive 时也能回复 ping 并在收到关闭帧时关闭连接；
+// 消息放入接收队列，队列已满时暂停读取，直到处理函数取走消息
+func (ws *WSContext) readLoop() {
+	for {
+		_, data, err := ws.readMessage()
+		if err != nil {
+			return
+		}
+		select {
+		case ws.in <- data:
+		case <-ws.closed:
+			return
+		}
+	}
+}
+
+// readMessage 读取一条完整的消息，合并分片并处理期间收到的控制帧；出现协议错误时按对应状态码关闭连接
+func (ws *WSContext) readMessage() (byte, []byte, error) {
+	var opcode byte
+	var message []byte
+	for {
+		// 客户端需要在两个心跳间隔内回复 pong 或发送消息
+		ws.conn.SetReadDeadline(time.Now().Add(2 * ws.pingInterval))
+		fin, op, payload, err := ws.readFrame()
+		if err != nil {
+			var closeErr *wsCloseError
+			if errors.As(err, &closeErr) {
+				ws.logger.WithField("reason", closeErr.reason).Warn("WebSocket protocol error")
+				ws.Close(closeErr.code, closeErr.reason)
+			} else {
+				ws.Close(WSCloseGoingAway, "")
+			}
+			return 0, nil, ErrWSClosed
+		}
+
+		switch op {
+		case wsOpPing:
+			ws.writeFrame(wsOpPong, payload)
+			continue
+		case wsOpPong:
+			continue
+		case wsOpClose:
+			// 回复客户端的关闭状态码
+			code := WSCloseNormal
+			if len(payload) >= 2 {
+				code = int(binary.BigEndian.Uint16(payload))
+			}
+			if code < 1000 || code == 1005 || code == 1006 || code == 1015 || code > 4999 {
+				code = WSCloseNormal
+			}
+			ws.Close(code, "")
+			return 0, nil, ErrWSClosed
+		case wsOpText, wsOpBinary:
+			if opcode != 0 {
+				ws.Close(WSCloseProtocolError, "unexpected data frame during fragmented message")
+				return 0, nil, ErrWSClosed
+			}
+			opcode = op
+		case wsOpContinuation:
+			if opcode == 0 {
+				ws.Close(WSCloseProtocolError, "unexpected continuation frame")
+				return 0, nil, ErrWSClosed
+			}
+		default:
+			ws.Close(WSCloseProtocolError, "unknown opcode")
+			return 0, nil, ErrWSClosed
+		}
+
+		if int64(len(message)+len(payload)) > ws.maxSize {
+			ws.Close(WSCloseTooLarge, "message too large")
+			return 0, nil, ErrWSClosed
+		}
+		message = append(message, payload...)
+		if !fin {
+			continue
+		}
+		if opcode == wsOpText && !utf8.Valid(message) {
+			ws.Close(WSCloseInvalidData, "invalid UTF-8 text")
+			return 0, nil, ErrWSClosed
+		}
+		return opcode, message, nil
+	}
+}
+
+// readFrame 读取一个帧并去掉掩码，客户端发送的帧必须带掩码
+func (ws *WSContext) readFrame() (fin bool, opcode byte, payload []byte, err error) {
+	var header [2]byte
+	if _, err = io.ReadFull(ws.reader, header[:]); err != nil {
+		return
+	}
+	fin = header[0]&0x80 != 0
+	opcode = header[0] & 0x0F
+	if header[0]&0x70 != 0 {
+		return fin, opcode, nil, &wsCloseError{WSCloseProtocolError, "reserved bits set"}
+	}
+	if header[1]&0x80 == 0 {
+		return fin, opcode, nil, &wsCloseError{WSCloseProtocolError, "client frame is not masked"}
+	}
+
+	length := int64(header[1] & 0x7F)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err = io.ReadFull(ws.reader, ext[:]); err != nil {
+			return
+		}
+		length = int64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err = io.ReadFull(ws.reader, ext[:]); err != nil {
+			return
+		}
+		length = int64(binary.BigEndian.Uint64(ext[:]))
+	}
+	if opcode >= wsOpClose && (length > 125 || !fin) {
+		return fin, opcode, nil, &wsCloseError{WSCloseProtocolError, "invalid control frame"}
+	}
+	if length < 0 || length > ws.maxSize {
+		return fin, opcode, nil, &wsCloseError{WSCloseTooLarge, "message too large"}
+	}
+
+	var mask [4]byte
+	if _, err = io.ReadFull(ws.reader, mask[:]); err != nil {
+		return
+	}
+	payload = make([]byte, length)
+	if _, err = io.ReadFull(ws.reader, payload); err != nil {
+		return
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return fin, opcode, payload, nil
+}
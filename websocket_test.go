@@ -0,0 +1,120 @@
+package mod
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// wsTestClient 测试使用的最小 WebSocket 客户端，发送带掩码的帧
+type wsTestClient struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// dialWS 连接测试服务并完成握手
+func dialWS(t *testing.T, addr, path string) *wsTestClient {
+	t.Helper()
+	conn, err := net.DialTimeout("tcp", addr, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	req := "GET " + path + " HTTP/1.1\r\nHost: " + addr + "\r\nConnection: Upgrade\r\nUpgrade: websocket\r\n" +
+		"Sec-WebSocket-Version: 13\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n\r\n"
+	if _, err := conn.Write([]byte(req)); err != nil {
+		t.Fatal(err)
+	}
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("expected 101, got %d", resp.StatusCode)
+	}
+	return &wsTestClient{conn: conn, reader: reader}
+}
+
+func (c *wsTestClient) write(t *testing.T, opcode byte, payload []byte) {
+	t.Helper()
+	mask := [4]byte{1, 2, 3, 4}
+	frame := []byte{0x80 | opcode, 0x80 | byte(len(payload))}
+	frame = append(frame, mask[:]...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+	if _, err := c.conn.Write(frame); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func (c *wsTestClient) read(t *testing.T) (byte, []byte) {
+	t.Helper()
+	var header [2]byte
+	if _, err := io.ReadFull(c.reader, header[:]); err != nil {
+		t.Fatal(err)
+	}
+	payload := make([]byte, header[1]&0x7F)
+	if _, err := io.ReadFull(c.reader, payload); err != nil {
+		t.Fatal(err)
+	}
+	return header[0] & 0x0F, payload
+}
+
+func TestWSControlFramesWithoutReceive(t *testing.T) {
+	app := newTestApp(t, "")
+	closed := make(chan struct{})
+	err := app.RegisterWS(WS{
+		Name:        "push_only",
+		DisplayName: "push_only",
+		SkipAuth:    true,
+		Handler: func(ws *WSContext) error {
+			// 只推送、不调用 Receive 的处理函数
+			if err := ws.SendText("hello"); err != nil {
+				return err
+			}
+			<-ws.Context().Done()
+			close(closed)
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go app.Listener(ln)
+	t.Cleanup(func() { app.Shutdown() })
+
+	client := dialWS(t, ln.Addr().String(), app.ServicePath("push_only"))
+	if op, payload := client.read(t); op != wsOpText || string(payload) != "hello" {
+		t.Fatalf("unexpected frame %x %q", op, payload)
+	}
+
+	client.write(t, wsOpPing, []byte("beat"))
+	if op, payload := client.read(t); op != wsOpPong || string(payload) != "beat" {
+		t.Fatalf("expected pong, got %x %q", op, payload)
+	}
+
+	code := make([]byte, 2)
+	binary.BigEndian.PutUint16(code, WSCloseNormal)
+	client.write(t, wsOpClose, code)
+	op, payload := client.read(t)
+	if op != wsOpClose || binary.BigEndian.Uint16(payload) != WSCloseNormal {
+		t.Fatalf("expected close 1000, got %x %v", op, payload)
+	}
+	select {
+	case <-closed:
+	case <-time.After(2 * time.Second):
+		t.Fatal("handler did not observe the close")
+	}
+}
@@ -0,0 +1,218 @@
+package mod
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// xmlNode 解析后的XML元素，属性与只有文本的子元素等同处理
+type xmlNode struct {
+	name     string
+	text     string
+	children []*xmlNode
+}
+
+// parseXML 解析XML文档，返回根元素
+func parseXML(data []byte) (*xmlNode, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	var root *xmlNode
+	var stack []*xmlNode
+	var text []strings.Builder
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			node := &xmlNode{name: t.Name.Local}
+			for _, attr := range t.Attr {
+				if attr.Name.Space == "xmlns" || attr.Name.Local == "xmlns" {
+					continue
+				}
+				node.children = append(node.children, &xmlNode{name: attr.Name.Local, text: attr.Value})
+			}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, node)
+			} else if root == nil {
+				root = node
+			} else {
+				return nil, errors.New("multiple root elements")
+			}
+			stack = append(stack, node)
+			text = append(text, strings.Builder{})
+		case xml.EndElement:
+			node := stack[len(stack)-1]
+			node.text = strings.TrimSpace(text[len(text)-1].String())
+			stack, text = stack[:len(stack)-1], text[:len(text)-1]
+		case xml.CharData:
+			if len(text) > 0 {
+				text[len(text)-1].Write(t)
+			}
+		}
+	}
+	if root == nil {
+		return nil, errors.New("empty document")
+	}
+	return root, nil
+}
+
+// decodeXML 将XML请求体解析到 v：根元素的名称不限，子元素按 json 字段名匹配结构体字段，
+// 切片字段可以是重复的同名元素，也可以是包含同名子元素（如 <tags><item>a</item></tags>）的元素。
+// 按字段类型转换为JSON后解析，自定义的 UnmarshalJSON 和 time.Time 等类型与JSON请求一致
+func decodeXML(data []byte, v any) error {
+	root, err := parseXML(data)
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(xmlValue(root, reflect.TypeOf(v)))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(body, v)
+}
+
+// xmlValue 按目标类型将元素转换为JSON值，空的数字、布尔元素返回 nil
+func xmlValue(node *xmlNode, t reflect.Type) any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == timeType {
+		return node.text
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		fields := map[string]any{}
+		for _, f := range reflect.VisibleFields(t) {
+			if !f.IsExported() || (f.Anonymous && f.Tag.Get("json") == "") {
+				continue
+			}
+			name, _, skip := openAPIFieldName(f)
+			if skip {
+				continue
+			}
+			var matches []*xmlNode
+			for _, child := range node.children {
+				if child.name == name {
+					matches = append(matches, child)
+				}
+			}
+			if len(matches) == 0 {
+				continue
+			}
+			if ft := derefType(f.Type); ft.Kind() == reflect.Slice && ft.Elem().Kind() != reflect.Uint8 {
+				fields[name] = xmlList(matches, ft.Elem())
+			} else if value := xmlValue(matches[0], f.Type); value != nil {
+				fields[name] = value
+			}
+		}
+		return fields
+	case reflect.Map:
+		fields := map[string]any{}
+		for _, child := range node.children {
+			if value := xmlValue(child, t.Elem()); value != nil {
+				fields[child.name] = value
+			}
+		}
+		return fields
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return node.text
+		}
+		return xmlList([]*xmlNode{node}, t.Elem())
+	case reflect.Array:
+		return xmlList([]*xmlNode{node}, t.Elem())
+	case reflect.Interface:
+		return xmlAny(node)
+	case reflect.Bool:
+		if node.text == "" {
+			return nil
+		}
+		if b, err := strconv.ParseBool(node.text); err == nil {
+			return b
+		}
+		return node.text
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		if node.text == "" {
+			return nil
+		}
+		return json.Number(node.text)
+	default:
+		return node.text
+	}
+}
+
+// xmlList 将同名元素转换为数组；只有一个元素且其子元素同名时（元素不是包含该字段的结构体）视为列表的包装元素
+func xmlList(nodes []*xmlNode, elem reflect.Type) []any {
+	if len(nodes) == 1 && xmlWrapper(nodes[0], elem) {
+		nodes = nodes[0].children
+	}
+	list := make([]any, 0, len(nodes))
+	for _, node := range nodes {
+		list = append(list, xmlValue(node, elem))
+	}
+	return list
+}
+
+func xmlWrapper(node *xmlNode, elem reflect.Type) bool {
+	if len(node.children) == 0 {
+		return false
+	}
+	name := node.children[0].name
+	for _, child := range node.children[1:] {
+		if child.name != name {
+			return false
+		}
+	}
+	elem = derefType(elem)
+	if elem.Kind() != reflect.Struct || elem == timeType {
+		return true
+	}
+	for _, f := range reflect.VisibleFields(elem) {
+		if fieldName, _, skip := openAPIFieldName(f); !skip && fieldName == name {
+			return false
+		}
+	}
+	return true
+}
+
+// xmlAny 没有类型信息时的转换：有子元素时为对象（同名子元素为数组），否则为文本
+func xmlAny(node *xmlNode) any {
+	if len(node.children) == 0 {
+		return node.text
+	}
+	fields := map[string]any{}
+	for _, child := range node.children {
+		value := xmlAny(child)
+		switch existing := fields[child.name].(type) {
+		case nil:
+			fields[child.name] = value
+		case []any:
+			fields[child.name] = append(existing, value)
+		default:
+			fields[child.name] = []any{existing, value}
+		}
+	}
+	return fields
+}
+
+// derefType 返回指针指向的类型
+func derefType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}